@@ -18,6 +18,7 @@ import (
 	corev1 "k8s.io/api/core/v1"
 	cliflag "k8s.io/component-base/cli/flag"
 	"k8s.io/klog/v2"
+	iamv1beta1 "kubesphere.io/api/iam/v1beta1"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/cluster"
@@ -29,6 +30,7 @@ import (
 	"kubesphere.io/kubesphere/pkg/models/auth"
 	"kubesphere.io/kubesphere/pkg/models/registries/imagesearch"
 	resourcev1beta1 "kubesphere.io/kubesphere/pkg/models/resources/v1beta1"
+	"kubesphere.io/kubesphere/pkg/models/terminal"
 	"kubesphere.io/kubesphere/pkg/scheme"
 	genericoptions "kubesphere.io/kubesphere/pkg/server/options"
 	"kubesphere.io/kubesphere/pkg/simple/client/cache"
@@ -101,6 +103,9 @@ func (s *APIServerOptions) NewAPIServer(ctx context.Context) (*apiserver.APIServ
 		if err = apiServer.RuntimeCache.IndexField(ctx, &corev1.Event{}, key, indexerFunc); err != nil {
 			klog.Fatalf("unable to create index field: %v", err)
 		}
+		if err = apiServer.RuntimeCache.IndexField(ctx, &iamv1beta1.AccessRequest{}, terminal.AccessRequestUserIndex, terminal.AccessRequestIndexByUser); err != nil {
+			klog.Fatalf("unable to create index field: %v", err)
+		}
 		apiServer.RuntimeClient = c.GetClient()
 	}
 
@@ -185,7 +190,13 @@ func (s *APIServerOptions) Merge(conf *config.Config) {
 	if conf.S3Options != nil {
 		s.S3Options = conf.S3Options
 	}
+	if conf.AppScanOptions != nil {
+		s.AppScanOptions = conf.AppScanOptions
+	}
 	if conf.ExperimentalOptions != nil {
 		s.ExperimentalOptions = conf.ExperimentalOptions
 	}
+	if conf.ExtensionOptions != nil {
+		s.ExtensionOptions = conf.ExtensionOptions
+	}
 }
@@ -104,6 +104,9 @@ func (s *ControllerManagerOptions) Validate() []error {
 	errs = append(errs, s.KubernetesOptions.Validate()...)
 	errs = append(errs, s.MultiClusterOptions.Validate()...)
 	errs = append(errs, s.ComposedAppOptions.Validate()...)
+	if s.AppScanOptions != nil {
+		errs = append(errs, s.AppScanOptions.Validate()...)
+	}
 
 	// genetic option: controllers, check all selectors are valid
 	allControllersNameSet := sets.KeySet(controller.Controllers)
@@ -147,6 +150,9 @@ func (s *ControllerManagerOptions) Merge(conf *config.Config) {
 	if conf.AuthenticationOptions != nil {
 		s.AuthenticationOptions = conf.AuthenticationOptions
 	}
+	if conf.AuthorizationOptions != nil {
+		s.AuthorizationOptions = conf.AuthorizationOptions
+	}
 	if conf.MultiClusterOptions != nil {
 		s.MultiClusterOptions = conf.MultiClusterOptions
 	}
@@ -171,6 +177,9 @@ func (s *ControllerManagerOptions) Merge(conf *config.Config) {
 	if conf.S3Options != nil {
 		s.S3Options = conf.S3Options
 	}
+	if conf.AppScanOptions != nil {
+		s.AppScanOptions = conf.AppScanOptions
+	}
 }
 
 func (s *ControllerManagerOptions) NewControllerManager() (*controller.Manager, error) {
@@ -16,11 +16,13 @@ import (
 	"k8s.io/apimachinery/pkg/util/runtime"
 	cliflag "k8s.io/component-base/cli/flag"
 	"k8s.io/klog/v2"
+	iamv1beta1 "kubesphere.io/api/iam/v1beta1"
 	"sigs.k8s.io/controller-runtime/pkg/manager/signals"
 
 	"kubesphere.io/kubesphere/cmd/ks-controller-manager/app/options"
 	"kubesphere.io/kubesphere/pkg/config"
 	"kubesphere.io/kubesphere/pkg/controller"
+	"kubesphere.io/kubesphere/pkg/controller/accessrequest"
 	"kubesphere.io/kubesphere/pkg/controller/application"
 	"kubesphere.io/kubesphere/pkg/controller/certificatesigningrequest"
 	"kubesphere.io/kubesphere/pkg/controller/cluster"
@@ -44,6 +46,7 @@ import (
 	"kubesphere.io/kubesphere/pkg/controller/resourceprotection"
 	"kubesphere.io/kubesphere/pkg/controller/role"
 	"kubesphere.io/kubesphere/pkg/controller/rolebinding"
+	"kubesphere.io/kubesphere/pkg/controller/rolebindingexpiration"
 	"kubesphere.io/kubesphere/pkg/controller/roletemplate"
 	"kubesphere.io/kubesphere/pkg/controller/secret"
 	"kubesphere.io/kubesphere/pkg/controller/serviceaccount"
@@ -81,18 +84,24 @@ func init() {
 	runtime.Must(controller.Register(&clusterrolebinding.Reconciler{}))
 	runtime.Must(controller.Register(&role.Reconciler{}))
 	runtime.Must(controller.Register(&rolebinding.Reconciler{}))
+	runtime.Must(controller.Register(&rolebindingexpiration.Reconciler{Kind: iamv1beta1.ResourceKindGlobalRoleBinding}))
+	runtime.Must(controller.Register(&rolebindingexpiration.Reconciler{Kind: iamv1beta1.ResourceKindWorkspaceRoleBinding}))
+	runtime.Must(controller.Register(&rolebindingexpiration.Reconciler{Kind: iamv1beta1.ResourceKindClusterRoleBinding}))
+	runtime.Must(controller.Register(&rolebindingexpiration.Reconciler{Kind: iamv1beta1.ResourceKindRoleBinding}))
 	runtime.Must(controller.Register(&roletemplate.Reconciler{}))
 	runtime.Must(controller.Register(&namespace.Reconciler{}))
 	// user management
 	runtime.Must(controller.Register(&user.Reconciler{}))
 	runtime.Must(controller.Register(&user.Webhook{}))
 	runtime.Must(controller.Register(&loginrecord.Reconciler{}))
+	runtime.Must(controller.Register(&accessrequest.Reconciler{}))
 	// multi cluster
 	runtime.Must(controller.Register(&cluster.Reconciler{}))
 	runtime.Must(controller.Register(&cluster.Webhook{}))
 	runtime.Must(controller.Register(&clusterlabel.Reconciler{}))
 	// multi tenancy
 	runtime.Must(controller.Register(&workspace.Reconciler{}))
+	runtime.Must(controller.Register(&workspace.SuspensionWebhook{}))
 	runtime.Must(controller.Register(&workspacetemplate.Reconciler{}))
 	// kubesphere service account
 	runtime.Must(controller.Register(&ksserviceaccount.Reconciler{}))
@@ -111,6 +120,7 @@ func init() {
 	// resource quota
 	runtime.Must(controller.Register(&quota.Reconciler{}))
 	runtime.Must(controller.Register(&quota.Webhook{}))
+	runtime.Must(controller.Register(&quota.WorkspaceQuotaReconciler{}))
 	// app store
 	runtime.Must(controller.Register(&application.AppReleaseReconciler{}))
 	runtime.Must(controller.Register(&application.RepoReconciler{}))
@@ -41,8 +41,10 @@ import (
 	"kubesphere.io/kubesphere/pkg/apiserver/authorization"
 	"kubesphere.io/kubesphere/pkg/apiserver/authorization/authorizer"
 	"kubesphere.io/kubesphere/pkg/apiserver/authorization/authorizerfactory"
+	"kubesphere.io/kubesphere/pkg/apiserver/authorization/passwordchange"
 	"kubesphere.io/kubesphere/pkg/apiserver/authorization/path"
 	"kubesphere.io/kubesphere/pkg/apiserver/authorization/rbac"
+	"kubesphere.io/kubesphere/pkg/apiserver/authorization/scope"
 	unionauthorizer "kubesphere.io/kubesphere/pkg/apiserver/authorization/union"
 	"kubesphere.io/kubesphere/pkg/apiserver/filters"
 	"kubesphere.io/kubesphere/pkg/apiserver/metrics"
@@ -170,15 +172,19 @@ func (s *APIServer) installKubeSphereAPIs() {
 	rbacAuthorizer := rbac.NewRBACAuthorizer(amOperator)
 	counter := overviewclient.New(s.RuntimeClient)
 	counter.RegisterResource(overviewclient.NewDefaultRegisterOptions(s.K8sVersion)...)
+	var portalURL string
+	if s.AuthenticationOptions != nil && s.AuthenticationOptions.Issuer != nil {
+		portalURL = s.AuthenticationOptions.Issuer.URL
+	}
 
 	handlers := []rest.Handler{
 		configv1alpha2.NewHandler(&s.Options, s.RuntimeClient),
 		resourcev1alpha3.NewHandler(s.RuntimeCache, counter, s.K8sVersion),
 		operationsv1alpha2.NewHandler(s.RuntimeClient),
-		resourcesv1alpha2.NewHandler(s.RuntimeClient, s.K8sVersion, s.K8sClient.Master(), s.TerminalOptions),
+		resourcesv1alpha2.NewHandler(s.RuntimeClient, s.K8sVersion, s.K8sClient.Master(), s.K8sClient.Config(), s.TerminalOptions, rbacAuthorizer),
 		tenantapiv1alpha3.NewHandler(s.RuntimeClient, s.K8sVersion, s.ClusterClient, amOperator, imOperator, rbacAuthorizer),
 		tenantapiv1beta1.NewHandler(s.RuntimeClient, s.K8sVersion, s.ClusterClient, amOperator, imOperator, rbacAuthorizer, counter),
-		terminalv1alpha2.NewHandler(s.K8sClient, rbacAuthorizer, s.K8sClient.Config(), s.TerminalOptions),
+		terminalv1alpha2.NewHandler(s.K8sClient, s.RuntimeClient, rbacAuthorizer, s.K8sClient.Config(), s.TerminalOptions, s.S3Options),
 		clusterkapisv1alpha1.NewHandler(s.RuntimeClient),
 		iamapiv1beta1.NewHandler(imOperator, amOperator),
 		oauth.NewHandler(imOperator, s.TokenOperator, auth.NewPasswordAuthenticator(s.RuntimeClient, s.AuthenticationOptions),
@@ -186,9 +192,9 @@ func (s *APIServer) installKubeSphereAPIs() {
 			auth.NewLoginRecorder(s.RuntimeClient), s.AuthenticationOptions,
 			oauth2.NewOAuthClientGetter(s.RuntimeClient)),
 		version.NewHandler(s.K8sVersionInfo),
-		packagev1alpha1.NewHandler(s.RuntimeCache),
+		packagev1alpha1.NewHandler(s.RuntimeCache, s.RuntimeClient, portalURL, s.ExtensionOptions),
 		gatewayv1alpha2.NewHandler(s.RuntimeCache),
-		appv2.NewHandler(s.RuntimeClient, s.ClusterClient, s.S3Options),
+		appv2.NewHandler(s.RuntimeClient, s.ClusterClient, s.S3Options, s.AppScanOptions, rbacAuthorizer),
 		workloadtemplatev1alpha1.NewHandler(s.RuntimeClient, s.K8sVersion, rbacAuthorizer),
 		static.NewHandler(s.CacheClient),
 	}
@@ -272,7 +278,7 @@ func (s *APIServer) buildHandlerChain(handler http.Handler, stopCh <-chan struct
 		excludedPaths := []string{"/oauth/*", "/dist/*", "/.well-known/openid-configuration", "/version", "/metrics", "/livez", "/healthz", "/openapi/v2", "/openapi/v3"}
 		pathAuthorizer, _ := path.NewAuthorizer(excludedPaths)
 		amOperator := am.NewReadOnlyOperator(s.ResourceManager)
-		authorizers = unionauthorizer.New(pathAuthorizer, rbac.NewRBACAuthorizer(amOperator))
+		authorizers = unionauthorizer.New(pathAuthorizer, passwordchange.NewAuthorizer(), scope.NewAuthorizer(), rbac.NewRBACAuthorizer(amOperator))
 	}
 
 	handler = filters.WithAuthorization(handler, authorizers)
@@ -8,7 +8,10 @@ package jwt
 
 import (
 	"context"
+	"fmt"
 
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apiserver/pkg/authentication/authenticator"
 	"k8s.io/apiserver/pkg/authentication/user"
@@ -17,6 +20,7 @@ import (
 	corev1alpha1 "kubesphere.io/api/core/v1alpha1"
 	iamv1beta1 "kubesphere.io/api/iam/v1beta1"
 	runtimecache "sigs.k8s.io/controller-runtime/pkg/cache"
+	runtimeclient "sigs.k8s.io/controller-runtime/pkg/client"
 
 	"kubesphere.io/kubesphere/pkg/apiserver/authentication/token"
 	"kubesphere.io/kubesphere/pkg/models/auth"
@@ -56,6 +60,10 @@ func (t *tokenAuthenticator) AuthenticateToken(ctx context.Context, token string
 				return nil, false, err
 			}
 		}
+		// the revoked tokens must be rejected by all the clusters
+		if err = t.validateRevocation(ctx, verified); err != nil {
+			return nil, false, err
+		}
 		return &authenticator.Response{
 			User: verified.User,
 		}, true, nil
@@ -67,6 +75,7 @@ func (t *tokenAuthenticator) AuthenticateToken(ctx context.Context, token string
 		}, true, nil
 	}
 
+	groups := []string{user.AllAuthenticated}
 	if t.clusterRole == string(clusterv1alpha1.ClusterRoleHost) {
 		userInfo := &iamv1beta1.User{}
 		if err := t.cache.Get(ctx, types.NamespacedName{Name: verified.User.GetName()}, userInfo); err != nil {
@@ -77,13 +86,13 @@ func (t *tokenAuthenticator) AuthenticateToken(ctx context.Context, token string
 		if userInfo.Status.State == iamv1beta1.UserDisabled {
 			return nil, false, auth.AccountIsNotActiveError
 		}
+		groups = auth.UserGroups(userInfo)
 	}
 
 	return &authenticator.Response{
 		User: &user.DefaultInfo{
-			Name: verified.User.GetName(),
-			// TODO(wenhaozhou) Add user`s groups(can be searched by GroupBinding)
-			Groups: []string{user.AllAuthenticated},
+			Name:   verified.User.GetName(),
+			Groups: groups,
 		},
 	}, true, nil
 }
@@ -97,3 +106,43 @@ func (t *tokenAuthenticator) validateServiceAccount(ctx context.Context, verify
 	}
 	return sa, nil
 }
+
+// validateRevocation ensures the token is not revoked by the secret it's issued to, the tokens issued
+// before the secret is recorded are reissued by the controller, and they are rejected once the secrets
+// of the service account are migrated. The secret may be absent on the member clusters, since the service
+// account can belong to another cluster.
+func (t *tokenAuthenticator) validateRevocation(ctx context.Context, verify *token.VerifiedResponse) error {
+	name, namespace := serviceaccount.SplitUsername(verify.Username)
+	secretName, secretNamespace := serviceaccount.GetSecretName(verify.User)
+	if secretName == "" {
+		return t.validateLegacyToken(ctx, name, namespace)
+	}
+	secret := &corev1.Secret{}
+	if err := t.cache.Get(ctx, types.NamespacedName{Name: secretName, Namespace: secretNamespace}, secret); err != nil {
+		if apierrors.IsNotFound(err) && t.clusterRole != string(clusterv1alpha1.ClusterRoleHost) {
+			return nil
+		}
+		return err
+	}
+	if secret.Annotations[corev1alpha1.ServiceAccountName] != name || serviceaccount.IsRevoked(secret, verify.ID) {
+		return fmt.Errorf("token has been revoked")
+	}
+	return nil
+}
+
+// validateLegacyToken rejects the tokens issued without the secret once any secret of the service account
+// records the ID of its token, since the secrets are migrated by reissuing the tokens.
+func (t *tokenAuthenticator) validateLegacyToken(ctx context.Context, name, namespace string) error {
+	secrets := &corev1.SecretList{}
+	if err := t.cache.List(ctx, secrets, runtimeclient.InNamespace(namespace)); err != nil {
+		return err
+	}
+	for _, secret := range secrets.Items {
+		if secret.Type == corev1alpha1.SecretTypeServiceAccountToken &&
+			secret.Annotations[corev1alpha1.ServiceAccountName] == name &&
+			secret.Annotations[corev1alpha1.ServiceAccountTokenID] != "" {
+			return fmt.Errorf("token has been revoked")
+		}
+	}
+	return nil
+}
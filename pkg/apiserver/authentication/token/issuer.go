@@ -121,6 +121,9 @@ func (s *issuer) IssueTo(request *IssueRequest) (string, error) {
 		},
 	}
 
+	if request.ID != "" {
+		claims.ID = request.ID
+	}
 	if len(request.Audience) > 0 {
 		claims.Audience = request.Audience
 	}
@@ -8,6 +8,7 @@ package authorization
 
 import (
 	"fmt"
+	"time"
 
 	"github.com/spf13/pflag"
 	"k8s.io/klog/v2"
@@ -17,10 +18,13 @@ import (
 
 type Options struct {
 	Mode string `json:"mode" yaml:"mode"`
+	// RoleBindingExpirationWarningPeriod is how long before a time-bound role binding expires
+	// that the user is warned, 0 means no warning.
+	RoleBindingExpirationWarningPeriod time.Duration `json:"roleBindingExpirationWarningPeriod,omitempty" yaml:"roleBindingExpirationWarningPeriod,omitempty"`
 }
 
 func NewOptions() *Options {
-	return &Options{Mode: RBAC}
+	return &Options{Mode: RBAC, RoleBindingExpirationWarningPeriod: 72 * time.Hour}
 }
 
 var (
@@ -31,6 +35,7 @@ var (
 
 func (o *Options) AddFlags(fs *pflag.FlagSet, s *Options) {
 	fs.StringVar(&o.Mode, "authorization", s.Mode, "Authorization setting, allowed values: AlwaysDeny, AlwaysAllow, RBAC.")
+	fs.DurationVar(&o.RoleBindingExpirationWarningPeriod, "role-binding-expiration-warning-period", s.RoleBindingExpirationWarningPeriod, "How long before a time-bound role binding expires that the user is warned, 0 means no warning.")
 }
 
 func (o *Options) Validate() []error {
@@ -40,5 +45,8 @@ func (o *Options) Validate() []error {
 		klog.Error(err)
 		errs = append(errs, err)
 	}
+	if o.RoleBindingExpirationWarningPeriod < 0 {
+		errs = append(errs, fmt.Errorf("role binding expiration warning period must not be negative"))
+	}
 	return errs
 }
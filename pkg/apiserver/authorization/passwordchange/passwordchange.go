@@ -0,0 +1,53 @@
+/*
+ * Copyright 2024 the KubeSphere Authors.
+ * Please refer to the LICENSE file in the root directory of the project.
+ * https://github.com/kubesphere/kubesphere/blob/master/LICENSE
+ */
+
+// Package passwordchange contains an authorizer that restricts the users who must change their password,
+// e.g. the password has expired, to changing their own password and logging out.
+package passwordchange
+
+import (
+	iamv1beta1 "kubesphere.io/api/iam/v1beta1"
+
+	"kubesphere.io/kubesphere/pkg/apiserver/authorization/authorizer"
+)
+
+const logoutPath = "/oauth/logout"
+
+// required returns true if the user must change the password before accessing the other APIs.
+func required(extra map[string][]string) bool {
+	for _, value := range extra[iamv1beta1.ExtraPasswordChangeRequired] {
+		if value == "true" {
+			return true
+		}
+	}
+	return false
+}
+
+// allows returns true if the request changes the password of the user or logs out.
+func allows(a authorizer.Attributes) bool {
+	if !a.IsResourceRequest() {
+		return a.GetPath() == logoutPath
+	}
+	return a.GetVerb() == "update" &&
+		a.GetAPIGroup() == iamv1beta1.GroupName &&
+		a.GetResource() == iamv1beta1.ResourcesPluralUser &&
+		a.GetSubresource() == "password" &&
+		a.GetName() == a.GetUser().GetName()
+}
+
+// NewAuthorizer returns an authorizer which denies the requests of the users who must change their password,
+// except changing the password and logging out, it has no opinion on the other requests.
+func NewAuthorizer() authorizer.Authorizer {
+	return authorizer.AuthorizerFunc(func(a authorizer.Attributes) (authorizer.Decision, string, error) {
+		if a.GetUser() == nil || !required(a.GetUser().GetExtra()) {
+			return authorizer.DecisionNoOpinion, "", nil
+		}
+		if allows(a) {
+			return authorizer.DecisionNoOpinion, "", nil
+		}
+		return authorizer.DecisionDeny, "the password must be changed", nil
+	})
+}
@@ -0,0 +1,50 @@
+/*
+ * Copyright 2024 the KubeSphere Authors.
+ * Please refer to the LICENSE file in the root directory of the project.
+ * https://github.com/kubesphere/kubesphere/blob/master/LICENSE
+ */
+
+package passwordchange
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apiserver/pkg/authentication/user"
+	iamv1beta1 "kubesphere.io/api/iam/v1beta1"
+
+	"kubesphere.io/kubesphere/pkg/apiserver/authorization/authorizer"
+)
+
+func TestAuthorizer(t *testing.T) {
+	expired := &user.DefaultInfo{
+		Name:  "admin",
+		Extra: map[string][]string{iamv1beta1.ExtraPasswordChangeRequired: {"true"}},
+	}
+	changePassword := func(u user.Info, name string) authorizer.AttributesRecord {
+		return authorizer.AttributesRecord{User: u, Verb: "update", APIGroup: iamv1beta1.GroupName,
+			Resource: "users", Subresource: "password", Name: name, ResourceRequest: true}
+	}
+	tests := []struct {
+		name  string
+		attrs authorizer.AttributesRecord
+		want  authorizer.Decision
+	}{
+		{"anonymous", authorizer.AttributesRecord{Verb: "get", Path: "/kapis/version"}, authorizer.DecisionNoOpinion},
+		{"not required", authorizer.AttributesRecord{User: &user.DefaultInfo{Name: "admin"}, Verb: "list", Resource: "pods", ResourceRequest: true}, authorizer.DecisionNoOpinion},
+		{"list pods", authorizer.AttributesRecord{User: expired, Verb: "list", Resource: "pods", ResourceRequest: true}, authorizer.DecisionDeny},
+		{"get user", authorizer.AttributesRecord{User: expired, Verb: "get", APIGroup: iamv1beta1.GroupName, Resource: "users", Name: "admin", ResourceRequest: true}, authorizer.DecisionDeny},
+		{"get version", authorizer.AttributesRecord{User: expired, Verb: "get", Path: "/kapis/version"}, authorizer.DecisionDeny},
+		{"change password", changePassword(expired, "admin"), authorizer.DecisionNoOpinion},
+		{"change password of another user", changePassword(expired, "guest"), authorizer.DecisionDeny},
+		{"logout", authorizer.AttributesRecord{User: expired, Verb: "get", Path: "/oauth/logout"}, authorizer.DecisionNoOpinion},
+	}
+	a := NewAuthorizer()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			decision, _, err := a.Authorize(tt.attrs)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, decision)
+		})
+	}
+}
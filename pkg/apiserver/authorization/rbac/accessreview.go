@@ -0,0 +1,238 @@
+/*
+ * Copyright 2024 the KubeSphere Authors.
+ * Please refer to the LICENSE file in the root directory of the project.
+ * https://github.com/kubesphere/kubesphere/blob/master/LICENSE
+ */
+
+package rbac
+
+import (
+	"fmt"
+	"sort"
+
+	rbacv1 "k8s.io/api/rbac/v1"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/apiserver/pkg/authentication/serviceaccount"
+	"k8s.io/apiserver/pkg/authentication/user"
+	corev1alpha1 "kubesphere.io/api/core/v1alpha1"
+	iamv1beta1 "kubesphere.io/api/iam/v1beta1"
+
+	"kubesphere.io/kubesphere/pkg/apiserver/authorization/authorizer"
+)
+
+// AccessReviewRecord is a record of the access review report, it tells the permission granted to the subject
+// and the binding path which grants it.
+type AccessReviewRecord struct {
+	Subject          rbacv1.Subject `json:"subject"`
+	BindingKind      string         `json:"bindingKind"`
+	BindingName      string         `json:"bindingName"`
+	BindingNamespace string         `json:"bindingNamespace,omitempty"`
+	RoleRef          rbacv1.RoleRef `json:"roleRef"`
+	// Group is the group of the user which the binding is bound to, the permission is granted to the user through the group.
+	Group string `json:"group,omitempty"`
+	// Rule is the rule of the role which grants the permission, it's nil if the permission is granted by the rego policy.
+	Rule       *rbacv1.PolicyRule `json:"rule,omitempty"`
+	RegoPolicy string             `json:"regoPolicy,omitempty"`
+}
+
+// accessReviewVisitor collects the records of the subject, only the rules allowing the request are collected
+// if the request attributes are specified.
+type accessReviewVisitor struct {
+	subject           rbacv1.Subject
+	requestAttributes authorizer.Attributes
+
+	records []AccessReviewRecord
+	errors  []error
+}
+
+func (v *accessReviewVisitor) visit(source fmt.Stringer, regoPolicy string, rule *rbacv1.PolicyRule, err error) bool {
+	if err != nil {
+		v.errors = append(v.errors, err)
+		return true
+	}
+	if regoPolicy == "" && rule == nil {
+		return true
+	}
+	record := newAccessReviewRecord(v.subject, source)
+	if v.requestAttributes != nil {
+		if !(regoPolicy != "" && regoPolicyAllows(v.requestAttributes, regoPolicy)) &&
+			!(rule != nil && ruleAllows(v.requestAttributes, rule)) {
+			return true
+		}
+		// one record for each binding is enough to tell the binding path
+		if n := len(v.records); n > 0 && v.records[n-1].sameBinding(record) {
+			return true
+		}
+	}
+	if rule != nil {
+		copied := *rule
+		record.Rule = &copied
+	} else {
+		record.RegoPolicy = regoPolicy
+	}
+	v.records = append(v.records, record)
+	return true
+}
+
+func newAccessReviewRecord(subject rbacv1.Subject, source fmt.Stringer) AccessReviewRecord {
+	record := AccessReviewRecord{Subject: subject}
+	var bound *rbacv1.Subject
+	switch d := source.(type) {
+	case *globalRoleBindingDescriber:
+		record.BindingKind = iamv1beta1.ResourceKindGlobalRoleBinding
+		record.BindingName = d.binding.Name
+		record.RoleRef = d.binding.RoleRef
+		bound = d.subject
+	case *workspaceRoleBindingDescriber:
+		record.BindingKind = iamv1beta1.ResourceKindWorkspaceRoleBinding
+		record.BindingName = d.binding.Name
+		record.RoleRef = d.binding.RoleRef
+		bound = d.subject
+	case *clusterRoleBindingDescriber:
+		record.BindingKind = iamv1beta1.ResourceKindClusterRoleBinding
+		record.BindingName = d.binding.Name
+		record.RoleRef = d.binding.RoleRef
+		bound = d.subject
+	case *roleBindingDescriber:
+		record.BindingKind = iamv1beta1.ResourceKindRoleBinding
+		record.BindingName = d.binding.Name
+		record.BindingNamespace = d.binding.Namespace
+		record.RoleRef = d.binding.RoleRef
+		bound = d.subject
+	}
+	if subject.Kind == rbacv1.UserKind && bound != nil && bound.Kind == rbacv1.GroupKind {
+		record.Group = bound.Name
+	}
+	return record
+}
+
+func (r AccessReviewRecord) sameBinding(other AccessReviewRecord) bool {
+	return r.BindingKind == other.BindingKind && r.BindingName == other.BindingName && r.BindingNamespace == other.BindingNamespace
+}
+
+// SubjectsAllowed returns the records of the users, groups and service accounts which are allowed to perform the request.
+// The subjects are collected from the bindings in the scope of the request, the users are not expanded into their groups,
+// so the permissions granted to a group are reported with the group.
+func (r *Authorizer) SubjectsAllowed(requestAttributes authorizer.AttributesRecord) ([]AccessReviewRecord, error) {
+	// the bindings are visited once, the records are grouped by the subjects
+	visitors := make(map[rbacv1.Subject]*accessReviewVisitor)
+	var errs []error
+	err := r.visitBindingsFor(requestAttributes, func(binding *scopedBinding) bool {
+		regoPolicy, rules, err := r.am.GetRoleReferenceRules(binding.roleRef, binding.namespace)
+		if err != nil {
+			errs = append(errs, err)
+			return binding.skipOnRoleError
+		}
+		visited := make(map[rbacv1.Subject]bool, len(binding.subjects))
+		for i := range binding.subjects {
+			subject := normalizeSubject(binding.subjects[i], binding.namespace)
+			if visited[subject] {
+				continue
+			}
+			visited[subject] = true
+			// the subject never applies to any user, see appliesToUser
+			if _, err := userInfoFor(subject); err != nil {
+				continue
+			}
+			visitor, ok := visitors[subject]
+			if !ok {
+				visitor = &accessReviewVisitor{subject: subject, requestAttributes: requestAttributes}
+				visitors[subject] = visitor
+			}
+			source := binding.describe(&binding.subjects[i])
+			visitor.visit(source, regoPolicy, nil, nil)
+			for j := range rules {
+				visitor.visit(source, "", &rules[j], nil)
+			}
+		}
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	subjects := make([]rbacv1.Subject, 0, len(visitors))
+	for subject := range visitors {
+		subjects = append(subjects, subject)
+	}
+	sortSubjects(subjects)
+	var records []AccessReviewRecord
+	for _, subject := range subjects {
+		records = append(records, visitors[subject].records...)
+	}
+	return records, utilerrors.NewAggregate(errs)
+}
+
+// SubjectRules returns the records of all the rules granted to the subject in the scope of the request attributes.
+// The user is expanded into the groups attached by the authenticators, the rules granted to the groups are
+// labeled with the group.
+func (r *Authorizer) SubjectRules(subject rbacv1.Subject, requestAttributes authorizer.AttributesRecord) ([]AccessReviewRecord, error) {
+	subject = normalizeSubject(subject, "")
+	userInfo, err := userInfoFor(subject)
+	if err != nil {
+		return nil, err
+	}
+	if subject.Kind == rbacv1.UserKind {
+		groups, err := r.am.GetGroupsOfUser(subject.Name)
+		if err != nil {
+			return nil, err
+		}
+		userInfo = &user.DefaultInfo{Name: subject.Name, Groups: groups}
+	}
+	visitor := &accessReviewVisitor{subject: subject}
+	requestAttributes.User = userInfo
+	r.visitRulesFor(requestAttributes, visitor.visit)
+	return visitor.records, utilerrors.NewAggregate(visitor.errors)
+}
+
+// sortSubjects sorts the subjects by the kind, the namespace, the name and the API group.
+func sortSubjects(subjects []rbacv1.Subject) {
+	sort.Slice(subjects, func(i, j int) bool {
+		if subjects[i].Kind != subjects[j].Kind {
+			return subjects[i].Kind < subjects[j].Kind
+		}
+		if subjects[i].Namespace != subjects[j].Namespace {
+			return subjects[i].Namespace < subjects[j].Namespace
+		}
+		if subjects[i].Name != subjects[j].Name {
+			return subjects[i].Name < subjects[j].Name
+		}
+		return subjects[i].APIGroup < subjects[j].APIGroup
+	})
+}
+
+// normalizeSubject makes the same subject referenced by different bindings comparable, the namespace of the service
+// account defaults to the namespace of the binding.
+func normalizeSubject(subject rbacv1.Subject, namespace string) rbacv1.Subject {
+	switch subject.Kind {
+	case rbacv1.ServiceAccountKind:
+		if subject.Namespace == "" {
+			subject.Namespace = namespace
+		}
+	default:
+		subject.APIGroup = ""
+		subject.Namespace = ""
+	}
+	return subject
+}
+
+// userInfoFor returns the user info which the subject applies to.
+func userInfoFor(subject rbacv1.Subject) (user.Info, error) {
+	switch subject.Kind {
+	case rbacv1.UserKind:
+		return &user.DefaultInfo{Name: subject.Name}, nil
+	case rbacv1.GroupKind:
+		return &user.DefaultInfo{Groups: []string{subject.Name}}, nil
+	case rbacv1.ServiceAccountKind:
+		if subject.Namespace == "" {
+			return nil, fmt.Errorf("the namespace of %s %q is required", subject.Kind, subject.Name)
+		}
+		switch subject.APIGroup {
+		case rbacv1.GroupName:
+			return &user.DefaultInfo{Name: serviceaccount.MakeUsername(subject.Namespace, subject.Name)}, nil
+		case corev1alpha1.GroupName:
+			return &user.DefaultInfo{Name: corev1alpha1.ServiceAccountTokenPrefix + subject.Namespace + ":" + subject.Name}, nil
+		}
+	}
+	return nil, fmt.Errorf("unsupported subject %s %q of API group %q", subject.Kind, subject.Name, subject.APIGroup)
+}
@@ -0,0 +1,140 @@
+/*
+ * Copyright 2024 the KubeSphere Authors.
+ * Please refer to the LICENSE file in the root directory of the project.
+ * https://github.com/kubesphere/kubesphere/blob/master/LICENSE
+ */
+
+package rbac
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	iamv1beta1 "kubesphere.io/api/iam/v1beta1"
+	tenantv1beta1 "kubesphere.io/api/tenant/v1beta1"
+
+	"kubesphere.io/kubesphere/pkg/apiserver/authorization/authorizer"
+	"kubesphere.io/kubesphere/pkg/apiserver/request"
+)
+
+func TestAccessReview(t *testing.T) {
+	ruleAdmin := rbacv1.PolicyRule{Verbs: []string{"*"}, APIGroups: []string{"*"}, Resources: []string{"*"}}
+	ruleReadSecrets := rbacv1.PolicyRule{Verbs: []string{"get", "list"}, APIGroups: []string{""}, Resources: []string{"secrets"}}
+	ruleDeleteSecrets := rbacv1.PolicyRule{Verbs: []string{"delete"}, APIGroups: []string{""}, Resources: []string{"secrets"}}
+	workspaceLabels := map[string]string{tenantv1beta1.WorkspaceLabel: "ws1"}
+
+	r, err := newMockRBACAuthorizer(&StaticRoles{
+		users: []*iamv1beta1.User{
+			{ObjectMeta: metav1.ObjectMeta{Name: "bob"}, Spec: iamv1beta1.UserSpec{Groups: []string{"ops"}}},
+		},
+		globalRoles: []*iamv1beta1.GlobalRole{
+			{ObjectMeta: metav1.ObjectMeta{Name: "platform-admin"}, Rules: []rbacv1.PolicyRule{ruleAdmin}},
+		},
+		globalRoleBindings: []*iamv1beta1.GlobalRoleBinding{
+			{
+				ObjectMeta: metav1.ObjectMeta{Name: "admin-platform-admin"},
+				RoleRef:    rbacv1.RoleRef{APIGroup: iamv1beta1.GroupName, Kind: iamv1beta1.ResourceKindGlobalRole, Name: "platform-admin"},
+				Subjects:   []rbacv1.Subject{{Kind: rbacv1.UserKind, APIGroup: iamv1beta1.GroupName, Name: "admin"}},
+			},
+		},
+		workspaceRoles: []*iamv1beta1.WorkspaceRole{
+			{ObjectMeta: metav1.ObjectMeta{Name: "ws1-secret-admin", Labels: workspaceLabels}, Rules: []rbacv1.PolicyRule{ruleReadSecrets, ruleDeleteSecrets}},
+			{ObjectMeta: metav1.ObjectMeta{Name: "ws1-viewer", Labels: workspaceLabels}, Rules: []rbacv1.PolicyRule{ruleReadSecrets}},
+		},
+		workspaceRoleBindings: []*iamv1beta1.WorkspaceRoleBinding{
+			{
+				ObjectMeta: metav1.ObjectMeta{Name: "ops-ws1-secret-admin", Labels: workspaceLabels},
+				RoleRef:    rbacv1.RoleRef{APIGroup: iamv1beta1.GroupName, Kind: iamv1beta1.ResourceKindWorkspaceRole, Name: "ws1-secret-admin"},
+				Subjects:   []rbacv1.Subject{{Kind: rbacv1.GroupKind, APIGroup: iamv1beta1.GroupName, Name: "ops"}},
+			},
+			{
+				ObjectMeta: metav1.ObjectMeta{Name: "alice-ws1-viewer", Labels: workspaceLabels},
+				RoleRef:    rbacv1.RoleRef{APIGroup: iamv1beta1.GroupName, Kind: iamv1beta1.ResourceKindWorkspaceRole, Name: "ws1-viewer"},
+				Subjects: []rbacv1.Subject{
+					{Kind: rbacv1.UserKind, APIGroup: iamv1beta1.GroupName, Name: "alice"},
+					{Kind: rbacv1.ServiceAccountKind, APIGroup: rbacv1.GroupName, Name: "robot", Namespace: "ns1"},
+					{Kind: rbacv1.ServiceAccountKind, Name: "invalid", Namespace: "ns1"},
+					{Kind: rbacv1.UserKind, Name: "alice"},
+				},
+			},
+		},
+	})
+	assert.NoError(t, err)
+
+	// who can delete secrets in the workspace
+	records, err := r.SubjectsAllowed(authorizer.AttributesRecord{
+		Verb:            "delete",
+		Resource:        "secrets",
+		Workspace:       "ws1",
+		ResourceScope:   request.WorkspaceScope,
+		ResourceRequest: true,
+	})
+	assert.NoError(t, err)
+	assert.Len(t, records, 2)
+	assert.Equal(t, rbacv1.Subject{Kind: rbacv1.GroupKind, Name: "ops"}, records[0].Subject)
+	assert.Equal(t, iamv1beta1.ResourceKindWorkspaceRoleBinding, records[0].BindingKind)
+	assert.Equal(t, "ops-ws1-secret-admin", records[0].BindingName)
+	assert.Equal(t, ruleDeleteSecrets, *records[0].Rule)
+	assert.Equal(t, rbacv1.Subject{Kind: rbacv1.UserKind, Name: "admin"}, records[1].Subject)
+	assert.Equal(t, iamv1beta1.ResourceKindGlobalRoleBinding, records[1].BindingKind)
+	assert.Equal(t, "platform-admin", records[1].RoleRef.Name)
+
+	// who can list secrets in the workspace
+	records, err = r.SubjectsAllowed(authorizer.AttributesRecord{
+		Verb:            "list",
+		Resource:        "secrets",
+		Workspace:       "ws1",
+		ResourceScope:   request.WorkspaceScope,
+		ResourceRequest: true,
+	})
+	assert.NoError(t, err)
+	var subjects []string
+	for _, record := range records {
+		subjects = append(subjects, record.Subject.Kind+"/"+record.Subject.Name)
+	}
+	assert.Equal(t, []string{"Group/ops", "ServiceAccount/robot", "User/admin", "User/alice"}, subjects)
+
+	// the workspace role bindings are out of the global scope
+	records, err = r.SubjectsAllowed(authorizer.AttributesRecord{
+		Verb:            "list",
+		Resource:        "secrets",
+		ResourceScope:   request.GlobalScope,
+		ResourceRequest: true,
+	})
+	assert.NoError(t, err)
+	assert.Len(t, records, 1)
+
+	// everything the group can do in the workspace
+	records, err = r.SubjectRules(rbacv1.Subject{Kind: rbacv1.GroupKind, Name: "ops"}, authorizer.AttributesRecord{
+		Workspace:     "ws1",
+		ResourceScope: request.WorkspaceScope,
+	})
+	assert.NoError(t, err)
+	assert.Len(t, records, 2)
+	assert.Equal(t, ruleReadSecrets, *records[0].Rule)
+	assert.Equal(t, ruleDeleteSecrets, *records[1].Rule)
+
+	// the user is expanded into the groups
+	records, err = r.SubjectRules(rbacv1.Subject{Kind: rbacv1.UserKind, Name: "bob"}, authorizer.AttributesRecord{
+		Workspace:     "ws1",
+		ResourceScope: request.WorkspaceScope,
+	})
+	assert.NoError(t, err)
+	assert.Len(t, records, 2)
+	assert.Equal(t, rbacv1.Subject{Kind: rbacv1.UserKind, Name: "bob"}, records[0].Subject)
+	assert.Equal(t, "ops", records[0].Group)
+	assert.Equal(t, "ops-ws1-secret-admin", records[0].BindingName)
+
+	records, err = r.SubjectRules(rbacv1.Subject{Kind: rbacv1.UserKind, Name: "alice"}, authorizer.AttributesRecord{
+		Workspace:     "ws1",
+		ResourceScope: request.WorkspaceScope,
+	})
+	assert.NoError(t, err)
+	assert.Len(t, records, 1)
+	assert.Empty(t, records[0].Group)
+
+	_, err = r.SubjectRules(rbacv1.Subject{Kind: rbacv1.ServiceAccountKind, Name: "robot"}, authorizer.AttributesRecord{})
+	assert.Error(t, err)
+}
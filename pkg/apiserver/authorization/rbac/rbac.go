@@ -22,9 +22,11 @@ import (
 	"bytes"
 	"context"
 	"fmt"
+	"time"
 
 	"github.com/open-policy-agent/opa/rego"
 	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	utilerrors "k8s.io/apimachinery/pkg/util/errors"
 	"k8s.io/apiserver/pkg/authentication/serviceaccount"
 	"k8s.io/apiserver/pkg/authentication/user"
@@ -202,45 +204,90 @@ func (r *Authorizer) rulesFor(requestAttributes authorizer.Attributes) ([]rbacv1
 }
 
 func (r *Authorizer) visitRulesFor(requestAttributes authorizer.Attributes, visitor func(source fmt.Stringer, regoPolicy string, rule *rbacv1.PolicyRule, err error) bool) {
-	if globalRoleBindings, err := r.am.ListGlobalRoleBindings("", ""); err != nil {
-		visitor(nil, "", nil, err)
-		return
-	} else {
-		sourceDescriber := &globalRoleBindingDescriber{}
-		for _, globalRoleBinding := range globalRoleBindings {
-			subjectIndex, applies := appliesTo(requestAttributes.GetUser(), globalRoleBinding.Subjects, "")
-			if !applies {
-				continue
-			}
-			regoPolicy, rules, err := r.am.GetRoleReferenceRules(globalRoleBinding.RoleRef, "")
-			if err != nil {
-				visitor(nil, "", nil, err)
-				continue
-			}
-			sourceDescriber.binding = &globalRoleBinding
-			sourceDescriber.subject = &globalRoleBinding.Subjects[subjectIndex]
-			if !visitor(sourceDescriber, regoPolicy, nil, nil) {
-				return
-			}
-			for i := range rules {
-				if !visitor(sourceDescriber, "", &rules[i], nil) {
-					return
-				}
+	err := r.visitBindingsFor(requestAttributes, func(binding *scopedBinding) bool {
+		subjectIndex, applies := appliesTo(requestAttributes.GetUser(), binding.subjects, binding.namespace)
+		if !applies {
+			return true
+		}
+		regoPolicy, rules, err := r.am.GetRoleReferenceRules(binding.roleRef, binding.namespace)
+		if err != nil {
+			visitor(nil, "", nil, err)
+			return binding.skipOnRoleError
+		}
+		sourceDescriber := binding.describe(&binding.subjects[subjectIndex])
+		if !visitor(sourceDescriber, regoPolicy, nil, nil) {
+			return false
+		}
+		for i := range rules {
+			if !visitor(sourceDescriber, "", &rules[i], nil) {
+				return false
 			}
 		}
+		return true
+	})
+	if err != nil {
+		visitor(nil, "", nil, err)
+	}
+}
+
+// scopedBinding is a binding in the scope of the request, the global role bindings, the workspace role bindings,
+// the role bindings and the cluster role bindings are visited in the same way.
+type scopedBinding struct {
+	// describe describes the binding to the subject
+	describe  func(subject *rbacv1.Subject) fmt.Stringer
+	subjects  []rbacv1.Subject
+	roleRef   rbacv1.RoleRef
+	namespace string
+	// skipOnRoleError tells whether the traversal goes on with the next binding or stops
+	// if the role referenced by the binding can't be resolved
+	skipOnRoleError bool
+}
+
+// expired returns whether the expiration time of the binding has passed. The expired bindings are deleted by
+// the controller, and they're skipped until then. The invalid expiration time is ignored as the controller does.
+func expired(binding metav1.Object, now time.Time) bool {
+	value := binding.GetAnnotations()[iamv1beta1.ExpirationTimeAnnotation]
+	if value == "" {
+		return false
+	}
+	expiration, err := time.Parse(time.RFC3339, value)
+	return err == nil && !now.Before(expiration)
+}
 
-		if requestAttributes.GetResourceScope() == request.GlobalScope {
-			return
+// visitBindingsFor visits the bindings in the scope of the request regardless of the user, until the visitor returns false.
+// The error is returned if the bindings can't be listed, and the remaining bindings are not visited. The expired
+// bindings are skipped.
+func (r *Authorizer) visitBindingsFor(requestAttributes authorizer.Attributes, visitor func(binding *scopedBinding) bool) error {
+	now := time.Now()
+	globalRoleBindings, err := r.am.ListGlobalRoleBindings("", "")
+	if err != nil {
+		return err
+	}
+	for i := range globalRoleBindings {
+		binding := &globalRoleBindings[i]
+		if expired(binding, now) {
+			continue
+		}
+		if !visitor(&scopedBinding{
+			describe: func(subject *rbacv1.Subject) fmt.Stringer {
+				return &globalRoleBindingDescriber{binding: binding, subject: subject}
+			},
+			subjects:        binding.Subjects,
+			roleRef:         binding.RoleRef,
+			skipOnRoleError: true,
+		}) {
+			return nil
 		}
 	}
 
+	if requestAttributes.GetResourceScope() == request.GlobalScope {
+		return nil
+	}
+
 	var targetWorkspace string
 	if requestAttributes.GetResourceScope() == request.NamespaceScope {
-		if workspace, err := r.am.GetNamespaceControlledWorkspace(requestAttributes.GetNamespace()); err != nil {
-			visitor(nil, "", nil, err)
-			return
-		} else {
-			targetWorkspace = workspace
+		if targetWorkspace, err = r.am.GetNamespaceControlledWorkspace(requestAttributes.GetNamespace()); err != nil {
+			return err
 		}
 	}
 
@@ -250,31 +297,23 @@ func (r *Authorizer) visitRulesFor(requestAttributes authorizer.Attributes, visi
 
 	// workspace managed resources
 	if targetWorkspace != "" {
-		if workspaceRoleBindings, err := r.am.ListWorkspaceRoleBindings("", "", nil, targetWorkspace); err != nil {
-			visitor(nil, "", nil, err)
-			return
-		} else {
-			sourceDescriber := &workspaceRoleBindingDescriber{}
-			for _, workspaceRoleBinding := range workspaceRoleBindings {
-				subjectIndex, applies := appliesTo(requestAttributes.GetUser(), workspaceRoleBinding.Subjects, "")
-				if !applies {
-					continue
-				}
-				regoPolicy, rules, err := r.am.GetRoleReferenceRules(workspaceRoleBinding.RoleRef, "")
-				if err != nil {
-					visitor(nil, "", nil, err)
-					return
-				}
-				sourceDescriber.binding = &workspaceRoleBinding
-				sourceDescriber.subject = &workspaceRoleBinding.Subjects[subjectIndex]
-				if !visitor(sourceDescriber, regoPolicy, nil, nil) {
-					return
-				}
-				for i := range rules {
-					if !visitor(sourceDescriber, "", &rules[i], nil) {
-						return
-					}
-				}
+		workspaceRoleBindings, err := r.am.ListWorkspaceRoleBindings("", "", nil, targetWorkspace)
+		if err != nil {
+			return err
+		}
+		for i := range workspaceRoleBindings {
+			binding := &workspaceRoleBindings[i]
+			if expired(binding, now) {
+				continue
+			}
+			if !visitor(&scopedBinding{
+				describe: func(subject *rbacv1.Subject) fmt.Stringer {
+					return &workspaceRoleBindingDescriber{binding: binding, subject: subject}
+				},
+				subjects: binding.Subjects,
+				roleRef:  binding.RoleRef,
+			}) {
+				return nil
 			}
 		}
 	}
@@ -285,62 +324,48 @@ func (r *Authorizer) visitRulesFor(requestAttributes authorizer.Attributes, visi
 	}
 
 	if targetNamespace != "" {
-		if roleBindings, err := r.am.ListRoleBindings("", "", nil, targetNamespace); err != nil {
-			visitor(nil, "", nil, err)
-			return
-		} else {
-			sourceDescriber := &roleBindingDescriber{}
-			for _, roleBinding := range roleBindings {
-				subjectIndex, applies := appliesTo(requestAttributes.GetUser(), roleBinding.Subjects, targetNamespace)
-				if !applies {
-					continue
-				}
-				regoPolicy, rules, err := r.am.GetRoleReferenceRules(roleBinding.RoleRef, targetNamespace)
-				if err != nil {
-					visitor(nil, "", nil, err)
-					return
-				}
-				sourceDescriber.binding = &roleBinding
-				sourceDescriber.subject = &roleBinding.Subjects[subjectIndex]
-				if !visitor(sourceDescriber, regoPolicy, nil, nil) {
-					return
-				}
-				for i := range rules {
-					if !visitor(sourceDescriber, "", &rules[i], nil) {
-						return
-					}
-				}
-			}
+		roleBindings, err := r.am.ListRoleBindings("", "", nil, targetNamespace)
+		if err != nil {
+			return err
 		}
-	}
-
-	if clusterRoleBindings, err := r.am.ListClusterRoleBindings("", ""); err != nil {
-		visitor(nil, "", nil, err)
-		return
-	} else {
-		sourceDescriber := &clusterRoleBindingDescriber{}
-		for _, clusterRoleBinding := range clusterRoleBindings {
-			subjectIndex, applies := appliesTo(requestAttributes.GetUser(), clusterRoleBinding.Subjects, "")
-			if !applies {
+		for i := range roleBindings {
+			binding := &roleBindings[i]
+			if expired(binding, now) {
 				continue
 			}
-			regoPolicy, rules, err := r.am.GetRoleReferenceRules(clusterRoleBinding.RoleRef, "")
-			if err != nil {
-				visitor(nil, "", nil, err)
-				return
-			}
-			sourceDescriber.binding = &clusterRoleBinding
-			sourceDescriber.subject = &clusterRoleBinding.Subjects[subjectIndex]
-			if !visitor(sourceDescriber, regoPolicy, nil, nil) {
-				return
-			}
-			for i := range rules {
-				if !visitor(sourceDescriber, "", &rules[i], nil) {
-					return
-				}
+			if !visitor(&scopedBinding{
+				describe: func(subject *rbacv1.Subject) fmt.Stringer {
+					return &roleBindingDescriber{binding: binding, subject: subject}
+				},
+				subjects:  binding.Subjects,
+				roleRef:   binding.RoleRef,
+				namespace: targetNamespace,
+			}) {
+				return nil
 			}
 		}
 	}
+
+	clusterRoleBindings, err := r.am.ListClusterRoleBindings("", "")
+	if err != nil {
+		return err
+	}
+	for i := range clusterRoleBindings {
+		binding := &clusterRoleBindings[i]
+		if expired(binding, now) {
+			continue
+		}
+		if !visitor(&scopedBinding{
+			describe: func(subject *rbacv1.Subject) fmt.Stringer {
+				return &clusterRoleBindingDescriber{binding: binding, subject: subject}
+			},
+			subjects: binding.Subjects,
+			roleRef:  binding.RoleRef,
+		}) {
+			return nil
+		}
+	}
+	return nil
 }
 
 // appliesTo returns whether any of the bindingSubjects applies to the specified subject,
@@ -422,7 +447,7 @@ type workspaceRoleBindingDescriber struct {
 }
 
 func (d *workspaceRoleBindingDescriber) String() string {
-	return fmt.Sprintf("GlobalRoleBinding %q of %s %q to %s",
+	return fmt.Sprintf("WorkspaceRoleBinding %q of %s %q to %s",
 		d.binding.Name,
 		d.binding.RoleRef.Kind,
 		d.binding.RoleRef.Name,
@@ -23,6 +23,7 @@ import (
 	"io"
 	"sort"
 	"testing"
+	"time"
 
 	"github.com/google/go-cmp/cmp"
 	corev1 "k8s.io/api/core/v1"
@@ -52,6 +53,7 @@ type StaticRoles struct {
 	globalRoles           []*iamv1beta1.GlobalRole
 	globalRoleBindings    []*iamv1beta1.GlobalRoleBinding
 	namespaces            []*corev1.Namespace
+	users                 []*iamv1beta1.User
 }
 
 func (r *StaticRoles) GetRole(namespace, name string) (*iamv1beta1.Role, error) {
@@ -866,6 +868,62 @@ func TestRBACAuthorizerMakeDecision(t *testing.T) {
 	}
 }
 
+func TestExpiredBindings(t *testing.T) {
+	ruleReadPods := rbacv1.PolicyRule{
+		Verbs:     []string{"get"},
+		APIGroups: []string{""},
+		Resources: []string{"pods"},
+	}
+	binding := func(name, expiration string) *iamv1beta1.RoleBinding {
+		roleBinding := &iamv1beta1.RoleBinding{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "namespace1", Name: name},
+			Subjects:   []rbacv1.Subject{{Kind: rbacv1.UserKind, Name: name}},
+			RoleRef:    rbacv1.RoleRef{APIGroup: rbacv1.GroupName, Kind: "Role", Name: "read-pods"},
+		}
+		if expiration != "" {
+			roleBinding.Annotations = map[string]string{iamv1beta1.ExpirationTimeAnnotation: expiration}
+		}
+		return roleBinding
+	}
+	staticRoles := &StaticRoles{
+		roles: []*iamv1beta1.Role{{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "namespace1", Name: "read-pods"},
+			Rules:      []rbacv1.PolicyRule{ruleReadPods},
+		}},
+		roleBindings: []*iamv1beta1.RoleBinding{
+			binding("expired", time.Now().Add(-time.Minute).UTC().Format(time.RFC3339)),
+			binding("expiring", time.Now().Add(time.Hour).UTC().Format(time.RFC3339)),
+			binding("invalid", "tomorrow"),
+		},
+	}
+	authz, err := newMockRBACAuthorizer(staticRoles)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := map[string]authorizer.Decision{
+		"expired":  authorizer.DecisionNoOpinion,
+		"expiring": authorizer.DecisionAllow,
+		"invalid":  authorizer.DecisionAllow,
+	}
+	for name, expected := range tests {
+		decision, _, err := authz.Authorize(authorizer.AttributesRecord{
+			User:            &user.DefaultInfo{Name: name},
+			Verb:            "get",
+			Namespace:       "namespace1",
+			Resource:        "pods",
+			ResourceScope:   request.NamespaceScope,
+			ResourceRequest: true,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if decision != expected {
+			t.Errorf("%s: %d != %d", name, decision, expected)
+		}
+	}
+}
+
 func newMockRBACAuthorizer(staticRoles *StaticRoles) (*Authorizer, error) {
 	client := runtimefakeclient.NewClientBuilder().
 		WithScheme(scheme.Scheme).Build()
@@ -918,6 +976,12 @@ func newMockRBACAuthorizer(staticRoles *StaticRoles) (*Authorizer, error) {
 		}
 	}
 
+	for _, user := range staticRoles.users {
+		if err := client.Create(context.Background(), user.DeepCopy()); err != nil {
+			return nil, err
+		}
+	}
+
 	fakeCache := &informertest.FakeInformers{Scheme: scheme.Scheme}
 
 	resourceManager, err := v1beta1.New(context.Background(), client, fakeCache)
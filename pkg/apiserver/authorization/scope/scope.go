@@ -0,0 +1,124 @@
+/*
+ * Copyright 2024 the KubeSphere Authors.
+ * Please refer to the LICENSE file in the root directory of the project.
+ * https://github.com/kubesphere/kubesphere/blob/master/LICENSE
+ */
+
+// Package scope contains an authorizer that restricts the requests of the scoped tokens,
+// the scopes can only narrow the permissions granted by the roles.
+package scope
+
+import (
+	"fmt"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/util/sets"
+	corev1alpha1 "kubesphere.io/api/core/v1alpha1"
+
+	"kubesphere.io/kubesphere/pkg/apiserver/authorization/authorizer"
+)
+
+const wildcard = "*"
+
+// Scope allows the verbs on the resources, the format is <verbs>:<resources>, e.g. "get,list:pods,deployments.apps".
+// A resource is in the format of <resource>[.<group>][/<subresource>], "*" matches all resources, "*.apps" matches
+// all resources in the group and "pods/*" matches all subresources of the pods. The non-resource URLs start with /,
+// and a trailing * means a prefix match.
+type Scope struct {
+	verbs     sets.Set[string]
+	resources []resource
+	paths     []string
+}
+
+type resource struct {
+	group       string
+	resource    string
+	subresource string
+}
+
+// Parse parses the scope.
+func Parse(scope string) (*Scope, error) {
+	verbs, resources, ok := strings.Cut(scope, ":")
+	if !ok || verbs == "" || resources == "" {
+		return nil, fmt.Errorf("invalid scope %q, the format is <verbs>:<resources>", scope)
+	}
+	result := &Scope{verbs: sets.New(strings.Split(verbs, ",")...)}
+	for _, item := range strings.Split(resources, ",") {
+		if strings.HasPrefix(item, "/") {
+			if strings.ContainsRune(strings.TrimSuffix(item, wildcard), '*') {
+				return nil, fmt.Errorf("invalid scope %q, only trailing * allowed in %q", scope, item)
+			}
+			result.paths = append(result.paths, item)
+			continue
+		}
+		name, subresource, _ := strings.Cut(item, "/")
+		name, group, _ := strings.Cut(name, ".")
+		if name == "" {
+			return nil, fmt.Errorf("invalid scope %q, empty resource", scope)
+		}
+		result.resources = append(result.resources, resource{group: group, resource: name, subresource: subresource})
+	}
+	return result, nil
+}
+
+// ParseScopes parses the space separated scopes.
+func ParseScopes(value string) ([]string, error) {
+	scopes := strings.Fields(value)
+	for _, scope := range scopes {
+		if _, err := Parse(scope); err != nil {
+			return nil, err
+		}
+	}
+	return scopes, nil
+}
+
+// Allows returns true if the request is allowed by the scope.
+func (s *Scope) Allows(a authorizer.Attributes) bool {
+	if !s.verbs.Has(wildcard) && !s.verbs.Has(a.GetVerb()) {
+		return false
+	}
+	if !a.IsResourceRequest() {
+		for _, path := range s.paths {
+			if path == a.GetPath() || (strings.HasSuffix(path, wildcard) && strings.HasPrefix(a.GetPath(), strings.TrimSuffix(path, wildcard))) {
+				return true
+			}
+		}
+		return false
+	}
+	for _, r := range s.resources {
+		if r.resource == wildcard && r.group == "" {
+			return true
+		}
+		if r.group != a.GetAPIGroup() || (r.resource != wildcard && r.resource != a.GetResource()) {
+			continue
+		}
+		if r.subresource == a.GetSubresource() || (r.subresource == wildcard && a.GetSubresource() != "") {
+			return true
+		}
+	}
+	return false
+}
+
+// NewAuthorizer returns an authorizer which denies the requests of the scoped tokens out of the scopes,
+// it has no opinion on the other requests.
+func NewAuthorizer() authorizer.Authorizer {
+	return authorizer.AuthorizerFunc(func(a authorizer.Attributes) (authorizer.Decision, string, error) {
+		if a.GetUser() == nil {
+			return authorizer.DecisionNoOpinion, "", nil
+		}
+		scopes := a.GetUser().GetExtra()[corev1alpha1.ServiceAccountTokenExtraScopes]
+		if len(scopes) == 0 {
+			return authorizer.DecisionNoOpinion, "", nil
+		}
+		for _, value := range scopes {
+			scope, err := Parse(value)
+			if err != nil {
+				return authorizer.DecisionDeny, "", err
+			}
+			if scope.Allows(a) {
+				return authorizer.DecisionNoOpinion, "", nil
+			}
+		}
+		return authorizer.DecisionDeny, "the request is out of the scopes of the token", nil
+	})
+}
@@ -0,0 +1,69 @@
+/*
+ * Copyright 2024 the KubeSphere Authors.
+ * Please refer to the LICENSE file in the root directory of the project.
+ * https://github.com/kubesphere/kubesphere/blob/master/LICENSE
+ */
+
+package scope
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apiserver/pkg/authentication/user"
+	corev1alpha1 "kubesphere.io/api/core/v1alpha1"
+
+	"kubesphere.io/kubesphere/pkg/apiserver/authorization/authorizer"
+)
+
+func TestParseScopes(t *testing.T) {
+	scopes, err := ParseScopes(" get,list:pods,deployments.apps  create:pods/exec ")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"get,list:pods,deployments.apps", "create:pods/exec"}, scopes)
+
+	for _, value := range []string{"get", "get:", ":pods", "get:/foo*bar", "get:.apps"} {
+		_, err = ParseScopes(value)
+		assert.Error(t, err, value)
+	}
+}
+
+func TestAuthorizer(t *testing.T) {
+	scoped := &user.DefaultInfo{
+		Name: "kubesphere:serviceaccount:default:robot",
+		Extra: map[string][]string{corev1alpha1.ServiceAccountTokenExtraScopes: {
+			"get,list,watch:pods,deployments.apps,*.iam.kubesphere.io",
+			"create:pods/exec,statefulsets.apps/*",
+			"get:/kapis/version,/kapis/config.kubesphere.io/*",
+		}},
+	}
+	tests := []struct {
+		name  string
+		attrs authorizer.AttributesRecord
+		want  authorizer.Decision
+	}{
+		{"unscoped", authorizer.AttributesRecord{User: &user.DefaultInfo{Name: "admin"}, Verb: "delete", Resource: "pods", ResourceRequest: true}, authorizer.DecisionNoOpinion},
+		{"get pods", authorizer.AttributesRecord{Verb: "get", Resource: "pods", ResourceRequest: true}, authorizer.DecisionNoOpinion},
+		{"delete pods", authorizer.AttributesRecord{Verb: "delete", Resource: "pods", ResourceRequest: true}, authorizer.DecisionDeny},
+		{"get pod logs", authorizer.AttributesRecord{Verb: "get", Resource: "pods", Subresource: "log", ResourceRequest: true}, authorizer.DecisionDeny},
+		{"exec pods", authorizer.AttributesRecord{Verb: "create", Resource: "pods", Subresource: "exec", ResourceRequest: true}, authorizer.DecisionNoOpinion},
+		{"list deployments", authorizer.AttributesRecord{Verb: "list", APIGroup: "apps", Resource: "deployments", ResourceRequest: true}, authorizer.DecisionNoOpinion},
+		{"list deployments in another group", authorizer.AttributesRecord{Verb: "list", APIGroup: "extensions", Resource: "deployments", ResourceRequest: true}, authorizer.DecisionDeny},
+		{"scale statefulsets", authorizer.AttributesRecord{Verb: "create", APIGroup: "apps", Resource: "statefulsets", Subresource: "scale", ResourceRequest: true}, authorizer.DecisionNoOpinion},
+		{"create statefulsets", authorizer.AttributesRecord{Verb: "create", APIGroup: "apps", Resource: "statefulsets", ResourceRequest: true}, authorizer.DecisionDeny},
+		{"list users", authorizer.AttributesRecord{Verb: "list", APIGroup: "iam.kubesphere.io", Resource: "users", ResourceRequest: true}, authorizer.DecisionNoOpinion},
+		{"get version", authorizer.AttributesRecord{Verb: "get", Path: "/kapis/version"}, authorizer.DecisionNoOpinion},
+		{"get configs", authorizer.AttributesRecord{Verb: "get", Path: "/kapis/config.kubesphere.io/v1alpha2/configs/oauth"}, authorizer.DecisionNoOpinion},
+		{"get metrics", authorizer.AttributesRecord{Verb: "get", Path: "/metrics"}, authorizer.DecisionDeny},
+	}
+	a := NewAuthorizer()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.attrs.User == nil {
+				tt.attrs.User = scoped
+			}
+			decision, _, err := a.Authorize(tt.attrs)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, decision)
+		})
+	}
+}
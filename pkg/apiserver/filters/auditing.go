@@ -46,6 +46,7 @@ func (a *auditingFilter) ServeHTTP(w http.ResponseWriter, req *http.Request) {
 
 	if event := a.LogRequestObject(req, info); event != nil {
 		resp := auditing.NewResponseCapture(w)
+		req = req.WithContext(request.WithAuditID(req.Context(), event.AuditID))
 		a.next.ServeHTTP(responsewriter.WrapForHTTP1Or2(resp), req)
 		go a.LogResponseObject(event, resp)
 	} else {
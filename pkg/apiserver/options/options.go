@@ -14,10 +14,12 @@ import (
 	"kubesphere.io/kubesphere/pkg/apiserver/auditing"
 	"kubesphere.io/kubesphere/pkg/apiserver/authentication"
 	"kubesphere.io/kubesphere/pkg/apiserver/authorization"
+	"kubesphere.io/kubesphere/pkg/models/extension"
 	"kubesphere.io/kubesphere/pkg/models/terminal"
 	"kubesphere.io/kubesphere/pkg/multicluster"
 	"kubesphere.io/kubesphere/pkg/simple/client/cache"
 	"kubesphere.io/kubesphere/pkg/simple/client/k8s"
+	"kubesphere.io/kubesphere/pkg/simple/client/scanner"
 )
 
 type Options struct {
@@ -29,5 +31,7 @@ type Options struct {
 	AuditingOptions       *auditing.Options           `json:"-"`
 	TerminalOptions       *terminal.Options           `json:"-"`
 	S3Options             *s3.Options                 `json:"-"`
+	AppScanOptions        *scanner.Options            `json:"-"`
 	ExperimentalOptions   *config.ExperimentalOptions `json:"-"`
+	ExtensionOptions      *extension.Options          `json:"-"`
 }
@@ -19,6 +19,7 @@ package request
 import (
 	"context"
 
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apiserver/pkg/authentication/user"
 )
 
@@ -30,6 +31,9 @@ type key int
 const (
 	// userKey is the context key for the request user.
 	userKey key = iota
+
+	// auditIDKey is the context key for the audit ID of the request.
+	auditIDKey
 )
 
 // WithValue returns a copy of parent in which the value associated with key is val.
@@ -47,3 +51,14 @@ func UserFrom(ctx context.Context) (user.Info, bool) {
 	user, ok := ctx.Value(userKey).(user.Info)
 	return user, ok
 }
+
+// WithAuditID returns a copy of parent in which the audit ID value is set
+func WithAuditID(parent context.Context, auditID types.UID) context.Context {
+	return WithValue(parent, auditIDKey, auditID)
+}
+
+// AuditIDFrom returns the value of the audit ID key on the ctx
+func AuditIDFrom(ctx context.Context) (types.UID, bool) {
+	auditID, ok := ctx.Value(auditIDKey).(types.UID)
+	return auditID, ok
+}
@@ -22,11 +22,13 @@ import (
 	"kubesphere.io/kubesphere/pkg/constants"
 	"kubesphere.io/kubesphere/pkg/controller/options"
 	"kubesphere.io/kubesphere/pkg/models/composedapp"
+	"kubesphere.io/kubesphere/pkg/models/extension"
 	"kubesphere.io/kubesphere/pkg/models/kubeconfig"
 	"kubesphere.io/kubesphere/pkg/models/terminal"
 	"kubesphere.io/kubesphere/pkg/multicluster"
 	"kubesphere.io/kubesphere/pkg/simple/client/cache"
 	"kubesphere.io/kubesphere/pkg/simple/client/k8s"
+	"kubesphere.io/kubesphere/pkg/simple/client/scanner"
 )
 
 // Package config saves configuration for running KubeSphere components
@@ -116,11 +118,12 @@ type Config struct {
 	KubeconfigOptions     *kubeconfig.Options          `json:"kubeconfig,omitempty" yaml:"kubeconfig,omitempty" mapstructure:"kubeconfig"`
 	TerminalOptions       *terminal.Options            `json:"terminal,omitempty" yaml:"terminal,omitempty" mapstructure:"terminal"`
 	HelmExecutorOptions   *options.HelmExecutorOptions `json:"helmExecutor,omitempty" yaml:"helmExecutor,omitempty" mapstructure:"helmExecutor"`
-	ExtensionOptions      *options.ExtensionOptions    `json:"extension,omitempty" yaml:"extension,omitempty" mapstructure:"extension"`
+	ExtensionOptions      *extension.Options           `json:"extension,omitempty" yaml:"extension,omitempty" mapstructure:"extension"`
 	S3Options             *s3.Options                  `json:"s3,omitempty" yaml:"s3,omitempty" mapstructure:"s3"`
 	KubeSphereOptions     *options.KubeSphereOptions   `json:"kubesphere,omitempty" yaml:"kubesphere,omitempty" mapstructure:"kubesphere"`
 	ComposedAppOptions    *composedapp.Options         `json:"composedApp,omitempty" yaml:"composedApp,omitempty" mapstructure:"composedApp"`
 	ExperimentalOptions   *ExperimentalOptions         `json:"experimental,omitempty" yaml:"experimental,omitempty" mapstructure:"experimental"`
+	AppScanOptions        *scanner.Options             `json:"appScan,omitempty" yaml:"appScan,omitempty" mapstructure:"appScan"`
 }
 
 // New config creates a default non-empty Config
@@ -135,11 +138,12 @@ func New() *Config {
 		KubeconfigOptions:     kubeconfig.NewOptions(),
 		AuditingOptions:       auditing.NewAuditingOptions(),
 		HelmExecutorOptions:   options.NewHelmExecutorOptions(),
-		ExtensionOptions:      options.NewExtensionOptions(),
+		ExtensionOptions:      extension.NewOptions(),
 		S3Options:             s3.NewS3Options(),
 		KubeSphereOptions:     options.NewKubeSphereOptions(),
 		ComposedAppOptions:    composedapp.NewOptions(),
 		ExperimentalOptions:   NewExperimentalOptions(),
+		AppScanOptions:        scanner.NewOptions(),
 	}
 }
 
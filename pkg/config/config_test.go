@@ -20,11 +20,13 @@ import (
 	"kubesphere.io/kubesphere/pkg/apiserver/authorization"
 	"kubesphere.io/kubesphere/pkg/controller/options"
 	"kubesphere.io/kubesphere/pkg/models/composedapp"
+	"kubesphere.io/kubesphere/pkg/models/extension"
 	"kubesphere.io/kubesphere/pkg/models/kubeconfig"
 	"kubesphere.io/kubesphere/pkg/models/terminal"
 	"kubesphere.io/kubesphere/pkg/multicluster"
 	"kubesphere.io/kubesphere/pkg/simple/client/cache"
 	"kubesphere.io/kubesphere/pkg/simple/client/k8s"
+	"kubesphere.io/kubesphere/pkg/simple/client/scanner"
 )
 
 func newTestConfig() (*Config, error) {
@@ -38,11 +40,12 @@ func newTestConfig() (*Config, error) {
 		KubeconfigOptions:     kubeconfig.NewOptions(),
 		TerminalOptions:       terminal.NewOptions(),
 		HelmExecutorOptions:   options.NewHelmExecutorOptions(),
-		ExtensionOptions:      options.NewExtensionOptions(),
+		ExtensionOptions:      extension.NewOptions(),
 		S3Options:             s3.NewS3Options(),
 		KubeSphereOptions:     options.NewKubeSphereOptions(),
 		ComposedAppOptions:    &composedapp.Options{},
 		ExperimentalOptions:   NewExperimentalOptions(),
+		AppScanOptions:        scanner.NewOptions(),
 	}
 	return conf, nil
 }
@@ -0,0 +1,207 @@
+/*
+ * Copyright 2024 the KubeSphere Authors.
+ * Please refer to the LICENSE file in the root directory of the project.
+ * https://github.com/kubesphere/kubesphere/blob/master/LICENSE
+ */
+
+package accessrequest
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+	iamv1beta1 "kubesphere.io/api/iam/v1beta1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	kscontroller "kubesphere.io/kubesphere/pkg/controller"
+)
+
+const (
+	controllerName = "accessrequest"
+
+	accessGranted = "AccessGranted"
+	accessRevoked = "AccessRevoked"
+)
+
+var _ kscontroller.Controller = &Reconciler{}
+var _ reconcile.Reconciler = &Reconciler{}
+
+// Reconciler grants the access of the approved access requests with temporary roles and role bindings,
+// and revokes the access when the requests expire.
+type Reconciler struct {
+	client.Client
+	logger   logr.Logger
+	recorder record.EventRecorder
+}
+
+func (r *Reconciler) Name() string {
+	return controllerName
+}
+
+func (r *Reconciler) SetupWithManager(mgr *kscontroller.Manager) error {
+	r.Client = mgr.GetClient()
+	r.logger = ctrl.Log.WithName("controllers").WithName(controllerName)
+	r.recorder = mgr.GetEventRecorderFor(controllerName)
+	return ctrl.NewControllerManagedBy(mgr).
+		Named(controllerName).
+		For(&iamv1beta1.AccessRequest{}).
+		Owns(&iamv1beta1.RoleBinding{}).
+		Owns(&iamv1beta1.ClusterRoleBinding{}).
+		Complete(r)
+}
+
+// +kubebuilder:rbac:groups=iam.kubesphere.io,resources=accessrequests,verbs=get;list;watch;update;patch
+// +kubebuilder:rbac:groups=iam.kubesphere.io,resources=accessrequests/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=iam.kubesphere.io,resources=roles;rolebindings;clusterroles;clusterrolebindings,verbs=get;list;watch;create;update;patch;delete
+
+func (r *Reconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	logger := r.logger.WithValues("accessrequest", req.Name)
+	ctx = logr.NewContext(ctx, logger)
+	accessRequest := &iamv1beta1.AccessRequest{}
+	if err := r.Get(ctx, req.NamespacedName, accessRequest); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+	if !accessRequest.DeletionTimestamp.IsZero() {
+		// the temporary roles and role bindings are deleted by the garbage collector
+		return ctrl.Result{}, nil
+	}
+
+	status := accessRequest.Status
+	switch status.Phase {
+	case "":
+		accessRequest.Status.Phase = iamv1beta1.AccessRequestPending
+		return ctrl.Result{}, r.Status().Update(ctx, accessRequest)
+	case iamv1beta1.AccessRequestApproved:
+		if status.ExpirationTime == nil {
+			start := metav1.Now()
+			if status.DecisionTime != nil {
+				start = *status.DecisionTime
+			}
+			accessRequest.Status.ExpirationTime = &metav1.Time{Time: start.Add(accessRequest.Spec.Duration.Duration)}
+			return ctrl.Result{}, r.Status().Update(ctx, accessRequest)
+		}
+		remaining := time.Until(status.ExpirationTime.Time)
+		if remaining > 0 {
+			if err := r.grant(ctx, accessRequest); err != nil {
+				return ctrl.Result{}, err
+			}
+			return ctrl.Result{RequeueAfter: remaining}, nil
+		}
+		if err := r.revoke(ctx, accessRequest); err != nil {
+			return ctrl.Result{}, err
+		}
+		accessRequest.Status.Phase = iamv1beta1.AccessRequestExpired
+		if err := r.Status().Update(ctx, accessRequest); err != nil {
+			return ctrl.Result{}, err
+		}
+		r.recorder.Event(accessRequest, corev1.EventTypeNormal, accessRevoked, "the access expired")
+	case iamv1beta1.AccessRequestDenied, iamv1beta1.AccessRequestExpired:
+		if err := r.revoke(ctx, accessRequest); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+	return ctrl.Result{}, nil
+}
+
+// accessRules returns the policy rules granted by the access request.
+func accessRules(spec iamv1beta1.AccessRequestSpec) []rbacv1.PolicyRule {
+	if spec.Type == iamv1beta1.AccessRequestTypeNodeShell {
+		return []rbacv1.PolicyRule{
+			{APIGroups: []string{""}, Resources: []string{"nodes"}, ResourceNames: []string{spec.Node}, Verbs: []string{"get"}},
+			{APIGroups: []string{""}, Resources: []string{"nodes/exec"}, ResourceNames: []string{spec.Node}, Verbs: []string{"create"}},
+		}
+	}
+	return []rbacv1.PolicyRule{
+		{APIGroups: []string{""}, Resources: []string{"pods"}, ResourceNames: []string{spec.Pod}, Verbs: []string{"get"}},
+		{APIGroups: []string{""}, Resources: []string{"pods/exec"}, ResourceNames: []string{spec.Pod}, Verbs: []string{"create"}},
+	}
+}
+
+func resourceName(accessRequest *iamv1beta1.AccessRequest) string {
+	return fmt.Sprintf("accessrequest-%s", accessRequest.Name)
+}
+
+// grant creates the temporary role and role binding, the access to the nodes is granted at the cluster scope.
+// The role bindings are not labeled with the user reference, so that they are not listed as the members.
+func (r *Reconciler) grant(ctx context.Context, accessRequest *iamv1beta1.AccessRequest) error {
+	name := resourceName(accessRequest)
+	labels := map[string]string{iamv1beta1.AccessRequestReferenceLabel: accessRequest.Name}
+	subjects := []rbacv1.Subject{{Kind: iamv1beta1.ResourceKindUser, APIGroup: iamv1beta1.SchemeGroupVersion.Group, Name: accessRequest.Spec.User}}
+	rules := accessRules(accessRequest.Spec)
+
+	if accessRequest.Spec.Type == iamv1beta1.AccessRequestTypeNodeShell {
+		clusterRole := &iamv1beta1.ClusterRole{ObjectMeta: metav1.ObjectMeta{Name: name}}
+		clusterRoleBinding := &iamv1beta1.ClusterRoleBinding{ObjectMeta: metav1.ObjectMeta{Name: name}}
+		if err := r.applyAccessResource(ctx, accessRequest, clusterRole, labels, func() { clusterRole.Rules = rules }); err != nil {
+			return err
+		}
+		return r.applyAccessResource(ctx, accessRequest, clusterRoleBinding, labels, func() {
+			clusterRoleBinding.Subjects = subjects
+			clusterRoleBinding.RoleRef = rbacv1.RoleRef{APIGroup: iamv1beta1.SchemeGroupVersion.Group, Kind: iamv1beta1.ResourceKindClusterRole, Name: name}
+		})
+	}
+
+	role := &iamv1beta1.Role{ObjectMeta: metav1.ObjectMeta{Namespace: accessRequest.Spec.Namespace, Name: name}}
+	roleBinding := &iamv1beta1.RoleBinding{ObjectMeta: metav1.ObjectMeta{Namespace: accessRequest.Spec.Namespace, Name: name}}
+	if err := r.applyAccessResource(ctx, accessRequest, role, labels, func() { role.Rules = rules }); err != nil {
+		return err
+	}
+	return r.applyAccessResource(ctx, accessRequest, roleBinding, labels, func() {
+		roleBinding.Subjects = subjects
+		roleBinding.RoleRef = rbacv1.RoleRef{APIGroup: iamv1beta1.SchemeGroupVersion.Group, Kind: iamv1beta1.ResourceKindRole, Name: name}
+	})
+}
+
+func (r *Reconciler) applyAccessResource(ctx context.Context, accessRequest *iamv1beta1.AccessRequest, obj client.Object, labels map[string]string, mutate func()) error {
+	op, err := controllerutil.CreateOrUpdate(ctx, r.Client, obj, func() error {
+		objLabels := obj.GetLabels()
+		if objLabels == nil {
+			objLabels = make(map[string]string)
+		}
+		for k, v := range labels {
+			objLabels[k] = v
+		}
+		obj.SetLabels(objLabels)
+		mutate()
+		return controllerutil.SetControllerReference(accessRequest, obj, r.Scheme())
+	})
+	if err != nil {
+		return fmt.Errorf("failed to apply %s: %s", obj.GetName(), err)
+	}
+	if op == controllerutil.OperationResultCreated {
+		r.recorder.Eventf(accessRequest, corev1.EventTypeNormal, accessGranted, "created %s", obj.GetName())
+	}
+	return nil
+}
+
+// revoke deletes the temporary role and role binding.
+func (r *Reconciler) revoke(ctx context.Context, accessRequest *iamv1beta1.AccessRequest) error {
+	name := resourceName(accessRequest)
+	var objects []client.Object
+	if accessRequest.Spec.Type == iamv1beta1.AccessRequestTypeNodeShell {
+		objects = []client.Object{
+			&iamv1beta1.ClusterRoleBinding{ObjectMeta: metav1.ObjectMeta{Name: name}},
+			&iamv1beta1.ClusterRole{ObjectMeta: metav1.ObjectMeta{Name: name}},
+		}
+	} else {
+		objects = []client.Object{
+			&iamv1beta1.RoleBinding{ObjectMeta: metav1.ObjectMeta{Namespace: accessRequest.Spec.Namespace, Name: name}},
+			&iamv1beta1.Role{ObjectMeta: metav1.ObjectMeta{Namespace: accessRequest.Spec.Namespace, Name: name}},
+		}
+	}
+	for _, obj := range objects {
+		if err := r.Delete(ctx, obj); client.IgnoreNotFound(err) != nil {
+			return fmt.Errorf("failed to delete %s: %s", name, err)
+		}
+	}
+	return nil
+}
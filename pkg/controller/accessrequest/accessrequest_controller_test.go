@@ -0,0 +1,116 @@
+/*
+ * Copyright 2024 the KubeSphere Authors.
+ * Please refer to the LICENSE file in the root directory of the project.
+ * https://github.com/kubesphere/kubesphere/blob/master/LICENSE
+ */
+
+package accessrequest
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/stretchr/testify/assert"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	iamv1beta1 "kubesphere.io/api/iam/v1beta1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"kubesphere.io/kubesphere/pkg/scheme"
+)
+
+func newReconciler(objects ...*iamv1beta1.AccessRequest) *Reconciler {
+	builder := fake.NewClientBuilder().WithScheme(scheme.Scheme).WithStatusSubresource(&iamv1beta1.AccessRequest{})
+	for _, obj := range objects {
+		builder = builder.WithObjects(obj)
+	}
+	return &Reconciler{Client: builder.Build(), logger: logr.Discard(), recorder: record.NewFakeRecorder(10)}
+}
+
+func reconcileAccessRequest(t *testing.T, r *Reconciler, name string) ctrl.Result {
+	result, err := r.Reconcile(context.Background(), ctrl.Request{NamespacedName: types.NamespacedName{Name: name}})
+	assert.NoError(t, err)
+	return result
+}
+
+func TestReconcilePodExec(t *testing.T) {
+	accessRequest := &iamv1beta1.AccessRequest{
+		ObjectMeta: metav1.ObjectMeta{Name: "alice-abcde"},
+		Spec: iamv1beta1.AccessRequestSpec{
+			User:      "alice",
+			Type:      iamv1beta1.AccessRequestTypePodExec,
+			Namespace: "default",
+			Pod:       "nginx",
+			Reason:    "debugging",
+			Duration:  metav1.Duration{Duration: time.Hour},
+		},
+	}
+	r := newReconciler(accessRequest)
+	ctx := context.Background()
+
+	reconcileAccessRequest(t, r, accessRequest.Name)
+	assert.NoError(t, r.Get(ctx, types.NamespacedName{Name: accessRequest.Name}, accessRequest))
+	assert.Equal(t, iamv1beta1.AccessRequestPending, accessRequest.Status.Phase)
+
+	accessRequest.Status.Phase = iamv1beta1.AccessRequestApproved
+	accessRequest.Status.ExpirationTime = &metav1.Time{Time: time.Now().Add(time.Hour)}
+	assert.NoError(t, r.Status().Update(ctx, accessRequest))
+	result := reconcileAccessRequest(t, r, accessRequest.Name)
+	assert.True(t, result.RequeueAfter > 0 && result.RequeueAfter <= time.Hour)
+
+	name := types.NamespacedName{Namespace: "default", Name: "accessrequest-alice-abcde"}
+	role := &iamv1beta1.Role{}
+	assert.NoError(t, r.Get(ctx, name, role))
+	assert.Equal(t, []string{"nginx"}, role.Rules[0].ResourceNames)
+	roleBinding := &iamv1beta1.RoleBinding{}
+	assert.NoError(t, r.Get(ctx, name, roleBinding))
+	assert.Equal(t, "alice", roleBinding.Subjects[0].Name)
+	assert.Empty(t, roleBinding.Labels[iamv1beta1.UserReferenceLabel])
+
+	accessRequest.Status.ExpirationTime = &metav1.Time{Time: time.Now().Add(-time.Second)}
+	assert.NoError(t, r.Status().Update(ctx, accessRequest))
+	reconcileAccessRequest(t, r, accessRequest.Name)
+	assert.NoError(t, r.Get(ctx, types.NamespacedName{Name: accessRequest.Name}, accessRequest))
+	assert.Equal(t, iamv1beta1.AccessRequestExpired, accessRequest.Status.Phase)
+	assert.True(t, apierrors.IsNotFound(r.Get(ctx, name, role)))
+	assert.True(t, apierrors.IsNotFound(r.Get(ctx, name, roleBinding)))
+}
+
+func TestReconcileNodeShell(t *testing.T) {
+	accessRequest := &iamv1beta1.AccessRequest{
+		ObjectMeta: metav1.ObjectMeta{Name: "bob-abcde"},
+		Spec: iamv1beta1.AccessRequestSpec{
+			User:     "bob",
+			Type:     iamv1beta1.AccessRequestTypeNodeShell,
+			Node:     "node1",
+			Reason:   "kernel logs",
+			Duration: metav1.Duration{Duration: time.Hour},
+		},
+		Status: iamv1beta1.AccessRequestStatus{
+			Phase:        iamv1beta1.AccessRequestApproved,
+			DecisionTime: &metav1.Time{Time: time.Now()},
+		},
+	}
+	r := newReconciler(accessRequest)
+	ctx := context.Background()
+
+	// the expiration time is filled from the decision time
+	reconcileAccessRequest(t, r, accessRequest.Name)
+	assert.NoError(t, r.Get(ctx, types.NamespacedName{Name: accessRequest.Name}, accessRequest))
+	assert.NotNil(t, accessRequest.Status.ExpirationTime)
+
+	reconcileAccessRequest(t, r, accessRequest.Name)
+	name := types.NamespacedName{Name: "accessrequest-bob-abcde"}
+	clusterRole := &iamv1beta1.ClusterRole{}
+	assert.NoError(t, r.Get(ctx, name, clusterRole))
+	assert.Equal(t, []string{"node1"}, clusterRole.Rules[1].ResourceNames)
+	assert.Equal(t, []string{"nodes/exec"}, clusterRole.Rules[1].Resources)
+	clusterRoleBinding := &iamv1beta1.ClusterRoleBinding{}
+	assert.NoError(t, r.Get(ctx, name, clusterRoleBinding))
+	assert.Equal(t, iamv1beta1.ResourceKindClusterRole, clusterRoleBinding.RoleRef.Kind)
+}
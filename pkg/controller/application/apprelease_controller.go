@@ -36,8 +36,12 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
+	"kubesphere.io/kubesphere/pkg/apiserver/authorization/authorizer"
+	"kubesphere.io/kubesphere/pkg/apiserver/authorization/rbac"
 	"kubesphere.io/kubesphere/pkg/controller"
 	kscontroller "kubesphere.io/kubesphere/pkg/controller/options"
+	"kubesphere.io/kubesphere/pkg/models/iam/am"
+	resourcev1beta1 "kubesphere.io/kubesphere/pkg/models/resources/v1beta1"
 	"kubesphere.io/kubesphere/pkg/simple/client/application"
 	"kubesphere.io/kubesphere/pkg/utils/clusterclient"
 )
@@ -83,6 +87,11 @@ func (r *AppReleaseReconciler) SetupWithManager(mgr *controller.Manager) error {
 		r.logger.Error(err, "failed to init store")
 		return err
 	}
+	resourceManager, err := resourcev1beta1.New(context.Background(), r.Client, mgr.GetCache())
+	if err != nil {
+		return fmt.Errorf("failed to create resource manager: %s", err)
+	}
+	r.authorizer = rbac.NewRBACAuthorizer(am.NewReadOnlyOperator(resourceManager))
 
 	return ctrl.NewControllerManagedBy(mgr).Named(helminstallerController).
 		Watches(
@@ -90,6 +99,14 @@ func (r *AppReleaseReconciler) SetupWithManager(mgr *controller.Manager) error {
 			handler.EnqueueRequestsFromMapFunc(r.mapper),
 			builder.WithPredicates(DeletePredicate{}),
 		).
+		Watches(
+			&clusterv1alpha1.Cluster{},
+			handler.EnqueueRequestsFromMapFunc(r.placementMapper),
+		).
+		Watches(
+			&appv2.ApplicationRelease{},
+			handler.EnqueueRequestForOwner(mgr.GetScheme(), mgr.GetRESTMapper(), &appv2.ApplicationRelease{}, handler.OnlyControllerOwner()),
+		).
 		WithEventFilter(IgnoreAnnotationChangePredicate{AnnotationKey: appv2.TimeoutRecheck}).
 		For(&appv2.ApplicationRelease{}).
 		Named(helminstallerController).
@@ -119,6 +136,8 @@ type AppReleaseReconciler struct {
 	ossStore            s3.Interface
 	cmStore             s3.Interface
 	logger              logr.Logger
+	// authorizer authorizes the creator of the release with placement to deploy to the newly selected clusters
+	authorizer authorizer.Authorizer
 }
 
 func (r *AppReleaseReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
@@ -126,6 +145,9 @@ func (r *AppReleaseReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 	if err := r.Client.Get(ctx, req.NamespacedName, apprls); err != nil {
 		return ctrl.Result{}, client.IgnoreNotFound(err)
 	}
+	if apprls.Spec.Placement != nil {
+		return r.reconcilePlacement(ctx, apprls)
+	}
 	logger := r.logger.WithValues("application release", apprls.Name).WithValues("namespace", apprls.Namespace)
 	timeoutRecheck := apprls.Annotations[appv2.TimeoutRecheck]
 	var reCheck int
@@ -414,6 +436,56 @@ func (r *AppReleaseReconciler) updateStatus(ctx context.Context, apprls *appv2.A
 		apprls.Status.Message = message[0]
 	}
 	apprls.Status.LastUpdate = metav1.Now()
+	var dropped []appv2.ApplicationReleaseRevision
+	if status == appv2.StatusActive {
+		var err error
+		if dropped, err = r.recordRevision(ctx, apprls); err != nil {
+			return err
+		}
+	}
 	patch, _ := json.Marshal(apprls)
-	return r.Status().Patch(ctx, apprls, client.RawPatch(client.Merge.Type(), patch))
+	if err := r.Status().Patch(ctx, apprls, client.RawPatch(client.Merge.Type(), patch)); err != nil {
+		return err
+	}
+	return r.deleteRevisionValues(ctx, dropped)
+}
+
+// recordRevision appends the deployed spec to the revision history unless it's the latest revision already,
+// the values of the revision are stored in a Secret. It returns the revisions dropped from the history,
+// whose values are deleted once the status is updated.
+func (r *AppReleaseReconciler) recordRevision(ctx context.Context, apprls *appv2.ApplicationRelease) ([]appv2.ApplicationReleaseRevision, error) {
+	specHash := apprls.HashSpec()
+	revision := 1
+	if latest := apprls.LatestRevision(); latest != nil {
+		if latest.SpecHash == specHash {
+			return nil, nil
+		}
+		revision = latest.Revision + 1
+	}
+	valuesRef, err := application.SaveRevisionValues(ctx, r.Client, apprls, revision, apprls.Spec.Values)
+	if err != nil {
+		return nil, err
+	}
+	apprls.Status.Revisions = append(apprls.Status.Revisions, appv2.ApplicationReleaseRevision{
+		Revision:     revision,
+		AppVersionID: apprls.Spec.AppVersionID,
+		ValuesRef:    valuesRef,
+		SpecHash:     specHash,
+		DeployedAt:   metav1.Now(),
+	})
+	var dropped []appv2.ApplicationReleaseRevision
+	if exceeded := len(apprls.Status.Revisions) - appv2.MaxNumOfRevisions; exceeded > 0 {
+		dropped = append(dropped, apprls.Status.Revisions[:exceeded]...)
+		apprls.Status.Revisions = apprls.Status.Revisions[exceeded:]
+	}
+	return dropped, nil
+}
+
+func (r *AppReleaseReconciler) deleteRevisionValues(ctx context.Context, revisions []appv2.ApplicationReleaseRevision) error {
+	for i := range revisions {
+		if err := application.DeleteRevisionValues(ctx, r.Client, &revisions[i]); err != nil {
+			return err
+		}
+	}
+	return nil
 }
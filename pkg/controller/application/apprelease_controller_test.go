@@ -0,0 +1,59 @@
+/*
+ * Copyright 2024 the KubeSphere Authors.
+ * Please refer to the LICENSE file in the root directory of the project.
+ * https://github.com/kubesphere/kubesphere/blob/master/LICENSE
+ */
+
+package application
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	appv2 "kubesphere.io/api/application/v2"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"kubesphere.io/kubesphere/pkg/scheme"
+	"kubesphere.io/kubesphere/pkg/simple/client/application"
+)
+
+func TestRecordRevision(t *testing.T) {
+	apprls := &appv2.ApplicationRelease{
+		ObjectMeta: metav1.ObjectMeta{Name: "nginx", UID: "uid"},
+		Spec:       appv2.ApplicationReleaseSpec{AppVersionID: "nginx-1.0.0", AppType: appv2.AppTypeYaml},
+	}
+	ctx := context.Background()
+	r := &AppReleaseReconciler{Client: fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(apprls).Build()}
+
+	var dropped []appv2.ApplicationReleaseRevision
+	for i := 1; i <= appv2.MaxNumOfRevisions+1; i++ {
+		apprls.Spec.Values = []byte(fmt.Sprintf("replicas: %d\n", i))
+		var err error
+		dropped, err = r.recordRevision(ctx, apprls)
+		assert.NoError(t, err)
+	}
+	assert.Len(t, apprls.Status.Revisions, appv2.MaxNumOfRevisions)
+	assert.Equal(t, 1, dropped[0].Revision)
+
+	// the deployed spec is recorded only once
+	dropped, err := r.recordRevision(ctx, apprls)
+	assert.NoError(t, err)
+	assert.Empty(t, dropped)
+	assert.Len(t, apprls.Status.Revisions, appv2.MaxNumOfRevisions)
+
+	latest := apprls.LatestRevision()
+	assert.Equal(t, appv2.MaxNumOfRevisions+1, latest.Revision)
+	values, err := application.GetRevisionValues(ctx, r.Client, apprls, latest)
+	assert.NoError(t, err)
+	assert.Equal(t, fmt.Sprintf("replicas: %d\n", appv2.MaxNumOfRevisions+1), string(values))
+
+	assert.NoError(t, r.deleteRevisionValues(ctx, []appv2.ApplicationReleaseRevision{{Revision: 1, ValuesRef: application.RevisionValuesSecretName(apprls.Name, 1)}}))
+	err = r.Get(ctx, client.ObjectKey{Namespace: appv2.ApplicationNamespace, Name: application.RevisionValuesSecretName(apprls.Name, 1)}, &corev1.Secret{})
+	assert.True(t, apierrors.IsNotFound(err))
+}
@@ -0,0 +1,333 @@
+/*
+ * Copyright 2024 the KubeSphere Authors.
+ * Please refer to the LICENSE file in the root directory of the project.
+ * https://github.com/kubesphere/kubesphere/blob/master/LICENSE
+ */
+
+package application
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	"helm.sh/helm/v3/pkg/chartutil"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apiserver/pkg/authentication/user"
+	appv2 "kubesphere.io/api/application/v2"
+	clusterv1alpha1 "kubesphere.io/api/cluster/v1alpha1"
+	"kubesphere.io/api/constants"
+	tenantv1beta1 "kubesphere.io/api/tenant/v1beta1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/yaml"
+
+	workspacetemplateutils "kubesphere.io/kubesphere/pkg/controller/workspacetemplate/utils"
+	"kubesphere.io/kubesphere/pkg/simple/client/application"
+	"kubesphere.io/kubesphere/pkg/utils/hashutil"
+)
+
+// placementMapper enqueues the releases with placement which select the changed cluster, or have been deployed to it,
+// since the placed clusters may change.
+func (r *AppReleaseReconciler) placementMapper(ctx context.Context, o client.Object) (requests []reconcile.Request) {
+	apprlsList := &appv2.ApplicationReleaseList{}
+	if err := r.List(ctx, apprlsList); err != nil {
+		r.logger.Error(err, "failed to list application releases")
+		return requests
+	}
+	for _, apprls := range apprlsList.Items {
+		if apprls.Spec.Placement != nil && placementMatches(&apprls, o) {
+			requests = append(requests, reconcile.Request{NamespacedName: types.NamespacedName{Name: apprls.Name}})
+		}
+	}
+	return requests
+}
+
+// placementMatches returns true if the cluster is selected by the placement of the release, or the release
+// has been deployed to it and may need to be removed.
+func placementMatches(apprls *appv2.ApplicationRelease, cluster client.Object) bool {
+	if _, ok := apprls.Status.ClusterStatuses[cluster.GetName()]; ok {
+		return true
+	}
+	placement := apprls.Spec.Placement
+	if len(placement.Clusters) > 0 {
+		return slices.Contains(placement.Clusters, cluster.GetName())
+	}
+	if placement.ClusterSelector == nil {
+		return false
+	}
+	selector, err := metav1.LabelSelectorAsSelector(placement.ClusterSelector)
+	if err != nil {
+		return false
+	}
+	return selector.Matches(labels.Set(cluster.GetLabels()))
+}
+
+// helmReleaseNameMaxLength is the max length of the helm release name.
+const helmReleaseNameMaxLength = 53
+
+// clusterReleaseName returns the name of the release created for the placed cluster, the name exceeding
+// the max length of the helm release name is truncated with a hash suffix.
+func clusterReleaseName(apprls *appv2.ApplicationRelease, cluster string) string {
+	name := fmt.Sprintf("%s-%s", apprls.Name, cluster)
+	if len(name) <= helmReleaseNameMaxLength {
+		return name
+	}
+	hash := hashutil.FNVString([]byte(name))[:8]
+	prefix := strings.TrimRight(name[:helmReleaseNameMaxLength-len(hash)-1], "-.")
+	return fmt.Sprintf("%s-%s", prefix, hash)
+}
+
+// placedClusters returns the names of the clusters which the release is placed to, the deleting clusters and
+// the clusters outside of the placement of the workspace are excluded.
+func (r *AppReleaseReconciler) placedClusters(ctx context.Context, apprls *appv2.ApplicationRelease) ([]string, error) {
+	workspace := &tenantv1beta1.WorkspaceTemplate{}
+	if err := r.Get(ctx, types.NamespacedName{Name: apprls.GetLabels()[constants.WorkspaceLabelKey]}, workspace); err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	placement := apprls.Spec.Placement
+	var clusters []clusterv1alpha1.Cluster
+	if len(placement.Clusters) > 0 {
+		for _, name := range placement.Clusters {
+			cluster := clusterv1alpha1.Cluster{}
+			if err := r.Get(ctx, types.NamespacedName{Name: name}, &cluster); err != nil {
+				if apierrors.IsNotFound(err) {
+					continue
+				}
+				return nil, err
+			}
+			clusters = append(clusters, cluster)
+		}
+	} else if placement.ClusterSelector != nil {
+		selector, err := metav1.LabelSelectorAsSelector(placement.ClusterSelector)
+		if err != nil {
+			return nil, err
+		}
+		clusterList := &clusterv1alpha1.ClusterList{}
+		if err = r.List(ctx, clusterList, client.MatchingLabelsSelector{Selector: selector}); err != nil {
+			return nil, err
+		}
+		clusters = clusterList.Items
+	}
+
+	var names []string
+	for _, cluster := range clusters {
+		if cluster.DeletionTimestamp.IsZero() && workspacetemplateutils.WorkspaceTemplateMatchTargetCluster(workspace, &cluster) {
+			names = append(names, cluster.Name)
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// clusterValues returns the values of the release in the cluster with the overrides of the cluster.
+func clusterValues(apprls *appv2.ApplicationRelease, cluster string) ([]byte, error) {
+	override, ok := apprls.Spec.Placement.Overrides[cluster]
+	if !ok || override == "" {
+		return apprls.Spec.Values, nil
+	}
+	if apprls.Spec.AppType != appv2.AppTypeHelm {
+		return []byte(override), nil
+	}
+	base, err := chartutil.ReadValues(apprls.Spec.Values)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse values: %s", err)
+	}
+	overrideValues, err := chartutil.ReadValues([]byte(override))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse the override values of cluster %s: %s", cluster, err)
+	}
+	return yaml.Marshal(chartutil.MergeTables(overrideValues, base))
+}
+
+// reconcilePlacement creates or updates the release of each placed cluster, deletes the releases of the clusters
+// no longer placed, and aggregates the status of the releases. The releases are deleted by the garbage collector
+// along with this release, so that each of them uninstalls itself. The creator of the release must be allowed to
+// deploy to the clusters newly selected by the placement, the others are skipped.
+func (r *AppReleaseReconciler) reconcilePlacement(ctx context.Context, apprls *appv2.ApplicationRelease) (ctrl.Result, error) {
+	logger := r.logger.WithValues("application release", apprls.Name)
+	if !apprls.DeletionTimestamp.IsZero() {
+		return ctrl.Result{}, nil
+	}
+
+	clusters, err := r.placedClusters(ctx, apprls)
+	if err != nil {
+		logger.Error(err, "failed to get placed clusters")
+		return ctrl.Result{}, err
+	}
+	clusterReleases := &appv2.ApplicationReleaseList{}
+	if err = r.List(ctx, clusterReleases, client.MatchingLabels{appv2.ParentAppReleaseLabelKey: apprls.Name}); err != nil {
+		return ctrl.Result{}, err
+	}
+	deployed := make(map[string]bool, len(clusterReleases.Items))
+	for i := range clusterReleases.Items {
+		if metav1.IsControlledBy(&clusterReleases.Items[i], apprls) {
+			deployed[clusterReleases.Items[i].GetRlsCluster()] = true
+		}
+	}
+
+	statuses := make(map[string]appv2.ClusterReleaseStatus, len(clusters))
+	skipped := make(map[string]string)
+	for _, cluster := range clusters {
+		if !deployed[cluster] {
+			if err = r.authorizeCluster(ctx, apprls, cluster); err != nil {
+				logger.V(4).Info("skip the cluster newly selected by the placement", "cluster", cluster, "reason", err.Error())
+				skipped[cluster] = err.Error()
+				continue
+			}
+		}
+		status, err := r.syncClusterRelease(ctx, apprls, cluster)
+		if err != nil {
+			logger.Error(err, "failed to sync the release of cluster", "cluster", cluster)
+			status = appv2.ClusterReleaseStatus{
+				ReleaseName: clusterReleaseName(apprls, cluster),
+				State:       appv2.StatusFailed,
+				Message:     err.Error(),
+				LastUpdate:  metav1.Now(),
+			}
+		}
+		statuses[cluster] = status
+	}
+
+	for i := range clusterReleases.Items {
+		clusterRelease := &clusterReleases.Items[i]
+		if _, ok := statuses[clusterRelease.GetRlsCluster()]; ok || !clusterRelease.DeletionTimestamp.IsZero() {
+			continue
+		}
+		logger.V(4).Info("cluster is no longer placed, delete the release", "cluster", clusterRelease.GetRlsCluster())
+		if err = r.Delete(ctx, clusterRelease); client.IgnoreNotFound(err) != nil {
+			return ctrl.Result{}, err
+		}
+	}
+
+	original := apprls.Status.DeepCopy()
+	apprls.Status.ClusterStatuses = statuses
+	apprls.Status.SpecHash = apprls.HashSpec()
+	apprls.Status.State, apprls.Status.Message = aggregateState(apprls, statuses, skipped)
+	var dropped []appv2.ApplicationReleaseRevision
+	if apprls.Status.State == appv2.StatusActive {
+		if dropped, err = r.recordRevision(ctx, apprls); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+	// the status is updated only if changed, otherwise the update triggers the reconciliation again
+	if equality.Semantic.DeepEqual(original, &apprls.Status) {
+		return ctrl.Result{}, nil
+	}
+	apprls.Status.LastUpdate = metav1.Now()
+	if err = r.Status().Update(ctx, apprls); err != nil {
+		return ctrl.Result{}, err
+	}
+	return ctrl.Result{}, r.deleteRevisionValues(ctx, dropped)
+}
+
+// authorizeCluster checks that the creator of the release is allowed to deploy to the cluster, with the identity
+// attached to the creator by the authenticator.
+func (r *AppReleaseReconciler) authorizeCluster(ctx context.Context, apprls *appv2.ApplicationRelease, cluster string) error {
+	creator := apprls.Annotations[constants.CreatorAnnotationKey]
+	if creator == "" {
+		return fmt.Errorf("the creator of the release is unknown")
+	}
+	clusterClient, err := r.clusterClientSet.GetRuntimeClient(cluster)
+	if err != nil {
+		return err
+	}
+	creatorInfo := &user.DefaultInfo{Name: creator, Groups: []string{user.AllAuthenticated}}
+	return application.AuthorizeClusterRelease(ctx, r.authorizer, creatorInfo, clusterClient, apprls, cluster)
+}
+
+func (r *AppReleaseReconciler) syncClusterRelease(ctx context.Context, apprls *appv2.ApplicationRelease, cluster string) (appv2.ClusterReleaseStatus, error) {
+	values, err := clusterValues(apprls, cluster)
+	if err != nil {
+		return appv2.ClusterReleaseStatus{}, err
+	}
+	clusterRelease := &appv2.ApplicationRelease{ObjectMeta: metav1.ObjectMeta{Name: clusterReleaseName(apprls, cluster)}}
+	if _, err = controllerutil.CreateOrUpdate(ctx, r.Client, clusterRelease, func() error {
+		if clusterRelease.ResourceVersion != "" && !metav1.IsControlledBy(clusterRelease, apprls) {
+			return fmt.Errorf("application release %s already exists", clusterRelease.Name)
+		}
+		if clusterRelease.Labels == nil {
+			clusterRelease.Labels = make(map[string]string)
+		}
+		for key, value := range apprls.Labels {
+			clusterRelease.Labels[key] = value
+		}
+		clusterRelease.Labels[constants.ClusterNameLabelKey] = cluster
+		clusterRelease.Labels[appv2.ParentAppReleaseLabelKey] = apprls.Name
+		if clusterRelease.Annotations == nil {
+			clusterRelease.Annotations = make(map[string]string)
+		}
+		clusterRelease.Annotations[constants.CreatorAnnotationKey] = apprls.Annotations[constants.CreatorAnnotationKey]
+		clusterRelease.Spec = apprls.Spec
+		clusterRelease.Spec.Placement = nil
+		clusterRelease.Spec.Values = values
+		return controllerutil.SetControllerReference(apprls, clusterRelease, r.Scheme())
+	}); err != nil {
+		return appv2.ClusterReleaseStatus{}, err
+	}
+	return appv2.ClusterReleaseStatus{
+		ReleaseName: clusterRelease.Name,
+		State:       clusterRelease.Status.State,
+		Message:     clusterRelease.Status.Message,
+		LastUpdate:  clusterRelease.Status.LastUpdate,
+	}, nil
+}
+
+// aggregateState returns the state of the release with placement, it's active only if the releases of
+// all placed clusters are active, failed if any of them failed, and pending if no cluster is placed.
+// The skipped clusters are reported in the message.
+func aggregateState(apprls *appv2.ApplicationRelease, statuses map[string]appv2.ClusterReleaseStatus, skipped map[string]string) (string, string) {
+	state, message := aggregateClusterStates(apprls, statuses)
+	if len(skipped) == 0 {
+		return state, message
+	}
+	clusters := make([]string, 0, len(skipped))
+	for cluster := range skipped {
+		clusters = append(clusters, cluster)
+	}
+	sort.Strings(clusters)
+	for i, cluster := range clusters {
+		clusters[i] = fmt.Sprintf("%s (%s)", cluster, skipped[cluster])
+	}
+	return state, fmt.Sprintf("%s, skipped clusters: %s", message, strings.Join(clusters, ", "))
+}
+
+func aggregateClusterStates(apprls *appv2.ApplicationRelease, statuses map[string]appv2.ClusterReleaseStatus) (string, string) {
+	if len(statuses) == 0 {
+		return appv2.StatusPending, "no cluster is placed"
+	}
+	var failed, pending []string
+	for cluster, status := range statuses {
+		switch status.State {
+		case appv2.StatusActive:
+		case appv2.StatusFailed, appv2.StatusDeployFailed, appv2.StatusTimeout, appv2.StatusClusterDeleted:
+			failed = append(failed, cluster)
+		default:
+			pending = append(pending, cluster)
+		}
+	}
+	sort.Strings(failed)
+	sort.Strings(pending)
+	switch {
+	case len(failed) > 0:
+		return appv2.StatusFailed, fmt.Sprintf("failed in clusters: %s", strings.Join(failed, ", "))
+	case len(pending) > 0 && len(apprls.Status.Revisions) == 0:
+		return appv2.StatusCreating, fmt.Sprintf("deploying to clusters: %s", strings.Join(pending, ", "))
+	case len(pending) > 0:
+		return appv2.StatusUpgrading, fmt.Sprintf("deploying to clusters: %s", strings.Join(pending, ", "))
+	default:
+		return appv2.StatusActive, fmt.Sprintf("deployed to %d clusters", len(statuses))
+	}
+}
@@ -0,0 +1,258 @@
+/*
+ * Copyright 2024 the KubeSphere Authors.
+ * Please refer to the LICENSE file in the root directory of the project.
+ * https://github.com/kubesphere/kubesphere/blob/master/LICENSE
+ */
+
+package application
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/go-logr/logr"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
+	appv2 "kubesphere.io/api/application/v2"
+	clusterv1alpha1 "kubesphere.io/api/cluster/v1alpha1"
+	"kubesphere.io/api/constants"
+	tenantv1beta1 "kubesphere.io/api/tenant/v1beta1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/yaml"
+
+	"kubesphere.io/kubesphere/pkg/apiserver/authorization/authorizer"
+	"kubesphere.io/kubesphere/pkg/scheme"
+	"kubesphere.io/kubesphere/pkg/utils/clusterclient"
+)
+
+type fakeClusterClientSet struct {
+	clients map[string]client.Client
+}
+
+func (f *fakeClusterClientSet) Get(name string) (*clusterv1alpha1.Cluster, error) {
+	if _, ok := f.clients[name]; !ok {
+		return nil, fmt.Errorf("cluster %s not found", name)
+	}
+	return &clusterv1alpha1.Cluster{ObjectMeta: metav1.ObjectMeta{Name: name}}, nil
+}
+
+func (f *fakeClusterClientSet) ListClusters(_ context.Context) ([]clusterv1alpha1.Cluster, error) {
+	clusters := make([]clusterv1alpha1.Cluster, 0, len(f.clients))
+	for name := range f.clients {
+		clusters = append(clusters, clusterv1alpha1.Cluster{ObjectMeta: metav1.ObjectMeta{Name: name}})
+	}
+	return clusters, nil
+}
+
+func (f *fakeClusterClientSet) GetClusterClient(name string) (*clusterclient.ClusterClient, error) {
+	c, err := f.GetRuntimeClient(name)
+	if err != nil {
+		return nil, err
+	}
+	return &clusterclient.ClusterClient{Client: c}, nil
+}
+
+func (f *fakeClusterClientSet) GetRuntimeClient(name string) (client.Client, error) {
+	c, ok := f.clients[name]
+	if !ok {
+		return nil, fmt.Errorf("cluster %s not found", name)
+	}
+	return c, nil
+}
+
+func TestReconcilePlacement(t *testing.T) {
+	apprls := &appv2.ApplicationRelease{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "nginx",
+			UID:  "uid",
+			Labels: map[string]string{
+				constants.WorkspaceLabelKey: "ws1",
+				constants.NamespaceLabelKey: "demo",
+			},
+			Annotations: map[string]string{constants.CreatorAnnotationKey: "admin"},
+		},
+		Spec: appv2.ApplicationReleaseSpec{
+			AppID:        "nginx",
+			AppVersionID: "nginx-1.0.0",
+			AppType:      appv2.AppTypeHelm,
+			Values:       []byte("replicas: 1\nimage: nginx\n"),
+			Placement: &appv2.ReleasePlacement{
+				ClusterSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"region": "east"}},
+				Overrides:       map[string]string{"east-2": "replicas: 3\n"},
+			},
+		},
+	}
+	cluster := func(name, region string) *clusterv1alpha1.Cluster {
+		return &clusterv1alpha1.Cluster{ObjectMeta: metav1.ObjectMeta{Name: name, Labels: map[string]string{"region": region}}}
+	}
+	// east-3 is selected by the release but not placed in the workspace
+	workspace := &tenantv1beta1.WorkspaceTemplate{
+		ObjectMeta: metav1.ObjectMeta{Name: "ws1"},
+		Spec: tenantv1beta1.WorkspaceTemplateSpec{Placement: tenantv1beta1.GenericPlacement{
+			Clusters: []tenantv1beta1.GenericClusterReference{{Name: "east-1"}, {Name: "east-2"}, {Name: "east-4"}, {Name: "west-1"}},
+		}},
+	}
+	legacy := &appv2.ApplicationRelease{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "nginx-west-1",
+			Labels: map[string]string{
+				constants.ClusterNameLabelKey:  "west-1",
+				appv2.ParentAppReleaseLabelKey: apprls.Name,
+			},
+		},
+	}
+	active := &appv2.ApplicationRelease{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "nginx-east-1",
+			Labels: map[string]string{
+				constants.ClusterNameLabelKey:  "east-1",
+				appv2.ParentAppReleaseLabelKey: apprls.Name,
+			},
+		},
+		Status: appv2.ApplicationReleaseStatus{State: appv2.StatusActive},
+	}
+	c := fake.NewClientBuilder().WithScheme(scheme.Scheme).
+		WithStatusSubresource(&appv2.ApplicationRelease{}).
+		WithObjects(apprls, legacy, workspace, cluster("east-1", "east"), cluster("east-2", "east"),
+			cluster("east-3", "east"), cluster("east-4", "east"), cluster("west-1", "west")).
+		Build()
+	// the release of east-1 has been deployed before
+	assert.NoError(t, c.Create(context.Background(), active))
+	active.OwnerReferences = []metav1.OwnerReference{{APIVersion: "application.kubesphere.io/v2", Kind: "ApplicationRelease",
+		Name: apprls.Name, UID: apprls.UID, Controller: ptr.To(true)}}
+	assert.NoError(t, c.Update(context.Background(), active))
+	assert.NoError(t, c.Status().Update(context.Background(), active))
+
+	// the creator is not allowed to deploy to east-4, nor to east-1 which has been deployed before
+	authz := authorizer.AuthorizerFunc(func(a authorizer.Attributes) (authorizer.Decision, string, error) {
+		if a.GetUser().GetName() != "admin" || a.GetCluster() == "east-4" || a.GetCluster() == "east-1" {
+			return authorizer.DecisionDeny, "denied", nil
+		}
+		return authorizer.DecisionAllow, "", nil
+	})
+	clusterClient := fake.NewClientBuilder().WithScheme(scheme.Scheme).Build()
+	r := &AppReleaseReconciler{
+		Client:     c,
+		logger:     logr.Discard(),
+		authorizer: authz,
+		clusterClientSet: &fakeClusterClientSet{clients: map[string]client.Client{
+			"east-1": clusterClient, "east-2": clusterClient, "east-4": clusterClient, "west-1": clusterClient,
+		}},
+	}
+	_, err := r.reconcilePlacement(context.Background(), apprls)
+	assert.NoError(t, err)
+
+	east2 := &appv2.ApplicationRelease{}
+	assert.NoError(t, c.Get(context.Background(), client.ObjectKey{Name: "nginx-east-2"}, east2))
+	assert.Equal(t, "east-2", east2.GetRlsCluster())
+	assert.Equal(t, "demo", east2.GetRlsNamespace())
+	assert.Equal(t, "admin", east2.Annotations[constants.CreatorAnnotationKey])
+	assert.Nil(t, east2.Spec.Placement)
+	values := map[string]interface{}{}
+	assert.NoError(t, yaml.Unmarshal(east2.Spec.Values, &values))
+	assert.Equal(t, map[string]interface{}{"replicas": float64(3), "image": "nginx"}, values)
+	assert.True(t, metav1.IsControlledBy(east2, apprls))
+
+	assert.NoError(t, c.Get(context.Background(), client.ObjectKey{Name: "nginx-east-1"}, active))
+	assert.Equal(t, apprls.Spec.Values, active.Spec.Values)
+
+	err = c.Get(context.Background(), client.ObjectKey{Name: "nginx-east-3"}, &appv2.ApplicationRelease{})
+	assert.True(t, client.IgnoreNotFound(err) == nil && err != nil, "the cluster outside of the workspace should be skipped")
+
+	err = c.Get(context.Background(), client.ObjectKey{Name: "nginx-east-4"}, &appv2.ApplicationRelease{})
+	assert.True(t, client.IgnoreNotFound(err) == nil && err != nil, "the cluster the creator is not allowed to deploy to should be skipped")
+
+	err = c.Get(context.Background(), client.ObjectKey{Name: legacy.Name}, legacy)
+	assert.True(t, client.IgnoreNotFound(err) == nil && err != nil, "the release of the cluster no longer placed should be deleted")
+
+	updated := &appv2.ApplicationRelease{}
+	assert.NoError(t, c.Get(context.Background(), client.ObjectKey{Name: apprls.Name}, updated))
+	assert.Equal(t, appv2.StatusCreating, updated.Status.State)
+	assert.Len(t, updated.Status.ClusterStatuses, 2)
+	assert.Equal(t, appv2.StatusActive, updated.Status.ClusterStatuses["east-1"].State)
+	assert.Equal(t, "nginx-east-2", updated.Status.ClusterStatuses["east-2"].ReleaseName)
+	assert.Contains(t, updated.Status.Message, "skipped clusters: east-4")
+}
+
+func TestReconcilePlacementWithoutClusters(t *testing.T) {
+	apprls := &appv2.ApplicationRelease{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   "nginx",
+			Labels: map[string]string{constants.WorkspaceLabelKey: "ws1", constants.NamespaceLabelKey: "demo"},
+		},
+		Spec: appv2.ApplicationReleaseSpec{
+			AppVersionID: "nginx-1.0.0",
+			Placement: &appv2.ReleasePlacement{
+				ClusterSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"region": "east"}},
+			},
+		},
+	}
+	c := fake.NewClientBuilder().WithScheme(scheme.Scheme).
+		WithStatusSubresource(&appv2.ApplicationRelease{}).
+		WithObjects(apprls, &tenantv1beta1.WorkspaceTemplate{ObjectMeta: metav1.ObjectMeta{Name: "ws1"}}).
+		Build()
+	r := &AppReleaseReconciler{Client: c, logger: logr.Discard()}
+	_, err := r.reconcilePlacement(context.Background(), apprls)
+	assert.NoError(t, err)
+
+	updated := &appv2.ApplicationRelease{}
+	assert.NoError(t, c.Get(context.Background(), client.ObjectKey{Name: apprls.Name}, updated))
+	assert.Equal(t, appv2.StatusPending, updated.Status.State)
+	assert.Empty(t, updated.Status.Revisions)
+}
+
+func TestPlacementMatches(t *testing.T) {
+	apprls := &appv2.ApplicationRelease{
+		Spec: appv2.ApplicationReleaseSpec{Placement: &appv2.ReleasePlacement{
+			ClusterSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"region": "east"}},
+		}},
+		Status: appv2.ApplicationReleaseStatus{ClusterStatuses: map[string]appv2.ClusterReleaseStatus{"west-2": {}}},
+	}
+	cluster := func(name, region string) *clusterv1alpha1.Cluster {
+		return &clusterv1alpha1.Cluster{ObjectMeta: metav1.ObjectMeta{Name: name, Labels: map[string]string{"region": region}}}
+	}
+	assert.True(t, placementMatches(apprls, cluster("east-1", "east")))
+	assert.False(t, placementMatches(apprls, cluster("west-1", "west")))
+	assert.True(t, placementMatches(apprls, cluster("west-2", "west")), "the deployed cluster should be matched to remove the release")
+
+	apprls.Spec.Placement = &appv2.ReleasePlacement{Clusters: []string{"west-1"}}
+	assert.True(t, placementMatches(apprls, cluster("west-1", "west")))
+	assert.False(t, placementMatches(apprls, cluster("east-1", "east")))
+}
+
+func TestAggregateState(t *testing.T) {
+	apprls := &appv2.ApplicationRelease{}
+	state, _ := aggregateState(apprls, map[string]appv2.ClusterReleaseStatus{
+		"c1": {State: appv2.StatusActive},
+		"c2": {State: appv2.StatusActive},
+	}, nil)
+	assert.Equal(t, appv2.StatusActive, state)
+
+	state, message := aggregateState(apprls, map[string]appv2.ClusterReleaseStatus{
+		"c1": {State: appv2.StatusDeployFailed},
+		"c2": {State: appv2.StatusUpgrading},
+	}, map[string]string{"c3": "forbidden"})
+	assert.Equal(t, appv2.StatusFailed, state)
+	assert.Equal(t, "failed in clusters: c1, skipped clusters: c3 (forbidden)", message)
+
+	apprls.Status.Revisions = []appv2.ApplicationReleaseRevision{{Revision: 1}}
+	state, _ = aggregateState(apprls, map[string]appv2.ClusterReleaseStatus{"c1": {State: appv2.StatusUpgraded}}, nil)
+	assert.Equal(t, appv2.StatusUpgrading, state)
+
+	state, _ = aggregateState(apprls, nil, nil)
+	assert.Equal(t, appv2.StatusPending, state)
+}
+
+func TestClusterReleaseName(t *testing.T) {
+	apprls := &appv2.ApplicationRelease{ObjectMeta: metav1.ObjectMeta{Name: "nginx"}}
+	assert.Equal(t, "nginx-east-1", clusterReleaseName(apprls, "east-1"))
+
+	apprls.Name = strings.Repeat("a", 40)
+	name := clusterReleaseName(apprls, "cluster-with-a-long-name")
+	assert.LessOrEqual(t, len(name), helmReleaseNameMaxLength)
+	assert.NotEqual(t, name, clusterReleaseName(apprls, "cluster-with-a-long-name-2"))
+}
@@ -9,6 +9,7 @@ package application
 import (
 	"context"
 	"strings"
+	"time"
 
 	"github.com/go-logr/logr"
 
@@ -17,29 +18,45 @@ import (
 	"kubesphere.io/utils/s3"
 
 	"kubesphere.io/kubesphere/pkg/simple/client/application"
+	"kubesphere.io/kubesphere/pkg/simple/client/scanner"
 
 	clusterv1alpha1 "kubesphere.io/api/cluster/v1alpha1"
 
 	kscontroller "kubesphere.io/kubesphere/pkg/controller"
 
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/util/workqueue"
 	appv2 "kubesphere.io/api/application/v2"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 )
 
 const (
 	appVersionController = "appversion"
+	// scanRetryInterval is the interval to scan the version again if the scan failed
+	scanRetryInterval = time.Minute
+	// scanWorkers is the number of the versions scanned at the same time
+	scanWorkers = 2
 )
 
 var _ reconcile.Reconciler = &AppVersionReconciler{}
 var _ kscontroller.Controller = &AppVersionReconciler{}
+var _ manager.Runnable = &AppVersionReconciler{}
 
 type AppVersionReconciler struct {
 	client.Client
 	ossStore s3.Interface
 	cmStore  s3.Interface
 	logger   logr.Logger
+	// scanOptions and scanner are used to scan the submitted versions, the versions are not scanned if disabled
+	scanOptions *scanner.Options
+	scanner     scanner.Interface
+	// scanQueue holds the names of the versions to scan, the versions are scanned by the workers
+	// out of the reconciliation, so that a slow scan never blocks the other versions
+	scanQueue workqueue.TypedInterface[string]
 }
 
 func (r *AppVersionReconciler) Name() string {
@@ -58,6 +75,12 @@ func (r *AppVersionReconciler) SetupWithManager(mgr *kscontroller.Manager) (err
 		r.logger.Error(err, "failed to init store")
 		return err
 	}
+	r.scanOptions = mgr.Options.AppScanOptions
+	r.scanner = scanner.NewScanner(r.scanOptions)
+	r.scanQueue = workqueue.NewTypedWithConfig(workqueue.TypedQueueConfig[string]{Name: "appversion-scan"})
+	if err = mgr.Add(r); err != nil {
+		return err
+	}
 	return ctrl.NewControllerManagedBy(mgr).
 		Named(appVersionController).
 		For(&appv2.ApplicationVersion{}).
@@ -83,11 +106,120 @@ func (r *AppVersionReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 		if err != nil {
 			logger.Error(err, "Failed to clean file")
 		}
+		return ctrl.Result{}, nil
 	}
 
+	return r.scan(ctx, appVersion)
+}
+
+// scan scans the images and the manifests of the submitted version, the result is recorded in the status,
+// and the version can't be published unless the scan is passed. The scan is reset when the version is submitted again.
+func (r *AppVersionReconciler) scan(ctx context.Context, appVersion *appv2.ApplicationVersion) (ctrl.Result, error) {
+	result := appVersion.Status.Scan
+	if r.scanOptions == nil || !r.scanOptions.Enabled {
+		// the pending scan is dropped, otherwise the version can never be published
+		if result != nil && result.State == appv2.ScanStateScanning {
+			appVersion.Status.Scan = nil
+			return ctrl.Result{}, r.Status().Update(ctx, appVersion)
+		}
+		return ctrl.Result{}, nil
+	}
+	if appVersion.Status.State != appv2.ReviewStatusSubmitted {
+		return ctrl.Result{}, nil
+	}
+	if result == nil {
+		// mark the version as scanning first, so that the version can't be published before scanned
+		appVersion.Status.Scan = &appv2.ApplicationVersionScan{State: appv2.ScanStateScanning}
+		return ctrl.Result{}, r.Status().Update(ctx, appVersion)
+	}
+	if result.State != appv2.ScanStateScanning && result.State != appv2.ScanStateFailed {
+		return ctrl.Result{}, nil
+	}
+	if delay := scanRetryDelay(result); delay > 0 {
+		return ctrl.Result{RequeueAfter: delay}, nil
+	}
+	r.scanQueue.Add(appVersion.Name)
 	return ctrl.Result{}, nil
 }
 
+// scanRetryDelay returns the time to wait before the failed scan is retried.
+func scanRetryDelay(scan *appv2.ApplicationVersionScan) time.Duration {
+	if scan.State != appv2.ScanStateFailed || scan.ScannedAt == nil {
+		return 0
+	}
+	return time.Until(scan.ScannedAt.Add(scanRetryInterval))
+}
+
+// Start runs the scan workers until the manager is stopped.
+func (r *AppVersionReconciler) Start(ctx context.Context) error {
+	for i := 0; i < scanWorkers; i++ {
+		go wait.UntilWithContext(ctx, r.runScanWorker, time.Second)
+	}
+	<-ctx.Done()
+	r.scanQueue.ShutDown()
+	return nil
+}
+
+// NeedLeaderElection implements the LeaderElectionRunnable interface, the versions are scanned by the leader only.
+func (r *AppVersionReconciler) NeedLeaderElection() bool {
+	return true
+}
+
+func (r *AppVersionReconciler) runScanWorker(ctx context.Context) {
+	for {
+		name, shutdown := r.scanQueue.Get()
+		if shutdown {
+			return
+		}
+		if err := r.scanAndRecord(ctx, name); err != nil {
+			r.logger.Error(err, "failed to record the scan of application version", "application version", name)
+		}
+		r.scanQueue.Done(name)
+	}
+}
+
+// scanAndRecord scans the version and records the result in the status, the failed scans are retried by the reconciliation.
+func (r *AppVersionReconciler) scanAndRecord(ctx context.Context, name string) error {
+	appVersion := &appv2.ApplicationVersion{}
+	if err := r.Get(ctx, client.ObjectKey{Name: name}, appVersion); err != nil {
+		return client.IgnoreNotFound(err)
+	}
+	// the version may be submitted again or withdrawn while queued
+	scan := appVersion.Status.Scan
+	if appVersion.Status.State != appv2.ReviewStatusSubmitted || scan == nil ||
+		(scan.State != appv2.ScanStateScanning && scan.State != appv2.ScanStateFailed) || scanRetryDelay(scan) > 0 {
+		return nil
+	}
+	logger := r.logger.WithValues("application version", name)
+	logger.V(4).Info("scan application version")
+	findings, err := r.scanVersion(ctx, appVersion)
+	if err != nil {
+		logger.Error(err, "failed to scan application version")
+		appVersion.Status.Scan = &appv2.ApplicationVersionScan{
+			State:     appv2.ScanStateFailed,
+			Message:   err.Error(),
+			Threshold: r.scanOptions.SeverityThreshold,
+			ScannedAt: &metav1.Time{Time: metav1.Now().Time},
+		}
+	} else {
+		appVersion.Status.Scan = scanner.Evaluate(findings, r.scanOptions.SeverityThreshold)
+	}
+	// the conflicts are retried by the reconciliation triggered by the newer version
+	return client.IgnoreNotFound(r.Status().Update(ctx, appVersion))
+}
+
+func (r *AppVersionReconciler) scanVersion(ctx context.Context, appVersion *appv2.ApplicationVersion) ([]appv2.ScanFinding, error) {
+	data, err := application.FailOverGet(r.cmStore, r.ossStore, appVersion.Name, r.Client, true)
+	if err != nil {
+		return nil, err
+	}
+	manifest, err := application.RenderManifest(data, appVersion.Spec.AppType)
+	if err != nil {
+		return nil, err
+	}
+	return scanner.ScanManifest(ctx, r.scanner, r.scanOptions.Policies, manifest)
+}
+
 func (r *AppVersionReconciler) deleteFile(ctx context.Context, appVersion *appv2.ApplicationVersion) error {
 	logger := r.logger.WithValues("application version", appVersion.Name)
 	defer func() {
@@ -0,0 +1,115 @@
+/*
+ * Copyright 2024 the KubeSphere Authors.
+ * Please refer to the LICENSE file in the root directory of the project.
+ * https://github.com/kubesphere/kubesphere/blob/master/LICENSE
+ */
+
+package application
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/go-logr/logr"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/util/workqueue"
+	appv2 "kubesphere.io/api/application/v2"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"kubesphere.io/kubesphere/pkg/scheme"
+	"kubesphere.io/kubesphere/pkg/simple/client/application"
+	"kubesphere.io/kubesphere/pkg/simple/client/scanner"
+)
+
+type fakeScanner struct {
+	err error
+}
+
+func (f fakeScanner) Scan(_ context.Context, image string) ([]appv2.ScanFinding, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	return []appv2.ScanFinding{{Type: appv2.FindingTypeVulnerability, Severity: appv2.SeverityLow, ID: "CVE-2024-0001", Image: image}}, nil
+}
+
+func TestScanAppVersion(t *testing.T) {
+	version := &appv2.ApplicationVersion{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:       "nginx-1.0.0",
+			Labels:     map[string]string{appv2.RepoIDLabelKey: appv2.UploadRepoKey},
+			Finalizers: []string{appv2.CleanupFinalizer},
+		},
+		Spec:   appv2.ApplicationVersionSpec{AppType: appv2.AppTypeYaml},
+		Status: appv2.ApplicationVersionStatus{State: appv2.ReviewStatusSubmitted},
+	}
+	pkg := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Namespace: appv2.ApplicationNamespace, Name: version.Name},
+		BinaryData: map[string][]byte{appv2.BinaryKey: []byte(`
+apiVersion: v1
+kind: Pod
+metadata:
+  name: nginx
+spec:
+  containers:
+  - name: nginx
+    image: nginx:1.25
+    securityContext:
+      privileged: true
+`)},
+	}
+	c := fake.NewClientBuilder().WithScheme(scheme.Scheme).
+		WithStatusSubresource(&appv2.ApplicationVersion{}).WithObjects(version, pkg).Build()
+	options := scanner.NewOptions()
+	options.Enabled = true
+	r := &AppVersionReconciler{
+		Client:      c,
+		cmStore:     application.CmStore{Client: c},
+		logger:      logr.Discard(),
+		scanOptions: options,
+		scanner:     fakeScanner{err: fmt.Errorf("connection refused")},
+		scanQueue:   workqueue.NewTyped[string](),
+	}
+	ctx := context.Background()
+	reconcile := func() *appv2.ApplicationVersion {
+		current := &appv2.ApplicationVersion{}
+		assert.NoError(t, c.Get(ctx, client.ObjectKeyFromObject(version), current))
+		_, err := r.scan(ctx, current)
+		assert.NoError(t, err)
+		// the versions are scanned by the workers out of the reconciliation
+		for r.scanQueue.Len() > 0 {
+			name, _ := r.scanQueue.Get()
+			assert.NoError(t, r.scanAndRecord(ctx, name))
+			r.scanQueue.Done(name)
+		}
+		assert.NoError(t, c.Get(ctx, client.ObjectKeyFromObject(version), current))
+		return current
+	}
+
+	current := reconcile()
+	assert.Equal(t, appv2.ScanStateScanning, current.Status.Scan.State)
+
+	current = reconcile()
+	assert.Equal(t, appv2.ScanStateFailed, current.Status.Scan.State)
+	assert.Contains(t, current.Status.Scan.Message, "connection refused")
+
+	// the failed scan is retried after the interval
+	current.Status.Scan.ScannedAt.Time = current.Status.Scan.ScannedAt.Add(-scanRetryInterval)
+	assert.NoError(t, c.Status().Update(ctx, current))
+	r.scanner = fakeScanner{}
+	current = reconcile()
+	assert.Equal(t, appv2.ScanStateBlocked, current.Status.Scan.State)
+	assert.Equal(t, appv2.SeverityHigh, current.Status.Scan.Findings[0].Severity)
+	assert.Equal(t, scanner.PolicyNoPrivileged, current.Status.Scan.Findings[0].ID)
+	assert.Equal(t, 1, current.Status.Scan.Summary[appv2.SeverityLow])
+
+	// the pending scan is dropped if the scan is disabled
+	current.Status.Scan = &appv2.ApplicationVersionScan{State: appv2.ScanStateScanning}
+	assert.NoError(t, c.Status().Update(ctx, current))
+	options.Enabled = false
+	current = reconcile()
+	assert.Nil(t, current.Status.Scan)
+}
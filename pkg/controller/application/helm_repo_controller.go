@@ -168,6 +168,15 @@ func (r *RepoReconciler) Reconcile(ctx context.Context, request reconcile.Reques
 		}
 	}
 
+	migrated, err := application.MigrateRepoCredential(ctx, r.Client, helmRepo)
+	if err != nil {
+		logger.Error(err, "migrate credential failed")
+		return reconcile.Result{}, err
+	}
+	if migrated {
+		logger.V(4).Info("credential migrated to secret", "secret", helmRepo.Spec.SecretRef)
+	}
+
 	if helmRepo.Spec.SyncPeriod == nil {
 		helmRepo.Spec.SyncPeriod = ptr.To(0)
 	}
@@ -204,7 +213,12 @@ func (r *RepoReconciler) Reconcile(ctx context.Context, request reconcile.Reques
 		return reconcile.Result{}, err
 	}
 
-	index, err := application.LoadRepoIndex(helmRepo.Spec.Url, helmRepo.Spec.Credential)
+	cred, err := application.ResolveRepoCredential(ctx, r.Client, helmRepo)
+	if err != nil {
+		logger.Error(err, "resolve credential failed")
+		return reconcile.Result{}, err
+	}
+	index, err := application.LoadRepoIndex(helmRepo.Spec.Url, cred)
 	if err != nil {
 		logger.Error(err, "load index failed", "url", helmRepo.Spec.Url)
 		return reconcile.Result{}, err
@@ -370,7 +384,6 @@ func generateVRequest(helmRepo *appv2.Repo, ver *helmrepo.ChartVersion, shortNam
 		Maintainers:  application.GetMaintainers(ver.Maintainers),
 		AppType:      appv2.AppTypeHelm,
 		Workspace:    helmRepo.GetWorkspace(),
-		Credential:   helmRepo.Spec.Credential,
 		FromRepo:     true,
 	}
 	url := ver.URLs[0]
@@ -26,6 +26,7 @@ import (
 	"k8s.io/apimachinery/pkg/util/sets"
 	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/record"
 	"k8s.io/client-go/util/retry"
 	"k8s.io/klog/v2"
 	ctrl "sigs.k8s.io/controller-runtime"
@@ -45,6 +46,7 @@ import (
 	kscontroller "kubesphere.io/kubesphere/pkg/controller"
 	clusterutils "kubesphere.io/kubesphere/pkg/controller/cluster/utils"
 	"kubesphere.io/kubesphere/pkg/controller/options"
+	"kubesphere.io/kubesphere/pkg/multicluster"
 	"kubesphere.io/kubesphere/pkg/utils/clusterclient"
 	"kubesphere.io/kubesphere/pkg/version"
 )
@@ -114,6 +116,10 @@ type Reconciler struct {
 	clusterUID          types.UID
 	tls                 bool
 	HelmExecutorOptions *options.HelmExecutorOptions
+	healthProbe         *multicluster.HealthProbeOptions
+	recorder            record.EventRecorder
+	// kubeConfigVerifier checks the rotated kubeconfig before it replaces the current one
+	kubeConfigVerifier func(ctx context.Context, kubeConfig []byte, uid types.UID) error
 }
 
 // SetupWithManager setups the Reconciler with manager.
@@ -130,6 +136,9 @@ func (r *Reconciler) SetupWithManager(mgr *kscontroller.Manager) error {
 	r.installLock = &sync.Map{}
 	r.tls = mgr.Options.KubeSphereOptions.TLS
 	r.HelmExecutorOptions = mgr.Options.HelmExecutorOptions
+	r.healthProbe = healthProbeOptions(mgr.MultiClusterOptions)
+	r.recorder = mgr.GetEventRecorderFor(controllerName)
+	r.kubeConfigVerifier = verifyKubeConfig
 	r.Client = mgr.GetClient()
 	if err := mgr.Add(r); err != nil {
 		return fmt.Errorf("unable to add cluster-controller to manager: %v", err)
@@ -265,6 +274,7 @@ func (r *Reconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Resu
 			klog.Errorf("Failed to sync cluster members for %s: %v", req.Name, err)
 			return ctrl.Result{}, err
 		}
+		deleteClusterMetrics(cluster.Name)
 
 		// remove our cluster finalizer
 		finalizers := sets.New(cluster.ObjectMeta.Finalizers...)
@@ -290,6 +300,7 @@ func (r *Reconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Resu
 
 	clusterClient, err := r.clusterClient.GetClusterClient(cluster.Name)
 	if err != nil {
+		r.setProbeResult(cluster, clusterv1alpha1.ClusterKubeAPIServerHealthy, probeResult{reason: "Unreachable", message: err.Error()})
 		return ctrl.Result{}, r.updateClusterReadyCondition(
 			ctx, cluster, fmt.Errorf("failed to get cluster client for %s: %s", cluster.Name, err),
 		)
@@ -298,6 +309,7 @@ func (r *Reconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Resu
 	// Use kube-system namespace UID as cluster ID
 	kubeSystem := &corev1.Namespace{}
 	if err = clusterClient.Client.Get(ctx, client.ObjectKey{Name: metav1.NamespaceSystem}, kubeSystem); err != nil {
+		r.setProbeResult(cluster, clusterv1alpha1.ClusterKubeAPIServerHealthy, probeResult{reason: "Unreachable", message: err.Error()})
 		return ctrl.Result{}, r.updateClusterReadyCondition(
 			ctx, cluster, fmt.Errorf("failed to get kube-system namespace for %s: %s", cluster.Name, err),
 		)
@@ -328,6 +340,9 @@ func (r *Reconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Resu
 		return ctrl.Result{}, fmt.Errorf("failed to sync cluster label for %s: %s", cluster.Name, err)
 	}
 
+	// the health conditions are saved together with the ready condition
+	healthy := r.syncClusterHealth(ctx, cluster, clusterClient)
+
 	if err := r.syncKubeSphereVersion(ctx, cluster); err != nil {
 		return ctrl.Result{}, fmt.Errorf("failed to sync kubesphere version for %s: %s", cluster.Name, err)
 	}
@@ -344,7 +359,7 @@ func (r *Reconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Resu
 		return ctrl.Result{}, fmt.Errorf("failed to sync cluster membership for %s: %s", cluster.Name, err)
 	}
 
-	return ctrl.Result{RequeueAfter: r.resyncPeriod}, nil
+	return ctrl.Result{RequeueAfter: r.probeInterval(healthy)}, nil
 }
 
 // syncClusterLabel syncs label IDs from annotations to the individual Label CRs.
@@ -478,10 +493,14 @@ func (r *Reconciler) reconcileMemberCluster(ctx context.Context, cluster *cluste
 		}
 		return nil
 	}
-	if err := r.updateKubeConfigExpirationDateCondition(ctx, cluster, clusterClient.Client, clusterClient.RestConfig); err != nil {
+	if err := r.updateKubeConfigExpirationDateCondition(cluster, clusterClient.RestConfig); err != nil {
 		// should not block the whole process
 		klog.Warningf("sync KubeConfig expiration date for cluster %s failed: %v", cluster.Name, err)
 	}
+	if err := r.rotateKubeConfig(ctx, cluster, clusterClient.Client, clusterClient.RestConfig); err != nil {
+		// should not block the whole process, the current credential is kept until it expires
+		klog.Warningf("rotate KubeConfig credential for cluster %s failed: %v", cluster.Name, err)
+	}
 	return nil
 }
 
@@ -520,15 +539,7 @@ func (r *Reconciler) tryFetchKubeSphereVersion(ctx context.Context, cluster *clu
 		return "", fmt.Errorf("failed to get cluster client: %s", err)
 	}
 
-	scheme := "http"
-	port := "80"
-	if r.tls {
-		scheme = "https"
-		port = "443"
-	}
-	response, err := clusterClient.KubernetesClient.CoreV1().Services(constants.KubeSphereNamespace).
-		ProxyGet(scheme, constants.KubeSphereAPIServerName, port, "/version", nil).
-		DoRaw(ctx)
+	response, err := r.proxyGetKubeSphereAPIServer(ctx, clusterClient, "/version")
 	if err != nil {
 		return "", err
 	}
@@ -552,6 +563,19 @@ func (r *Reconciler) tryFetchKubeSphereVersion(ctx context.Context, cluster *clu
 	return info.GitVersion, nil
 }
 
+// proxyGetKubeSphereAPIServer requests the ks-apiserver of the cluster through the kube-apiserver service proxy.
+func (r *Reconciler) proxyGetKubeSphereAPIServer(ctx context.Context, clusterClient *clusterclient.ClusterClient, path string) ([]byte, error) {
+	scheme := "http"
+	port := "80"
+	if r.tls {
+		scheme = "https"
+		port = "443"
+	}
+	return clusterClient.KubernetesClient.CoreV1().Services(constants.KubeSphereNamespace).
+		ProxyGet(scheme, constants.KubeSphereAPIServerName, port, path, nil).
+		DoRaw(ctx)
+}
+
 func (r *Reconciler) updateClusterReadyCondition(ctx context.Context, cluster *clusterv1alpha1.Cluster, err error) error {
 	condition := clusterv1alpha1.ClusterCondition{
 		Type:               clusterv1alpha1.ClusterReady,
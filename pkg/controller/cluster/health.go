@@ -0,0 +1,261 @@
+/*
+ * Copyright 2024 the KubeSphere Authors.
+ * Please refer to the LICENSE file in the root directory of the project.
+ * https://github.com/kubesphere/kubesphere/blob/master/LICENSE
+ */
+
+package cluster
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/clientcmd"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	clusterv1alpha1 "kubesphere.io/api/cluster/v1alpha1"
+
+	"kubesphere.io/kubesphere/pkg/multicluster"
+	"kubesphere.io/kubesphere/pkg/utils/clusterclient"
+)
+
+const (
+	probeFailed    = "ProbeFailed"
+	probeRecovered = "ProbeRecovered"
+)
+
+// probeResult is the result of a cluster health probe.
+type probeResult struct {
+	healthy bool
+	reason  string
+	message string
+}
+
+// syncClusterHealth probes the components of the cluster and updates the health conditions,
+// it returns false if any probe failed.
+func (r *Reconciler) syncClusterHealth(ctx context.Context, cluster *clusterv1alpha1.Cluster, clusterClient *clusterclient.ClusterClient) bool {
+	options := r.healthProbe
+	healthy := true
+	setResult := func(conditionType clusterv1alpha1.ClusterConditionType, result *probeResult) {
+		if result != nil {
+			r.setProbeResult(cluster, conditionType, *result)
+			healthy = healthy && result.healthy
+		}
+	}
+
+	// the AgentAvailable condition of the clusters in proxy mode is reported by tower, which maintains the tunnel
+	kubeAPIServer, latency := probeKubeAPIServer(ctx, clusterClient.KubernetesClient, options.KubeAPIServerLatencyThreshold)
+	clusterAPIServerLatency.WithLabelValues(cluster.Name).Set(latency.Seconds())
+	setResult(clusterv1alpha1.ClusterKubeAPIServerHealthy, kubeAPIServer)
+
+	setResult(clusterv1alpha1.ClusterKubeSphereAPIServerHealthy, r.probeKubeSphereAPIServer(ctx, clusterClient))
+
+	nodes, ratio := probeNodes(ctx, clusterClient.Client, options.NodeReadyRatioThreshold)
+	clusterNodeReadyRatio.WithLabelValues(cluster.Name).Set(ratio)
+	setResult(clusterv1alpha1.ClusterNodesReady, nodes)
+
+	// the certs of the clusters in proxy mode are managed by tower, the expiration is reported by
+	// the KubeConfigCertExpiresInSevenDays condition and the credentials are rotated before expiring
+	if cluster.Spec.Connection.Type != clusterv1alpha1.ConnectionTypeProxy {
+		if remaining := kubeConfigCertRemaining(cluster.Spec.Connection.KubeConfig, time.Now()); remaining != nil {
+			clusterCertExpiration.WithLabelValues(cluster.Name).Set(remaining.Seconds())
+			setResult(clusterv1alpha1.ClusterKubeConfigCertValid, probeCertExpiration(*remaining, options.CertExpirationThreshold))
+		}
+	}
+	return healthy
+}
+
+// probeCertExpiration checks the remaining validity of the kubeconfig client certificate against the threshold.
+func probeCertExpiration(remaining, threshold time.Duration) *probeResult {
+	if remaining <= 0 {
+		return &probeResult{reason: "Expired", message: "the kubeconfig client certificate has expired"}
+	}
+	message := fmt.Sprintf("the kubeconfig client certificate expires in %s", remaining.Round(time.Minute))
+	if remaining <= threshold {
+		return &probeResult{reason: "ExpiringSoon", message: fmt.Sprintf("%s, within the threshold %s", message, threshold)}
+	}
+	return &probeResult{healthy: true, reason: "Valid", message: message}
+}
+
+// probeInterval returns the interval of the next health probe, the unhealthy clusters are probed again
+// after the recovery interval.
+func (r *Reconciler) probeInterval(healthy bool) time.Duration {
+	interval := r.resyncPeriod
+	if r.healthProbe.Period > 0 && r.healthProbe.Period < interval {
+		interval = r.healthProbe.Period
+	}
+	if !healthy && r.healthProbe.RecoveryInterval > 0 && r.healthProbe.RecoveryInterval < interval {
+		interval = r.healthProbe.RecoveryInterval
+	}
+	return interval
+}
+
+// setProbeResult updates the condition of the probe, records the metrics and emits an event if the result changed.
+func (r *Reconciler) setProbeResult(cluster *clusterv1alpha1.Cluster, conditionType clusterv1alpha1.ClusterConditionType, result probeResult) {
+	probe := string(conditionType)
+	previous := setHealthCondition(cluster, conditionType, result, r.healthProbe.HistoryLimit, metav1.Now())
+	if result.healthy {
+		clusterProbeHealthy.WithLabelValues(cluster.Name, probe).Set(1)
+		if previous == corev1.ConditionFalse {
+			r.recorder.Eventf(cluster, corev1.EventTypeNormal, probeRecovered, "%s: %s", probe, result.message)
+		}
+		return
+	}
+	clusterProbeHealthy.WithLabelValues(cluster.Name, probe).Set(0)
+	clusterProbeFailures.WithLabelValues(cluster.Name, probe).Inc()
+	if previous != corev1.ConditionFalse {
+		r.recorder.Eventf(cluster, corev1.EventTypeWarning, probeFailed, "%s: %s", probe, result.message)
+	}
+}
+
+// setHealthCondition sets the condition of the probe result, the replaced condition is moved to the history
+// if the status changed. It returns the previous status of the condition.
+func setHealthCondition(cluster *clusterv1alpha1.Cluster, conditionType clusterv1alpha1.ClusterConditionType, result probeResult, historyLimit int, now metav1.Time) corev1.ConditionStatus {
+	condition := clusterv1alpha1.ClusterCondition{
+		Type:               conditionType,
+		Status:             corev1.ConditionFalse,
+		LastUpdateTime:     now,
+		LastTransitionTime: now,
+		Reason:             result.reason,
+		Message:            result.message,
+	}
+	if result.healthy {
+		condition.Status = corev1.ConditionTrue
+	}
+	for i, cond := range cluster.Status.Conditions {
+		if cond.Type != conditionType {
+			continue
+		}
+		if cond.Status == condition.Status {
+			condition.LastTransitionTime = cond.LastTransitionTime
+		} else {
+			cluster.Status.ConditionHistory = appendConditionHistory(cluster.Status.ConditionHistory, cond, historyLimit)
+		}
+		cluster.Status.Conditions[i] = condition
+		return cond.Status
+	}
+	cluster.Status.Conditions = append(cluster.Status.Conditions, condition)
+	return ""
+}
+
+// appendConditionHistory appends the condition to the history, and drops the oldest conditions of the same type
+// beyond the limit.
+func appendConditionHistory(history []clusterv1alpha1.ClusterCondition, condition clusterv1alpha1.ClusterCondition, limit int) []clusterv1alpha1.ClusterCondition {
+	history = append(history, condition)
+	count := 0
+	for _, cond := range history {
+		if cond.Type == condition.Type {
+			count++
+		}
+	}
+	result := make([]clusterv1alpha1.ClusterCondition, 0, len(history))
+	for _, cond := range history {
+		if cond.Type == condition.Type && count > limit {
+			count--
+			continue
+		}
+		result = append(result, cond)
+	}
+	return result
+}
+
+// probeKubeAPIServer checks the readiness of the kube-apiserver.
+func probeKubeAPIServer(ctx context.Context, kubernetesClient kubernetes.Interface, latencyThreshold time.Duration) (*probeResult, time.Duration) {
+	start := time.Now()
+	_, err := kubernetesClient.Discovery().RESTClient().Get().AbsPath("/readyz").DoRaw(ctx)
+	latency := time.Since(start)
+
+	var status apierrors.APIStatus
+	if err != nil && !errors.As(err, &status) {
+		return &probeResult{reason: "Unreachable", message: fmt.Sprintf("failed to connect to the kube-apiserver: %s", err)}, latency
+	}
+	if err != nil {
+		return &probeResult{reason: "NotReady", message: fmt.Sprintf("kube-apiserver is not ready: %s", err)}, latency
+	}
+	if latency > latencyThreshold {
+		return &probeResult{reason: "HighLatency", message: fmt.Sprintf("kube-apiserver responded in %s, exceeding the threshold %s", latency, latencyThreshold)}, latency
+	}
+	return &probeResult{healthy: true, reason: "Ready", message: fmt.Sprintf("kube-apiserver responded in %s", latency)}, latency
+}
+
+func (r *Reconciler) probeKubeSphereAPIServer(ctx context.Context, clusterClient *clusterclient.ClusterClient) *probeResult {
+	if _, err := r.proxyGetKubeSphereAPIServer(ctx, clusterClient, "/healthz"); err != nil {
+		return &probeResult{reason: "Unreachable", message: fmt.Sprintf("failed to connect to the ks-apiserver: %s", err)}
+	}
+	return &probeResult{healthy: true, reason: "Ready", message: "ks-apiserver is available"}
+}
+
+// probeNodes checks the ratio of the ready nodes.
+func probeNodes(ctx context.Context, c client.Client, threshold float64) (*probeResult, float64) {
+	nodes := &corev1.NodeList{}
+	if err := c.List(ctx, nodes); err != nil {
+		return &probeResult{reason: "Unknown", message: fmt.Sprintf("failed to list nodes: %s", err)}, 0
+	}
+	if len(nodes.Items) == 0 {
+		return &probeResult{reason: "NoNodes", message: "no nodes found"}, 0
+	}
+	ready := 0
+	for _, node := range nodes.Items {
+		for _, condition := range node.Status.Conditions {
+			if condition.Type == corev1.NodeReady && condition.Status == corev1.ConditionTrue {
+				ready++
+				break
+			}
+		}
+	}
+	ratio := float64(ready) / float64(len(nodes.Items))
+	message := fmt.Sprintf("%d of %d nodes are ready", ready, len(nodes.Items))
+	if ratio < threshold {
+		return &probeResult{reason: "NodesNotReady", message: message}, ratio
+	}
+	return &probeResult{healthy: true, reason: "NodesReady", message: message}, ratio
+}
+
+// kubeConfigCertRemaining returns the remaining validity of the client certificate in the kubeconfig,
+// nil if the kubeconfig does not use a valid client certificate.
+func kubeConfigCertRemaining(kubeConfig []byte, now time.Time) *time.Duration {
+	config, err := clientcmd.RESTConfigFromKubeConfig(kubeConfig)
+	if err != nil {
+		return nil
+	}
+	cert, err := parseKubeConfigCert(config)
+	if err != nil || cert == nil || cert.NotAfter.IsZero() {
+		return nil
+	}
+	remaining := cert.NotAfter.Sub(now)
+	return &remaining
+}
+
+// healthProbeOptions returns the health probe options, the defaults are used for the fields which are not configured.
+func healthProbeOptions(options *multicluster.Options) *multicluster.HealthProbeOptions {
+	defaults := multicluster.NewHealthProbeOptions()
+	if options == nil || options.HealthProbe == nil {
+		return defaults
+	}
+	result := *options.HealthProbe
+	if result.Period <= 0 {
+		result.Period = defaults.Period
+	}
+	if result.RecoveryInterval <= 0 {
+		result.RecoveryInterval = defaults.RecoveryInterval
+	}
+	if result.KubeAPIServerLatencyThreshold <= 0 {
+		result.KubeAPIServerLatencyThreshold = defaults.KubeAPIServerLatencyThreshold
+	}
+	if result.NodeReadyRatioThreshold <= 0 {
+		result.NodeReadyRatioThreshold = defaults.NodeReadyRatioThreshold
+	}
+	if result.CertExpirationThreshold <= 0 {
+		result.CertExpirationThreshold = defaults.CertExpirationThreshold
+	}
+	if result.HistoryLimit <= 0 {
+		result.HistoryLimit = defaults.HistoryLimit
+	}
+	return &result
+}
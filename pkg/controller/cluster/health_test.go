@@ -0,0 +1,178 @@
+/*
+ * Copyright 2024 the KubeSphere Authors.
+ * Please refer to the LICENSE file in the root directory of the project.
+ * https://github.com/kubesphere/kubesphere/blob/master/LICENSE
+ */
+
+package cluster
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	clusterv1alpha1 "kubesphere.io/api/cluster/v1alpha1"
+
+	"kubesphere.io/kubesphere/pkg/multicluster"
+	"kubesphere.io/kubesphere/pkg/scheme"
+)
+
+func TestSetHealthCondition(t *testing.T) {
+	cluster := &clusterv1alpha1.Cluster{}
+	start := metav1.NewTime(time.Now().Add(-time.Hour))
+
+	previous := setHealthCondition(cluster, clusterv1alpha1.ClusterNodesReady, probeResult{healthy: true}, 2, start)
+	assert.Equal(t, corev1.ConditionStatus(""), previous)
+	assert.Len(t, cluster.Status.Conditions, 1)
+
+	// the transition time is kept if the status is not changed
+	previous = setHealthCondition(cluster, clusterv1alpha1.ClusterNodesReady, probeResult{healthy: true}, 2, metav1.Now())
+	assert.Equal(t, corev1.ConditionTrue, previous)
+	assert.Equal(t, start, cluster.Status.Conditions[0].LastTransitionTime)
+	assert.Empty(t, cluster.Status.ConditionHistory)
+
+	for i := 0; i < 3; i++ {
+		setHealthCondition(cluster, clusterv1alpha1.ClusterNodesReady, probeResult{healthy: i%2 == 1, reason: "NodesNotReady"}, 2, metav1.Now())
+	}
+	setHealthCondition(cluster, clusterv1alpha1.ClusterKubeAPIServerHealthy, probeResult{healthy: true}, 2, metav1.Now())
+	setHealthCondition(cluster, clusterv1alpha1.ClusterKubeAPIServerHealthy, probeResult{}, 2, metav1.Now())
+	assert.Len(t, cluster.Status.Conditions, 2)
+	assert.Equal(t, corev1.ConditionFalse, cluster.Status.Conditions[0].Status)
+	// at most two conditions are kept for each type
+	assert.Len(t, cluster.Status.ConditionHistory, 3)
+	assert.Equal(t, clusterv1alpha1.ClusterNodesReady, cluster.Status.ConditionHistory[0].Type)
+	assert.Equal(t, corev1.ConditionFalse, cluster.Status.ConditionHistory[0].Status)
+	assert.Equal(t, corev1.ConditionTrue, cluster.Status.ConditionHistory[1].Status)
+	assert.Equal(t, clusterv1alpha1.ClusterKubeAPIServerHealthy, cluster.Status.ConditionHistory[2].Type)
+}
+
+func TestProbeNodes(t *testing.T) {
+	node := func(name string, status corev1.ConditionStatus) *corev1.Node {
+		return &corev1.Node{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Status:     corev1.NodeStatus{Conditions: []corev1.NodeCondition{{Type: corev1.NodeReady, Status: status}}},
+		}
+	}
+	c := fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(
+		node("node1", corev1.ConditionTrue),
+		node("node2", corev1.ConditionTrue),
+		node("node3", corev1.ConditionTrue),
+		node("node4", corev1.ConditionFalse),
+	).Build()
+
+	result, ratio := probeNodes(context.Background(), c, 0.8)
+	assert.False(t, result.healthy)
+	assert.Equal(t, 0.75, ratio)
+	result, _ = probeNodes(context.Background(), c, 0.5)
+	assert.True(t, result.healthy)
+}
+
+func TestKubeConfigCertRemaining(t *testing.T) {
+	// the validity of the certificates is truncated to seconds
+	now := time.Now().Truncate(time.Second)
+	kubeConfig := func(notAfter time.Time) []byte {
+		key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		assert.NoError(t, err)
+		template := &x509.Certificate{
+			SerialNumber: big.NewInt(1),
+			Subject:      pkix.Name{CommonName: "admin"},
+			NotBefore:    now.Add(-time.Hour),
+			NotAfter:     notAfter,
+		}
+		der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+		assert.NoError(t, err)
+		keyDer, err := x509.MarshalECPrivateKey(key)
+		assert.NoError(t, err)
+		config := clientcmdapi.NewConfig()
+		config.Clusters["cluster"] = &clientcmdapi.Cluster{Server: "https://127.0.0.1:6443"}
+		config.AuthInfos["admin"] = &clientcmdapi.AuthInfo{
+			ClientCertificateData: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+			ClientKeyData:         pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}),
+		}
+		config.Contexts["admin@cluster"] = &clientcmdapi.Context{Cluster: "cluster", AuthInfo: "admin"}
+		config.CurrentContext = "admin@cluster"
+		data, err := clientcmd.Write(*config)
+		assert.NoError(t, err)
+		return data
+	}
+
+	remaining := kubeConfigCertRemaining(kubeConfig(now.Add(24*time.Hour)), now)
+	assert.Equal(t, 24*time.Hour, *remaining)
+	remaining = kubeConfigCertRemaining(kubeConfig(now.Add(-time.Minute)), now)
+	assert.Equal(t, -time.Minute, *remaining)
+	assert.Nil(t, kubeConfigCertRemaining([]byte("invalid"), now))
+}
+
+func TestHealthProbeOptions(t *testing.T) {
+	defaults := multicluster.NewHealthProbeOptions()
+	assert.Equal(t, defaults, healthProbeOptions(nil))
+
+	// the fields which are not configured are defaulted separately
+	options := healthProbeOptions(&multicluster.Options{HealthProbe: &multicluster.HealthProbeOptions{Period: time.Minute}})
+	assert.Equal(t, time.Minute, options.Period)
+	assert.Equal(t, defaults.KubeAPIServerLatencyThreshold, options.KubeAPIServerLatencyThreshold)
+	assert.Equal(t, defaults.CertExpirationThreshold, options.CertExpirationThreshold)
+	assert.Equal(t, defaults.NodeReadyRatioThreshold, options.NodeReadyRatioThreshold)
+	assert.Equal(t, defaults.RecoveryInterval, options.RecoveryInterval)
+	assert.Equal(t, defaults.HistoryLimit, options.HistoryLimit)
+
+	r := &Reconciler{resyncPeriod: time.Hour, healthProbe: options}
+	assert.Equal(t, time.Minute, r.probeInterval(true))
+	// the unhealthy clusters are probed again after the recovery interval
+	assert.Equal(t, defaults.RecoveryInterval, r.probeInterval(false))
+}
+
+func TestProbeKubeAPIServer(t *testing.T) {
+	ready := true
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !ready {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		_, _ = w.Write([]byte("ok"))
+	}))
+	kubernetesClient, err := kubernetes.NewForConfig(&rest.Config{Host: server.URL})
+	assert.NoError(t, err)
+	ctx := context.Background()
+
+	kubeAPIServer, _ := probeKubeAPIServer(ctx, kubernetesClient, time.Minute)
+	assert.True(t, kubeAPIServer.healthy)
+
+	ready = false
+	kubeAPIServer, _ = probeKubeAPIServer(ctx, kubernetesClient, time.Minute)
+	assert.Equal(t, "NotReady", kubeAPIServer.reason)
+
+	server.Close()
+	kubeAPIServer, _ = probeKubeAPIServer(ctx, kubernetesClient, time.Minute)
+	assert.Equal(t, "Unreachable", kubeAPIServer.reason)
+}
+
+func TestProbeCertExpiration(t *testing.T) {
+	threshold := 7 * 24 * time.Hour
+	result := probeCertExpiration(30*24*time.Hour, threshold)
+	assert.True(t, result.healthy)
+	result = probeCertExpiration(24*time.Hour, threshold)
+	assert.False(t, result.healthy)
+	assert.Equal(t, "ExpiringSoon", result.reason)
+	result = probeCertExpiration(-time.Hour, threshold)
+	assert.False(t, result.healthy)
+	assert.Equal(t, "Expired", result.reason)
+}
@@ -11,22 +11,28 @@ import (
 	"context"
 	"crypto/rand"
 	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
 	"encoding/pem"
 	"fmt"
 	"os"
+	"strings"
 	"time"
 
+	authenticationv1 "k8s.io/api/authentication/v1"
 	certificatesv1 "k8s.io/api/certificates/v1"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/apiserver/pkg/authentication/user"
+	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/clientcmd"
 	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
 	certutil "k8s.io/client-go/util/cert"
 	"k8s.io/klog/v2"
+	"k8s.io/utils/ptr"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	clusterv1alpha1 "kubesphere.io/api/cluster/v1alpha1"
@@ -36,9 +42,16 @@ import (
 	"kubesphere.io/kubesphere/pkg/utils/pkiutil"
 )
 
-func (r *Reconciler) updateKubeConfigExpirationDateCondition(
-	ctx context.Context, cluster *clusterv1alpha1.Cluster, clusterClient client.Client, config *rest.Config,
-) error {
+const (
+	credentialRotated        = "CredentialRotated"
+	credentialRotationFailed = "CredentialRotationFailed"
+	// kubeSphereServiceAccountName is used if the service account token secret of ks-core is not found
+	kubeSphereServiceAccountName = "kubesphere"
+	// kubeConfigTokenExpiration is the requested validity of the minted service account tokens
+	kubeConfigTokenExpiration = 365 * 24 * time.Hour
+)
+
+func (r *Reconciler) updateKubeConfigExpirationDateCondition(cluster *clusterv1alpha1.Cluster, config *rest.Config) error {
 	// we don't need to check member clusters which using proxy mode, their certs are managed and will be renewed by tower.
 	if cluster.Spec.Connection.Type == clusterv1alpha1.ConnectionTypeProxy {
 		return nil
@@ -61,12 +74,6 @@ func (r *Reconciler) updateKubeConfigExpirationDateCondition(
 		cluster.Status.Conditions = conditions
 		return nil
 	}
-	seconds := time.Until(cert.NotAfter).Seconds()
-	if seconds/86400 <= 7 {
-		if err = r.renewKubeConfig(ctx, cluster, clusterClient, config, cert); err != nil {
-			return err
-		}
-	}
 
 	r.updateClusterCondition(cluster, clusterv1alpha1.ClusterCondition{
 		Type:               clusterv1alpha1.ClusterKubeConfigCertExpiresInSevenDays,
@@ -93,47 +100,170 @@ func parseKubeConfigCert(config *rest.Config) (*x509.Certificate, error) {
 	return cert, nil
 }
 
-func (r *Reconciler) renewKubeConfig(
-	ctx context.Context, cluster *clusterv1alpha1.Cluster, clusterClient client.Client, config *rest.Config, cert *x509.Certificate,
+// kubeConfigCredential is the credential of the current context in the kubeconfig.
+type kubeConfigCredential struct {
+	credentialType clusterv1alpha1.CredentialType
+	username       string
+	// expiration is nil if the credential does not expire or can not be rotated
+	expiration *time.Time
+	// the certificates of the system:masters group can not be issued by CSR
+	privileged bool
+}
+
+func parseKubeConfigCredential(kubeConfig []byte) (*clientcmdapi.Config, *kubeConfigCredential, error) {
+	apiConfig, err := clientcmd.Load(kubeConfig)
+	if err != nil {
+		return nil, nil, err
+	}
+	currentContext, ok := apiConfig.Contexts[apiConfig.CurrentContext]
+	if !ok {
+		return nil, nil, fmt.Errorf("current context %s not found", apiConfig.CurrentContext)
+	}
+	authInfo, ok := apiConfig.AuthInfos[currentContext.AuthInfo]
+	if !ok {
+		return nil, nil, fmt.Errorf("user %s not found", currentContext.AuthInfo)
+	}
+	credential := &kubeConfigCredential{username: currentContext.AuthInfo}
+	switch {
+	case authInfo.Token != "":
+		credential.credentialType = clusterv1alpha1.CredentialTypeServiceAccountToken
+		credential.expiration = tokenExpiration(authInfo.Token)
+	case len(authInfo.ClientCertificateData) > 0:
+		credential.credentialType = clusterv1alpha1.CredentialTypeClientCertificate
+		block, _ := pem.Decode(authInfo.ClientCertificateData)
+		if block == nil {
+			return nil, nil, fmt.Errorf("pem.Decode failed, got empty block data")
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, nil, err
+		}
+		if !cert.NotAfter.IsZero() {
+			credential.expiration = &cert.NotAfter
+		}
+		for _, v := range cert.Subject.Organization {
+			if v == user.SystemPrivilegedGroup {
+				credential.privileged = true
+			}
+		}
+	}
+	return apiConfig, credential, nil
+}
+
+// tokenExpiration returns the expiration of the JWT token, nil if the token does not expire.
+func tokenExpiration(token string) *time.Time {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil
+	}
+	claims := &struct {
+		Expiration *int64 `json:"exp"`
+	}{}
+	if err = json.Unmarshal(payload, claims); err != nil || claims.Expiration == nil {
+		return nil
+	}
+	expiration := time.Unix(*claims.Expiration, 0)
+	return &expiration
+}
+
+// rotateKubeConfig mints a new credential in the member cluster with the current credential before it expires,
+// the kubeconfig is replaced only after the new credential is verified.
+func (r *Reconciler) rotateKubeConfig(
+	ctx context.Context, cluster *clusterv1alpha1.Cluster, clusterClient client.Client, config *rest.Config,
 ) error {
-	apiConfig, err := clientcmd.Load(cluster.Spec.Connection.KubeConfig)
+	// the certs of the clusters in proxy mode are managed and will be renewed by tower.
+	if cluster.Spec.Connection.Type == clusterv1alpha1.ConnectionTypeProxy {
+		return nil
+	}
+	apiConfig, credential, err := parseKubeConfigCredential(cluster.Spec.Connection.KubeConfig)
 	if err != nil {
 		return err
 	}
-	currentContext := apiConfig.Contexts[apiConfig.CurrentContext]
-	username := currentContext.AuthInfo
-	authInfo := apiConfig.AuthInfos[username]
-	if authInfo.Token != "" {
+	if credential.expiration == nil {
+		cluster.Status.CredentialRotation = nil
+		return nil
+	}
+	rotation := cluster.Status.CredentialRotation
+	if rotation == nil {
+		rotation = &clusterv1alpha1.CredentialRotationStatus{}
+		cluster.Status.CredentialRotation = rotation
+	}
+	rotation.Type = credential.credentialType
+	rotation.ExpirationTime = &metav1.Time{Time: *credential.expiration}
+	if time.Until(*credential.expiration) > r.healthProbe.CertExpirationThreshold {
 		return nil
 	}
 
-	for _, v := range cert.Subject.Organization {
-		// we cannot update the certificate of the system:masters group and will use the certificate of the admin user directly
-		// certificatesigningrequests.certificates.k8s.io is forbidden:
-		// use of kubernetes.io/kube-apiserver-client signer with system:masters group is not allowed
-		//
-		// for cases where we can't issue a certificate, we use the token of the kubesphere service account directly
-		if v == user.SystemPrivilegedGroup {
-			data, err := setKubeSphereSAToken(ctx, clusterClient, apiConfig, username)
-			if err != nil {
-				return err
-			}
-			cluster.Spec.Connection.KubeConfig = data
-			return nil
-		}
+	klog.Infof("rotating the kubeconfig credential of cluster %s, expires at %s", cluster.Name, credential.expiration)
+	now := metav1.Now()
+	rotation.LastAttemptTime = &now
+	var kubeConfig []byte
+	if credential.credentialType == clusterv1alpha1.CredentialTypeClientCertificate && !credential.privileged {
+		kubeConfig, err = genKubeConfig(ctx, clusterClient, config, credential.username)
+	} else {
+		kubeConfig, err = mintServiceAccountToken(ctx, clusterClient, apiConfig, credential.username)
+	}
+	if err == nil {
+		err = r.kubeConfigVerifier(ctx, kubeConfig, cluster.Status.UID)
+	}
+	var rotated *kubeConfigCredential
+	if err == nil {
+		_, rotated, err = parseKubeConfigCredential(kubeConfig)
+	}
+	if err != nil {
+		rotation.Phase = clusterv1alpha1.CredentialRotationFailed
+		rotation.Message = err.Error()
+		r.recorder.Eventf(cluster, corev1.EventTypeWarning, credentialRotationFailed, "failed to rotate kubeconfig credential: %s", err)
+		return err
+	}
+
+	// the kubeconfig and the rotation status are swapped in a single update
+	cluster.Spec.Connection.KubeConfig = kubeConfig
+	rotation.Type = rotated.credentialType
+	rotation.ExpirationTime = nil
+	if rotated.expiration != nil {
+		rotation.ExpirationTime = &metav1.Time{Time: *rotated.expiration}
+	}
+	rotation.Phase = clusterv1alpha1.CredentialRotationSucceeded
+	rotation.LastRotationTime = &now
+	rotation.Message = ""
+	if err = r.Update(ctx, cluster); err != nil {
+		return fmt.Errorf("failed to update kubeconfig: %s", err)
 	}
+	r.recorder.Eventf(cluster, corev1.EventTypeNormal, credentialRotated, "kubeconfig credential rotated, type: %s", rotation.Type)
+	return nil
+}
 
-	kubeconfig, err := genKubeConfig(ctx, clusterClient, config, username)
+// verifyKubeConfig checks whether the kubeconfig is able to access the same cluster.
+func verifyKubeConfig(ctx context.Context, kubeConfig []byte, uid types.UID) error {
+	config, err := clientcmd.RESTConfigFromKubeConfig(kubeConfig)
 	if err != nil {
 		return err
 	}
-	cluster.Spec.Connection.KubeConfig = kubeconfig
+	config.Timeout = 10 * time.Second
+	kubernetesClient, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		return err
+	}
+	kubeSystem, err := kubernetesClient.CoreV1().Namespaces().Get(ctx, metav1.NamespaceSystem, metav1.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to verify the new credential: %s", err)
+	}
+	if kubeSystem.UID != uid {
+		return fmt.Errorf("the new credential corresponds to a different cluster")
+	}
 	return nil
 }
 
-func setKubeSphereSAToken(
+// mintServiceAccountToken requests a token of the kubesphere service account.
+func mintServiceAccountToken(
 	ctx context.Context, clusterClient client.Client, apiConfig *clientcmdapi.Config, username string,
 ) ([]byte, error) {
+	serviceAccount := &corev1.ServiceAccount{ObjectMeta: metav1.ObjectMeta{Namespace: constants.KubeSphereNamespace, Name: kubeSphereServiceAccountName}}
 	secrets := &corev1.SecretList{}
 	if err := clusterClient.List(ctx, secrets,
 		client.InNamespace(constants.KubeSphereNamespace),
@@ -141,26 +271,26 @@ func setKubeSphereSAToken(
 	); err != nil {
 		return nil, err
 	}
-	var secret *corev1.Secret
-	for i, item := range secrets.Items {
-		if item.Type == corev1.SecretTypeServiceAccountToken {
-			secret = &secrets.Items[i]
+	for _, item := range secrets.Items {
+		if item.Type == corev1.SecretTypeServiceAccountToken && item.Annotations[corev1.ServiceAccountNameKey] != "" {
+			serviceAccount.Name = item.Annotations[corev1.ServiceAccountNameKey]
 			break
 		}
 	}
-	if secret == nil {
-		return nil, fmt.Errorf("no kubesphere ServiceAccount secret found")
+
+	tokenRequest := &authenticationv1.TokenRequest{
+		Spec: authenticationv1.TokenRequestSpec{ExpirationSeconds: ptr.To(int64(kubeConfigTokenExpiration.Seconds()))},
+	}
+	if err := clusterClient.SubResource("token").Create(ctx, serviceAccount, tokenRequest); err != nil {
+		return nil, fmt.Errorf("failed to request token for service account %s: %s", serviceAccount.Name, err)
 	}
+	apiConfig = apiConfig.DeepCopy()
 	apiConfig.AuthInfos = map[string]*clientcmdapi.AuthInfo{
 		username: {
-			Token: string(secret.Data["token"]),
+			Token: tokenRequest.Status.Token,
 		},
 	}
-	data, err := clientcmd.Write(*apiConfig)
-	if err != nil {
-		return nil, err
-	}
-	return data, nil
+	return clientcmd.Write(*apiConfig)
 }
 
 func genKubeConfig(ctx context.Context, clusterClient client.Client, clusterConfig *rest.Config, username string) ([]byte, error) {
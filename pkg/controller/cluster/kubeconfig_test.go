@@ -0,0 +1,106 @@
+/*
+ * Copyright 2024 the KubeSphere Authors.
+ * Please refer to the LICENSE file in the root directory of the project.
+ * https://github.com/kubesphere/kubesphere/blob/master/LICENSE
+ */
+
+package cluster
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	clusterv1alpha1 "kubesphere.io/api/cluster/v1alpha1"
+
+	"kubesphere.io/kubesphere/pkg/constants"
+	"kubesphere.io/kubesphere/pkg/multicluster"
+	"kubesphere.io/kubesphere/pkg/scheme"
+)
+
+func fakeToken(expiration time.Time) string {
+	payload := base64.RawURLEncoding.EncodeToString([]byte(fmt.Sprintf(`{"exp":%d}`, expiration.Unix())))
+	return fmt.Sprintf("header.%s.signature", payload)
+}
+
+func tokenKubeConfig(t *testing.T, token string) []byte {
+	config := clientcmdapi.NewConfig()
+	config.Clusters["cluster"] = &clientcmdapi.Cluster{Server: "https://127.0.0.1:6443"}
+	config.AuthInfos["kubesphere"] = &clientcmdapi.AuthInfo{Token: token}
+	config.Contexts["kubesphere@cluster"] = &clientcmdapi.Context{Cluster: "cluster", AuthInfo: "kubesphere"}
+	config.CurrentContext = "kubesphere@cluster"
+	data, err := clientcmd.Write(*config)
+	assert.NoError(t, err)
+	return data
+}
+
+func TestTokenExpiration(t *testing.T) {
+	expiration := time.Unix(time.Now().Add(time.Hour).Unix(), 0)
+	assert.Equal(t, expiration, *tokenExpiration(fakeToken(expiration)))
+	assert.Nil(t, tokenExpiration("opaque-token"))
+}
+
+func TestRotateKubeConfig(t *testing.T) {
+	cluster := &clusterv1alpha1.Cluster{
+		ObjectMeta: metav1.ObjectMeta{Name: "member"},
+		Spec: clusterv1alpha1.ClusterSpec{Connection: clusterv1alpha1.Connection{
+			Type:       clusterv1alpha1.ConnectionTypeDirect,
+			KubeConfig: tokenKubeConfig(t, fakeToken(time.Now().Add(24*time.Hour))),
+		}},
+		Status: clusterv1alpha1.ClusterStatus{UID: "uid"},
+	}
+	hostClient := fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(cluster).Build()
+	memberClient := fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(
+		&corev1.ServiceAccount{ObjectMeta: metav1.ObjectMeta{Namespace: constants.KubeSphereNamespace, Name: "kubesphere"}},
+	).Build()
+	verifyErr := fmt.Errorf("unauthorized")
+	r := &Reconciler{
+		Client:      hostClient,
+		recorder:    record.NewFakeRecorder(10),
+		healthProbe: multicluster.NewHealthProbeOptions(),
+		kubeConfigVerifier: func(_ context.Context, _ []byte, uid types.UID) error {
+			assert.Equal(t, types.UID("uid"), uid)
+			return verifyErr
+		},
+	}
+	ctx := context.Background()
+	current := cluster.Spec.Connection.KubeConfig
+
+	// the current kubeconfig is kept if the new credential can not be verified
+	assert.Error(t, r.rotateKubeConfig(ctx, cluster, memberClient, &rest.Config{}))
+	assert.Equal(t, current, cluster.Spec.Connection.KubeConfig)
+	assert.Equal(t, clusterv1alpha1.CredentialRotationFailed, cluster.Status.CredentialRotation.Phase)
+	assert.Nil(t, cluster.Status.CredentialRotation.LastRotationTime)
+
+	verifyErr = nil
+	assert.NoError(t, r.rotateKubeConfig(ctx, cluster, memberClient, &rest.Config{}))
+	updated := &clusterv1alpha1.Cluster{}
+	assert.NoError(t, hostClient.Get(ctx, types.NamespacedName{Name: "member"}, updated))
+	rotation := updated.Status.CredentialRotation
+	assert.Equal(t, clusterv1alpha1.CredentialRotationSucceeded, rotation.Phase)
+	assert.Equal(t, clusterv1alpha1.CredentialTypeServiceAccountToken, rotation.Type)
+	assert.NotNil(t, rotation.LastRotationTime)
+	apiConfig, err := clientcmd.Load(updated.Spec.Connection.KubeConfig)
+	assert.NoError(t, err)
+	assert.Equal(t, "fake-token", apiConfig.AuthInfos["kubesphere"].Token)
+	assert.Equal(t, "https://127.0.0.1:6443", apiConfig.Clusters["cluster"].Server)
+
+	// the credential is not rotated before the threshold
+	cluster = updated.DeepCopy()
+	cluster.Spec.Connection.KubeConfig = tokenKubeConfig(t, fakeToken(time.Now().Add(30*24*time.Hour)))
+	assert.NoError(t, r.rotateKubeConfig(ctx, cluster, memberClient, &rest.Config{}))
+	assert.Equal(t, rotation.LastRotationTime.Unix(), cluster.Status.CredentialRotation.LastRotationTime.Unix())
+	assert.True(t, cluster.Status.CredentialRotation.ExpirationTime.After(time.Now().Add(29*24*time.Hour)))
+}
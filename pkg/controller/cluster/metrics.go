@@ -0,0 +1,64 @@
+/*
+ * Copyright 2024 the KubeSphere Authors.
+ * Please refer to the LICENSE file in the root directory of the project.
+ * https://github.com/kubesphere/kubesphere/blob/master/LICENSE
+ */
+
+package cluster
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+var (
+	clusterProbeFailures = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "ks_controller_manager_cluster_probe_failures_total",
+			Help: "Counter of the failed cluster health probes broken out for each cluster, probe",
+		},
+		[]string{"cluster", "probe"},
+	)
+	clusterProbeHealthy = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "ks_controller_manager_cluster_probe_healthy",
+			Help: "Result of the last cluster health probe, 1 if healthy, 0 otherwise",
+		},
+		[]string{"cluster", "probe"},
+	)
+	clusterAPIServerLatency = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "ks_controller_manager_cluster_apiserver_latency_seconds",
+			Help: "Latency of the last kube-apiserver readiness check of the cluster",
+		},
+		[]string{"cluster"},
+	)
+	clusterNodeReadyRatio = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "ks_controller_manager_cluster_node_ready_ratio",
+			Help: "Ratio of the ready nodes of the cluster",
+		},
+		[]string{"cluster"},
+	)
+	clusterCertExpiration = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "ks_controller_manager_cluster_kubeconfig_cert_expiration_seconds",
+			Help: "Remaining validity of the client certificate in the kubeconfig of the cluster",
+		},
+		[]string{"cluster"},
+	)
+)
+
+func init() {
+	metrics.Registry.MustRegister(clusterProbeFailures, clusterProbeHealthy, clusterAPIServerLatency, clusterNodeReadyRatio, clusterCertExpiration)
+}
+
+// deleteClusterMetrics deletes the metrics of the deleted cluster.
+func deleteClusterMetrics(cluster string) {
+	labels := prometheus.Labels{"cluster": cluster}
+	clusterProbeFailures.DeletePartialMatch(labels)
+	clusterProbeHealthy.DeletePartialMatch(labels)
+	clusterAPIServerLatency.DeletePartialMatch(labels)
+	clusterNodeReadyRatio.DeletePartialMatch(labels)
+	clusterCertExpiration.DeletePartialMatch(labels)
+}
@@ -18,7 +18,6 @@ import (
 
 	"github.com/go-logr/logr"
 	"golang.org/x/exp/slices"
-	"helm.sh/helm/v3/pkg/chart"
 	"helm.sh/helm/v3/pkg/chart/loader"
 	helmrelease "helm.sh/helm/v3/pkg/release"
 	batchv1 "k8s.io/api/batch/v1"
@@ -29,6 +28,7 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/sets"
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/record"
 	"k8s.io/client-go/util/retry"
@@ -54,39 +54,30 @@ import (
 	clusterpredicate "kubesphere.io/kubesphere/pkg/controller/cluster/predicate"
 	clusterutils "kubesphere.io/kubesphere/pkg/controller/cluster/utils"
 	"kubesphere.io/kubesphere/pkg/controller/options"
+	"kubesphere.io/kubesphere/pkg/models/extension"
 	"kubesphere.io/kubesphere/pkg/utils/clusterclient"
 	"kubesphere.io/kubesphere/pkg/utils/hashutil"
-	"kubesphere.io/kubesphere/pkg/utils/sliceutil"
 )
 
 const (
-	installPlanController              = "installplan"
-	installPlanProtection              = "kubesphere.io/installplan-protection"
-	systemWorkspace                    = "system-workspace"
-	agentReleaseFormat                 = "%s-agent"
-	defaultRoleFormat                  = "kubesphere:%s:helm-executor"
-	defaultRoleBindingFormat           = defaultRoleFormat
-	defaultClusterRoleFormat           = "kubesphere:%s:helm-executor"
-	permissionDefinitionFile           = "permissions.yaml"
-	defaultClusterRoleBindingFormat    = defaultClusterRoleFormat
-	tagAgent                           = "agent"
-	tagExtension                       = "extension"
-	upgradeSuccessful                  = "UpgradeSuccessful"
-	upgradeFailed                      = "UpgradeFailed"
-	installSuccessful                  = "InstallSuccessful"
-	installFailed                      = "InstallFailed"
-	initialized                        = "Initialized"
-	uninstallFailed                    = "UninstallFailed"
-	typeHelmRelease                    = "helm.sh/release.v1"
-	globalExtensionIngressClassName    = "global.extension.ingress.ingressClassName"
-	globalExtensionIngressDomainSuffix = "global.extension.ingress.domainSuffix"
-	globalExtensionIngressHTTPPort     = "global.extension.ingress.httpPort"
-	globalExtensionIngressHTTPSPort    = "global.extension.ingress.httpsPort"
-	globalNodeSelector                 = "global.nodeSelector"
-	globalImageRegistry                = "global.imageRegistry"
-	globalClusterName                  = "global.clusterInfo.name"
-	globalClusterRole                  = "global.clusterInfo.role"
-	globalPortalURL                    = "global.portal.url"
+	installPlanController           = "installplan"
+	installPlanProtection           = "kubesphere.io/installplan-protection"
+	systemWorkspace                 = "system-workspace"
+	agentReleaseFormat              = "%s-agent"
+	defaultRoleFormat               = "kubesphere:%s:helm-executor"
+	defaultRoleBindingFormat        = defaultRoleFormat
+	defaultClusterRoleFormat        = "kubesphere:%s:helm-executor"
+	permissionDefinitionFile        = "permissions.yaml"
+	defaultClusterRoleBindingFormat = defaultClusterRoleFormat
+	tagAgent                        = extension.TagAgent
+	tagExtension                    = extension.TagExtension
+	upgradeSuccessful               = "UpgradeSuccessful"
+	upgradeFailed                   = "UpgradeFailed"
+	installSuccessful               = "InstallSuccessful"
+	installFailed                   = "InstallFailed"
+	initialized                     = "Initialized"
+	uninstallFailed                 = "UninstallFailed"
+	typeHelmRelease                 = "helm.sh/release.v1"
 )
 
 var _ kscontroller.Controller = &InstallPlanReconciler{}
@@ -107,7 +98,7 @@ type InstallPlanReconciler struct {
 	logger              logr.Logger
 	PortalURL           string
 	HelmExecutorOptions *options.HelmExecutorOptions
-	ExtensionOptions    *options.ExtensionOptions
+	ExtensionOptions    *extension.Options
 	hostResetConfig     *rest.Config
 	clusterClientSet    clusterclient.Interface
 }
@@ -285,10 +276,14 @@ func (r *InstallPlanReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 
 	// Multi-cluster installation
 	if plan.Spec.ClusterScheduling != nil {
-		if err := r.syncClusterSchedulingStatus(ctx, plan); err != nil {
+		requeueAfter, err := r.syncClusterSchedulingStatus(ctx, plan)
+		if err != nil {
 			logger.Error(err, "failed to sync scheduling status")
 			return ctrl.Result{}, fmt.Errorf("failed to sync scheduling status: %v", err)
 		}
+		if requeueAfter > 0 {
+			return ctrl.Result{RequeueAfter: requeueAfter}, nil
+		}
 	}
 
 	logger.V(4).Info("Successfully synced")
@@ -895,10 +890,10 @@ func (r *InstallPlanReconciler) syncExtensionStatus(ctx context.Context, plan *c
 	return nil
 }
 
-func (r *InstallPlanReconciler) syncClusterSchedulingStatus(ctx context.Context, plan *corev1alpha1.InstallPlan) error {
+func (r *InstallPlanReconciler) syncClusterSchedulingStatus(ctx context.Context, plan *corev1alpha1.InstallPlan) (time.Duration, error) {
 	logger := klog.FromContext(ctx)
 	if plan.Status.State != corev1alpha1.StateDeployed {
-		return nil
+		return 0, nil
 	}
 	// extension is already installed
 	var targetClusters []clusterv1alpha1.Cluster
@@ -910,7 +905,7 @@ func (r *InstallPlanReconciler) syncClusterSchedulingStatus(ctx context.Context,
 					logger.V(4).Info("cluster not found")
 					continue
 				}
-				return err
+				return 0, err
 			}
 			targetClusters = append(targetClusters, cluster)
 		}
@@ -918,29 +913,45 @@ func (r *InstallPlanReconciler) syncClusterSchedulingStatus(ctx context.Context,
 		clusterList := &clusterv1alpha1.ClusterList{}
 		selector, err := metav1.LabelSelectorAsSelector(plan.Spec.ClusterScheduling.Placement.ClusterSelector)
 		if err != nil {
-			return err
+			return 0, err
 		}
 		if err := r.List(ctx, clusterList, client.MatchingLabelsSelector{Selector: selector}); err != nil {
-			return err
+			return 0, err
 		}
 		targetClusters = clusterList.Items
 	}
 
+	var requeueAfter time.Duration
+	var allowedClusters sets.Set[string]
+	if plan.Spec.ClusterScheduling.RolloutStrategy != nil {
+		var err error
+		if allowedClusters, requeueAfter, err = r.syncRollout(ctx, plan, targetClusters); err != nil {
+			return 0, err
+		}
+	} else if plan.Status.Rollout != nil {
+		plan.Status.Rollout = nil
+		if err := r.updateInstallPlan(ctx, plan); err != nil {
+			return 0, err
+		}
+	}
+
 	for _, cluster := range targetClusters {
-		if err := r.syncClusterAgentStatus(ctx, plan, &cluster); err != nil {
-			return err
+		// clusters in batches that have not started are only allowed to sync the status
+		allowed := allowedClusters == nil || allowedClusters.Has(cluster.Name)
+		if err := r.syncClusterAgentStatus(ctx, plan, &cluster, allowed); err != nil {
+			return 0, err
 		}
 	}
 
 	for clusterName := range plan.Status.ClusterSchedulingStatuses {
 		if !hasCluster(targetClusters, clusterName) {
 			if err := r.uninstallClusterAgent(ctx, plan, clusterName); err != nil {
-				return err
+				return 0, err
 			}
 		}
 	}
 
-	return nil
+	return requeueAfter, nil
 }
 
 func (r *InstallPlanReconciler) cleanupOutdatedJobsAndConfigMaps(ctx context.Context, plan *corev1alpha1.InstallPlan) error {
@@ -1033,7 +1044,7 @@ func (r *InstallPlanReconciler) syncInstallPlanStatus(ctx context.Context, plan
 }
 
 func (r *InstallPlanReconciler) syncClusterAgentStatus(ctx context.Context,
-	plan *corev1alpha1.InstallPlan, cluster *clusterv1alpha1.Cluster) error {
+	plan *corev1alpha1.InstallPlan, cluster *clusterv1alpha1.Cluster, allowed bool) error {
 	if !clusterutils.IsClusterSchedulable(cluster) {
 		klog.V(4).Infof("cluster %s is not schedulable", cluster.Name)
 		return nil
@@ -1056,6 +1067,11 @@ func (r *InstallPlanReconciler) syncClusterAgentStatus(ctx context.Context,
 		return fmt.Errorf("failed to sync cluster agent status: %v", err)
 	}
 
+	if !allowed {
+		klog.FromContext(ctx).V(4).Info("waiting for the rollout", "cluster", cluster.Name)
+		return nil
+	}
+
 	switch plan.Status.ClusterSchedulingStatuses[cluster.Name].State {
 	case "":
 		return r.installOrUpgradeClusterAgent(ctx, plan, cluster, false)
@@ -1116,6 +1132,13 @@ func (r *InstallPlanReconciler) installOrUpgradeExtension(ctx context.Context, p
 		return onFailed(fmt.Errorf("failed to load chart data: %v", err))
 	}
 
+	// the webhook can't validate the config against the remote chart, it's always validated before the installation
+	values := clusterConfig(plan, "")
+	overrides := extension.Overrides(mainChart, tagExtension, nil, r.PortalURL, r.ExtensionOptions)
+	if err = validateValuesSchema(mainChart, values, overrides); err != nil {
+		return onFailed(fmt.Errorf("invalid extension config: %v", err))
+	}
+
 	releaseName := plan.Spec.Extension.Name
 	clusterRole, role := usesPermissions(mainChart)
 	if err = initTargetNamespace(ctx, r.Client, plan.Status.TargetNamespace, plan.Spec.Extension.Name, clusterRole, role); err != nil {
@@ -1144,7 +1167,7 @@ func (r *InstallPlanReconciler) installOrUpgradeExtension(ctx context.Context, p
 		helm.SetTimeout(r.HelmExecutorOptions.Timeout),
 		helm.SetKubeAsUser(fmt.Sprintf("system:serviceaccount:%s:helm-executor.%s", plan.Status.TargetNamespace, plan.Spec.Extension.Name)),
 		helm.SetLabels(map[string]string{corev1alpha1.ExtensionReferenceLabel: plan.Spec.Extension.Name}),
-		helm.SetOverrides(r.getOverrides(mainChart, tagExtension, nil)),
+		helm.SetOverrides(overrides),
 		helm.SetCABundle(caBundle),
 		helm.SetHistoryMax(r.HelmExecutorOptions.HistoryMax),
 		helm.SetHookImage(r.getHookImageForInstall(extensionVersion, upgrade)),
@@ -1156,7 +1179,6 @@ func (r *InstallPlanReconciler) installOrUpgradeExtension(ctx context.Context, p
 	}
 
 	chartURL, helmOptions := fixedOptions(extensionVersion.Spec.ChartURL, chartData, helmOptions)
-	values := clusterConfig(plan, "")
 	jobName, err := executor.Upgrade(ctx, releaseName, chartURL, values, helmOptions...)
 	if err != nil {
 		return onFailed(fmt.Errorf("failed to create executor job: %v", err))
@@ -1197,76 +1219,6 @@ func (r *InstallPlanReconciler) getHookImageForInstall(extensionVersion *corev1a
 	return hookImage
 }
 
-func (r *InstallPlanReconciler) getOverrides(mainChart *chart.Chart, tag string, cluster *clusterv1alpha1.Cluster) (overrides []string) {
-	disableSubchartsOverrides := func(mainChart *chart.Chart, tag string) (overrides []string) {
-		for _, condition := range conditions(mainChart, tag) {
-			overrides = append(overrides, fmt.Sprintf("%s=%s", condition, "false"))
-		}
-		return overrides
-	}
-
-	switch tag {
-	case tagExtension:
-		overrides = append(overrides,
-			fmt.Sprintf("tags.%s=%s", tagExtension, "true"),
-			fmt.Sprintf("tags.%s=%s", tagAgent, "false"),
-		)
-
-		if r.PortalURL != "" {
-			overrides = append(overrides, fmt.Sprintf("%s=%s", globalPortalURL, r.PortalURL))
-		}
-
-		overrides = append(overrides, disableSubchartsOverrides(mainChart, tagAgent)...)
-	case tagAgent:
-		overrides = append(overrides,
-			fmt.Sprintf("tags.%s=%s", tagAgent, "true"),
-			fmt.Sprintf("tags.%s=%s", tagExtension, "false"),
-		)
-		overrides = append(overrides, disableSubchartsOverrides(mainChart, tagExtension)...)
-	}
-
-	if cluster != nil {
-		clusterRole := clusterv1alpha1.ClusterRoleMember
-		if clusterutils.IsHostCluster(cluster) {
-			clusterRole = clusterv1alpha1.ClusterRoleHost
-		}
-		overrides = append(overrides,
-			fmt.Sprintf("%s=%s", globalClusterName, cluster.Name),
-			fmt.Sprintf("%s=%s", globalClusterRole, clusterRole),
-		)
-	}
-
-	if r.ExtensionOptions != nil {
-		if r.ExtensionOptions.ImageRegistry != "" {
-			overrides = append(overrides, fmt.Sprintf("%s=%s", globalImageRegistry, r.ExtensionOptions.ImageRegistry))
-		}
-		if r.ExtensionOptions.NodeSelector != nil {
-			for k, v := range r.ExtensionOptions.NodeSelector {
-				k = strings.ReplaceAll(k, ".", "\\.")
-				overrides = append(overrides, fmt.Sprintf("%s.%s=%s", globalNodeSelector, k, v))
-			}
-		}
-		if r.ExtensionOptions.Ingress != nil {
-			overrides = append(overrides, fmt.Sprintf("%s=%s", globalExtensionIngressClassName, r.ExtensionOptions.Ingress.IngressClassName))
-			overrides = append(overrides, fmt.Sprintf("%s=%s", globalExtensionIngressDomainSuffix, r.ExtensionOptions.Ingress.DomainSuffix))
-			overrides = append(overrides, fmt.Sprintf("%s=%d", globalExtensionIngressHTTPPort, r.ExtensionOptions.Ingress.HTTPPort))
-			overrides = append(overrides, fmt.Sprintf("%s=%d", globalExtensionIngressHTTPSPort, r.ExtensionOptions.Ingress.HTTPSPort))
-		}
-	}
-
-	return overrides
-}
-
-func conditions(mainChart *chart.Chart, tag string) []string {
-	var conditions []string
-	for _, dependency := range mainChart.Metadata.Dependencies {
-		if dependency.Condition != "" && sliceutil.HasString(dependency.Tags, tag) {
-			conditions = append(conditions, strings.Split(dependency.Condition, ",")...)
-		}
-	}
-	return conditions
-}
-
 func (r *InstallPlanReconciler) installOrUpgradeClusterAgent(ctx context.Context, plan *corev1alpha1.InstallPlan, cluster *clusterv1alpha1.Cluster, upgrade bool) error {
 	clusterName := cluster.Name
 	targetNamespace := plan.Status.TargetNamespace
@@ -1308,6 +1260,12 @@ func (r *InstallPlanReconciler) installOrUpgradeClusterAgent(ctx context.Context
 		return onFailed(fmt.Errorf("failed to load chart data: %v", err))
 	}
 
+	values := clusterConfig(plan, clusterName)
+	overrides := extension.Overrides(mainChart, tagAgent, cluster, r.PortalURL, r.ExtensionOptions)
+	if err = validateValuesSchema(mainChart, values, overrides); err != nil {
+		return onFailed(fmt.Errorf("invalid cluster %s agent config: %v", clusterName, err))
+	}
+
 	clusterRole, role := usesPermissions(mainChart)
 	if err = initTargetNamespace(ctx, clusterClient, targetNamespace, plan.Spec.Extension.Name, clusterRole, role); err != nil {
 		return onFailed(fmt.Errorf("failed to init target namespace: %v", err))
@@ -1342,7 +1300,7 @@ func (r *InstallPlanReconciler) installOrUpgradeClusterAgent(ctx context.Context
 		helm.SetInstall(!upgrade),
 		helm.SetTimeout(r.HelmExecutorOptions.Timeout),
 		helm.SetKubeAsUser(fmt.Sprintf("system:serviceaccount:%s:helm-executor.%s", targetNamespace, plan.Spec.Extension.Name)),
-		helm.SetOverrides(r.getOverrides(mainChart, tagAgent, cluster)),
+		helm.SetOverrides(overrides),
 		helm.SetLabels(map[string]string{corev1alpha1.ExtensionReferenceLabel: plan.Spec.Extension.Name}),
 		helm.SetCABundle(caBundle),
 		helm.SetHistoryMax(r.HelmExecutorOptions.HistoryMax),
@@ -1352,7 +1310,6 @@ func (r *InstallPlanReconciler) installOrUpgradeClusterAgent(ctx context.Context
 	}
 
 	chartURL, helmOptions := fixedOptions(extensionVersion.Spec.ChartURL, chartData, helmOptions)
-	values := clusterConfig(plan, cluster.Name)
 	jobName, err := executor.Upgrade(ctx, releaseName, chartURL, values, helmOptions...)
 	if err != nil {
 		return onFailed(fmt.Errorf("failed to create executor job: %v", err))
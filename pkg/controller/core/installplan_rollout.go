@@ -0,0 +1,229 @@
+/*
+ * Copyright 2024 the KubeSphere Authors.
+ * Please refer to the LICENSE file in the root directory of the project.
+ * https://github.com/kubesphere/kubesphere/blob/master/LICENSE
+ */
+
+package core
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/klog/v2"
+	clusterv1alpha1 "kubesphere.io/api/cluster/v1alpha1"
+	corev1alpha1 "kubesphere.io/api/core/v1alpha1"
+
+	clusterutils "kubesphere.io/kubesphere/pkg/controller/cluster/utils"
+	"kubesphere.io/kubesphere/pkg/utils/hashutil"
+)
+
+const (
+	rolloutStarted   = "RolloutStarted"
+	rolloutHalted    = "RolloutHalted"
+	rolloutCompleted = "RolloutCompleted"
+)
+
+// rolloutRevision returns the hash of the target version and configuration of the agent chart.
+func rolloutRevision(plan *corev1alpha1.InstallPlan) string {
+	overrides := make([]string, 0, len(plan.Spec.ClusterScheduling.Overrides))
+	for cluster, override := range plan.Spec.ClusterScheduling.Overrides {
+		overrides = append(overrides, fmt.Sprintf("%s=%s", cluster, override))
+	}
+	sort.Strings(overrides)
+	return hashutil.FNVString([]byte(fmt.Sprintf("%s\n%s\n%s", plan.Spec.Extension.Version, plan.Spec.Config, strings.Join(overrides, "\n"))))
+}
+
+// newRolloutBatches splits the clusters into batches, the clusters are sorted by name to make the batches stable.
+func newRolloutBatches(clusters []string, batchSize int) []corev1alpha1.RolloutBatchStatus {
+	clusters = append([]string(nil), clusters...)
+	sort.Strings(clusters)
+	if batchSize <= 0 || batchSize > len(clusters) {
+		batchSize = len(clusters)
+	}
+	var batches []corev1alpha1.RolloutBatchStatus
+	for start := 0; start < len(clusters); start += batchSize {
+		end := start + batchSize
+		if end > len(clusters) {
+			end = len(clusters)
+		}
+		batches = append(batches, corev1alpha1.RolloutBatchStatus{
+			Clusters: clusters[start:end],
+			Phase:    corev1alpha1.RolloutBatchPhasePending,
+		})
+	}
+	return batches
+}
+
+// clusterRolledOut returns true if the agent chart of the target version and configuration has been deployed in the cluster.
+func clusterRolledOut(plan *corev1alpha1.InstallPlan, cluster string) bool {
+	status, ok := plan.Status.ClusterSchedulingStatuses[cluster]
+	if !ok || status.State != corev1alpha1.StateDeployed {
+		return false
+	}
+	return clusterAtRevision(plan, status, cluster)
+}
+
+// clusterRolloutFailed returns true if the agent chart of the target version and configuration failed to deploy in the cluster,
+// failures of the previous revisions will be retried and do not count against the unavailability budget.
+func clusterRolloutFailed(plan *corev1alpha1.InstallPlan, cluster string) bool {
+	status, ok := plan.Status.ClusterSchedulingStatuses[cluster]
+	if !ok || (status.State != corev1alpha1.StateInstallFailed && status.State != corev1alpha1.StateUpgradeFailed) {
+		return false
+	}
+	return clusterAtRevision(plan, status, cluster)
+}
+
+// clusterAtRevision returns true if the status of the cluster is of the target version and configuration.
+func clusterAtRevision(plan *corev1alpha1.InstallPlan, status corev1alpha1.InstallationStatus, cluster string) bool {
+	return status.Version == plan.Spec.Extension.Version && status.ConfigHash == hashutil.FNVString(clusterConfig(plan, cluster))
+}
+
+// syncRollout advances the rollout according to the health of the clusters in the current batch,
+// and returns the clusters that are allowed to be installed or upgraded.
+// The returned duration is greater than zero if the rollout is waiting for the pause between batches.
+func (r *InstallPlanReconciler) syncRollout(ctx context.Context, plan *corev1alpha1.InstallPlan, targetClusters []clusterv1alpha1.Cluster) (sets.Set[string], time.Duration, error) {
+	strategy := plan.Spec.ClusterScheduling.RolloutStrategy
+	clusters := make(map[string]*clusterv1alpha1.Cluster, len(targetClusters))
+	clusterNames := make([]string, 0, len(targetClusters))
+	for i := range targetClusters {
+		clusters[targetClusters[i].Name] = &targetClusters[i]
+		clusterNames = append(clusterNames, targetClusters[i].Name)
+	}
+
+	now := metav1.NewTime(time.Now().Round(time.Second))
+	revision := rolloutRevision(plan)
+	rollout := plan.Status.Rollout.DeepCopy()
+	if rollout == nil || rollout.Revision != revision {
+		rollout = &corev1alpha1.RolloutStatus{
+			Revision:           revision,
+			Phase:              corev1alpha1.RolloutPhaseProgressing,
+			Batches:            newRolloutBatches(clusterNames, strategy.BatchSize),
+			LastTransitionTime: now,
+		}
+		r.recorder.Eventf(plan, corev1.EventTypeNormal, rolloutStarted, "start rolling out version %s to %d clusters in %d batches", plan.Spec.Extension.Version, len(clusterNames), len(rollout.Batches))
+	} else {
+		// clusters which are placed after the rollout started are appended as new batches
+		planned := sets.New[string]()
+		for _, batch := range rollout.Batches {
+			planned.Insert(batch.Clusters...)
+		}
+		var unplanned []string
+		for _, name := range clusterNames {
+			if !planned.Has(name) {
+				unplanned = append(unplanned, name)
+			}
+		}
+		if len(unplanned) > 0 {
+			rollout.Batches = append(rollout.Batches, newRolloutBatches(unplanned, strategy.BatchSize)...)
+			if rollout.Phase == corev1alpha1.RolloutPhaseCompleted {
+				rollout.Phase = corev1alpha1.RolloutPhaseProgressing
+				rollout.LastTransitionTime = now
+			}
+		}
+	}
+
+	allowed := sets.New[string]()
+	var requeueAfter time.Duration
+	unavailable := 0
+	for i := range rollout.Batches {
+		batch := &rollout.Batches[i]
+		if i > rollout.CurrentBatch {
+			break
+		}
+		allowed.Insert(batch.Clusters...)
+		if i < rollout.CurrentBatch {
+			unavailable += len(batch.UnavailableClusters)
+			continue
+		}
+
+		if batch.Phase == corev1alpha1.RolloutBatchPhasePending {
+			if strategy.Paused {
+				allowed.Delete(batch.Clusters...)
+				break
+			}
+			batch.Phase = corev1alpha1.RolloutBatchPhaseProgressing
+			batch.StartTime = &now
+		}
+
+		var pending bool
+		batch.UnavailableClusters = nil
+		for _, name := range batch.Clusters {
+			cluster, placed := clusters[name]
+			if !placed {
+				// the cluster has been removed from the placement
+				continue
+			}
+			if !clusterutils.IsClusterSchedulable(cluster) || clusterRolloutFailed(plan, name) {
+				batch.UnavailableClusters = append(batch.UnavailableClusters, name)
+				continue
+			}
+			if !clusterRolledOut(plan, name) {
+				pending = true
+			}
+		}
+		unavailable += len(batch.UnavailableClusters)
+
+		if unavailable > strategy.MaxUnavailable {
+			batch.Phase = corev1alpha1.RolloutBatchPhaseFailed
+			if rollout.Phase != corev1alpha1.RolloutPhaseHalted {
+				rollout.Phase = corev1alpha1.RolloutPhaseHalted
+				rollout.Message = fmt.Sprintf("The rollout has been halted, unavailable clusters: %s", strings.Join(batch.UnavailableClusters, ","))
+				rollout.LastTransitionTime = now
+				r.recorder.Event(plan, corev1.EventTypeWarning, rolloutHalted, rollout.Message)
+			}
+			break
+		}
+
+		if pending {
+			batch.Phase = corev1alpha1.RolloutBatchPhaseProgressing
+			rollout.Phase = corev1alpha1.RolloutPhaseProgressing
+			rollout.Message = ""
+			break
+		}
+
+		if batch.Phase != corev1alpha1.RolloutBatchPhaseSucceeded {
+			batch.Phase = corev1alpha1.RolloutBatchPhaseSucceeded
+			batch.CompletionTime = &now
+		}
+
+		if i == len(rollout.Batches)-1 {
+			if rollout.Phase != corev1alpha1.RolloutPhaseCompleted {
+				rollout.Phase = corev1alpha1.RolloutPhaseCompleted
+				rollout.Message = ""
+				rollout.LastTransitionTime = now
+				r.recorder.Eventf(plan, corev1.EventTypeNormal, rolloutCompleted, "version %s has been rolled out to all clusters", plan.Spec.Extension.Version)
+			}
+			break
+		}
+
+		if strategy.Paused {
+			rollout.Phase = corev1alpha1.RolloutPhasePaused
+			break
+		}
+
+		if wait := batch.CompletionTime.Add(strategy.PauseBetweenBatches.Duration).Sub(now.Time); wait > 0 {
+			requeueAfter = wait
+			break
+		}
+		rollout.CurrentBatch = i + 1
+	}
+
+	if strategy.Paused && rollout.Phase == corev1alpha1.RolloutPhaseProgressing {
+		rollout.Phase = corev1alpha1.RolloutPhasePaused
+	}
+
+	plan.Status.Rollout = rollout
+	if err := r.updateInstallPlan(ctx, plan); err != nil {
+		return nil, 0, fmt.Errorf("failed to update rollout status: %v", err)
+	}
+
+	klog.FromContext(ctx).V(4).Info("rollout synced", "phase", rollout.Phase, "batch", rollout.CurrentBatch, "allowed", allowed.UnsortedList())
+	return allowed, requeueAfter, nil
+}
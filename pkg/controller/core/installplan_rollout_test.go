@@ -0,0 +1,122 @@
+/*
+ * Copyright 2024 the KubeSphere Authors.
+ * Please refer to the LICENSE file in the root directory of the project.
+ * https://github.com/kubesphere/kubesphere/blob/master/LICENSE
+ */
+
+package core
+
+import (
+	"context"
+	"testing"
+
+	"github.com/go-logr/logr"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+	clusterv1alpha1 "kubesphere.io/api/cluster/v1alpha1"
+	corev1alpha1 "kubesphere.io/api/core/v1alpha1"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"kubesphere.io/kubesphere/pkg/scheme"
+	"kubesphere.io/kubesphere/pkg/utils/hashutil"
+)
+
+func TestNewRolloutBatches(t *testing.T) {
+	batches := newRolloutBatches([]string{"c5", "c1", "c3", "c2", "c4"}, 2)
+	assert.Len(t, batches, 3)
+	assert.Equal(t, []string{"c1", "c2"}, batches[0].Clusters)
+	assert.Equal(t, []string{"c3", "c4"}, batches[1].Clusters)
+	assert.Equal(t, []string{"c5"}, batches[2].Clusters)
+
+	batches = newRolloutBatches([]string{"c1", "c2"}, 0)
+	assert.Len(t, batches, 1)
+	assert.Equal(t, []string{"c1", "c2"}, batches[0].Clusters)
+}
+
+func TestSyncRollout(t *testing.T) {
+	readyCluster := func(name string) clusterv1alpha1.Cluster {
+		return clusterv1alpha1.Cluster{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Status: clusterv1alpha1.ClusterStatus{Conditions: []clusterv1alpha1.ClusterCondition{
+				{Type: clusterv1alpha1.ClusterReady, Status: corev1.ConditionTrue},
+			}},
+		}
+	}
+	clusters := []clusterv1alpha1.Cluster{readyCluster("c1"), readyCluster("c2"), readyCluster("c3")}
+
+	plan := &corev1alpha1.InstallPlan{
+		ObjectMeta: metav1.ObjectMeta{Name: "devops"},
+		Spec: corev1alpha1.InstallPlanSpec{
+			Extension: corev1alpha1.ExtensionRef{Name: "devops", Version: "1.1.0"},
+			ClusterScheduling: &corev1alpha1.ClusterScheduling{
+				Placement:       &corev1alpha1.Placement{Clusters: []string{"c1", "c2", "c3"}},
+				RolloutStrategy: &corev1alpha1.RolloutStrategy{BatchSize: 2},
+			},
+		},
+	}
+	r := &InstallPlanReconciler{
+		Client:   fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(plan.DeepCopy()).Build(),
+		recorder: record.NewFakeRecorder(10),
+		logger:   logr.Discard(),
+	}
+	ctx := context.Background()
+
+	deployed := func(cluster string) {
+		if plan.Status.ClusterSchedulingStatuses == nil {
+			plan.Status.ClusterSchedulingStatuses = make(map[string]corev1alpha1.InstallationStatus)
+		}
+		plan.Status.ClusterSchedulingStatuses[cluster] = corev1alpha1.InstallationStatus{
+			State:      corev1alpha1.StateDeployed,
+			Version:    plan.Spec.Extension.Version,
+			ConfigHash: hashutil.FNVString(clusterConfig(plan, cluster)),
+		}
+	}
+
+	// the first batch is in progress
+	allowed, _, err := r.syncRollout(ctx, plan, clusters)
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []string{"c1", "c2"}, allowed.UnsortedList())
+	assert.Equal(t, corev1alpha1.RolloutPhaseProgressing, plan.Status.Rollout.Phase)
+
+	// the first batch succeeded, the second batch starts
+	deployed("c1")
+	deployed("c2")
+	allowed, _, err = r.syncRollout(ctx, plan, clusters)
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []string{"c1", "c2", "c3"}, allowed.UnsortedList())
+	assert.Equal(t, 1, plan.Status.Rollout.CurrentBatch)
+	assert.Equal(t, corev1alpha1.RolloutBatchPhaseSucceeded, plan.Status.Rollout.Batches[0].Phase)
+
+	// the failure of the previous revision does not halt the rollout
+	plan.Status.ClusterSchedulingStatuses["c3"] = corev1alpha1.InstallationStatus{State: corev1alpha1.StateUpgradeFailed, Version: "1.0.0"}
+	_, _, err = r.syncRollout(ctx, plan, clusters)
+	assert.NoError(t, err)
+	assert.Equal(t, corev1alpha1.RolloutPhaseProgressing, plan.Status.Rollout.Phase)
+	assert.Empty(t, plan.Status.Rollout.Batches[1].UnavailableClusters)
+
+	// the rollout is halted if the cluster fails
+	deployed("c3")
+	status := plan.Status.ClusterSchedulingStatuses["c3"]
+	status.State = corev1alpha1.StateInstallFailed
+	plan.Status.ClusterSchedulingStatuses["c3"] = status
+	_, _, err = r.syncRollout(ctx, plan, clusters)
+	assert.NoError(t, err)
+	assert.Equal(t, corev1alpha1.RolloutPhaseHalted, plan.Status.Rollout.Phase)
+	assert.Equal(t, []string{"c3"}, plan.Status.Rollout.Batches[1].UnavailableClusters)
+
+	// the rollout is completed after the cluster recovered
+	deployed("c3")
+	_, _, err = r.syncRollout(ctx, plan, clusters)
+	assert.NoError(t, err)
+	assert.Equal(t, corev1alpha1.RolloutPhaseCompleted, plan.Status.Rollout.Phase)
+
+	// a new rollout starts after the version changed
+	plan.Spec.Extension.Version = "1.2.0"
+	allowed, _, err = r.syncRollout(ctx, plan, clusters)
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []string{"c1", "c2"}, allowed.UnsortedList())
+	assert.Equal(t, 0, plan.Status.Rollout.CurrentBatch)
+	assert.Equal(t, corev1alpha1.RolloutPhaseProgressing, plan.Status.Rollout.Phase)
+}
@@ -10,13 +10,22 @@ import (
 	"bytes"
 	"context"
 	"fmt"
+	"reflect"
 	"strings"
 	"unicode"
 
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/chart/loader"
+	"helm.sh/helm/v3/pkg/chartutil"
+	"helm.sh/helm/v3/pkg/strvals"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/yaml"
+	clusterv1alpha1 "kubesphere.io/api/cluster/v1alpha1"
 	corev1alpha1 "kubesphere.io/api/core/v1alpha1"
 
 	kscontroller "kubesphere.io/kubesphere/pkg/controller"
+	"kubesphere.io/kubesphere/pkg/models/extension"
 
 	"k8s.io/apimachinery/pkg/runtime"
 	ctrl "sigs.k8s.io/controller-runtime"
@@ -33,6 +42,8 @@ func (r *InstallPlanWebhook) Name() string {
 
 type InstallPlanWebhook struct {
 	client.Client
+	PortalURL        string
+	ExtensionOptions *extension.Options
 }
 
 func trimSpace(data string) string {
@@ -63,15 +74,22 @@ func (r *InstallPlanWebhook) ValidateCreate(ctx context.Context, obj runtime.Obj
 	return r.validateInstallPlan(ctx, obj.(*corev1alpha1.InstallPlan))
 }
 
-func (r *InstallPlanWebhook) ValidateUpdate(ctx context.Context, _, newObj runtime.Object) (admission.Warnings, error) {
-	return r.validateInstallPlan(ctx, newObj.(*corev1alpha1.InstallPlan))
+func (r *InstallPlanWebhook) ValidateUpdate(ctx context.Context, oldObj, newObj runtime.Object) (admission.Warnings, error) {
+	oldPlan, newPlan := oldObj.(*corev1alpha1.InstallPlan), newObj.(*corev1alpha1.InstallPlan)
+	// skip the values schema validation if neither the chart nor the values changed, e.g. only the metadata is updated
+	if oldPlan.Spec.Extension.Version == newPlan.Spec.Extension.Version &&
+		oldPlan.Spec.Config == newPlan.Spec.Config &&
+		reflect.DeepEqual(clusterOverrides(oldPlan), clusterOverrides(newPlan)) {
+		return nil, nil
+	}
+	return r.validateInstallPlan(ctx, newPlan)
 }
 
 func (r *InstallPlanWebhook) ValidateDelete(_ context.Context, _ runtime.Object) (admission.Warnings, error) {
 	return nil, nil
 }
 
-func (r *InstallPlanWebhook) validateInstallPlan(_ context.Context, installPlan *corev1alpha1.InstallPlan) (admission.Warnings, error) {
+func (r *InstallPlanWebhook) validateInstallPlan(ctx context.Context, installPlan *corev1alpha1.InstallPlan) (admission.Warnings, error) {
 	var data interface{}
 
 	if err := yaml.Unmarshal([]byte(installPlan.Spec.Config), &data); err != nil {
@@ -86,10 +104,85 @@ func (r *InstallPlanWebhook) validateInstallPlan(_ context.Context, installPlan
 		}
 	}
 
+	return r.validateValuesSchema(ctx, installPlan)
+}
+
+// validateValuesSchema validates the config and the cluster overrides against the values.schema.json of the chart,
+// together with the global overrides applied by the install plan controller.
+// The remote chart is not fetched during the admission, if the chart is not stored in the cluster, the validation is
+// deferred to the install plan controller, which validates the values before the installation and reports the error
+// in the status of the install plan.
+func (r *InstallPlanWebhook) validateValuesSchema(ctx context.Context, installPlan *corev1alpha1.InstallPlan) (admission.Warnings, error) {
+	extensionVersion := &corev1alpha1.ExtensionVersion{}
+	extensionVersionName := fmt.Sprintf("%s-%s", installPlan.Spec.Extension.Name, installPlan.Spec.Extension.Version)
+	if err := r.Get(ctx, types.NamespacedName{Name: extensionVersionName}, extensionVersion); err != nil {
+		if apierrors.IsNotFound(err) {
+			return admission.Warnings{fmt.Sprintf("extension version %s not found, the values will be validated before the installation", extensionVersionName)}, nil
+		}
+		return nil, err
+	}
+	if extensionVersion.Spec.ChartDataRef == nil {
+		return admission.Warnings{fmt.Sprintf("the chart of %s is not stored in the cluster, the values will be validated before the installation", extensionVersionName)}, nil
+	}
+	chartData, err := fetchChartDataFromConfigMap(ctx, r.Client, extensionVersion.Spec.ChartDataRef)
+	if err != nil {
+		return admission.Warnings{fmt.Sprintf("failed to fetch the chart of %s, the values will be validated before the installation: %v", extensionVersionName, err)}, nil
+	}
+	mainChart, err := loader.LoadArchive(bytes.NewReader(chartData))
+	if err != nil {
+		return nil, fmt.Errorf("failed to load chart data: %v", err)
+	}
+
+	overrides := extension.Overrides(mainChart, tagExtension, nil, r.PortalURL, r.ExtensionOptions)
+	if err = validateValuesSchema(mainChart, clusterConfig(installPlan, ""), overrides); err != nil {
+		return nil, fmt.Errorf("invalid extension config: %v", err)
+	}
+	for clusterName := range clusterOverrides(installPlan) {
+		cluster := &clusterv1alpha1.Cluster{}
+		if err = r.Get(ctx, types.NamespacedName{Name: clusterName}, cluster); err != nil {
+			if !apierrors.IsNotFound(err) {
+				return nil, err
+			}
+			cluster.Name = clusterName
+		}
+		overrides = extension.Overrides(mainChart, tagAgent, cluster, r.PortalURL, r.ExtensionOptions)
+		if err = validateValuesSchema(mainChart, clusterConfig(installPlan, clusterName), overrides); err != nil {
+			return nil, fmt.Errorf("invalid cluster %s agent config: %v", clusterName, err)
+		}
+	}
 	return nil, nil
 }
 
+// validateValuesSchema applies the overrides to the values in the same way as "helm --set" before the validation.
+func validateValuesSchema(mainChart *chart.Chart, values []byte, overrides []string) error {
+	vals, err := chartutil.ReadValues(values)
+	if err != nil {
+		return err
+	}
+	for _, override := range overrides {
+		if err = strvals.ParseInto(override, vals); err != nil {
+			return fmt.Errorf("failed to parse override %s: %v", override, err)
+		}
+	}
+	coalesced, err := chartutil.CoalesceValues(mainChart, vals)
+	if err != nil {
+		return err
+	}
+	return chartutil.ValidateAgainstSchema(mainChart, coalesced)
+}
+
+func clusterOverrides(installPlan *corev1alpha1.InstallPlan) map[string]string {
+	if installPlan.Spec.ClusterScheduling == nil {
+		return nil
+	}
+	return installPlan.Spec.ClusterScheduling.Overrides
+}
+
 func (r *InstallPlanWebhook) SetupWithManager(mgr *kscontroller.Manager) error {
+	if mgr.AuthenticationOptions != nil && mgr.Options.AuthenticationOptions.Issuer != nil {
+		r.PortalURL = mgr.Options.AuthenticationOptions.Issuer.URL
+	}
+	r.ExtensionOptions = mgr.ExtensionOptions
 	r.Client = mgr.GetClient()
 	return ctrl.NewWebhookManagedBy(mgr).
 		WithValidator(r).
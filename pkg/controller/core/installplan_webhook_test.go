@@ -0,0 +1,140 @@
+/*
+ * Copyright 2024 the KubeSphere Authors.
+ * Please refer to the LICENSE file in the root directory of the project.
+ * https://github.com/kubesphere/kubesphere/blob/master/LICENSE
+ */
+
+package core
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/chartutil"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	corev1alpha1 "kubesphere.io/api/core/v1alpha1"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"kubesphere.io/kubesphere/pkg/constants"
+	"kubesphere.io/kubesphere/pkg/models/extension"
+	"kubesphere.io/kubesphere/pkg/scheme"
+)
+
+const testValuesSchema = `{
+  "$schema": "https://json-schema.org/draft-07/schema#",
+  "type": "object",
+  "properties": {
+    "replicas": {"type": "integer", "minimum": 1},
+    "image": {"type": "string"},
+    "global": {
+      "type": "object",
+      "properties": {
+        "imageRegistry": {"type": "string"}
+      }
+    },
+    "tags": {"type": "object"}
+  },
+  "additionalProperties": false
+}`
+
+func TestValidateInstallPlanValuesSchema(t *testing.T) {
+	dir := t.TempDir()
+	chartFile, err := chartutil.Save(&chart.Chart{
+		Metadata: &chart.Metadata{APIVersion: chart.APIVersionV2, Name: "devops", Version: "1.0.0"},
+		Values:   map[string]interface{}{"replicas": 1, "image": "kubesphere/devops"},
+		Schema:   []byte(testValuesSchema),
+	}, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	chartData, err := os.ReadFile(chartFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	configMap := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "devops-chart", Namespace: constants.KubeSphereNamespace},
+		BinaryData: map[string][]byte{"chart.tgz": chartData},
+	}
+	extensionVersion := &corev1alpha1.ExtensionVersion{
+		ObjectMeta: metav1.ObjectMeta{Name: "devops-1.0.0"},
+		Spec: corev1alpha1.ExtensionVersionSpec{
+			Version: "1.0.0",
+			ChartDataRef: &corev1alpha1.ConfigMapKeyRef{
+				ConfigMapKeySelector: corev1.ConfigMapKeySelector{
+					LocalObjectReference: corev1.LocalObjectReference{Name: configMap.Name},
+					Key:                  "chart.tgz",
+				},
+				Namespace: constants.KubeSphereNamespace,
+			},
+		},
+	}
+	webhook := &InstallPlanWebhook{
+		Client:           fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(configMap, extensionVersion).Build(),
+		ExtensionOptions: &extension.Options{ImageRegistry: "registry.local"},
+	}
+
+	tests := []struct {
+		name         string
+		version      string
+		config       string
+		overrides    map[string]string
+		wantErr      bool
+		wantWarnings bool
+	}{
+		{
+			name:   "valid config",
+			config: "replicas: 2",
+		},
+		{
+			name:   "config overridden by the global overrides",
+			config: "global:\n  imageRegistry: 1",
+		},
+		{
+			name:    "unknown key",
+			config:  "replica: 2",
+			wantErr: true,
+		},
+		{
+			name:    "invalid type",
+			config:  "replicas: two",
+			wantErr: true,
+		},
+		{
+			name:      "invalid cluster override",
+			config:    "replicas: 2",
+			overrides: map[string]string{"member": "replicas: 0"},
+			wantErr:   true,
+		},
+		{
+			name:         "extension version not found",
+			version:      "2.0.0",
+			config:       "replica: 2",
+			wantWarnings: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plan := &corev1alpha1.InstallPlan{
+				ObjectMeta: metav1.ObjectMeta{Name: "devops"},
+				Spec: corev1alpha1.InstallPlanSpec{
+					Extension: corev1alpha1.ExtensionRef{Name: "devops", Version: "1.0.0"},
+					Config:    tt.config,
+				},
+			}
+			if tt.version != "" {
+				plan.Spec.Extension.Version = tt.version
+			}
+			if tt.overrides != nil {
+				plan.Spec.ClusterScheduling = &corev1alpha1.ClusterScheduling{Overrides: tt.overrides}
+			}
+			warnings, err := webhook.ValidateCreate(context.Background(), plan)
+			assert.Equal(t, tt.wantErr, err != nil, "error: %v", err)
+			assert.Equal(t, tt.wantWarnings, len(warnings) > 0)
+		})
+	}
+}
@@ -32,6 +32,7 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
 	kscontroller "kubesphere.io/kubesphere/pkg/controller"
+	"kubesphere.io/kubesphere/pkg/models/extension"
 )
 
 const (
@@ -169,7 +170,6 @@ func (r *RepositoryReconciler) createOrUpdateExtensionVersion(ctx context.Contex
 }
 
 func (r *RepositoryReconciler) syncExtensionsFromURL(ctx context.Context, repo *corev1alpha1.Repository, timeout time.Duration) error {
-	logger := klog.FromContext(ctx)
 	ctx, cancel := context.WithTimeout(ctx, timeout)
 	defer cancel()
 
@@ -188,6 +188,62 @@ func (r *RepositoryReconciler) syncExtensionsFromURL(ctx context.Context, repo *
 		return errors.Wrapf(err, "failed to load repo index")
 	}
 
+	return r.syncExtensions(ctx, repo, index, func(version *helmrepo.ChartVersion, spec *corev1alpha1.ExtensionVersionSpec) bool {
+		chartURL := resolveChartURL(version, repoURL)
+		if chartURL == nil {
+			return false
+		}
+		spec.ChartURL = chartURL.String()
+		return true
+	})
+}
+
+// syncExtensionsFromBundle imports the bundle from the path if it has changed,
+// and then syncs the extensions from the imported bundle.
+func (r *RepositoryReconciler) syncExtensionsFromBundle(ctx context.Context, repo *corev1alpha1.Repository, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	if repo.Spec.Bundle.Path != "" {
+		bundle, err := extension.LoadBundleFromPath(repo.Spec.Bundle.Path, repo.Spec.Bundle.Format)
+		if err != nil {
+			return errors.Wrapf(err, "failed to load bundle")
+		}
+		if repo.Status.Bundle == nil || repo.Status.Bundle.Digest != bundle.Digest {
+			if err := extension.ImportBundle(ctx, r.Client, repo, bundle); err != nil {
+				return errors.Wrapf(err, "failed to import bundle")
+			}
+			repo.Status.Bundle = &corev1alpha1.RepositoryBundleStatus{
+				Digest:     bundle.Digest,
+				ImportTime: &metav1.Time{Time: time.Now()},
+				Verified:   repo.Spec.Bundle.PublicKey != "",
+			}
+		}
+	}
+
+	index, err := extension.LoadImportedBundleIndex(ctx, r.Client, repo.Name)
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			klog.FromContext(ctx).V(4).Info("bundle has not been imported yet", "name", repo.Name)
+			return nil
+		}
+		return errors.Wrapf(err, "failed to load bundle index")
+	}
+
+	return r.syncExtensions(ctx, repo, index, func(version *helmrepo.ChartVersion, spec *corev1alpha1.ExtensionVersionSpec) bool {
+		chartDataRef, err := extension.BundleChartDataRef(repo.Name, version)
+		if err != nil {
+			return false
+		}
+		spec.ChartDataRef = chartDataRef
+		return true
+	})
+}
+
+// syncExtensions creates or updates the extensions and extension versions from the repository index,
+// resolveChart sets the chart source of the extension version and returns false if the chart cannot be resolved.
+func (r *RepositoryReconciler) syncExtensions(ctx context.Context, repo *corev1alpha1.Repository, index *helmrepo.IndexFile, resolveChart func(version *helmrepo.ChartVersion, spec *corev1alpha1.ExtensionVersionSpec) bool) error {
+	logger := klog.FromContext(ctx)
 	for extensionName, versions := range index.Entries {
 		// check extensionName
 		if errs := isValidExtensionName(extensionName); len(errs) > 0 {
@@ -202,9 +258,9 @@ func (r *RepositoryReconciler) syncExtensionsFromURL(ctx context.Context, repo *
 				continue
 			}
 
-			chartURL := resolveChartURL(version, repoURL)
-			if chartURL == nil {
-				logger.V(4).Info("failed to resolve chart URL", "extension", extensionName, "version", version.Version)
+			chartSource := corev1alpha1.ExtensionVersionSpec{}
+			if !resolveChart(version, &chartSource) {
+				logger.V(4).Info("failed to resolve chart", "extension", extensionName, "version", version.Version)
 				continue
 			}
 
@@ -218,8 +274,9 @@ func (r *RepositoryReconciler) syncExtensionsFromURL(ctx context.Context, repo *
 					Annotations: version.Metadata.Annotations,
 				},
 				Spec: corev1alpha1.ExtensionVersionSpec{
-					ChartURL:   chartURL.String(),
-					Repository: repo.Name,
+					ChartURL:     chartSource.ChartURL,
+					ChartDataRef: chartSource.ChartDataRef,
+					Repository:   repo.Name,
 				},
 			}
 
@@ -233,7 +290,8 @@ func (r *RepositoryReconciler) syncExtensionsFromURL(ctx context.Context, repo *
 				continue
 			}
 
-			extensionVersionSpec.ChartURL = chartURL.String()
+			extensionVersionSpec.ChartURL = chartSource.ChartURL
+			extensionVersionSpec.ChartDataRef = chartSource.ChartDataRef
 			extensionVersionSpec.Created = metav1.NewTime(version.Created)
 			extensionVersionSpec.Digest = version.Digest
 			extensionVersionSpec.Repository = repo.Name
@@ -315,6 +373,14 @@ func (r *RepositoryReconciler) reconcileRepository(ctx context.Context, repo *co
 	}
 
 	repoURL := repo.Spec.URL
+	syncExtensions := r.syncExtensionsFromURL
+	if repo.Spec.Bundle != nil {
+		repoURL = "uploaded bundle"
+		if repo.Spec.Bundle.Path != "" {
+			repoURL = repo.Spec.Bundle.Path
+		}
+		syncExtensions = r.syncExtensionsFromBundle
+	}
 	if repoURL == "" {
 		return ctrl.Result{}, nil
 	}
@@ -323,12 +389,12 @@ func (r *RepositoryReconciler) reconcileRepository(ctx context.Context, repo *co
 
 	outOfSync := repo.Status.LastSyncTime == nil || time.Now().After(repo.Status.LastSyncTime.Add(registryPollInterval))
 	if outOfSync {
-		if err := r.syncExtensionsFromURL(ctx, repo, registryPollTimeout); err != nil {
+		repo = repo.DeepCopy()
+		if err := syncExtensions(ctx, repo, registryPollTimeout); err != nil {
 			r.recorder.Eventf(repo, corev1.EventTypeWarning, kscontroller.SyncFailed, "failed to sync extensions from %s: %s", repoURL, err)
 			return ctrl.Result{}, errors.Wrapf(err, "failed to sync extensions from %s", repoURL)
 		}
 		r.recorder.Eventf(repo, corev1.EventTypeNormal, kscontroller.Synced, "sync extensions from %s successfully", repoURL)
-		repo = repo.DeepCopy()
 		repo.Status.LastSyncTime = &metav1.Time{Time: time.Now()}
 		if err := r.Update(ctx, repo); err != nil {
 			return ctrl.Result{}, errors.Wrapf(err, "failed to update repository status")
@@ -24,7 +24,6 @@ import (
 
 	"github.com/Masterminds/semver/v3"
 	"github.com/pkg/errors"
-	yaml3 "gopkg.in/yaml.v3"
 	"helm.sh/helm/v3/pkg/chart"
 	"helm.sh/helm/v3/pkg/chart/loader"
 	"helm.sh/helm/v3/pkg/getter"
@@ -41,6 +40,7 @@ import (
 	"kubesphere.io/utils/helm"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
+	"kubesphere.io/kubesphere/pkg/models/extension"
 	"kubesphere.io/kubesphere/pkg/utils/hashutil"
 	"kubesphere.io/kubesphere/pkg/version"
 )
@@ -164,70 +164,12 @@ func clusterConfig(sub *corev1alpha1.InstallPlan, clusterName string) []byte {
 	}
 	for cluster, config := range sub.Spec.ClusterScheduling.Overrides {
 		if cluster == clusterName {
-			return merge(sub.Spec.Config, config)
+			return extension.MergeConfig(sub.Spec.Config, config)
 		}
 	}
 	return []byte(sub.Spec.Config)
 }
 
-func merge(config string, override string) []byte {
-	config = strings.TrimSpace(config)
-	override = strings.TrimSpace(override)
-
-	if config == "" && override == "" {
-		return []byte("")
-	}
-
-	if override == "" {
-		return []byte(config)
-	}
-
-	if config == "" {
-		return []byte(override)
-	}
-
-	baseConf := map[string]interface{}{}
-	if err := yaml3.Unmarshal([]byte(config), &baseConf); err != nil {
-		klog.Warningf("failed to unmarshal config: %v", err)
-	}
-
-	overrideConf := map[string]interface{}{}
-	if err := yaml3.Unmarshal([]byte(override), overrideConf); err != nil {
-		klog.Warningf("failed to unmarshal config: %v", err)
-	}
-
-	finalConf := mergeValues(baseConf, overrideConf)
-	data, _ := yaml3.Marshal(finalConf)
-	return data
-}
-
-// mergeValues will merge source and destination map, preferring values from the source map
-func mergeValues(dest map[string]interface{}, src map[string]interface{}) map[string]interface{} {
-	for k, v := range src {
-		// If the key doesn't exist already, then just set the key to that value
-		if _, exists := dest[k]; !exists {
-			dest[k] = v
-			continue
-		}
-		nextMap, ok := v.(map[string]interface{})
-		// If it isn't another map, overwrite the value
-		if !ok {
-			dest[k] = v
-			continue
-		}
-		// Edge case: If the key exists in the destination, but isn't a map
-		destMap, isMap := dest[k].(map[string]interface{})
-		// If the source map has a map for this key, prefer it
-		if !isMap {
-			dest[k] = v
-			continue
-		}
-		// If we got to this point, it is a map in both, so merge them
-		dest[k] = mergeValues(destMap, nextMap)
-	}
-	return dest
-}
-
 func usesPermissions(mainChart *chart.Chart) (rbacv1.ClusterRole, rbacv1.Role) {
 	var clusterRole rbacv1.ClusterRole
 	var role rbacv1.Role
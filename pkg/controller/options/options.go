@@ -15,24 +15,29 @@ import (
 	"kubesphere.io/utils/s3"
 
 	"kubesphere.io/kubesphere/pkg/apiserver/authentication"
+	"kubesphere.io/kubesphere/pkg/apiserver/authorization"
 	"kubesphere.io/kubesphere/pkg/models/composedapp"
+	"kubesphere.io/kubesphere/pkg/models/extension"
 	"kubesphere.io/kubesphere/pkg/models/kubeconfig"
 	"kubesphere.io/kubesphere/pkg/models/terminal"
 	"kubesphere.io/kubesphere/pkg/multicluster"
 	"kubesphere.io/kubesphere/pkg/simple/client/k8s"
+	"kubesphere.io/kubesphere/pkg/simple/client/scanner"
 )
 
 type Options struct {
 	KubernetesOptions     *k8s.Options
 	AuthenticationOptions *authentication.Options
+	AuthorizationOptions  *authorization.Options
 	MultiClusterOptions   *multicluster.Options
 	KubeconfigOptions     *kubeconfig.Options
 	TerminalOptions       *terminal.Options
 	ComposedAppOptions    *composedapp.Options
 	HelmExecutorOptions   *HelmExecutorOptions
-	ExtensionOptions      *ExtensionOptions
+	ExtensionOptions      *extension.Options
 	KubeSphereOptions     *KubeSphereOptions
 	S3Options             *s3.Options
+	AppScanOptions        *scanner.Options
 }
 
 type HelmExecutorOptions struct {
@@ -58,23 +63,6 @@ func NewHelmExecutorOptions() *HelmExecutorOptions {
 	}
 }
 
-type ExtensionIngressOptions struct {
-	IngressClassName string `json:"ingressClassName,omitempty" yaml:"ingressClassName,omitempty" mapstructure:"ingressClassName,omitempty"`
-	DomainSuffix     string `json:"domainSuffix,omitempty" yaml:"domainSuffix,omitempty" mapstructure:"domainSuffix,omitempty"`
-	HTTPPort         uint   `json:"httpPort,omitempty" yaml:"httpPort,omitempty" mapstructure:"httpPort,omitempty"`
-	HTTPSPort        uint   `json:"httpsPort,omitempty" yaml:"httpsPort,omitempty" mapstructure:"httpsPort,omitempty"`
-}
-
-type ExtensionOptions struct {
-	ImageRegistry string                   `json:"imageRegistry,omitempty" yaml:"imageRegistry,omitempty" mapstructure:"imageRegistry,omitempty"`
-	NodeSelector  map[string]string        `json:"nodeSelector,omitempty" yaml:"nodeSelector,omitempty" mapstructure:"nodeSelector,omitempty"`
-	Ingress       *ExtensionIngressOptions `json:"ingress,omitempty" yaml:"ingress,omitempty" mapstructure:"ingress,omitempty"`
-}
-
-func NewExtensionOptions() *ExtensionOptions {
-	return &ExtensionOptions{}
-}
-
 type KubeSphereOptions struct {
 	TLS bool `json:"tls,omitempty" yaml:"tls,omitempty" mapstructure:"tls,omitempty"`
 }
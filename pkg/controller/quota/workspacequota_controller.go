@@ -0,0 +1,460 @@
+/*
+ * Copyright 2024 the KubeSphere Authors.
+ * Please refer to the LICENSE file in the root directory of the project.
+ * https://github.com/kubesphere/kubesphere/blob/master/LICENSE
+ */
+
+package quota
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/klog/v2"
+	clusterv1alpha1 "kubesphere.io/api/cluster/v1alpha1"
+	quotav1alpha2 "kubesphere.io/api/quota/v1alpha2"
+	tenantv1beta1 "kubesphere.io/api/tenant/v1beta1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	quotav1 "kubesphere.io/kubesphere/kube/pkg/quota/v1"
+	kscontroller "kubesphere.io/kubesphere/pkg/controller"
+	"kubesphere.io/kubesphere/pkg/controller/cluster/predicate"
+	clusterutils "kubesphere.io/kubesphere/pkg/controller/cluster/utils"
+	"kubesphere.io/kubesphere/pkg/controller/workspacetemplate/utils"
+	"kubesphere.io/kubesphere/pkg/utils/clusterclient"
+)
+
+const (
+	workspaceQuotaControllerName = "workspacequota"
+	// workspaceQuotaResyncPeriod is the period to collect the usage from the member clusters,
+	// since the resource quotas in the member clusters are not watched.
+	workspaceQuotaResyncPeriod = time.Minute
+)
+
+var _ kscontroller.Controller = &WorkspaceQuotaReconciler{}
+var _ kscontroller.ClusterSelector = &WorkspaceQuotaReconciler{}
+var _ reconcile.Reconciler = &WorkspaceQuotaReconciler{}
+
+// WorkspaceQuotaReconciler allots the capacity of the workspace quotas to the member clusters
+// and aggregates the usage of the workspaces from the member clusters.
+type WorkspaceQuotaReconciler struct {
+	client.Client
+	logger           logr.Logger
+	recorder         record.EventRecorder
+	clusterClientSet clusterclient.Interface
+}
+
+func (r *WorkspaceQuotaReconciler) Name() string {
+	return workspaceQuotaControllerName
+}
+
+func (r *WorkspaceQuotaReconciler) Enabled(clusterRole string) bool {
+	return strings.EqualFold(clusterRole, string(clusterv1alpha1.ClusterRoleHost))
+}
+
+func (r *WorkspaceQuotaReconciler) SetupWithManager(mgr *kscontroller.Manager) error {
+	r.Client = mgr.GetClient()
+	r.clusterClientSet = mgr.ClusterClient
+	r.logger = ctrl.Log.WithName("controllers").WithName(workspaceQuotaControllerName)
+	r.recorder = mgr.GetEventRecorderFor(workspaceQuotaControllerName)
+	return ctrl.NewControllerManagedBy(mgr).
+		Named(workspaceQuotaControllerName).
+		For(&quotav1alpha2.WorkspaceQuota{}).
+		Watches(
+			&tenantv1beta1.WorkspaceTemplate{},
+			handler.EnqueueRequestsFromMapFunc(r.mapWorkspaceToQuotas),
+		).
+		Watches(
+			&clusterv1alpha1.Cluster{},
+			handler.EnqueueRequestsFromMapFunc(r.mapClusterToQuotas),
+			builder.WithPredicates(predicate.ClusterStatusChangedPredicate{}),
+		).
+		Complete(r)
+}
+
+// +kubebuilder:rbac:groups=quota.kubesphere.io,resources=workspacequotas,verbs=get;list;watch;update;patch
+// +kubebuilder:rbac:groups=quota.kubesphere.io,resources=workspacequotas/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=quota.kubesphere.io,resources=resourcequotas,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=tenant.kubesphere.io,resources=workspacetemplates,verbs=get;list;watch
+
+func (r *WorkspaceQuotaReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	logger := r.logger.WithValues("workspacequota", req.NamespacedName)
+	ctx = klog.NewContext(ctx, logger)
+	workspaceQuota := &quotav1alpha2.WorkspaceQuota{}
+	if err := r.Get(ctx, req.NamespacedName, workspaceQuota); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+
+	if !workspaceQuota.DeletionTimestamp.IsZero() {
+		if controllerutil.ContainsFinalizer(workspaceQuota, quotav1alpha2.WorkspaceQuotaFinalizer) {
+			// the finalizer is kept until the resource quotas are removed from all the clusters
+			var errs []error
+			for _, cluster := range workspaceQuota.Status.Clusters {
+				if err := r.releaseAllotment(ctx, workspaceQuota, cluster.Name); err != nil {
+					errs = append(errs, err)
+				}
+			}
+			if len(errs) > 0 {
+				return ctrl.Result{}, utilerrors.NewAggregate(errs)
+			}
+			controllerutil.RemoveFinalizer(workspaceQuota, quotav1alpha2.WorkspaceQuotaFinalizer)
+			if err := r.Update(ctx, workspaceQuota); err != nil {
+				return ctrl.Result{}, errors.Wrapf(err, "failed to remove finalizer from workspace quota %s", workspaceQuota.Name)
+			}
+		}
+		return ctrl.Result{}, nil
+	}
+
+	if !controllerutil.ContainsFinalizer(workspaceQuota, quotav1alpha2.WorkspaceQuotaFinalizer) {
+		expected := workspaceQuota.DeepCopy()
+		controllerutil.AddFinalizer(expected, quotav1alpha2.WorkspaceQuotaFinalizer)
+		return ctrl.Result{}, r.Patch(ctx, expected, client.MergeFrom(workspaceQuota))
+	}
+
+	if err := r.syncAllotments(ctx, workspaceQuota); err != nil {
+		logger.Error(err, "failed to sync workspace quota")
+		return ctrl.Result{}, err
+	}
+	return ctrl.Result{RequeueAfter: workspaceQuotaResyncPeriod}, nil
+}
+
+type clusterQuota struct {
+	quotav1alpha2.ClusterQuotaStatus
+	client client.Client
+}
+
+// placementClusters returns the clusters where the workspace is placed, the last observed values are kept for
+// the clusters that are not ready.
+func (r *WorkspaceQuotaReconciler) placementClusters(ctx context.Context, workspaceQuota *quotav1alpha2.WorkspaceQuota) ([]clusterQuota, error) {
+	workspaceTemplate := &tenantv1beta1.WorkspaceTemplate{}
+	if err := r.Get(ctx, types.NamespacedName{Name: workspaceQuota.Spec.Workspace}, workspaceTemplate); err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, errors.Wrapf(err, "failed to get workspace template %s", workspaceQuota.Spec.Workspace)
+	}
+	clusters, err := r.clusterClientSet.ListClusters(ctx)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to list clusters")
+	}
+	previous := make(map[string]quotav1alpha2.ClusterQuotaStatus, len(workspaceQuota.Status.Clusters))
+	for _, cluster := range workspaceQuota.Status.Clusters {
+		previous[cluster.Name] = cluster
+	}
+
+	var result []clusterQuota
+	for i := range clusters {
+		cluster := &clusters[i]
+		if !utils.WorkspaceTemplateMatchTargetCluster(workspaceTemplate, cluster) {
+			continue
+		}
+		last := previous[cluster.Name]
+		target := clusterQuota{ClusterQuotaStatus: *last.DeepCopy()}
+		target.Name = cluster.Name
+		target.Ready = false
+		if clusterutils.IsClusterReady(cluster) {
+			if target.client, err = r.clusterClientSet.GetRuntimeClient(cluster.Name); err != nil {
+				klog.FromContext(ctx).V(4).Info("failed to get cluster client", "cluster", cluster.Name, "error", err)
+				target.client = nil
+			}
+		}
+		if target.client != nil {
+			resourceQuota := &quotav1alpha2.ResourceQuota{}
+			if err := target.client.Get(ctx, types.NamespacedName{Name: workspaceQuota.Name}, resourceQuota); err != nil && !apierrors.IsNotFound(err) {
+				klog.FromContext(ctx).V(4).Info("failed to get resource quota", "cluster", cluster.Name, "error", err)
+				target.client = nil
+			} else {
+				target.Ready = true
+				target.Used = quotav1.Mask(resourceQuota.Status.Total.Used, quotav1.ResourceNames(workspaceQuota.Spec.Hard))
+			}
+		}
+		result = append(result, target)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Name < result[j].Name })
+	return result, nil
+}
+
+// syncAllotments pushes the allotments down to the member clusters and updates the status of the workspace quota.
+func (r *WorkspaceQuotaReconciler) syncAllotments(ctx context.Context, workspaceQuota *quotav1alpha2.WorkspaceQuota) error {
+	clusters, err := r.placementClusters(ctx, workspaceQuota)
+	if err != nil {
+		return err
+	}
+
+	allotments := allot(workspaceQuota, clusters)
+	status := workspaceQuota.Status.DeepCopy()
+	status.Used = nil
+	status.Clusters = make([]quotav1alpha2.ClusterQuotaStatus, 0, len(clusters))
+	placed := make(map[string]bool, len(clusters))
+	var notReadyClusters []string
+	for _, cluster := range clusters {
+		placed[cluster.Name] = true
+		if cluster.Ready {
+			if err := r.allotToCluster(ctx, workspaceQuota, cluster.client, allotments[cluster.Name]); err != nil {
+				klog.FromContext(ctx).V(4).Info("failed to allot quota", "cluster", cluster.Name, "error", err)
+				cluster.Ready = false
+			} else {
+				cluster.Hard = allotments[cluster.Name]
+			}
+		}
+		if !cluster.Ready {
+			notReadyClusters = append(notReadyClusters, cluster.Name)
+		}
+		status.Used = quotav1.Add(status.Used, cluster.Used)
+		status.Clusters = append(status.Clusters, cluster.ClusterQuotaStatus)
+	}
+
+	// the clusters removed from the placement are kept in the status until the allotments are released
+	var unreleasedClusters []string
+	for _, cluster := range workspaceQuota.Status.Clusters {
+		if placed[cluster.Name] {
+			continue
+		}
+		if err := r.releaseAllotment(ctx, workspaceQuota, cluster.Name); err != nil {
+			klog.FromContext(ctx).V(4).Info("failed to release quota", "cluster", cluster.Name, "error", err)
+			cluster.Ready = false
+			cluster.Used = nil
+			unreleasedClusters = append(unreleasedClusters, cluster.Name)
+			status.Clusters = append(status.Clusters, cluster)
+		}
+	}
+
+	if len(unreleasedClusters) > 0 {
+		meta.SetStatusCondition(&status.Conditions, metav1.Condition{
+			Type:    quotav1alpha2.WorkspaceQuotaConditionSynced,
+			Status:  metav1.ConditionFalse,
+			Reason:  "ReleaseFailed",
+			Message: fmt.Sprintf("failed to release the allotments in the clusters: %s", strings.Join(unreleasedClusters, ",")),
+		})
+	} else if len(notReadyClusters) > 0 {
+		meta.SetStatusCondition(&status.Conditions, metav1.Condition{
+			Type:    quotav1alpha2.WorkspaceQuotaConditionSynced,
+			Status:  metav1.ConditionFalse,
+			Reason:  "ClustersNotReady",
+			Message: fmt.Sprintf("the last allotments are kept in the clusters: %s", strings.Join(notReadyClusters, ",")),
+		})
+	} else {
+		meta.SetStatusCondition(&status.Conditions, metav1.Condition{
+			Type:   quotav1alpha2.WorkspaceQuotaConditionSynced,
+			Status: metav1.ConditionTrue,
+			Reason: "Synced",
+		})
+	}
+
+	if exceeded := exceededResources(workspaceQuota.Spec.Hard, status.Used); len(exceeded) > 0 {
+		meta.SetStatusCondition(&status.Conditions, metav1.Condition{
+			Type:    quotav1alpha2.WorkspaceQuotaConditionExceeded,
+			Status:  metav1.ConditionTrue,
+			Reason:  "QuotaExceeded",
+			Message: fmt.Sprintf("resource quota exceeded: %s", strings.Join(exceeded, ",")),
+		})
+	} else {
+		meta.SetStatusCondition(&status.Conditions, metav1.Condition{
+			Type:   quotav1alpha2.WorkspaceQuotaConditionExceeded,
+			Status: metav1.ConditionFalse,
+			Reason: "WithinQuota",
+		})
+	}
+
+	if equality.Semantic.DeepEqual(status, &workspaceQuota.Status) {
+		return nil
+	}
+	updated := workspaceQuota.DeepCopy()
+	updated.Status = *status
+	if err := r.Status().Patch(ctx, updated, client.MergeFrom(workspaceQuota)); err != nil {
+		return errors.Wrapf(err, "failed to update status of workspace quota %s", workspaceQuota.Name)
+	}
+	// the workspace quota is resynced periodically, the event is only recorded when the status changes
+	r.recorder.Event(workspaceQuota, corev1.EventTypeNormal, kscontroller.Synced, kscontroller.MessageResourceSynced)
+	return nil
+}
+
+// allotToCluster creates or updates the resource quota of the workspace in the member cluster.
+func (r *WorkspaceQuotaReconciler) allotToCluster(ctx context.Context, workspaceQuota *quotav1alpha2.WorkspaceQuota, c client.Client, hard corev1.ResourceList) error {
+	resourceQuota := &quotav1alpha2.ResourceQuota{ObjectMeta: metav1.ObjectMeta{Name: workspaceQuota.Name}}
+	_, err := controllerutil.CreateOrUpdate(ctx, c, resourceQuota, func() error {
+		if !resourceQuota.CreationTimestamp.IsZero() && resourceQuota.Labels[quotav1alpha2.WorkspaceQuotaLabel] != workspaceQuota.Name {
+			return fmt.Errorf("resource quota %s is not managed by the workspace quota", resourceQuota.Name)
+		}
+		if resourceQuota.Labels == nil {
+			resourceQuota.Labels = make(map[string]string)
+		}
+		resourceQuota.Labels[tenantv1beta1.WorkspaceLabel] = workspaceQuota.Spec.Workspace
+		resourceQuota.Labels[quotav1alpha2.WorkspaceQuotaLabel] = workspaceQuota.Name
+		resourceQuota.Spec.LabelSelector = map[string]string{tenantv1beta1.WorkspaceLabel: workspaceQuota.Spec.Workspace}
+		resourceQuota.Spec.Quota.Hard = hard
+		return nil
+	})
+	return err
+}
+
+// releaseAllotment removes the resource quota allotted to the cluster, nothing needs to be released
+// if the cluster no longer exists.
+func (r *WorkspaceQuotaReconciler) releaseAllotment(ctx context.Context, workspaceQuota *quotav1alpha2.WorkspaceQuota, cluster string) error {
+	if _, err := r.clusterClientSet.Get(cluster); apierrors.IsNotFound(err) {
+		return nil
+	}
+	c, err := r.clusterClientSet.GetRuntimeClient(cluster)
+	if err != nil {
+		return errors.Wrapf(err, "failed to get client of cluster %s", cluster)
+	}
+	resourceQuota := &quotav1alpha2.ResourceQuota{}
+	if err := c.Get(ctx, types.NamespacedName{Name: workspaceQuota.Name}, resourceQuota); err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil
+		}
+		return errors.Wrapf(err, "failed to get resource quota in cluster %s", cluster)
+	}
+	if resourceQuota.Labels[quotav1alpha2.WorkspaceQuotaLabel] != workspaceQuota.Name {
+		return nil
+	}
+	if err := c.Delete(ctx, resourceQuota); client.IgnoreNotFound(err) != nil {
+		return errors.Wrapf(err, "failed to delete resource quota in cluster %s", cluster)
+	}
+	return nil
+}
+
+// exceededResources returns the resources whose usage has reached the hard limit.
+func exceededResources(hard, used corev1.ResourceList) []string {
+	var exceeded []string
+	for _, name := range quotav1.ResourceNames(hard) {
+		limit := hard[name]
+		if usage, ok := used[name]; ok && !limit.IsZero() && usage.Cmp(limit) >= 0 {
+			exceeded = append(exceeded, string(name))
+		}
+	}
+	sort.Strings(exceeded)
+	return exceeded
+}
+
+// allot divides the capacity of the workspace quota among the clusters by weight. The allotments of the clusters
+// that are not ready are reserved, since they are still enforced in those clusters. With the Borrow policy,
+// each cluster is allotted its current usage plus a weighted share of the unused capacity.
+// The sum of the allotments never exceeds the capacity.
+func allot(workspaceQuota *quotav1alpha2.WorkspaceQuota, clusters []clusterQuota) map[string]corev1.ResourceList {
+	weights := make(map[string]int64, len(workspaceQuota.Spec.Clusters))
+	for _, cluster := range workspaceQuota.Spec.Clusters {
+		weights[cluster.Name] = int64(cluster.Weight)
+	}
+
+	available := workspaceQuota.Spec.Hard.DeepCopy()
+	var totalWeight int64
+	for _, cluster := range clusters {
+		if !cluster.Ready {
+			available = quotav1.SubtractWithNonNegativeResult(available, cluster.Hard)
+			continue
+		}
+		weight, ok := weights[cluster.Name]
+		if !ok {
+			weight = 1
+		}
+		totalWeight += weight
+	}
+	if workspaceQuota.Spec.Policy == quotav1alpha2.AllocationPolicyBorrow {
+		for _, cluster := range clusters {
+			if cluster.Ready {
+				available = quotav1.SubtractWithNonNegativeResult(available, cluster.Used)
+			}
+		}
+	}
+
+	allotments := make(map[string]corev1.ResourceList, len(clusters))
+	for _, cluster := range clusters {
+		if !cluster.Ready {
+			continue
+		}
+		weight, ok := weights[cluster.Name]
+		if !ok {
+			weight = 1
+		}
+		hard := make(corev1.ResourceList, len(available))
+		for name, quantity := range available {
+			hard[name] = share(name, quantity, weight, totalWeight)
+		}
+		if workspaceQuota.Spec.Policy == quotav1alpha2.AllocationPolicyBorrow {
+			hard = quotav1.Add(hard, quotav1.Mask(cluster.Used, quotav1.ResourceNames(available)))
+		}
+		allotments[cluster.Name] = hard
+	}
+	return allotments
+}
+
+// share returns quantity * weight / totalWeight rounded down, the cpu resources are rounded to millicores
+// and the others to whole units.
+func share(name corev1.ResourceName, quantity resource.Quantity, weight, totalWeight int64) resource.Quantity {
+	if totalWeight == 0 || weight == 0 {
+		return *resource.NewQuantity(0, quantity.Format)
+	}
+	milli := strings.HasSuffix(string(name), string(corev1.ResourceCPU))
+	value := big.NewInt(quantity.Value())
+	if milli {
+		value = big.NewInt(quantity.MilliValue())
+	}
+	value.Mul(value, big.NewInt(weight))
+	value.Quo(value, big.NewInt(totalWeight))
+	if milli {
+		return *resource.NewMilliQuantity(value.Int64(), quantity.Format)
+	}
+	return *resource.NewQuantity(value.Int64(), quantity.Format)
+}
+
+// mapWorkspaceToQuotas enqueues the workspace quotas of the workspace.
+func (r *WorkspaceQuotaReconciler) mapWorkspaceToQuotas(ctx context.Context, o client.Object) []reconcile.Request {
+	return r.quotaRequests(ctx, func(workspaceQuota *quotav1alpha2.WorkspaceQuota) bool {
+		return workspaceQuota.Spec.Workspace == o.GetName()
+	})
+}
+
+// mapClusterToQuotas enqueues the workspace quotas of the workspaces placed in the cluster.
+func (r *WorkspaceQuotaReconciler) mapClusterToQuotas(ctx context.Context, o client.Object) []reconcile.Request {
+	cluster := o.(*clusterv1alpha1.Cluster)
+	workspaceTemplates := &tenantv1beta1.WorkspaceTemplateList{}
+	if err := r.List(ctx, workspaceTemplates); err != nil {
+		r.logger.Error(err, "failed to list workspace templates")
+		return nil
+	}
+	workspaces := make(map[string]bool)
+	for i := range workspaceTemplates.Items {
+		if utils.WorkspaceTemplateMatchTargetCluster(&workspaceTemplates.Items[i], cluster) {
+			workspaces[workspaceTemplates.Items[i].Name] = true
+		}
+	}
+	return r.quotaRequests(ctx, func(workspaceQuota *quotav1alpha2.WorkspaceQuota) bool {
+		return workspaces[workspaceQuota.Spec.Workspace]
+	})
+}
+
+func (r *WorkspaceQuotaReconciler) quotaRequests(ctx context.Context, match func(*quotav1alpha2.WorkspaceQuota) bool) []reconcile.Request {
+	workspaceQuotas := &quotav1alpha2.WorkspaceQuotaList{}
+	if err := r.List(ctx, workspaceQuotas); err != nil {
+		r.logger.Error(err, "failed to list workspace quotas")
+		return nil
+	}
+	var result []reconcile.Request
+	for i := range workspaceQuotas.Items {
+		if match(&workspaceQuotas.Items[i]) {
+			result = append(result, reconcile.Request{NamespacedName: types.NamespacedName{Name: workspaceQuotas.Items[i].Name}})
+		}
+	}
+	return result
+}
@@ -0,0 +1,218 @@
+/*
+ * Copyright 2024 the KubeSphere Authors.
+ * Please refer to the LICENSE file in the root directory of the project.
+ * https://github.com/kubesphere/kubesphere/blob/master/LICENSE
+ */
+
+package quota
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/go-logr/logr"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	clusterv1alpha1 "kubesphere.io/api/cluster/v1alpha1"
+	quotav1alpha2 "kubesphere.io/api/quota/v1alpha2"
+	tenantv1beta1 "kubesphere.io/api/tenant/v1beta1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"kubesphere.io/kubesphere/pkg/scheme"
+	"kubesphere.io/kubesphere/pkg/utils/clusterclient"
+)
+
+type fakeClusterClientSet struct {
+	clusters []clusterv1alpha1.Cluster
+	clients  map[string]client.Client
+}
+
+func (f *fakeClusterClientSet) Get(name string) (*clusterv1alpha1.Cluster, error) {
+	for i := range f.clusters {
+		if f.clusters[i].Name == name {
+			return &f.clusters[i], nil
+		}
+	}
+	return nil, fmt.Errorf("cluster %s not found", name)
+}
+
+func (f *fakeClusterClientSet) ListClusters(_ context.Context) ([]clusterv1alpha1.Cluster, error) {
+	return f.clusters, nil
+}
+
+func (f *fakeClusterClientSet) GetClusterClient(name string) (*clusterclient.ClusterClient, error) {
+	c, err := f.GetRuntimeClient(name)
+	if err != nil {
+		return nil, err
+	}
+	return &clusterclient.ClusterClient{Client: c}, nil
+}
+
+func (f *fakeClusterClientSet) GetRuntimeClient(name string) (client.Client, error) {
+	c, ok := f.clients[name]
+	if !ok {
+		return nil, fmt.Errorf("cluster %s not found", name)
+	}
+	return c, nil
+}
+
+func quantity(resources corev1.ResourceList, name corev1.ResourceName) *resource.Quantity {
+	q := resources[name]
+	return &q
+}
+
+func TestAllot(t *testing.T) {
+	workspaceQuota := &quotav1alpha2.WorkspaceQuota{
+		Spec: quotav1alpha2.WorkspaceQuotaSpec{
+			Workspace: "ws1",
+			Hard: corev1.ResourceList{
+				corev1.ResourceLimitsCPU:    resource.MustParse("10"),
+				corev1.ResourceLimitsMemory: resource.MustParse("10Gi"),
+				"count/pods":                resource.MustParse("10"),
+			},
+			Clusters: []quotav1alpha2.ClusterAllotment{{Name: "c2", Weight: 2}},
+		},
+	}
+	ready := func(name string, used corev1.ResourceList) clusterQuota {
+		return clusterQuota{ClusterQuotaStatus: quotav1alpha2.ClusterQuotaStatus{Name: name, Ready: true, Used: used}}
+	}
+
+	// divided by weight
+	allotments := allot(workspaceQuota, []clusterQuota{ready("c1", nil), ready("c2", nil)})
+	assert.Equal(t, "3333m", quantity(allotments["c1"], corev1.ResourceLimitsCPU).String())
+	assert.Equal(t, "6666m", quantity(allotments["c2"], corev1.ResourceLimitsCPU).String())
+	assert.Equal(t, int64(3), quantity(allotments["c1"], "count/pods").Value())
+	assert.Equal(t, int64(6), quantity(allotments["c2"], "count/pods").Value())
+
+	// the allotment of the cluster which is not ready is reserved
+	notReady := clusterQuota{ClusterQuotaStatus: quotav1alpha2.ClusterQuotaStatus{Name: "c3", Hard: corev1.ResourceList{
+		corev1.ResourceLimitsCPU: resource.MustParse("4"),
+		"count/pods":             resource.MustParse("4"),
+	}}}
+	allotments = allot(workspaceQuota, []clusterQuota{ready("c1", nil), notReady})
+	assert.NotContains(t, allotments, "c3")
+	assert.Equal(t, "6", quantity(allotments["c1"], corev1.ResourceLimitsCPU).String())
+	assert.Equal(t, "10Gi", quantity(allotments["c1"], corev1.ResourceLimitsMemory).String())
+
+	// the unused capacity is shared with the borrow policy
+	workspaceQuota.Spec.Policy = quotav1alpha2.AllocationPolicyBorrow
+	workspaceQuota.Spec.Clusters = nil
+	allotments = allot(workspaceQuota, []clusterQuota{
+		ready("c1", corev1.ResourceList{"count/pods": resource.MustParse("6")}),
+		ready("c2", corev1.ResourceList{"count/pods": resource.MustParse("2")}),
+	})
+	assert.Equal(t, int64(7), quantity(allotments["c1"], "count/pods").Value())
+	assert.Equal(t, int64(3), quantity(allotments["c2"], "count/pods").Value())
+
+	// the allotments never exceed the capacity
+	allotments = allot(workspaceQuota, []clusterQuota{
+		ready("c1", corev1.ResourceList{"count/pods": resource.MustParse("8")}),
+		ready("c2", corev1.ResourceList{"count/pods": resource.MustParse("2")}),
+	})
+	assert.Equal(t, int64(8), quantity(allotments["c1"], "count/pods").Value())
+	assert.Equal(t, int64(2), quantity(allotments["c2"], "count/pods").Value())
+}
+
+func TestWorkspaceQuotaReconcile(t *testing.T) {
+	readyCluster := func(name string) clusterv1alpha1.Cluster {
+		return clusterv1alpha1.Cluster{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Status: clusterv1alpha1.ClusterStatus{Conditions: []clusterv1alpha1.ClusterCondition{
+				{Type: clusterv1alpha1.ClusterReady, Status: corev1.ConditionTrue},
+			}},
+		}
+	}
+	workspaceTemplate := &tenantv1beta1.WorkspaceTemplate{
+		ObjectMeta: metav1.ObjectMeta{Name: "ws1"},
+		Spec: tenantv1beta1.WorkspaceTemplateSpec{
+			Placement: tenantv1beta1.GenericPlacement{Clusters: []tenantv1beta1.GenericClusterReference{{Name: "host"}, {Name: "member"}}},
+		},
+	}
+	workspaceQuota := &quotav1alpha2.WorkspaceQuota{
+		ObjectMeta: metav1.ObjectMeta{Name: "ws1", Finalizers: []string{quotav1alpha2.WorkspaceQuotaFinalizer}},
+		Spec: quotav1alpha2.WorkspaceQuotaSpec{
+			Workspace: "ws1",
+			Hard:      corev1.ResourceList{"count/pods": resource.MustParse("10")},
+		},
+	}
+	// the usage reported by the resource quota controller in the member cluster
+	memberQuota := &quotav1alpha2.ResourceQuota{
+		ObjectMeta: metav1.ObjectMeta{Name: "ws1", Labels: map[string]string{quotav1alpha2.WorkspaceQuotaLabel: "ws1"}},
+		Status: quotav1alpha2.ResourceQuotaStatus{Total: corev1.ResourceQuotaStatus{
+			Used: corev1.ResourceList{"count/pods": resource.MustParse("3")},
+		}},
+	}
+
+	hostClient := fake.NewClientBuilder().WithScheme(scheme.Scheme).
+		WithObjects(workspaceTemplate, workspaceQuota).
+		WithStatusSubresource(workspaceQuota).
+		Build()
+	memberClient := fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(memberQuota).Build()
+	r := &WorkspaceQuotaReconciler{
+		Client:   hostClient,
+		logger:   logr.Discard(),
+		recorder: record.NewFakeRecorder(10),
+		clusterClientSet: &fakeClusterClientSet{
+			clusters: []clusterv1alpha1.Cluster{readyCluster("host"), readyCluster("member"), readyCluster("other")},
+			clients:  map[string]client.Client{"host": hostClient, "member": memberClient},
+		},
+	}
+	ctx := context.Background()
+	_, err := r.Reconcile(ctx, ctrl.Request{NamespacedName: types.NamespacedName{Name: "ws1"}})
+	assert.NoError(t, err)
+
+	for _, c := range []client.Client{hostClient, memberClient} {
+		allotted := &quotav1alpha2.ResourceQuota{}
+		assert.NoError(t, c.Get(ctx, types.NamespacedName{Name: "ws1"}, allotted))
+		assert.Equal(t, "ws1", allotted.Labels[tenantv1beta1.WorkspaceLabel])
+		assert.Equal(t, map[string]string{tenantv1beta1.WorkspaceLabel: "ws1"}, allotted.Spec.LabelSelector)
+		assert.Equal(t, int64(5), quantity(allotted.Spec.Quota.Hard, "count/pods").Value())
+	}
+
+	updated := &quotav1alpha2.WorkspaceQuota{}
+	assert.NoError(t, hostClient.Get(ctx, types.NamespacedName{Name: "ws1"}, updated))
+	assert.Len(t, updated.Status.Clusters, 2)
+	assert.Equal(t, int64(3), quantity(updated.Status.Used, "count/pods").Value())
+	assert.True(t, meta.IsStatusConditionTrue(updated.Status.Conditions, quotav1alpha2.WorkspaceQuotaConditionSynced))
+	assert.False(t, meta.IsStatusConditionTrue(updated.Status.Conditions, quotav1alpha2.WorkspaceQuotaConditionExceeded))
+	assert.Len(t, r.recorder.(*record.FakeRecorder).Events, 1)
+
+	// the event is not recorded again if nothing changed
+	_, err = r.Reconcile(ctx, ctrl.Request{NamespacedName: types.NamespacedName{Name: "ws1"}})
+	assert.NoError(t, err)
+	assert.Len(t, r.recorder.(*record.FakeRecorder).Events, 1)
+
+	// the allotment is released after the cluster is removed from the placement
+	workspaceTemplate = &tenantv1beta1.WorkspaceTemplate{}
+	assert.NoError(t, hostClient.Get(ctx, types.NamespacedName{Name: "ws1"}, workspaceTemplate))
+	workspaceTemplate.Spec.Placement.Clusters = []tenantv1beta1.GenericClusterReference{{Name: "host"}}
+	assert.NoError(t, hostClient.Update(ctx, workspaceTemplate))
+	_, err = r.Reconcile(ctx, ctrl.Request{NamespacedName: types.NamespacedName{Name: "ws1"}})
+	assert.NoError(t, err)
+	assert.Error(t, memberClient.Get(ctx, types.NamespacedName{Name: "ws1"}, &quotav1alpha2.ResourceQuota{}))
+
+	allotted := &quotav1alpha2.ResourceQuota{}
+	assert.NoError(t, hostClient.Get(ctx, types.NamespacedName{Name: "ws1"}, allotted))
+	assert.Equal(t, int64(10), quantity(allotted.Spec.Quota.Hard, "count/pods").Value())
+
+	// the finalizer is kept until the allotments in the unreachable clusters are released
+	r.clusterClientSet.(*fakeClusterClientSet).clients = map[string]client.Client{}
+	assert.NoError(t, hostClient.Delete(ctx, updated))
+	_, err = r.Reconcile(ctx, ctrl.Request{NamespacedName: types.NamespacedName{Name: "ws1"}})
+	assert.Error(t, err)
+	assert.NoError(t, hostClient.Get(ctx, types.NamespacedName{Name: "ws1"}, updated))
+	assert.Contains(t, updated.Finalizers, quotav1alpha2.WorkspaceQuotaFinalizer)
+
+	r.clusterClientSet.(*fakeClusterClientSet).clients = map[string]client.Client{"host": hostClient}
+	_, err = r.Reconcile(ctx, ctrl.Request{NamespacedName: types.NamespacedName{Name: "ws1"}})
+	assert.NoError(t, err)
+	assert.Error(t, hostClient.Get(ctx, types.NamespacedName{Name: "ws1"}, &quotav1alpha2.ResourceQuota{}))
+}
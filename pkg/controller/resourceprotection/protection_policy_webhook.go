@@ -0,0 +1,561 @@
+/*
+ * Copyright 2024 the KubeSphere Authors.
+ * Please refer to the LICENSE file in the root directory of the project.
+ * https://github.com/kubesphere/kubesphere/blob/master/LICENSE
+ */
+
+package resourceprotection
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	corev1 "k8s.io/api/core/v1"
+	apiequality "k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/json"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/apiserver/pkg/authentication/serviceaccount"
+	"k8s.io/klog/v2"
+	"k8s.io/utils/ptr"
+	clusterv1alpha1 "kubesphere.io/api/cluster/v1alpha1"
+	corev1alpha1 "kubesphere.io/api/core/v1alpha1"
+	tenantv1beta1 "kubesphere.io/api/tenant/v1beta1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	"kubesphere.io/kubesphere/pkg/constants"
+)
+
+const (
+	protectionDenied     = "ProtectionDenied"
+	protectionOverridden = "ProtectionOverridden"
+
+	auditAnnotationPolicy    = "policy"
+	auditAnnotationOperation = "operation"
+	auditAnnotationDecision  = "decision"
+	auditAnnotationReason    = "reason"
+
+	subresourceFinalize = "finalize"
+	subresourceStatus   = "status"
+	subresourceScale    = "scale"
+
+	// the break-glass annotation is removed once the object is updated with the overridden request
+	breakGlassClearInterval = time.Second
+	breakGlassClearTimeout  = 30 * time.Second
+	breakGlassClearWorkers  = 2
+)
+
+// finalizingControllers finalize the terminating objects, they are not protected by the Finalize operation.
+// The ks-apiserver is not one of them, it writes on behalf of the users.
+var finalizingControllers = sets.New(
+	serviceaccount.MakeUsername(metav1.NamespaceSystem, "namespace-controller"),
+	serviceaccount.MakeUsername(metav1.NamespaceSystem, "generic-garbage-collector"),
+)
+
+// supportedResources are the resources intercepted by the protection policy webhook, see policy.protector.kubesphere.io
+var supportedResources = map[string]sets.Set[string]{
+	corev1.GroupName:          sets.New("Namespace", "PersistentVolumeClaim", "Service", "ConfigMap", "Secret"),
+	"apps":                    sets.New("Deployment", "StatefulSet", "DaemonSet", "ReplicaSet"),
+	tenantv1beta1.GroupName:   sets.New(tenantv1beta1.ResourceKindWorkspaceTemplate),
+	clusterv1alpha1.GroupName: sets.New(clusterv1alpha1.ResourceKindCluster),
+}
+
+// excludedNamespaces are not intercepted by the protection policy webhook, see the namespaceSelector of
+// policy.protector.kubesphere.io
+var excludedNamespaces = []string{metav1.NamespaceSystem, constants.KubeSphereNamespace}
+
+var _ admission.CustomValidator = &Webhook{}
+
+func (w *Webhook) ValidateCreate(_ context.Context, obj runtime.Object) (admission.Warnings, error) {
+	return validatePolicy(obj)
+}
+
+func (w *Webhook) ValidateUpdate(_ context.Context, _, newObj runtime.Object) (admission.Warnings, error) {
+	return validatePolicy(newObj)
+}
+
+func (w *Webhook) ValidateDelete(_ context.Context, _ runtime.Object) (admission.Warnings, error) {
+	return nil, nil
+}
+
+// validatePolicy returns an error if the policy is invalid, and a warning if the policy selects the namespaces
+// which are not intercepted by the webhook, the resources in them are not protected.
+func validatePolicy(obj runtime.Object) (admission.Warnings, error) {
+	policy, ok := obj.(*corev1alpha1.ProtectionPolicy)
+	if !ok {
+		return nil, fmt.Errorf("expected a ProtectionPolicy but got a %T", obj)
+	}
+	if err := validateResources(policy.Spec.Resources); err != nil {
+		return nil, err
+	}
+	if !namespacedResources(policy.Spec.Resources) {
+		return nil, nil
+	}
+	var namespaces []string
+	for _, namespace := range excludedNamespaces {
+		matched, err := selectorMatches(policy.Spec.NamespaceSelector, map[string]string{corev1.LabelMetadataName: namespace})
+		if err != nil {
+			return nil, err
+		}
+		if matched {
+			namespaces = append(namespaces, namespace)
+		}
+	}
+	if len(namespaces) == 0 {
+		return nil, nil
+	}
+	return admission.Warnings{fmt.Sprintf("the resources in the namespaces %s are not protected by the protection policy",
+		strings.Join(namespaces, ", "))}, nil
+}
+
+// namespacedResources returns true if any of the resources is a namespace or a namespaced resource.
+func namespacedResources(resources []corev1alpha1.ProtectedResource) bool {
+	for _, resource := range resources {
+		if resource.APIGroup != tenantv1beta1.GroupName && resource.APIGroup != clusterv1alpha1.GroupName {
+			return true
+		}
+	}
+	return false
+}
+
+// protectionRequest is the admission request with the decoded objects, the target is the object
+// whose labels and annotations are used to match the policies.
+type protectionRequest struct {
+	admission.Request
+	gvk    schema.GroupVersionKind
+	obj    *unstructured.Unstructured
+	oldObj *unstructured.Unstructured
+	target *unstructured.Unstructured
+}
+
+// handlePolicies denies the operations protected by the ProtectionPolicies, the exempted users and groups
+// can override the protection with the break-glass annotation.
+func (w *Webhook) handlePolicies(ctx context.Context, req admission.Request) admission.Response {
+	if req.Operation != admissionv1.Delete && req.Operation != admissionv1.Update {
+		return admission.Allowed("")
+	}
+	policies := &corev1alpha1.ProtectionPolicyList{}
+	if err := w.List(ctx, policies); err != nil {
+		return webhook.Errored(http.StatusInternalServerError, err)
+	}
+	if len(policies.Items) == 0 {
+		return admission.Allowed("")
+	}
+
+	preq, err := w.decodeRequest(ctx, req)
+	if err != nil {
+		return webhook.Errored(http.StatusInternalServerError, err)
+	}
+	if preq == nil || onlyChangesBreakGlass(preq) {
+		return admission.Allowed("")
+	}
+
+	// the request is denied if any of the matched policies is not overridden
+	response := admission.Allowed("")
+	overridden := false
+	for i := range policies.Items {
+		policy := &policies.Items[i]
+		operation, ok := matchOperation(policy, preq)
+		if !ok || !matchResource(policy.Spec.Resources, preq.gvk) {
+			continue
+		}
+		matched, err := w.matchSelectors(ctx, policy, preq)
+		if err != nil {
+			return webhook.Errored(http.StatusInternalServerError, err)
+		}
+		if !matched {
+			continue
+		}
+		if response = w.decide(policy, operation, preq); !response.Allowed {
+			return response
+		}
+		overridden = true
+	}
+	if overridden && req.Operation == admissionv1.Update && !ptr.Deref(req.DryRun, false) {
+		resourceVersion := preq.target.GetResourceVersion()
+		if preq.oldObj != nil {
+			resourceVersion = preq.oldObj.GetResourceVersion()
+		}
+		w.breakGlassQueue.Add(breakGlassRequest{
+			gvk:             preq.gvk,
+			key:             client.ObjectKey{Namespace: req.Namespace, Name: req.Name},
+			resourceVersion: resourceVersion,
+			deadline:        time.Now().Add(breakGlassClearTimeout),
+		})
+	}
+	return response
+}
+
+// onlyChangesBreakGlass returns true if the update only adds, changes or removes the break-glass annotation,
+// such an update is not a protected operation.
+func onlyChangesBreakGlass(req *protectionRequest) bool {
+	if req.Operation != admissionv1.Update || req.SubResource != "" || req.obj == nil || req.oldObj == nil {
+		return false
+	}
+	if req.oldObj.GetAnnotations()[corev1alpha1.BreakGlassAnnotation] == req.obj.GetAnnotations()[corev1alpha1.BreakGlassAnnotation] {
+		return false
+	}
+	oldObj := req.oldObj.DeepCopy()
+	obj := req.obj.DeepCopy()
+	for _, o := range []*unstructured.Unstructured{oldObj, obj} {
+		annotations := o.GetAnnotations()
+		delete(annotations, corev1alpha1.BreakGlassAnnotation)
+		if len(annotations) == 0 {
+			annotations = nil
+		}
+		o.SetAnnotations(annotations)
+		o.SetManagedFields(nil)
+		o.SetResourceVersion("")
+	}
+	return apiequality.Semantic.DeepEqual(oldObj.Object, obj.Object)
+}
+
+// breakGlassRequest is the object updated with an overridden request, the break-glass annotation of it is removed
+// once the resource version is changed.
+type breakGlassRequest struct {
+	gvk             schema.GroupVersionKind
+	key             client.ObjectKey
+	resourceVersion string
+	deadline        time.Time
+}
+
+// Start clears the break-glass annotations, the requests are queued by the replica which admitted the update,
+// so it runs on every replica.
+func (w *Webhook) Start(ctx context.Context) error {
+	for i := 0; i < breakGlassClearWorkers; i++ {
+		go wait.UntilWithContext(ctx, w.clearBreakGlassWorker, time.Second)
+	}
+	<-ctx.Done()
+	w.breakGlassQueue.ShutDown()
+	return nil
+}
+
+func (w *Webhook) NeedLeaderElection() bool {
+	return false
+}
+
+func (w *Webhook) clearBreakGlassWorker(ctx context.Context) {
+	for {
+		req, shutdown := w.breakGlassQueue.Get()
+		if shutdown {
+			return
+		}
+		done, err := w.clearBreakGlass(ctx, req)
+		if err != nil {
+			klog.Warningf("failed to clear the break-glass annotation of %s %s: %v", req.gvk.Kind, req.key, err)
+		}
+		if !done && time.Now().Before(req.deadline) {
+			w.breakGlassQueue.AddAfter(req, breakGlassClearInterval)
+		}
+		w.breakGlassQueue.Done(req)
+	}
+}
+
+// clearBreakGlass removes the break-glass annotation once the overridden update is persisted,
+// so that the reason can not be used to override the later operations. It returns false if the
+// update is not persisted yet and the request should be retried.
+func (w *Webhook) clearBreakGlass(ctx context.Context, req breakGlassRequest) (bool, error) {
+	obj := &unstructured.Unstructured{}
+	obj.SetGroupVersionKind(req.gvk)
+	if err := w.Get(ctx, req.key, obj); err != nil {
+		return apierrors.IsNotFound(err), client.IgnoreNotFound(err)
+	}
+	// the update is not persisted yet, or is rejected by the other admission controllers
+	if obj.GetResourceVersion() == req.resourceVersion {
+		return false, nil
+	}
+	annotations := obj.GetAnnotations()
+	if _, ok := annotations[corev1alpha1.BreakGlassAnnotation]; !ok {
+		return true, nil
+	}
+	patch := client.MergeFromWithOptions(obj.DeepCopy(), client.MergeFromWithOptimisticLock{})
+	delete(annotations, corev1alpha1.BreakGlassAnnotation)
+	obj.SetAnnotations(annotations)
+	if err := w.Patch(ctx, obj, patch); err != nil {
+		if apierrors.IsConflict(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+func (w *Webhook) decodeRequest(ctx context.Context, req admission.Request) (*protectionRequest, error) {
+	gvk, err := w.RESTMapper().KindFor(schema.GroupVersionResource{
+		Group:    req.Resource.Group,
+		Version:  req.Resource.Version,
+		Resource: req.Resource.Resource,
+	})
+	if err != nil {
+		return nil, err
+	}
+	preq := &protectionRequest{Request: req, gvk: gvk}
+	if len(req.Object.Raw) > 0 {
+		preq.obj = &unstructured.Unstructured{}
+		if err = json.Unmarshal(req.Object.Raw, &preq.obj.Object); err != nil {
+			return nil, err
+		}
+	}
+	if len(req.OldObject.Raw) > 0 {
+		preq.oldObj = &unstructured.Unstructured{}
+		if err = json.Unmarshal(req.OldObject.Raw, &preq.oldObj.Object); err != nil {
+			return nil, err
+		}
+	}
+
+	switch {
+	case req.SubResource != "" || preq.oldObj == nil:
+		// the subresources such as scale do not carry the labels and annotations of the object
+		preq.target = &unstructured.Unstructured{}
+		preq.target.SetGroupVersionKind(gvk)
+		if err = w.Get(ctx, client.ObjectKey{Namespace: req.Namespace, Name: req.Name}, preq.target); err != nil {
+			if apierrors.IsNotFound(err) {
+				return nil, nil
+			}
+			return nil, err
+		}
+	case req.Operation == admissionv1.Update:
+		// the break-glass annotation is set with the update
+		preq.target = preq.obj
+	default:
+		preq.target = preq.oldObj
+	}
+	return preq, nil
+}
+
+// matchOperation returns the protected operation of the request.
+func matchOperation(policy *corev1alpha1.ProtectionPolicy, req *protectionRequest) (corev1alpha1.ProtectionOperation, bool) {
+	for _, operation := range policy.Spec.Operations {
+		switch operation {
+		case corev1alpha1.ProtectionOperationDelete:
+			if req.Operation == admissionv1.Delete {
+				return operation, true
+			}
+		case corev1alpha1.ProtectionOperationUpdate:
+			if req.Operation == admissionv1.Update && updatesFields(req, policy.Spec.Fields) {
+				return operation, true
+			}
+		case corev1alpha1.ProtectionOperationScaleToZero:
+			if req.Operation == admissionv1.Update && scalesToZero(req) {
+				return operation, true
+			}
+		case corev1alpha1.ProtectionOperationFinalize:
+			if req.Operation == admissionv1.Update && removesFinalizers(req) {
+				return operation, true
+			}
+		}
+	}
+	return "", false
+}
+
+// updatesFields returns true if any of the fields is changed, any update of the object is matched if no field is specified.
+func updatesFields(req *protectionRequest, fields []string) bool {
+	if req.obj == nil || req.oldObj == nil {
+		return false
+	}
+	if len(fields) == 0 {
+		return req.SubResource == ""
+	}
+	if req.SubResource == subresourceStatus {
+		return false
+	}
+	for _, field := range fields {
+		path := strings.Split(strings.TrimPrefix(field, "."), ".")
+		value, _, _ := unstructured.NestedFieldNoCopy(req.obj.Object, path...)
+		oldValue, _, _ := unstructured.NestedFieldNoCopy(req.oldObj.Object, path...)
+		if !apiequality.Semantic.DeepEqual(value, oldValue) {
+			return true
+		}
+	}
+	return false
+}
+
+// scalesToZero returns true if the replicas are changed to zero, either by the object or by the scale subresource.
+// The old ReplicaSets scaled down by the Deployment controller during the rollouts are not matched.
+func scalesToZero(req *protectionRequest) bool {
+	if req.obj == nil || req.oldObj == nil {
+		return false
+	}
+	if req.gvk.Group == "apps" && req.gvk.Kind == "ReplicaSet" {
+		if owner := metav1.GetControllerOfNoCopy(req.target); owner != nil && owner.Kind == "Deployment" {
+			return false
+		}
+	}
+	replicas, found, _ := unstructured.NestedInt64(req.obj.Object, "spec", "replicas")
+	// the zero replicas are omitted by the scale subresource
+	if (!found && req.SubResource != subresourceScale) || replicas != 0 {
+		return false
+	}
+	// the replicas of the workloads default to 1
+	oldReplicas, found, _ := unstructured.NestedInt64(req.oldObj.Object, "spec", "replicas")
+	if !found {
+		return req.SubResource != subresourceScale
+	}
+	return oldReplicas != 0
+}
+
+// removesFinalizers returns true for the namespace finalization and the removal of the finalizers, except for
+// the controllers which finalize the terminating objects.
+func removesFinalizers(req *protectionRequest) bool {
+	if finalizingControllers.Has(req.UserInfo.Username) || req.obj == nil || req.oldObj == nil {
+		return false
+	}
+	if req.SubResource == subresourceFinalize {
+		return true
+	}
+	return len(req.obj.GetFinalizers()) < len(req.oldObj.GetFinalizers())
+}
+
+// validateResources returns an error if any of the resources is not intercepted by the protection policy webhook.
+func validateResources(resources []corev1alpha1.ProtectedResource) error {
+	for _, resource := range resources {
+		kinds, ok := supportedResources[resource.APIGroup]
+		if !ok || (resource.Kind != "*" && !kinds.Has(resource.Kind)) {
+			return fmt.Errorf("the resource %s of the group %q is not supported by the protection policy", resource.Kind, resource.APIGroup)
+		}
+	}
+	return nil
+}
+
+func matchResource(resources []corev1alpha1.ProtectedResource, gvk schema.GroupVersionKind) bool {
+	for _, resource := range resources {
+		if resource.APIGroup == gvk.Group && (resource.Kind == "*" || resource.Kind == gvk.Kind) {
+			return true
+		}
+	}
+	return false
+}
+
+func (w *Webhook) matchSelectors(ctx context.Context, policy *corev1alpha1.ProtectionPolicy, req *protectionRequest) (bool, error) {
+	objectLabels := req.target.GetLabels()
+	matched, err := selectorMatches(policy.Spec.ObjectSelector, objectLabels)
+	if err == nil && !matched && req.SubResource == "" && req.oldObj != nil {
+		// the labels before the update are matched as well, so that the protection can not be bypassed
+		// by removing the labels
+		matched, err = selectorMatches(policy.Spec.ObjectSelector, req.oldObj.GetLabels())
+	}
+	if err != nil || !matched {
+		return false, err
+	}
+
+	namespaced := true
+	var namespaceLabels map[string]string
+	switch {
+	case req.gvk.Group == corev1.GroupName && req.gvk.Kind == "Namespace":
+		namespaceLabels = objectLabels
+	case req.Namespace != "":
+		namespace := &corev1.Namespace{}
+		if err = w.Get(ctx, client.ObjectKey{Name: req.Namespace}, namespace); err != nil {
+			return false, client.IgnoreNotFound(err)
+		}
+		namespaceLabels = namespace.Labels
+	default:
+		namespaced = false
+	}
+	if policy.Spec.NamespaceSelector != nil {
+		// the namespace selector does not select the cluster scoped resources
+		if !namespaced {
+			return false, nil
+		}
+		if matched, err = selectorMatches(policy.Spec.NamespaceSelector, namespaceLabels); err != nil || !matched {
+			return false, err
+		}
+	}
+
+	if policy.Spec.WorkspaceSelector == nil {
+		return true, nil
+	}
+	var workspaceLabels map[string]string
+	if req.gvk.Group == tenantv1beta1.GroupName && req.gvk.Kind == tenantv1beta1.ResourceKindWorkspaceTemplate {
+		workspaceLabels = objectLabels
+	} else {
+		workspaceName := namespaceLabels[constants.WorkspaceLabelKey]
+		if workspaceName == "" {
+			return false, nil
+		}
+		workspaceTemplate := &tenantv1beta1.WorkspaceTemplate{}
+		if err = w.Get(ctx, client.ObjectKey{Name: workspaceName}, workspaceTemplate); err != nil {
+			return false, client.IgnoreNotFound(err)
+		}
+		workspaceLabels = workspaceTemplate.Labels
+	}
+	return selectorMatches(policy.Spec.WorkspaceSelector, workspaceLabels)
+}
+
+// selectorMatches returns true if the selector is nil or matches the labels.
+func selectorMatches(selector *metav1.LabelSelector, objectLabels map[string]string) (bool, error) {
+	if selector == nil {
+		return true, nil
+	}
+	s, err := metav1.LabelSelectorAsSelector(selector)
+	if err != nil {
+		return false, fmt.Errorf("invalid label selector: %s", err)
+	}
+	return s.Matches(labels.Set(objectLabels)), nil
+}
+
+func exempted(exemption corev1alpha1.ProtectionExemption, userInfo authenticationv1.UserInfo) bool {
+	for _, user := range exemption.Users {
+		if user == userInfo.Username {
+			return true
+		}
+	}
+	for _, group := range exemption.Groups {
+		for _, g := range userInfo.Groups {
+			if group == g {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// decide denies the protected operation, or allows it if the user is exempted and the break-glass reason is given.
+// The decision is recorded as an event of the policy and the audit annotations.
+func (w *Webhook) decide(policy *corev1alpha1.ProtectionPolicy, operation corev1alpha1.ProtectionOperation, req *protectionRequest) admission.Response {
+	userInfo := req.UserInfo
+	resource := req.gvk.Kind + " " + req.Name
+	if req.Namespace != "" {
+		resource = fmt.Sprintf("%s %s/%s", req.gvk.Kind, req.Namespace, req.Name)
+	}
+	auditAnnotations := map[string]string{
+		auditAnnotationPolicy:    policy.Name,
+		auditAnnotationOperation: string(operation),
+	}
+
+	isExempted := exempted(policy.Spec.Exemption, userInfo)
+	reason := strings.TrimSpace(req.target.GetAnnotations()[corev1alpha1.BreakGlassAnnotation])
+	if isExempted && reason != "" {
+		w.recorder.Eventf(policy, corev1.EventTypeWarning, protectionOverridden,
+			"%s of %s is overridden by %s: %s", operation, resource, userInfo.Username, reason)
+		auditAnnotations[auditAnnotationDecision] = "overridden"
+		auditAnnotations[auditAnnotationReason] = reason
+		response := admission.Allowed(fmt.Sprintf("the protection policy %s is overridden", policy.Name))
+		response.AuditAnnotations = auditAnnotations
+		response.Warnings = []string{fmt.Sprintf("%s of %s overrides the protection policy %s", operation, resource, policy.Name)}
+		return response
+	}
+
+	w.recorder.Eventf(policy, corev1.EventTypeWarning, protectionDenied, "%s of %s by %s is denied", operation, resource, userInfo.Username)
+	auditAnnotations[auditAnnotationDecision] = "denied"
+	message := fmt.Sprintf("%s of %s is denied by the protection policy %s", operation, resource, policy.Name)
+	if isExempted {
+		message = fmt.Sprintf("%s, set the annotation %s with the reason to override it", message, corev1alpha1.BreakGlassAnnotation)
+	}
+	response := webhook.Denied(message)
+	response.AuditAnnotations = auditAnnotations
+	return response
+}
@@ -0,0 +1,337 @@
+/*
+ * Copyright 2024 the KubeSphere Authors.
+ * Please refer to the LICENSE file in the root directory of the project.
+ * https://github.com/kubesphere/kubesphere/blob/master/LICENSE
+ */
+
+package resourceprotection
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	admissionv1 "k8s.io/api/admission/v1"
+	appsv1 "k8s.io/api/apps/v1"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	autoscalingv1 "k8s.io/api/autoscaling/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/client-go/util/workqueue"
+	"k8s.io/utils/ptr"
+	corev1alpha1 "kubesphere.io/api/core/v1alpha1"
+	tenantv1beta1 "kubesphere.io/api/tenant/v1beta1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	"kubesphere.io/kubesphere/pkg/constants"
+	"kubesphere.io/kubesphere/pkg/scheme"
+)
+
+func TestProtectionPolicy(t *testing.T) {
+	restMapper := meta.NewDefaultRESTMapper(nil)
+	restMapper.Add(corev1.SchemeGroupVersion.WithKind("Namespace"), meta.RESTScopeRoot)
+	restMapper.Add(appsv1.SchemeGroupVersion.WithKind("Deployment"), meta.RESTScopeNamespace)
+	restMapper.Add(appsv1.SchemeGroupVersion.WithKind("ReplicaSet"), meta.RESTScopeNamespace)
+
+	policy := &corev1alpha1.ProtectionPolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: "production"},
+		Spec: corev1alpha1.ProtectionPolicySpec{
+			Resources: []corev1alpha1.ProtectedResource{
+				{APIGroup: "", Kind: "Namespace"},
+				{APIGroup: "apps", Kind: "*"},
+			},
+			WorkspaceSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"env": "production"}},
+			Operations: []corev1alpha1.ProtectionOperation{
+				corev1alpha1.ProtectionOperationDelete,
+				corev1alpha1.ProtectionOperationScaleToZero,
+				corev1alpha1.ProtectionOperationFinalize,
+				corev1alpha1.ProtectionOperationUpdate,
+			},
+			Fields:    []string{"spec.template.spec.containers"},
+			Exemption: corev1alpha1.ProtectionExemption{Groups: []string{"sre"}},
+		},
+	}
+	namespace := func(name, workspace string) *corev1.Namespace {
+		return &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: name, Labels: map[string]string{constants.WorkspaceLabelKey: workspace}}}
+	}
+	deployment := func(replicas int32, image string, annotations map[string]string) *appsv1.Deployment {
+		return &appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "prod", Annotations: annotations},
+			Spec: appsv1.DeploymentSpec{
+				Replicas: ptr.To(replicas),
+				Template: corev1.PodTemplateSpec{Spec: corev1.PodSpec{Containers: []corev1.Container{{Name: "app", Image: image}}}},
+			},
+		}
+	}
+	replicaSet := func(replicas int32, owner *metav1.OwnerReference) *appsv1.ReplicaSet {
+		rs := &appsv1.ReplicaSet{
+			ObjectMeta: metav1.ObjectMeta{Name: "app-1", Namespace: "prod"},
+			Spec:       appsv1.ReplicaSetSpec{Replicas: ptr.To(replicas)},
+		}
+		if owner != nil {
+			rs.OwnerReferences = []metav1.OwnerReference{*owner}
+		}
+		return rs
+	}
+	deploymentOwner := &metav1.OwnerReference{APIVersion: "apps/v1", Kind: "Deployment", Name: "app", Controller: ptr.To(true)}
+	w := &Webhook{
+		Client: fake.NewClientBuilder().WithScheme(scheme.Scheme).WithRESTMapper(restMapper).WithObjects(
+			policy,
+			&tenantv1beta1.WorkspaceTemplate{ObjectMeta: metav1.ObjectMeta{Name: "ws-prod", Labels: map[string]string{"env": "production"}}},
+			&tenantv1beta1.WorkspaceTemplate{ObjectMeta: metav1.ObjectMeta{Name: "ws-dev"}},
+			namespace("prod", "ws-prod"),
+			namespace("dev", "ws-dev"),
+			deployment(3, "app:v1", nil),
+		).Build(),
+		recorder:        record.NewFakeRecorder(100),
+		breakGlassQueue: workqueue.NewTypedDelayingQueue[breakGlassRequest](),
+	}
+	raw := func(obj runtime.Object) runtime.RawExtension {
+		data, _ := json.Marshal(obj)
+		return runtime.RawExtension{Raw: data}
+	}
+	deployments := metav1.GroupVersionResource{Group: "apps", Version: "v1", Resource: "deployments"}
+	replicaSets := metav1.GroupVersionResource{Group: "apps", Version: "v1", Resource: "replicasets"}
+	namespaces := metav1.GroupVersionResource{Version: "v1", Resource: "namespaces"}
+	breakGlass := map[string]string{corev1alpha1.BreakGlassAnnotation: "incident-42"}
+	admin := authenticationv1.UserInfo{Username: "admin"}
+	sre := authenticationv1.UserInfo{Username: "alice", Groups: []string{"sre"}}
+
+	tests := []struct {
+		name       string
+		request    admissionv1.AdmissionRequest
+		allowed    bool
+		overridden bool
+	}{
+		{
+			name: "delete protected namespace",
+			request: admissionv1.AdmissionRequest{Name: "prod", Operation: admissionv1.Delete, Resource: namespaces,
+				OldObject: raw(namespace("prod", "ws-prod")), UserInfo: admin},
+		},
+		{
+			name: "delete namespace of another workspace",
+			request: admissionv1.AdmissionRequest{Name: "dev", Operation: admissionv1.Delete, Resource: namespaces,
+				OldObject: raw(namespace("dev", "ws-dev")), UserInfo: admin},
+			allowed: true,
+		},
+		{
+			name: "finalize protected namespace",
+			request: admissionv1.AdmissionRequest{Name: "prod", Operation: admissionv1.Update, Resource: namespaces, SubResource: "finalize",
+				Object: raw(namespace("prod", "ws-prod")), OldObject: raw(namespace("prod", "ws-prod")), UserInfo: admin},
+		},
+		{
+			name: "finalize namespace by the namespace controller",
+			request: admissionv1.AdmissionRequest{Name: "prod", Operation: admissionv1.Update, Resource: namespaces, SubResource: "finalize",
+				Object: raw(namespace("prod", "ws-prod")), OldObject: raw(namespace("prod", "ws-prod")),
+				UserInfo: authenticationv1.UserInfo{Username: "system:serviceaccount:kube-system:namespace-controller"}},
+			allowed: true,
+		},
+		{
+			name: "finalize namespace by another service account",
+			request: admissionv1.AdmissionRequest{Name: "prod", Operation: admissionv1.Update, Resource: namespaces, SubResource: "finalize",
+				Object: raw(namespace("prod", "ws-prod")), OldObject: raw(namespace("prod", "ws-prod")),
+				UserInfo: authenticationv1.UserInfo{Username: "system:serviceaccount:prod:default"}},
+		},
+		{
+			name: "scale old replica set of deployment to zero",
+			request: admissionv1.AdmissionRequest{Name: "app-1", Namespace: "prod", Operation: admissionv1.Update, Resource: replicaSets,
+				Object: raw(replicaSet(0, deploymentOwner)), OldObject: raw(replicaSet(3, deploymentOwner)),
+				UserInfo: authenticationv1.UserInfo{Username: "system:serviceaccount:kube-system:deployment-controller"}},
+			allowed: true,
+		},
+		{
+			name: "scale standalone replica set to zero",
+			request: admissionv1.AdmissionRequest{Name: "app-1", Namespace: "prod", Operation: admissionv1.Update, Resource: replicaSets,
+				Object: raw(replicaSet(0, nil)), OldObject: raw(replicaSet(3, nil)), UserInfo: admin},
+		},
+		{
+			name: "scale deployment to zero",
+			request: admissionv1.AdmissionRequest{Name: "app", Namespace: "prod", Operation: admissionv1.Update, Resource: deployments,
+				Object: raw(deployment(0, "app:v1", nil)), OldObject: raw(deployment(3, "app:v1", nil)), UserInfo: admin},
+		},
+		{
+			name: "scale deployment to zero through subresource",
+			request: admissionv1.AdmissionRequest{Name: "app", Namespace: "prod", Operation: admissionv1.Update, Resource: deployments, SubResource: "scale",
+				Object:    raw(&autoscalingv1.Scale{Spec: autoscalingv1.ScaleSpec{Replicas: 0}}),
+				OldObject: raw(&autoscalingv1.Scale{Spec: autoscalingv1.ScaleSpec{Replicas: 3}}), UserInfo: admin},
+		},
+		{
+			name: "scale deployment down",
+			request: admissionv1.AdmissionRequest{Name: "app", Namespace: "prod", Operation: admissionv1.Update, Resource: deployments,
+				Object: raw(deployment(1, "app:v1", nil)), OldObject: raw(deployment(3, "app:v1", nil)), UserInfo: admin},
+			allowed: true,
+		},
+		{
+			name: "update protected field",
+			request: admissionv1.AdmissionRequest{Name: "app", Namespace: "prod", Operation: admissionv1.Update, Resource: deployments,
+				Object: raw(deployment(3, "app:v2", nil)), OldObject: raw(deployment(3, "app:v1", nil)), UserInfo: admin},
+		},
+		{
+			name: "update protected field by exempted group without break-glass reason",
+			request: admissionv1.AdmissionRequest{Name: "app", Namespace: "prod", Operation: admissionv1.Update, Resource: deployments,
+				Object: raw(deployment(3, "app:v2", nil)), OldObject: raw(deployment(3, "app:v1", nil)), UserInfo: sre},
+		},
+		{
+			name: "update protected field by non-exempted user with break-glass reason",
+			request: admissionv1.AdmissionRequest{Name: "app", Namespace: "prod", Operation: admissionv1.Update, Resource: deployments,
+				Object: raw(deployment(3, "app:v2", breakGlass)), OldObject: raw(deployment(3, "app:v1", nil)), UserInfo: admin},
+		},
+		{
+			name: "override by exempted group with break-glass reason",
+			request: admissionv1.AdmissionRequest{Name: "app", Namespace: "prod", Operation: admissionv1.Update, Resource: deployments,
+				Object: raw(deployment(3, "app:v2", breakGlass)), OldObject: raw(deployment(3, "app:v1", nil)), UserInfo: sre},
+			allowed:    true,
+			overridden: true,
+		},
+		{
+			name: "remove break-glass annotation",
+			request: admissionv1.AdmissionRequest{Name: "app", Namespace: "prod", Operation: admissionv1.Update, Resource: deployments,
+				Object: raw(deployment(3, "app:v2", nil)), OldObject: raw(deployment(3, "app:v2", breakGlass)), UserInfo: admin},
+			allowed: true,
+		},
+		{
+			name: "remove break-glass annotation with protected field",
+			request: admissionv1.AdmissionRequest{Name: "app", Namespace: "prod", Operation: admissionv1.Update, Resource: deployments,
+				Object: raw(deployment(3, "app:v3", nil)), OldObject: raw(deployment(3, "app:v2", breakGlass)), UserInfo: admin},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := w.handlePolicies(context.Background(), admission.Request{AdmissionRequest: tt.request})
+			assert.Equal(t, tt.allowed, resp.Allowed, resp.Result)
+			if !tt.allowed {
+				assert.Equal(t, "denied", resp.AuditAnnotations[auditAnnotationDecision])
+			}
+			if tt.overridden {
+				assert.Equal(t, "overridden", resp.AuditAnnotations[auditAnnotationDecision])
+				assert.Equal(t, "incident-42", resp.AuditAnnotations[auditAnnotationReason])
+			}
+		})
+	}
+}
+
+func TestBreakGlassAnnotation(t *testing.T) {
+	restMapper := meta.NewDefaultRESTMapper(nil)
+	restMapper.Add(appsv1.SchemeGroupVersion.WithKind("Deployment"), meta.RESTScopeNamespace)
+	policy := &corev1alpha1.ProtectionPolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: "frozen"},
+		Spec: corev1alpha1.ProtectionPolicySpec{
+			Resources:  []corev1alpha1.ProtectedResource{{APIGroup: "apps", Kind: "Deployment"}},
+			Operations: []corev1alpha1.ProtectionOperation{corev1alpha1.ProtectionOperationUpdate},
+			Exemption:  corev1alpha1.ProtectionExemption{Users: []string{"alice"}},
+		},
+	}
+	w := &Webhook{
+		Client: fake.NewClientBuilder().WithScheme(scheme.Scheme).WithRESTMapper(restMapper).WithObjects(
+			policy, &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "prod"}}).Build(),
+		recorder:        record.NewFakeRecorder(100),
+		breakGlassQueue: workqueue.NewTypedDelayingQueue[breakGlassRequest](),
+	}
+	deployment := func(image, reason string) runtime.RawExtension {
+		d := &appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "prod", ResourceVersion: "1"},
+			Spec:       appsv1.DeploymentSpec{Template: corev1.PodTemplateSpec{Spec: corev1.PodSpec{Containers: []corev1.Container{{Name: "app", Image: image}}}}},
+		}
+		if reason != "" {
+			d.Annotations = map[string]string{corev1alpha1.BreakGlassAnnotation: reason}
+		}
+		data, _ := json.Marshal(d)
+		return runtime.RawExtension{Raw: data}
+	}
+	handle := func(obj, oldObj runtime.RawExtension) admission.Response {
+		return w.handlePolicies(context.Background(), admission.Request{AdmissionRequest: admissionv1.AdmissionRequest{
+			Name: "app", Namespace: "prod", Operation: admissionv1.Update,
+			Resource: metav1.GroupVersionResource{Group: "apps", Version: "v1", Resource: "deployments"},
+			Object:   obj, OldObject: oldObj, UserInfo: authenticationv1.UserInfo{Username: "alice"},
+		}})
+	}
+
+	// setting or changing the reason is not a protected update
+	resp := handle(deployment("app:v1", "incident-42"), deployment("app:v1", ""))
+	assert.True(t, resp.Allowed)
+	assert.Empty(t, resp.AuditAnnotations)
+	resp = handle(deployment("app:v1", "incident-43"), deployment("app:v1", "incident-42"))
+	assert.True(t, resp.Allowed)
+	assert.Empty(t, resp.AuditAnnotations)
+	assert.Equal(t, 0, w.breakGlassQueue.Len())
+
+	resp = handle(deployment("app:v2", "incident-43"), deployment("app:v1", "incident-43"))
+	assert.True(t, resp.Allowed)
+	assert.Equal(t, "overridden", resp.AuditAnnotations[auditAnnotationDecision])
+	assert.Equal(t, 1, w.breakGlassQueue.Len())
+}
+
+func TestClearBreakGlass(t *testing.T) {
+	deployment := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "prod", Annotations: map[string]string{corev1alpha1.BreakGlassAnnotation: "incident-42"}},
+	}
+	w := &Webhook{Client: fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(deployment).Build()}
+	req := breakGlassRequest{
+		gvk: appsv1.SchemeGroupVersion.WithKind("Deployment"),
+		key: client.ObjectKeyFromObject(deployment),
+	}
+	current := &appsv1.Deployment{}
+	assert.NoError(t, w.Get(context.Background(), req.key, current))
+
+	// the overridden update is not persisted yet
+	req.resourceVersion = current.ResourceVersion
+	done, err := w.clearBreakGlass(context.Background(), req)
+	assert.NoError(t, err)
+	assert.False(t, done)
+
+	req.resourceVersion = "0"
+	done, err = w.clearBreakGlass(context.Background(), req)
+	assert.NoError(t, err)
+	assert.True(t, done)
+
+	updated := &appsv1.Deployment{}
+	assert.NoError(t, w.Get(context.Background(), req.key, updated))
+	assert.NotContains(t, updated.Annotations, corev1alpha1.BreakGlassAnnotation)
+}
+
+func TestValidatePolicy(t *testing.T) {
+	policy := func(namespaceSelector *metav1.LabelSelector, resources ...corev1alpha1.ProtectedResource) *corev1alpha1.ProtectionPolicy {
+		return &corev1alpha1.ProtectionPolicy{Spec: corev1alpha1.ProtectionPolicySpec{
+			Resources:         resources,
+			NamespaceSelector: namespaceSelector,
+			Operations:        []corev1alpha1.ProtectionOperation{corev1alpha1.ProtectionOperationDelete},
+		}}
+	}
+	configMaps := corev1alpha1.ProtectedResource{APIGroup: "", Kind: "ConfigMap"}
+
+	warnings, err := validatePolicy(policy(nil, configMaps))
+	assert.NoError(t, err)
+	assert.Len(t, warnings, 1)
+	assert.Contains(t, warnings[0], "kube-system, kubesphere-system")
+
+	warnings, err = validatePolicy(policy(&metav1.LabelSelector{MatchLabels: map[string]string{corev1.LabelMetadataName: "kube-system"}}, configMaps))
+	assert.NoError(t, err)
+	assert.Len(t, warnings, 1)
+
+	warnings, err = validatePolicy(policy(&metav1.LabelSelector{MatchLabels: map[string]string{"env": "production"}}, configMaps))
+	assert.NoError(t, err)
+	assert.Empty(t, warnings)
+
+	warnings, err = validatePolicy(policy(nil, corev1alpha1.ProtectedResource{APIGroup: "tenant.kubesphere.io", Kind: "WorkspaceTemplate"}))
+	assert.NoError(t, err)
+	assert.Empty(t, warnings)
+
+	_, err = validatePolicy(policy(nil, corev1alpha1.ProtectedResource{APIGroup: "", Kind: "Pod"}))
+	assert.Error(t, err)
+}
+
+func TestValidateResources(t *testing.T) {
+	assert.NoError(t, validateResources([]corev1alpha1.ProtectedResource{
+		{APIGroup: "", Kind: "Namespace"},
+		{APIGroup: "apps", Kind: "*"},
+		{APIGroup: "tenant.kubesphere.io", Kind: "WorkspaceTemplate"},
+	}))
+	assert.Error(t, validateResources([]corev1alpha1.ProtectedResource{{APIGroup: "", Kind: "Pod"}}))
+	assert.Error(t, validateResources([]corev1alpha1.ProtectedResource{{APIGroup: "batch", Kind: "*"}}))
+}
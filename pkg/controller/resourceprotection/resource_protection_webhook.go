@@ -13,6 +13,10 @@ import (
 	admissionv1 "k8s.io/api/admission/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/client-go/util/workqueue"
+	corev1alpha1 "kubesphere.io/api/core/v1alpha1"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/webhook"
 	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
@@ -29,12 +33,24 @@ func (w *Webhook) Name() string {
 
 type Webhook struct {
 	client.Client
+	recorder record.EventRecorder
+	// breakGlassQueue queues the objects updated with the overridden requests
+	breakGlassQueue workqueue.TypedDelayingInterface[breakGlassRequest]
 }
 
 func (w *Webhook) SetupWithManager(mgr *kscontroller.Manager) error {
 	w.Client = mgr.GetClient()
+	w.recorder = mgr.GetEventRecorderFor(webhookName)
+	w.breakGlassQueue = workqueue.NewTypedDelayingQueueWithConfig(workqueue.TypedDelayingQueueConfig[breakGlassRequest]{Name: "break-glass"})
+	if err := mgr.Add(w); err != nil {
+		return err
+	}
 	mgr.GetWebhookServer().Register("/resource-protector", &webhook.Admission{Handler: w})
-	return nil
+	mgr.GetWebhookServer().Register("/protection-policy", &webhook.Admission{Handler: admission.HandlerFunc(w.handlePolicies)})
+	return builder.WebhookManagedBy(mgr).
+		For(&corev1alpha1.ProtectionPolicy{}).
+		WithValidator(w).
+		Complete()
 }
 
 func (w *Webhook) Handle(ctx context.Context, req admission.Request) admission.Response {
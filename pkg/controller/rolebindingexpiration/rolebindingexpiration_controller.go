@@ -0,0 +1,189 @@
+/*
+ * Copyright 2024 the KubeSphere Authors.
+ * Please refer to the LICENSE file in the root directory of the project.
+ * https://github.com/kubesphere/kubesphere/blob/master/LICENSE
+ */
+
+package rolebindingexpiration
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/tools/record"
+	clusterv1alpha1 "kubesphere.io/api/cluster/v1alpha1"
+	iamv1beta1 "kubesphere.io/api/iam/v1beta1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"kubesphere.io/kubesphere/pkg/apiserver/authorization"
+	kscontroller "kubesphere.io/kubesphere/pkg/controller"
+)
+
+const (
+	controllerNameSuffix = "-expiration"
+
+	reasonExpiring = "Expiring"
+	reasonExpired  = "Expired"
+)
+
+var _ kscontroller.Controller = &Reconciler{}
+var _ kscontroller.ClusterSelector = &Reconciler{}
+var _ reconcile.Reconciler = &Reconciler{}
+
+// Reconciler deletes the expired role bindings of the kind, and warns the users before the role bindings expire.
+type Reconciler struct {
+	client.Client
+	// Kind is one of GlobalRoleBinding, WorkspaceRoleBinding, ClusterRoleBinding and RoleBinding.
+	Kind          string
+	logger        logr.Logger
+	recorder      record.EventRecorder
+	warningPeriod time.Duration
+}
+
+func (r *Reconciler) Name() string {
+	return strings.ToLower(r.Kind) + controllerNameSuffix
+}
+
+func (r *Reconciler) Enabled(clusterRole string) bool {
+	switch r.Kind {
+	// the global role bindings and the workspace role bindings are managed in the host cluster
+	case iamv1beta1.ResourceKindGlobalRoleBinding, iamv1beta1.ResourceKindWorkspaceRoleBinding:
+		return strings.EqualFold(clusterRole, string(clusterv1alpha1.ClusterRoleHost))
+	default:
+		return true
+	}
+}
+
+func (r *Reconciler) SetupWithManager(mgr *kscontroller.Manager) error {
+	r.Client = mgr.GetClient()
+	r.logger = ctrl.Log.WithName("controllers").WithName(r.Name())
+	r.recorder = mgr.GetEventRecorderFor(r.Name())
+	r.warningPeriod = authorization.NewOptions().RoleBindingExpirationWarningPeriod
+	if mgr.AuthorizationOptions != nil {
+		r.warningPeriod = mgr.AuthorizationOptions.RoleBindingExpirationWarningPeriod
+	}
+	object, err := r.newObject()
+	if err != nil {
+		return err
+	}
+	return ctrl.NewControllerManagedBy(mgr).
+		Named(r.Name()).
+		For(object, builder.WithPredicates(predicate.NewPredicateFuncs(func(object client.Object) bool {
+			return object.GetAnnotations()[iamv1beta1.ExpirationTimeAnnotation] != ""
+		}))).
+		Complete(r)
+}
+
+func (r *Reconciler) newObject() (client.Object, error) {
+	switch r.Kind {
+	case iamv1beta1.ResourceKindGlobalRoleBinding:
+		return &iamv1beta1.GlobalRoleBinding{}, nil
+	case iamv1beta1.ResourceKindWorkspaceRoleBinding:
+		return &iamv1beta1.WorkspaceRoleBinding{}, nil
+	case iamv1beta1.ResourceKindClusterRoleBinding:
+		return &iamv1beta1.ClusterRoleBinding{}, nil
+	case iamv1beta1.ResourceKindRoleBinding:
+		return &iamv1beta1.RoleBinding{}, nil
+	default:
+		return nil, fmt.Errorf("unsupported role binding kind %s", r.Kind)
+	}
+}
+
+func (r *Reconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	logger := r.logger.WithValues(r.Kind, req.NamespacedName)
+	roleBinding, err := r.newObject()
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	if err = r.Get(ctx, req.NamespacedName, roleBinding); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+	if !roleBinding.GetDeletionTimestamp().IsZero() {
+		return ctrl.Result{}, nil
+	}
+
+	value := roleBinding.GetAnnotations()[iamv1beta1.ExpirationTimeAnnotation]
+	if value == "" {
+		return ctrl.Result{}, nil
+	}
+	expiration, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		r.recorder.Event(roleBinding, corev1.EventTypeWarning, kscontroller.SyncFailed, fmt.Sprintf("invalid expiration time %q", value))
+		return ctrl.Result{}, nil
+	}
+
+	user := r.userOf(ctx, roleBinding)
+	now := time.Now()
+	if !now.Before(expiration) {
+		if err = r.Delete(ctx, roleBinding); err != nil {
+			return ctrl.Result{}, client.IgnoreNotFound(err)
+		}
+		message := fmt.Sprintf("%s %s of role %s has expired at %s", r.Kind, roleBinding.GetName(), roleRefOf(roleBinding), value)
+		r.recorder.Event(roleBinding, corev1.EventTypeNormal, reasonExpired, message)
+		if user != nil {
+			r.recorder.Event(user, corev1.EventTypeNormal, reasonExpired, message)
+		}
+		logger.V(4).Info("expired role binding deleted")
+		return ctrl.Result{}, nil
+	}
+
+	warningTime := expiration.Add(-r.warningPeriod)
+	if r.warningPeriod > 0 && !now.Before(warningTime) {
+		if roleBinding.GetAnnotations()[iamv1beta1.ExpirationWarningAnnotation] != value {
+			message := fmt.Sprintf("%s %s of role %s will expire at %s", r.Kind, roleBinding.GetName(), roleRefOf(roleBinding), value)
+			r.recorder.Event(roleBinding, corev1.EventTypeWarning, reasonExpiring, message)
+			if user != nil {
+				r.recorder.Event(user, corev1.EventTypeWarning, reasonExpiring, message)
+			}
+			// warn only once before the role binding expires
+			expected := roleBinding.DeepCopyObject().(client.Object)
+			annotations := expected.GetAnnotations()
+			annotations[iamv1beta1.ExpirationWarningAnnotation] = value
+			expected.SetAnnotations(annotations)
+			if err = r.Patch(ctx, expected, client.MergeFrom(roleBinding)); err != nil {
+				return ctrl.Result{}, err
+			}
+		}
+		return ctrl.Result{RequeueAfter: expiration.Sub(now)}, nil
+	}
+	if r.warningPeriod > 0 {
+		return ctrl.Result{RequeueAfter: warningTime.Sub(now)}, nil
+	}
+	return ctrl.Result{RequeueAfter: expiration.Sub(now)}, nil
+}
+
+// userOf returns the user bound by the role binding, nil if it's not found, e.g. it's in the member cluster.
+func (r *Reconciler) userOf(ctx context.Context, roleBinding client.Object) *iamv1beta1.User {
+	username := roleBinding.GetLabels()[iamv1beta1.UserReferenceLabel]
+	if username == "" {
+		return nil
+	}
+	user := &iamv1beta1.User{}
+	if err := r.Get(ctx, client.ObjectKey{Name: username}, user); err != nil {
+		return nil
+	}
+	return user
+}
+
+func roleRefOf(roleBinding client.Object) string {
+	switch binding := roleBinding.(type) {
+	case *iamv1beta1.GlobalRoleBinding:
+		return binding.RoleRef.Name
+	case *iamv1beta1.WorkspaceRoleBinding:
+		return binding.RoleRef.Name
+	case *iamv1beta1.ClusterRoleBinding:
+		return binding.RoleRef.Name
+	case *iamv1beta1.RoleBinding:
+		return binding.RoleRef.Name
+	default:
+		return ""
+	}
+}
@@ -0,0 +1,87 @@
+/*
+ * Copyright 2024 the KubeSphere Authors.
+ * Please refer to the LICENSE file in the root directory of the project.
+ * https://github.com/kubesphere/kubesphere/blob/master/LICENSE
+ */
+
+package rolebindingexpiration
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	rbacv1 "k8s.io/api/rbac/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	iamv1beta1 "kubesphere.io/api/iam/v1beta1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"kubesphere.io/kubesphere/pkg/scheme"
+)
+
+func newRoleBinding(name string, expiration time.Time) *iamv1beta1.RoleBinding {
+	return &iamv1beta1.RoleBinding{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        name,
+			Namespace:   "default",
+			Labels:      map[string]string{iamv1beta1.UserReferenceLabel: "contractor"},
+			Annotations: map[string]string{iamv1beta1.ExpirationTimeAnnotation: expiration.UTC().Format(time.RFC3339)},
+		},
+		RoleRef: rbacv1.RoleRef{APIGroup: iamv1beta1.SchemeGroupVersion.Group, Kind: iamv1beta1.ResourceKindRole, Name: "admin"},
+	}
+}
+
+func TestReconcile(t *testing.T) {
+	now := time.Now()
+	user := &iamv1beta1.User{ObjectMeta: metav1.ObjectMeta{Name: "contractor"}}
+	client := fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(
+		user,
+		newRoleBinding("expired", now.Add(-time.Minute)),
+		newRoleBinding("expiring", now.Add(time.Hour)),
+		newRoleBinding("permanent", now.Add(30*24*time.Hour)),
+	).Build()
+	recorder := record.NewFakeRecorder(10)
+	r := &Reconciler{
+		Client:        client,
+		Kind:          iamv1beta1.ResourceKindRoleBinding,
+		logger:        ctrl.Log.WithName("controllers").WithName("rolebinding-expiration"),
+		recorder:      recorder,
+		warningPeriod: 72 * time.Hour,
+	}
+	assert.Equal(t, "rolebinding-expiration", r.Name())
+	assert.True(t, r.Enabled("member"))
+	ctx := context.Background()
+
+	// the expired role binding is deleted
+	_, err := r.Reconcile(ctx, ctrl.Request{NamespacedName: types.NamespacedName{Namespace: "default", Name: "expired"}})
+	assert.NoError(t, err)
+	err = client.Get(ctx, types.NamespacedName{Namespace: "default", Name: "expired"}, &iamv1beta1.RoleBinding{})
+	assert.True(t, apierrors.IsNotFound(err))
+	assert.Contains(t, <-recorder.Events, "Expired")
+	assert.Contains(t, <-recorder.Events, "Expired")
+
+	// the user is warned once before the role binding expires
+	request := ctrl.Request{NamespacedName: types.NamespacedName{Namespace: "default", Name: "expiring"}}
+	result, err := r.Reconcile(ctx, request)
+	assert.NoError(t, err)
+	assert.True(t, result.RequeueAfter > 59*time.Minute && result.RequeueAfter <= time.Hour)
+	assert.Contains(t, <-recorder.Events, "Expiring")
+	assert.Contains(t, <-recorder.Events, "Expiring")
+	roleBinding := &iamv1beta1.RoleBinding{}
+	assert.NoError(t, client.Get(ctx, request.NamespacedName, roleBinding))
+	assert.Equal(t, roleBinding.Annotations[iamv1beta1.ExpirationTimeAnnotation], roleBinding.Annotations[iamv1beta1.ExpirationWarningAnnotation])
+	_, err = r.Reconcile(ctx, request)
+	assert.NoError(t, err)
+	assert.Empty(t, recorder.Events)
+
+	// requeue until the warning period
+	result, err = r.Reconcile(ctx, ctrl.Request{NamespacedName: types.NamespacedName{Namespace: "default", Name: "permanent"}})
+	assert.NoError(t, err)
+	assert.True(t, result.RequeueAfter > 26*24*time.Hour && result.RequeueAfter <= 27*24*time.Hour)
+	assert.Empty(t, recorder.Events)
+}
@@ -9,10 +9,14 @@ package secret
 import (
 	"context"
 	"fmt"
+	"slices"
+	"time"
 
 	"github.com/go-logr/logr"
+	"github.com/golang-jwt/jwt/v4"
 	v1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/util/uuid"
 	"k8s.io/apiserver/pkg/authentication/user"
 	"k8s.io/client-go/tools/record"
 	corev1alpha1 "kubesphere.io/api/core/v1alpha1"
@@ -24,7 +28,9 @@ import (
 
 	"kubesphere.io/kubesphere/pkg/apiserver/authentication/oauth"
 	"kubesphere.io/kubesphere/pkg/apiserver/authentication/token"
+	"kubesphere.io/kubesphere/pkg/apiserver/authorization/scope"
 	kscontroller "kubesphere.io/kubesphere/pkg/controller"
+	"kubesphere.io/kubesphere/pkg/utils/serviceaccount"
 )
 
 const (
@@ -75,10 +81,18 @@ func (r *ServiceAccountSecretReconciler) Reconcile(ctx context.Context, req ctrl
 	}
 
 	saName := secret.Annotations[corev1alpha1.ServiceAccountName]
-	if secret.Data[corev1alpha1.ServiceAccountToken] == nil &&
-		saName != "" {
-		sa := &corev1alpha1.ServiceAccount{}
+	if saName == "" {
+		return ctrl.Result{}, nil
+	}
+
+	options, err := tokenOptionsFrom(secret)
+	if err != nil {
+		r.EventRecorder.Event(secret, v1.EventTypeWarning, kscontroller.SyncFailed, err.Error())
+		return ctrl.Result{}, nil
+	}
 
+	if issue, previousID := r.shouldIssueToken(secret, options); issue {
+		sa := &corev1alpha1.ServiceAccount{}
 		if err := r.Get(ctx, client.ObjectKey{Namespace: req.Namespace, Name: saName}, sa); err != nil {
 			if errors.IsNotFound(err) {
 				return ctrl.Result{}, nil
@@ -87,7 +101,7 @@ func (r *ServiceAccountSecretReconciler) Reconcile(ctx context.Context, req ctrl
 			return ctrl.Result{}, err
 		}
 
-		tokenTo, err := r.issueTokenTo(sa)
+		tokenTo, err := r.issueTokenTo(sa, secret, options)
 		if err != nil {
 			logger.Error(err, "issue token failed")
 			return ctrl.Result{}, err
@@ -96,32 +110,132 @@ func (r *ServiceAccountSecretReconciler) Reconcile(ctx context.Context, req ctrl
 			secret.Data = make(map[string][]byte, 0)
 		}
 		secret.Data[corev1alpha1.ServiceAccountToken] = []byte(tokenTo.AccessToken)
+		secret.Annotations[corev1alpha1.ServiceAccountTokenID] = tokenTo.ID
+		delete(secret.Annotations, corev1alpha1.ServiceAccountTokenPreviousID)
+		if previousID != "" {
+			secret.Annotations[corev1alpha1.ServiceAccountTokenPreviousID] = previousID
+		}
+		delete(secret.Annotations, corev1alpha1.ServiceAccountTokenExpiration)
+		if tokenTo.ExpiresAt != nil {
+			secret.Annotations[corev1alpha1.ServiceAccountTokenExpiration] = tokenTo.ExpiresAt.UTC().Format(time.RFC3339)
+		}
 		if err = r.Update(ctx, secret); err != nil {
 			logger.Error(err, "update secret failed")
 			return ctrl.Result{}, err
 		}
 	}
 
+	// rotate the token before it expires
+	if options.ttl > 0 {
+		if expiration, err := time.Parse(time.RFC3339, secret.Annotations[corev1alpha1.ServiceAccountTokenExpiration]); err == nil {
+			return ctrl.Result{RequeueAfter: time.Until(expiration.Add(-options.rotationOverlap))}, nil
+		}
+	}
+
 	return ctrl.Result{}, nil
 }
 
-func (r *ServiceAccountSecretReconciler) issueTokenTo(sa *corev1alpha1.ServiceAccount) (*oauth.Token, error) {
-	// We verify that the token is valid by checking the validity of SA, so we issue a token with no expiration date
+// tokenOptions is the lifetime and the scopes of the tokens issued to the secret.
+type tokenOptions struct {
+	ttl             time.Duration
+	rotationOverlap time.Duration
+	scopes          []string
+}
+
+func tokenOptionsFrom(secret *v1.Secret) (*tokenOptions, error) {
+	options := &tokenOptions{}
+	var err error
+	if value := secret.Annotations[corev1alpha1.ServiceAccountTokenTTL]; value != "" {
+		if options.ttl, err = time.ParseDuration(value); err != nil || options.ttl <= 0 {
+			return nil, fmt.Errorf("invalid token ttl %q", value)
+		}
+		options.rotationOverlap = options.ttl / 5
+	}
+	if value := secret.Annotations[corev1alpha1.ServiceAccountTokenRotationOverlap]; value != "" && options.ttl > 0 {
+		if options.rotationOverlap, err = time.ParseDuration(value); err != nil || options.rotationOverlap < 0 || options.rotationOverlap >= options.ttl {
+			return nil, fmt.Errorf("invalid token rotation overlap %q, it must be less than the ttl", value)
+		}
+	}
+	if options.scopes, err = scope.ParseScopes(secret.Annotations[corev1alpha1.ServiceAccountTokenScopes]); err != nil {
+		return nil, err
+	}
+	return options, nil
+}
+
+// shouldIssueToken returns whether a new token should be issued, e.g. the token is about to expire,
+// it's revoked or the options are changed. The ID of the current token is returned if it's still valid
+// after the new token is issued, which is only the case when it's rotated before expiring.
+func (r *ServiceAccountSecretReconciler) shouldIssueToken(secret *v1.Secret, options *tokenOptions) (bool, string) {
+	current := secret.Data[corev1alpha1.ServiceAccountToken]
+	if len(current) == 0 {
+		return true, ""
+	}
+	verified, err := r.TokenIssuer.Verify(string(current))
+	if err != nil {
+		return true, ""
+	}
+	// the tokens without the ID or the secret can't be revoked, e.g. the ones issued before the revocation is supported
+	if secretName, secretNamespace := serviceaccount.GetSecretName(verified.User); verified.ID == "" ||
+		secretName != secret.Name || secretNamespace != secret.Namespace {
+		return true, ""
+	}
+	if serviceaccount.IsRevoked(secret, verified.ID) {
+		return true, ""
+	}
+	if !slices.Equal(verified.Extra[corev1alpha1.ServiceAccountTokenExtraScopes], options.scopes) {
+		return true, ""
+	}
+	if options.ttl == 0 {
+		return verified.ExpiresAt != nil, ""
+	}
+	if verified.ExpiresAt == nil || verified.IssuedAt == nil ||
+		verified.ExpiresAt.Sub(verified.IssuedAt.Time).Round(time.Second) != options.ttl.Round(time.Second) {
+		return true, ""
+	}
+	return !time.Now().Before(verified.ExpiresAt.Add(-options.rotationOverlap)), verified.ID
+}
+
+func (r *ServiceAccountSecretReconciler) issueTokenTo(sa *corev1alpha1.ServiceAccount, secret *v1.Secret, options *tokenOptions) (*issuedToken, error) {
+	extra := map[string][]string{
+		corev1alpha1.ServiceAccountTokenExtraSecretNamespace: {secret.Namespace},
+		corev1alpha1.ServiceAccountTokenExtraSecretName:      {secret.Name},
+	}
+	if len(options.scopes) > 0 {
+		extra[corev1alpha1.ServiceAccountTokenExtraScopes] = options.scopes
+	}
+	id := string(uuid.NewUUID())
+	// The validity of the token is also verified by checking the SA and the secret, so the TTL is optional
 	accessToken, err := r.TokenIssuer.IssueTo(&token.IssueRequest{
 		User: &user.DefaultInfo{
-			Name: fmt.Sprintf(serviceAccountUsernameFormat, sa.Namespace, sa.Name),
+			Name:  fmt.Sprintf(serviceAccountUsernameFormat, sa.Namespace, sa.Name),
+			Extra: extra,
 		},
-		Claims: token.Claims{TokenType: token.StaticToken},
+		Claims:    token.Claims{TokenType: token.StaticToken, RegisteredClaims: jwt.RegisteredClaims{ID: id}},
+		ExpiresIn: options.ttl,
 	})
 	if err != nil {
 		return nil, err
 	}
 
-	result := oauth.Token{
-		AccessToken: accessToken,
-		// The OAuth 2.0 token_type response parameter value MUST be Bearer,
-		// as specified in OAuth 2.0 Bearer Token Usage [RFC6750]
-		TokenType: "Bearer",
+	result := &issuedToken{
+		Token: oauth.Token{
+			AccessToken: accessToken,
+			// The OAuth 2.0 token_type response parameter value MUST be Bearer,
+			// as specified in OAuth 2.0 Bearer Token Usage [RFC6750]
+			TokenType: "Bearer",
+			ExpiresIn: int(options.ttl.Seconds()),
+		},
+		ID: id,
+	}
+	if options.ttl > 0 {
+		expiresAt := time.Now().Add(options.ttl)
+		result.ExpiresAt = &expiresAt
 	}
-	return &result, nil
+	return result, nil
+}
+
+type issuedToken struct {
+	oauth.Token
+	ID        string
+	ExpiresAt *time.Time
 }
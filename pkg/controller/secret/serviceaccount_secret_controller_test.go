@@ -0,0 +1,139 @@
+/*
+ * Copyright 2024 the KubeSphere Authors.
+ * Please refer to the LICENSE file in the root directory of the project.
+ * https://github.com/kubesphere/kubesphere/blob/master/LICENSE
+ */
+
+package secret
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apiserver/pkg/authentication/user"
+	"k8s.io/client-go/tools/record"
+	corev1alpha1 "kubesphere.io/api/core/v1alpha1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"kubesphere.io/kubesphere/pkg/apiserver/authentication/oauth"
+	"kubesphere.io/kubesphere/pkg/apiserver/authentication/token"
+	"kubesphere.io/kubesphere/pkg/scheme"
+	"kubesphere.io/kubesphere/pkg/utils/serviceaccount"
+)
+
+func TestServiceAccountSecretReconciler(t *testing.T) {
+	issuer, err := token.NewIssuer(&oauth.IssuerOptions{JWTSecret: "secret"})
+	assert.NoError(t, err)
+	sa := &corev1alpha1.ServiceAccount{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "robot"}}
+	secret := &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: "default",
+			Name:      "robot-token",
+			Annotations: map[string]string{
+				corev1alpha1.ServiceAccountName:        sa.Name,
+				corev1alpha1.ServiceAccountTokenTTL:    "1h",
+				corev1alpha1.ServiceAccountTokenScopes: "get,list:pods",
+			},
+		},
+		Type: corev1alpha1.SecretTypeServiceAccountToken,
+	}
+	client := fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(sa, secret).Build()
+	r := &ServiceAccountSecretReconciler{
+		Client:        client,
+		Logger:        ctrl.Log.WithName("controllers").WithName(serviceAccountSecretController),
+		EventRecorder: record.NewFakeRecorder(10),
+		TokenIssuer:   issuer,
+	}
+	ctx := context.Background()
+	request := ctrl.Request{NamespacedName: types.NamespacedName{Namespace: "default", Name: secret.Name}}
+
+	result, err := r.Reconcile(ctx, request)
+	assert.NoError(t, err)
+	assert.True(t, result.RequeueAfter > 47*time.Minute && result.RequeueAfter <= 48*time.Minute)
+	assert.NoError(t, client.Get(ctx, request.NamespacedName, secret))
+	verified, err := issuer.Verify(string(secret.Data[corev1alpha1.ServiceAccountToken]))
+	assert.NoError(t, err)
+	assert.Equal(t, "kubesphere:serviceaccount:default:robot", verified.User.GetName())
+	assert.Equal(t, secret.Annotations[corev1alpha1.ServiceAccountTokenID], verified.ID)
+	assert.Equal(t, []string{"get,list:pods"}, verified.User.GetExtra()[corev1alpha1.ServiceAccountTokenExtraScopes])
+	assert.Equal(t, []string{secret.Name}, verified.User.GetExtra()[corev1alpha1.ServiceAccountTokenExtraSecretName])
+	assert.NotNil(t, verified.ExpiresAt)
+
+	// the token is not changed
+	issued := secret.Data[corev1alpha1.ServiceAccountToken]
+	_, err = r.Reconcile(ctx, request)
+	assert.NoError(t, err)
+	assert.NoError(t, client.Get(ctx, request.NamespacedName, secret))
+	assert.Equal(t, issued, secret.Data[corev1alpha1.ServiceAccountToken])
+
+	// a new token is issued after the current one is revoked
+	secret.Annotations[corev1alpha1.ServiceAccountTokenRevoked] = "unknown," + verified.ID
+	assert.NoError(t, client.Update(ctx, secret))
+	_, err = r.Reconcile(ctx, request)
+	assert.NoError(t, err)
+	assert.NoError(t, client.Get(ctx, request.NamespacedName, secret))
+	assert.NotEqual(t, issued, secret.Data[corev1alpha1.ServiceAccountToken])
+	assert.NotEqual(t, verified.ID, secret.Annotations[corev1alpha1.ServiceAccountTokenID])
+
+	// the replaced token is rejected after the scopes are changed
+	replaced := secret.Annotations[corev1alpha1.ServiceAccountTokenID]
+	secret.Annotations[corev1alpha1.ServiceAccountTokenScopes] = "get:pods"
+	assert.NoError(t, client.Update(ctx, secret))
+	_, err = r.Reconcile(ctx, request)
+	assert.NoError(t, err)
+	assert.NoError(t, client.Get(ctx, request.NamespacedName, secret))
+	assert.NotEqual(t, replaced, secret.Annotations[corev1alpha1.ServiceAccountTokenID])
+	assert.Empty(t, secret.Annotations[corev1alpha1.ServiceAccountTokenPreviousID])
+	assert.True(t, serviceaccount.IsRevoked(secret, replaced))
+	assert.True(t, serviceaccount.IsRevoked(secret, ""))
+	assert.False(t, serviceaccount.IsRevoked(secret, secret.Annotations[corev1alpha1.ServiceAccountTokenID]))
+
+	// the tokens without the ID or the secret are reissued
+	legacy, err := issuer.IssueTo(&token.IssueRequest{
+		User:   &user.DefaultInfo{Name: "kubesphere:serviceaccount:default:robot"},
+		Claims: token.Claims{TokenType: token.StaticToken},
+	})
+	assert.NoError(t, err)
+	secret.Data[corev1alpha1.ServiceAccountToken] = []byte(legacy)
+	assert.NoError(t, client.Update(ctx, secret))
+	_, err = r.Reconcile(ctx, request)
+	assert.NoError(t, err)
+	assert.NoError(t, client.Get(ctx, request.NamespacedName, secret))
+	assert.NotEqual(t, legacy, string(secret.Data[corev1alpha1.ServiceAccountToken]))
+
+	// the token never expires without the ttl
+	delete(secret.Annotations, corev1alpha1.ServiceAccountTokenTTL)
+	assert.NoError(t, client.Update(ctx, secret))
+	result, err = r.Reconcile(ctx, request)
+	assert.NoError(t, err)
+	assert.Zero(t, result.RequeueAfter)
+	assert.NoError(t, client.Get(ctx, request.NamespacedName, secret))
+	verified, err = issuer.Verify(string(secret.Data[corev1alpha1.ServiceAccountToken]))
+	assert.NoError(t, err)
+	assert.Nil(t, verified.ExpiresAt)
+	assert.Empty(t, secret.Annotations[corev1alpha1.ServiceAccountTokenExpiration])
+}
+
+func TestTokenOptions(t *testing.T) {
+	for annotations, valid := range map[[3]string]bool{
+		{"", "", ""}:                    true,
+		{"720h", "24h", "*:*"}:          true,
+		{"1h", "1h", ""}:                false,
+		{"-1h", "", ""}:                 false,
+		{"", "", "get"}:                 false,
+		{"", "1h", "get:pods /version"}: false,
+	} {
+		_, err := tokenOptionsFrom(&v1.Secret{ObjectMeta: metav1.ObjectMeta{Annotations: map[string]string{
+			corev1alpha1.ServiceAccountTokenTTL:             annotations[0],
+			corev1alpha1.ServiceAccountTokenRotationOverlap: annotations[1],
+			corev1alpha1.ServiceAccountTokenScopes:          annotations[2],
+		}}})
+		assert.Equal(t, valid, err == nil, annotations)
+	}
+}
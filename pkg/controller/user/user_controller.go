@@ -8,6 +8,7 @@ package user
 
 import (
 	"context"
+	goerrors "errors"
 	"fmt"
 	"strings"
 	"time"
@@ -17,6 +18,7 @@ import (
 	"k8s.io/apimachinery/pkg/api/errors"
 
 	"kubesphere.io/kubesphere/pkg/constants"
+	"kubesphere.io/kubesphere/pkg/models/iam/passwordpolicy"
 	"kubesphere.io/kubesphere/pkg/models/kubeconfig"
 	"kubesphere.io/kubesphere/pkg/utils/clusterclient"
 
@@ -104,15 +106,27 @@ func (r *Reconciler) SetupWithManager(mgr *kscontroller.Manager) error {
 			handler.EnqueueRequestsFromMapFunc(r.mapper),
 			builder.WithPredicates(clusterpredicate.ClusterStatusChangedPredicate{}),
 		).
+		Watches(
+			&corev1.Secret{},
+			handler.EnqueueRequestsFromMapFunc(r.listUsers),
+			builder.WithPredicates(predicate.NewPredicateFuncs(func(object client.Object) bool {
+				return object.GetNamespace() == constants.KubeSphereNamespace && object.GetName() == passwordpolicy.ConfigName
+			})),
+		).
 		Complete(r)
 }
 
 func (r *Reconciler) mapper(ctx context.Context, o client.Object) []reconcile.Request {
 	cluster := o.(*clusterv1alpha1.Cluster)
-	var requests []reconcile.Request
 	if !clusterutils.IsClusterReady(cluster) {
-		return requests
+		return nil
 	}
+	return r.listUsers(ctx, o)
+}
+
+// listUsers enqueues all users, e.g. the password policy has been changed.
+func (r *Reconciler) listUsers(ctx context.Context, _ client.Object) []reconcile.Request {
+	var requests []reconcile.Request
 	users := &iamv1beta1.UserList{}
 	if err := r.List(ctx, users); err != nil {
 		r.logger.Error(err, "failed to list users")
@@ -161,12 +175,24 @@ func (r *Reconciler) Reconcile(ctx context.Context, req reconcile.Request) (reco
 	if err := r.updateGlobalRoleAnnotation(ctx, user); err != nil {
 		return reconcile.Result{}, err
 	}
-	if err := r.encryptPassword(ctx, user); err != nil {
+	policy, err := passwordpolicy.Load(ctx, r.Client)
+	if goerrors.Is(err, passwordpolicy.ErrInvalidPolicy) {
+		r.recorder.Event(user, corev1.EventTypeWarning, kscontroller.SyncFailed, fmt.Sprintf("fall back to the default password policy: %s", err))
+		policy, err = passwordpolicy.NewPolicy(), nil
+	}
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	if err := r.encryptPassword(ctx, user, policy); err != nil {
 		return ctrl.Result{}, err
 	}
 	if err := r.reconcileUserStatus(ctx, user); err != nil {
 		return ctrl.Result{}, err
 	}
+	expiresAfter, err := r.reconcilePasswordExpiration(ctx, user, policy)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
 	if err := r.multiClusterSync(ctx, user); err != nil {
 		return ctrl.Result{}, err
 	}
@@ -176,12 +202,16 @@ func (r *Reconciler) Reconcile(ctx context.Context, req reconcile.Request) (reco
 	if user.Status.State == iamv1beta1.UserAuthLimitExceeded {
 		return ctrl.Result{Requeue: true, RequeueAfter: r.authenticationOptions.AuthenticateRateLimiterDuration}, nil
 	}
+	// put it back to the queue to require the password change after the password expires
+	if expiresAfter > 0 {
+		return ctrl.Result{RequeueAfter: expiresAfter}, nil
+	}
 
 	return ctrl.Result{}, nil
 }
 
-// encryptPassword Encrypt and update the user password
-func (r *Reconciler) encryptPassword(ctx context.Context, user *iamv1beta1.User) error {
+// encryptPassword Encrypt and update the user password, the encrypted password is recorded in the password history
+func (r *Reconciler) encryptPassword(ctx context.Context, user *iamv1beta1.User, policy *passwordpolicy.Policy) error {
 	// password must be encrypted if not empty
 	if user.Spec.EncryptedPassword != "" && !isEncrypted(user.Spec.EncryptedPassword) {
 		encryptedPassword, err := encrypt(user.Spec.EncryptedPassword)
@@ -192,7 +222,15 @@ func (r *Reconciler) encryptPassword(ctx context.Context, user *iamv1beta1.User)
 		if user.Annotations == nil {
 			user.Annotations = make(map[string]string)
 		}
-		user.Annotations[iamv1beta1.LastPasswordChangeTimeAnnotation] = time.Now().UTC().Format(time.RFC3339)
+		now := time.Now().UTC()
+		user.Annotations[iamv1beta1.LastPasswordChangeTimeAnnotation] = now.Format(time.RFC3339)
+		user.Status.LastPasswordChangeTime = &metav1.Time{Time: now}
+		user.Status.PasswordExpirationTime = nil
+		if expiration := policy.ExpirationTime(now); expiration != nil {
+			user.Status.PasswordExpirationTime = &metav1.Time{Time: *expiration}
+		}
+		user.Status.PasswordChangeRequired = false
+		user.Status.PasswordHistory = policy.AppendHistory(user.Status.PasswordHistory, encryptedPassword)
 		// ensure plain text password won't be kept anywhere
 		delete(user.Annotations, corev1.LastAppliedConfigAnnotation)
 		if err = r.Update(ctx, user, &client.UpdateOptions{}); err != nil {
@@ -212,6 +250,9 @@ func (r *Reconciler) deleteRelatedResources(ctx context.Context, user *iamv1beta
 	if err := r.DeleteAllOf(ctx, &iamv1beta1.WorkspaceRoleBinding{}, client.MatchingLabels{iamv1beta1.UserReferenceLabel: user.Name}); err != nil {
 		return err
 	}
+	if err := r.DeleteAllOf(ctx, &iamv1beta1.AccessRequest{}, client.MatchingLabels{iamv1beta1.UserReferenceLabel: user.Name}); err != nil {
+		return err
+	}
 	return nil
 }
 
@@ -272,10 +313,9 @@ func (r *Reconciler) reconcileUserStatus(ctx context.Context, user *iamv1beta1.U
 	// becomes active after password encrypted
 	if user.Status.State == "" {
 		if user.Spec.EncryptedPassword == "" || isEncrypted(user.Spec.EncryptedPassword) {
-			user.Status = iamv1beta1.UserStatus{
-				State:              iamv1beta1.UserActive,
-				LastTransitionTime: &metav1.Time{Time: time.Now()},
-			}
+			user.Status.State = iamv1beta1.UserActive
+			user.Status.Reason = ""
+			user.Status.LastTransitionTime = &metav1.Time{Time: time.Now()}
 			if err := r.Update(ctx, user, &client.UpdateOptions{}); err != nil {
 				return err
 			}
@@ -287,10 +327,9 @@ func (r *Reconciler) reconcileUserStatus(ctx context.Context, user *iamv1beta1.U
 		if user.Status.LastTransitionTime != nil &&
 			user.Status.LastTransitionTime.Add(r.authenticationOptions.AuthenticateRateLimiterDuration).Before(time.Now()) {
 			// unblock user
-			user.Status = iamv1beta1.UserStatus{
-				State:              iamv1beta1.UserActive,
-				LastTransitionTime: &metav1.Time{Time: time.Now()},
-			}
+			user.Status.State = iamv1beta1.UserActive
+			user.Status.Reason = ""
+			user.Status.LastTransitionTime = &metav1.Time{Time: time.Now()}
 			if err := r.Update(ctx, user, &client.UpdateOptions{}); err != nil {
 				return err
 			}
@@ -317,11 +356,9 @@ func (r *Reconciler) reconcileUserStatus(ctx context.Context, user *iamv1beta1.U
 
 	// block user if failed login attempts exceeds maximum tries setting
 	if failedLoginAttempts >= r.authenticationOptions.AuthenticateRateLimiterMaxTries {
-		user.Status = iamv1beta1.UserStatus{
-			State:              iamv1beta1.UserAuthLimitExceeded,
-			Reason:             fmt.Sprintf("Failed login attempts exceed %d in last %s", failedLoginAttempts, r.authenticationOptions.AuthenticateRateLimiterDuration),
-			LastTransitionTime: &metav1.Time{Time: time.Now()},
-		}
+		user.Status.State = iamv1beta1.UserAuthLimitExceeded
+		user.Status.Reason = fmt.Sprintf("Failed login attempts exceed %d in last %s", failedLoginAttempts, r.authenticationOptions.AuthenticateRateLimiterDuration)
+		user.Status.LastTransitionTime = &metav1.Time{Time: time.Now()}
 		if err := r.Update(ctx, user, &client.UpdateOptions{}); err != nil {
 			return err
 		}
@@ -330,6 +367,45 @@ func (r *Reconciler) reconcileUserStatus(ctx context.Context, user *iamv1beta1.U
 	return nil
 }
 
+// reconcilePasswordExpiration updates the password expiration time according to the maximum age of the password policy,
+// the password change is required after it expires. It returns the duration until the password expires.
+func (r *Reconciler) reconcilePasswordExpiration(ctx context.Context, user *iamv1beta1.User, policy *passwordpolicy.Policy) (time.Duration, error) {
+	// the users authenticated by the identity providers may have no password
+	if user.Spec.EncryptedPassword == "" || !isEncrypted(user.Spec.EncryptedPassword) {
+		return 0, nil
+	}
+
+	changed := user.CreationTimestamp.Time
+	if user.Status.LastPasswordChangeTime != nil {
+		changed = user.Status.LastPasswordChangeTime.Time
+	} else if t, err := time.Parse(time.RFC3339, user.Annotations[iamv1beta1.LastPasswordChangeTimeAnnotation]); err == nil {
+		changed = t
+	}
+
+	var expirationTime *metav1.Time
+	var expiresAfter time.Duration
+	changeRequired := user.Status.PasswordChangeRequired
+	if expiration := policy.ExpirationTime(changed); expiration != nil {
+		// the time is persisted in seconds
+		t := metav1.NewTime(*expiration).Rfc3339Copy()
+		expirationTime = &t
+		if expiresAfter = time.Until(*expiration); expiresAfter <= 0 {
+			expiresAfter = 0
+			changeRequired = true
+		}
+	}
+
+	if expirationTime.Equal(user.Status.PasswordExpirationTime) && changeRequired == user.Status.PasswordChangeRequired {
+		return expiresAfter, nil
+	}
+	user.Status.PasswordExpirationTime = expirationTime
+	user.Status.PasswordChangeRequired = changeRequired
+	if err := r.Update(ctx, user, &client.UpdateOptions{}); err != nil {
+		return 0, err
+	}
+	return expiresAfter, nil
+}
+
 func (r *Reconciler) updateGlobalRoleAnnotation(ctx context.Context, user *iamv1beta1.User) error {
 	globalRoles := &iamv1beta1.GlobalRoleBindingList{}
 	if err := r.List(ctx, globalRoles, client.MatchingLabels{iamv1beta1.UserReferenceLabel: user.Name}); err != nil {
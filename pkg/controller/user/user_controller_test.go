@@ -13,6 +13,7 @@ import (
 	"time"
 
 	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
@@ -26,6 +27,8 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
 	"kubesphere.io/kubesphere/pkg/apiserver/authentication"
+	"kubesphere.io/kubesphere/pkg/constants"
+	"kubesphere.io/kubesphere/pkg/models/iam/passwordpolicy"
 	"kubesphere.io/kubesphere/pkg/scheme"
 	"kubesphere.io/kubesphere/pkg/utils/clusterclient"
 )
@@ -139,3 +142,112 @@ func TestDoNothing(t *testing.T) {
 	user = updateEvent.Object.(*iamv1beta1.User)
 	assert.Equal(t, iamv1beta1.UserActive, user.Status.State)
 }
+
+func TestPasswordPolicy(t *testing.T) {
+	policy := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Namespace: constants.KubeSphereNamespace, Name: passwordpolicy.ConfigName},
+		Type:       constants.SecretTypeGenericPlatformConfig,
+		Data: map[string][]byte{constants.GenericPlatformConfigFileName: []byte(`historyDepth: 2
+maxAge: 720h
+`)},
+	}
+	encrypted, err := encrypt("P@88w0rd")
+	assert.NoError(t, err)
+	user := newUser("test")
+	user.Finalizers = []string{finalizer}
+	user.Spec.EncryptedPassword = encrypted
+	user.Status = iamv1beta1.UserStatus{
+		State:                  iamv1beta1.UserActive,
+		LastPasswordChangeTime: &metav1.Time{Time: time.Now().Add(-31 * 24 * time.Hour)},
+	}
+
+	client := runtimefakeclient.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(user, policy).Build()
+	clusterClientSet, err := clusterclient.NewClusterClientSet(&informertest.FakeInformers{Scheme: scheme.Scheme})
+	assert.NoError(t, err)
+	c := &Reconciler{
+		recorder:              &record.FakeRecorder{},
+		logger:                ctrl.Log.WithName("controllers").WithName(controllerName),
+		Client:                client,
+		authenticationOptions: authentication.NewOptions(),
+		clusterClient:         clusterClientSet,
+	}
+	ctx := context.Background()
+	request := reconcile.Request{NamespacedName: types.NamespacedName{Name: user.Name}}
+
+	// the password has expired
+	_, err = c.Reconcile(ctx, request)
+	assert.NoError(t, err)
+	assert.NoError(t, client.Get(ctx, request.NamespacedName, user))
+	assert.True(t, user.Status.PasswordChangeRequired)
+	assert.NotNil(t, user.Status.PasswordExpirationTime)
+
+	// the current password can't be reused
+	webhook := &Webhook{Client: client}
+	changed := user.DeepCopy()
+	changed.Spec.EncryptedPassword = "P@88w0rd"
+	_, err = webhook.ValidateUpdate(ctx, user, changed)
+	assert.Error(t, err)
+	changed.Spec.EncryptedPassword = "password"
+	_, err = webhook.ValidateUpdate(ctx, user, changed)
+	assert.Error(t, err)
+	changed.Spec.EncryptedPassword = "P@88w0rd2"
+	_, err = webhook.ValidateUpdate(ctx, user, changed)
+	assert.NoError(t, err)
+
+	// the password is changed
+	assert.NoError(t, client.Update(ctx, changed))
+	result, err := c.Reconcile(ctx, request)
+	assert.NoError(t, err)
+	assert.NoError(t, client.Get(ctx, request.NamespacedName, user))
+	assert.True(t, isEncrypted(user.Spec.EncryptedPassword))
+	assert.False(t, user.Status.PasswordChangeRequired)
+	assert.Len(t, user.Status.PasswordHistory, 1)
+	assert.Equal(t, iamv1beta1.UserActive, user.Status.State)
+	assert.True(t, result.RequeueAfter > 29*24*time.Hour)
+
+	changed = user.DeepCopy()
+	changed.Spec.EncryptedPassword = "P@88w0rd2"
+	_, err = webhook.ValidateUpdate(ctx, user, changed)
+	assert.Error(t, err)
+}
+
+func TestMalformedPasswordPolicy(t *testing.T) {
+	policy := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Namespace: constants.KubeSphereNamespace, Name: passwordpolicy.ConfigName},
+		Type:       constants.SecretTypeGenericPlatformConfig,
+		Data:       map[string][]byte{constants.GenericPlatformConfigFileName: []byte("minLength: [")},
+	}
+	user := newUser("test")
+	user.Finalizers = []string{finalizer}
+	user.Spec.EncryptedPassword = "P@88w0rd"
+
+	client := runtimefakeclient.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(user, policy).Build()
+	clusterClientSet, err := clusterclient.NewClusterClientSet(&informertest.FakeInformers{Scheme: scheme.Scheme})
+	assert.NoError(t, err)
+	recorder := record.NewFakeRecorder(10)
+	c := &Reconciler{
+		recorder:              recorder,
+		logger:                ctrl.Log.WithName("controllers").WithName(controllerName),
+		Client:                client,
+		authenticationOptions: authentication.NewOptions(),
+		clusterClient:         clusterClientSet,
+	}
+	ctx := context.Background()
+
+	// the default policy is used
+	webhook := &Webhook{Client: client}
+	changed := user.DeepCopy()
+	changed.Spec.EncryptedPassword = "password"
+	_, err = webhook.ValidateUpdate(ctx, user, changed)
+	assert.Error(t, err)
+	changed.Spec.EncryptedPassword = "P@88w0rd2"
+	_, err = webhook.ValidateUpdate(ctx, user, changed)
+	assert.NoError(t, err)
+
+	request := reconcile.Request{NamespacedName: types.NamespacedName{Name: user.Name}}
+	_, err = c.Reconcile(ctx, request)
+	assert.NoError(t, err)
+	assert.NoError(t, client.Get(ctx, request.NamespacedName, user))
+	assert.True(t, isEncrypted(user.Spec.EncryptedPassword))
+	assert.Contains(t, <-recorder.Events, "fall back to the default password policy")
+}
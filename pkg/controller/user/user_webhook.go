@@ -8,16 +8,19 @@ package user
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net/mail"
 
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/klog/v2"
 	iamv1beta1 "kubesphere.io/api/iam/v1beta1"
 	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
 
 	kscontroller "kubesphere.io/kubesphere/pkg/controller"
+	"kubesphere.io/kubesphere/pkg/models/iam/passwordpolicy"
 )
 
 const webhookName = "user-webhook"
@@ -69,11 +72,55 @@ func (v *Webhook) validate(ctx context.Context, obj runtime.Object) (admission.W
 	return nil, nil
 }
 
+// validatePassword validates the plain text password against the password policy, the encrypted
+// passwords have been validated before.
+func (v *Webhook) validatePassword(ctx context.Context, oldObj, newObj runtime.Object) error {
+	user, ok := newObj.(*iamv1beta1.User)
+	if !ok {
+		return fmt.Errorf("expected a User but got a %T", newObj)
+	}
+	password := user.Spec.EncryptedPassword
+	if password == "" || isEncrypted(password) {
+		return nil
+	}
+
+	var history []string
+	if oldUser, ok := oldObj.(*iamv1beta1.User); ok {
+		// the password is not changed
+		if oldUser.Spec.EncryptedPassword == password {
+			return nil
+		}
+		history = passwordpolicy.RecentPasswords(oldUser)
+	}
+
+	policy, err := passwordpolicy.Load(ctx, v.Client)
+	if errors.Is(err, passwordpolicy.ErrInvalidPolicy) {
+		klog.Warningf("fall back to the default password policy: %s", err)
+		policy, err = passwordpolicy.NewPolicy(), nil
+	}
+	if err != nil {
+		return err
+	}
+	if err = policy.Check(password); err != nil {
+		return fmt.Errorf("invalid password: %s", err)
+	}
+	if err = policy.CheckReuse(password, history); err != nil {
+		return fmt.Errorf("invalid password: %s", err)
+	}
+	return nil
+}
+
 func (v *Webhook) ValidateCreate(ctx context.Context, obj runtime.Object) (admission.Warnings, error) {
+	if err := v.validatePassword(ctx, nil, obj); err != nil {
+		return nil, err
+	}
 	return v.validate(ctx, obj)
 }
 
-func (v *Webhook) ValidateUpdate(ctx context.Context, _, newObj runtime.Object) (admission.Warnings, error) {
+func (v *Webhook) ValidateUpdate(ctx context.Context, oldObj, newObj runtime.Object) (admission.Warnings, error) {
+	if err := v.validatePassword(ctx, oldObj, newObj); err != nil {
+		return nil, err
+	}
 	return v.validate(ctx, newObj)
 }
 
@@ -0,0 +1,141 @@
+/*
+ * Copyright 2024 the KubeSphere Authors.
+ * Please refer to the LICENSE file in the root directory of the project.
+ * https://github.com/kubesphere/kubesphere/blob/master/LICENSE
+ */
+
+package workspace
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	appsv1 "k8s.io/api/apps/v1"
+	autoscalingv1 "k8s.io/api/autoscaling/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/utils/ptr"
+	tenantv1beta1 "kubesphere.io/api/tenant/v1beta1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	kscontroller "kubesphere.io/kubesphere/pkg/controller"
+)
+
+const suspensionWebhookName = "workspace-suspension-webhook"
+
+// SuspensionWebhook rejects new workloads in the namespaces of the suspended workspaces.
+type SuspensionWebhook struct {
+	client.Client
+}
+
+func (w *SuspensionWebhook) Name() string {
+	return suspensionWebhookName
+}
+
+func (w *SuspensionWebhook) SetupWithManager(mgr *kscontroller.Manager) error {
+	w.Client = mgr.GetClient()
+	mgr.GetWebhookServer().Register("/validate-workspace-suspension", &webhook.Admission{Handler: w})
+	return nil
+}
+
+func (w *SuspensionWebhook) Handle(ctx context.Context, req admission.Request) admission.Response {
+	if req.Namespace == "" {
+		return admission.Allowed("")
+	}
+	namespace := &corev1.Namespace{}
+	if err := w.Get(ctx, client.ObjectKey{Name: req.Namespace}, namespace); err != nil {
+		if apierrors.IsNotFound(err) {
+			return admission.Allowed("")
+		}
+		return admission.Errored(http.StatusInternalServerError, err)
+	}
+	if namespace.Labels[tenantv1beta1.WorkspaceSuspendedLabel] != "true" {
+		return admission.Allowed("")
+	}
+	denied := admission.Denied(fmt.Sprintf("workspace %s is suspended", namespace.Labels[tenantv1beta1.WorkspaceLabel]))
+
+	if req.SubResource == "scale" {
+		scale := &autoscalingv1.Scale{}
+		if err := json.Unmarshal(req.Object.Raw, scale); err != nil {
+			return admission.Errored(http.StatusBadRequest, err)
+		}
+		if scale.Spec.Replicas > 0 {
+			return denied
+		}
+		return admission.Allowed("")
+	}
+
+	switch req.Kind.Kind {
+	case "Pod", "Job":
+		if req.Operation == admissionv1.Create {
+			return denied
+		}
+	case "DaemonSet":
+		switch req.Operation {
+		case admissionv1.Create:
+			return denied
+		case admissionv1.Update:
+			// the node selector which evicts the pods can only be removed by the controller after resuming
+			daemonSet := &appsv1.DaemonSet{}
+			if err := json.Unmarshal(req.Object.Raw, daemonSet); err != nil {
+				return admission.Errored(http.StatusBadRequest, err)
+			}
+			if _, ok := daemonSet.Spec.Template.Spec.NodeSelector[tenantv1beta1.WorkspaceSuspendedLabel]; !ok {
+				return denied
+			}
+		}
+	case "CronJob":
+		switch req.Operation {
+		case admissionv1.Create:
+			return denied
+		case admissionv1.Update:
+			cronJob := &batchv1.CronJob{}
+			if err := json.Unmarshal(req.Object.Raw, cronJob); err != nil {
+				return admission.Errored(http.StatusBadRequest, err)
+			}
+			if !ptr.Deref(cronJob.Spec.Suspend, false) {
+				return denied
+			}
+		}
+	case "Deployment", "StatefulSet", "ReplicaSet":
+		replicas, err := desiredReplicas(req.Object.Raw)
+		if err != nil {
+			return admission.Errored(http.StatusBadRequest, err)
+		}
+		switch req.Operation {
+		case admissionv1.Create:
+			if replicas > 0 {
+				return denied
+			}
+		case admissionv1.Update:
+			// scaling down and updates which keep the replicas are allowed
+			oldReplicas, err := desiredReplicas(req.OldObject.Raw)
+			if err != nil {
+				return admission.Errored(http.StatusBadRequest, err)
+			}
+			if replicas > oldReplicas {
+				return denied
+			}
+		}
+	}
+	return admission.Allowed("")
+}
+
+// desiredReplicas returns the replicas of the workload, the default value is 1.
+func desiredReplicas(raw []byte) (int32, error) {
+	workload := &struct {
+		Spec struct {
+			Replicas *int32 `json:"replicas"`
+		} `json:"spec"`
+	}{}
+	if err := json.Unmarshal(raw, workload); err != nil {
+		return 0, err
+	}
+	return ptr.Deref(workload.Spec.Replicas, 1), nil
+}
@@ -8,6 +8,7 @@ package workspace
 
 import (
 	"context"
+	"strings"
 
 	"github.com/go-logr/logr"
 	"github.com/pkg/errors"
@@ -17,15 +18,22 @@ import (
 	"k8s.io/client-go/tools/record"
 	"k8s.io/client-go/util/retry"
 	"k8s.io/klog/v2"
+	clusterv1alpha1 "kubesphere.io/api/cluster/v1alpha1"
+	iamv1beta1 "kubesphere.io/api/iam/v1beta1"
+	quotav1alpha2 "kubesphere.io/api/quota/v1alpha2"
 	tenantv1beta1 "kubesphere.io/api/tenant/v1beta1"
 	ctrl "sigs.k8s.io/controller-runtime"
+	ctrlbuilder "sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
 	"kubesphere.io/kubesphere/pkg/constants"
 	kscontroller "kubesphere.io/kubesphere/pkg/controller"
+	"kubesphere.io/kubesphere/pkg/controller/cluster/predicate"
+	"kubesphere.io/kubesphere/pkg/utils/clusterclient"
 )
 
 const (
@@ -38,8 +46,13 @@ var _ reconcile.Reconciler = &Reconciler{}
 // Reconciler reconciles a Workspace object
 type Reconciler struct {
 	client.Client
-	logger   logr.Logger
-	recorder record.EventRecorder
+	// apiReader reads the workloads of the suspended namespaces without caching them
+	apiReader        client.Reader
+	logger           logr.Logger
+	recorder         record.EventRecorder
+	clusterClientSet clusterclient.Interface
+	hostCluster      bool
+	clusterName      string
 }
 
 func (r *Reconciler) Name() string {
@@ -48,16 +61,37 @@ func (r *Reconciler) Name() string {
 
 func (r *Reconciler) SetupWithManager(mgr *kscontroller.Manager) error {
 	r.Client = mgr.GetClient()
+	r.apiReader = mgr.GetAPIReader()
 	r.logger = mgr.GetLogger().WithName(controllerName)
 	r.recorder = mgr.GetEventRecorderFor(controllerName)
-	return ctrl.NewControllerManagedBy(mgr).
+	r.clusterClientSet = mgr.ClusterClient
+	if mgr.MultiClusterOptions != nil {
+		r.hostCluster = strings.EqualFold(mgr.MultiClusterOptions.ClusterRole, string(clusterv1alpha1.ClusterRoleHost))
+		r.clusterName = mgr.MultiClusterOptions.ClusterName
+	}
+	builder := ctrl.NewControllerManagedBy(mgr).
 		Named(controllerName).
 		WithOptions(controller.Options{MaxConcurrentReconciles: 2}).
 		For(&tenantv1beta1.Workspace{}).
-		Complete(r)
+		Watches(&corev1.Namespace{}, handler.EnqueueRequestsFromMapFunc(r.mapToWorkspace)).
+		Watches(&iamv1beta1.WorkspaceRoleBinding{}, handler.EnqueueRequestsFromMapFunc(r.mapToWorkspace)).
+		Watches(&quotav1alpha2.ResourceQuota{}, handler.EnqueueRequestsFromMapFunc(r.mapToWorkspace))
+	if r.hostCluster {
+		builder = builder.
+			Watches(&tenantv1beta1.WorkspaceTemplate{}, &handler.EnqueueRequestForObject{}).
+			Watches(&clusterv1alpha1.Cluster{}, handler.EnqueueRequestsFromMapFunc(r.mapClusterToWorkspaces),
+				ctrlbuilder.WithPredicates(predicate.ClusterStatusChangedPredicate{}))
+	}
+	return builder.Complete(r)
 }
 
 // +kubebuilder:rbac:groups=tenant.kubesphere.io,resources=workspaces,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=tenant.kubesphere.io,resources=workspaces/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=iam.kubesphere.io,resources=workspacerolebindings,verbs=get;list;watch
+// +kubebuilder:rbac:groups=quota.kubesphere.io,resources=resourcequotas,verbs=get;list;watch
+// +kubebuilder:rbac:groups=core,resources=namespaces,verbs=get;list;watch;update;patch
+// +kubebuilder:rbac:groups=apps,resources=deployments;statefulsets;daemonsets,verbs=get;list;watch;update;patch
+// +kubebuilder:rbac:groups=batch,resources=cronjobs,verbs=get;list;watch;update;patch
 
 func (r *Reconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
 	logger := r.logger.WithValues("workspace", req.NamespacedName)
@@ -98,8 +132,11 @@ func (r *Reconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Resu
 		return ctrl.Result{}, nil
 	}
 
-	r.recorder.Event(workspace, corev1.EventTypeNormal, "Reconcile", "Reconcile workspace successfully")
-	return ctrl.Result{}, nil
+	if err := r.syncSuspension(ctx, workspace); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	return r.syncStatus(ctx, workspace)
 }
 
 // workspaceCascadingDeletion handles the cascading deletion of a workspace based on its deletion propagation policy.
@@ -0,0 +1,257 @@
+/*
+ * Copyright 2024 the KubeSphere Authors.
+ * Please refer to the LICENSE file in the root directory of the project.
+ * https://github.com/kubesphere/kubesphere/blob/master/LICENSE
+ */
+
+package workspace
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/sets"
+	quotav1 "k8s.io/apiserver/pkg/quota/v1"
+	"k8s.io/klog/v2"
+	clusterv1alpha1 "kubesphere.io/api/cluster/v1alpha1"
+	iamv1beta1 "kubesphere.io/api/iam/v1beta1"
+	quotav1alpha2 "kubesphere.io/api/quota/v1alpha2"
+	tenantv1beta1 "kubesphere.io/api/tenant/v1beta1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	clusterutils "kubesphere.io/kubesphere/pkg/controller/cluster/utils"
+	"kubesphere.io/kubesphere/pkg/controller/workspacetemplate/utils"
+)
+
+// statusResyncPeriod is the period to resync the status of the workspaces placed in multiple clusters,
+// since the namespaces and resource quotas in the member clusters are not watched.
+const statusResyncPeriod = 5 * time.Minute
+
+type placementCluster struct {
+	name   string
+	ready  bool
+	client client.Client
+}
+
+// placementClusters returns the clusters where the workspace is placed. In the host cluster, the placement is defined by
+// the workspace template, otherwise only the current cluster is returned.
+func (r *Reconciler) placementClusters(ctx context.Context, workspace *tenantv1beta1.Workspace) ([]placementCluster, error) {
+	local := []placementCluster{{name: r.clusterName, ready: true, client: r.Client}}
+	if !r.hostCluster || r.clusterClientSet == nil {
+		return local, nil
+	}
+	workspaceTemplate := &tenantv1beta1.WorkspaceTemplate{}
+	if err := r.Get(ctx, types.NamespacedName{Name: workspace.Name}, workspaceTemplate); err != nil {
+		if apierrors.IsNotFound(err) {
+			return local, nil
+		}
+		return nil, errors.Wrapf(err, "failed to get workspace template %s", workspace.Name)
+	}
+	clusters, err := r.clusterClientSet.ListClusters(ctx)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to list clusters")
+	}
+	var result []placementCluster
+	for i := range clusters {
+		cluster := &clusters[i]
+		if !utils.WorkspaceTemplateMatchTargetCluster(workspaceTemplate, cluster) {
+			continue
+		}
+		target := placementCluster{name: cluster.Name, ready: clusterutils.IsClusterReady(cluster)}
+		if target.ready {
+			if target.client, err = r.clusterClientSet.GetRuntimeClient(cluster.Name); err != nil {
+				klog.FromContext(ctx).V(4).Info("failed to get cluster client", "cluster", cluster.Name, "error", err)
+				target.ready = false
+			}
+		}
+		result = append(result, target)
+	}
+	return result, nil
+}
+
+// syncStatus aggregates the namespaces, members, resource quotas and the readiness of the placement clusters into the
+// status of the workspace.
+func (r *Reconciler) syncStatus(ctx context.Context, workspace *tenantv1beta1.Workspace) (ctrl.Result, error) {
+	clusters, err := r.placementClusters(ctx, workspace)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	status := workspace.Status.DeepCopy()
+	previous := make(map[string]tenantv1beta1.WorkspaceClusterStatus, len(status.Clusters))
+	for _, cluster := range status.Clusters {
+		previous[cluster.Name] = cluster
+	}
+
+	status.Clusters = make([]tenantv1beta1.WorkspaceClusterStatus, 0, len(clusters))
+	quota := corev1.ResourceQuotaStatus{}
+	var hasQuota bool
+	var notReadyClusters []string
+	for _, cluster := range clusters {
+		clusterStatus := tenantv1beta1.WorkspaceClusterStatus{Name: cluster.name, Ready: cluster.ready}
+		if cluster.ready {
+			namespaces := &corev1.NamespaceList{}
+			resourceQuotas := &quotav1alpha2.ResourceQuotaList{}
+			if err := cluster.client.List(ctx, namespaces, client.MatchingLabels{tenantv1beta1.WorkspaceLabel: workspace.Name}); err != nil {
+				klog.FromContext(ctx).V(4).Info("failed to list namespaces", "cluster", cluster.name, "error", err)
+				clusterStatus.Ready = false
+			} else if err := cluster.client.List(ctx, resourceQuotas, client.MatchingLabels{tenantv1beta1.WorkspaceLabel: workspace.Name}); err != nil {
+				klog.FromContext(ctx).V(4).Info("failed to list resource quotas", "cluster", cluster.name, "error", err)
+				clusterStatus.Ready = false
+			} else {
+				clusterStatus.Namespaces = int32(len(namespaces.Items))
+				for _, resourceQuota := range resourceQuotas.Items {
+					hasQuota = true
+					quota.Hard = quotav1.Add(quota.Hard, resourceQuota.Status.Total.Hard)
+					quota.Used = quotav1.Add(quota.Used, resourceQuota.Status.Total.Used)
+				}
+			}
+		}
+		if !clusterStatus.Ready {
+			// keep the last observed value
+			clusterStatus.Namespaces = previous[cluster.name].Namespaces
+			notReadyClusters = append(notReadyClusters, cluster.name)
+		}
+		status.Clusters = append(status.Clusters, clusterStatus)
+	}
+
+	status.Quota = nil
+	if hasQuota {
+		status.Quota = &quota
+	}
+
+	if status.Members, err = r.countMembers(ctx, workspace); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	if len(notReadyClusters) > 0 {
+		meta.SetStatusCondition(&status.Conditions, metav1.Condition{
+			Type:    tenantv1beta1.WorkspaceConditionClustersReady,
+			Status:  metav1.ConditionFalse,
+			Reason:  "ClustersNotReady",
+			Message: fmt.Sprintf("clusters not ready: %s", strings.Join(notReadyClusters, ",")),
+		})
+	} else {
+		meta.SetStatusCondition(&status.Conditions, metav1.Condition{
+			Type:   tenantv1beta1.WorkspaceConditionClustersReady,
+			Status: metav1.ConditionTrue,
+			Reason: "ClustersReady",
+		})
+	}
+
+	if exceeded := exceededResources(quota); len(exceeded) > 0 {
+		meta.SetStatusCondition(&status.Conditions, metav1.Condition{
+			Type:    tenantv1beta1.WorkspaceConditionQuotaExceeded,
+			Status:  metav1.ConditionTrue,
+			Reason:  "QuotaExceeded",
+			Message: fmt.Sprintf("resource quota exceeded: %s", strings.Join(exceeded, ",")),
+		})
+	} else {
+		meta.SetStatusCondition(&status.Conditions, metav1.Condition{
+			Type:   tenantv1beta1.WorkspaceConditionQuotaExceeded,
+			Status: metav1.ConditionFalse,
+			Reason: "WithinQuota",
+		})
+	}
+
+	status.State = workspaceState(workspace)
+
+	var result ctrl.Result
+	if len(clusters) > 1 || (len(clusters) == 1 && clusters[0].name != r.clusterName) {
+		result.RequeueAfter = statusResyncPeriod
+	}
+
+	status.LastUpdateTime = workspace.Status.LastUpdateTime
+	if equality.Semantic.DeepEqual(status, &workspace.Status) {
+		return result, nil
+	}
+	status.LastUpdateTime = &metav1.Time{Time: time.Now()}
+	updated := workspace.DeepCopy()
+	updated.Status = *status
+	if err := r.Status().Patch(ctx, updated, client.MergeFrom(workspace)); err != nil {
+		return ctrl.Result{}, errors.Wrapf(err, "failed to update status of workspace %s", workspace.Name)
+	}
+	// the workspace is reconciled on every change of its namespaces, role bindings and quotas,
+	// the event is only recorded when the status changes
+	r.recorder.Event(workspace, corev1.EventTypeNormal, "Reconcile", "Reconcile workspace successfully")
+	return result, nil
+}
+
+// countMembers returns the number of users bound to each workspace role.
+func (r *Reconciler) countMembers(ctx context.Context, workspace *tenantv1beta1.Workspace) (map[string]int32, error) {
+	workspaceRoleBindings := &iamv1beta1.WorkspaceRoleBindingList{}
+	if err := r.List(ctx, workspaceRoleBindings, client.MatchingLabels{tenantv1beta1.WorkspaceLabel: workspace.Name}); err != nil {
+		return nil, errors.Wrapf(err, "failed to list workspace role bindings of workspace %s", workspace.Name)
+	}
+	users := make(map[string]sets.Set[string])
+	for _, workspaceRoleBinding := range workspaceRoleBindings.Items {
+		role := strings.TrimPrefix(workspaceRoleBinding.RoleRef.Name, workspace.Name+"-")
+		for _, subject := range workspaceRoleBinding.Subjects {
+			if subject.Kind != iamv1beta1.ResourceKindUser {
+				continue
+			}
+			if users[role] == nil {
+				users[role] = sets.New[string]()
+			}
+			users[role].Insert(subject.Name)
+		}
+	}
+	if len(users) == 0 {
+		return nil, nil
+	}
+	members := make(map[string]int32, len(users))
+	for role, names := range users {
+		members[role] = int32(names.Len())
+	}
+	return members, nil
+}
+
+// exceededResources returns the resources whose usage has reached the hard limit.
+func exceededResources(quota corev1.ResourceQuotaStatus) []string {
+	var exceeded []string
+	for _, name := range quotav1.ResourceNames(quota.Hard) {
+		hard := quota.Hard[name]
+		used, ok := quota.Used[name]
+		if ok && !hard.IsZero() && used.Cmp(hard) >= 0 {
+			exceeded = append(exceeded, string(name))
+		}
+	}
+	return sets.List(sets.New(exceeded...))
+}
+
+// mapToWorkspace enqueues the workspace which the object belongs to.
+func (r *Reconciler) mapToWorkspace(_ context.Context, o client.Object) []reconcile.Request {
+	workspace := o.GetLabels()[tenantv1beta1.WorkspaceLabel]
+	if workspace == "" {
+		return nil
+	}
+	return []reconcile.Request{{NamespacedName: types.NamespacedName{Name: workspace}}}
+}
+
+// mapClusterToWorkspaces enqueues the workspaces placed in the cluster.
+func (r *Reconciler) mapClusterToWorkspaces(ctx context.Context, o client.Object) []reconcile.Request {
+	cluster := o.(*clusterv1alpha1.Cluster)
+	workspaceTemplates := &tenantv1beta1.WorkspaceTemplateList{}
+	if err := r.List(ctx, workspaceTemplates); err != nil {
+		r.logger.Error(err, "failed to list workspace templates")
+		return nil
+	}
+	var result []reconcile.Request
+	for i := range workspaceTemplates.Items {
+		if utils.WorkspaceTemplateMatchTargetCluster(&workspaceTemplates.Items[i], cluster) {
+			result = append(result, reconcile.Request{NamespacedName: types.NamespacedName{Name: workspaceTemplates.Items[i].Name}})
+		}
+	}
+	return result
+}
@@ -0,0 +1,91 @@
+/*
+ * Copyright 2024 the KubeSphere Authors.
+ * Please refer to the LICENSE file in the root directory of the project.
+ * https://github.com/kubesphere/kubesphere/blob/master/LICENSE
+ */
+
+package workspace
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	iamv1beta1 "kubesphere.io/api/iam/v1beta1"
+	quotav1alpha2 "kubesphere.io/api/quota/v1alpha2"
+	tenantv1beta1 "kubesphere.io/api/tenant/v1beta1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"kubesphere.io/kubesphere/pkg/scheme"
+)
+
+func TestSyncStatus(t *testing.T) {
+	workspace := &tenantv1beta1.Workspace{ObjectMeta: metav1.ObjectMeta{Name: "demo"}}
+	workspaceLabels := map[string]string{tenantv1beta1.WorkspaceLabel: workspace.Name}
+	userSubject := func(name string) rbacv1.Subject {
+		return rbacv1.Subject{Kind: iamv1beta1.ResourceKindUser, APIGroup: iamv1beta1.SchemeGroupVersion.Group, Name: name}
+	}
+	objects := []client.Object{
+		workspace,
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "demo-ns1", Labels: workspaceLabels}},
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "demo-ns2", Labels: workspaceLabels}},
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "other"}},
+		&iamv1beta1.WorkspaceRoleBinding{
+			ObjectMeta: metav1.ObjectMeta{Name: "demo-admin", Labels: workspaceLabels},
+			RoleRef:    rbacv1.RoleRef{Kind: iamv1beta1.ResourceKindWorkspaceRole, Name: "demo-admin"},
+			Subjects:   []rbacv1.Subject{userSubject("admin")},
+		},
+		&iamv1beta1.WorkspaceRoleBinding{
+			ObjectMeta: metav1.ObjectMeta{Name: "demo-viewer", Labels: workspaceLabels},
+			RoleRef:    rbacv1.RoleRef{Kind: iamv1beta1.ResourceKindWorkspaceRole, Name: "demo-viewer"},
+			Subjects:   []rbacv1.Subject{userSubject("alice"), userSubject("bob")},
+		},
+		&quotav1alpha2.ResourceQuota{
+			ObjectMeta: metav1.ObjectMeta{Name: "demo", Labels: workspaceLabels},
+			Status: quotav1alpha2.ResourceQuotaStatus{Total: corev1.ResourceQuotaStatus{
+				Hard: corev1.ResourceList{corev1.ResourcePods: resource.MustParse("10"), corev1.ResourceLimitsCPU: resource.MustParse("4")},
+				Used: corev1.ResourceList{corev1.ResourcePods: resource.MustParse("10"), corev1.ResourceLimitsCPU: resource.MustParse("1")},
+			}},
+		},
+	}
+	r := &Reconciler{
+		Client: fake.NewClientBuilder().WithScheme(scheme.Scheme).
+			WithObjects(objects...).
+			WithStatusSubresource(&tenantv1beta1.Workspace{}).
+			Build(),
+		clusterName: "host",
+		recorder:    record.NewFakeRecorder(10),
+	}
+
+	ctx := context.Background()
+	_, err := r.syncStatus(ctx, workspace)
+	assert.NoError(t, err)
+
+	updated := &tenantv1beta1.Workspace{}
+	assert.NoError(t, r.Get(ctx, types.NamespacedName{Name: workspace.Name}, updated))
+	assert.Equal(t, []tenantv1beta1.WorkspaceClusterStatus{{Name: "host", Ready: true, Namespaces: 2}}, updated.Status.Clusters)
+	assert.Equal(t, map[string]int32{"admin": 1, "viewer": 2}, updated.Status.Members)
+	assert.True(t, updated.Status.Quota.Used.Pods().Equal(resource.MustParse("10")))
+	assert.True(t, meta.IsStatusConditionTrue(updated.Status.Conditions, tenantv1beta1.WorkspaceConditionClustersReady))
+	assert.True(t, meta.IsStatusConditionTrue(updated.Status.Conditions, tenantv1beta1.WorkspaceConditionQuotaExceeded))
+	assert.NotNil(t, updated.Status.LastUpdateTime)
+	assert.Len(t, r.recorder.(*record.FakeRecorder).Events, 1)
+
+	// the status is not updated if nothing changed
+	lastUpdateTime := updated.Status.LastUpdateTime
+	resourceVersion := updated.ResourceVersion
+	_, err = r.syncStatus(ctx, updated)
+	assert.NoError(t, err)
+	assert.NoError(t, r.Get(ctx, types.NamespacedName{Name: workspace.Name}, updated))
+	assert.Equal(t, resourceVersion, updated.ResourceVersion)
+	assert.Equal(t, lastUpdateTime, updated.Status.LastUpdateTime)
+	assert.Len(t, r.recorder.(*record.FakeRecorder).Events, 1)
+}
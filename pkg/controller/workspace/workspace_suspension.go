@@ -0,0 +1,205 @@
+/*
+ * Copyright 2024 the KubeSphere Authors.
+ * Please refer to the LICENSE file in the root directory of the project.
+ * https://github.com/kubesphere/kubesphere/blob/master/LICENSE
+ */
+
+package workspace
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/pkg/errors"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/utils/ptr"
+	tenantv1beta1 "kubesphere.io/api/tenant/v1beta1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// workspaceState returns the lifecycle state propagated from the workspace template.
+func workspaceState(workspace *tenantv1beta1.Workspace) tenantv1beta1.WorkspaceState {
+	if tenantv1beta1.WorkspaceState(workspace.Labels[tenantv1beta1.WorkspaceStateLabel]) == tenantv1beta1.WorkspaceStateSuspended {
+		return tenantv1beta1.WorkspaceStateSuspended
+	}
+	return tenantv1beta1.WorkspaceStateActive
+}
+
+// suspensionResuming is the value of the suspended label while the workloads of the resumed workspace are being
+// restored, the namespace is no longer guarded by the suspension webhook but the restoration is not finished yet.
+const suspensionResuming = "resuming"
+
+// syncSuspension scales the workloads of the suspended workspace to zero, suspends its cron jobs, evicts its daemon sets
+// and labels its namespaces, so that new workloads are rejected by the suspension webhook. The workloads are restored
+// after the workspace is resumed. Only the namespaces labeled as suspended are inspected, the workloads are listed from
+// the API server in these namespaces instead of being cached cluster wide.
+func (r *Reconciler) syncSuspension(ctx context.Context, workspace *tenantv1beta1.Workspace) error {
+	suspended := workspaceState(workspace) == tenantv1beta1.WorkspaceStateSuspended
+	namespaces := &corev1.NamespaceList{}
+	if err := r.List(ctx, namespaces, client.MatchingLabels{tenantv1beta1.WorkspaceLabel: workspace.Name}); err != nil {
+		return errors.Wrapf(err, "failed to list namespaces in workspace %s", workspace.Name)
+	}
+	for i := range namespaces.Items {
+		namespace := &namespaces.Items[i]
+		if !namespace.DeletionTimestamp.IsZero() {
+			continue
+		}
+		if _, ok := namespace.Labels[tenantv1beta1.WorkspaceSuspendedLabel]; !ok && !suspended {
+			continue
+		}
+		// the namespace is labeled before suspending the workloads, and marked as resuming before restoring them,
+		// otherwise the workloads would be rejected by the webhook
+		label := suspensionResuming
+		if suspended {
+			label = "true"
+		}
+		if err := r.labelSuspendedNamespace(ctx, namespace, label); err != nil {
+			return err
+		}
+		if err := r.suspendWorkloads(ctx, namespace.Name, suspended); err != nil {
+			return err
+		}
+		if !suspended {
+			if err := r.labelSuspendedNamespace(ctx, namespace, ""); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (r *Reconciler) suspendWorkloads(ctx context.Context, namespace string, suspended bool) error {
+	deployments := &appsv1.DeploymentList{}
+	if err := r.apiReader.List(ctx, deployments, client.InNamespace(namespace)); err != nil {
+		return errors.Wrapf(err, "failed to list deployments in namespace %s", namespace)
+	}
+	for i := range deployments.Items {
+		if err := r.suspendWorkload(ctx, &deployments.Items[i], &deployments.Items[i].Spec.Replicas, suspended); err != nil {
+			return err
+		}
+	}
+	statefulSets := &appsv1.StatefulSetList{}
+	if err := r.apiReader.List(ctx, statefulSets, client.InNamespace(namespace)); err != nil {
+		return errors.Wrapf(err, "failed to list statefulsets in namespace %s", namespace)
+	}
+	for i := range statefulSets.Items {
+		if err := r.suspendWorkload(ctx, &statefulSets.Items[i], &statefulSets.Items[i].Spec.Replicas, suspended); err != nil {
+			return err
+		}
+	}
+	daemonSets := &appsv1.DaemonSetList{}
+	if err := r.apiReader.List(ctx, daemonSets, client.InNamespace(namespace)); err != nil {
+		return errors.Wrapf(err, "failed to list daemonsets in namespace %s", namespace)
+	}
+	for i := range daemonSets.Items {
+		if err := r.suspendDaemonSet(ctx, &daemonSets.Items[i], suspended); err != nil {
+			return err
+		}
+	}
+	cronJobs := &batchv1.CronJobList{}
+	if err := r.apiReader.List(ctx, cronJobs, client.InNamespace(namespace)); err != nil {
+		return errors.Wrapf(err, "failed to list cronjobs in namespace %s", namespace)
+	}
+	for i := range cronJobs.Items {
+		if err := r.suspendCronJob(ctx, &cronJobs.Items[i], suspended); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// labelSuspendedNamespace sets the suspended label of the namespace, the label is removed if the value is empty.
+func (r *Reconciler) labelSuspendedNamespace(ctx context.Context, namespace *corev1.Namespace, value string) error {
+	if namespace.Labels[tenantv1beta1.WorkspaceSuspendedLabel] == value {
+		return nil
+	}
+	expected := namespace.DeepCopy()
+	if value != "" {
+		expected.Labels[tenantv1beta1.WorkspaceSuspendedLabel] = value
+	} else {
+		delete(expected.Labels, tenantv1beta1.WorkspaceSuspendedLabel)
+	}
+	if err := r.Patch(ctx, expected, client.MergeFrom(namespace)); err != nil {
+		return errors.Wrapf(err, "failed to update namespace %s", namespace.Name)
+	}
+	expected.DeepCopyInto(namespace)
+	return nil
+}
+
+// suspendDaemonSet evicts the pods of the daemon set by a node selector which no node matches, the daemon set
+// can not be scaled to zero. The node selector is removed after the workspace is resumed.
+func (r *Reconciler) suspendDaemonSet(ctx context.Context, daemonSet *appsv1.DaemonSet, suspended bool) error {
+	nodeSelector := daemonSet.Spec.Template.Spec.NodeSelector
+	if _, ok := nodeSelector[tenantv1beta1.WorkspaceSuspendedLabel]; ok == suspended {
+		return nil
+	}
+	original := daemonSet.DeepCopy()
+	if suspended {
+		if nodeSelector == nil {
+			nodeSelector = make(map[string]string)
+		}
+		nodeSelector[tenantv1beta1.WorkspaceSuspendedLabel] = "true"
+		daemonSet.Spec.Template.Spec.NodeSelector = nodeSelector
+	} else {
+		delete(nodeSelector, tenantv1beta1.WorkspaceSuspendedLabel)
+	}
+	if err := r.Patch(ctx, daemonSet, client.MergeFrom(original)); err != nil {
+		return errors.Wrapf(err, "failed to suspend daemonset %s/%s", daemonSet.Namespace, daemonSet.Name)
+	}
+	return nil
+}
+
+// suspendCronJob suspends the cron job, the cron jobs suspended by the users are kept suspended after the workspace
+// is resumed.
+func (r *Reconciler) suspendCronJob(ctx context.Context, cronJob *batchv1.CronJob, suspended bool) error {
+	_, ok := cronJob.Annotations[tenantv1beta1.SuspendedCronJobAnnotation]
+	if suspended && (ok || ptr.Deref(cronJob.Spec.Suspend, false)) || !suspended && !ok {
+		return nil
+	}
+	original := cronJob.DeepCopy()
+	if suspended {
+		if cronJob.Annotations == nil {
+			cronJob.Annotations = make(map[string]string)
+		}
+		cronJob.Annotations[tenantv1beta1.SuspendedCronJobAnnotation] = "true"
+	} else {
+		delete(cronJob.Annotations, tenantv1beta1.SuspendedCronJobAnnotation)
+	}
+	cronJob.Spec.Suspend = ptr.To(suspended)
+	if err := r.Patch(ctx, cronJob, client.MergeFrom(original)); err != nil {
+		return errors.Wrapf(err, "failed to suspend cronjob %s/%s", cronJob.Namespace, cronJob.Name)
+	}
+	return nil
+}
+
+// suspendWorkload scales the workload to zero and records the original replicas, or restores the replicas.
+func (r *Reconciler) suspendWorkload(ctx context.Context, workload client.Object, replicas **int32, suspended bool) error {
+	original := workload.DeepCopyObject().(client.Object)
+	annotations := workload.GetAnnotations()
+	recorded, ok := annotations[tenantv1beta1.SuspendedReplicasAnnotation]
+	if suspended {
+		if ok || (*replicas != nil && **replicas == 0) {
+			return nil
+		}
+		if annotations == nil {
+			annotations = make(map[string]string)
+		}
+		annotations[tenantv1beta1.SuspendedReplicasAnnotation] = strconv.Itoa(int(ptr.Deref(*replicas, 1)))
+		*replicas = ptr.To[int32](0)
+	} else {
+		if !ok {
+			return nil
+		}
+		delete(annotations, tenantv1beta1.SuspendedReplicasAnnotation)
+		if value, err := strconv.ParseInt(recorded, 10, 32); err == nil {
+			*replicas = ptr.To(int32(value))
+		}
+	}
+	workload.SetAnnotations(annotations)
+	if err := r.Patch(ctx, workload, client.MergeFrom(original)); err != nil {
+		return errors.Wrapf(err, "failed to scale %s/%s", workload.GetNamespace(), workload.GetName())
+	}
+	return nil
+}
@@ -0,0 +1,170 @@
+/*
+ * Copyright 2024 the KubeSphere Authors.
+ * Please refer to the LICENSE file in the root directory of the project.
+ * https://github.com/kubesphere/kubesphere/blob/master/LICENSE
+ */
+
+package workspace
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	admissionv1 "k8s.io/api/admission/v1"
+	appsv1 "k8s.io/api/apps/v1"
+	autoscalingv1 "k8s.io/api/autoscaling/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/ptr"
+	tenantv1beta1 "kubesphere.io/api/tenant/v1beta1"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	"kubesphere.io/kubesphere/pkg/scheme"
+)
+
+func TestSyncSuspension(t *testing.T) {
+	workspace := &tenantv1beta1.Workspace{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   "ws1",
+			Labels: map[string]string{tenantv1beta1.WorkspaceStateLabel: string(tenantv1beta1.WorkspaceStateSuspended)},
+		},
+	}
+	fakeClient := fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "ns1", Labels: map[string]string{tenantv1beta1.WorkspaceLabel: "ws1"}}},
+		&appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "ns1"}, Spec: appsv1.DeploymentSpec{Replicas: ptr.To[int32](3)}},
+		&appsv1.StatefulSet{ObjectMeta: metav1.ObjectMeta{Name: "db", Namespace: "ns1"}},
+		&appsv1.DaemonSet{ObjectMeta: metav1.ObjectMeta{Name: "agent", Namespace: "ns1"}},
+		&batchv1.CronJob{ObjectMeta: metav1.ObjectMeta{Name: "backup", Namespace: "ns1"}},
+		&batchv1.CronJob{ObjectMeta: metav1.ObjectMeta{Name: "paused", Namespace: "ns1"}, Spec: batchv1.CronJobSpec{Suspend: ptr.To(true)}},
+	).Build()
+	r := &Reconciler{Client: fakeClient, apiReader: fakeClient}
+	ctx := context.Background()
+	daemonSet := &appsv1.DaemonSet{}
+	cronJob := &batchv1.CronJob{}
+
+	assert.NoError(t, r.syncSuspension(ctx, workspace))
+	namespace := &corev1.Namespace{}
+	deployment := &appsv1.Deployment{}
+	statefulSet := &appsv1.StatefulSet{}
+	assert.NoError(t, r.Get(ctx, types.NamespacedName{Name: "ns1"}, namespace))
+	assert.Equal(t, "true", namespace.Labels[tenantv1beta1.WorkspaceSuspendedLabel])
+	assert.NoError(t, r.Get(ctx, types.NamespacedName{Namespace: "ns1", Name: "app"}, deployment))
+	assert.Equal(t, int32(0), *deployment.Spec.Replicas)
+	assert.Equal(t, "3", deployment.Annotations[tenantv1beta1.SuspendedReplicasAnnotation])
+	assert.NoError(t, r.Get(ctx, types.NamespacedName{Namespace: "ns1", Name: "db"}, statefulSet))
+	assert.Equal(t, int32(0), *statefulSet.Spec.Replicas)
+	assert.Equal(t, "1", statefulSet.Annotations[tenantv1beta1.SuspendedReplicasAnnotation])
+	assert.NoError(t, r.Get(ctx, types.NamespacedName{Namespace: "ns1", Name: "agent"}, daemonSet))
+	assert.Equal(t, "true", daemonSet.Spec.Template.Spec.NodeSelector[tenantv1beta1.WorkspaceSuspendedLabel])
+	assert.NoError(t, r.Get(ctx, types.NamespacedName{Namespace: "ns1", Name: "backup"}, cronJob))
+	assert.True(t, *cronJob.Spec.Suspend)
+
+	// the replicas are restored after the workspace is resumed
+	delete(workspace.Labels, tenantv1beta1.WorkspaceStateLabel)
+	assert.NoError(t, r.syncSuspension(ctx, workspace))
+	assert.NoError(t, r.Get(ctx, types.NamespacedName{Name: "ns1"}, namespace))
+	assert.NotContains(t, namespace.Labels, tenantv1beta1.WorkspaceSuspendedLabel)
+	assert.NoError(t, r.Get(ctx, types.NamespacedName{Namespace: "ns1", Name: "app"}, deployment))
+	assert.Equal(t, int32(3), *deployment.Spec.Replicas)
+	assert.NotContains(t, deployment.Annotations, tenantv1beta1.SuspendedReplicasAnnotation)
+	assert.NoError(t, r.Get(ctx, types.NamespacedName{Namespace: "ns1", Name: "db"}, statefulSet))
+	assert.Equal(t, int32(1), *statefulSet.Spec.Replicas)
+	assert.NoError(t, r.Get(ctx, types.NamespacedName{Namespace: "ns1", Name: "agent"}, daemonSet))
+	assert.NotContains(t, daemonSet.Spec.Template.Spec.NodeSelector, tenantv1beta1.WorkspaceSuspendedLabel)
+	assert.NoError(t, r.Get(ctx, types.NamespacedName{Namespace: "ns1", Name: "backup"}, cronJob))
+	assert.False(t, *cronJob.Spec.Suspend)
+	// the cron job suspended by the user is kept suspended
+	assert.NoError(t, r.Get(ctx, types.NamespacedName{Namespace: "ns1", Name: "paused"}, cronJob))
+	assert.True(t, *cronJob.Spec.Suspend)
+}
+
+func TestSuspensionWebhook(t *testing.T) {
+	w := &SuspensionWebhook{
+		Client: fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(
+			&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "suspended", Labels: map[string]string{
+				tenantv1beta1.WorkspaceLabel:          "ws1",
+				tenantv1beta1.WorkspaceSuspendedLabel: "true",
+			}}},
+			&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "active", Labels: map[string]string{tenantv1beta1.WorkspaceLabel: "ws2"}}},
+		).Build(),
+	}
+	raw := func(obj runtime.Object) runtime.RawExtension {
+		data, _ := json.Marshal(obj)
+		return runtime.RawExtension{Raw: data}
+	}
+	deployment := func(replicas int32) runtime.RawExtension {
+		return raw(&appsv1.Deployment{Spec: appsv1.DeploymentSpec{Replicas: ptr.To(replicas)}})
+	}
+
+	tests := []struct {
+		name    string
+		request admissionv1.AdmissionRequest
+		allowed bool
+	}{
+		{
+			name: "create pod in active workspace",
+			request: admissionv1.AdmissionRequest{Namespace: "active", Operation: admissionv1.Create,
+				Kind: metav1.GroupVersionKind{Version: "v1", Kind: "Pod"}, Object: raw(&corev1.Pod{})},
+			allowed: true,
+		},
+		{
+			name: "create pod in suspended workspace",
+			request: admissionv1.AdmissionRequest{Namespace: "suspended", Operation: admissionv1.Create,
+				Kind: metav1.GroupVersionKind{Version: "v1", Kind: "Pod"}, Object: raw(&corev1.Pod{})},
+		},
+		{
+			name: "create deployment without replicas",
+			request: admissionv1.AdmissionRequest{Namespace: "suspended", Operation: admissionv1.Create,
+				Kind: metav1.GroupVersionKind{Group: "apps", Version: "v1", Kind: "Deployment"}, Object: raw(&appsv1.Deployment{})},
+		},
+		{
+			name: "scale down deployment",
+			request: admissionv1.AdmissionRequest{Namespace: "suspended", Operation: admissionv1.Update,
+				Kind:   metav1.GroupVersionKind{Group: "apps", Version: "v1", Kind: "Deployment"},
+				Object: deployment(0), OldObject: deployment(3)},
+			allowed: true,
+		},
+		{
+			name: "scale up deployment",
+			request: admissionv1.AdmissionRequest{Namespace: "suspended", Operation: admissionv1.Update,
+				Kind:   metav1.GroupVersionKind{Group: "apps", Version: "v1", Kind: "Deployment"},
+				Object: deployment(1), OldObject: deployment(0)},
+		},
+		{
+			name: "remove node selector of suspended daemonset",
+			request: admissionv1.AdmissionRequest{Namespace: "suspended", Operation: admissionv1.Update,
+				Kind: metav1.GroupVersionKind{Group: "apps", Version: "v1", Kind: "DaemonSet"}, Object: raw(&appsv1.DaemonSet{})},
+		},
+		{
+			name: "resume suspended cronjob",
+			request: admissionv1.AdmissionRequest{Namespace: "suspended", Operation: admissionv1.Update,
+				Kind:   metav1.GroupVersionKind{Group: "batch", Version: "v1", Kind: "CronJob"},
+				Object: raw(&batchv1.CronJob{Spec: batchv1.CronJobSpec{Suspend: ptr.To(false)}})},
+		},
+		{
+			name: "suspend cronjob",
+			request: admissionv1.AdmissionRequest{Namespace: "suspended", Operation: admissionv1.Update,
+				Kind:   metav1.GroupVersionKind{Group: "batch", Version: "v1", Kind: "CronJob"},
+				Object: raw(&batchv1.CronJob{Spec: batchv1.CronJobSpec{Suspend: ptr.To(true)}})},
+			allowed: true,
+		},
+		{
+			name: "scale up through subresource",
+			request: admissionv1.AdmissionRequest{Namespace: "suspended", Operation: admissionv1.Update, SubResource: "scale",
+				Kind:   metav1.GroupVersionKind{Group: "autoscaling", Version: "v1", Kind: "Scale"},
+				Object: raw(&autoscalingv1.Scale{Spec: autoscalingv1.ScaleSpec{Replicas: 2}})},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := w.Handle(context.Background(), admission.Request{AdmissionRequest: tt.request})
+			assert.Equal(t, tt.allowed, resp.Allowed)
+		})
+	}
+}
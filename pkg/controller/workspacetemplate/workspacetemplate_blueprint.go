@@ -0,0 +1,356 @@
+/*
+ * Copyright 2024 the KubeSphere Authors.
+ * Please refer to the LICENSE file in the root directory of the project.
+ * https://github.com/kubesphere/kubesphere/blob/master/LICENSE
+ */
+
+package workspacetemplate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/sets"
+	iamv1beta1 "kubesphere.io/api/iam/v1beta1"
+	quotav1alpha2 "kubesphere.io/api/quota/v1alpha2"
+	tenantv1beta1 "kubesphere.io/api/tenant/v1beta1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+const (
+	// blueprintResyncPeriod is the period to correct the drift of the resources applied from the blueprints.
+	blueprintResyncPeriod = 10 * time.Minute
+	// blueprintResourceName is the name of the quota, the limit ranges and the isolation network policies applied from the blueprint.
+	blueprintResourceName = "workspace-blueprint"
+	blueprintNotFound     = "BlueprintNotFound"
+	blueprintApplied      = "Applied"
+	resourceConflict      = "ResourceConflict"
+)
+
+// errNotOwnedByBlueprint is returned if the existing resource is not applied from the blueprint, it is never overwritten.
+var errNotOwnedByBlueprint = errors.New("the resource is not owned by the blueprint")
+
+// resolveBlueprint returns the blueprint of the workspace merged with the overrides, nil if the workspace has no blueprint.
+func (r *Reconciler) resolveBlueprint(ctx context.Context, workspaceTemplate *tenantv1beta1.WorkspaceTemplate) (*tenantv1beta1.WorkspaceBlueprintSpec, error) {
+	reference := workspaceTemplate.Spec.Blueprint
+	if reference == nil {
+		return nil, nil
+	}
+	blueprint := &tenantv1beta1.WorkspaceBlueprint{}
+	if err := r.Get(ctx, types.NamespacedName{Name: reference.Name}, blueprint); err != nil {
+		if apierrors.IsNotFound(err) {
+			r.recorder.Eventf(workspaceTemplate, corev1.EventTypeWarning, blueprintNotFound, "workspace blueprint %s not found", reference.Name)
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get workspace blueprint %s: %s", reference.Name, err)
+	}
+	return mergeBlueprint(&blueprint.Spec, reference.Overrides), nil
+}
+
+// mergeBlueprint merges the namespaces, network policies and roles by name, the other fields of the overrides
+// replace the values of the blueprint.
+func mergeBlueprint(blueprint, overrides *tenantv1beta1.WorkspaceBlueprintSpec) *tenantv1beta1.WorkspaceBlueprintSpec {
+	merged := blueprint.DeepCopy()
+	if overrides == nil {
+		return merged
+	}
+	overrides = overrides.DeepCopy()
+	merged.Namespaces = mergeByName(merged.Namespaces, overrides.Namespaces, func(ns tenantv1beta1.BlueprintNamespace) string { return ns.Name })
+	merged.NetworkPolicies = mergeByName(merged.NetworkPolicies, overrides.NetworkPolicies, func(np tenantv1beta1.BlueprintNetworkPolicy) string { return np.Name })
+	merged.Roles = mergeByName(merged.Roles, overrides.Roles, func(role tenantv1beta1.BlueprintRole) string { return role.Name })
+	if overrides.Quota != nil {
+		merged.Quota = overrides.Quota
+	}
+	if overrides.LimitRange != nil {
+		merged.LimitRange = overrides.LimitRange
+	}
+	if overrides.NetworkIsolation != nil {
+		merged.NetworkIsolation = overrides.NetworkIsolation
+	}
+	return merged
+}
+
+func mergeByName[T any](items, overrides []T, name func(T) string) []T {
+	result := append([]T(nil), items...)
+	for _, override := range overrides {
+		replaced := false
+		for i := range result {
+			if name(result[i]) == name(override) {
+				result[i] = override
+				replaced = true
+				break
+			}
+		}
+		if !replaced {
+			result = append(result, override)
+		}
+	}
+	return result
+}
+
+// syncBlueprintRoles creates the custom workspace roles of the blueprint, and deletes the roles removed from the blueprint,
+// all of the roles applied from the blueprint are deleted if the workspace has no blueprint.
+// It returns whether any of the roles has been changed.
+func (r *Reconciler) syncBlueprintRoles(ctx context.Context, workspaceTemplate *tenantv1beta1.WorkspaceTemplate, blueprint *tenantv1beta1.WorkspaceBlueprintSpec) (bool, error) {
+	if blueprint == nil {
+		blueprint = &tenantv1beta1.WorkspaceBlueprintSpec{}
+	}
+	changed := false
+	desired := sets.New[string]()
+	for _, role := range blueprint.Roles {
+		workspaceRole := &iamv1beta1.WorkspaceRole{ObjectMeta: metav1.ObjectMeta{Name: ensureWorkspaceRoleName(workspaceTemplate.Name, role.Name)}}
+		desired.Insert(workspaceRole.Name)
+		applied, err := r.applyBlueprintResource(ctx, r.Client, workspaceTemplate, workspaceRole, func() {
+			workspaceRole.Labels[tenantv1beta1.WorkspaceLabel] = workspaceTemplate.Name
+			// the rules of the aggregated roles are maintained by the role template controller
+			if len(role.TemplateNames) > 0 {
+				workspaceRole.AggregationRoleTemplates = &iamv1beta1.AggregationRoleTemplates{TemplateNames: role.TemplateNames}
+			} else {
+				workspaceRole.AggregationRoleTemplates = nil
+				workspaceRole.Rules = role.Rules
+			}
+		})
+		if errors.Is(err, errNotOwnedByBlueprint) {
+			r.logger.V(4).Info("workspace role is not owned by the blueprint", "workspace", workspaceTemplate.Name, "name", workspaceRole.Name)
+			continue
+		}
+		if err != nil {
+			return false, err
+		}
+		changed = changed || applied
+	}
+	workspaceRoles := &iamv1beta1.WorkspaceRoleList{}
+	if err := r.List(ctx, workspaceRoles, client.MatchingLabels{tenantv1beta1.WorkspaceLabel: workspaceTemplate.Name}, client.HasLabels{tenantv1beta1.WorkspaceBlueprintLabel}); err != nil {
+		return false, fmt.Errorf("failed to list workspace roles: %s", err)
+	}
+	for i := range workspaceRoles.Items {
+		if !desired.Has(workspaceRoles.Items[i].Name) {
+			if err := r.Delete(ctx, &workspaceRoles.Items[i]); client.IgnoreNotFound(err) != nil {
+				return false, fmt.Errorf("failed to delete workspace role %s: %s", workspaceRoles.Items[i].Name, err)
+			}
+			changed = true
+		}
+	}
+	return changed, nil
+}
+
+// applyBlueprint applies the namespaces, the quota, the limit ranges and the network policies of the blueprint
+// to the workspace in the cluster, the resources applied from the blueprint are deleted if the workspace has no blueprint.
+// The existing resources which are not owned by the blueprint of the workspace are never adopted, they are skipped
+// and returned as conflicts, together with whether any of the resources has been changed.
+func (r *Reconciler) applyBlueprint(ctx context.Context, clusterClient client.Client, workspaceTemplate *tenantv1beta1.WorkspaceTemplate, blueprint *tenantv1beta1.WorkspaceBlueprintSpec) ([]string, bool, error) {
+	if blueprint == nil {
+		blueprint = &tenantv1beta1.WorkspaceBlueprintSpec{}
+	}
+	var conflicts []string
+	changed := false
+	for _, ns := range blueprint.Namespaces {
+		namespace := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: fmt.Sprintf("%s-%s", workspaceTemplate.Name, ns.Name)}}
+		if err := clusterClient.Get(ctx, client.ObjectKeyFromObject(namespace), namespace); err == nil {
+			if _, ok := namespace.Labels[tenantv1beta1.WorkspaceBlueprintLabel]; !ok ||
+				namespace.Labels[tenantv1beta1.WorkspaceLabel] != workspaceTemplate.Name {
+				conflicts = append(conflicts, "Namespace "+namespace.Name)
+				continue
+			}
+		} else if !apierrors.IsNotFound(err) {
+			return nil, false, fmt.Errorf("failed to get namespace %s: %s", namespace.Name, err)
+		}
+		applied, err := r.applyBlueprintResource(ctx, clusterClient, workspaceTemplate, namespace, func() {
+			for k, v := range ns.Labels {
+				namespace.Labels[k] = v
+			}
+			namespace.Labels[tenantv1beta1.WorkspaceLabel] = workspaceTemplate.Name
+			for k, v := range ns.Annotations {
+				if namespace.Annotations == nil {
+					namespace.Annotations = make(map[string]string)
+				}
+				namespace.Annotations[k] = v
+			}
+		})
+		if err != nil {
+			return nil, false, err
+		}
+		changed = changed || applied
+	}
+
+	resourceQuota := &quotav1alpha2.ResourceQuota{ObjectMeta: metav1.ObjectMeta{Name: fmt.Sprintf("%s-%s", workspaceTemplate.Name, blueprintResourceName)}}
+	var applied bool
+	var err error
+	if blueprint.Quota != nil {
+		applied, err = r.applyBlueprintResource(ctx, clusterClient, workspaceTemplate, resourceQuota, func() {
+			resourceQuota.Labels[tenantv1beta1.WorkspaceLabel] = workspaceTemplate.Name
+			resourceQuota.Spec.LabelSelector = map[string]string{tenantv1beta1.WorkspaceLabel: workspaceTemplate.Name}
+			resourceQuota.Spec.Quota = *blueprint.Quota
+		})
+		if errors.Is(err, errNotOwnedByBlueprint) {
+			conflicts = append(conflicts, "ResourceQuota "+resourceQuota.Name)
+			err = nil
+		}
+	} else {
+		applied, err = deleteBlueprintResource(ctx, clusterClient, resourceQuota)
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	changed = changed || applied
+
+	namespaces := &corev1.NamespaceList{}
+	if err := clusterClient.List(ctx, namespaces, client.MatchingLabels{tenantv1beta1.WorkspaceLabel: workspaceTemplate.Name}); err != nil {
+		return nil, false, fmt.Errorf("failed to list namespaces: %s", err)
+	}
+	for _, namespace := range namespaces.Items {
+		if !namespace.DeletionTimestamp.IsZero() {
+			continue
+		}
+		namespaceConflicts, applied, err := r.applyNamespaceBlueprint(ctx, clusterClient, workspaceTemplate, namespace.Name, blueprint)
+		if err != nil {
+			return nil, false, err
+		}
+		conflicts = append(conflicts, namespaceConflicts...)
+		changed = changed || applied
+	}
+	return conflicts, changed, nil
+}
+
+// applyNamespaceBlueprint applies the limit range and the network policies of the blueprint to the namespace,
+// and returns the existing resources which are not owned by the blueprint as conflicts.
+func (r *Reconciler) applyNamespaceBlueprint(ctx context.Context, clusterClient client.Client, workspaceTemplate *tenantv1beta1.WorkspaceTemplate, namespace string, blueprint *tenantv1beta1.WorkspaceBlueprintSpec) ([]string, bool, error) {
+	var conflicts []string
+	limitRange := &corev1.LimitRange{ObjectMeta: metav1.ObjectMeta{Name: blueprintResourceName, Namespace: namespace}}
+	var changed bool
+	var err error
+	if blueprint.LimitRange != nil {
+		changed, err = r.applyBlueprintResource(ctx, clusterClient, workspaceTemplate, limitRange, func() {
+			limitRange.Spec = *blueprint.LimitRange
+		})
+		if errors.Is(err, errNotOwnedByBlueprint) {
+			conflicts = append(conflicts, fmt.Sprintf("LimitRange %s/%s", namespace, limitRange.Name))
+			err = nil
+		}
+	} else {
+		changed, err = deleteBlueprintResource(ctx, clusterClient, limitRange)
+	}
+	if err != nil {
+		return nil, false, err
+	}
+
+	desired := sets.New[string]()
+	if blueprint.NetworkIsolation != nil && *blueprint.NetworkIsolation {
+		desired.Insert(blueprintResourceName)
+		isolation := &networkingv1.NetworkPolicy{ObjectMeta: metav1.ObjectMeta{Name: blueprintResourceName, Namespace: namespace}}
+		applied, err := r.applyBlueprintResource(ctx, clusterClient, workspaceTemplate, isolation, func() {
+			isolation.Spec = networkingv1.NetworkPolicySpec{
+				PolicyTypes: []networkingv1.PolicyType{networkingv1.PolicyTypeIngress},
+				Ingress: []networkingv1.NetworkPolicyIngressRule{{
+					From: []networkingv1.NetworkPolicyPeer{{
+						NamespaceSelector: &metav1.LabelSelector{MatchLabels: map[string]string{tenantv1beta1.WorkspaceLabel: workspaceTemplate.Name}},
+					}},
+				}},
+			}
+		})
+		if errors.Is(err, errNotOwnedByBlueprint) {
+			conflicts = append(conflicts, fmt.Sprintf("NetworkPolicy %s/%s", namespace, isolation.Name))
+			err = nil
+		}
+		if err != nil {
+			return nil, false, err
+		}
+		changed = changed || applied
+	}
+	for _, policy := range blueprint.NetworkPolicies {
+		desired.Insert(policy.Name)
+		networkPolicy := &networkingv1.NetworkPolicy{ObjectMeta: metav1.ObjectMeta{Name: policy.Name, Namespace: namespace}}
+		applied, err := r.applyBlueprintResource(ctx, clusterClient, workspaceTemplate, networkPolicy, func() {
+			networkPolicy.Spec = *policy.Spec.DeepCopy()
+		})
+		if errors.Is(err, errNotOwnedByBlueprint) {
+			conflicts = append(conflicts, fmt.Sprintf("NetworkPolicy %s/%s", namespace, networkPolicy.Name))
+			err = nil
+		}
+		if err != nil {
+			return nil, false, err
+		}
+		changed = changed || applied
+	}
+	networkPolicies := &networkingv1.NetworkPolicyList{}
+	if err := clusterClient.List(ctx, networkPolicies, client.InNamespace(namespace), client.HasLabels{tenantv1beta1.WorkspaceBlueprintLabel}); err != nil {
+		return nil, false, fmt.Errorf("failed to list network policies in namespace %s: %s", namespace, err)
+	}
+	for i := range networkPolicies.Items {
+		if !desired.Has(networkPolicies.Items[i].Name) {
+			if err := clusterClient.Delete(ctx, &networkPolicies.Items[i]); client.IgnoreNotFound(err) != nil {
+				return nil, false, fmt.Errorf("failed to delete network policy %s/%s: %s", namespace, networkPolicies.Items[i].Name, err)
+			}
+			changed = true
+		}
+	}
+	return conflicts, changed, nil
+}
+
+// applyBlueprintResource creates or updates the resource and marks it as applied from the blueprint,
+// the changes made to the resource are reverted. It returns whether the resource has been created or updated,
+// and errNotOwnedByBlueprint if the resource exists but is not applied from the blueprint.
+func (r *Reconciler) applyBlueprintResource(ctx context.Context, c client.Client, workspaceTemplate *tenantv1beta1.WorkspaceTemplate, obj client.Object, mutate func()) (bool, error) {
+	op, err := controllerutil.CreateOrUpdate(ctx, c, obj, func() error {
+		labels := obj.GetLabels()
+		if _, ok := labels[tenantv1beta1.WorkspaceBlueprintLabel]; !ok && obj.GetResourceVersion() != "" {
+			return errNotOwnedByBlueprint
+		}
+		if labels == nil {
+			labels = make(map[string]string)
+		}
+		labels[tenantv1beta1.WorkspaceBlueprintLabel] = workspaceTemplate.Spec.Blueprint.Name
+		obj.SetLabels(labels)
+		mutate()
+		return nil
+	})
+	if errors.Is(err, errNotOwnedByBlueprint) {
+		return false, err
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to apply %T %s: %s", obj, client.ObjectKeyFromObject(obj), err)
+	}
+	return op != controllerutil.OperationResultNone, nil
+}
+
+// deleteBlueprintResource deletes the resource if it was applied from the blueprint, and returns whether it has been deleted.
+func deleteBlueprintResource(ctx context.Context, c client.Client, obj client.Object) (bool, error) {
+	if err := c.Get(ctx, client.ObjectKeyFromObject(obj), obj); err != nil {
+		return false, client.IgnoreNotFound(err)
+	}
+	if _, ok := obj.GetLabels()[tenantv1beta1.WorkspaceBlueprintLabel]; !ok {
+		return false, nil
+	}
+	if err := c.Delete(ctx, obj); err != nil {
+		if apierrors.IsNotFound(err) {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to delete %T %s: %s", obj, client.ObjectKeyFromObject(obj), err)
+	}
+	return true, nil
+}
+
+// mapBlueprintToTemplates enqueues the workspace templates which reference the blueprint.
+func (r *Reconciler) mapBlueprintToTemplates(ctx context.Context, o client.Object) []reconcile.Request {
+	workspaceTemplates := &tenantv1beta1.WorkspaceTemplateList{}
+	if err := r.List(ctx, workspaceTemplates); err != nil {
+		r.logger.Error(err, "failed to list workspace templates")
+		return nil
+	}
+	var result []reconcile.Request
+	for _, workspaceTemplate := range workspaceTemplates.Items {
+		if workspaceTemplate.Spec.Blueprint != nil && workspaceTemplate.Spec.Blueprint.Name == o.GetName() {
+			result = append(result, reconcile.Request{NamespacedName: types.NamespacedName{Name: workspaceTemplate.Name}})
+		}
+	}
+	return result
+}
@@ -0,0 +1,230 @@
+/*
+ * Copyright 2024 the KubeSphere Authors.
+ * Please refer to the LICENSE file in the root directory of the project.
+ * https://github.com/kubesphere/kubesphere/blob/master/LICENSE
+ */
+
+package workspacetemplate
+
+import (
+	"context"
+	"testing"
+
+	"github.com/go-logr/logr"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/utils/ptr"
+	clusterv1alpha1 "kubesphere.io/api/cluster/v1alpha1"
+	iamv1beta1 "kubesphere.io/api/iam/v1beta1"
+	quotav1alpha2 "kubesphere.io/api/quota/v1alpha2"
+	tenantv1beta1 "kubesphere.io/api/tenant/v1beta1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"kubesphere.io/kubesphere/pkg/scheme"
+)
+
+func TestMergeBlueprint(t *testing.T) {
+	blueprint := &tenantv1beta1.WorkspaceBlueprintSpec{
+		Namespaces: []tenantv1beta1.BlueprintNamespace{
+			{Name: "dev", Labels: map[string]string{"env": "dev"}},
+			{Name: "prod"},
+		},
+		Quota:            &corev1.ResourceQuotaSpec{Hard: corev1.ResourceList{corev1.ResourceLimitsCPU: resource.MustParse("8")}},
+		NetworkIsolation: ptr.To(true),
+		Roles:            []tenantv1beta1.BlueprintRole{{Name: "developer", TemplateNames: []string{"view-apps"}}},
+	}
+	overrides := &tenantv1beta1.WorkspaceBlueprintSpec{
+		Namespaces:       []tenantv1beta1.BlueprintNamespace{{Name: "dev", Labels: map[string]string{"env": "test"}}, {Name: "staging"}},
+		Quota:            &corev1.ResourceQuotaSpec{Hard: corev1.ResourceList{corev1.ResourceLimitsCPU: resource.MustParse("16")}},
+		NetworkIsolation: ptr.To(false),
+	}
+
+	merged := mergeBlueprint(blueprint, overrides)
+	assert.Len(t, merged.Namespaces, 3)
+	assert.Equal(t, "test", merged.Namespaces[0].Labels["env"])
+	assert.Equal(t, "staging", merged.Namespaces[2].Name)
+	assert.Equal(t, "16", ptr.To(merged.Quota.Hard[corev1.ResourceLimitsCPU]).String())
+	assert.False(t, *merged.NetworkIsolation)
+	assert.Equal(t, blueprint.Roles, merged.Roles)
+	// the blueprint is not modified
+	assert.Equal(t, "dev", blueprint.Namespaces[0].Labels["env"])
+	assert.Len(t, blueprint.Namespaces, 2)
+}
+
+func TestApplyBlueprint(t *testing.T) {
+	cluster := clusterv1alpha1.Cluster{
+		ObjectMeta: metav1.ObjectMeta{Name: "member"},
+		Status: clusterv1alpha1.ClusterStatus{Conditions: []clusterv1alpha1.ClusterCondition{
+			{Type: clusterv1alpha1.ClusterReady, Status: corev1.ConditionTrue},
+		}},
+	}
+	blueprint := &tenantv1beta1.WorkspaceBlueprint{
+		ObjectMeta: metav1.ObjectMeta{Name: "standard"},
+		Spec: tenantv1beta1.WorkspaceBlueprintSpec{
+			Namespaces:       []tenantv1beta1.BlueprintNamespace{{Name: "dev"}, {Name: "prod"}},
+			Quota:            &corev1.ResourceQuotaSpec{Hard: corev1.ResourceList{corev1.ResourceLimitsCPU: resource.MustParse("8")}},
+			LimitRange:       &corev1.LimitRangeSpec{Limits: []corev1.LimitRangeItem{{Type: corev1.LimitTypeContainer}}},
+			NetworkIsolation: ptr.To(true),
+			NetworkPolicies:  []tenantv1beta1.BlueprintNetworkPolicy{{Name: "deny-egress", Spec: networkingv1.NetworkPolicySpec{PolicyTypes: []networkingv1.PolicyType{networkingv1.PolicyTypeEgress}}}},
+			Roles: []tenantv1beta1.BlueprintRole{{Name: "auditor", Rules: []rbacv1.PolicyRule{
+				{APIGroups: []string{"*"}, Resources: []string{"*"}, Verbs: []string{"get", "list"}},
+			}}},
+		},
+	}
+	workspaceTemplate := &tenantv1beta1.WorkspaceTemplate{
+		ObjectMeta: metav1.ObjectMeta{Name: "ws1"},
+		Spec: tenantv1beta1.WorkspaceTemplateSpec{
+			Template:  tenantv1beta1.Template{Spec: tenantv1beta1.WorkspaceSpec{Manager: "admin"}},
+			Placement: tenantv1beta1.GenericPlacement{Clusters: []tenantv1beta1.GenericClusterReference{{Name: "member"}}},
+			Blueprint: &tenantv1beta1.WorkspaceBlueprintReference{Name: "standard"},
+		},
+	}
+	hostClient := fake.NewClientBuilder().WithScheme(scheme.Scheme).
+		WithObjects(workspaceTemplate, blueprint).
+		WithStatusSubresource(workspaceTemplate).
+		Build()
+	// the existing namespace of another workspace and the existing limit range are not adopted
+	memberClient := fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "ws1-prod", Labels: map[string]string{tenantv1beta1.WorkspaceLabel: "ws2"}}},
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "legacy", Labels: map[string]string{tenantv1beta1.WorkspaceLabel: "ws1"}}},
+		&corev1.LimitRange{ObjectMeta: metav1.ObjectMeta{Name: blueprintResourceName, Namespace: "legacy"}},
+	).Build()
+	r := &Reconciler{
+		Client:   hostClient,
+		logger:   logr.Discard(),
+		recorder: record.NewFakeRecorder(10),
+		clusterClientSet: &fakeClusterClientSet{
+			clusters: []clusterv1alpha1.Cluster{cluster},
+			clients:  map[string]client.Client{"member": memberClient},
+		},
+	}
+	ctx := context.Background()
+
+	changed, err := r.multiClusterSync(ctx, workspaceTemplate, tenantv1beta1.WorkspaceStateActive, &workspaceTemplate.Status)
+	assert.NoError(t, err)
+	assert.True(t, changed)
+	workspaceRole := &iamv1beta1.WorkspaceRole{}
+	assert.NoError(t, hostClient.Get(ctx, types.NamespacedName{Name: "ws1-auditor"}, workspaceRole))
+	assert.Equal(t, "ws1", workspaceRole.Labels[tenantv1beta1.WorkspaceLabel])
+	assert.Equal(t, blueprint.Spec.Roles[0].Rules, workspaceRole.Rules)
+	namespace := &corev1.Namespace{}
+	assert.NoError(t, memberClient.Get(ctx, types.NamespacedName{Name: "ws1-dev"}, namespace))
+	assert.Equal(t, "ws1", namespace.Labels[tenantv1beta1.WorkspaceLabel])
+	assert.Equal(t, "standard", namespace.Labels[tenantv1beta1.WorkspaceBlueprintLabel])
+	assert.NoError(t, memberClient.Get(ctx, types.NamespacedName{Name: "ws1-prod"}, namespace))
+	assert.Equal(t, "ws2", namespace.Labels[tenantv1beta1.WorkspaceLabel])
+	assert.NotContains(t, namespace.Labels, tenantv1beta1.WorkspaceBlueprintLabel)
+	condition := meta.FindStatusCondition(workspaceTemplate.Status.Conditions, tenantv1beta1.WorkspaceTemplateConditionBlueprintApplied)
+	assert.Equal(t, metav1.ConditionFalse, condition.Status)
+	assert.Equal(t, resourceConflict, condition.Reason)
+	assert.Contains(t, condition.Message, "Namespace ws1-prod in cluster member")
+	assert.Contains(t, condition.Message, "LimitRange legacy/workspace-blueprint in cluster member")
+	limitRange := &corev1.LimitRange{}
+	assert.NoError(t, memberClient.Get(ctx, types.NamespacedName{Namespace: "legacy", Name: blueprintResourceName}, limitRange))
+	assert.NotContains(t, limitRange.Labels, tenantv1beta1.WorkspaceBlueprintLabel)
+	assert.Empty(t, limitRange.Spec.Limits)
+	resourceQuota := &quotav1alpha2.ResourceQuota{}
+	assert.NoError(t, memberClient.Get(ctx, types.NamespacedName{Name: "ws1-workspace-blueprint"}, resourceQuota))
+	assert.Equal(t, "ws1", resourceQuota.Spec.LabelSelector[tenantv1beta1.WorkspaceLabel])
+	assert.NoError(t, memberClient.Get(ctx, types.NamespacedName{Namespace: "ws1-dev", Name: blueprintResourceName}, limitRange))
+	isolation := &networkingv1.NetworkPolicy{}
+	assert.NoError(t, memberClient.Get(ctx, types.NamespacedName{Namespace: "ws1-dev", Name: blueprintResourceName}, isolation))
+	assert.Equal(t, "ws1", isolation.Spec.Ingress[0].From[0].NamespaceSelector.MatchLabels[tenantv1beta1.WorkspaceLabel])
+	networkPolicy := &networkingv1.NetworkPolicy{}
+	assert.NoError(t, memberClient.Get(ctx, types.NamespacedName{Namespace: "ws1-dev", Name: "deny-egress"}, networkPolicy))
+
+	// nothing is changed if the resources are in sync
+	changed, err = r.multiClusterSync(ctx, workspaceTemplate, tenantv1beta1.WorkspaceStateActive, &workspaceTemplate.Status)
+	assert.NoError(t, err)
+	assert.False(t, changed)
+
+	// the drift is corrected
+	networkPolicy.Spec.PolicyTypes = []networkingv1.PolicyType{networkingv1.PolicyTypeIngress}
+	assert.NoError(t, memberClient.Update(ctx, networkPolicy))
+	changed, err = r.multiClusterSync(ctx, workspaceTemplate, tenantv1beta1.WorkspaceStateActive, &workspaceTemplate.Status)
+	assert.NoError(t, err)
+	assert.True(t, changed)
+	assert.NoError(t, memberClient.Get(ctx, types.NamespacedName{Namespace: "ws1-dev", Name: "deny-egress"}, networkPolicy))
+	assert.Equal(t, []networkingv1.PolicyType{networkingv1.PolicyTypeEgress}, networkPolicy.Spec.PolicyTypes)
+
+	// the resources removed from the blueprint are deleted, except the namespaces
+	workspaceTemplate.Spec.Blueprint.Overrides = &tenantv1beta1.WorkspaceBlueprintSpec{NetworkIsolation: ptr.To(false)}
+	blueprint.Spec.NetworkPolicies = nil
+	blueprint.Spec.LimitRange = nil
+	blueprint.Spec.Quota = nil
+	blueprint.Spec.Roles = nil
+	assert.NoError(t, hostClient.Update(ctx, blueprint))
+	changed, err = r.multiClusterSync(ctx, workspaceTemplate, tenantv1beta1.WorkspaceStateActive, &workspaceTemplate.Status)
+	assert.NoError(t, err)
+	assert.True(t, changed)
+	assert.True(t, apierrors.IsNotFound(hostClient.Get(ctx, types.NamespacedName{Name: "ws1-auditor"}, workspaceRole)))
+	assert.True(t, apierrors.IsNotFound(memberClient.Get(ctx, types.NamespacedName{Name: "ws1-workspace-blueprint"}, resourceQuota)))
+	assert.True(t, apierrors.IsNotFound(memberClient.Get(ctx, types.NamespacedName{Namespace: "ws1-dev", Name: blueprintResourceName}, limitRange)))
+	assert.True(t, apierrors.IsNotFound(memberClient.Get(ctx, types.NamespacedName{Namespace: "ws1-dev", Name: blueprintResourceName}, isolation)))
+	assert.True(t, apierrors.IsNotFound(memberClient.Get(ctx, types.NamespacedName{Namespace: "ws1-dev", Name: "deny-egress"}, networkPolicy)))
+	assert.NoError(t, memberClient.Get(ctx, types.NamespacedName{Name: "ws1-dev"}, namespace))
+}
+
+func TestCleanupBlueprint(t *testing.T) {
+	cluster := clusterv1alpha1.Cluster{
+		ObjectMeta: metav1.ObjectMeta{Name: "member"},
+		Status: clusterv1alpha1.ClusterStatus{Conditions: []clusterv1alpha1.ClusterCondition{
+			{Type: clusterv1alpha1.ClusterReady, Status: corev1.ConditionTrue},
+		}},
+	}
+	// the blueprint is removed from the workspace
+	workspaceTemplate := &tenantv1beta1.WorkspaceTemplate{
+		ObjectMeta: metav1.ObjectMeta{Name: "ws1"},
+		Spec: tenantv1beta1.WorkspaceTemplateSpec{
+			Placement: tenantv1beta1.GenericPlacement{Clusters: []tenantv1beta1.GenericClusterReference{{Name: "member"}}},
+		},
+	}
+	applied := map[string]string{tenantv1beta1.WorkspaceLabel: "ws1", tenantv1beta1.WorkspaceBlueprintLabel: "standard"}
+	hostClient := fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(
+		workspaceTemplate,
+		&iamv1beta1.WorkspaceRole{ObjectMeta: metav1.ObjectMeta{Name: "ws1-auditor", Labels: applied}},
+		&iamv1beta1.WorkspaceRole{ObjectMeta: metav1.ObjectMeta{Name: "ws1-admin", Labels: map[string]string{tenantv1beta1.WorkspaceLabel: "ws1"}}},
+	).Build()
+	memberClient := fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "ws1-dev", Labels: applied}},
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "legacy", Labels: map[string]string{tenantv1beta1.WorkspaceLabel: "ws1"}}},
+		&quotav1alpha2.ResourceQuota{ObjectMeta: metav1.ObjectMeta{Name: "ws1-workspace-blueprint", Labels: applied}},
+		&corev1.LimitRange{ObjectMeta: metav1.ObjectMeta{Name: blueprintResourceName, Namespace: "ws1-dev", Labels: applied}},
+		&networkingv1.NetworkPolicy{ObjectMeta: metav1.ObjectMeta{Name: blueprintResourceName, Namespace: "ws1-dev", Labels: applied}},
+		&corev1.LimitRange{ObjectMeta: metav1.ObjectMeta{Name: blueprintResourceName, Namespace: "legacy"}},
+		&networkingv1.NetworkPolicy{ObjectMeta: metav1.ObjectMeta{Name: blueprintResourceName, Namespace: "legacy"}},
+	).Build()
+	r := &Reconciler{
+		Client:   hostClient,
+		logger:   logr.Discard(),
+		recorder: record.NewFakeRecorder(10),
+		clusterClientSet: &fakeClusterClientSet{
+			clusters: []clusterv1alpha1.Cluster{cluster},
+			clients:  map[string]client.Client{"member": memberClient},
+		},
+	}
+	ctx := context.Background()
+
+	changed, err := r.multiClusterSync(ctx, workspaceTemplate, tenantv1beta1.WorkspaceStateActive, &workspaceTemplate.Status)
+	assert.NoError(t, err)
+	assert.True(t, changed)
+	assert.True(t, apierrors.IsNotFound(hostClient.Get(ctx, types.NamespacedName{Name: "ws1-auditor"}, &iamv1beta1.WorkspaceRole{})))
+	assert.NoError(t, hostClient.Get(ctx, types.NamespacedName{Name: "ws1-admin"}, &iamv1beta1.WorkspaceRole{}))
+	assert.True(t, apierrors.IsNotFound(memberClient.Get(ctx, types.NamespacedName{Name: "ws1-workspace-blueprint"}, &quotav1alpha2.ResourceQuota{})))
+	assert.True(t, apierrors.IsNotFound(memberClient.Get(ctx, types.NamespacedName{Namespace: "ws1-dev", Name: blueprintResourceName}, &corev1.LimitRange{})))
+	assert.True(t, apierrors.IsNotFound(memberClient.Get(ctx, types.NamespacedName{Namespace: "ws1-dev", Name: blueprintResourceName}, &networkingv1.NetworkPolicy{})))
+	// the resources which are not applied from the blueprint are kept
+	assert.NoError(t, memberClient.Get(ctx, types.NamespacedName{Namespace: "legacy", Name: blueprintResourceName}, &corev1.LimitRange{}))
+	assert.NoError(t, memberClient.Get(ctx, types.NamespacedName{Namespace: "legacy", Name: blueprintResourceName}, &networkingv1.NetworkPolicy{}))
+	assert.NoError(t, memberClient.Get(ctx, types.NamespacedName{Name: "ws1-dev"}, &corev1.Namespace{}))
+	assert.Nil(t, meta.FindStatusCondition(workspaceTemplate.Status.Conditions, tenantv1beta1.WorkspaceTemplateConditionBlueprintApplied))
+}
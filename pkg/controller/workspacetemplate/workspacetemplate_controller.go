@@ -15,6 +15,8 @@ import (
 	"github.com/go-logr/logr"
 	corev1 "k8s.io/api/core/v1"
 	rbacv1 "k8s.io/api/rbac/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/types"
@@ -40,6 +42,7 @@ import (
 	"kubesphere.io/kubesphere/pkg/controller/workspacetemplate/utils"
 	"kubesphere.io/kubesphere/pkg/utils/clusterclient"
 	"kubesphere.io/kubesphere/pkg/utils/hashutil"
+	"kubesphere.io/utils/s3"
 )
 
 const (
@@ -52,6 +55,8 @@ type Reconciler struct {
 	logger           logr.Logger
 	recorder         record.EventRecorder
 	clusterClientSet clusterclient.Interface
+	// s3Client stores the manifests of the archived workspaces
+	s3Client s3.Interface
 }
 
 func (r *Reconciler) Enabled(clusterRole string) bool {
@@ -70,10 +75,21 @@ func (r *Reconciler) SetupWithManager(mgr *kscontroller.Manager) error {
 	r.Client = mgr.GetClient()
 	r.logger = ctrl.Log.WithName("controllers").WithName(controllerName)
 	r.recorder = mgr.GetEventRecorderFor(controllerName)
+	if mgr.Options.S3Options != nil && mgr.Options.S3Options.Endpoint != "" {
+		s3Client, err := s3.NewS3Client(mgr.Options.S3Options)
+		if err != nil {
+			return fmt.Errorf("failed to create s3 client: %s", err)
+		}
+		r.s3Client = s3Client
+	}
 	return ctrl.NewControllerManagedBy(mgr).
 		Named(controllerName).
 		WithOptions(controller.Options{MaxConcurrentReconciles: 2}).
 		For(&tenantv1beta1.WorkspaceTemplate{}).
+		Watches(
+			&tenantv1beta1.WorkspaceBlueprint{},
+			handler.EnqueueRequestsFromMapFunc(r.mapBlueprintToTemplates),
+		).
 		Watches(
 			&clusterv1alpha1.Cluster{},
 			handler.EnqueueRequestsFromMapFunc(r.mapper),
@@ -103,6 +119,12 @@ func (r *Reconciler) mapper(ctx context.Context, o client.Object) []reconcile.Re
 
 // +kubebuilder:rbac:groups=iam.kubesphere.io,resources=workspacerolebindings,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=tenant.kubesphere.io,resources=workspaces,verbs=get;list;watch;
+// +kubebuilder:rbac:groups=tenant.kubesphere.io,resources=workspacetemplates/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=tenant.kubesphere.io,resources=workspaceblueprints,verbs=get;list;watch
+// +kubebuilder:rbac:groups=iam.kubesphere.io,resources=workspaceroles,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=quota.kubesphere.io,resources=resourcequotas,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=networking.k8s.io,resources=networkpolicies,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups="",resources=namespaces;limitranges,verbs=get;list;watch;create;update;patch;delete
 
 func (r *Reconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
 	logger := r.logger.WithValues("workspacetemplate", req.NamespacedName)
@@ -131,7 +153,7 @@ func (r *Reconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Resu
 	} else {
 		// The object is being deleted
 		if controllerutil.ContainsFinalizer(workspaceTemplate, constants.CascadingDeletionFinalizer) {
-			ok, err := r.workspaceTemplateCascadingDeletion(ctx, workspaceTemplate)
+			ok, err := r.workspaceTemplateCascadingDeletion(ctx, workspaceTemplate, workspaceTemplate.Annotations[constants.DeletionPropagationAnnotation])
 			if err != nil {
 				return ctrl.Result{}, fmt.Errorf("failed to cascade delete workspacetemplate %s: %s", workspaceTemplate.Name, err)
 			}
@@ -147,41 +169,93 @@ func (r *Reconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Resu
 		return ctrl.Result{}, nil
 	}
 
-	if err := r.multiClusterSync(ctx, workspaceTemplate); err != nil {
+	result, changed, err := r.syncLifecycle(ctx, workspaceTemplate)
+	if err != nil {
 		return ctrl.Result{}, err
 	}
 
-	r.recorder.Event(workspaceTemplate, corev1.EventTypeNormal, kscontroller.Synced, kscontroller.MessageResourceSynced)
-	return ctrl.Result{}, nil
+	// resync periodically to correct the drift of the resources applied from the blueprint
+	if workspaceTemplate.Spec.Blueprint != nil && (result.RequeueAfter == 0 || result.RequeueAfter > blueprintResyncPeriod) {
+		result.RequeueAfter = blueprintResyncPeriod
+	}
+
+	r.pruneTerminalRecordings(ctx, workspaceTemplate)
+	if workspaceTemplate.Spec.TerminalRecording != nil && (result.RequeueAfter == 0 || result.RequeueAfter > recordingPruneInterval) {
+		result.RequeueAfter = recordingPruneInterval
+	}
+
+	// the workspace template is requeued periodically, the event is only recorded if anything has been changed
+	if changed {
+		r.recorder.Event(workspaceTemplate, corev1.EventTypeNormal, kscontroller.Synced, kscontroller.MessageResourceSynced)
+	}
+	return result, nil
 }
 
-func (r *Reconciler) multiClusterSync(ctx context.Context, workspaceTemplate *tenantv1beta1.WorkspaceTemplate) error {
+// multiClusterSync syncs the workspace template and its blueprint to the clusters, the result of applying
+// the blueprint is recorded in the status. It returns whether any of the resources has been changed.
+func (r *Reconciler) multiClusterSync(ctx context.Context, workspaceTemplate *tenantv1beta1.WorkspaceTemplate, state tenantv1beta1.WorkspaceState, status *tenantv1beta1.WorkspaceTemplateStatus) (bool, error) {
+	blueprint, err := r.resolveBlueprint(ctx, workspaceTemplate)
+	if err != nil {
+		return false, err
+	}
+	changed, err := r.syncBlueprintRoles(ctx, workspaceTemplate, blueprint)
+	if err != nil {
+		return false, fmt.Errorf("failed to sync blueprint roles of workspace %s: %s", workspaceTemplate.Name, err)
+	}
 	clusters, err := r.clusterClientSet.ListClusters(ctx)
 	if err != nil {
-		return fmt.Errorf("failed to list clusters: %s", err)
+		return false, fmt.Errorf("failed to list clusters: %s", err)
 	}
 	var notReadyClusters []string
+	var conflicts []string
 	for _, cluster := range clusters {
 		// skip if cluster is not ready
 		if !clusterutils.IsClusterReady(&cluster) {
 			notReadyClusters = append(notReadyClusters, cluster.Name)
 			continue
 		}
-		if err := r.syncWorkspaceTemplate(ctx, cluster, workspaceTemplate); err != nil {
-			return fmt.Errorf("failed to sync workspace template %s to cluster %s: %s", workspaceTemplate.Name, cluster.Name, err)
+		clusterConflicts, clusterChanged, err := r.syncWorkspaceTemplate(ctx, cluster, workspaceTemplate, state, blueprint)
+		if err != nil {
+			return false, fmt.Errorf("failed to sync workspace template %s to cluster %s: %s", workspaceTemplate.Name, cluster.Name, err)
+		}
+		changed = changed || clusterChanged
+		for _, conflict := range clusterConflicts {
+			conflicts = append(conflicts, fmt.Sprintf("%s in cluster %s", conflict, cluster.Name))
 		}
 	}
+	if blueprint == nil {
+		meta.RemoveStatusCondition(&status.Conditions, tenantv1beta1.WorkspaceTemplateConditionBlueprintApplied)
+	} else if len(conflicts) > 0 {
+		if !meta.IsStatusConditionFalse(status.Conditions, tenantv1beta1.WorkspaceTemplateConditionBlueprintApplied) {
+			r.recorder.Eventf(workspaceTemplate, corev1.EventTypeWarning, resourceConflict,
+				"%s are not owned by the blueprint of workspace %s", strings.Join(conflicts, ", "), workspaceTemplate.Name)
+		}
+		meta.SetStatusCondition(&status.Conditions, metav1.Condition{
+			Type:    tenantv1beta1.WorkspaceTemplateConditionBlueprintApplied,
+			Status:  metav1.ConditionFalse,
+			Reason:  resourceConflict,
+			Message: fmt.Sprintf("the existing resources %s are not owned by the blueprint of the workspace", strings.Join(conflicts, ", ")),
+		})
+	} else {
+		meta.SetStatusCondition(&status.Conditions, metav1.Condition{
+			Type:   tenantv1beta1.WorkspaceTemplateConditionBlueprintApplied,
+			Status: metav1.ConditionTrue,
+			Reason: blueprintApplied,
+		})
+	}
 	if len(notReadyClusters) > 0 {
 		klog.FromContext(ctx).V(4).Info("cluster not ready", "clusters", strings.Join(notReadyClusters, ","))
 		r.recorder.Event(workspaceTemplate, corev1.EventTypeWarning, kscontroller.SyncFailed, fmt.Sprintf("cluster not ready: %s", strings.Join(notReadyClusters, ",")))
 	}
-	return nil
+	return changed, nil
 }
 
-func (r *Reconciler) syncWorkspaceTemplate(ctx context.Context, cluster clusterv1alpha1.Cluster, workspaceTemplate *tenantv1beta1.WorkspaceTemplate) error {
+// syncWorkspaceTemplate syncs the workspace to the cluster, and returns the namespaces of the blueprint which
+// conflict with the existing namespaces, together with whether any of the resources has been changed.
+func (r *Reconciler) syncWorkspaceTemplate(ctx context.Context, cluster clusterv1alpha1.Cluster, workspaceTemplate *tenantv1beta1.WorkspaceTemplate, state tenantv1beta1.WorkspaceState, blueprint *tenantv1beta1.WorkspaceBlueprintSpec) ([]string, bool, error) {
 	clusterClient, err := r.clusterClientSet.GetRuntimeClient(cluster.Name)
 	if err != nil {
-		return fmt.Errorf("failed to get cluster client for %s: %s", cluster.Name, err)
+		return nil, false, fmt.Errorf("failed to get cluster client for %s: %s", cluster.Name, err)
 	}
 	if utils.WorkspaceTemplateMatchTargetCluster(workspaceTemplate, &cluster) {
 		target := &tenantv1beta1.Workspace{ObjectMeta: metav1.ObjectMeta{Name: workspaceTemplate.Name}}
@@ -198,18 +272,36 @@ func (r *Reconciler) syncWorkspaceTemplate(ctx context.Context, cluster clusterv
 				}
 				target.Annotations[k] = v
 			}
+			if state == tenantv1beta1.WorkspaceStateActive {
+				delete(target.Labels, tenantv1beta1.WorkspaceStateLabel)
+			} else {
+				if target.Labels == nil {
+					target.Labels = make(map[string]string)
+				}
+				target.Labels[tenantv1beta1.WorkspaceStateLabel] = string(state)
+			}
 			target.Spec = workspaceTemplate.Spec.Template.Spec
 			return nil
 		})
 		if err != nil {
-			return err
+			return nil, false, err
 		}
 		klog.FromContext(ctx).V(4).Info("workspace successfully synced", "cluster", cluster.Name, "operation", op)
-	} else {
-		err = clusterClient.Delete(ctx, &tenantv1beta1.Workspace{ObjectMeta: metav1.ObjectMeta{Name: workspaceTemplate.Name}})
-		return client.IgnoreNotFound(err)
+		changed := op != controllerutil.OperationResultNone
+		// the resources applied from the blueprint are cleaned up if the workspace has no blueprint
+		conflicts, applied, err := r.applyBlueprint(ctx, clusterClient, workspaceTemplate, blueprint)
+		if err != nil {
+			return nil, false, fmt.Errorf("failed to apply blueprint: %s", err)
+		}
+		return conflicts, changed || applied, nil
 	}
-	return nil
+	if err = clusterClient.Delete(ctx, &tenantv1beta1.Workspace{ObjectMeta: metav1.ObjectMeta{Name: workspaceTemplate.Name}}); err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil, false, nil
+		}
+		return nil, false, err
+	}
+	return nil, true, nil
 }
 
 func (r *Reconciler) initWorkspaceRoles(ctx context.Context, workspaceTemplate *tenantv1beta1.WorkspaceTemplate) error {
@@ -255,7 +347,12 @@ func (r *Reconciler) initWorkspaceRoles(ctx context.Context, workspaceTemplate *
 			logger.Error(err, "invalid builtin workspace role found", "name", template.Name)
 		}
 	}
-	return nil
+	blueprint, err := r.resolveBlueprint(ctx, workspaceTemplate)
+	if err != nil {
+		return err
+	}
+	_, err = r.syncBlueprintRoles(ctx, workspaceTemplate, blueprint)
+	return err
 }
 
 func ensureWorkspaceRoleName(workspace, role string) string {
@@ -299,7 +396,9 @@ func (r *Reconciler) initManagerRoleBinding(ctx context.Context, workspaceTempla
 	return nil
 }
 
-func (r *Reconciler) workspaceTemplateCascadingDeletion(ctx context.Context, workspaceTemplate *tenantv1beta1.WorkspaceTemplate) (bool, error) {
+// workspaceTemplateCascadingDeletion deletes the workspaces from all clusters, the namespaces are deleted or orphaned
+// according to the propagation policy.
+func (r *Reconciler) workspaceTemplateCascadingDeletion(ctx context.Context, workspaceTemplate *tenantv1beta1.WorkspaceTemplate, propagation string) (bool, error) {
 	clusters, err := r.clusterClientSet.ListClusters(ctx)
 	if err != nil {
 		return false, fmt.Errorf("failed to list clusters: %s", err)
@@ -316,7 +415,7 @@ func (r *Reconciler) workspaceTemplateCascadingDeletion(ctx context.Context, wor
 			notReadyClusters = append(notReadyClusters, cluster.Name)
 			continue
 		}
-		if err := r.workspaceCascadingDeletion(ctx, cluster.Name, clusterClient, workspaceTemplate); err != nil {
+		if err := r.workspaceCascadingDeletion(ctx, cluster.Name, clusterClient, workspaceTemplate, propagation); err != nil {
 			return false, fmt.Errorf("failed to delete workspace %s in cluster %s: %s", workspaceTemplate.Name, cluster.Name, err)
 		}
 	}
@@ -328,7 +427,7 @@ func (r *Reconciler) workspaceTemplateCascadingDeletion(ctx context.Context, wor
 	return true, nil
 }
 
-func (r *Reconciler) workspaceCascadingDeletion(ctx context.Context, clusterName string, clusterClient client.Client, workspaceTemplate *tenantv1beta1.WorkspaceTemplate) error {
+func (r *Reconciler) workspaceCascadingDeletion(ctx context.Context, clusterName string, clusterClient client.Client, workspaceTemplate *tenantv1beta1.WorkspaceTemplate, propagation string) error {
 	workspace := &tenantv1beta1.Workspace{}
 	if err := clusterClient.Get(ctx, types.NamespacedName{Name: workspaceTemplate.Name}, workspace); err != nil {
 		return client.IgnoreNotFound(err)
@@ -339,7 +438,7 @@ func (r *Reconciler) workspaceCascadingDeletion(ctx context.Context, clusterName
 	if workspace.Annotations == nil {
 		workspace.Annotations = make(map[string]string)
 	}
-	workspace.Annotations[constants.DeletionPropagationAnnotation] = workspaceTemplate.Annotations[constants.DeletionPropagationAnnotation]
+	workspace.Annotations[constants.DeletionPropagationAnnotation] = propagation
 	if err := clusterClient.Update(ctx, workspace); err != nil {
 		return fmt.Errorf("failed to update workspace %s in cluster %s: %s", workspace.Name, clusterName, err)
 	}
@@ -0,0 +1,363 @@
+/*
+ * Copyright 2024 the KubeSphere Authors.
+ * Please refer to the LICENSE file in the root directory of the project.
+ * https://github.com/kubesphere/kubesphere/blob/master/LICENSE
+ */
+
+package workspacetemplate
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	clusterv1alpha1 "kubesphere.io/api/cluster/v1alpha1"
+	iamv1beta1 "kubesphere.io/api/iam/v1beta1"
+	tenantv1beta1 "kubesphere.io/api/tenant/v1beta1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+	"sigs.k8s.io/yaml"
+
+	clusterutils "kubesphere.io/kubesphere/pkg/controller/cluster/utils"
+	"kubesphere.io/kubesphere/pkg/controller/workspacetemplate/utils"
+)
+
+const (
+	defaultNotifyBefore = 72 * time.Hour
+
+	workspaceExpiring  = "WorkspaceExpiring"
+	workspaceSuspended = "WorkspaceSuspended"
+	workspaceArchived  = "WorkspaceArchived"
+	workspaceActivated = "WorkspaceActivated"
+)
+
+// archiveResources are the namespaced resources exported when the workspace is archived.
+// Secrets are never exported, the archive is stored in the object storage without encryption.
+var archiveResources = []schema.GroupVersionKind{
+	{Version: "v1", Kind: "ConfigMap"},
+	{Version: "v1", Kind: "Service"},
+	{Version: "v1", Kind: "ServiceAccount"},
+	{Version: "v1", Kind: "PersistentVolumeClaim"},
+	{Group: "apps", Version: "v1", Kind: "Deployment"},
+	{Group: "apps", Version: "v1", Kind: "StatefulSet"},
+	{Group: "apps", Version: "v1", Kind: "DaemonSet"},
+	{Group: "batch", Version: "v1", Kind: "Job"},
+	{Group: "batch", Version: "v1", Kind: "CronJob"},
+	{Group: "networking.k8s.io", Version: "v1", Kind: "Ingress"},
+	{Group: "rbac.authorization.k8s.io", Version: "v1", Kind: "Role"},
+	{Group: "rbac.authorization.k8s.io", Version: "v1", Kind: "RoleBinding"},
+}
+
+// stateRank orders the states, the expiration action only takes effect if it is more restrictive than the desired state.
+var stateRank = map[tenantv1beta1.WorkspaceState]int{
+	tenantv1beta1.WorkspaceStateActive:    0,
+	tenantv1beta1.WorkspaceStateSuspended: 1,
+	tenantv1beta1.WorkspaceStateArchived:  2,
+}
+
+// expirationTime returns the effective expiration time of the workspace, nil if the workspace never expires.
+func expirationTime(workspaceTemplate *tenantv1beta1.WorkspaceTemplate) *metav1.Time {
+	lifecycle := workspaceTemplate.Spec.Lifecycle
+	if lifecycle == nil {
+		return nil
+	}
+	if lifecycle.ExpirationTime != nil {
+		return lifecycle.ExpirationTime.DeepCopy()
+	}
+	if lifecycle.TTL != nil {
+		return &metav1.Time{Time: workspaceTemplate.CreationTimestamp.Add(lifecycle.TTL.Duration)}
+	}
+	return nil
+}
+
+// desiredState returns the state which the workspace should be in at the given time.
+func desiredState(workspaceTemplate *tenantv1beta1.WorkspaceTemplate, now time.Time) tenantv1beta1.WorkspaceState {
+	lifecycle := workspaceTemplate.Spec.Lifecycle
+	if lifecycle == nil {
+		return tenantv1beta1.WorkspaceStateActive
+	}
+	state := lifecycle.State
+	if state == "" {
+		state = tenantv1beta1.WorkspaceStateActive
+	}
+	if expiration := expirationTime(workspaceTemplate); expiration != nil && !now.Before(expiration.Time) {
+		action := lifecycle.ExpirationAction
+		if action == "" {
+			action = tenantv1beta1.WorkspaceStateSuspended
+		}
+		if stateRank[action] > stateRank[state] {
+			state = action
+		}
+	}
+	return state
+}
+
+// syncLifecycle applies the lifecycle state to the placement clusters, notifies the manager before the workspace expires,
+// and requeues the workspace template at the next deadline. It returns whether the status or any of the resources has been changed.
+func (r *Reconciler) syncLifecycle(ctx context.Context, workspaceTemplate *tenantv1beta1.WorkspaceTemplate) (ctrl.Result, bool, error) {
+	now := time.Now()
+	status := workspaceTemplate.Status.DeepCopy()
+	status.ExpirationTime = expirationTime(workspaceTemplate)
+	state := desiredState(workspaceTemplate, now)
+
+	var result ctrl.Result
+	if status.ExpirationTime != nil && now.Before(status.ExpirationTime.Time) {
+		notifyBefore := defaultNotifyBefore
+		if workspaceTemplate.Spec.Lifecycle.NotifyBefore != nil {
+			notifyBefore = workspaceTemplate.Spec.Lifecycle.NotifyBefore.Duration
+		}
+		if notifyAt := status.ExpirationTime.Add(-notifyBefore); now.Before(notifyAt) {
+			result.RequeueAfter = notifyAt.Sub(now)
+			meta.RemoveStatusCondition(&status.Conditions, tenantv1beta1.WorkspaceTemplateConditionExpiring)
+		} else {
+			result.RequeueAfter = status.ExpirationTime.Sub(now)
+			if !meta.IsStatusConditionTrue(status.Conditions, tenantv1beta1.WorkspaceTemplateConditionExpiring) {
+				r.recorder.Eventf(workspaceTemplate, corev1.EventTypeWarning, workspaceExpiring, "workspace %s managed by %s expires at %s",
+					workspaceTemplate.Name, workspaceTemplate.Spec.Template.Spec.Manager, status.ExpirationTime.Format(time.RFC3339))
+			}
+			meta.SetStatusCondition(&status.Conditions, metav1.Condition{
+				Type:    tenantv1beta1.WorkspaceTemplateConditionExpiring,
+				Status:  metav1.ConditionTrue,
+				Reason:  workspaceExpiring,
+				Message: fmt.Sprintf("the workspace expires at %s", status.ExpirationTime.Format(time.RFC3339)),
+			})
+		}
+	} else {
+		meta.RemoveStatusCondition(&status.Conditions, tenantv1beta1.WorkspaceTemplateConditionExpiring)
+	}
+
+	if state == tenantv1beta1.WorkspaceStateArchived {
+		archived := false
+		// the manifests are exported again if the workspace has been activated after the last archive
+		if status.Archive == nil || status.State != tenantv1beta1.WorkspaceStateArchived {
+			archive, err := r.exportManifests(ctx, workspaceTemplate)
+			if err != nil {
+				meta.SetStatusCondition(&status.Conditions, metav1.Condition{
+					Type:    tenantv1beta1.WorkspaceTemplateConditionArchived,
+					Status:  metav1.ConditionFalse,
+					Reason:  "ArchiveFailed",
+					Message: err.Error(),
+				})
+				if updateErr := r.updateStatus(ctx, workspaceTemplate, status); updateErr != nil {
+					return ctrl.Result{}, false, updateErr
+				}
+				return ctrl.Result{}, false, fmt.Errorf("failed to archive workspace %s: %s", workspaceTemplate.Name, err)
+			}
+			status.Archive = archive
+			status.State = state
+			meta.SetStatusCondition(&status.Conditions, metav1.Condition{
+				Type:    tenantv1beta1.WorkspaceTemplateConditionArchived,
+				Status:  metav1.ConditionTrue,
+				Reason:  workspaceArchived,
+				Message: fmt.Sprintf("the manifests have been exported to %s", archive.Location),
+			})
+			// the archive must be recorded before the workspace is deleted from the member clusters
+			if err := r.updateStatus(ctx, workspaceTemplate, status); err != nil {
+				return ctrl.Result{}, false, err
+			}
+			workspaceTemplate.Status = *status.DeepCopy()
+			archived = true
+			r.recorder.Eventf(workspaceTemplate, corev1.EventTypeWarning, workspaceArchived, "workspace %s managed by %s has been archived to %s",
+				workspaceTemplate.Name, workspaceTemplate.Spec.Template.Spec.Manager, archive.Location)
+		}
+		if _, err := r.workspaceTemplateCascadingDeletion(ctx, workspaceTemplate, string(metav1.DeletePropagationBackground)); err != nil {
+			return ctrl.Result{}, false, err
+		}
+		// the archived workspace is not affected by the expiration any more
+		changed := archived || !equality.Semantic.DeepEqual(status, &workspaceTemplate.Status)
+		return ctrl.Result{}, changed, r.updateStatus(ctx, workspaceTemplate, status)
+	}
+
+	changed, err := r.multiClusterSync(ctx, workspaceTemplate, state, status)
+	if err != nil {
+		return ctrl.Result{}, false, err
+	}
+	if status.State != state {
+		if state == tenantv1beta1.WorkspaceStateSuspended {
+			r.recorder.Eventf(workspaceTemplate, corev1.EventTypeWarning, workspaceSuspended, "workspace %s managed by %s has been suspended",
+				workspaceTemplate.Name, workspaceTemplate.Spec.Template.Spec.Manager)
+		} else if status.State != "" {
+			r.recorder.Eventf(workspaceTemplate, corev1.EventTypeNormal, workspaceActivated, "workspace %s has been activated", workspaceTemplate.Name)
+		}
+		status.State = state
+	}
+	changed = changed || !equality.Semantic.DeepEqual(status, &workspaceTemplate.Status)
+	return result, changed, r.updateStatus(ctx, workspaceTemplate, status)
+}
+
+func (r *Reconciler) updateStatus(ctx context.Context, workspaceTemplate *tenantv1beta1.WorkspaceTemplate, status *tenantv1beta1.WorkspaceTemplateStatus) error {
+	if equality.Semantic.DeepEqual(status, &workspaceTemplate.Status) {
+		return nil
+	}
+	updated := workspaceTemplate.DeepCopy()
+	updated.Status = *status
+	if err := r.Status().Patch(ctx, updated, client.MergeFrom(workspaceTemplate)); err != nil {
+		return fmt.Errorf("failed to update status of workspace template %s: %s", workspaceTemplate.Name, err)
+	}
+	return nil
+}
+
+// exportManifests exports the workspace template, the members and the resources in the namespaces of the workspace from
+// all the placement clusters as a gzipped tarball, and uploads it to the object storage.
+func (r *Reconciler) exportManifests(ctx context.Context, workspaceTemplate *tenantv1beta1.WorkspaceTemplate) (*tenantv1beta1.WorkspaceArchive, error) {
+	if r.s3Client == nil {
+		return nil, fmt.Errorf("object storage is not configured")
+	}
+	clusters, err := r.clusterClientSet.ListClusters(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list clusters: %s", err)
+	}
+	var placementClusters []clusterv1alpha1.Cluster
+	for i := range clusters {
+		if !utils.WorkspaceTemplateMatchTargetCluster(workspaceTemplate, &clusters[i]) {
+			continue
+		}
+		// the archive must not be incomplete
+		if !clusterutils.IsClusterReady(&clusters[i]) {
+			return nil, fmt.Errorf("cluster %s is not ready", clusters[i].Name)
+		}
+		placementClusters = append(placementClusters, clusters[i])
+	}
+
+	// the tarball is streamed to the object storage instead of being buffered in memory
+	reader, writer := io.Pipe()
+	writeErr := make(chan error, 1)
+	go func() {
+		err := r.writeArchive(ctx, workspaceTemplate, placementClusters, writer)
+		_ = writer.CloseWithError(err)
+		writeErr <- err
+	}()
+
+	now := time.Now()
+	key := fmt.Sprintf("workspaces/%s/%s-%s.tar.gz", workspaceTemplate.Name, workspaceTemplate.Name, now.Format("20060102150405"))
+	uploadErr := r.s3Client.Upload(key, path.Base(key), reader, 0)
+	// unblock the writer if the upload is aborted
+	_ = reader.CloseWithError(io.ErrClosedPipe)
+	if err := <-writeErr; err != nil {
+		return nil, fmt.Errorf("failed to export manifests: %s", err)
+	}
+	if uploadErr != nil {
+		return nil, fmt.Errorf("failed to upload %s: %s", key, uploadErr)
+	}
+	return &tenantv1beta1.WorkspaceArchive{Location: key, ArchiveTime: metav1.NewTime(now.Round(time.Second))}, nil
+}
+
+// writeArchive writes the manifests of the workspace in the clusters to the writer as a gzipped tarball.
+func (r *Reconciler) writeArchive(ctx context.Context, workspaceTemplate *tenantv1beta1.WorkspaceTemplate, clusters []clusterv1alpha1.Cluster, writer io.Writer) error {
+	gzipWriter := gzip.NewWriter(writer)
+	tarWriter := tar.NewWriter(gzipWriter)
+	writeObject := func(name string, obj map[string]interface{}) error {
+		data, err := yaml.Marshal(sanitizeObject(obj))
+		if err != nil {
+			return err
+		}
+		if err := tarWriter.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: int64(len(data)), Typeflag: tar.TypeReg}); err != nil {
+			return err
+		}
+		_, err = tarWriter.Write(data)
+		return err
+	}
+
+	template, err := r.toUnstructured(workspaceTemplate)
+	if err != nil {
+		return err
+	}
+	if err := writeObject("workspacetemplate.yaml", template); err != nil {
+		return err
+	}
+	workspaceRoleBindings := &iamv1beta1.WorkspaceRoleBindingList{}
+	if err := r.List(ctx, workspaceRoleBindings, client.MatchingLabels{tenantv1beta1.WorkspaceLabel: workspaceTemplate.Name}); err != nil {
+		return fmt.Errorf("failed to list workspace role bindings: %s", err)
+	}
+	for i := range workspaceRoleBindings.Items {
+		obj, err := r.toUnstructured(&workspaceRoleBindings.Items[i])
+		if err != nil {
+			return err
+		}
+		if err := writeObject(path.Join("workspacerolebindings", workspaceRoleBindings.Items[i].Name+".yaml"), obj); err != nil {
+			return err
+		}
+	}
+
+	for i := range clusters {
+		cluster := &clusters[i]
+		clusterClient, err := r.clusterClientSet.GetRuntimeClient(cluster.Name)
+		if err != nil {
+			return fmt.Errorf("failed to get cluster client for %s: %s", cluster.Name, err)
+		}
+		namespaces := &corev1.NamespaceList{}
+		if err := clusterClient.List(ctx, namespaces, client.MatchingLabels{tenantv1beta1.WorkspaceLabel: workspaceTemplate.Name}); err != nil {
+			return fmt.Errorf("failed to list namespaces in cluster %s: %s", cluster.Name, err)
+		}
+		for j := range namespaces.Items {
+			namespace := namespaces.Items[j].Name
+			obj, err := r.toUnstructured(&namespaces.Items[j])
+			if err != nil {
+				return err
+			}
+			if err := writeObject(path.Join(cluster.Name, namespace, "namespace.yaml"), obj); err != nil {
+				return err
+			}
+			for _, gvk := range archiveResources {
+				list := &unstructured.UnstructuredList{}
+				list.SetGroupVersionKind(gvk.GroupVersion().WithKind(gvk.Kind + "List"))
+				if err := clusterClient.List(ctx, list, client.InNamespace(namespace)); err != nil {
+					if meta.IsNoMatchError(err) {
+						continue
+					}
+					return fmt.Errorf("failed to list %s in cluster %s: %s", gvk.Kind, cluster.Name, err)
+				}
+				for _, item := range list.Items {
+					// the objects created by controllers are recreated by their owners
+					if metav1.GetControllerOf(&item) != nil {
+						continue
+					}
+					name := path.Join(cluster.Name, namespace, strings.ToLower(gvk.Kind), item.GetName()+".yaml")
+					if err := writeObject(name, item.Object); err != nil {
+						return err
+					}
+				}
+			}
+		}
+	}
+	if err := tarWriter.Close(); err != nil {
+		return err
+	}
+	return gzipWriter.Close()
+}
+
+func (r *Reconciler) toUnstructured(obj client.Object) (map[string]interface{}, error) {
+	gvk, err := apiutil.GVKForObject(obj, r.Scheme())
+	if err != nil {
+		return nil, err
+	}
+	result, err := runtime.DefaultUnstructuredConverter.ToUnstructured(obj)
+	if err != nil {
+		return nil, err
+	}
+	result["apiVersion"], result["kind"] = gvk.GroupVersion().String(), gvk.Kind
+	return result, nil
+}
+
+// sanitizeObject removes the status and the fields populated by the server.
+func sanitizeObject(obj map[string]interface{}) map[string]interface{} {
+	unstructured.RemoveNestedField(obj, "status")
+	for _, field := range []string{"uid", "resourceVersion", "generation", "creationTimestamp", "deletionTimestamp",
+		"deletionGracePeriodSeconds", "managedFields", "ownerReferences", "finalizers", "selfLink"} {
+		unstructured.RemoveNestedField(obj, "metadata", field)
+	}
+	return obj
+}
@@ -0,0 +1,260 @@
+/*
+ * Copyright 2024 the KubeSphere Authors.
+ * Please refer to the LICENSE file in the root directory of the project.
+ * https://github.com/kubesphere/kubesphere/blob/master/LICENSE
+ */
+
+package workspacetemplate
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	s3lib "github.com/aws/aws-sdk-go/service/s3"
+	"github.com/go-logr/logr"
+	"github.com/stretchr/testify/assert"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	clusterv1alpha1 "kubesphere.io/api/cluster/v1alpha1"
+	tenantv1beta1 "kubesphere.io/api/tenant/v1beta1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"kubesphere.io/kubesphere/pkg/scheme"
+	"kubesphere.io/kubesphere/pkg/utils/clusterclient"
+)
+
+type fakeClusterClientSet struct {
+	clusters []clusterv1alpha1.Cluster
+	clients  map[string]client.Client
+}
+
+func (f *fakeClusterClientSet) Get(name string) (*clusterv1alpha1.Cluster, error) {
+	for i := range f.clusters {
+		if f.clusters[i].Name == name {
+			return &f.clusters[i], nil
+		}
+	}
+	return nil, fmt.Errorf("cluster %s not found", name)
+}
+
+func (f *fakeClusterClientSet) ListClusters(_ context.Context) ([]clusterv1alpha1.Cluster, error) {
+	return f.clusters, nil
+}
+
+func (f *fakeClusterClientSet) GetClusterClient(name string) (*clusterclient.ClusterClient, error) {
+	c, err := f.GetRuntimeClient(name)
+	if err != nil {
+		return nil, err
+	}
+	return &clusterclient.ClusterClient{Client: c}, nil
+}
+
+func (f *fakeClusterClientSet) GetRuntimeClient(name string) (client.Client, error) {
+	c, ok := f.clients[name]
+	if !ok {
+		return nil, fmt.Errorf("cluster %s not found", name)
+	}
+	return c, nil
+}
+
+type fakeS3 struct {
+	objects map[string][]byte
+}
+
+func (f *fakeS3) Read(key string) ([]byte, error) {
+	data, ok := f.objects[key]
+	if !ok {
+		return nil, awserr.New(s3lib.ErrCodeNoSuchKey, key, nil)
+	}
+	return data, nil
+}
+
+func (f *fakeS3) Upload(key, _ string, body io.Reader, _ int) error {
+	data, err := io.ReadAll(body)
+	if err != nil {
+		return err
+	}
+	f.objects[key] = data
+	return nil
+}
+
+func (f *fakeS3) Delete(keys []string) error {
+	for _, key := range keys {
+		delete(f.objects, key)
+	}
+	return nil
+}
+
+func TestDesiredState(t *testing.T) {
+	now := time.Now()
+	created := metav1.NewTime(now.Add(-2 * time.Hour))
+	tests := []struct {
+		name      string
+		lifecycle *tenantv1beta1.WorkspaceLifecycle
+		want      tenantv1beta1.WorkspaceState
+	}{
+		{
+			name: "no lifecycle",
+			want: tenantv1beta1.WorkspaceStateActive,
+		},
+		{
+			name:      "suspended",
+			lifecycle: &tenantv1beta1.WorkspaceLifecycle{State: tenantv1beta1.WorkspaceStateSuspended},
+			want:      tenantv1beta1.WorkspaceStateSuspended,
+		},
+		{
+			name:      "ttl not reached",
+			lifecycle: &tenantv1beta1.WorkspaceLifecycle{TTL: &metav1.Duration{Duration: 3 * time.Hour}},
+			want:      tenantv1beta1.WorkspaceStateActive,
+		},
+		{
+			name:      "ttl reached",
+			lifecycle: &tenantv1beta1.WorkspaceLifecycle{TTL: &metav1.Duration{Duration: time.Hour}},
+			want:      tenantv1beta1.WorkspaceStateSuspended,
+		},
+		{
+			name: "expiration time takes precedence over ttl",
+			lifecycle: &tenantv1beta1.WorkspaceLifecycle{
+				TTL:              &metav1.Duration{Duration: time.Hour},
+				ExpirationTime:   &metav1.Time{Time: now.Add(time.Hour)},
+				ExpirationAction: tenantv1beta1.WorkspaceStateArchived,
+			},
+			want: tenantv1beta1.WorkspaceStateActive,
+		},
+		{
+			name: "expired and archived",
+			lifecycle: &tenantv1beta1.WorkspaceLifecycle{
+				State:            tenantv1beta1.WorkspaceStateSuspended,
+				ExpirationTime:   &metav1.Time{Time: now.Add(-time.Hour)},
+				ExpirationAction: tenantv1beta1.WorkspaceStateArchived,
+			},
+			want: tenantv1beta1.WorkspaceStateArchived,
+		},
+		{
+			name: "the expiration action does not relax the desired state",
+			lifecycle: &tenantv1beta1.WorkspaceLifecycle{
+				State:          tenantv1beta1.WorkspaceStateArchived,
+				ExpirationTime: &metav1.Time{Time: now.Add(-time.Hour)},
+			},
+			want: tenantv1beta1.WorkspaceStateArchived,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			workspaceTemplate := &tenantv1beta1.WorkspaceTemplate{
+				ObjectMeta: metav1.ObjectMeta{Name: "ws1", CreationTimestamp: created},
+				Spec:       tenantv1beta1.WorkspaceTemplateSpec{Lifecycle: tt.lifecycle},
+			}
+			assert.Equal(t, tt.want, desiredState(workspaceTemplate, now))
+		})
+	}
+}
+
+func TestSyncLifecycle(t *testing.T) {
+	cluster := clusterv1alpha1.Cluster{
+		ObjectMeta: metav1.ObjectMeta{Name: "member"},
+		Status: clusterv1alpha1.ClusterStatus{Conditions: []clusterv1alpha1.ClusterCondition{
+			{Type: clusterv1alpha1.ClusterReady, Status: corev1.ConditionTrue},
+		}},
+	}
+	workspaceTemplate := &tenantv1beta1.WorkspaceTemplate{
+		ObjectMeta: metav1.ObjectMeta{Name: "ws1"},
+		Spec: tenantv1beta1.WorkspaceTemplateSpec{
+			Template:  tenantv1beta1.Template{Spec: tenantv1beta1.WorkspaceSpec{Manager: "admin"}},
+			Placement: tenantv1beta1.GenericPlacement{Clusters: []tenantv1beta1.GenericClusterReference{{Name: "member"}}},
+			Lifecycle: &tenantv1beta1.WorkspaceLifecycle{
+				ExpirationTime: &metav1.Time{Time: time.Now().Add(time.Hour)},
+			},
+		},
+	}
+	hostClient := fake.NewClientBuilder().WithScheme(scheme.Scheme).
+		WithObjects(workspaceTemplate).
+		WithStatusSubresource(workspaceTemplate).
+		Build()
+	memberClient := fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "ns1", Labels: map[string]string{tenantv1beta1.WorkspaceLabel: "ws1"}}},
+		&appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "ns1"}},
+		&corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "credentials", Namespace: "ns1"}, Data: map[string][]byte{"password": []byte("P@88w0rd")}},
+	).Build()
+	recorder := record.NewFakeRecorder(10)
+	storage := &fakeS3{objects: make(map[string][]byte)}
+	r := &Reconciler{
+		Client:   hostClient,
+		logger:   logr.Discard(),
+		recorder: recorder,
+		clusterClientSet: &fakeClusterClientSet{
+			clusters: []clusterv1alpha1.Cluster{cluster},
+			clients:  map[string]client.Client{"member": memberClient},
+		},
+		s3Client: storage,
+	}
+	ctx := context.Background()
+	getTemplate := func() *tenantv1beta1.WorkspaceTemplate {
+		workspaceTemplate := &tenantv1beta1.WorkspaceTemplate{}
+		assert.NoError(t, hostClient.Get(ctx, types.NamespacedName{Name: "ws1"}, workspaceTemplate))
+		return workspaceTemplate
+	}
+
+	// the manager is notified before the workspace expires
+	result, _, err := r.syncLifecycle(ctx, getTemplate())
+	assert.NoError(t, err)
+	assert.True(t, result.RequeueAfter > 0 && result.RequeueAfter <= time.Hour)
+	assert.Contains(t, <-recorder.Events, workspaceExpiring)
+	updated := getTemplate()
+	assert.Equal(t, tenantv1beta1.WorkspaceStateActive, updated.Status.State)
+	assert.True(t, meta.IsStatusConditionTrue(updated.Status.Conditions, tenantv1beta1.WorkspaceTemplateConditionExpiring))
+	workspace := &tenantv1beta1.Workspace{}
+	assert.NoError(t, memberClient.Get(ctx, types.NamespacedName{Name: "ws1"}, workspace))
+	assert.NotContains(t, workspace.Labels, tenantv1beta1.WorkspaceStateLabel)
+
+	// the state is propagated to the member clusters after the workspace expired
+	updated.Spec.Lifecycle.ExpirationTime = &metav1.Time{Time: time.Now().Add(-time.Minute)}
+	assert.NoError(t, hostClient.Update(ctx, updated))
+	_, _, err = r.syncLifecycle(ctx, getTemplate())
+	assert.NoError(t, err)
+	assert.Contains(t, <-recorder.Events, workspaceSuspended)
+	assert.Equal(t, tenantv1beta1.WorkspaceStateSuspended, getTemplate().Status.State)
+	assert.NoError(t, memberClient.Get(ctx, types.NamespacedName{Name: "ws1"}, workspace))
+	assert.Equal(t, string(tenantv1beta1.WorkspaceStateSuspended), workspace.Labels[tenantv1beta1.WorkspaceStateLabel])
+
+	// the manifests are exported before the workspace is deleted
+	updated = getTemplate()
+	updated.Spec.Lifecycle.State = tenantv1beta1.WorkspaceStateArchived
+	assert.NoError(t, hostClient.Update(ctx, updated))
+	_, _, err = r.syncLifecycle(ctx, getTemplate())
+	assert.NoError(t, err)
+	updated = getTemplate()
+	assert.Equal(t, tenantv1beta1.WorkspaceStateArchived, updated.Status.State)
+	assert.NotNil(t, updated.Status.Archive)
+	assert.True(t, meta.IsStatusConditionTrue(updated.Status.Conditions, tenantv1beta1.WorkspaceTemplateConditionArchived))
+	assert.True(t, apierrors.IsNotFound(memberClient.Get(ctx, types.NamespacedName{Name: "ws1"}, workspace)))
+
+	files := make(map[string]bool)
+	gzipReader, err := gzip.NewReader(bytes.NewReader(storage.objects[updated.Status.Archive.Location]))
+	assert.NoError(t, err)
+	tarReader := tar.NewReader(gzipReader)
+	for {
+		header, err := tarReader.Next()
+		if err != nil {
+			break
+		}
+		files[header.Name] = true
+	}
+	assert.True(t, files["workspacetemplate.yaml"])
+	assert.True(t, files["member/ns1/namespace.yaml"])
+	assert.True(t, files["member/ns1/deployment/app.yaml"])
+	assert.False(t, files["member/ns1/secret/credentials.yaml"])
+}
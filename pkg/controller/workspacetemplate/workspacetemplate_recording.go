@@ -0,0 +1,42 @@
+/*
+ * Copyright 2024 the KubeSphere Authors.
+ * Please refer to the LICENSE file in the root directory of the project.
+ * https://github.com/kubesphere/kubesphere/blob/master/LICENSE
+ */
+
+package workspacetemplate
+
+import (
+	"context"
+	"time"
+
+	"k8s.io/klog/v2"
+	tenantv1beta1 "kubesphere.io/api/tenant/v1beta1"
+
+	"kubesphere.io/kubesphere/pkg/models/terminal"
+)
+
+// recordingPruneInterval is the interval to delete the terminal session recordings beyond the retention period.
+const recordingPruneInterval = time.Hour
+
+// pruneTerminalRecordings deletes the terminal session recordings of the workspace beyond the retention period,
+// the recordings are stored in the same object storage as the archived manifests. The index of the recordings
+// is repaired even if the retention period is not set.
+func (r *Reconciler) pruneTerminalRecordings(ctx context.Context, workspaceTemplate *tenantv1beta1.WorkspaceTemplate) {
+	if r.s3Client == nil {
+		return
+	}
+	var deadline time.Time
+	if policy := workspaceTemplate.Spec.TerminalRecording; policy != nil && policy.RetentionPeriod != nil {
+		deadline = time.Now().Add(-policy.RetentionPeriod.Duration)
+	}
+	logger := klog.FromContext(ctx)
+	deleted, err := terminal.PruneRecordings(r.s3Client, workspaceTemplate.Name, deadline)
+	if err != nil {
+		logger.Error(err, "failed to prune terminal recordings")
+		return
+	}
+	if deleted > 0 {
+		logger.V(4).Info("terminal recordings pruned", "count", deleted)
+	}
+}
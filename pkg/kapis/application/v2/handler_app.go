@@ -287,7 +287,7 @@ func (h *appHandler) DoAppAction(req *restful.Request, resp *restful.Response) {
 	}
 	for _, version := range versions.Items {
 		if version.Status.State == appv2.StatusActive || version.Status.State == appv2.ReviewStatusSuspended {
-			err = DoAppVersionAction(ctx, version.Name, doActionRequest, h.client)
+			err = DoAppVersionAction(ctx, version.Name, doActionRequest, h.client, h.scanEnabled)
 			if err != nil {
 				klog.V(4).Infoln(err)
 				api.HandleInternalError(resp, nil, err)
@@ -9,6 +9,9 @@ package v2
 import (
 	"bytes"
 	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
 
 	k8suitl "kubesphere.io/kubesphere/pkg/utils/k8sutil"
 
@@ -21,15 +24,21 @@ import (
 	"github.com/emicklei/go-restful/v3"
 	"golang.org/x/net/context"
 	"helm.sh/helm/v3/pkg/action"
+	"helm.sh/helm/v3/pkg/storage/driver"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/klog/v2"
 	appv2 "kubesphere.io/api/application/v2"
+	clusterv1alpha1 "kubesphere.io/api/cluster/v1alpha1"
 	"kubesphere.io/api/constants"
+	tenantv1beta1 "kubesphere.io/api/tenant/v1beta1"
 	"kubesphere.io/utils/helm"
 	runtimeclient "sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 
 	"kubesphere.io/kubesphere/pkg/api"
+	workspacetemplateutils "kubesphere.io/kubesphere/pkg/controller/workspacetemplate/utils"
 	"kubesphere.io/kubesphere/pkg/server/errors"
 	"kubesphere.io/kubesphere/pkg/simple/client/application"
 )
@@ -41,6 +50,15 @@ func (h *appHandler) CreateOrUpdateAppRls(req *restful.Request, resp *restful.Re
 		return
 	}
 	list := []string{appv2.AppIDLabelKey, constants.ClusterNameLabelKey, constants.WorkspaceLabelKey, constants.NamespaceLabelKey}
+	clusters := []string{createRlsRequest.GetRlsCluster()}
+	if placement := createRlsRequest.Spec.Placement; placement != nil {
+		// the clusters are set by the placement instead of the label
+		list = []string{appv2.AppIDLabelKey, constants.WorkspaceLabelKey, constants.NamespaceLabelKey}
+		if len(placement.Clusters) == 0 && placement.ClusterSelector == nil {
+			api.HandleBadRequest(resp, nil, errors.New("placement must set clusters or clusterSelector"))
+			return
+		}
+	}
 	for _, i := range list {
 		value, ok := createRlsRequest.GetLabels()[i]
 		if !ok || value == "" {
@@ -56,21 +74,41 @@ func (h *appHandler) CreateOrUpdateAppRls(req *restful.Request, resp *restful.Re
 	if h.conflictedDone(req, resp, "application", &apprls) {
 		return
 	}
-
-	if createRlsRequest.Spec.AppType != appv2.AppTypeHelm {
-		runtimeClient, _, _, err := h.getCluster(req, createRlsRequest.GetRlsCluster())
-		if requestDone(err, resp) {
+	if parent := apprls.Labels[appv2.ParentAppReleaseLabelKey]; parent != "" {
+		api.HandleBadRequest(resp, nil, errors.New("application release %s is managed by %s", apprls.Name, parent))
+		return
+	}
+	if apprls.ResourceVersion != "" && (apprls.Spec.Placement == nil) != (createRlsRequest.Spec.Placement == nil) {
+		api.HandleBadRequest(resp, nil, errors.New("placement of application release %s can't be added or removed", apprls.Name))
+		return
+	}
+	if createRlsRequest.Spec.Placement != nil {
+		if clusters, err = h.authorizePlacement(req, &createRlsRequest); err != nil {
+			api.HandleError(resp, req, err)
 			return
 		}
+	}
+
+	if createRlsRequest.Spec.AppType != appv2.AppTypeHelm {
 		template, err := application.FailOverGet(h.cmStore, h.ossStore, createRlsRequest.Spec.AppVersionID, h.client, true)
 		if err != nil {
 			api.HandleInternalError(resp, nil, err)
 			return
 		}
-		_, err = application.ComplianceCheck(createRlsRequest.Spec.Values, template,
-			runtimeClient.RESTMapper(), createRlsRequest.GetRlsNamespace())
-		if requestDone(err, resp) {
-			return
+		for _, cluster := range clusters {
+			runtimeClient, _, _, err := h.getCluster(req, cluster)
+			if requestDone(err, resp) {
+				return
+			}
+			values := createRlsRequest.Spec.Values
+			if placement := createRlsRequest.Spec.Placement; placement != nil && placement.Overrides[cluster] != "" {
+				values = []byte(placement.Overrides[cluster])
+			}
+			_, err = application.ComplianceCheck(values, template,
+				runtimeClient.RESTMapper(), createRlsRequest.GetRlsNamespace())
+			if requestDone(err, resp) {
+				return
+			}
 		}
 	}
 
@@ -102,6 +140,63 @@ func (h *appHandler) CreateOrUpdateAppRls(req *restful.Request, resp *restful.Re
 	resp.WriteEntity(errors.None)
 }
 
+// authorizePlacement returns the clusters which the release is placed to. The clusters must be in the placement
+// of the workspace, the clusters selected by the label selector outside of it are skipped. The user must be allowed
+// to create the release in the namespace of each cluster, and the namespace must belong to the workspace.
+func (h *appHandler) authorizePlacement(req *restful.Request, rls *appv2.ApplicationRelease) ([]string, error) {
+	ctx := req.Request.Context()
+	workspaceName := rls.GetLabels()[constants.WorkspaceLabelKey]
+	workspace := &tenantv1beta1.WorkspaceTemplate{}
+	if err := h.client.Get(ctx, types.NamespacedName{Name: workspaceName}, workspace); err != nil {
+		return nil, err
+	}
+
+	var clusters []clusterv1alpha1.Cluster
+	if placement := rls.Spec.Placement; len(placement.Clusters) > 0 {
+		for _, name := range placement.Clusters {
+			cluster := clusterv1alpha1.Cluster{}
+			if err := h.client.Get(ctx, types.NamespacedName{Name: name}, &cluster); err != nil {
+				return nil, err
+			}
+			if !workspacetemplateutils.WorkspaceTemplateMatchTargetCluster(workspace, &cluster) {
+				return nil, apierrors.NewBadRequest(fmt.Sprintf("cluster %s is not placed in workspace %s", name, workspaceName))
+			}
+			clusters = append(clusters, cluster)
+		}
+	} else {
+		selector, err := metav1.LabelSelectorAsSelector(placement.ClusterSelector)
+		if err != nil {
+			return nil, apierrors.NewBadRequest(err.Error())
+		}
+		clusterList := &clusterv1alpha1.ClusterList{}
+		if err = h.client.List(ctx, clusterList, runtimeclient.MatchingLabelsSelector{Selector: selector}); err != nil {
+			return nil, err
+		}
+		for _, cluster := range clusterList.Items {
+			if workspacetemplateutils.WorkspaceTemplateMatchTargetCluster(workspace, &cluster) {
+				clusters = append(clusters, cluster)
+			}
+		}
+	}
+
+	user, ok := request.UserFrom(ctx)
+	if !ok {
+		return nil, apierrors.NewUnauthorized("user not found in request")
+	}
+	names := make([]string, 0, len(clusters))
+	for _, cluster := range clusters {
+		runtimeClient, err := h.clusterClient.GetRuntimeClient(cluster.Name)
+		if err != nil {
+			return nil, err
+		}
+		if err = application.AuthorizeClusterRelease(ctx, h.authorizer, user, runtimeClient, rls, cluster.Name); err != nil {
+			return nil, err
+		}
+		names = append(names, cluster.Name)
+	}
+	return names, nil
+}
+
 func (h *appHandler) DescribeAppRls(req *restful.Request, resp *restful.Response) {
 	applicationId := req.PathParameter("application")
 	ctx := req.Request.Context()
@@ -113,6 +208,11 @@ func (h *appHandler) DescribeAppRls(req *restful.Request, resp *restful.Response
 		return
 	}
 	app.SetManagedFields(nil)
+	if app.Spec.Placement != nil {
+		// the resources are deployed by the release of each placed cluster
+		resp.WriteEntity(app)
+		return
+	}
 	if app.Spec.AppType == appv2.AppTypeYaml || app.Spec.AppType == appv2.AppTypeEdge {
 		data, err := h.getRealTimeYaml(ctx, req, app)
 		if err != nil {
@@ -297,3 +397,249 @@ func (h *appHandler) ListAppRls(req *restful.Request, resp *restful.Response) {
 
 	resp.WriteEntity(k8suitl.ConvertToListResult(&appList, req))
 }
+
+const (
+	appReleaseActionRollback = "rollback"
+	// helmFieldManager is the field manager of the resources deployed by helm.
+	helmFieldManager = "helm"
+)
+
+// appReleaseAction is the request of an action on the application release.
+type appReleaseAction struct {
+	Action string `json:"action"`
+	// Revision is the revision to roll back to, it defaults to the last revision different from the current spec.
+	Revision int `json:"revision,omitempty"`
+}
+
+// getAppRls returns the application release in the path, the release out of the namespace in the path is not found.
+func (h *appHandler) getAppRls(ctx context.Context, req *restful.Request) (*appv2.ApplicationRelease, error) {
+	apprls := &appv2.ApplicationRelease{}
+	if err := h.client.Get(ctx, runtimeclient.ObjectKey{Name: req.PathParameter("application")}, apprls); err != nil {
+		return nil, err
+	}
+	if namespace := req.PathParameter("namespace"); namespace != "" && apprls.GetRlsNamespace() != namespace {
+		return nil, apierrors.NewNotFound(appv2.Resource("applicationreleases"), apprls.Name)
+	}
+	return apprls, nil
+}
+
+func (h *appHandler) DoAppRlsAction(req *restful.Request, resp *restful.Response) {
+	action := appReleaseAction{}
+	if err := req.ReadEntity(&action); err != nil {
+		api.HandleBadRequest(resp, nil, err)
+		return
+	}
+	if action.Action != appReleaseActionRollback {
+		api.HandleBadRequest(resp, nil, fmt.Errorf("unsupported action %q", action.Action))
+		return
+	}
+	ctx := req.Request.Context()
+	apprls, err := h.getAppRls(ctx, req)
+	if requestDone(err, resp) {
+		return
+	}
+	if parent := apprls.Labels[appv2.ParentAppReleaseLabelKey]; parent != "" {
+		api.HandleBadRequest(resp, nil, fmt.Errorf("application release %s is managed by %s", apprls.Name, parent))
+		return
+	}
+	switch apprls.Status.State {
+	case appv2.StatusCreating, appv2.StatusCreated, appv2.StatusUpgrading, appv2.StatusUpgraded, appv2.StatusDeleting:
+		api.HandleConflict(resp, nil, fmt.Errorf("application release %s is %s", apprls.Name, apprls.Status.State))
+		return
+	}
+
+	target, err := rollbackRevision(apprls, action.Revision)
+	if err != nil {
+		api.HandleBadRequest(resp, nil, err)
+		return
+	}
+	appVersion := &appv2.ApplicationVersion{}
+	if err = h.client.Get(ctx, runtimeclient.ObjectKey{Name: target.AppVersionID}, appVersion); err != nil {
+		if apierrors.IsNotFound(err) {
+			api.HandleBadRequest(resp, nil, fmt.Errorf("application version %s of revision %d has been deleted", target.AppVersionID, target.Revision))
+			return
+		}
+		api.HandleInternalError(resp, nil, err)
+		return
+	}
+
+	values, err := application.GetRevisionValues(ctx, h.client, apprls, target)
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			api.HandleBadRequest(resp, nil, fmt.Errorf("values of revision %d have been deleted", target.Revision))
+			return
+		}
+		api.HandleInternalError(resp, nil, err)
+		return
+	}
+
+	user, _ := request.UserFrom(ctx)
+	creator := ""
+	if user != nil {
+		creator = user.GetName()
+	}
+	apprls.Spec.AppVersionID = target.AppVersionID
+	apprls.Spec.Values = values
+	if apprls.Labels == nil {
+		apprls.Labels = map[string]string{}
+	}
+	apprls.Labels[appv2.AppVersionIDLabelKey] = target.AppVersionID
+	if apprls.Annotations == nil {
+		apprls.Annotations = map[string]string{}
+	}
+	apprls.Annotations[constants.CreatorAnnotationKey] = creator
+	if err = h.client.Update(ctx, apprls); err != nil {
+		if apierrors.IsConflict(err) {
+			api.HandleConflict(resp, nil, err)
+			return
+		}
+		api.HandleInternalError(resp, nil, err)
+		return
+	}
+	klog.V(4).Infof("application release %s is rolled back to revision %d", apprls.Name, target.Revision)
+
+	resp.WriteEntity(errors.None)
+}
+
+// rollbackRevision returns the revision to roll back to, if the revision is not specified,
+// it's the last revision different from the current spec, e.g. the previous revision if the current spec is deployed.
+func rollbackRevision(apprls *appv2.ApplicationRelease, revision int) (*appv2.ApplicationReleaseRevision, error) {
+	if revision != 0 {
+		target := apprls.GetRevision(revision)
+		if target == nil {
+			return nil, fmt.Errorf("revision %d of application release %s not found", revision, apprls.Name)
+		}
+		return target, nil
+	}
+	specHash := apprls.HashSpec()
+	for i := len(apprls.Status.Revisions) - 1; i >= 0; i-- {
+		if apprls.Status.Revisions[i].SpecHash != specHash {
+			return &apprls.Status.Revisions[i], nil
+		}
+	}
+	return nil, fmt.Errorf("application release %s has no revision to roll back to", apprls.Name)
+}
+
+// DiffAppRls renders the application release with the version and the values in the request, or with the
+// revision in the query, and compares the rendered resources with the live resources without deploying them.
+func (h *appHandler) DiffAppRls(req *restful.Request, resp *restful.Response) {
+	diffRequest := appv2.ApplicationRelease{}
+	if err := req.ReadEntity(&diffRequest); err != nil {
+		api.HandleBadRequest(resp, nil, err)
+		return
+	}
+	ctx := req.Request.Context()
+
+	installed := true
+	apprls, err := h.getAppRls(ctx, req)
+	if apierrors.IsNotFound(err) {
+		installed = false
+		apprls = diffRequest.DeepCopy()
+		apprls.Name = req.PathParameter("application")
+		for _, key := range []string{constants.ClusterNameLabelKey, constants.NamespaceLabelKey} {
+			if apprls.GetLabels()[key] == "" {
+				api.HandleBadRequest(resp, nil, errors.New("must set %s", key))
+				return
+			}
+		}
+	} else if requestDone(err, resp) {
+		return
+	}
+
+	if apprls.Spec.Placement != nil {
+		api.HandleBadRequest(resp, nil, fmt.Errorf("application release %s is placed to multiple clusters, "+
+			"compare the release of each cluster instead", apprls.Name))
+		return
+	}
+
+	target := apprls.DeepCopy()
+	if revision := req.QueryParameter("revision"); revision != "" {
+		number, err := strconv.Atoi(revision)
+		if err != nil {
+			api.HandleBadRequest(resp, nil, fmt.Errorf("invalid revision %q", revision))
+			return
+		}
+		deployed := apprls.GetRevision(number)
+		if deployed == nil {
+			api.HandleBadRequest(resp, nil, fmt.Errorf("revision %d of application release %s not found", number, apprls.Name))
+			return
+		}
+		values, err := application.GetRevisionValues(ctx, h.client, apprls, deployed)
+		if requestDone(err, resp) {
+			return
+		}
+		target.Spec.AppVersionID = deployed.AppVersionID
+		target.Spec.Values = values
+	} else {
+		if diffRequest.Spec.AppVersionID != "" {
+			target.Spec.AppVersionID = diffRequest.Spec.AppVersionID
+		}
+		if diffRequest.Spec.Values != nil {
+			target.Spec.Values = diffRequest.Spec.Values
+		}
+	}
+
+	runtimeClient, dynamicClient, cluster, err := h.getCluster(req, target.GetRlsCluster())
+	if requestDone(err, resp) {
+		return
+	}
+	data, err := application.FailOverGet(h.cmStore, h.ossStore, target.Spec.AppVersionID, h.client, true)
+	if requestDone(err, resp) {
+		return
+	}
+
+	result := application.ReleaseDiff{Installed: installed, AppVersionID: target.Spec.AppVersionID}
+	current := ""
+	fieldManager := helmFieldManager
+	if target.Spec.AppType == appv2.AppTypeHelm {
+		executor, err := helm.NewExecutor(helm.SetExecutorKubeConfig(cluster.Spec.Connection.KubeConfig),
+			helm.SetExecutorNamespace(target.GetRlsNamespace()))
+		if err != nil {
+			api.HandleInternalError(resp, nil, err)
+			return
+		}
+		options := []helm.HelmOption{
+			helm.SetKubeconfig(cluster.Spec.Connection.KubeConfig),
+			helm.SetNamespace(target.GetRlsNamespace()),
+		}
+		if installed {
+			deployed, err := executor.Get(ctx, target.Name, options...)
+			if err != nil && !strings.Contains(err.Error(), driver.ErrReleaseNotFound.Error()) {
+				api.HandleInternalError(resp, nil, err)
+				return
+			}
+			if deployed != nil {
+				current = deployed.Manifest
+			}
+		}
+		proposed, err := executor.DryRun(ctx, target.Name, target.Spec.Values, append(options, helm.SetChartData(data))...)
+		if err != nil {
+			api.HandleBadRequest(resp, nil, err)
+			return
+		}
+		result.Manifest = proposed.Manifest
+	} else {
+		if _, err = application.ComplianceCheck(target.Spec.Values, data, runtimeClient.RESTMapper(), target.GetRlsNamespace()); err != nil {
+			api.HandleBadRequest(resp, nil, err)
+			return
+		}
+		// the live resources are compared with the deployed revision, the spec may not be deployed yet
+		if latest := apprls.LatestRevision(); installed && latest != nil {
+			values, err := application.GetRevisionValues(ctx, h.client, apprls, latest)
+			if requestDone(err, resp) {
+				return
+			}
+			current = string(values)
+		}
+		result.Manifest = string(target.Spec.Values)
+		fieldManager = application.YamlFieldManager
+	}
+
+	result.Diffs, err = application.DiffLiveResources(ctx, dynamicClient, runtimeClient.RESTMapper(),
+		target.GetRlsNamespace(), fieldManager, current, result.Manifest)
+	if err != nil {
+		api.HandleBadRequest(resp, nil, err)
+		return
+	}
+	resp.WriteEntity(result)
+}
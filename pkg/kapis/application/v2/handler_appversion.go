@@ -13,6 +13,7 @@ import (
 	"strings"
 
 	"github.com/Masterminds/semver/v3"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/api/meta"
 	"k8s.io/apimachinery/pkg/runtime"
 
@@ -246,7 +247,11 @@ func (h *appHandler) AppVersionAction(req *restful.Request, resp *restful.Respon
 
 	// app version check state draft -> submitted -> (rejected -> submitted ) -> passed-> active -> (suspended -> active), draft -> submitted -> active
 
-	err = DoAppVersionAction(ctx, versionID, doActionRequest, h.client)
+	err = DoAppVersionAction(ctx, versionID, doActionRequest, h.client, h.scanEnabled)
+	if apierrors.IsForbidden(err) {
+		api.HandleError(resp, nil, err)
+		return
+	}
 	if requestDone(err, resp) {
 		return
 	}
@@ -254,7 +259,7 @@ func (h *appHandler) AppVersionAction(req *restful.Request, resp *restful.Respon
 	resp.WriteEntity(errors.None)
 }
 
-func DoAppVersionAction(ctx context.Context, versionId string, actionReq appv2.ApplicationVersionStatus, client runtimeclient.Client) error {
+func DoAppVersionAction(ctx context.Context, versionId string, actionReq appv2.ApplicationVersionStatus, client runtimeclient.Client, scanEnabled bool) error {
 
 	key := runtimeclient.ObjectKey{Name: versionId}
 	version := &appv2.ApplicationVersion{}
@@ -263,6 +268,13 @@ func DoAppVersionAction(ctx context.Context, versionId string, actionReq appv2.A
 		klog.Errorf("get app version %s failed, error: %s", versionId, err)
 		return err
 	}
+	if err = checkAppVersionScan(version, actionReq.State, scanEnabled); err != nil {
+		return err
+	}
+	// the version is scanned again when submitted
+	if actionReq.State == appv2.ReviewStatusSubmitted {
+		version.Status.Scan = nil
+	}
 	version.Status.State = actionReq.State
 	if actionReq.Message != "" {
 		version.Status.Message = actionReq.Message
@@ -287,6 +299,35 @@ func DoAppVersionAction(ctx context.Context, versionId string, actionReq appv2.A
 	return err
 }
 
+// checkAppVersionScan forbids passing the review of the submitted version unless the scan is passed, the versions
+// not scanned yet are not passed either if the scan is enabled. The other transitions are not checked, e.g. resuming
+// the suspended versions, and the versions imported from the repos are never scanned.
+func checkAppVersionScan(version *appv2.ApplicationVersion, state string, scanEnabled bool) error {
+	if version.Status.State != appv2.ReviewStatusSubmitted ||
+		(state != appv2.ReviewStatusPassed && state != appv2.ReviewStatusActive) {
+		return nil
+	}
+	if repo := version.GetLabels()[appv2.RepoIDLabelKey]; repo != "" && repo != appv2.UploadRepoKey {
+		return nil
+	}
+	scan := version.Status.Scan
+	if scan == nil {
+		if !scanEnabled {
+			return nil
+		}
+		return apierrors.NewForbidden(appv2.Resource("applicationversions"), version.Name,
+			fmt.Errorf("app version %s has not been scanned yet", version.Name))
+	}
+	if scan.State == appv2.ScanStatePassed {
+		return nil
+	}
+	reason := fmt.Sprintf("the scan of app version %s is %s", version.Name, scan.State)
+	if scan.Message != "" {
+		reason = fmt.Sprintf("%s: %s", reason, scan.Message)
+	}
+	return apierrors.NewForbidden(appv2.Resource("applicationversions"), version.Name, fmt.Errorf("%s", reason))
+}
+
 func checkAppStatus(ctx context.Context, appID, action string, client runtimeclient.Client) error {
 	//If all appVersions are Suspended, then the app's status is not active
 	if action != appv2.ReviewStatusSuspended {
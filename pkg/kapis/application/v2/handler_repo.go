@@ -7,6 +7,7 @@
 package v2
 
 import (
+	"context"
 	"fmt"
 	"net/url"
 
@@ -51,12 +52,26 @@ func (h *appHandler) CreateOrUpdateRepo(req *restful.Request, resp *restful.Resp
 		return
 	}
 
+	cred := application.RepoCredential{
+		Username: repoRequest.Spec.Credential.Username,
+		Password: repoRequest.Spec.Credential.Password,
+		// the repos created by the API always skip the tls verification as before
+		InsecureSkipTLSVerify: true,
+	}
 	if parsedUrl.User != nil {
-		repoRequest.Spec.Credential.Username = parsedUrl.User.Username()
-		repoRequest.Spec.Credential.Password, _ = parsedUrl.User.Password()
+		cred.Username = parsedUrl.User.Username()
+		cred.Password, _ = parsedUrl.User.Password()
+		// the credential is stored in the Secret, never in the URL of the repo
+		parsedUrl.User = nil
+	}
+	if cred.Username == "" && cred.Password == "" && req.PathParameter("repo") != "" {
+		cred, err = h.existingRepoCredential(req.Request.Context(), repoId, parsedUrl)
+		if requestDone(err, resp) {
+			return
+		}
 	}
 
-	_, err = application.LoadRepoIndex(repoRequest.Spec.Url, repoRequest.Spec.Credential)
+	_, err = application.LoadRepoIndex(parsedUrl.String(), cred)
 	if requestDone(err, resp) {
 		return
 	}
@@ -79,9 +94,8 @@ func (h *appHandler) CreateOrUpdateRepo(req *restful.Request, resp *restful.Resp
 			SyncPeriod:  repoRequest.Spec.SyncPeriod,
 			Description: stringutils.ShortenString(repoRequest.Spec.Description, 512),
 		}
-		if parsedUrl.User != nil {
-			repo.Spec.Credential.Username = parsedUrl.User.Username()
-			repo.Spec.Credential.Password, _ = parsedUrl.User.Password()
+		if !cred.IsEmpty() {
+			repo.Spec.SecretRef = &v1.LocalObjectReference{Name: application.RepoCredentialSecretName(repo.Name)}
 		}
 		if repo.GetLabels() == nil {
 			repo.SetLabels(map[string]string{})
@@ -101,11 +115,30 @@ func (h *appHandler) CreateOrUpdateRepo(req *restful.Request, resp *restful.Resp
 	if requestDone(err, resp) {
 		return
 	}
+	err = application.SaveRepoCredential(req.Request.Context(), h.client, repo, cred)
+	if requestDone(err, resp) {
+		return
+	}
 	data := map[string]interface{}{"repo_id": repoId}
 
 	resp.WriteAsJson(data)
 }
 
+// existingRepoCredential returns the credential of the existing repo, which is only reused
+// when the host of the repo is not changed, so that the credential is never sent to another host.
+func (h *appHandler) existingRepoCredential(ctx context.Context, repoId string, parsedUrl *url.URL) (application.RepoCredential, error) {
+	cred := application.RepoCredential{InsecureSkipTLSVerify: true}
+	repo := &appv2.Repo{}
+	if err := h.client.Get(ctx, runtimeclient.ObjectKey{Name: repoId}, repo); err != nil {
+		return cred, runtimeclient.IgnoreNotFound(err)
+	}
+	existingUrl, err := url.Parse(repo.Spec.Url)
+	if err != nil || existingUrl.Host != parsedUrl.Host {
+		return cred, nil
+	}
+	return application.ResolveRepoCredential(ctx, h.client, repo)
+}
+
 func (h *appHandler) DeleteRepo(req *restful.Request, resp *restful.Response) {
 	repoId := req.PathParameter("repo")
 
@@ -146,6 +179,7 @@ func (h *appHandler) DescribeRepo(req *restful.Request, resp *restful.Response)
 		return
 	}
 	repo.SetManagedFields(nil)
+	application.RedactRepo(repo)
 
 	resp.WriteEntity(repo)
 }
@@ -164,6 +198,7 @@ func (h *appHandler) ListRepos(req *restful.Request, resp *restful.Response) {
 		if !stringutils.StringIn(repo.Labels[constants.WorkspaceLabelKey], allowList) {
 			continue
 		}
+		application.RedactRepo(&repo)
 		filteredList.Items = append(filteredList.Items, repo)
 	}
 
@@ -19,8 +19,10 @@ import (
 	appv2 "kubesphere.io/api/application/v2"
 	runtimeclient "sigs.k8s.io/controller-runtime/pkg/client"
 
+	"kubesphere.io/kubesphere/pkg/apiserver/authorization/authorizer"
 	"kubesphere.io/kubesphere/pkg/apiserver/rest"
 	"kubesphere.io/kubesphere/pkg/apiserver/runtime"
+	"kubesphere.io/kubesphere/pkg/simple/client/scanner"
 	"kubesphere.io/kubesphere/pkg/utils/clusterclient"
 )
 
@@ -30,13 +32,20 @@ type appHandler struct {
 	s3opts        *s3.Options
 	ossStore      s3.Interface
 	cmStore       s3.Interface
+	// scanEnabled forbids publishing the versions unless the scan is passed
+	scanEnabled bool
+	// authorizer authorizes the user to deploy the release to each placed cluster
+	authorizer authorizer.Authorizer
 }
 
-func NewHandler(cacheClient runtimeclient.Client, clusterClient clusterclient.Interface, s3opts *s3.Options) rest.Handler {
+func NewHandler(cacheClient runtimeclient.Client, clusterClient clusterclient.Interface, s3opts *s3.Options,
+	scanOptions *scanner.Options, authorizer authorizer.Authorizer) rest.Handler {
 	handler := &appHandler{
 		client:        cacheClient,
 		clusterClient: clusterClient,
 		s3opts:        s3opts,
+		scanEnabled:   scanOptions != nil && scanOptions.Enabled,
+		authorizer:    authorizer,
 	}
 	return handler
 }
@@ -90,6 +99,9 @@ func (h *appHandler) AddToContainer(c *restful.Container) (err error) {
 		{Route: "/applications/{application}", Func: h.CreateOrUpdateAppRls, Method: ws.POST, Namespace: true},
 		{Route: "/applications/{application}", Func: h.DescribeAppRls, Method: ws.GET, Namespace: true},
 		{Route: "/applications/{application}", Func: h.DeleteAppRls, Method: ws.DELETE, Namespace: true},
+		{Route: "/applications/{application}/action", Func: h.DoAppRlsAction, Method: ws.POST, Namespace: true},
+		{Route: "/applications/{application}/diff", Func: h.DiffAppRls, Method: ws.POST, Namespace: true,
+			Params: []*restful.Parameter{ws.QueryParameter("revision", "the revision to compare with instead of the version and the values in the request")}},
 		{Route: "/categories", Func: h.CreateOrUpdateCategory, Method: ws.POST},
 		{Route: "/categories", Func: h.ListCategories, Method: ws.GET},
 		{Route: "/categories/{category}", Func: h.DeleteCategory, Method: ws.DELETE},
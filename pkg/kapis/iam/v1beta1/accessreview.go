@@ -0,0 +1,165 @@
+/*
+ * Copyright 2024 the KubeSphere Authors.
+ * Please refer to the LICENSE file in the root directory of the project.
+ * https://github.com/kubesphere/kubesphere/blob/master/LICENSE
+ */
+
+package v1beta1
+
+import (
+	"encoding/csv"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/emicklei/go-restful/v3"
+	rbacv1 "k8s.io/api/rbac/v1"
+
+	"kubesphere.io/kubesphere/pkg/api"
+	"kubesphere.io/kubesphere/pkg/apiserver/authorization/authorizer"
+	"kubesphere.io/kubesphere/pkg/apiserver/authorization/rbac"
+	apirequest "kubesphere.io/kubesphere/pkg/apiserver/request"
+)
+
+const (
+	accessReviewFormatJSON = "json"
+	accessReviewFormatCSV  = "csv"
+	mimeCSV                = "text/csv"
+)
+
+// AccessReviewReport is the report of the access review, the records can be exported as CSV for the access recertification.
+type AccessReviewReport struct {
+	Items      []rbac.AccessReviewRecord `json:"items"`
+	TotalItems int                       `json:"totalItems"`
+}
+
+var accessReviewCSVHeader = []string{
+	"subjectKind", "subjectName", "subjectNamespace", "group",
+	"bindingKind", "bindingName", "bindingNamespace",
+	"roleKind", "roleName",
+	"verbs", "apiGroups", "resources", "resourceNames", "nonResourceURLs", "regoPolicy",
+}
+
+// ListAllowedSubjects answers who can perform the verb on the resource in the scope, e.g. who can delete secrets in a workspace.
+func (h *handler) ListAllowedSubjects(request *restful.Request, response *restful.Response) {
+	attrs, err := accessReviewAttributes(request)
+	if err != nil {
+		api.HandleBadRequest(response, request, err)
+		return
+	}
+	if attrs.Verb == "" || (attrs.Resource == "" && attrs.Path == "") {
+		api.HandleBadRequest(response, request, fmt.Errorf("verb and either resource or path are required"))
+		return
+	}
+
+	records, err := h.authorizer.SubjectsAllowed(attrs)
+	if err != nil {
+		api.HandleInternalError(response, request, err)
+		return
+	}
+
+	writeAccessReviewReport(request, response, records)
+}
+
+// ListSubjectRules lists everything the user, group or service account can do in the scope.
+func (h *handler) ListSubjectRules(request *restful.Request, response *restful.Response) {
+	attrs, err := accessReviewAttributes(request)
+	if err != nil {
+		api.HandleBadRequest(response, request, err)
+		return
+	}
+	subject := rbacv1.Subject{
+		Kind:      request.QueryParameter("subjectkind"),
+		APIGroup:  request.QueryParameter("subjectapigroup"),
+		Name:      request.QueryParameter("subject"),
+		Namespace: request.QueryParameter("subjectnamespace"),
+	}
+	if subject.Kind == "" {
+		subject.Kind = rbacv1.UserKind
+	}
+	if subject.Kind == rbacv1.ServiceAccountKind && subject.APIGroup == "" {
+		subject.APIGroup = rbacv1.GroupName
+	}
+	if subject.Name == "" {
+		api.HandleBadRequest(response, request, fmt.Errorf("subject is required"))
+		return
+	}
+
+	records, err := h.authorizer.SubjectRules(subject, attrs)
+	if err != nil {
+		api.HandleBadRequest(response, request, err)
+		return
+	}
+
+	writeAccessReviewReport(request, response, records)
+}
+
+func accessReviewAttributes(request *restful.Request) (authorizer.AttributesRecord, error) {
+	attrs := authorizer.AttributesRecord{
+		Verb:          request.QueryParameter("verb"),
+		APIGroup:      request.QueryParameter("apigroup"),
+		Resource:      request.QueryParameter("resource"),
+		Subresource:   request.QueryParameter("subresource"),
+		Name:          request.QueryParameter("name"),
+		Path:          request.QueryParameter("path"),
+		Workspace:     request.QueryParameter("workspace"),
+		Namespace:     request.QueryParameter("namespace"),
+		ResourceScope: request.QueryParameter("scope"),
+	}
+	attrs.ResourceRequest = attrs.Path == ""
+	if attrs.Namespace != "" {
+		attrs.ResourceScope = apirequest.NamespaceScope
+	} else if attrs.Workspace != "" {
+		attrs.ResourceScope = apirequest.WorkspaceScope
+	}
+	switch attrs.ResourceScope {
+	case "":
+		attrs.ResourceScope = apirequest.ClusterScope
+	case apirequest.GlobalScope, apirequest.ClusterScope, apirequest.WorkspaceScope, apirequest.NamespaceScope:
+	default:
+		return attrs, fmt.Errorf("invalid scope %q", attrs.ResourceScope)
+	}
+	return attrs, nil
+}
+
+func writeAccessReviewReport(request *restful.Request, response *restful.Response, records []rbac.AccessReviewRecord) {
+	switch format := request.QueryParameter("format"); format {
+	case "", accessReviewFormatJSON:
+		if records == nil {
+			records = []rbac.AccessReviewRecord{}
+		}
+		_ = response.WriteEntity(AccessReviewReport{Items: records, TotalItems: len(records)})
+	case accessReviewFormatCSV:
+		response.AddHeader("Content-Disposition", "attachment; filename=accessreview.csv")
+		response.AddHeader("Content-Type", mimeCSV)
+		response.WriteHeader(http.StatusOK)
+		writer := csv.NewWriter(response)
+		_ = writer.Write(accessReviewCSVHeader)
+		for _, record := range records {
+			_ = writer.Write(accessReviewCSVRow(record))
+		}
+		writer.Flush()
+	default:
+		api.HandleBadRequest(response, request, fmt.Errorf("unsupported format %q", format))
+	}
+}
+
+func accessReviewCSVRow(record rbac.AccessReviewRecord) []string {
+	row := []string{
+		record.Subject.Kind, record.Subject.Name, record.Subject.Namespace, record.Group,
+		record.BindingKind, record.BindingName, record.BindingNamespace,
+		record.RoleRef.Kind, record.RoleRef.Name,
+	}
+	if record.Rule != nil {
+		row = append(row,
+			strings.Join(record.Rule.Verbs, ","),
+			strings.Join(record.Rule.APIGroups, ","),
+			strings.Join(record.Rule.Resources, ","),
+			strings.Join(record.Rule.ResourceNames, ","),
+			strings.Join(record.Rule.NonResourceURLs, ","),
+		)
+	} else {
+		row = append(row, "", "", "", "", "")
+	}
+	return append(row, record.RegoPolicy)
+}
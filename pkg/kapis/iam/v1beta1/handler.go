@@ -9,6 +9,7 @@ package v1beta1
 import (
 	"fmt"
 	"sync"
+	"time"
 
 	"kubesphere.io/kubesphere/pkg/apiserver/authorization/rbac"
 	"kubesphere.io/kubesphere/pkg/apiserver/rest"
@@ -37,6 +38,51 @@ import (
 type Member struct {
 	Username string `json:"username"`
 	RoleRef  string `json:"roleRef"`
+	// ExpirationTime is the optional time when the role binding expires and is deleted, the current
+	// expiration time of the role binding is kept if it's not set.
+	ExpirationTime *metav1.Time `json:"expirationTime,omitempty"`
+	// NeverExpire removes the current expiration time of the role binding.
+	NeverExpire bool `json:"neverExpire,omitempty"`
+}
+
+func (m *Member) validate() error {
+	if m.ExpirationTime != nil && m.NeverExpire {
+		return fmt.Errorf("the expiration time of the member %s can't be set with neverExpire", m.Username)
+	}
+	if m.ExpirationTime != nil && !m.ExpirationTime.After(time.Now()) {
+		return fmt.Errorf("the expiration time of the member %s must be in the future", m.Username)
+	}
+	return nil
+}
+
+// expiration returns the expiration time of the role binding, it's zero if the expiration time should be removed.
+func (m *Member) expiration() *metav1.Time {
+	if m.NeverExpire {
+		return &metav1.Time{}
+	}
+	return m.ExpirationTime
+}
+
+// globalRoleExpirationTime returns the expiration time of the global role binding, which is set by the annotation
+// of the user, the annotation is removed since it's not a property of the user. The empty annotation removes the
+// expiration time, a zero time is returned for it.
+func globalRoleExpirationTime(user *iamv1beta1.User) (*metav1.Time, error) {
+	value, ok := user.Annotations[iamv1beta1.GlobalRoleExpirationTimeAnnotation]
+	if !ok {
+		return nil, nil
+	}
+	delete(user.Annotations, iamv1beta1.GlobalRoleExpirationTimeAnnotation)
+	if value == "" {
+		return &metav1.Time{}, nil
+	}
+	expiration, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid expiration time of the global role: %s", err)
+	}
+	if !expiration.After(time.Now()) {
+		return nil, fmt.Errorf("the expiration time of the global role must be in the future")
+	}
+	return &metav1.Time{Time: expiration}, nil
 }
 
 type GroupMember struct {
@@ -61,7 +107,7 @@ type TOTPAuthKey struct {
 type handler struct {
 	im         im.IdentityManagementInterface
 	am         am.AccessManagementInterface
-	authorizer authorizer.Authorizer
+	authorizer *rbac.Authorizer
 }
 
 func NewHandler(im im.IdentityManagementInterface, am am.AccessManagementInterface) rest.Handler {
@@ -159,6 +205,11 @@ func (h *handler) CreateUser(req *restful.Request, resp *restful.Response) {
 			return
 		}
 	}
+	expiration, err := globalRoleExpirationTime(&user)
+	if err != nil {
+		api.HandleBadRequest(resp, req, err)
+		return
+	}
 
 	created, err := h.im.CreateUser(&user)
 	if err != nil {
@@ -167,7 +218,7 @@ func (h *handler) CreateUser(req *restful.Request, resp *restful.Response) {
 	}
 
 	if globalRole != "" {
-		if err := h.am.CreateOrUpdateGlobalRoleBinding(user.Name, globalRole); err != nil {
+		if err := h.am.CreateOrUpdateGlobalRoleBinding(user.Name, globalRole, expiration); err != nil {
 			api.HandleError(resp, req, err)
 			return
 		}
@@ -175,6 +226,7 @@ func (h *handler) CreateUser(req *restful.Request, resp *restful.Response) {
 
 	// ensure encrypted password will not be output
 	created.Spec.EncryptedPassword = ""
+	created.Status.PasswordHistory = nil
 
 	resp.WriteEntity(created)
 }
@@ -195,6 +247,11 @@ func (h *handler) UpdateUser(request *restful.Request, response *restful.Respons
 	}
 
 	globalRole := user.Annotations[iamv1beta1.GlobalRoleAnnotation]
+	expiration, err := globalRoleExpirationTime(&user)
+	if err != nil {
+		api.HandleBadRequest(response, request, err)
+		return
+	}
 
 	updated, err := h.im.UpdateUser(&user)
 	if err != nil {
@@ -204,7 +261,7 @@ func (h *handler) UpdateUser(request *restful.Request, response *restful.Respons
 
 	operator, ok := apirequest.UserFrom(request.Request.Context())
 	if globalRole != "" && ok {
-		if err = h.updateGlobalRoleBinding(operator, updated, globalRole); err != nil {
+		if err = h.updateGlobalRoleBinding(operator, updated, globalRole, expiration); err != nil {
 			api.HandleError(response, request, err)
 			return
 		}
@@ -213,12 +270,12 @@ func (h *handler) UpdateUser(request *restful.Request, response *restful.Respons
 	response.WriteEntity(updated)
 }
 
-func (h *handler) updateGlobalRoleBinding(operator authuser.Info, user *iamv1beta1.User, globalRole string) error {
+func (h *handler) updateGlobalRoleBinding(operator authuser.Info, user *iamv1beta1.User, globalRole string, expiration *metav1.Time) error {
 	oldGlobalRole, err := h.am.GetGlobalRoleOfUser(user.Name)
 	if err != nil && !errors.IsNotFound(err) {
 		return err
 	}
-	if oldGlobalRole != nil && oldGlobalRole.Name == globalRole {
+	if oldGlobalRole != nil && oldGlobalRole.Name == globalRole && expiration == nil {
 		return nil
 	}
 	userManagement := authorizer.AttributesRecord{
@@ -236,7 +293,7 @@ func (h *handler) updateGlobalRoleBinding(operator authuser.Info, user *iamv1bet
 		return errors.NewForbidden(iamv1beta1.Resource(iamv1beta1.ResourcesSingularUser),
 			user.Name, fmt.Errorf("update global role binding is not allowed"))
 	}
-	if err := h.am.CreateOrUpdateGlobalRoleBinding(user.Name, globalRole); err != nil {
+	if err := h.am.CreateOrUpdateGlobalRoleBinding(user.Name, globalRole, expiration); err != nil {
 		return err
 	}
 	return nil
@@ -424,9 +481,15 @@ func (h *handler) CreateClusterMembers(request *restful.Request, response *restf
 		api.HandleBadRequest(response, request, err)
 		return
 	}
+	for _, member := range members {
+		if err = member.validate(); err != nil {
+			api.HandleBadRequest(response, request, err)
+			return
+		}
+	}
 
 	for _, member := range members {
-		err := h.am.CreateOrUpdateClusterRoleBinding(member.Username, member.RoleRef)
+		err := h.am.CreateOrUpdateClusterRoleBinding(member.Username, member.RoleRef, member.expiration())
 		if err != nil {
 			api.HandleError(response, request, err)
 			return
@@ -458,9 +521,15 @@ func (h *handler) CreateNamespaceMembers(request *restful.Request, response *res
 		api.HandleBadRequest(response, request, err)
 		return
 	}
+	for _, member := range members {
+		if err = member.validate(); err != nil {
+			api.HandleBadRequest(response, request, err)
+			return
+		}
+	}
 
 	for _, member := range members {
-		err := h.am.CreateOrUpdateNamespaceRoleBinding(member.Username, namespace, member.RoleRef)
+		err := h.am.CreateOrUpdateNamespaceRoleBinding(member.Username, namespace, member.RoleRef, member.expiration())
 		if err != nil {
 			api.HandleError(response, request, err)
 			return
@@ -711,9 +780,15 @@ func (h *handler) CreateWorkspaceMembers(request *restful.Request, response *res
 		api.HandleBadRequest(response, request, err)
 		return
 	}
+	for _, member := range members {
+		if err = member.validate(); err != nil {
+			api.HandleBadRequest(response, request, err)
+			return
+		}
+	}
 
 	for _, member := range members {
-		err := h.am.CreateOrUpdateUserWorkspaceRoleBinding(member.Username, workspace, member.RoleRef)
+		err := h.am.CreateOrUpdateUserWorkspaceRoleBinding(member.Username, workspace, member.RoleRef, member.expiration())
 		if err != nil {
 			api.HandleError(response, request, err)
 			return
@@ -755,6 +830,9 @@ func (h *handler) DescribeWorkspaceMember(request *restful.Request, response *re
 		return
 	}
 	user.Annotations[iamv1beta1.WorkspaceRoleAnnotation] = bindings[0].RoleRef.Name
+	if expiration := bindings[0].Annotations[iamv1beta1.ExpirationTimeAnnotation]; expiration != "" {
+		user.Annotations[iamv1beta1.ExpirationTimeAnnotation] = expiration
+	}
 	_ = response.WriteEntity(user)
 }
 
@@ -773,6 +851,10 @@ func (h *handler) UpdateWorkspaceMember(request *restful.Request, response *rest
 		api.HandleBadRequest(response, request, NewErrIncorrectUsername(memberName))
 		return
 	}
+	if err = member.validate(); err != nil {
+		api.HandleBadRequest(response, request, err)
+		return
+	}
 
 	bindings, err := h.am.ListWorkspaceRoleBindings(memberName, "", nil, workspace)
 	if err != nil {
@@ -784,7 +866,7 @@ func (h *handler) UpdateWorkspaceMember(request *restful.Request, response *rest
 		return
 	}
 
-	err = h.am.CreateOrUpdateUserWorkspaceRoleBinding(member.Username, workspace, member.RoleRef)
+	err = h.am.CreateOrUpdateUserWorkspaceRoleBinding(member.Username, workspace, member.RoleRef, member.expiration())
 	if err != nil {
 		api.HandleError(response, request, err)
 		return
@@ -806,6 +888,10 @@ func (h *handler) UpdateClusterMember(request *restful.Request, response *restfu
 		api.HandleBadRequest(response, request, NewErrIncorrectUsername(memberName))
 		return
 	}
+	if err = member.validate(); err != nil {
+		api.HandleBadRequest(response, request, err)
+		return
+	}
 
 	bindings, err := h.am.ListClusterRoleBindings(memberName, "")
 	if err != nil {
@@ -817,7 +903,7 @@ func (h *handler) UpdateClusterMember(request *restful.Request, response *restfu
 		return
 	}
 
-	err = h.am.CreateOrUpdateClusterRoleBinding(member.Username, member.RoleRef)
+	err = h.am.CreateOrUpdateClusterRoleBinding(member.Username, member.RoleRef, member.expiration())
 	if err != nil {
 		api.HandleError(response, request, err)
 		return
@@ -841,6 +927,10 @@ func (h *handler) UpdateNamespaceMember(request *restful.Request, response *rest
 		api.HandleBadRequest(response, request, NewErrIncorrectUsername(memberName))
 		return
 	}
+	if err = member.validate(); err != nil {
+		api.HandleBadRequest(response, request, err)
+		return
+	}
 
 	bindings, err := h.am.ListRoleBindings(member.Username, "", nil, namespace)
 	if err != nil {
@@ -852,7 +942,7 @@ func (h *handler) UpdateNamespaceMember(request *restful.Request, response *rest
 		return
 	}
 
-	err = h.am.CreateOrUpdateNamespaceRoleBinding(member.Username, namespace, member.RoleRef)
+	err = h.am.CreateOrUpdateNamespaceRoleBinding(member.Username, namespace, member.RoleRef, member.expiration())
 	if err != nil {
 		api.HandleError(response, request, err)
 		return
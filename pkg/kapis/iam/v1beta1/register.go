@@ -11,11 +11,13 @@ import (
 
 	restfulspec "github.com/emicklei/go-restful-openapi/v2"
 	"github.com/emicklei/go-restful/v3"
+	rbacv1 "k8s.io/api/rbac/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	iamv1beta1 "kubesphere.io/api/iam/v1beta1"
 
 	"kubesphere.io/kubesphere/pkg/api"
+	apirequest "kubesphere.io/kubesphere/pkg/apiserver/request"
 	apiserverruntime "kubesphere.io/kubesphere/pkg/apiserver/runtime"
 	"kubesphere.io/kubesphere/pkg/server/errors"
 )
@@ -180,6 +182,38 @@ func (h *handler) AddToContainer(container *restful.Container) error {
 		Metadata(restfulspec.KeyOpenAPITags, []string{api.TagAccessManagement}).
 		Reads(iamv1beta1.SubjectAccessReview{}).
 		Returns(http.StatusOK, api.StatusOK, iamv1beta1.SubjectAccessReview{}))
+	ws.Route(ws.GET("/accessreviews/subjects").
+		To(h.ListAllowedSubjects).
+		Doc("List allowed subjects").
+		Notes("List the users, groups and service accounts allowed to perform the request, with the bindings which grant the permission.").
+		Metadata(restfulspec.KeyOpenAPITags, []string{api.TagAccessManagement}).
+		Produces(restful.MIME_JSON, mimeCSV).
+		Param(ws.QueryParameter("verb", "the verb of the request, e.g. delete").Required(true)).
+		Param(ws.QueryParameter("apigroup", "the API group of the resource")).
+		Param(ws.QueryParameter("resource", "the resource, e.g. secrets")).
+		Param(ws.QueryParameter("subresource", "the subresource")).
+		Param(ws.QueryParameter("name", "the name of the resource")).
+		Param(ws.QueryParameter("path", "the non-resource URL, it's required if the resource is not specified")).
+		Param(ws.QueryParameter("workspace", "the workspace of the resource")).
+		Param(ws.QueryParameter("namespace", "the namespace of the resource")).
+		Param(ws.QueryParameter("scope", "the scope of the resource, one of Global, Cluster, Workspace and Namespace").DefaultValue(apirequest.ClusterScope)).
+		Param(ws.QueryParameter("format", "the format of the report, json or csv").DefaultValue(accessReviewFormatJSON)).
+		Returns(http.StatusOK, api.StatusOK, AccessReviewReport{}))
+	ws.Route(ws.GET("/accessreviews/rules").
+		To(h.ListSubjectRules).
+		Doc("List subject rules").
+		Notes("List the rules granted to the user, group or service account, with the bindings which grant the rules. The rules granted to the groups of the user are included and labeled with the group.").
+		Metadata(restfulspec.KeyOpenAPITags, []string{api.TagAccessManagement}).
+		Produces(restful.MIME_JSON, mimeCSV).
+		Param(ws.QueryParameter("subjectkind", "the kind of the subject, one of User, Group and ServiceAccount").DefaultValue(rbacv1.UserKind)).
+		Param(ws.QueryParameter("subject", "the name of the subject").Required(true)).
+		Param(ws.QueryParameter("subjectnamespace", "the namespace of the service account")).
+		Param(ws.QueryParameter("subjectapigroup", "the API group of the service account, rbac.authorization.k8s.io or kubesphere.io").DefaultValue(rbacv1.GroupName)).
+		Param(ws.QueryParameter("workspace", "the workspace scope")).
+		Param(ws.QueryParameter("namespace", "the namespace scope")).
+		Param(ws.QueryParameter("scope", "one of Global, Cluster, Workspace and Namespace").DefaultValue(apirequest.ClusterScope)).
+		Param(ws.QueryParameter("format", "the format of the report, json or csv").DefaultValue(accessReviewFormatJSON)).
+		Returns(http.StatusOK, api.StatusOK, AccessReviewReport{}))
 
 	container.Add(ws)
 	return nil
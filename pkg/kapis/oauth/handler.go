@@ -548,6 +548,21 @@ func (h *handler) refreshTokenGrant(req *restful.Request, response *restful.Resp
 		authenticated = &user.DefaultInfo{Name: users.Items[0].(*iamv1beta1.User).Name}
 	}
 
+	// the password may have been changed or expired since the refresh token was issued
+	if authenticated.GetName() != iamv1beta1.PreRegistrationUser {
+		userDetails, err := h.im.DescribeUser(authenticated.GetName())
+		if err != nil {
+			if apierrors.IsNotFound(err) {
+				_ = response.WriteHeaderAndEntity(http.StatusBadRequest, oauth.NewInvalidGrant("Authenticated user does not exist."))
+				return
+			}
+			klog.Errorf("failed to get user details: %s", err)
+			_ = response.WriteHeaderAndEntity(http.StatusInternalServerError, oauth.NewServerError(internalServerErrorMessage))
+			return
+		}
+		authenticated = withPasswordChangeRequired(authenticated, auth.PasswordChangeRequired(userDetails))
+	}
+
 	result, err := h.issueTokenTo(authenticated, client)
 	if err != nil {
 		klog.Errorf("failed to issue token: %s", err)
@@ -558,6 +573,23 @@ func (h *handler) refreshTokenGrant(req *restful.Request, response *restful.Resp
 	_ = response.WriteEntity(result)
 }
 
+// withPasswordChangeRequired returns a copy of the user info whose extra tells whether the password must be changed.
+func withPasswordChangeRequired(info user.Info, required bool) user.Info {
+	extra := make(map[string][]string)
+	for k, v := range info.GetExtra() {
+		if k != iamv1beta1.ExtraPasswordChangeRequired {
+			extra[k] = v
+		}
+	}
+	if required {
+		extra[iamv1beta1.ExtraPasswordChangeRequired] = []string{"true"}
+	}
+	if len(extra) == 0 {
+		extra = nil
+	}
+	return &user.DefaultInfo{Name: info.GetName(), UID: info.GetUID(), Groups: info.GetGroups(), Extra: extra}
+}
+
 func (h *handler) codeGrant(req *restful.Request, response *restful.Response, client *oauth.Client) {
 	code, _ := req.BodyParameter("code")
 	if code == "" {
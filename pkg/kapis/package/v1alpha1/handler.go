@@ -8,27 +8,48 @@ package v1alpha1
 
 import (
 	"bytes"
+	"context"
+	"errors"
 	"fmt"
+	"io"
+	"mime"
 	"net/http"
 	"net/url"
+	"time"
 
 	"github.com/emicklei/go-restful/v3"
 	"helm.sh/helm/v3/pkg/chart/loader"
 	"helm.sh/helm/v3/pkg/getter"
 	"helm.sh/helm/v3/pkg/registry"
+	"helm.sh/helm/v3/pkg/storage/driver"
 	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
+	clusterv1alpha1 "kubesphere.io/api/cluster/v1alpha1"
 	corev1alpha1 "kubesphere.io/api/core/v1alpha1"
 	"kubesphere.io/utils/helm"
 	runtimeclient "sigs.k8s.io/controller-runtime/pkg/client"
 
 	"kubesphere.io/kubesphere/pkg/api"
+	"kubesphere.io/kubesphere/pkg/apiserver/runtime"
+	clusterutils "kubesphere.io/kubesphere/pkg/controller/cluster/utils"
+	"kubesphere.io/kubesphere/pkg/models/extension"
 )
 
 var caTemplate = "{{ .TempDIR }}/repository/{{ .RepositoryName }}/ssl/ca.crt"
 
+const (
+	mimeOctetStream = "application/octet-stream"
+	// maxBundleMemory is the maximum memory used to parse the multipart form, the rest is stored on disk.
+	maxBundleMemory = 32 << 20
+)
+
 type handler struct {
-	cache runtimeclient.Reader
+	cache            runtimeclient.Reader
+	client           runtimeclient.Client
+	portalURL        string
+	extensionOptions *extension.Options
 }
 
 func (h *handler) ListFiles(request *restful.Request, response *restful.Response) {
@@ -38,37 +59,43 @@ func (h *handler) ListFiles(request *restful.Request, response *restful.Response
 		return
 	}
 
+	data, err := h.fetchChartData(request.Request.Context(), &extensionVersion)
+	if err != nil {
+		api.HandleInternalError(response, request, err)
+		return
+	}
+	if data == nil {
+		response.WriteEntity([]interface{}{})
+		return
+	}
+
+	files, err := loader.LoadArchiveFiles(bytes.NewReader(data))
+	if err != nil {
+		api.HandleInternalError(response, request, err)
+		return
+	}
+
+	_ = response.WriteEntity(files)
+}
+
+func (h *handler) fetchChartData(ctx context.Context, extensionVersion *corev1alpha1.ExtensionVersion) ([]byte, error) {
 	if extensionVersion.Spec.ChartDataRef != nil {
 		configMap := &corev1.ConfigMap{}
-		if err := h.cache.Get(request.Request.Context(), types.NamespacedName{Namespace: extensionVersion.Spec.ChartDataRef.Namespace, Name: extensionVersion.Spec.ChartDataRef.Name}, configMap); err != nil {
-			api.HandleInternalError(response, request, err)
-			return
-		}
-		data := configMap.BinaryData[extensionVersion.Spec.ChartDataRef.Key]
-		if data == nil {
-			response.WriteEntity([]interface{}{})
-			return
+		if err := h.cache.Get(ctx, types.NamespacedName{Namespace: extensionVersion.Spec.ChartDataRef.Namespace, Name: extensionVersion.Spec.ChartDataRef.Name}, configMap); err != nil {
+			return nil, err
 		}
-		files, err := loader.LoadArchiveFiles(bytes.NewReader(data))
-		if err != nil {
-			api.HandleInternalError(response, request, err)
-			return
-		}
-		response.WriteEntity(files)
-		return
+		return configMap.BinaryData[extensionVersion.Spec.ChartDataRef.Key], nil
 	}
 
 	chartURL, err := url.Parse(extensionVersion.Spec.ChartURL)
 	if err != nil {
-		api.HandleInternalError(response, request, err)
-		return
+		return nil, err
 	}
 
 	repo := &corev1alpha1.Repository{}
 	if extensionVersion.Spec.Repository != "" {
-		if err := h.cache.Get(request.Request.Context(), types.NamespacedName{Name: extensionVersion.Spec.Repository}, repo); err != nil {
-			api.HandleInternalError(response, request, err)
-			return
+		if err := h.cache.Get(ctx, types.NamespacedName{Name: extensionVersion.Spec.Repository}, repo); err != nil {
+			return nil, err
 		}
 	}
 
@@ -84,8 +111,7 @@ func (h *handler) ListFiles(request *restful.Request, response *restful.Response
 		}
 		chartGetter, err = getter.NewOCIGetter(opts...)
 		if err != nil {
-			api.HandleInternalError(response, request, fmt.Errorf("failed to create chart getter: %v", err))
-			return
+			return nil, fmt.Errorf("failed to create chart getter: %v", err)
 		}
 	case "http", "https":
 		opts := make([]getter.Option, 0)
@@ -95,8 +121,7 @@ func (h *handler) ListFiles(request *restful.Request, response *restful.Response
 		if repo.Spec.CABundle != "" {
 			tlsConfig, err := helm.NewTLSConfig(repo.Spec.CABundle, repo.Spec.Insecure)
 			if err != nil {
-				api.HandleInternalError(response, request, err)
-				return
+				return nil, err
 			}
 			opts = append(opts, getter.WithTransport(&http.Transport{TLSClientConfig: tlsConfig}))
 		}
@@ -105,25 +130,200 @@ func (h *handler) ListFiles(request *restful.Request, response *restful.Response
 		}
 		chartGetter, err = getter.NewHTTPGetter(opts...)
 		if err != nil {
-			api.HandleInternalError(response, request, fmt.Errorf("failed to create chart getter: %v", err))
-			return
+			return nil, fmt.Errorf("failed to create chart getter: %v", err)
 		}
 	default:
-		api.HandleInternalError(response, request, fmt.Errorf("cannot support chartURL %s, it's schame should be: oci,http,https", extensionVersion.Spec.ChartURL))
-		return
+		return nil, fmt.Errorf("cannot support chartURL %s, it's schame should be: oci,http,https", extensionVersion.Spec.ChartURL)
 	}
 
 	data, err := chartGetter.Get(extensionVersion.Spec.ChartURL)
+	if err != nil {
+		return nil, err
+	}
+	return data.Bytes(), nil
+}
+
+// UploadBundle imports the uploaded offline bundle into the repository,
+// the repository controller will sync the extensions from the bundle later.
+func (h *handler) UploadBundle(request *restful.Request, response *restful.Response) {
+	ctx := request.Request.Context()
+	repo := &corev1alpha1.Repository{}
+	if err := h.client.Get(ctx, types.NamespacedName{Name: request.PathParameter("repository")}, repo); err != nil {
+		api.HandleError(response, request, err)
+		return
+	}
+	if repo.Spec.Bundle == nil {
+		api.HandleBadRequest(response, request, fmt.Errorf("repository %s is not a bundle repository", repo.Name))
+		return
+	}
+	if repo.Spec.Bundle.Path != "" {
+		api.HandleBadRequest(response, request, fmt.Errorf("the bundle of repository %s is loaded from %s", repo.Name, repo.Spec.Bundle.Path))
+		return
+	}
+
+	var reader io.Reader = request.Request.Body
+	mediaType, _, _ := mime.ParseMediaType(request.HeaderParameter("Content-Type"))
+	if mediaType == runtime.MimeMultipartFormData {
+		if err := request.Request.ParseMultipartForm(maxBundleMemory); err != nil {
+			api.HandleBadRequest(response, request, fmt.Errorf("failed to parse multipart form: %s", err))
+			return
+		}
+		file, _, err := request.Request.FormFile("bundle")
+		if err != nil {
+			api.HandleBadRequest(response, request, fmt.Errorf("failed to read bundle: %s", err))
+			return
+		}
+		defer file.Close()
+		reader = file
+	}
+
+	bundle, err := extension.LoadBundle(reader, repo.Spec.Bundle.Format)
+	if err != nil {
+		api.HandleBadRequest(response, request, err)
+		return
+	}
+	if err := extension.ImportBundle(ctx, h.client, repo, bundle); err != nil {
+		api.HandleBadRequest(response, request, err)
+		return
+	}
+
+	// trigger the repository controller to sync extensions from the imported bundle,
+	// the Repository CRD has no status subresource, so the status is updated with the object.
+	repo.Status.LastSyncTime = nil
+	repo.Status.Bundle = &corev1alpha1.RepositoryBundleStatus{
+		Digest:     bundle.Digest,
+		ImportTime: &metav1.Time{Time: time.Now()},
+		Verified:   repo.Spec.Bundle.PublicKey != "",
+	}
+	if err := h.client.Update(ctx, repo); err != nil {
+		api.HandleError(response, request, err)
+		return
+	}
+	_ = response.WriteEntity(repo)
+}
+
+// DryRunInstallPlan renders the extension (or the agent if the cluster is specified) with the proposed spec,
+// and compares the rendered manifest with the deployed release.
+func (h *handler) DryRunInstallPlan(request *restful.Request, response *restful.Response) {
+	ctx := request.Request.Context()
+	clusterName := request.QueryParameter("cluster")
+	spec := corev1alpha1.InstallPlanSpec{}
+	if err := request.ReadEntity(&spec); err != nil {
+		api.HandleBadRequest(response, request, err)
+		return
+	}
+	if spec.Extension.Name == "" {
+		spec.Extension.Name = request.PathParameter("installplan")
+	}
+	if spec.Extension.Name != request.PathParameter("installplan") {
+		api.HandleBadRequest(response, request, fmt.Errorf("the extension name %s does not match the install plan", spec.Extension.Name))
+		return
+	}
+
+	extensionVersion := &corev1alpha1.ExtensionVersion{}
+	if err := h.cache.Get(ctx, types.NamespacedName{Name: fmt.Sprintf("%s-%s", spec.Extension.Name, spec.Extension.Version)}, extensionVersion); err != nil {
+		api.HandleError(response, request, err)
+		return
+	}
+	chartData, err := h.fetchChartData(ctx, extensionVersion)
 	if err != nil {
 		api.HandleInternalError(response, request, err)
 		return
 	}
+	mainChart, err := loader.LoadArchive(bytes.NewReader(chartData))
+	if err != nil {
+		api.HandleInternalError(response, request, fmt.Errorf("failed to load chart data: %v", err))
+		return
+	}
 
-	files, err := loader.LoadArchiveFiles(data)
+	targetNamespace := extensionVersion.Spec.Namespace
+	if targetNamespace == "" {
+		targetNamespace = fmt.Sprintf("extension-%s", spec.Extension.Name)
+	}
+	plan := &corev1alpha1.InstallPlan{}
+	if err := h.cache.Get(ctx, types.NamespacedName{Name: spec.Extension.Name}, plan); err != nil {
+		if !apierrors.IsNotFound(err) {
+			api.HandleError(response, request, err)
+			return
+		}
+	} else if plan.Status.TargetNamespace != "" {
+		targetNamespace = plan.Status.TargetNamespace
+	}
+
+	cluster, err := h.getCluster(ctx, clusterName)
+	if err != nil {
+		api.HandleError(response, request, err)
+		return
+	}
+
+	releaseName := spec.Extension.Name
+	values := []byte(spec.Config)
+	overrides := extension.Overrides(mainChart, extension.TagExtension, nil, h.portalURL, h.extensionOptions)
+	if clusterName != "" {
+		releaseName = fmt.Sprintf("%s-agent", spec.Extension.Name)
+		if spec.ClusterScheduling != nil && spec.ClusterScheduling.Overrides[clusterName] != "" {
+			values = extension.MergeConfig(spec.Config, spec.ClusterScheduling.Overrides[clusterName])
+		}
+		overrides = extension.Overrides(mainChart, extension.TagAgent, cluster, h.portalURL, h.extensionOptions)
+	}
+
+	executor, err := helm.NewExecutor(helm.SetExecutorKubeConfig(cluster.Spec.Connection.KubeConfig), helm.SetExecutorNamespace(targetNamespace))
 	if err != nil {
 		api.HandleInternalError(response, request, err)
 		return
 	}
+	helmOptions := []helm.HelmOption{
+		helm.SetKubeconfig(cluster.Spec.Connection.KubeConfig),
+		helm.SetNamespace(targetNamespace),
+	}
 
-	_ = response.WriteEntity(files)
+	result := &extension.DryRunResult{Cluster: clusterName, ReleaseName: releaseName, Namespace: targetNamespace}
+	var currentManifest string
+	current, err := executor.Get(ctx, releaseName, helmOptions...)
+	if err != nil {
+		if !errors.Is(err, driver.ErrReleaseNotFound) {
+			api.HandleInternalError(response, request, err)
+			return
+		}
+	} else {
+		result.Installed = true
+		currentManifest = current.Manifest
+	}
+
+	proposed, err := executor.DryRun(ctx, releaseName, values, append(helmOptions,
+		helm.SetDryRun(true),
+		helm.SetChartData(chartData),
+		helm.SetOverrides(overrides),
+		helm.SetLabels(map[string]string{corev1alpha1.ExtensionReferenceLabel: spec.Extension.Name}))...)
+	if err != nil {
+		api.HandleBadRequest(response, request, err)
+		return
+	}
+	result.Manifest = proposed.Manifest
+	if result.Diffs, err = extension.DiffManifests(currentManifest, proposed.Manifest); err != nil {
+		api.HandleInternalError(response, request, err)
+		return
+	}
+	_ = response.WriteEntity(result)
+}
+
+// getCluster returns the specified cluster, or the host cluster if the name is empty.
+func (h *handler) getCluster(ctx context.Context, name string) (*clusterv1alpha1.Cluster, error) {
+	if name != "" {
+		cluster := &clusterv1alpha1.Cluster{}
+		if err := h.cache.Get(ctx, types.NamespacedName{Name: name}, cluster); err != nil {
+			return nil, err
+		}
+		return cluster, nil
+	}
+	clusters := &clusterv1alpha1.ClusterList{}
+	if err := h.cache.List(ctx, clusters); err != nil {
+		return nil, err
+	}
+	for i := range clusters.Items {
+		if clusterutils.IsHostCluster(&clusters.Items[i]) {
+			return &clusters.Items[i], nil
+		}
+	}
+	return nil, apierrors.NewNotFound(clusterv1alpha1.Resource("clusters"), "host")
 }
@@ -12,11 +12,13 @@ import (
 	"github.com/emicklei/go-restful/v3"
 	"helm.sh/helm/v3/pkg/chart/loader"
 	"k8s.io/apimachinery/pkg/runtime/schema"
+	corev1alpha1 "kubesphere.io/api/core/v1alpha1"
 	runtimeclient "sigs.k8s.io/controller-runtime/pkg/client"
 
 	"kubesphere.io/kubesphere/pkg/api"
 	"kubesphere.io/kubesphere/pkg/apiserver/rest"
 	"kubesphere.io/kubesphere/pkg/apiserver/runtime"
+	"kubesphere.io/kubesphere/pkg/models/extension"
 )
 
 const (
@@ -26,8 +28,8 @@ const (
 
 var GroupVersion = schema.GroupVersion{Group: GroupName, Version: Version}
 
-func NewHandler(cache runtimeclient.Reader) rest.Handler {
-	return &handler{cache: cache}
+func NewHandler(cache runtimeclient.Reader, client runtimeclient.Client, portalURL string, extensionOptions *extension.Options) rest.Handler {
+	return &handler{cache: cache, client: client, portalURL: portalURL, extensionOptions: extensionOptions}
 }
 
 func NewFakeHandler() rest.Handler {
@@ -42,6 +44,24 @@ func (h *handler) AddToContainer(container *restful.Container) error {
 		Operation("list-extension-version-files").
 		Param(ws.PathParameter("version", "The specified extension version name.")).
 		Returns(http.StatusOK, api.StatusOK, []loader.BufferedFile{}))
+	ws.Route(ws.POST("/repositories/{repository}/bundle").
+		To(h.UploadBundle).
+		Doc("Upload an offline bundle to the repository").
+		Notes("The bundle is a (gzipped) tarball which contains index.yaml and the charts, or an OCI image layout.").
+		Operation("upload-repository-bundle").
+		Consumes(runtime.MimeMultipartFormData, mimeOctetStream).
+		Param(ws.PathParameter("repository", "The specified repository name.")).
+		Param(ws.FormParameter("bundle", "The bundle file, only required if the content type is multipart/form-data.")).
+		Returns(http.StatusOK, api.StatusOK, corev1alpha1.Repository{}))
+	ws.Route(ws.POST("/installplans/{installplan}/dryrun").
+		To(h.DryRunInstallPlan).
+		Doc("Preview the changes of the install plan").
+		Notes("Render the extension with the proposed spec in dry-run mode and compare it with the deployed release.").
+		Operation("dryrun-installplan").
+		Reads(corev1alpha1.InstallPlanSpec{}).
+		Param(ws.PathParameter("installplan", "The specified install plan name, which is the same as the extension name.")).
+		Param(ws.QueryParameter("cluster", "Render the agent of the specified cluster instead of the extension.").Required(false)).
+		Returns(http.StatusOK, api.StatusOK, extension.DryRunResult{}))
 	container.Add(ws)
 	return nil
 }
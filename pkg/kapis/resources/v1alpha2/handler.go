@@ -7,16 +7,20 @@
 package v1alpha2
 
 import (
+	"fmt"
 	"net/http"
 	"strconv"
 	"strings"
 
 	"github.com/emicklei/go-restful/v3"
+	corev1 "k8s.io/api/core/v1"
 	k8serr "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/klog/v2"
 
 	"kubesphere.io/kubesphere/pkg/api"
+	"kubesphere.io/kubesphere/pkg/apiserver/authorization/authorizer"
 	"kubesphere.io/kubesphere/pkg/apiserver/query"
+	requestctx "kubesphere.io/kubesphere/pkg/apiserver/request"
 	"kubesphere.io/kubesphere/pkg/models/components"
 	"kubesphere.io/kubesphere/pkg/models/git"
 	"kubesphere.io/kubesphere/pkg/models/kubeconfig"
@@ -31,6 +35,8 @@ type handler struct {
 	componentsGetter    components.Getter
 	resourceQuotaGetter quotas.ResourceQuotaGetter
 	revisionGetter      revisions.RevisionGetter
+	revisionOperator    revisions.Operator
+	authorizer          authorizer.Authorizer
 	gitVerifier         git.GitVerifier
 	registryGetter      registries.RegistryGetter
 	kubeconfigOperator  kubeconfig.Interface
@@ -144,6 +150,93 @@ func (h *handler) GetStatefulSetRevision(request *restful.Request, response *res
 	response.WriteAsJson(result)
 }
 
+// GitVerifyResponse is the result of the git credential verification, the refs are listed with the credential.
+type GitVerifyResponse struct {
+	errors.Error
+	Refs *git.Refs `json:"refs,omitempty" description:"branches and tags of the repository"`
+}
+
+// RollbackRequest is the request to roll back a workload.
+type RollbackRequest struct {
+	Revision int64 `json:"revision" description:"the revision to roll back to"`
+}
+
+func (h *handler) ListWorkloadRevisions(request *restful.Request, response *restful.Response) {
+	kind := request.PathParameter("kind")
+	if !revisions.IsWorkloadKind(kind) {
+		api.HandleNotFound(response, request, fmt.Errorf("workload kind %s not found", kind))
+		return
+	}
+	result, err := h.revisionOperator.ListRevisions(request.Request.Context(), kind,
+		request.PathParameter("namespace"), request.PathParameter("workload"))
+	if err != nil {
+		api.HandleError(response, request, err)
+		return
+	}
+	response.WriteAsJson(result)
+}
+
+func (h *handler) DiffWorkloadRevisions(request *restful.Request, response *restful.Response) {
+	kind := request.PathParameter("kind")
+	if !revisions.IsWorkloadKind(kind) {
+		api.HandleNotFound(response, request, fmt.Errorf("workload kind %s not found", kind))
+		return
+	}
+	from, err := strconv.ParseInt(request.QueryParameter("from"), 10, 64)
+	if err != nil {
+		api.HandleBadRequest(response, request, fmt.Errorf("invalid revision from: %s", err))
+		return
+	}
+	var to int64
+	if value := request.QueryParameter("to"); value != "" {
+		if to, err = strconv.ParseInt(value, 10, 64); err != nil {
+			api.HandleBadRequest(response, request, fmt.Errorf("invalid revision to: %s", err))
+			return
+		}
+	}
+	result, err := h.revisionOperator.DiffRevisions(request.Request.Context(), kind,
+		request.PathParameter("namespace"), request.PathParameter("workload"), from, to)
+	if err != nil {
+		api.HandleError(response, request, err)
+		return
+	}
+	response.WriteAsJson(result)
+}
+
+// RollbackWorkload rolls back the workload on behalf of the user, the workload is updated by impersonating the user.
+func (h *handler) RollbackWorkload(request *restful.Request, response *restful.Response) {
+	kind := request.PathParameter("kind")
+	namespace := request.PathParameter("namespace")
+	name := request.PathParameter("workload")
+	if !revisions.IsWorkloadKind(kind) {
+		api.HandleNotFound(response, request, fmt.Errorf("workload kind %s not found", kind))
+		return
+	}
+	rollback := &RollbackRequest{}
+	if err := request.ReadEntity(rollback); err != nil {
+		api.HandleBadRequest(response, request, err)
+		return
+	}
+	if rollback.Revision <= 0 {
+		api.HandleBadRequest(response, request, fmt.Errorf("invalid revision %d", rollback.Revision))
+		return
+	}
+
+	user, ok := requestctx.UserFrom(request.Request.Context())
+	if !ok {
+		api.HandleUnauthorized(response, request, fmt.Errorf("user not found in request"))
+		return
+	}
+
+	changeCause := fmt.Sprintf("rollback to revision %d by %s", rollback.Revision, user.GetName())
+	result, err := h.revisionOperator.Rollback(request.Request.Context(), user, kind, namespace, name, rollback.Revision, changeCause)
+	if err != nil {
+		api.HandleError(response, request, err)
+		return
+	}
+	response.WriteAsJson(result)
+}
+
 func (h *handler) VerifyGitCredential(request *restful.Request, response *restful.Response) {
 	var credential api.GitCredential
 	err := request.ReadEntity(&credential)
@@ -155,13 +248,48 @@ func (h *handler) VerifyGitCredential(request *restful.Request, response *restfu
 	if credential.SecretRef != nil {
 		namespace = credential.SecretRef.Namespace
 		secretName = credential.SecretRef.Name
+		if err = h.authorizeGetSecret(request, namespace, secretName); err != nil {
+			api.HandleError(response, request, err)
+			return
+		}
 	}
-	err = h.gitVerifier.VerifyGitCredential(credential.RemoteUrl, namespace, secretName)
+	refs, err := h.gitVerifier.VerifyGitCredential(credential.RemoteUrl, namespace, secretName)
 	if err != nil {
 		response.WriteHeaderAndEntity(http.StatusInternalServerError, errors.Wrap(err))
 		return
 	}
-	response.WriteAsJson(errors.None)
+	response.WriteAsJson(GitVerifyResponse{Error: errors.None, Refs: refs})
+}
+
+// authorizeGetSecret checks that the user is allowed to get the Secret, the credential of the Secret is sent
+// to the remote on behalf of the user.
+func (h *handler) authorizeGetSecret(request *restful.Request, namespace, name string) error {
+	if namespace == "" || name == "" {
+		return k8serr.NewBadRequest("the namespace and name of the secret are required")
+	}
+	user, ok := requestctx.UserFrom(request.Request.Context())
+	if !ok {
+		return k8serr.NewUnauthorized("user not found in request")
+	}
+	getSecret := authorizer.AttributesRecord{
+		User:            user,
+		Verb:            "get",
+		APIVersion:      "v1",
+		Resource:        "secrets",
+		Name:            name,
+		Namespace:       namespace,
+		ResourceRequest: true,
+		ResourceScope:   requestctx.NamespaceScope,
+	}
+	decision, reason, err := h.authorizer.Authorize(getSecret)
+	if err != nil {
+		return err
+	}
+	if decision != authorizer.DecisionAllow {
+		return k8serr.NewForbidden(corev1.Resource("secrets"), name,
+			fmt.Errorf("user %s is not allowed to get secret %s/%s: %s", user.GetName(), namespace, name, reason))
+	}
+	return nil
 }
 
 func (h *handler) VerifyRegistryCredential(request *restful.Request, response *restful.Response) {
@@ -14,10 +14,12 @@ import (
 	"github.com/emicklei/go-restful/v3"
 	appsv1 "k8s.io/api/apps/v1"
 	"k8s.io/apimachinery/pkg/runtime/schema"
+	restclient "k8s.io/client-go/rest"
 	runtimeclient "sigs.k8s.io/controller-runtime/pkg/client"
 
 	"kubesphere.io/kubesphere/pkg/api"
 	"kubesphere.io/kubesphere/pkg/api/resource/v1alpha2"
+	"kubesphere.io/kubesphere/pkg/apiserver/authorization/authorizer"
 	"kubesphere.io/kubesphere/pkg/apiserver/rest"
 	"kubesphere.io/kubesphere/pkg/apiserver/runtime"
 	"kubesphere.io/kubesphere/pkg/models/components"
@@ -39,12 +41,14 @@ const (
 
 var GroupVersion = schema.GroupVersion{Group: GroupName, Version: "v1alpha2"}
 
-func NewHandler(cacheClient runtimeclient.Client, k8sVersion *semver.Version, masterURL string, options *terminal.Options) rest.Handler {
+func NewHandler(cacheClient runtimeclient.Client, k8sVersion *semver.Version, masterURL string, config *restclient.Config, options *terminal.Options, authorizer authorizer.Authorizer) rest.Handler {
 	return &handler{
 		resourceGetter:      resourcev1alpha3.NewResourceGetter(cacheClient, k8sVersion),
 		componentsGetter:    components.NewComponentsGetter(cacheClient),
 		resourceQuotaGetter: quotas.NewResourceQuotaGetter(cacheClient, k8sVersion),
 		revisionGetter:      revisions.NewRevisionGetter(cacheClient),
+		revisionOperator:    revisions.NewOperator(cacheClient, config),
+		authorizer:          authorizer,
 		gitVerifier:         git.NewGitVerifier(cacheClient),
 		registryGetter:      registries.NewRegistryGetter(cacheClient),
 		kubeconfigOperator:  kubeconfig.NewReadOnlyOperator(cacheClient, masterURL),
@@ -136,9 +140,13 @@ func (h *handler) AddToContainer(c *restful.Container) error {
 		To(h.VerifyGitCredential).
 		Deprecate().
 		Doc("Verify the git credential").
+		Notes("The secret can be a kubernetes.io/ssh-auth Secret with the known_hosts key for the ssh remotes, "+
+			"a git.kubesphere.io/token Secret or a kubernetes.io/basic-auth Secret for the http(s) remotes, "+
+			"the ca.crt key of the secret is used to verify the https server. The user must be allowed to get the secret. "+
+			"The branches and tags of the repository are returned in the refs field.").
 		Metadata(restfulspec.KeyOpenAPITags, []string{api.TagAdvancedOperations}).
 		Reads(gitmodel.AuthInfo{}).
-		Returns(http.StatusOK, api.StatusOK, errors.Error{}),
+		Returns(http.StatusOK, api.StatusOK, GitVerifyResponse{}),
 	)
 
 	ws.Route(ws.GET("/namespaces/{namespace}/daemonsets/{daemonset}/revisions/{revision}").
@@ -169,6 +177,36 @@ func (h *handler) AddToContainer(c *restful.Container) error {
 		Param(ws.PathParameter("revision", "the revision of the statefulset")).
 		Returns(http.StatusOK, api.StatusOK, appsv1.StatefulSet{}))
 
+	ws.Route(ws.GET("/namespaces/{namespace}/{kind}/{workload}/revisions").
+		To(h.ListWorkloadRevisions).
+		Doc("List the revision history of the workload").
+		Metadata(restfulspec.KeyOpenAPITags, []string{api.TagNamespacedResources}).
+		Param(ws.PathParameter("namespace", "The specified namespace.")).
+		Param(ws.PathParameter("kind", "the kind of the workload, one of deployments, statefulsets and daemonsets")).
+		Param(ws.PathParameter("workload", "the name of the workload")).
+		Returns(http.StatusOK, api.StatusOK, []revisions.Revision{}))
+
+	ws.Route(ws.GET("/namespaces/{namespace}/{kind}/{workload}/revisiondiff").
+		To(h.DiffWorkloadRevisions).
+		Doc("Compare the pod templates of two revisions of the workload").
+		Metadata(restfulspec.KeyOpenAPITags, []string{api.TagNamespacedResources}).
+		Param(ws.PathParameter("namespace", "The specified namespace.")).
+		Param(ws.PathParameter("kind", "the kind of the workload, one of deployments, statefulsets and daemonsets")).
+		Param(ws.PathParameter("workload", "the name of the workload")).
+		Param(ws.QueryParameter("from", "the revision compared from").Required(true).DataFormat("from=%d")).
+		Param(ws.QueryParameter("to", "the revision compared to, the current revision by default").Required(false).DataFormat("to=%d")).
+		Returns(http.StatusOK, api.StatusOK, revisions.RevisionDiff{}))
+
+	ws.Route(ws.POST("/namespaces/{namespace}/{kind}/{workload}/rollback").
+		To(h.RollbackWorkload).
+		Doc("Roll back the workload to the specified revision").
+		Metadata(restfulspec.KeyOpenAPITags, []string{api.TagNamespacedResources}).
+		Param(ws.PathParameter("namespace", "The specified namespace.")).
+		Param(ws.PathParameter("kind", "the kind of the workload, one of deployments, statefulsets and daemonsets")).
+		Param(ws.PathParameter("workload", "the name of the workload")).
+		Reads(RollbackRequest{}).
+		Returns(http.StatusOK, api.StatusOK, nil))
+
 	ws.Route(ws.GET("/abnormalworkloads").
 		To(h.GetNamespacedAbnormalWorkloads).
 		Deprecate().
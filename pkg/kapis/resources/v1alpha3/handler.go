@@ -24,6 +24,8 @@ import (
 	"kubesphere.io/kubesphere/pkg/models/registries/imagesearch"
 	"kubesphere.io/kubesphere/pkg/models/registries/imagesearch/dockerhub"
 	"kubesphere.io/kubesphere/pkg/models/registries/imagesearch/harbor"
+	_ "kubesphere.io/kubesphere/pkg/models/registries/imagesearch/oci"
+	"kubesphere.io/kubesphere/pkg/models/registries/imagesearch/quay"
 	v2 "kubesphere.io/kubesphere/pkg/models/registries/v2"
 	resourcev1alpha3 "kubesphere.io/kubesphere/pkg/models/resources/v1alpha3/resource"
 	"kubesphere.io/kubesphere/pkg/simple/client/overview"
@@ -171,6 +173,38 @@ func (h *handler) GetImageConfig(request *restful.Request, response *restful.Res
 	response.WriteHeaderAndJson(http.StatusOK, config, restful.MIME_JSON)
 }
 
+// InspectImage fetches the manifests of the image, the layers of each platform and the artifacts attached to it,
+// the registry is authenticated with the registry secret in the namespace.
+func (h *handler) InspectImage(request *restful.Request, response *restful.Response) {
+	secretName := request.QueryParameter("secret")
+	namespace := request.PathParameter("namespace")
+	image := request.QueryParameter("image")
+
+	if len(image) == 0 {
+		api.HandleBadRequest(response, request, fmt.Errorf("empty image name"))
+		return
+	}
+
+	var secret *v1.Secret
+	// empty secret means anonymous fetching
+	if len(secretName) != 0 {
+		object, err := h.resourceGetterV1alpha3.Get("secrets", namespace, secretName)
+		if err != nil {
+			api.HandleError(response, request, err)
+			return
+		}
+		secret = object.(*v1.Secret)
+	}
+
+	inspection, err := h.registryHelper.Inspect(request.Request.Context(), secret, image)
+	if err != nil {
+		canonicalizeRegistryError(request, response, err)
+		return
+	}
+
+	response.WriteHeaderAndJson(http.StatusOK, inspection, restful.MIME_JSON)
+}
+
 // GetRepositoryTags fetchs all tags of given repository, no paging.
 func (h *handler) GetRepositoryTags(request *restful.Request, response *restful.Response) {
 	secretName := request.QueryParameter("secret")
@@ -232,6 +266,7 @@ func (h *handler) SearchImages(request *restful.Request, response *restful.Respo
 	imageName := request.QueryParameter("q")
 	namespace := request.PathParameter("namespace")
 	searchSecret := request.QueryParameter("secret")
+	registry := request.QueryParameter("registry")
 
 	var (
 		config   = &imagesearch.SearchConfig{}
@@ -239,7 +274,7 @@ func (h *handler) SearchImages(request *restful.Request, response *restful.Respo
 		provider imagesearch.SearchProvider
 	)
 	if searchSecret != "" {
-		config, err = h.imageSearchSecretGetter.GetSecretConfig(request.Request.Context(), searchSecret, namespace)
+		config, err = h.imageSearchSecretGetter.GetSecretConfig(request.Request.Context(), searchSecret, namespace, registry)
 		if err != nil {
 			api.HandleError(response, request, err)
 			return
@@ -281,9 +316,14 @@ func canonicalizeRegistryError(request *restful.Request, response *restful.Respo
 	}
 }
 
+// getProviderTypeByHost returns the provider of the well-known registries, the others are regarded as Harbor,
+// the provider of the other registries, e.g. OCIRegistryProvider, is set by the annotation of the secret.
 func getProviderTypeByHost(host string) string {
-	if host == imagesearch.HostDockerIo {
+	switch imagesearch.RegistryHost(host) {
+	case imagesearch.RegistryHost(imagesearch.HostDockerIo):
 		return dockerhub.DockerHubRegisterProvider
+	case imagesearch.RegistryHost(imagesearch.HostQuayIo):
+		return quay.QuayRegisterProvider
 	}
 	return harbor.HarborRegisterProvider
 }
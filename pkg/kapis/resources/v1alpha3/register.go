@@ -148,6 +148,7 @@ func (h *handler) AddToContainer(c *restful.Container) error {
 		Metadata(restfulspec.KeyOpenAPITags, []string{api.TagNamespacedResources}).
 		Param(ws.PathParameter("namespace", "The specified namespace.")).
 		Param(ws.QueryParameter("secret", "Secret name of the image repository credential, left empty means anonymous fetch.").Required(false)).
+		Param(ws.QueryParameter("registry", "The registry to search, required if the secret holds the credentials of multiple registries.").Required(false)).
 		Param(ws.QueryParameter("q", "Search parameter for project and repository name.")).
 		Returns(http.StatusOK, api.StatusOK, imagesearch.Results{}))
 
@@ -161,6 +162,16 @@ func (h *handler) AddToContainer(c *restful.Container) error {
 		Param(ws.QueryParameter("image", "Image name to query, e.g. kubesphere/ks-apiserver:v3.1.1").Required(true)).
 		Returns(http.StatusOK, api.StatusOK, v2.ImageConfig{}))
 
+	ws.Route(ws.GET("/namespaces/{namespace}/imageinspection").
+		To(h.InspectImage).
+		Doc("Inspect image").
+		Notes("Inspect the platforms of the manifest list, the layers of each platform and the signatures and SBOMs attached to the image.").
+		Metadata(restfulspec.KeyOpenAPITags, []string{api.TagNamespacedResources}).
+		Param(ws.PathParameter("namespace", "The specified namespace.")).
+		Param(ws.QueryParameter("secret", "Secret name of the image repository credential, left empty means anonymous fetch.").Required(false)).
+		Param(ws.QueryParameter("image", "Image name to inspect, e.g. kubesphere/ks-apiserver:v4.1.0").Required(true)).
+		Returns(http.StatusOK, api.StatusOK, v2.ImageInspection{}))
+
 	ws.Route(ws.GET("/namespaces/{namespace}/repositorytags").
 		To(h.GetRepositoryTags).
 		Deprecate().
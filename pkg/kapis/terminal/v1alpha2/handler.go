@@ -8,6 +8,7 @@ package v1alpha2
 
 import (
 	"archive/tar"
+	"bytes"
 	"context"
 	"errors"
 	"fmt"
@@ -19,16 +20,23 @@ import (
 	"strings"
 
 	"github.com/emicklei/go-restful/v3"
+	"github.com/google/uuid"
 	"github.com/gorilla/websocket"
 	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apiserver/pkg/authentication/user"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/remotecommand"
 	"k8s.io/klog/v2"
 	"k8s.io/kubectl/pkg/scheme"
+	iamv1beta1 "kubesphere.io/api/iam/v1beta1"
+	"kubesphere.io/utils/s3"
+	runtimeclient "sigs.k8s.io/controller-runtime/pkg/client"
 
 	"kubesphere.io/kubesphere/pkg/api"
 	"kubesphere.io/kubesphere/pkg/apiserver/authorization/authorizer"
+	"kubesphere.io/kubesphere/pkg/apiserver/query"
 	requestctx "kubesphere.io/kubesphere/pkg/apiserver/request"
 	"kubesphere.io/kubesphere/pkg/constants"
 	"kubesphere.io/kubesphere/pkg/models/terminal"
@@ -47,6 +55,37 @@ type handler struct {
 	terminaler      terminal.Interface
 	authorizer      authorizer.Authorizer
 	uploadFileLimit int64
+	// recordingStore stores the terminal session recordings
+	recordingStore s3.Interface
+	runtimeClient  runtimeclient.Client
+	accessRequest  terminal.AccessRequestOptions
+}
+
+var errAccessRequestRequired = errors.New("an approved access request is required")
+
+// accessRequestContext limits the session to the expiration of the active access request of the user to the target,
+// errAccessRequestRequired is returned if the access request is required but not found.
+func (h *handler) accessRequestContext(ctx context.Context, user user.Info, target iamv1beta1.AccessRequestSpec, required bool) (context.Context, context.CancelFunc, error) {
+	accessRequest, err := terminal.ActiveAccessRequest(ctx, h.runtimeClient, user.GetName(), target)
+	if err != nil {
+		return nil, nil, err
+	}
+	if accessRequest == nil {
+		if required {
+			return nil, nil, errAccessRequestRequired
+		}
+		return ctx, func() {}, nil
+	}
+	ctx, cancel := context.WithDeadline(ctx, accessRequest.Status.ExpirationTime.Time)
+	return ctx, cancel, nil
+}
+
+func handleAccessRequestError(response *restful.Response, request *restful.Request, err error) {
+	if errors.Is(err, errAccessRequestRequired) {
+		api.HandleForbidden(response, request, err)
+		return
+	}
+	api.HandleInternalError(response, request, err)
 }
 
 func (h *handler) HandleTerminalSession(request *restful.Request, response *restful.Response) {
@@ -79,13 +118,21 @@ func (h *handler) HandleTerminalSession(request *restful.Request, response *rest
 		return
 	}
 
+	target := iamv1beta1.AccessRequestSpec{Type: iamv1beta1.AccessRequestTypePodExec, Namespace: namespace, Pod: podName}
+	ctx, cancel, err := h.accessRequestContext(request.Request.Context(), user, target, h.accessRequest.PodExecRequired)
+	if err != nil {
+		handleAccessRequestError(response, request, err)
+		return
+	}
+	defer cancel()
+
 	conn, err := upgrader.Upgrade(response.ResponseWriter, request.Request, nil)
 	if err != nil {
 		klog.Warning(err)
 		return
 	}
 
-	h.terminaler.HandleSession(request.Request.Context(), shell, namespace, podName, containerName, conn)
+	h.terminaler.HandleSession(ctx, shell, namespace, podName, containerName, conn)
 }
 
 func (h *handler) HandleUserKubectlSession(request *restful.Request, response *restful.Response) {
@@ -131,6 +178,7 @@ func (h *handler) HandleShellAccessToNode(request *restful.Request, response *re
 		Verb:            "create",
 		Resource:        "nodes",
 		Subresource:     "exec",
+		Name:            nodename,
 		ResourceRequest: true,
 		ResourceScope:   requestctx.ClusterScope,
 	}
@@ -146,13 +194,21 @@ func (h *handler) HandleShellAccessToNode(request *restful.Request, response *re
 		return
 	}
 
+	target := iamv1beta1.AccessRequestSpec{Type: iamv1beta1.AccessRequestTypeNodeShell, Node: nodename}
+	ctx, cancel, err := h.accessRequestContext(request.Request.Context(), user, target, h.accessRequest.NodeShellRequired)
+	if err != nil {
+		handleAccessRequestError(response, request, err)
+		return
+	}
+	defer cancel()
+
 	conn, err := upgrader.Upgrade(response.ResponseWriter, request.Request, nil)
 	if err != nil {
 		klog.Warning(err)
 		return
 	}
 
-	h.terminaler.HandleShellAccessToNode(request.Request.Context(), nodename, conn)
+	h.terminaler.HandleShellAccessToNode(ctx, nodename, conn)
 }
 
 type fileWithHeader struct {
@@ -257,11 +313,16 @@ type tarPipe struct {
 	bytesRead uint64
 	size      uint64
 	ctx       context.Context
+	// cachePath is the archive cached in the container, and etag identifies it. The path is archived again
+	// on each read if the archive can't be cached, e.g. the file system of the container is read-only.
+	cachePath string
+	etag      string
 
 	namespace, name, container, filePath string
 }
 
-func newTarPipe(ctx context.Context, config *rest.Config, client rest.Interface, namespace, name, container, filePath string) (*tarPipe, error) {
+// newTarPipe reads the cached archive identified by the etag if it still exists, or caches a new archive of the path.
+func newTarPipe(ctx context.Context, config *rest.Config, client rest.Interface, namespace, name, container, filePath, etag string) (*tarPipe, error) {
 	t := &tarPipe{
 		config:    config,
 		client:    client,
@@ -272,16 +333,46 @@ func newTarPipe(ctx context.Context, config *rest.Config, client rest.Interface,
 		ctx:       ctx,
 	}
 
-	if err := t.getFileSize(); err != nil {
-		return nil, err
+	if cachePath, ok := archiveCachePath(etag); ok {
+		if err := t.getFileSize(fmt.Sprintf("test -f %[1]s && wc -c < %[1]s", shellQuote(cachePath))); err == nil {
+			t.cachePath, t.etag = cachePath, etag
+			return t, nil
+		}
+	}
+
+	etag = fmt.Sprintf(`"%s"`, strings.ReplaceAll(uuid.New().String(), "-", ""))
+	cachePath, _ := archiveCachePath(etag)
+	command := fmt.Sprintf("find %s -maxdepth 1 -name %s -mmin +%d -exec rm -f {} + 2>/dev/null; tar cf %s %s 2>/dev/null; wc -c < %s",
+		archiveCacheDir, shellQuote(archiveCachePrefix+"*"), archiveCacheMaxAge,
+		shellQuote(cachePath), shellQuote(filePath), shellQuote(cachePath))
+	if err := t.getFileSize(command); err == nil {
+		t.cachePath, t.etag = cachePath, etag
+		return t, nil
 	}
-	if err := t.initReadFrom(0); err != nil {
+
+	if err := t.getFileSize(fmt.Sprintf("tar cf - %s | wc -c", shellQuote(filePath))); err != nil {
 		return nil, err
 	}
 	return t, nil
 }
 
-func (t *tarPipe) getFileSize() error {
+// removeCache removes the cached archive once it's completely downloaded.
+func (t *tarPipe) removeCache() {
+	if t.cachePath == "" {
+		return
+	}
+	if _, err := t.output(fmt.Sprintf("rm -f %s", shellQuote(t.cachePath))); err != nil {
+		klog.Warningf("failed to remove the cached archive %s in pod %s/%s: %s", t.cachePath, t.namespace, t.name, err)
+	}
+}
+
+// seek starts reading the tar stream from the offset.
+func (t *tarPipe) seek(offset uint64) error {
+	t.bytesRead = offset
+	return t.initReadFrom(offset + 1)
+}
+
+func (t *tarPipe) executor(command string) (remotecommand.Executor, error) {
 	req := t.client.Post().
 		Resource("pods").
 		Name(t.name).
@@ -289,37 +380,41 @@ func (t *tarPipe) getFileSize() error {
 		SubResource("exec").
 		VersionedParams(&corev1.PodExecOptions{
 			Container: t.container,
-			Command:   []string{"sh", "-c", fmt.Sprintf("tar cf - %s | wc -c", t.filePath)},
+			Command:   []string{"sh", "-c", command},
 			Stdin:     false,
 			Stdout:    true,
 			Stderr:    false,
 			TTY:       false,
 		}, scheme.ParameterCodec)
 
-	exec, err := remotecommand.NewSPDYExecutor(t.config, "POST", req.URL())
+	return remotecommand.NewSPDYExecutor(t.config, "POST", req.URL())
+}
+
+func (t *tarPipe) output(command string) (string, error) {
+	exec, err := t.executor(command)
 	if err != nil {
-		return err
+		return "", err
 	}
 
-	reader, writer := io.Pipe()
-	go func() {
-		defer writer.Close()
-
-		if err = exec.StreamWithContext(t.ctx, remotecommand.StreamOptions{
-			Stdin:             nil,
-			Stdout:            writer,
-			Stderr:            nil,
-			TerminalSizeQueue: nil,
-		}); err != nil {
-			klog.Error(err)
-		}
-	}()
+	stdout := &bytes.Buffer{}
+	if err = exec.StreamWithContext(t.ctx, remotecommand.StreamOptions{
+		Stdin:             nil,
+		Stdout:            stdout,
+		Stderr:            nil,
+		TerminalSizeQueue: nil,
+	}); err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(stdout.String()), nil
+}
 
-	result, err := io.ReadAll(reader)
+// getFileSize gets the size of the archive with the command.
+func (t *tarPipe) getFileSize(command string) error {
+	result, err := t.output(command)
 	if err != nil {
 		return err
 	}
-	num, err := strconv.ParseUint(strings.TrimSpace(string(result)), 10, 64)
+	num, err := strconv.ParseUint(result, 10, 64)
 	if err != nil {
 		return err
 	}
@@ -330,21 +425,11 @@ func (t *tarPipe) getFileSize() error {
 func (t *tarPipe) initReadFrom(n uint64) error {
 	t.reader, t.outStream = io.Pipe()
 
-	req := t.client.Post().
-		Resource("pods").
-		Name(t.name).
-		Namespace(t.namespace).
-		SubResource("exec").
-		VersionedParams(&corev1.PodExecOptions{
-			Container: t.container,
-			Command:   []string{"sh", "-c", fmt.Sprintf("tar cf - %s | tail -c+%d", t.filePath, n)},
-			Stdin:     false,
-			Stdout:    true,
-			Stderr:    false,
-			TTY:       false,
-		}, scheme.ParameterCodec)
-
-	exec, err := remotecommand.NewSPDYExecutor(t.config, "POST", req.URL())
+	command := fmt.Sprintf("tar cf - %s | tail -c+%d", shellQuote(t.filePath), n)
+	if t.cachePath != "" {
+		command = fmt.Sprintf("tail -c+%d %s", n, shellQuote(t.cachePath))
+	}
+	exec, err := t.executor(command)
 	if err != nil {
 		return err
 	}
@@ -380,20 +465,188 @@ func (h *handler) DownloadFile(request *restful.Request, response *restful.Respo
 	filePath := request.QueryParameter("path")
 	fileName := filepath.Base(filePath)
 
-	response.AddHeader("Content-Disposition", fmt.Sprintf("attachment; filename=%s.tar", fileName))
-
 	namespace := request.PathParameter("namespace")
 	podName := request.PathParameter("pod")
 	containerName := request.QueryParameter("container")
 
-	reader, err := newTarPipe(request.Request.Context(), h.config, h.client.CoreV1().RESTClient(), namespace, podName, containerName, filePath)
+	if request.QueryParameter("format") == archiveFormatTarGz {
+		h.downloadArchive(request, response, namespace, podName, containerName, filePath)
+		return
+	}
+
+	// the tar stream is resumable with the range requests, the cached archive is resumed if the If-Range header
+	// matches its ETag, otherwise the path is archived again and the whole archive is returned
+	ifRange := request.HeaderParameter("If-Range")
+	reader, err := newTarPipe(request.Request.Context(), h.config, h.client.CoreV1().RESTClient(), namespace, podName, containerName, filePath, ifRange)
 	if err != nil {
 		api.HandleInternalError(response, nil, err)
 		return
 	}
-
-	if _, err = io.Copy(response.ResponseWriter, reader); err != nil {
+	rangeHeader := request.HeaderParameter("Range")
+	if ifRange != "" && ifRange != reader.etag {
+		rangeHeader = ""
+	}
+	start, end, partial, err := parseRange(rangeHeader, reader.size)
+	if err != nil {
+		response.AddHeader("Content-Range", fmt.Sprintf("bytes */%d", reader.size))
+		_ = response.WriteErrorString(http.StatusRequestedRangeNotSatisfiable, err.Error())
+		return
+	}
+	if err = reader.seek(start); err != nil {
 		api.HandleInternalError(response, nil, err)
 		return
 	}
+
+	response.AddHeader("Content-Disposition", fmt.Sprintf("attachment; filename=%s.tar", fileName))
+	response.AddHeader("Content-Type", "application/x-tar")
+	response.AddHeader("Accept-Ranges", "bytes")
+	if reader.etag != "" {
+		response.AddHeader("ETag", reader.etag)
+	}
+	response.AddHeader("Content-Length", strconv.FormatUint(end-start, 10))
+	if partial {
+		response.AddHeader("Content-Range", fmt.Sprintf("bytes %d-%d/%d", start, end-1, reader.size))
+		response.WriteHeader(http.StatusPartialContent)
+	}
+
+	if _, err = io.Copy(response.ResponseWriter, io.LimitReader(reader, int64(end-start))); err != nil {
+		klog.Warningf("failed to download %s from pod %s/%s: %s", filePath, namespace, podName, err)
+		return
+	}
+	if end == reader.size {
+		reader.removeCache()
+	}
+}
+
+func (h *handler) ListRecordings(request *restful.Request, response *restful.Response) {
+	if h.recordingStore == nil {
+		api.HandleNotFound(response, request, errors.New("terminal recording is not enabled"))
+		return
+	}
+	recordings, err := terminal.ListRecordings(h.recordingStore, request.PathParameter("workspace"))
+	if err != nil {
+		api.HandleInternalError(response, request, err)
+		return
+	}
+
+	q := query.ParseQueryParameter(request)
+	filtered := make([]terminal.Recording, 0)
+	for _, recording := range recordings {
+		if matchRecording(recording, q.Filters) {
+			filtered = append(filtered, recording)
+		}
+	}
+	start, end := q.Pagination.GetValidPagination(len(filtered))
+	_ = response.WriteEntity(terminal.RecordingList{Items: filtered[start:end], TotalItems: len(filtered)})
+}
+
+func matchRecording(recording terminal.Recording, filters map[query.Field]query.Value) bool {
+	fields := map[query.Field]string{
+		"user":      recording.User,
+		"type":      string(recording.Type),
+		"workspace": recording.Workspace,
+		"namespace": recording.Namespace,
+		"node":      recording.Node,
+		"auditID":   recording.AuditID,
+	}
+	for field, value := range filters {
+		if actual, ok := fields[field]; ok && actual != string(value) {
+			return false
+		}
+	}
+	return true
+}
+
+func (h *handler) DownloadRecording(request *restful.Request, response *restful.Response) {
+	if h.recordingStore == nil {
+		api.HandleNotFound(response, request, errors.New("terminal recording is not enabled"))
+		return
+	}
+	id := request.PathParameter("recording")
+	data, err := terminal.ReadRecording(h.recordingStore, request.PathParameter("workspace"), id)
+	if err != nil {
+		if errors.Is(err, terminal.ErrRecordingNotFound) {
+			api.HandleNotFound(response, request, err)
+			return
+		}
+		api.HandleInternalError(response, request, err)
+		return
+	}
+
+	response.AddHeader("Content-Type", "application/x-asciicast")
+	response.AddHeader("Content-Disposition", fmt.Sprintf("attachment; filename=%s.cast", id))
+	_, _ = response.Write(data)
+}
+
+func (h *handler) CreateAccessRequest(request *restful.Request, response *restful.Response) {
+	spec := iamv1beta1.AccessRequestSpec{}
+	if err := request.ReadEntity(&spec); err != nil {
+		api.HandleBadRequest(response, request, err)
+		return
+	}
+	if err := terminal.ValidateAccessRequest(&spec, h.accessRequest.MaxDuration); err != nil {
+		api.HandleBadRequest(response, request, err)
+		return
+	}
+
+	// the requester is always the current user
+	user, _ := requestctx.UserFrom(request.Request.Context())
+	spec.User = user.GetName()
+	accessRequest := &iamv1beta1.AccessRequest{
+		ObjectMeta: metav1.ObjectMeta{
+			GenerateName: fmt.Sprintf("%s-", user.GetName()),
+			Labels:       map[string]string{iamv1beta1.UserReferenceLabel: user.GetName()},
+		},
+		Spec: spec,
+	}
+	if err := h.runtimeClient.Create(request.Request.Context(), accessRequest); err != nil {
+		api.HandleError(response, request, err)
+		return
+	}
+	_ = response.WriteHeaderAndEntity(http.StatusCreated, accessRequest)
+}
+
+func (h *handler) ListAccessRequests(request *restful.Request, response *restful.Response) {
+	user, _ := requestctx.UserFrom(request.Request.Context())
+	accessRequests := &iamv1beta1.AccessRequestList{}
+	if err := h.runtimeClient.List(request.Request.Context(), accessRequests,
+		runtimeclient.MatchingLabels{iamv1beta1.UserReferenceLabel: user.GetName()}); err != nil {
+		api.HandleInternalError(response, request, err)
+		return
+	}
+	_ = response.WriteEntity(accessRequests)
+}
+
+func (h *handler) ApproveAccessRequest(request *restful.Request, response *restful.Response) {
+	h.decideAccessRequest(request, response, true)
+}
+
+func (h *handler) DenyAccessRequest(request *restful.Request, response *restful.Response) {
+	h.decideAccessRequest(request, response, false)
+}
+
+func (h *handler) decideAccessRequest(request *restful.Request, response *restful.Response, approved bool) {
+	decision := terminal.AccessRequestDecision{}
+	if request.Request.ContentLength > 0 {
+		if err := request.ReadEntity(&decision); err != nil {
+			api.HandleBadRequest(response, request, err)
+			return
+		}
+	}
+
+	user, _ := requestctx.UserFrom(request.Request.Context())
+	accessRequest, err := terminal.DecideAccessRequest(request.Request.Context(), h.runtimeClient, h.authorizer,
+		request.PathParameter("accessrequest"), user, approved, decision)
+	if err != nil {
+		switch {
+		case errors.Is(err, terminal.ErrSelfApproval), errors.Is(err, terminal.ErrApproverNotAuthorized):
+			api.HandleForbidden(response, request, err)
+		case errors.Is(err, terminal.ErrAccessRequestDecided):
+			api.HandleConflict(response, request, err)
+		default:
+			api.HandleError(response, request, err)
+		}
+		return
+	}
+	_ = response.WriteEntity(accessRequest)
 }
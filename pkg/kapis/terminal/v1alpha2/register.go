@@ -16,9 +16,13 @@ import (
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
 	"k8s.io/klog/v2"
+	iamv1beta1 "kubesphere.io/api/iam/v1beta1"
+	"kubesphere.io/utils/s3"
+	runtimeclient "sigs.k8s.io/controller-runtime/pkg/client"
 
 	"kubesphere.io/kubesphere/pkg/api"
 	"kubesphere.io/kubesphere/pkg/apiserver/authorization/authorizer"
+	"kubesphere.io/kubesphere/pkg/apiserver/query"
 	restapi "kubesphere.io/kubesphere/pkg/apiserver/rest"
 	"kubesphere.io/kubesphere/pkg/apiserver/runtime"
 	"kubesphere.io/kubesphere/pkg/models/terminal"
@@ -30,7 +34,7 @@ const (
 
 var GroupVersion = schema.GroupVersion{Group: GroupName, Version: "v1alpha2"}
 
-func NewHandler(client kubernetes.Interface, authorizer authorizer.Authorizer, config *rest.Config, options *terminal.Options) restapi.Handler {
+func NewHandler(client kubernetes.Interface, runtimeClient runtimeclient.Client, authorizer authorizer.Authorizer, config *rest.Config, options *terminal.Options, s3Options *s3.Options) restapi.Handler {
 	var uploadFileLimit int64 = 100 << 20 // 100 MB
 	q, err := resource.ParseQuantity(options.UploadFileLimit)
 	if err != nil {
@@ -39,12 +43,22 @@ func NewHandler(client kubernetes.Interface, authorizer authorizer.Authorizer, c
 		uploadFileLimit = q.Value()
 	}
 
+	var recordingStore s3.Interface
+	if options.Recording.Enabled && s3Options != nil && s3Options.Endpoint != "" {
+		if recordingStore, err = s3.NewS3Client(s3Options); err != nil {
+			klog.Errorf("failed to create s3 client for terminal recording: %s", err)
+		}
+	}
+
 	return &handler{
 		client:          client,
 		config:          config,
 		authorizer:      authorizer,
-		terminaler:      terminal.NewTerminaler(client, config, options),
+		terminaler:      terminal.NewTerminaler(client, config, options, recordingStore),
 		uploadFileLimit: uploadFileLimit,
+		recordingStore:  recordingStore,
+		runtimeClient:   runtimeClient,
+		accessRequest:   options.AccessRequest,
 	}
 }
 
@@ -84,9 +98,71 @@ func (h *handler) AddToContainer(c *restful.Container) error {
 		Param(ws.PathParameter("namespace", "The specified namespace.")).
 		Param(ws.PathParameter("pod", "pod name")).
 		Param(ws.QueryParameter("container", "container name")).
-		Param(ws.QueryParameter("path", "file path")).
+		Param(ws.QueryParameter("path", "file or directory path")).
+		Param(ws.QueryParameter("format", "archive format, tar (default) supports range requests, tar.gz for compressed file or directory").Required(false)).
+		Param(ws.HeaderParameter("Range", "byte range of the tar archive to resume the download").Required(false)).
+		Param(ws.HeaderParameter("If-Range", "ETag of the tar archive returned by the previous download, the cached archive is resumed if it still exists, otherwise the whole new archive is returned").Required(false)).
+		Returns(http.StatusOK, api.StatusOK, nil).
+		Returns(http.StatusPartialContent, "Partial Content", nil))
+
+	ws.Route(ws.GET("/namespaces/{namespace}/pods/{pod}/file/stat").
+		To(h.StatFile).
+		Doc("Get the type and the archive size of the file or directory in pod, which is used to report the download progress").
+		Metadata(restfulspec.KeyOpenAPITags, []string{api.TagTerminal}).
+		Operation("stat-file-in-pod").
+		Param(ws.PathParameter("namespace", "The specified namespace.")).
+		Param(ws.PathParameter("pod", "pod name")).
+		Param(ws.QueryParameter("container", "container name")).
+		Param(ws.QueryParameter("path", "file or directory path")).
+		Returns(http.StatusOK, api.StatusOK, FileInfo{}))
+
+	ws.Route(ws.POST("/namespaces/{namespace}/pods/{pod}/file/uploads").
+		To(h.CreateUpload).
+		Doc("Create a resumable upload to pod following the tus protocol, the file name is required in the Upload-Metadata header").
+		Metadata(restfulspec.KeyOpenAPITags, []string{api.TagTerminal}).
+		Operation("create-upload-to-pod").
+		Param(ws.PathParameter("namespace", "The specified namespace.")).
+		Param(ws.PathParameter("pod", "pod name")).
+		Param(ws.QueryParameter("container", "container name")).
+		Param(ws.QueryParameter("path", "dest dir path")).
+		Param(ws.HeaderParameter(headerUploadLength, "size of the file in bytes, which is limited by the upload file limit").Required(true)).
+		Param(ws.HeaderParameter(headerUploadMetadata, "base64 encoded metadata, e.g. filename ZGVtby50eHQ=").Required(true)).
+		Returns(http.StatusCreated, "Created", nil).
+		Returns(http.StatusRequestEntityTooLarge, "Request Entity Too Large", nil))
+
+	ws.Route(ws.HEAD("/namespaces/{namespace}/pods/{pod}/file/uploads/{upload}").
+		To(h.UploadOffset).
+		Doc("Get the offset of the resumable upload").
+		Metadata(restfulspec.KeyOpenAPITags, []string{api.TagTerminal}).
+		Operation("get-upload-offset").
+		Param(ws.PathParameter("namespace", "The specified namespace.")).
+		Param(ws.PathParameter("pod", "pod name")).
+		Param(ws.PathParameter("upload", "upload id")).
 		Returns(http.StatusOK, api.StatusOK, nil))
 
+	ws.Route(ws.PATCH("/namespaces/{namespace}/pods/{pod}/file/uploads/{upload}").
+		To(h.UploadChunk).
+		Doc("Upload a chunk of the file at the offset of the resumable upload").
+		Consumes(mimeOffsetOctetData).
+		Metadata(restfulspec.KeyOpenAPITags, []string{api.TagTerminal}).
+		Operation("upload-chunk-to-pod").
+		Param(ws.PathParameter("namespace", "The specified namespace.")).
+		Param(ws.PathParameter("pod", "pod name")).
+		Param(ws.PathParameter("upload", "upload id")).
+		Param(ws.HeaderParameter(headerUploadOffset, "offset of the chunk").Required(true)).
+		Returns(http.StatusNoContent, "No Content", nil).
+		Returns(http.StatusConflict, "the offset is changed or another chunk is in progress", nil))
+
+	ws.Route(ws.DELETE("/namespaces/{namespace}/pods/{pod}/file/uploads/{upload}").
+		To(h.TerminateUpload).
+		Doc("Terminate the resumable upload and delete the uploaded chunks").
+		Metadata(restfulspec.KeyOpenAPITags, []string{api.TagTerminal}).
+		Operation("terminate-upload-to-pod").
+		Param(ws.PathParameter("namespace", "The specified namespace.")).
+		Param(ws.PathParameter("pod", "pod name")).
+		Param(ws.PathParameter("upload", "upload id")).
+		Returns(http.StatusNoContent, "No Content", nil))
+
 	ws.Route(ws.GET("/users/{user}/kubectl").
 		To(h.HandleUserKubectlSession).
 		Param(ws.PathParameter("user", "username")).
@@ -103,6 +179,80 @@ func (h *handler) AddToContainer(c *restful.Container) error {
 		Param(ws.PathParameter("nodename", "node name")).
 		Metadata(restfulspec.KeyOpenAPITags, []string{api.TagTerminal}))
 
+	ws.Route(ws.GET("/terminalrecordings").
+		To(h.ListRecordings).
+		Doc("List terminal session recordings").
+		Metadata(restfulspec.KeyOpenAPITags, []string{api.TagTerminal}).
+		Operation("list-terminal-recordings").
+		Param(ws.QueryParameter("user", "filter by the user who started the session")).
+		Param(ws.QueryParameter("type", "filter by the session type, one of pod, node and kubectl")).
+		Param(ws.QueryParameter("workspace", "filter by workspace")).
+		Param(ws.QueryParameter(query.ParameterPage, "page").Required(false).DataFormat("page=%d").DefaultValue("page=1")).
+		Param(ws.QueryParameter(query.ParameterLimit, "limit").Required(false)).
+		Returns(http.StatusOK, api.StatusOK, terminal.RecordingList{}))
+
+	ws.Route(ws.GET("/terminalrecordings/{recording}").
+		To(h.DownloadRecording).
+		Doc("Download the terminal session recording in asciicast v2 format").
+		Metadata(restfulspec.KeyOpenAPITags, []string{api.TagTerminal}).
+		Operation("download-terminal-recording").
+		Param(ws.PathParameter("recording", "recording id")).
+		Returns(http.StatusOK, api.StatusOK, nil))
+
+	ws.Route(ws.GET("/workspaces/{workspace}/terminalrecordings").
+		To(h.ListRecordings).
+		Doc("List terminal session recordings in the workspace").
+		Metadata(restfulspec.KeyOpenAPITags, []string{api.TagTerminal}).
+		Operation("list-workspace-terminal-recordings").
+		Param(ws.PathParameter("workspace", "workspace name")).
+		Param(ws.QueryParameter("user", "filter by the user who started the session")).
+		Param(ws.QueryParameter("type", "filter by the session type, one of pod, node and kubectl")).
+		Param(ws.QueryParameter(query.ParameterPage, "page").Required(false).DataFormat("page=%d").DefaultValue("page=1")).
+		Param(ws.QueryParameter(query.ParameterLimit, "limit").Required(false)).
+		Returns(http.StatusOK, api.StatusOK, terminal.RecordingList{}))
+
+	ws.Route(ws.GET("/workspaces/{workspace}/terminalrecordings/{recording}").
+		To(h.DownloadRecording).
+		Doc("Download the terminal session recording of the workspace in asciicast v2 format").
+		Metadata(restfulspec.KeyOpenAPITags, []string{api.TagTerminal}).
+		Operation("download-workspace-terminal-recording").
+		Param(ws.PathParameter("workspace", "workspace name")).
+		Param(ws.PathParameter("recording", "recording id")).
+		Returns(http.StatusOK, api.StatusOK, nil))
+
+	ws.Route(ws.POST("/accessrequests").
+		To(h.CreateAccessRequest).
+		Doc("Request the just-in-time access to exec into a pod or open a node shell").
+		Metadata(restfulspec.KeyOpenAPITags, []string{api.TagTerminal}).
+		Operation("create-access-request").
+		Reads(iamv1beta1.AccessRequestSpec{}).
+		Returns(http.StatusCreated, api.StatusOK, iamv1beta1.AccessRequest{}))
+
+	ws.Route(ws.GET("/accessrequests").
+		To(h.ListAccessRequests).
+		Doc("List the access requests of the current user").
+		Metadata(restfulspec.KeyOpenAPITags, []string{api.TagTerminal}).
+		Operation("list-access-requests").
+		Returns(http.StatusOK, api.StatusOK, iamv1beta1.AccessRequestList{}))
+
+	ws.Route(ws.POST("/accessrequests/{accessrequest}/approve").
+		To(h.ApproveAccessRequest).
+		Doc("Approve the access request").
+		Metadata(restfulspec.KeyOpenAPITags, []string{api.TagTerminal}).
+		Operation("approve-access-request").
+		Param(ws.PathParameter("accessrequest", "access request name")).
+		Reads(terminal.AccessRequestDecision{}).
+		Returns(http.StatusOK, api.StatusOK, iamv1beta1.AccessRequest{}))
+
+	ws.Route(ws.POST("/accessrequests/{accessrequest}/deny").
+		To(h.DenyAccessRequest).
+		Doc("Deny the access request").
+		Metadata(restfulspec.KeyOpenAPITags, []string{api.TagTerminal}).
+		Operation("deny-access-request").
+		Param(ws.PathParameter("accessrequest", "access request name")).
+		Reads(terminal.AccessRequestDecision{}).
+		Returns(http.StatusOK, api.StatusOK, iamv1beta1.AccessRequest{}))
+
 	c.Add(ws)
 
 	return nil
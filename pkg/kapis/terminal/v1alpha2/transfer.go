@@ -0,0 +1,489 @@
+/*
+ * Copyright 2024 the KubeSphere Authors.
+ * Please refer to the LICENSE file in the root directory of the project.
+ * https://github.com/kubesphere/kubesphere/blob/master/LICENSE
+ */
+
+package v1alpha2
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/emicklei/go-restful/v3"
+	"github.com/google/uuid"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/tools/remotecommand"
+	"k8s.io/klog/v2"
+	"k8s.io/kubectl/pkg/scheme"
+
+	"kubesphere.io/kubesphere/pkg/api"
+)
+
+const (
+	archiveFormatTarGz = "tar.gz"
+
+	// tusVersion is the version of the tus resumable upload protocol, see https://tus.io/protocols/resumable-upload
+	tusVersion          = "1.0.0"
+	mimeOffsetOctetData = "application/offset+octet-stream"
+
+	headerTusResumable   = "Tus-Resumable"
+	headerUploadLength   = "Upload-Length"
+	headerUploadOffset   = "Upload-Offset"
+	headerUploadMetadata = "Upload-Metadata"
+
+	// the exit status of the commands in the container when the upload is not found or the offset is changed
+	exitUploadNotFound   = 2
+	exitOffsetMismatched = 3
+
+	// the tar archive of the download is cached in the container until it's completely downloaded, so that
+	// resuming the download doesn't archive the path again. The caches of the incomplete downloads are removed
+	// after archiveCacheMaxAge minutes, when another download is started in the container.
+	archiveCacheDir    = "/tmp"
+	archiveCachePrefix = ".kubesphere-download-"
+	archiveCacheMaxAge = 60
+)
+
+var errUploadNotFound = errors.New("upload not found")
+
+// uploadLocks are the uploads receiving a chunk in this apiserver, the concurrent chunks of the same upload are
+// rejected. The offset is compared again in the container right before appending the chunk, in case the chunks
+// are sent to different replicas.
+var uploadLocks = &uploadLockSet{uploads: map[string]struct{}{}}
+
+type uploadLockSet struct {
+	mu      sync.Mutex
+	uploads map[string]struct{}
+}
+
+func (l *uploadLockSet) tryLock(id string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if _, ok := l.uploads[id]; ok {
+		return false
+	}
+	l.uploads[id] = struct{}{}
+	return true
+}
+
+func (l *uploadLockSet) unlock(id string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	delete(l.uploads, id)
+}
+
+// FileInfo is the file or directory in the container, ArchiveSize is the length of the download in the tar format,
+// which is used to report the progress of the download.
+type FileInfo struct {
+	Path        string `json:"path"`
+	Directory   bool   `json:"directory"`
+	ArchiveSize int64  `json:"archiveSize"`
+}
+
+// upload is the state of a resumable upload. It's encoded in the upload ID, and the uploaded chunks are appended
+// to a hidden part file next to the target file in the container, so any replica of the apiserver can resume it.
+type upload struct {
+	Container string `json:"c,omitempty"`
+	Dir       string `json:"d"`
+	Name      string `json:"n"`
+	Length    int64  `json:"l"`
+	Nonce     string `json:"r"`
+}
+
+func (u *upload) id() string {
+	data, _ := json.Marshal(u)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func (u *upload) partPath() string {
+	return path.Join(u.Dir, fmt.Sprintf(".%s.%s.part", u.Name, u.Nonce))
+}
+
+func (u *upload) targetPath() string {
+	return path.Join(u.Dir, u.Name)
+}
+
+func parseUploadID(id string) (*upload, error) {
+	data, err := base64.RawURLEncoding.DecodeString(id)
+	if err != nil {
+		return nil, errUploadNotFound
+	}
+	u := &upload{}
+	if err = json.Unmarshal(data, u); err != nil || u.Nonce == "" || !validFileName(u.Name) || !path.IsAbs(u.Dir) {
+		return nil, errUploadNotFound
+	}
+	return u, nil
+}
+
+func validFileName(name string) bool {
+	return name != "" && name != "." && name != ".." && !strings.Contains(name, "/")
+}
+
+// parseUploadMetadata parses the Upload-Metadata header, which is a comma separated list of
+// the keys and the base64 encoded values.
+func parseUploadMetadata(header string) (map[string]string, error) {
+	metadata := make(map[string]string)
+	for _, pair := range strings.Split(header, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		key, encoded, _ := strings.Cut(pair, " ")
+		value, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("invalid upload metadata %s: %s", key, err)
+		}
+		metadata[key] = string(value)
+	}
+	return metadata, nil
+}
+
+// parseRange parses the single byte range of the Range header, it returns the start and the exclusive end of the range,
+// and whether the range is partial. Multiple ranges are not supported, the whole content is returned instead.
+func parseRange(header string, size uint64) (uint64, uint64, bool, error) {
+	spec, found := strings.CutPrefix(header, "bytes=")
+	if !found || strings.Contains(spec, ",") {
+		return 0, size, false, nil
+	}
+	first, last, found := strings.Cut(strings.TrimSpace(spec), "-")
+	if !found {
+		return 0, 0, false, fmt.Errorf("invalid range %s", header)
+	}
+	if first == "" {
+		// the suffix range of the last n bytes
+		n, err := strconv.ParseUint(last, 10, 64)
+		if err != nil || n == 0 || size == 0 {
+			return 0, 0, false, fmt.Errorf("invalid range %s", header)
+		}
+		if n > size {
+			n = size
+		}
+		return size - n, size, true, nil
+	}
+	start, err := strconv.ParseUint(first, 10, 64)
+	if err != nil || start >= size {
+		return 0, 0, false, fmt.Errorf("range %s is not satisfiable", header)
+	}
+	end := size
+	if last != "" {
+		n, err := strconv.ParseUint(last, 10, 64)
+		if err != nil || n < start {
+			return 0, 0, false, fmt.Errorf("invalid range %s", header)
+		}
+		if n+1 < size {
+			end = n + 1
+		}
+	}
+	return start, end, true, nil
+}
+
+// archiveCachePath returns the path of the cached archive identified by the ETag, which is returned by
+// the previous download and sent back in the If-Range header to resume it.
+func archiveCachePath(etag string) (string, bool) {
+	nonce := strings.Trim(etag, `"`)
+	if len(nonce) != 32 || strings.Trim(nonce, "0123456789abcdef") != "" {
+		return "", false
+	}
+	return path.Join(archiveCacheDir, archiveCachePrefix+nonce+".tar"), true
+}
+
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// execInPod runs the shell command in the container, the stdin and the stdout are optional.
+func (h *handler) execInPod(ctx context.Context, namespace, pod, container, command string, stdin io.Reader, stdout io.Writer) error {
+	req := h.client.CoreV1().RESTClient().Post().
+		Resource("pods").
+		Name(pod).
+		Namespace(namespace).
+		SubResource("exec").
+		VersionedParams(&corev1.PodExecOptions{
+			Container: container,
+			Command:   []string{"sh", "-c", command},
+			Stdin:     stdin != nil,
+			Stdout:    stdout != nil,
+			Stderr:    true,
+			TTY:       false,
+		}, scheme.ParameterCodec)
+
+	exec, err := remotecommand.NewSPDYExecutor(h.config, "POST", req.URL())
+	if err != nil {
+		return err
+	}
+	stderr := &bytes.Buffer{}
+	if err = exec.StreamWithContext(ctx, remotecommand.StreamOptions{
+		Stdin:  stdin,
+		Stdout: stdout,
+		Stderr: stderr,
+	}); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return fmt.Errorf("%w: %s", err, msg)
+		}
+		return err
+	}
+	return nil
+}
+
+// exitStatus returns the exit status of the command if it exits with a non-zero status.
+func exitStatus(err error) (int, bool) {
+	var exitErr interface{ ExitStatus() int }
+	if errors.As(err, &exitErr) {
+		return exitErr.ExitStatus(), true
+	}
+	return 0, false
+}
+
+func (h *handler) execOutput(ctx context.Context, namespace, pod, container, command string) (string, error) {
+	stdout := &bytes.Buffer{}
+	if err := h.execInPod(ctx, namespace, pod, container, command, nil, stdout); err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(stdout.String()), nil
+}
+
+// downloadArchive streams the file or the whole directory as a tar.gz archive, the archive is not resumable.
+func (h *handler) downloadArchive(request *restful.Request, response *restful.Response, namespace, pod, container, filePath string) {
+	dir, base := path.Split(path.Clean(filePath))
+	if dir == "" {
+		dir = "."
+	}
+	if base == "/" {
+		base = "."
+	}
+	response.AddHeader("Content-Disposition", fmt.Sprintf("attachment; filename=%s.tar.gz", path.Base(path.Clean(filePath))))
+	response.AddHeader("Content-Type", "application/gzip")
+	command := fmt.Sprintf("tar czf - -C %s %s", shellQuote(dir), shellQuote(base))
+	if err := h.execInPod(request.Request.Context(), namespace, pod, container, command, nil, response.ResponseWriter); err != nil {
+		klog.Warningf("failed to download %s from pod %s/%s: %s", filePath, namespace, pod, err)
+	}
+}
+
+func (h *handler) StatFile(request *restful.Request, response *restful.Response) {
+	filePath := request.QueryParameter("path")
+	if filePath == "" {
+		api.HandleBadRequest(response, request, errors.New("path is required"))
+		return
+	}
+	command := fmt.Sprintf("test -e %[1]s || exit 2; test -d %[1]s && echo d || echo f; tar cf - %[1]s 2>/dev/null | wc -c", shellQuote(filePath))
+	output, err := h.execOutput(request.Request.Context(), request.PathParameter("namespace"), request.PathParameter("pod"),
+		request.QueryParameter("container"), command)
+	if err != nil {
+		api.HandleNotFound(response, request, fmt.Errorf("failed to stat %s: %s", filePath, err))
+		return
+	}
+	fields := strings.Fields(output)
+	if len(fields) != 2 {
+		api.HandleInternalError(response, request, fmt.Errorf("unexpected output of stat %s: %s", filePath, output))
+		return
+	}
+	size, err := strconv.ParseInt(fields[1], 10, 64)
+	if err != nil {
+		api.HandleInternalError(response, request, err)
+		return
+	}
+	_ = response.WriteEntity(FileInfo{Path: filePath, Directory: fields[0] == "d", ArchiveSize: size})
+}
+
+// exceedsUploadLimit writes the error if the length of the upload exceeds the upload file limit.
+func (h *handler) exceedsUploadLimit(response *restful.Response, length int64) bool {
+	if h.uploadFileLimit > 0 && length > h.uploadFileLimit {
+		_ = response.WriteErrorString(http.StatusRequestEntityTooLarge,
+			fmt.Sprintf("the upload length %d exceeds the limit %d", length, h.uploadFileLimit))
+		return true
+	}
+	return false
+}
+
+// CreateUpload creates a resumable upload with the tus creation extension, the file name is required in the
+// Upload-Metadata header as "filename".
+func (h *handler) CreateUpload(request *restful.Request, response *restful.Response) {
+	response.AddHeader(headerTusResumable, tusVersion)
+	length, err := strconv.ParseInt(request.HeaderParameter(headerUploadLength), 10, 64)
+	if err != nil || length < 0 {
+		api.HandleBadRequest(response, request, fmt.Errorf("invalid %s", headerUploadLength))
+		return
+	}
+	if h.exceedsUploadLimit(response, length) {
+		return
+	}
+	metadata, err := parseUploadMetadata(request.HeaderParameter(headerUploadMetadata))
+	if err != nil {
+		api.HandleBadRequest(response, request, err)
+		return
+	}
+	name := metadata["filename"]
+	if !validFileName(name) {
+		api.HandleBadRequest(response, request, fmt.Errorf("invalid file name %q", name))
+		return
+	}
+	dir := request.QueryParameter("path")
+	if dir == "" {
+		dir = "/"
+	}
+	u := &upload{
+		Container: request.QueryParameter("container"),
+		Dir:       path.Clean(dir),
+		Name:      name,
+		Length:    length,
+		Nonce:     strings.ReplaceAll(uuid.New().String(), "-", ""),
+	}
+	if !path.IsAbs(u.Dir) {
+		api.HandleBadRequest(response, request, fmt.Errorf("path %s must be absolute", dir))
+		return
+	}
+
+	namespace := request.PathParameter("namespace")
+	pod := request.PathParameter("pod")
+	command := fmt.Sprintf("mkdir -p %s && : > %s", shellQuote(u.Dir), shellQuote(u.partPath()))
+	if _, err = h.execOutput(request.Request.Context(), namespace, pod, u.Container, command); err != nil {
+		api.HandleInternalError(response, request, err)
+		return
+	}
+	// the empty file is completed on creation
+	if length == 0 {
+		if err = h.completeUpload(request.Request.Context(), namespace, pod, u); err != nil {
+			api.HandleInternalError(response, request, err)
+			return
+		}
+	}
+	response.AddHeader("Location", strings.TrimSuffix(request.Request.URL.Path, "/")+"/"+u.id())
+	response.AddHeader(headerUploadOffset, "0")
+	response.WriteHeader(http.StatusCreated)
+}
+
+// uploadOffset returns the size of the uploaded part file, errUploadNotFound is returned if the part file
+// does not exist.
+func (h *handler) uploadOffset(ctx context.Context, namespace, pod string, u *upload) (int64, error) {
+	command := fmt.Sprintf("test -f %[1]s || exit %[2]d; wc -c < %[1]s", shellQuote(u.partPath()), exitUploadNotFound)
+	output, err := h.execOutput(ctx, namespace, pod, u.Container, command)
+	if err != nil {
+		if status, ok := exitStatus(err); ok && status == exitUploadNotFound {
+			return 0, errUploadNotFound
+		}
+		return 0, err
+	}
+	return strconv.ParseInt(output, 10, 64)
+}
+
+func (h *handler) completeUpload(ctx context.Context, namespace, pod string, u *upload) error {
+	command := fmt.Sprintf("mv -f %s %s", shellQuote(u.partPath()), shellQuote(u.targetPath()))
+	_, err := h.execOutput(ctx, namespace, pod, u.Container, command)
+	return err
+}
+
+func (h *handler) handleUploadError(response *restful.Response, request *restful.Request, err error) {
+	if errors.Is(err, errUploadNotFound) {
+		api.HandleNotFound(response, request, err)
+		return
+	}
+	api.HandleInternalError(response, request, err)
+}
+
+// UploadOffset returns the offset of the upload, which is used to resume the upload and report the progress.
+func (h *handler) UploadOffset(request *restful.Request, response *restful.Response) {
+	response.AddHeader(headerTusResumable, tusVersion)
+	response.AddHeader("Cache-Control", "no-store")
+	u, err := parseUploadID(request.PathParameter("upload"))
+	if err != nil {
+		api.HandleNotFound(response, request, err)
+		return
+	}
+	offset, err := h.uploadOffset(request.Request.Context(), request.PathParameter("namespace"), request.PathParameter("pod"), u)
+	if err != nil {
+		h.handleUploadError(response, request, err)
+		return
+	}
+	response.AddHeader(headerUploadOffset, strconv.FormatInt(offset, 10))
+	response.AddHeader(headerUploadLength, strconv.FormatInt(u.Length, 10))
+	response.WriteHeader(http.StatusOK)
+}
+
+// UploadChunk appends the chunk at the offset of the upload, the file is moved to the target path
+// once all the bytes are uploaded.
+func (h *handler) UploadChunk(request *restful.Request, response *restful.Response) {
+	response.AddHeader(headerTusResumable, tusVersion)
+	u, err := parseUploadID(request.PathParameter("upload"))
+	if err != nil {
+		api.HandleNotFound(response, request, err)
+		return
+	}
+	offset, err := strconv.ParseInt(request.HeaderParameter(headerUploadOffset), 10, 64)
+	if err != nil {
+		api.HandleBadRequest(response, request, fmt.Errorf("invalid %s", headerUploadOffset))
+		return
+	}
+	// the upload ID is not trusted, the length is checked again
+	if h.exceedsUploadLimit(response, u.Length) {
+		return
+	}
+	if !uploadLocks.tryLock(u.Nonce) {
+		api.HandleConflict(response, request, errors.New("another chunk of the upload is in progress"))
+		return
+	}
+	defer uploadLocks.unlock(u.Nonce)
+
+	ctx := request.Request.Context()
+	namespace := request.PathParameter("namespace")
+	pod := request.PathParameter("pod")
+	current, err := h.uploadOffset(ctx, namespace, pod, u)
+	if err != nil {
+		h.handleUploadError(response, request, err)
+		return
+	}
+	if offset != current {
+		api.HandleConflict(response, request, fmt.Errorf("the offset %d does not match the uploaded size %d", offset, current))
+		return
+	}
+
+	// a chunk never exceeds the upload length, and it's appended only if the offset is not changed
+	body := io.LimitReader(request.Request.Body, u.Length-offset)
+	command := fmt.Sprintf(`test "$(wc -c < %[1]s)" -eq %[2]d || exit %[3]d; cat >> %[1]s`,
+		shellQuote(u.partPath()), offset, exitOffsetMismatched)
+	if err = h.execInPod(ctx, namespace, pod, u.Container, command, body, nil); err != nil {
+		if status, ok := exitStatus(err); ok && status == exitOffsetMismatched {
+			api.HandleConflict(response, request, fmt.Errorf("the offset %d does not match the uploaded size", offset))
+			return
+		}
+		klog.Warningf("failed to upload the chunk of %s to pod %s/%s: %s", u.targetPath(), namespace, pod, err)
+	}
+	// the uploaded size is checked again, the interrupted chunk is resumed from there
+	if current, err = h.uploadOffset(ctx, namespace, pod, u); err != nil {
+		h.handleUploadError(response, request, err)
+		return
+	}
+	if current == u.Length {
+		if err = h.completeUpload(ctx, namespace, pod, u); err != nil {
+			api.HandleInternalError(response, request, err)
+			return
+		}
+	}
+	response.AddHeader(headerUploadOffset, strconv.FormatInt(current, 10))
+	response.WriteHeader(http.StatusNoContent)
+}
+
+// TerminateUpload deletes the uploaded part file with the tus termination extension.
+func (h *handler) TerminateUpload(request *restful.Request, response *restful.Response) {
+	response.AddHeader(headerTusResumable, tusVersion)
+	u, err := parseUploadID(request.PathParameter("upload"))
+	if err != nil {
+		api.HandleNotFound(response, request, err)
+		return
+	}
+	command := fmt.Sprintf("rm -f %s", shellQuote(u.partPath()))
+	if _, err = h.execOutput(request.Request.Context(), request.PathParameter("namespace"), request.PathParameter("pod"), u.Container, command); err != nil {
+		api.HandleInternalError(response, request, err)
+		return
+	}
+	response.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,95 @@
+/*
+ * Copyright 2024 the KubeSphere Authors.
+ * Please refer to the LICENSE file in the root directory of the project.
+ * https://github.com/kubesphere/kubesphere/blob/master/LICENSE
+ */
+
+package v1alpha2
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseRange(t *testing.T) {
+	tests := []struct {
+		header  string
+		start   uint64
+		end     uint64
+		partial bool
+		wantErr bool
+	}{
+		{header: "", start: 0, end: 100},
+		{header: "bytes=0-", start: 0, end: 100, partial: true},
+		{header: "bytes=10-19", start: 10, end: 20, partial: true},
+		{header: "bytes=90-200", start: 90, end: 100, partial: true},
+		{header: "bytes=-10", start: 90, end: 100, partial: true},
+		{header: "bytes=0-1,5-6", start: 0, end: 100},
+		{header: "bytes=100-", wantErr: true},
+		{header: "bytes=20-10", wantErr: true},
+		{header: "bytes=abc", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.header, func(t *testing.T) {
+			start, end, partial, err := parseRange(tt.header, 100)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.start, start)
+			assert.Equal(t, tt.end, end)
+			assert.Equal(t, tt.partial, partial)
+		})
+	}
+}
+
+func TestParseUploadMetadata(t *testing.T) {
+	metadata, err := parseUploadMetadata("filename Y29yZS5kdW1w, is_confidential")
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"filename": "core.dump", "is_confidential": ""}, metadata)
+
+	_, err = parseUploadMetadata("filename !!!")
+	assert.Error(t, err)
+}
+
+func TestUploadID(t *testing.T) {
+	u := &upload{Container: "app", Dir: "/tmp", Name: "core.dump", Length: 1 << 30, Nonce: "abc"}
+	parsed, err := parseUploadID(u.id())
+	assert.NoError(t, err)
+	assert.Equal(t, u, parsed)
+	assert.Equal(t, "/tmp/.core.dump.abc.part", parsed.partPath())
+	assert.Equal(t, "/tmp/core.dump", parsed.targetPath())
+
+	forged := &upload{Dir: "/tmp", Name: "../etc/passwd", Nonce: "abc"}
+	_, err = parseUploadID(forged.id())
+	assert.ErrorIs(t, err, errUploadNotFound)
+	_, err = parseUploadID("not-an-id")
+	assert.ErrorIs(t, err, errUploadNotFound)
+}
+
+func TestShellQuote(t *testing.T) {
+	assert.Equal(t, `'/tmp/a b'`, shellQuote("/tmp/a b"))
+	assert.Equal(t, `'it'\''s'`, shellQuote("it's"))
+}
+
+func TestArchiveCachePath(t *testing.T) {
+	cachePath, ok := archiveCachePath(`"0123456789abcdef0123456789abcdef"`)
+	assert.True(t, ok)
+	assert.Equal(t, "/tmp/.kubesphere-download-0123456789abcdef0123456789abcdef.tar", cachePath)
+
+	for _, etag := range []string{"", `"abc"`, `"../../etc/passwd0123456789abcdef"`, "W/\"0123456789abcdef0123456789abcdef\""} {
+		_, ok = archiveCachePath(etag)
+		assert.False(t, ok, etag)
+	}
+}
+
+func TestUploadLockSet(t *testing.T) {
+	locks := &uploadLockSet{uploads: map[string]struct{}{}}
+	assert.True(t, locks.tryLock("u1"))
+	assert.False(t, locks.tryLock("u1"), "the concurrent chunk should be rejected")
+	assert.True(t, locks.tryLock("u2"))
+	locks.unlock("u1")
+	assert.True(t, locks.tryLock("u1"))
+}
@@ -46,6 +46,14 @@ type OAuthAuthenticator interface {
 	Authenticate(ctx context.Context, provider string, req *http.Request) (authuser.Info, error)
 }
 
+// UserGroups returns the groups attached to the authenticated user, which are the groups the user is bound to,
+// including the groups of the workspaces, and system:authenticated.
+func UserGroups(user *iamv1beta1.User) []string {
+	groups := make([]string, 0, len(user.Spec.Groups)+1)
+	groups = append(groups, user.Spec.Groups...)
+	return append(groups, authuser.AllAuthenticated)
+}
+
 func newRreRegistrationUser(idp string, identity identityprovider.Identity) authuser.Info {
 	return &authuser.DefaultInfo{
 		Name: iamv1beta1.PreRegistrationUser,
@@ -9,6 +9,7 @@ package auth
 import (
 	"context"
 	"fmt"
+	"time"
 
 	"golang.org/x/crypto/bcrypt"
 	"k8s.io/apimachinery/pkg/api/errors"
@@ -84,6 +85,13 @@ func (p *passwordAuthenticator) authByKubeSphere(ctx context.Context, username,
 		}
 	}
 
+	if PasswordChangeRequired(&user) {
+		if info.Extra == nil {
+			info.Extra = make(map[string][]string)
+		}
+		info.Extra[iamv1beta1.ExtraPasswordChangeRequired] = []string{"true"}
+	}
+
 	return info, nil
 }
 
@@ -110,6 +118,12 @@ func (p *passwordAuthenticator) authByProvider(ctx context.Context, provider, us
 	return authByIdentityProvider(ctx, p.client, p.userMapper, providerConfig, identity)
 }
 
+// PasswordChangeRequired returns whether the user must change the password, e.g. it has expired.
+func PasswordChangeRequired(user *iamv1beta1.User) bool {
+	return user.Status.PasswordChangeRequired ||
+		(user.Status.PasswordExpirationTime != nil && time.Now().After(user.Status.PasswordExpirationTime.Time))
+}
+
 func PasswordVerify(encryptedPassword, password string) error {
 	if err := bcrypt.CompareHashAndPassword([]byte(encryptedPassword), []byte(password)); err != nil {
 		return IncorrectPasswordError
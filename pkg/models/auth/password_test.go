@@ -10,6 +10,7 @@ import (
 	"context"
 	"reflect"
 	"testing"
+	"time"
 
 	"github.com/mitchellh/mapstructure"
 	"golang.org/x/crypto/bcrypt"
@@ -42,6 +43,22 @@ func TestEncryptPassword(t *testing.T) {
 	}
 }
 
+func TestPasswordChangeRequired(t *testing.T) {
+	u := &iamv1beta1.User{}
+	if PasswordChangeRequired(u) {
+		t.Fatal("the password change is not required")
+	}
+	u.Status.PasswordExpirationTime = &metav1.Time{Time: time.Now().Add(-time.Minute)}
+	if !PasswordChangeRequired(u) {
+		t.Fatal("the expired password must be changed")
+	}
+	u.Status.PasswordExpirationTime = &metav1.Time{Time: time.Now().Add(time.Hour)}
+	u.Status.PasswordChangeRequired = true
+	if !PasswordChangeRequired(u) {
+		t.Fatal("the password change is required")
+	}
+}
+
 func hashPassword(password string) (string, error) {
 	bytes, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.MinCost)
 	return string(bytes), err
@@ -70,6 +70,8 @@ func (t *tokenOperator) Verify(tokenStr string) (*token.VerifiedResponse, error)
 	if err != nil {
 		return nil, err
 	}
+	// the static tokens are not cached, the tokens of the service accounts are
+	// validated against the revocation list of the secrets by the authenticator
 	if t.options.Issuer.AccessTokenMaxAge == 0 ||
 		response.TokenType == token.StaticToken {
 		return response, nil
@@ -0,0 +1,414 @@
+/*
+ * Copyright 2024 the KubeSphere Authors.
+ * Please refer to the LICENSE file in the root directory of the project.
+ * https://github.com/kubesphere/kubesphere/blob/master/LICENSE
+ */
+
+package extension
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+	helmrepo "helm.sh/helm/v3/pkg/repo"
+	corev1alpha1 "kubesphere.io/api/core/v1alpha1"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	// BundleIndexFile is the helm repository index of the bundle.
+	BundleIndexFile = "index.yaml"
+	// BundleSignatureFile contains the base64 encoded signature of the sha256 digest of the index.
+	BundleSignatureFile = "index.yaml.sig"
+	// BundleImagesFile is an optional list of images required by the charts, one image per line.
+	BundleImagesFile = "images.txt"
+
+	ociLayoutFile         = "oci-layout"
+	ociIndexFile          = "index.json"
+	ociBlobsDir           = "blobs"
+	ociTitleAnnotation    = "org.opencontainers.image.title"
+	sha256DigestAlgorithm = "sha256"
+	// The bundle is loaded into memory, and the charts are imported into ConfigMaps
+	// which are limited to 1MiB, so the bundle does not need to be large.
+	maxBundleFileSize  = 16 << 20
+	maxBundleTotalSize = 128 << 20
+)
+
+var errBundleTooLarge = fmt.Errorf("bundle exceeds %dMiB", maxBundleTotalSize>>20)
+
+// Bundle is an offline extension repository.
+// A bundle is either a (gzipped) tarball, or an OCI image layout whose layers are annotated with
+// org.opencontainers.image.title, and contains:
+//
+//	index.yaml      the helm repository index, chart URLs are paths relative to the bundle root
+//	index.yaml.sig  optional signature of the index
+//	images.txt      optional list of images
+//	charts/*.tgz    chart archives referenced by the index
+type Bundle struct {
+	Format    corev1alpha1.BundleFormat
+	Digest    string
+	Index     *helmrepo.IndexFile
+	IndexData []byte
+	Signature []byte
+	Images    []string
+	// Charts are indexed by the path in the bundle.
+	Charts map[string][]byte
+}
+
+type ociIndex struct {
+	Manifests []ociDescriptor `json:"manifests"`
+}
+
+type ociManifest struct {
+	Layers []ociDescriptor `json:"layers"`
+}
+
+type ociDescriptor struct {
+	MediaType   string            `json:"mediaType"`
+	Digest      string            `json:"digest"`
+	Size        int64             `json:"size"`
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
+// LoadBundleFromPath loads a bundle from a tarball file or an OCI image layout directory.
+func LoadBundleFromPath(bundlePath string, format corev1alpha1.BundleFormat) (*Bundle, error) {
+	info, err := os.Stat(bundlePath)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to stat bundle %s", bundlePath)
+	}
+	if info.IsDir() {
+		if format == corev1alpha1.BundleFormatTarball {
+			return nil, fmt.Errorf("bundle %s is a directory, but a tarball is expected", bundlePath)
+		}
+		return loadOCILayout(func(name string) ([]byte, error) {
+			return readFileWithLimit(filepath.Join(bundlePath, filepath.FromSlash(name)))
+		})
+	}
+	file, err := os.Open(bundlePath)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to open bundle %s", bundlePath)
+	}
+	defer file.Close()
+	return LoadBundle(file, format)
+}
+
+// LoadBundle loads a bundle from a tarball, the tarball can contain an OCI image layout.
+func LoadBundle(reader io.Reader, format corev1alpha1.BundleFormat) (*Bundle, error) {
+	hash := sha256.New()
+	files, err := readTarball(io.TeeReader(&bundleReader{reader: reader}, hash))
+	if errors.Is(err, errBundleTooLarge) {
+		return nil, errBundleTooLarge
+	}
+	if err != nil {
+		return nil, err
+	}
+	digest := fmt.Sprintf("%s:%s", sha256DigestAlgorithm, hex.EncodeToString(hash.Sum(nil)))
+
+	readFile := func(name string) ([]byte, error) {
+		data, ok := files[name]
+		if !ok {
+			return nil, os.ErrNotExist
+		}
+		return data, nil
+	}
+
+	var bundle *Bundle
+	_, isOCILayout := files[ociLayoutFile]
+	if format == corev1alpha1.BundleFormatOCILayout || (format == "" && isOCILayout) {
+		bundle, err = loadOCILayout(readFile)
+	} else {
+		bundle, err = loadBundleFiles(readFile)
+		if bundle != nil {
+			bundle.Format = corev1alpha1.BundleFormatTarball
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	bundle.Digest = digest
+	return bundle, nil
+}
+
+// bundleReader fails with errBundleTooLarge once more than maxBundleTotalSize bytes are read.
+type bundleReader struct {
+	reader io.Reader
+	read   int64
+}
+
+func (r *bundleReader) Read(p []byte) (int, error) {
+	n, err := r.reader.Read(p)
+	r.read += int64(n)
+	if r.read > maxBundleTotalSize {
+		return n, errBundleTooLarge
+	}
+	return n, err
+}
+
+func readTarball(reader io.Reader) (map[string][]byte, error) {
+	bufferedReader := bufio.NewReader(reader)
+	var tarReader *tar.Reader
+	// gzip magic number
+	if magic, err := bufferedReader.Peek(2); err == nil && magic[0] == 0x1f && magic[1] == 0x8b {
+		gzipReader, err := gzip.NewReader(bufferedReader)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to read gzipped bundle")
+		}
+		defer gzipReader.Close()
+		tarReader = tar.NewReader(gzipReader)
+	} else {
+		tarReader = tar.NewReader(bufferedReader)
+	}
+
+	files := make(map[string][]byte)
+	var total int64
+	for {
+		header, err := tarReader.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to read bundle")
+		}
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+		name := path.Clean(strings.TrimPrefix(header.Name, "./"))
+		if strings.HasPrefix(name, "../") || path.IsAbs(name) {
+			return nil, fmt.Errorf("invalid file path %s in bundle", header.Name)
+		}
+		if header.Size > maxBundleFileSize {
+			return nil, fmt.Errorf("file %s in bundle exceeds the size limit %d", name, maxBundleFileSize)
+		}
+		total += header.Size
+		if total > maxBundleTotalSize {
+			return nil, errBundleTooLarge
+		}
+		data, err := io.ReadAll(tarReader)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to read %s from bundle", name)
+		}
+		files[name] = data
+	}
+	return files, nil
+}
+
+func readFileWithLimit(name string) ([]byte, error) {
+	info, err := os.Stat(name)
+	if err != nil {
+		return nil, err
+	}
+	if info.Size() > maxBundleFileSize {
+		return nil, fmt.Errorf("file %s exceeds the size limit %d", name, maxBundleFileSize)
+	}
+	return os.ReadFile(name)
+}
+
+// loadOCILayout maps the layers of the manifests in the OCI image layout to bundle files by their titles.
+func loadOCILayout(readFile func(name string) ([]byte, error)) (*Bundle, error) {
+	indexData, err := readFile(ociIndexFile)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read %s of OCI image layout", ociIndexFile)
+	}
+	index := &ociIndex{}
+	if err := json.Unmarshal(indexData, index); err != nil {
+		return nil, errors.Wrapf(err, "failed to parse %s of OCI image layout", ociIndexFile)
+	}
+
+	readBlob := func(descriptor ociDescriptor) ([]byte, error) {
+		algorithm, encoded, ok := strings.Cut(descriptor.Digest, ":")
+		if !ok || algorithm != sha256DigestAlgorithm || strings.ContainsAny(encoded, "/\\.") {
+			return nil, fmt.Errorf("unsupported digest %s", descriptor.Digest)
+		}
+		data, err := readFile(path.Join(ociBlobsDir, algorithm, encoded))
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to read blob %s", descriptor.Digest)
+		}
+		sum := sha256.Sum256(data)
+		if hex.EncodeToString(sum[:]) != encoded {
+			return nil, fmt.Errorf("digest mismatch for blob %s", descriptor.Digest)
+		}
+		return data, nil
+	}
+
+	files := make(map[string][]byte)
+	for _, descriptor := range index.Manifests {
+		manifestData, err := readBlob(descriptor)
+		if err != nil {
+			return nil, err
+		}
+		manifest := &ociManifest{}
+		if err := json.Unmarshal(manifestData, manifest); err != nil {
+			return nil, errors.Wrapf(err, "failed to parse manifest %s", descriptor.Digest)
+		}
+		for _, layer := range manifest.Layers {
+			title := layer.Annotations[ociTitleAnnotation]
+			if title == "" {
+				continue
+			}
+			data, err := readBlob(layer)
+			if err != nil {
+				return nil, err
+			}
+			files[path.Clean(title)] = data
+		}
+	}
+
+	bundle, err := loadBundleFiles(func(name string) ([]byte, error) {
+		data, ok := files[name]
+		if !ok {
+			return nil, os.ErrNotExist
+		}
+		return data, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(indexData)
+	bundle.Format = corev1alpha1.BundleFormatOCILayout
+	bundle.Digest = fmt.Sprintf("%s:%s", sha256DigestAlgorithm, hex.EncodeToString(sum[:]))
+	return bundle, nil
+}
+
+func loadBundleFiles(readFile func(name string) ([]byte, error)) (*Bundle, error) {
+	indexData, err := readFile(BundleIndexFile)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read %s from bundle", BundleIndexFile)
+	}
+	index := &helmrepo.IndexFile{}
+	if err := yaml.Unmarshal(indexData, index); err != nil {
+		return nil, errors.Wrapf(err, "failed to parse %s", BundleIndexFile)
+	}
+	if index.APIVersion == "" {
+		return nil, helmrepo.ErrNoAPIVersion
+	}
+	index.SortEntries()
+
+	bundle := &Bundle{
+		Index:     index,
+		IndexData: indexData,
+		Charts:    make(map[string][]byte),
+	}
+
+	if signature, err := readFile(BundleSignatureFile); err == nil {
+		bundle.Signature = bytes.TrimSpace(signature)
+	}
+
+	if images, err := readFile(BundleImagesFile); err == nil {
+		for _, line := range strings.Split(string(images), "\n") {
+			line = strings.TrimSpace(line)
+			if line == "" || strings.HasPrefix(line, "#") {
+				continue
+			}
+			bundle.Images = append(bundle.Images, line)
+		}
+	}
+
+	for _, versions := range index.Entries {
+		for _, version := range versions {
+			chartPath, err := ChartPathInBundle(version)
+			if err != nil {
+				return nil, err
+			}
+			if _, ok := bundle.Charts[chartPath]; ok {
+				continue
+			}
+			data, err := readFile(chartPath)
+			if err != nil {
+				return nil, errors.Wrapf(err, "failed to read chart %s from bundle", chartPath)
+			}
+			if version.Digest != "" {
+				sum := sha256.Sum256(data)
+				if hex.EncodeToString(sum[:]) != strings.TrimPrefix(version.Digest, sha256DigestAlgorithm+":") {
+					return nil, fmt.Errorf("digest mismatch for chart %s", chartPath)
+				}
+			}
+			bundle.Charts[chartPath] = data
+		}
+	}
+	return bundle, nil
+}
+
+// ChartPathInBundle returns the path of the chart archive in the bundle.
+func ChartPathInBundle(version *helmrepo.ChartVersion) (string, error) {
+	if len(version.URLs) == 0 {
+		return "", fmt.Errorf("chart %s-%s has no URL", version.Name, version.Version)
+	}
+	chartPath := path.Clean(strings.TrimPrefix(version.URLs[0], "./"))
+	if strings.Contains(chartPath, "://") || strings.HasPrefix(chartPath, "../") || path.IsAbs(chartPath) {
+		return "", fmt.Errorf("chart URL %s of %s-%s should be a relative path in the bundle", version.URLs[0], version.Name, version.Version)
+	}
+	return chartPath, nil
+}
+
+// Verify checks the signature of the bundle index with the PEM encoded public key.
+// ECDSA and RSA (PKCS #1 v1.5) signatures are computed over the sha256 digest of the index,
+// Ed25519 signatures are computed over the index itself. Every chart in the signed index must have a digest.
+func (b *Bundle) Verify(publicKeyPEM string) error {
+	if len(b.Signature) == 0 {
+		return fmt.Errorf("bundle is not signed, %s is missing", BundleSignatureFile)
+	}
+	block, _ := pem.Decode([]byte(publicKeyPEM))
+	if block == nil {
+		return fmt.Errorf("failed to decode public key")
+	}
+	publicKey, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return errors.Wrapf(err, "failed to parse public key")
+	}
+	signature, err := base64.StdEncoding.DecodeString(string(b.Signature))
+	if err != nil {
+		return errors.Wrapf(err, "failed to decode signature")
+	}
+
+	digest := sha256.Sum256(b.IndexData)
+	switch key := publicKey.(type) {
+	case *ecdsa.PublicKey:
+		if !ecdsa.VerifyASN1(key, digest[:], signature) {
+			return fmt.Errorf("invalid bundle signature")
+		}
+	case *rsa.PublicKey:
+		if err := rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], signature); err != nil {
+			return errors.Wrapf(err, "invalid bundle signature")
+		}
+	case ed25519.PublicKey:
+		if !ed25519.Verify(key, b.IndexData, signature) {
+			return fmt.Errorf("invalid bundle signature")
+		}
+	default:
+		return fmt.Errorf("unsupported public key type %T", publicKey)
+	}
+
+	// the chart digests are checked when the bundle is loaded, the signed index must have a digest
+	// for every chart, otherwise the integrity of the charts is not covered by the signature.
+	index := &helmrepo.IndexFile{}
+	if err := yaml.Unmarshal(b.IndexData, index); err != nil {
+		return errors.Wrapf(err, "failed to parse %s", BundleIndexFile)
+	}
+	for _, versions := range index.Entries {
+		for _, version := range versions {
+			if version.Digest == "" {
+				return fmt.Errorf("chart %s-%s has no digest in the signed index", version.Name, version.Version)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,260 @@
+/*
+ * Copyright 2024 the KubeSphere Authors.
+ * Please refer to the LICENSE file in the root directory of the project.
+ * https://github.com/kubesphere/kubesphere/blob/master/LICENSE
+ */
+
+package extension
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	corev1alpha1 "kubesphere.io/api/core/v1alpha1"
+)
+
+var testChart = []byte("fake chart data")
+
+func testIndex(digest string) []byte {
+	return []byte(fmt.Sprintf(`apiVersion: v1
+entries:
+  devops:
+  - name: devops
+    version: 1.0.0
+    digest: %s
+    urls:
+    - charts/devops-1.0.0.tgz
+`, digest))
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func newTarball(t *testing.T, files map[string][]byte) []byte {
+	buf := &bytes.Buffer{}
+	gzipWriter := gzip.NewWriter(buf)
+	tarWriter := tar.NewWriter(gzipWriter)
+	for name, data := range files {
+		if err := tarWriter.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: int64(len(data)), Typeflag: tar.TypeReg}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tarWriter.Write(data); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tarWriter.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := gzipWriter.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func encodePublicKey(t *testing.T, publicKey any) string {
+	der, err := x509.MarshalPKIXPublicKey(publicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))
+}
+
+func TestLoadBundle(t *testing.T) {
+	index := testIndex(sha256Hex(testChart))
+	tests := []struct {
+		name    string
+		files   map[string][]byte
+		wantErr bool
+	}{
+		{
+			name: "valid bundle",
+			files: map[string][]byte{
+				BundleIndexFile:           index,
+				BundleImagesFile:          []byte("# images\nkubesphere/devops:v1.0.0\n\nkubesphere/jenkins:v1.0.0\n"),
+				"charts/devops-1.0.0.tgz": testChart,
+			},
+		},
+		{
+			name: "chart missing",
+			files: map[string][]byte{
+				BundleIndexFile: index,
+			},
+			wantErr: true,
+		},
+		{
+			name: "digest mismatch",
+			files: map[string][]byte{
+				BundleIndexFile:           index,
+				"charts/devops-1.0.0.tgz": []byte("tampered chart data"),
+			},
+			wantErr: true,
+		},
+		{
+			name: "remote chart URL",
+			files: map[string][]byte{
+				BundleIndexFile: []byte("apiVersion: v1\nentries:\n  devops:\n  - name: devops\n    version: 1.0.0\n    urls:\n    - https://charts.kubesphere.io/devops-1.0.0.tgz\n"),
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bundle, err := LoadBundle(bytes.NewReader(newTarball(t, tt.files)), "")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("LoadBundle() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if bundle.Format != corev1alpha1.BundleFormatTarball {
+				t.Errorf("unexpected format %s", bundle.Format)
+			}
+			if len(bundle.Images) != 2 {
+				t.Errorf("unexpected images %v", bundle.Images)
+			}
+			if !bytes.Equal(bundle.Charts["charts/devops-1.0.0.tgz"], testChart) {
+				t.Errorf("unexpected chart data")
+			}
+		})
+	}
+}
+
+func TestBundleReader(t *testing.T) {
+	zeros := bytes.NewReader(make([]byte, maxBundleTotalSize+1))
+	if _, err := io.Copy(io.Discard, &bundleReader{reader: io.LimitReader(zeros, maxBundleTotalSize)}); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	zeros.Reset(make([]byte, maxBundleTotalSize+1))
+	if _, err := io.Copy(io.Discard, &bundleReader{reader: zeros}); !errors.Is(err, errBundleTooLarge) {
+		t.Fatalf("unexpected error %v", err)
+	}
+}
+
+func TestLoadBundleFromOCILayout(t *testing.T) {
+	index := testIndex("")
+	dir := t.TempDir()
+	blobsDir := filepath.Join(dir, ociBlobsDir, sha256DigestAlgorithm)
+	if err := os.MkdirAll(blobsDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	writeBlob := func(data []byte) string {
+		encoded := sha256Hex(data)
+		if err := os.WriteFile(filepath.Join(blobsDir, encoded), data, 0644); err != nil {
+			t.Fatal(err)
+		}
+		return sha256DigestAlgorithm + ":" + encoded
+	}
+
+	manifest, _ := json.Marshal(ociManifest{Layers: []ociDescriptor{
+		{Digest: writeBlob(index), Annotations: map[string]string{ociTitleAnnotation: BundleIndexFile}},
+		{Digest: writeBlob(testChart), Annotations: map[string]string{ociTitleAnnotation: "charts/devops-1.0.0.tgz"}},
+	}})
+	ociIndexData, _ := json.Marshal(ociIndex{Manifests: []ociDescriptor{{Digest: writeBlob(manifest)}}})
+	if err := os.WriteFile(filepath.Join(dir, ociIndexFile), ociIndexData, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, ociLayoutFile), []byte(`{"imageLayoutVersion":"1.0.0"}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	bundle, err := LoadBundleFromPath(dir, "")
+	if err != nil {
+		t.Fatalf("LoadBundleFromPath() error = %v", err)
+	}
+	if bundle.Format != corev1alpha1.BundleFormatOCILayout {
+		t.Errorf("unexpected format %s", bundle.Format)
+	}
+	if !bytes.Equal(bundle.Charts["charts/devops-1.0.0.tgz"], testChart) {
+		t.Errorf("unexpected chart data")
+	}
+}
+
+func TestBundleVerify(t *testing.T) {
+	index := testIndex(sha256Hex(testChart))
+	digest := sha256.Sum256(index)
+	indexWithoutDigest := testIndex("")
+
+	ecdsaKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecdsaSignature, err := ecdsa.SignASN1(rand.Reader, ecdsaKey, digest[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	ed25519PublicKey, ed25519PrivateKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, anotherEd25519PrivateKey, _ := ed25519.GenerateKey(rand.Reader)
+
+	tests := []struct {
+		name      string
+		publicKey string
+		index     []byte
+		signature []byte
+		wantErr   bool
+	}{
+		{
+			name:      "ecdsa",
+			publicKey: encodePublicKey(t, &ecdsaKey.PublicKey),
+			signature: ecdsaSignature,
+		},
+		{
+			name:      "ed25519",
+			publicKey: encodePublicKey(t, ed25519PublicKey),
+			signature: ed25519.Sign(ed25519PrivateKey, index),
+		},
+		{
+			name:      "signed by another key",
+			publicKey: encodePublicKey(t, ed25519PublicKey),
+			signature: ed25519.Sign(anotherEd25519PrivateKey, index),
+			wantErr:   true,
+		},
+		{
+			name:      "unsigned",
+			publicKey: encodePublicKey(t, ed25519PublicKey),
+			wantErr:   true,
+		},
+		{
+			name:      "chart digest missing",
+			publicKey: encodePublicKey(t, ed25519PublicKey),
+			index:     indexWithoutDigest,
+			signature: ed25519.Sign(ed25519PrivateKey, indexWithoutDigest),
+			wantErr:   true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bundle := &Bundle{IndexData: index}
+			if tt.index != nil {
+				bundle.IndexData = tt.index
+			}
+			if tt.signature != nil {
+				bundle.Signature = []byte(base64.StdEncoding.EncodeToString(tt.signature))
+			}
+			if err := bundle.Verify(tt.publicKey); (err != nil) != tt.wantErr {
+				t.Errorf("Verify() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
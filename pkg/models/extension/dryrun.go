@@ -0,0 +1,160 @@
+/*
+ * Copyright 2024 the KubeSphere Authors.
+ * Please refer to the LICENSE file in the root directory of the project.
+ * https://github.com/kubesphere/kubesphere/blob/master/LICENSE
+ */
+
+package extension
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/pmezard/go-difflib/difflib"
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/releaseutil"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	// TagExtension is the tag of the subcharts installed in the host cluster.
+	TagExtension = "extension"
+	// TagAgent is the tag of the subcharts installed in the member clusters.
+	TagAgent = "agent"
+)
+
+// TagOverrides returns the overrides which enable the subcharts with the tag, and disable the subcharts of the other tag.
+func TagOverrides(mainChart *chart.Chart, tag string) []string {
+	disabled := TagAgent
+	if tag == TagAgent {
+		disabled = TagExtension
+	}
+	overrides := []string{
+		fmt.Sprintf("tags.%s=%s", tag, "true"),
+		fmt.Sprintf("tags.%s=%s", disabled, "false"),
+	}
+	for _, dependency := range mainChart.Metadata.Dependencies {
+		if dependency.Condition == "" {
+			continue
+		}
+		for _, t := range dependency.Tags {
+			if t == disabled {
+				for _, condition := range strings.Split(dependency.Condition, ",") {
+					overrides = append(overrides, fmt.Sprintf("%s=%s", condition, "false"))
+				}
+				break
+			}
+		}
+	}
+	return overrides
+}
+
+type ResourceChange string
+
+const (
+	ResourceAdded    ResourceChange = "Added"
+	ResourceRemoved  ResourceChange = "Removed"
+	ResourceModified ResourceChange = "Modified"
+)
+
+// ResourceDiff is the change of a resource in the release manifest.
+type ResourceDiff struct {
+	APIVersion string         `json:"apiVersion"`
+	Kind       string         `json:"kind"`
+	Namespace  string         `json:"namespace,omitempty"`
+	Name       string         `json:"name"`
+	Change     ResourceChange `json:"change"`
+	// Diff is the unified diff of the resource.
+	Diff string `json:"diff"`
+}
+
+// DryRunResult is the result of rendering the extension with the proposed config.
+type DryRunResult struct {
+	Cluster     string `json:"cluster,omitempty"`
+	ReleaseName string `json:"releaseName"`
+	Namespace   string `json:"namespace"`
+	// Installed is false if the release has not been installed, all resources are added.
+	Installed bool           `json:"installed"`
+	Manifest  string         `json:"manifest"`
+	Diffs     []ResourceDiff `json:"diffs"`
+}
+
+type manifestResource struct {
+	APIVersion string `json:"apiVersion"`
+	Kind       string `json:"kind"`
+	Metadata   struct {
+		Name      string `json:"name"`
+		Namespace string `json:"namespace"`
+	} `json:"metadata"`
+}
+
+func (r manifestResource) key() string {
+	return fmt.Sprintf("%s/%s/%s/%s", r.APIVersion, r.Kind, r.Metadata.Namespace, r.Metadata.Name)
+}
+
+type resourceManifest struct {
+	manifestResource
+	content string
+}
+
+func parseManifest(manifest string) (map[string]resourceManifest, error) {
+	resources := make(map[string]resourceManifest)
+	for _, content := range releaseutil.SplitManifests(manifest) {
+		var resource manifestResource
+		if err := yaml.Unmarshal([]byte(content), &resource); err != nil {
+			return nil, err
+		}
+		if resource.Kind == "" {
+			continue
+		}
+		resources[resource.key()] = resourceManifest{manifestResource: resource, content: strings.TrimSpace(content) + "\n"}
+	}
+	return resources, nil
+}
+
+// DiffManifests compares the resources of the manifests, the unchanged resources are omitted.
+func DiffManifests(current, proposed string) ([]ResourceDiff, error) {
+	currentResources, err := parseManifest(current)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse current manifest: %v", err)
+	}
+	proposedResources, err := parseManifest(proposed)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse proposed manifest: %v", err)
+	}
+
+	diffs := make([]ResourceDiff, 0)
+	for _, key := range sets.List(sets.KeySet(currentResources).Union(sets.KeySet(proposedResources))) {
+		from, existing := currentResources[key]
+		to, proposing := proposedResources[key]
+		if existing && proposing && from.content == to.content {
+			continue
+		}
+		diff := ResourceDiff{Change: ResourceModified}
+		resource := to.manifestResource
+		switch {
+		case !existing:
+			diff.Change = ResourceAdded
+		case !proposing:
+			diff.Change = ResourceRemoved
+			resource = from.manifestResource
+		}
+		diff.APIVersion = resource.APIVersion
+		diff.Kind = resource.Kind
+		diff.Namespace = resource.Metadata.Namespace
+		diff.Name = resource.Metadata.Name
+		diff.Diff, err = difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+			A:        difflib.SplitLines(from.content),
+			B:        difflib.SplitLines(to.content),
+			FromFile: "current",
+			ToFile:   "proposed",
+			Context:  3,
+		})
+		if err != nil {
+			return nil, err
+		}
+		diffs = append(diffs, diff)
+	}
+	return diffs, nil
+}
@@ -0,0 +1,120 @@
+/*
+ * Copyright 2024 the KubeSphere Authors.
+ * Please refer to the LICENSE file in the root directory of the project.
+ * https://github.com/kubesphere/kubesphere/blob/master/LICENSE
+ */
+
+package extension
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v3"
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/strvals"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clusterv1alpha1 "kubesphere.io/api/cluster/v1alpha1"
+)
+
+func TestTagOverrides(t *testing.T) {
+	mainChart := &chart.Chart{Metadata: &chart.Metadata{Dependencies: []*chart.Dependency{
+		{Name: "frontend", Tags: []string{TagExtension}, Condition: "frontend.enabled"},
+		{Name: "agent", Tags: []string{TagAgent}, Condition: "agent.enabled,global.agent.enabled"},
+	}}}
+	assert.Equal(t, []string{"tags.extension=true", "tags.agent=false", "agent.enabled=false", "global.agent.enabled=false"},
+		TagOverrides(mainChart, TagExtension))
+	assert.Equal(t, []string{"tags.agent=true", "tags.extension=false", "frontend.enabled=false"},
+		TagOverrides(mainChart, TagAgent))
+}
+
+func TestOverrides(t *testing.T) {
+	mainChart := &chart.Chart{Metadata: &chart.Metadata{}}
+	extensionOptions := &Options{
+		ImageRegistry: "registry.local",
+		NodeSelector:  map[string]string{"kubernetes.io/os": "linux"},
+	}
+	vals := map[string]interface{}{}
+	for _, override := range Overrides(mainChart, TagExtension, nil, "https://ks.local", extensionOptions) {
+		assert.NoError(t, strvals.ParseInto(override, vals))
+	}
+	global := vals["global"].(map[string]interface{})
+	assert.Equal(t, "registry.local", global["imageRegistry"])
+	assert.Equal(t, map[string]interface{}{"kubernetes.io/os": "linux"}, global["nodeSelector"])
+	assert.Equal(t, map[string]interface{}{"url": "https://ks.local"}, global["portal"])
+
+	cluster := &clusterv1alpha1.Cluster{ObjectMeta: metav1.ObjectMeta{Name: "member"}}
+	assert.Contains(t, Overrides(mainChart, TagAgent, cluster, "https://ks.local", nil), "global.clusterInfo.name=member")
+	assert.NotContains(t, Overrides(mainChart, TagAgent, cluster, "https://ks.local", nil), "global.portal.url=https://ks.local")
+}
+
+func TestDiffManifests(t *testing.T) {
+	current := `---
+# Source: devops/templates/configmap.yaml
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: devops-config
+  namespace: extension-devops
+data:
+  replicas: "1"
+---
+# Source: devops/templates/service.yaml
+apiVersion: v1
+kind: Service
+metadata:
+  name: devops
+  namespace: extension-devops
+`
+	proposed := `---
+# Source: devops/templates/configmap.yaml
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: devops-config
+  namespace: extension-devops
+data:
+  replicas: "2"
+---
+# Source: devops/templates/deployment.yaml
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: devops
+  namespace: extension-devops
+`
+	diffs, err := DiffManifests(current, proposed)
+	assert.NoError(t, err)
+	assert.Len(t, diffs, 3)
+
+	changes := make(map[string]ResourceChange)
+	for _, diff := range diffs {
+		changes[diff.Kind] = diff.Change
+	}
+	assert.Equal(t, map[string]ResourceChange{
+		"ConfigMap":  ResourceModified,
+		"Service":    ResourceRemoved,
+		"Deployment": ResourceAdded,
+	}, changes)
+	for _, diff := range diffs {
+		if diff.Kind == "ConfigMap" {
+			assert.Contains(t, diff.Diff, `-  replicas: "1"`)
+			assert.Contains(t, diff.Diff, `+  replicas: "2"`)
+		}
+	}
+
+	diffs, err = DiffManifests(current, current)
+	assert.NoError(t, err)
+	assert.Empty(t, diffs)
+}
+
+func TestMergeConfig(t *testing.T) {
+	merged := MergeConfig("replicas: 1\nimage:\n  tag: v1\n  pullPolicy: Always\n", "image:\n  tag: v2\n")
+	vals := map[string]interface{}{}
+	assert.NoError(t, yaml.Unmarshal(merged, &vals))
+	assert.Equal(t, map[string]interface{}{
+		"replicas": 1,
+		"image":    map[string]interface{}{"tag": "v2", "pullPolicy": "Always"},
+	}, vals)
+	assert.Equal(t, []byte("replicas: 1"), MergeConfig("replicas: 1", ""))
+}
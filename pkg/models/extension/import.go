@@ -0,0 +1,139 @@
+/*
+ * Copyright 2024 the KubeSphere Authors.
+ * Please refer to the LICENSE file in the root directory of the project.
+ * https://github.com/kubesphere/kubesphere/blob/master/LICENSE
+ */
+
+package extension
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+	helmrepo "helm.sh/helm/v3/pkg/repo"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/sets"
+	corev1alpha1 "kubesphere.io/api/core/v1alpha1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/yaml"
+
+	"kubesphere.io/kubesphere/pkg/constants"
+	"kubesphere.io/kubesphere/pkg/utils/hashutil"
+)
+
+const (
+	BundleChartDataKey = "chart.tgz"
+	// BundleDataLabel marks the ConfigMaps which store the imported bundle data.
+	BundleDataLabel = "kubesphere.io/repository-bundle"
+
+	// maxConfigMapDataSize is the size limit of a ConfigMap, with some room for the metadata.
+	maxConfigMapDataSize = 1000 * 1024
+)
+
+// BundleIndexConfigMapName returns the name of the ConfigMap which stores the index of the imported bundle.
+func BundleIndexConfigMapName(repoName string) string {
+	return fmt.Sprintf("repository-%s-bundle", repoName)
+}
+
+// BundleChartConfigMapName returns the name of the ConfigMap which stores the chart data of the imported bundle.
+func BundleChartConfigMapName(repoName, chartPath string) string {
+	return fmt.Sprintf("repository-%s-chart-%s", repoName, hashutil.FNVString([]byte(chartPath)))
+}
+
+// BundleChartDataRef returns the reference of the chart data imported from the bundle.
+func BundleChartDataRef(repoName string, version *helmrepo.ChartVersion) (*corev1alpha1.ConfigMapKeyRef, error) {
+	chartPath, err := ChartPathInBundle(version)
+	if err != nil {
+		return nil, err
+	}
+	return &corev1alpha1.ConfigMapKeyRef{
+		ConfigMapKeySelector: corev1.ConfigMapKeySelector{
+			LocalObjectReference: corev1.LocalObjectReference{Name: BundleChartConfigMapName(repoName, chartPath)},
+			Key:                  BundleChartDataKey,
+		},
+		Namespace: constants.KubeSphereNamespace,
+	}, nil
+}
+
+// ImportBundle stores the index and charts of the bundle into ConfigMaps owned by the repository,
+// so that the bundle can be served by the repository controller like a remote repository.
+// Charts that no longer exist in the bundle will be removed.
+func ImportBundle(ctx context.Context, c client.Client, repo *corev1alpha1.Repository, bundle *Bundle) error {
+	if repo.Spec.Bundle != nil && repo.Spec.Bundle.PublicKey != "" {
+		if err := bundle.Verify(repo.Spec.Bundle.PublicKey); err != nil {
+			return err
+		}
+	}
+
+	chartConfigMaps := sets.New[string]()
+	for chartPath, data := range bundle.Charts {
+		if len(data) > maxConfigMapDataSize {
+			return fmt.Errorf("chart %s exceeds the size limit %d", chartPath, maxConfigMapDataSize)
+		}
+		name := BundleChartConfigMapName(repo.Name, chartPath)
+		chartConfigMaps.Insert(name)
+		if err := createOrUpdateBundleConfigMap(ctx, c, repo, name, nil, map[string][]byte{BundleChartDataKey: data}); err != nil {
+			return errors.Wrapf(err, "failed to import chart %s", chartPath)
+		}
+	}
+
+	indexName := BundleIndexConfigMapName(repo.Name)
+	data := map[string]string{
+		BundleIndexFile:  string(bundle.IndexData),
+		BundleImagesFile: strings.Join(bundle.Images, "\n"),
+	}
+	if err := createOrUpdateBundleConfigMap(ctx, c, repo, indexName, data, nil); err != nil {
+		return errors.Wrapf(err, "failed to import %s", BundleIndexFile)
+	}
+
+	configMaps := &corev1.ConfigMapList{}
+	if err := c.List(ctx, configMaps, client.InNamespace(constants.KubeSphereNamespace),
+		client.MatchingLabels{BundleDataLabel: repo.Name}); err != nil {
+		return errors.Wrapf(err, "failed to list bundle data")
+	}
+	for i := range configMaps.Items {
+		configMap := &configMaps.Items[i]
+		if configMap.Name == indexName || chartConfigMaps.Has(configMap.Name) {
+			continue
+		}
+		if err := c.Delete(ctx, configMap); err != nil && !apierrors.IsNotFound(err) {
+			return errors.Wrapf(err, "failed to delete stale bundle data %s", configMap.Name)
+		}
+	}
+	return nil
+}
+
+func createOrUpdateBundleConfigMap(ctx context.Context, c client.Client, repo *corev1alpha1.Repository, name string, data map[string]string, binaryData map[string][]byte) error {
+	configMap := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: constants.KubeSphereNamespace}}
+	_, err := controllerutil.CreateOrUpdate(ctx, c, configMap, func() error {
+		if configMap.Labels == nil {
+			configMap.Labels = make(map[string]string)
+		}
+		configMap.Labels[BundleDataLabel] = repo.Name
+		configMap.Labels[corev1alpha1.RepositoryReferenceLabel] = repo.Name
+		configMap.Data = data
+		configMap.BinaryData = binaryData
+		return controllerutil.SetOwnerReference(repo, configMap, c.Scheme())
+	})
+	return err
+}
+
+// LoadImportedBundleIndex loads the index of the bundle imported by ImportBundle.
+func LoadImportedBundleIndex(ctx context.Context, c client.Reader, repoName string) (*helmrepo.IndexFile, error) {
+	configMap := &corev1.ConfigMap{}
+	if err := c.Get(ctx, types.NamespacedName{Namespace: constants.KubeSphereNamespace, Name: BundleIndexConfigMapName(repoName)}, configMap); err != nil {
+		return nil, err
+	}
+	index := &helmrepo.IndexFile{}
+	if err := yaml.Unmarshal([]byte(configMap.Data[BundleIndexFile]), index); err != nil {
+		return nil, errors.Wrapf(err, "failed to parse %s", BundleIndexFile)
+	}
+	index.SortEntries()
+	return index, nil
+}
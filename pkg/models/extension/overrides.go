@@ -0,0 +1,67 @@
+/*
+ * Copyright 2024 the KubeSphere Authors.
+ * Please refer to the LICENSE file in the root directory of the project.
+ * https://github.com/kubesphere/kubesphere/blob/master/LICENSE
+ */
+
+package extension
+
+import (
+	"fmt"
+	"strings"
+
+	"helm.sh/helm/v3/pkg/chart"
+	clusterv1alpha1 "kubesphere.io/api/cluster/v1alpha1"
+)
+
+const (
+	globalExtensionIngressClassName    = "global.extension.ingress.ingressClassName"
+	globalExtensionIngressDomainSuffix = "global.extension.ingress.domainSuffix"
+	globalExtensionIngressHTTPPort     = "global.extension.ingress.httpPort"
+	globalExtensionIngressHTTPSPort    = "global.extension.ingress.httpsPort"
+	globalNodeSelector                 = "global.nodeSelector"
+	globalImageRegistry                = "global.imageRegistry"
+	globalClusterName                  = "global.clusterInfo.name"
+	globalClusterRole                  = "global.clusterInfo.role"
+	globalPortalURL                    = "global.portal.url"
+)
+
+// Overrides returns the overrides (in the format of "helm --set") which are applied when the subcharts with the tag
+// are installed, the cluster is only required for the agent. They are shared by the install plan controller and the
+// dry run API, so that the dry run renders the same values as the installation.
+func Overrides(mainChart *chart.Chart, tag string, cluster *clusterv1alpha1.Cluster, portalURL string, extensionOptions *Options) []string {
+	overrides := TagOverrides(mainChart, tag)
+	if tag == TagExtension && portalURL != "" {
+		overrides = append(overrides, fmt.Sprintf("%s=%s", globalPortalURL, portalURL))
+	}
+
+	if cluster != nil {
+		clusterRole := clusterv1alpha1.ClusterRoleMember
+		if _, ok := cluster.Labels[clusterv1alpha1.HostCluster]; ok {
+			clusterRole = clusterv1alpha1.ClusterRoleHost
+		}
+		overrides = append(overrides,
+			fmt.Sprintf("%s=%s", globalClusterName, cluster.Name),
+			fmt.Sprintf("%s=%s", globalClusterRole, clusterRole),
+		)
+	}
+
+	if extensionOptions != nil {
+		if extensionOptions.ImageRegistry != "" {
+			overrides = append(overrides, fmt.Sprintf("%s=%s", globalImageRegistry, extensionOptions.ImageRegistry))
+		}
+		for k, v := range extensionOptions.NodeSelector {
+			k = strings.ReplaceAll(k, ".", "\\.")
+			overrides = append(overrides, fmt.Sprintf("%s.%s=%s", globalNodeSelector, k, v))
+		}
+		if extensionOptions.Ingress != nil {
+			overrides = append(overrides,
+				fmt.Sprintf("%s=%s", globalExtensionIngressClassName, extensionOptions.Ingress.IngressClassName),
+				fmt.Sprintf("%s=%s", globalExtensionIngressDomainSuffix, extensionOptions.Ingress.DomainSuffix),
+				fmt.Sprintf("%s=%d", globalExtensionIngressHTTPPort, extensionOptions.Ingress.HTTPPort),
+				fmt.Sprintf("%s=%d", globalExtensionIngressHTTPSPort, extensionOptions.Ingress.HTTPSPort),
+			)
+		}
+	}
+	return overrides
+}
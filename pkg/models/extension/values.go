@@ -0,0 +1,74 @@
+/*
+ * Copyright 2024 the KubeSphere Authors.
+ * Please refer to the LICENSE file in the root directory of the project.
+ * https://github.com/kubesphere/kubesphere/blob/master/LICENSE
+ */
+
+package extension
+
+import (
+	"strings"
+
+	yaml3 "gopkg.in/yaml.v3"
+	"k8s.io/klog/v2"
+)
+
+// MergeConfig merges the override config of the cluster into the extension config, the values of the override are preferred.
+// It's shared by the install plan controller and the dry run API, so that both render the same values.
+func MergeConfig(config string, override string) []byte {
+	config = strings.TrimSpace(config)
+	override = strings.TrimSpace(override)
+
+	if config == "" && override == "" {
+		return []byte("")
+	}
+
+	if override == "" {
+		return []byte(config)
+	}
+
+	if config == "" {
+		return []byte(override)
+	}
+
+	baseConf := map[string]interface{}{}
+	if err := yaml3.Unmarshal([]byte(config), &baseConf); err != nil {
+		klog.Warningf("failed to unmarshal config: %v", err)
+	}
+
+	overrideConf := map[string]interface{}{}
+	if err := yaml3.Unmarshal([]byte(override), overrideConf); err != nil {
+		klog.Warningf("failed to unmarshal config: %v", err)
+	}
+
+	finalConf := mergeValues(baseConf, overrideConf)
+	data, _ := yaml3.Marshal(finalConf)
+	return data
+}
+
+// mergeValues will merge source and destination map, preferring values from the source map
+func mergeValues(dest map[string]interface{}, src map[string]interface{}) map[string]interface{} {
+	for k, v := range src {
+		// If the key doesn't exist already, then just set the key to that value
+		if _, exists := dest[k]; !exists {
+			dest[k] = v
+			continue
+		}
+		nextMap, ok := v.(map[string]interface{})
+		// If it isn't another map, overwrite the value
+		if !ok {
+			dest[k] = v
+			continue
+		}
+		// Edge case: If the key exists in the destination, but isn't a map
+		destMap, isMap := dest[k].(map[string]interface{})
+		// If the source map has a map for this key, prefer it
+		if !isMap {
+			dest[k] = v
+			continue
+		}
+		// If we got to this point, it is a map in both, so merge them
+		dest[k] = mergeValues(destMap, nextMap)
+	}
+	return dest
+}
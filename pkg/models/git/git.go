@@ -9,14 +9,41 @@ package git
 import (
 	"context"
 	"fmt"
+	"net"
+	"os"
+	"sort"
+	"strconv"
 
 	"github.com/go-git/go-git/v5"
 	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing/transport"
 	"github.com/go-git/go-git/v5/plumbing/transport/http"
+	"github.com/go-git/go-git/v5/plumbing/transport/ssh"
 	"github.com/go-git/go-git/v5/storage/memory"
+	"github.com/skeema/knownhosts"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/types"
 	runtimeclient "sigs.k8s.io/controller-runtime/pkg/client"
+
+	corev1alpha1 "kubesphere.io/api/core/v1alpha1"
+)
+
+const (
+	// SecretTypeGitToken is the type of the Secret which holds a bearer token in the "token" key for the git server.
+	SecretTypeGitToken corev1.SecretType = "git.kubesphere.io/token"
+	// SecretKeyToken is the key of the bearer token in the Secret, it is only read from the basic-auth Secrets
+	// and the Secrets of SecretTypeGitToken.
+	SecretKeyToken = "token"
+	// SecretKeyKnownHosts is the key of the known_hosts in the ssh-auth Secret, the host key of the server is
+	// verified against it.
+	SecretKeyKnownHosts = "known_hosts"
+	// SecretKeyPassphrase is the key of the passphrase of the encrypted private key in the ssh-auth Secret.
+	SecretKeyPassphrase = "passphrase"
+
+	defaultSSHUser = "git"
+	defaultSSHPort = 22
+	// listTimeout is the timeout in seconds to list the refs of the remote.
+	listTimeout = 30
 )
 
 type AuthInfo struct {
@@ -24,8 +51,15 @@ type AuthInfo struct {
 	SecretRef *corev1.SecretReference `json:"secretRef,omitempty" description:"auth secret reference"`
 }
 
+// Refs are the branches and tags of the remote repository.
+type Refs struct {
+	Branches []string `json:"branches" description:"branches of the repository"`
+	Tags     []string `json:"tags" description:"tags of the repository"`
+}
+
 type GitVerifier interface {
-	VerifyGitCredential(remoteUrl, namespace, secretName string) error
+	// VerifyGitCredential verifies the credential in the Secret by listing the branches and tags of the remote.
+	VerifyGitCredential(remoteUrl, namespace, secretName string) (*Refs, error)
 }
 
 type gitVerifier struct {
@@ -36,31 +70,134 @@ func NewGitVerifier(cacheReader runtimeclient.Reader) GitVerifier {
 	return &gitVerifier{cache: cacheReader}
 }
 
-func (c *gitVerifier) VerifyGitCredential(remoteUrl, namespace, secretName string) error {
-	var username, password string
+// credential is the credential to access the remote resolved from the Secret.
+type credential struct {
+	auth     transport.AuthMethod
+	caBundle []byte
+}
 
+func (c *gitVerifier) VerifyGitCredential(remoteUrl, namespace, secretName string) (*Refs, error) {
+	endpoint, err := transport.NewEndpoint(remoteUrl)
+	if err != nil {
+		return nil, err
+	}
+
+	cred := &credential{}
 	if len(secretName) > 0 {
 		secret := &corev1.Secret{}
 		if err := c.cache.Get(context.Background(),
 			types.NamespacedName{Namespace: namespace, Name: secretName}, secret); err != nil {
-			return err
+			return nil, err
 		}
-		usernameBytes, ok := secret.Data[corev1.BasicAuthUsernameKey]
-		if !ok {
-			return fmt.Errorf("could not get username in secret %s", secret.Name)
+		if cred, err = credentialFromSecret(secret, endpoint); err != nil {
+			return nil, err
 		}
-		passwordBytes, ok := secret.Data[corev1.BasicAuthPasswordKey]
-		if !ok {
-			return fmt.Errorf("could not get password in secret %s", secret.Name)
+	}
+
+	return c.listRefs(remoteUrl, cred)
+}
+
+// credentialFromSecret resolves the credential from the Secret. The ssh-auth Secrets are used with the ssh
+// remotes, the git token Secrets and the basic-auth Secrets with a token are used as bearer tokens, the others
+// are used as basic auth, both of them can only be used with the http(s) remotes. The service account token
+// Secrets are never sent to the remote. The CA bundle in the "ca.crt" key is used to verify the https remotes.
+func credentialFromSecret(secret *corev1.Secret, endpoint *transport.Endpoint) (*credential, error) {
+	if secret.Type == corev1.SecretTypeServiceAccountToken || secret.Type == corev1alpha1.SecretTypeServiceAccountToken {
+		return nil, fmt.Errorf("secret %s of type %s can not be used as git credential", secret.Name, secret.Type)
+	}
+	cred := &credential{caBundle: secret.Data[corev1.ServiceAccountRootCAKey]}
+
+	if secret.Type == corev1.SecretTypeSSHAuth {
+		if endpoint.Protocol != "ssh" {
+			return nil, fmt.Errorf("secret %s can only be used with ssh remotes", secret.Name)
+		}
+		auth, err := sshAuth(secret, endpoint)
+		if err != nil {
+			return nil, err
+		}
+		cred.auth = auth
+		return cred, nil
+	}
+	if endpoint.Protocol == "ssh" {
+		return nil, fmt.Errorf("secret %s can not be used with ssh remotes, a %s secret is required", secret.Name, corev1.SecretTypeSSHAuth)
+	}
+
+	if secret.Type == SecretTypeGitToken || secret.Type == corev1.SecretTypeBasicAuth {
+		if token, ok := secret.Data[SecretKeyToken]; ok {
+			cred.auth = &http.TokenAuth{Token: string(token)}
+			return cred, nil
 		}
-		username = string(usernameBytes)
-		password = string(passwordBytes)
+	}
+	if secret.Type == SecretTypeGitToken {
+		return nil, fmt.Errorf("could not get %s in secret %s", SecretKeyToken, secret.Name)
 	}
 
-	return c.gitReadVerifyWithBasicAuth(username, password, remoteUrl)
+	usernameBytes, ok := secret.Data[corev1.BasicAuthUsernameKey]
+	if !ok {
+		return nil, fmt.Errorf("could not get username in secret %s", secret.Name)
+	}
+	passwordBytes, ok := secret.Data[corev1.BasicAuthPasswordKey]
+	if !ok {
+		return nil, fmt.Errorf("could not get password in secret %s", secret.Name)
+	}
+	cred.auth = &http.BasicAuth{Username: string(usernameBytes), Password: string(passwordBytes)}
+	return cred, nil
 }
 
-func (c *gitVerifier) gitReadVerifyWithBasicAuth(username string, password string, remote string) error {
+// sshAuth creates the public keys auth from the ssh-auth Secret, the host key of the server must be
+// in the known_hosts of the Secret, the unknown hosts are never trusted.
+func sshAuth(secret *corev1.Secret, endpoint *transport.Endpoint) (*ssh.PublicKeys, error) {
+	privateKey, ok := secret.Data[corev1.SSHAuthPrivateKey]
+	if !ok {
+		return nil, fmt.Errorf("could not get %s in secret %s", corev1.SSHAuthPrivateKey, secret.Name)
+	}
+	knownHosts, ok := secret.Data[SecretKeyKnownHosts]
+	if !ok || len(knownHosts) == 0 {
+		return nil, fmt.Errorf("could not get %s in secret %s", SecretKeyKnownHosts, secret.Name)
+	}
+
+	user := endpoint.User
+	if user == "" {
+		user = defaultSSHUser
+	}
+	auth, err := ssh.NewPublicKeys(user, privateKey, string(secret.Data[SecretKeyPassphrase]))
+	if err != nil {
+		return nil, fmt.Errorf("invalid private key in secret %s: %s", secret.Name, err)
+	}
+
+	db, err := knownHostsDB(knownHosts)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s in secret %s: %s", SecretKeyKnownHosts, secret.Name, err)
+	}
+	port := endpoint.Port
+	if port <= 0 {
+		port = defaultSSHPort
+	}
+	auth.HostKeyCallback = db.HostKeyCallback()
+	// only the algorithms of the known host keys are negotiated, otherwise the server may present a key of
+	// another type which is reported as a mismatch
+	auth.HostKeyAlgorithms = db.HostKeyAlgorithms(net.JoinHostPort(endpoint.Host, strconv.Itoa(port)))
+	return auth, nil
+}
+
+// knownHostsDB parses the known_hosts, which can only be read from files.
+func knownHostsDB(knownHosts []byte) (*knownhosts.HostKeyDB, error) {
+	file, err := os.CreateTemp("", "known_hosts")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(file.Name())
+	_, err = file.Write(knownHosts)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return nil, err
+	}
+	return knownhosts.NewDB(file.Name())
+}
+
+func (c *gitVerifier) listRefs(remote string, cred *credential) (*Refs, error) {
 	r, _ := git.Init(memory.NewStorage(), nil)
 	// Add a new remote, with the default fetch refspec
 	origin, err := r.CreateRemote(&config.RemoteConfig{
@@ -68,8 +205,27 @@ func (c *gitVerifier) gitReadVerifyWithBasicAuth(username string, password strin
 		URLs: []string{remote},
 	})
 	if err != nil {
-		return err
+		return nil, err
+	}
+	references, err := origin.List(&git.ListOptions{
+		Auth:     cred.auth,
+		CABundle: cred.caBundle,
+		Timeout:  listTimeout,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	refs := &Refs{Branches: make([]string, 0), Tags: make([]string, 0)}
+	for _, reference := range references {
+		switch {
+		case reference.Name().IsBranch():
+			refs.Branches = append(refs.Branches, reference.Name().Short())
+		case reference.Name().IsTag():
+			refs.Tags = append(refs.Tags, reference.Name().Short())
+		}
 	}
-	_, err = origin.List(&git.ListOptions{Auth: &http.BasicAuth{Username: username, Password: password}})
-	return err
+	sort.Strings(refs.Branches)
+	sort.Strings(refs.Tags)
+	return refs, nil
 }
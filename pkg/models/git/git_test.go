@@ -7,8 +7,19 @@
 package git
 
 import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/pem"
+	"net"
 	"testing"
 
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	"github.com/go-git/go-git/v5/plumbing/transport/http"
+	gitssh "github.com/go-git/go-git/v5/plumbing/transport/ssh"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/ssh"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	runtimefakeclient "sigs.k8s.io/controller-runtime/pkg/client/fake"
 
 	"kubesphere.io/kubesphere/pkg/scheme"
@@ -49,9 +60,12 @@ func TestGitReadVerifyWithBasicAuth(t *testing.T) {
 		Build()
 
 	verifier := gitVerifier{cache: client}
+	basicAuth := func(item map[string]string) *credential {
+		return &credential{auth: &http.BasicAuth{Username: item["username"], Password: item["password"]}}
+	}
 
 	for _, item := range shouldSuccess {
-		err := verifier.gitReadVerifyWithBasicAuth(item["username"], item["password"], item["remote"])
+		_, err := verifier.listRefs(item["remote"], basicAuth(item))
 		if err != nil {
 
 			t.Errorf("should could access repo [%s] with %s:%s, %v", item["username"], item["password"], item["remote"], err)
@@ -59,9 +73,102 @@ func TestGitReadVerifyWithBasicAuth(t *testing.T) {
 	}
 
 	for _, item := range shouldFailed {
-		err := verifier.gitReadVerifyWithBasicAuth(item["username"], item["password"], item["remote"])
+		_, err := verifier.listRefs(item["remote"], basicAuth(item))
 		if err == nil {
 			t.Errorf("should could access repo [%s] with %s:%s ", item["username"], item["password"], item["remote"])
 		}
 	}
 }
+
+func newSSHKey(t *testing.T) ([]byte, ssh.PublicKey) {
+	public, private, err := ed25519.GenerateKey(rand.Reader)
+	assert.NoError(t, err)
+	block, err := ssh.MarshalPrivateKey(private, "")
+	assert.NoError(t, err)
+	sshPublic, err := ssh.NewPublicKey(public)
+	assert.NoError(t, err)
+	return pem.EncodeToMemory(block), sshPublic
+}
+
+func TestCredentialFromSecret(t *testing.T) {
+	privateKey, _ := newSSHKey(t)
+	_, hostKey := newSSHKey(t)
+	_, otherHostKey := newSSHKey(t)
+	knownHosts := []byte("git.example.com " + string(ssh.MarshalAuthorizedKey(hostKey)))
+
+	sshEndpoint, err := transport.NewEndpoint("deploy@git.example.com:kubesphere/kubesphere.git")
+	assert.NoError(t, err)
+	httpsEndpoint, err := transport.NewEndpoint("https://git.example.com/kubesphere/kubesphere.git")
+	assert.NoError(t, err)
+
+	sshSecret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "ssh"},
+		Type:       corev1.SecretTypeSSHAuth,
+		Data:       map[string][]byte{corev1.SSHAuthPrivateKey: privateKey, SecretKeyKnownHosts: knownHosts},
+	}
+	cred, err := credentialFromSecret(sshSecret, sshEndpoint)
+	assert.NoError(t, err)
+	auth, ok := cred.auth.(*gitssh.PublicKeys)
+	assert.True(t, ok)
+	assert.Equal(t, "deploy", auth.User)
+	assert.Equal(t, []string{ssh.KeyAlgoED25519}, auth.HostKeyAlgorithms)
+	addr := &net.TCPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 22}
+	assert.NoError(t, auth.HostKeyCallback("git.example.com:22", addr, hostKey))
+	assert.Error(t, auth.HostKeyCallback("git.example.com:22", addr, otherHostKey))
+	assert.Error(t, auth.HostKeyCallback("unknown.example.com:22", addr, hostKey))
+
+	_, err = credentialFromSecret(sshSecret, httpsEndpoint)
+	assert.Error(t, err)
+	_, err = credentialFromSecret(&corev1.Secret{
+		Type: corev1.SecretTypeSSHAuth,
+		Data: map[string][]byte{corev1.SSHAuthPrivateKey: privateKey},
+	}, sshEndpoint)
+	assert.Error(t, err, "known_hosts is required")
+
+	cred, err = credentialFromSecret(&corev1.Secret{
+		Type: SecretTypeGitToken,
+		Data: map[string][]byte{SecretKeyToken: []byte("token"), corev1.ServiceAccountRootCAKey: []byte("ca")},
+	}, httpsEndpoint)
+	assert.NoError(t, err)
+	assert.Equal(t, &http.TokenAuth{Token: "token"}, cred.auth)
+	assert.Equal(t, []byte("ca"), cred.caBundle)
+	cred, err = credentialFromSecret(&corev1.Secret{
+		Type: corev1.SecretTypeBasicAuth,
+		Data: map[string][]byte{corev1.BasicAuthUsernameKey: []byte("admin"), SecretKeyToken: []byte("token")},
+	}, httpsEndpoint)
+	assert.NoError(t, err)
+	assert.Equal(t, &http.TokenAuth{Token: "token"}, cred.auth)
+	_, err = credentialFromSecret(&corev1.Secret{
+		Type: corev1.SecretTypeOpaque,
+		Data: map[string][]byte{SecretKeyToken: []byte("token")},
+	}, httpsEndpoint)
+	assert.Error(t, err, "token is only read from the git token and basic-auth secrets")
+	_, err = credentialFromSecret(&corev1.Secret{
+		Type: corev1.SecretTypeServiceAccountToken,
+		Data: map[string][]byte{SecretKeyToken: []byte("token"), corev1.BasicAuthUsernameKey: []byte("admin"), corev1.BasicAuthPasswordKey: []byte("password")},
+	}, httpsEndpoint)
+	assert.Error(t, err, "service account token can not be used as git credential")
+	_, err = credentialFromSecret(&corev1.Secret{
+		Type: SecretTypeGitToken,
+		Data: map[string][]byte{SecretKeyToken: []byte("token")},
+	}, sshEndpoint)
+	assert.Error(t, err, "token can not be used with ssh remotes")
+
+	cred, err = credentialFromSecret(&corev1.Secret{
+		Type: corev1.SecretTypeBasicAuth,
+		Data: map[string][]byte{corev1.BasicAuthUsernameKey: []byte("admin"), corev1.BasicAuthPasswordKey: []byte("password")},
+	}, httpsEndpoint)
+	assert.NoError(t, err)
+	assert.Equal(t, &http.BasicAuth{Username: "admin", Password: "password"}, cred.auth)
+	_, err = credentialFromSecret(&corev1.Secret{
+		Type: corev1.SecretTypeBasicAuth,
+		Data: map[string][]byte{corev1.BasicAuthUsernameKey: []byte("admin"), corev1.BasicAuthPasswordKey: []byte("password")},
+	}, sshEndpoint)
+	assert.Error(t, err, "basic auth can not be used with ssh remotes")
+
+	_, err = credentialFromSecret(&corev1.Secret{
+		Type: corev1.SecretTypeBasicAuth,
+		Data: map[string][]byte{corev1.BasicAuthPasswordKey: []byte("password")},
+	}, httpsEndpoint)
+	assert.Error(t, err)
+}
@@ -9,6 +9,7 @@ package am
 import (
 	"context"
 	"fmt"
+	"time"
 
 	v1 "k8s.io/api/core/v1"
 	rbacv1 "k8s.io/api/rbac/v1"
@@ -17,6 +18,7 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/selection"
+	authuser "k8s.io/apiserver/pkg/authentication/user"
 	"k8s.io/klog/v2"
 	iamv1beta1 "kubesphere.io/api/iam/v1beta1"
 	tenantv1beta1 "kubesphere.io/api/tenant/v1beta1"
@@ -25,6 +27,7 @@ import (
 	"kubesphere.io/kubesphere/pkg/api"
 	"kubesphere.io/kubesphere/pkg/apiserver/query"
 	"kubesphere.io/kubesphere/pkg/constants"
+	"kubesphere.io/kubesphere/pkg/models/auth"
 	"kubesphere.io/kubesphere/pkg/models/kubeconfig"
 	resourcev1beta1 "kubesphere.io/kubesphere/pkg/models/resources/v1beta1"
 	"kubesphere.io/kubesphere/pkg/utils/sliceutil"
@@ -53,6 +56,8 @@ type AccessManagementInterface interface {
 
 	GetRoleReferenceRules(roleRef rbacv1.RoleRef, namespace string) (regoPolicy string, rules []rbacv1.PolicyRule, err error)
 	GetNamespaceControlledWorkspace(namespace string) (string, error)
+	// GetGroupsOfUser returns the groups attached to the user by the authenticators.
+	GetGroupsOfUser(username string) ([]string, error)
 
 	ListGroupWorkspaceRoleBindings(workspace string, query *query.Query) (*api.ListResult, error)
 
@@ -60,10 +65,12 @@ type AccessManagementInterface interface {
 
 	GetRoleTemplate(name string) (*iamv1beta1.RoleTemplate, error)
 
-	CreateOrUpdateGlobalRoleBinding(username string, globalRole string) error
-	CreateOrUpdateUserWorkspaceRoleBinding(username string, workspace string, role string) error
-	CreateOrUpdateNamespaceRoleBinding(username string, namespace string, role string) error
-	CreateOrUpdateClusterRoleBinding(username string, role string) error
+	// CreateOrUpdateGlobalRoleBinding binds the role to the user, the binding expires at the expiration time if it's not nil,
+	// the expiration time of the current binding is kept if it's nil and removed if it's zero.
+	CreateOrUpdateGlobalRoleBinding(username string, globalRole string, expiration *metav1.Time) error
+	CreateOrUpdateUserWorkspaceRoleBinding(username string, workspace string, role string, expiration *metav1.Time) error
+	CreateOrUpdateNamespaceRoleBinding(username string, namespace string, role string, expiration *metav1.Time) error
+	CreateOrUpdateClusterRoleBinding(username string, role string, expiration *metav1.Time) error
 
 	RemoveGlobalRoleBinding(username string) error
 	RemoveUserFromWorkspace(username string, workspace string) error
@@ -427,6 +434,17 @@ func (am *amOperator) GetNamespaceControlledWorkspace(namespaceName string) (str
 	return ns.Labels[tenantv1beta1.WorkspaceLabel], nil
 }
 
+func (am *amOperator) GetGroupsOfUser(username string) ([]string, error) {
+	user := &iamv1beta1.User{}
+	if err := am.resourceManager.Get(context.Background(), metav1.NamespaceAll, username, user); err != nil {
+		if errors.IsNotFound(err) {
+			return []string{authuser.AllAuthenticated}, nil
+		}
+		return nil, err
+	}
+	return auth.UserGroups(user), nil
+}
+
 func (am *amOperator) ListGroupWorkspaceRoleBindings(workspace string, query *query.Query) (*api.ListResult, error) {
 	roleList := &iamv1beta1.WorkspaceRoleBindingList{}
 	workspaceRequirement, err := labels.NewRequirement(tenantv1beta1.WorkspaceLabel, selection.Equals, []string{workspace})
@@ -464,7 +482,7 @@ func (am *amOperator) ListGroupRoleBindings(workspace string, query *query.Query
 	return result, nil
 }
 
-func (am *amOperator) CreateOrUpdateGlobalRoleBinding(username string, role string) error {
+func (am *amOperator) CreateOrUpdateGlobalRoleBinding(username string, role string, expiration *metav1.Time) error {
 	if _, err := am.GetGlobalRole(role); err != nil {
 		return err
 	}
@@ -476,7 +494,7 @@ func (am *amOperator) CreateOrUpdateGlobalRoleBinding(username string, role stri
 
 	for _, roleBinding := range roleBindings {
 		if role == roleBinding.RoleRef.Name {
-			return nil
+			return am.updateExpirationTime(&roleBinding, expiration)
 		}
 		if err := am.resourceManager.Delete(context.Background(), &roleBinding); err != nil {
 			if errors.IsNotFound(err) {
@@ -484,6 +502,7 @@ func (am *amOperator) CreateOrUpdateGlobalRoleBinding(username string, role stri
 			}
 			return err
 		}
+		expiration = inheritExpirationTime(&roleBinding, expiration)
 	}
 
 	globalRoleBinding := iamv1beta1.GlobalRoleBinding{
@@ -506,10 +525,12 @@ func (am *amOperator) CreateOrUpdateGlobalRoleBinding(username string, role stri
 		},
 	}
 
+	setExpirationTime(&globalRoleBinding, expiration)
+
 	return am.resourceManager.Create(context.Background(), &globalRoleBinding)
 }
 
-func (am *amOperator) CreateOrUpdateUserWorkspaceRoleBinding(username string, workspace string, role string) error {
+func (am *amOperator) CreateOrUpdateUserWorkspaceRoleBinding(username string, workspace string, role string, expiration *metav1.Time) error {
 	if _, err := am.GetWorkspaceRole(workspace, role); err != nil {
 		return err
 	}
@@ -521,7 +542,7 @@ func (am *amOperator) CreateOrUpdateUserWorkspaceRoleBinding(username string, wo
 
 	for _, roleBinding := range roleBindings {
 		if role == roleBinding.RoleRef.Name {
-			return nil
+			return am.updateExpirationTime(&roleBinding, expiration)
 		}
 		if err := am.resourceManager.Delete(context.Background(), &roleBinding); err != nil {
 			if errors.IsNotFound(err) {
@@ -529,6 +550,7 @@ func (am *amOperator) CreateOrUpdateUserWorkspaceRoleBinding(username string, wo
 			}
 			return err
 		}
+		expiration = inheritExpirationTime(&roleBinding, expiration)
 	}
 
 	roleBinding := iamv1beta1.WorkspaceRoleBinding{
@@ -552,10 +574,12 @@ func (am *amOperator) CreateOrUpdateUserWorkspaceRoleBinding(username string, wo
 		},
 	}
 
+	setExpirationTime(&roleBinding, expiration)
+
 	return am.resourceManager.Create(context.Background(), &roleBinding)
 }
 
-func (am *amOperator) CreateOrUpdateNamespaceRoleBinding(username string, namespace string, role string) error {
+func (am *amOperator) CreateOrUpdateNamespaceRoleBinding(username string, namespace string, role string, expiration *metav1.Time) error {
 	if _, err := am.GetNamespaceRole(namespace, role); err != nil {
 		return err
 	}
@@ -568,7 +592,7 @@ func (am *amOperator) CreateOrUpdateNamespaceRoleBinding(username string, namesp
 
 	for _, roleBinding := range roleBindings {
 		if role == roleBinding.RoleRef.Name {
-			return nil
+			return am.updateExpirationTime(&roleBinding, expiration)
 		}
 		if err := am.resourceManager.Delete(context.Background(), &roleBinding); err != nil {
 			if errors.IsNotFound(err) {
@@ -576,6 +600,7 @@ func (am *amOperator) CreateOrUpdateNamespaceRoleBinding(username string, namesp
 			}
 			return err
 		}
+		expiration = inheritExpirationTime(&roleBinding, expiration)
 	}
 
 	roleBinding := iamv1beta1.RoleBinding{
@@ -604,6 +629,8 @@ func (am *amOperator) CreateOrUpdateNamespaceRoleBinding(username string, namesp
 		},
 	}
 
+	setExpirationTime(&roleBinding, expiration)
+
 	if err := am.resourceManager.Create(context.Background(), &roleBinding); err != nil {
 		return err
 	}
@@ -611,7 +638,7 @@ func (am *amOperator) CreateOrUpdateNamespaceRoleBinding(username string, namesp
 	return nil
 }
 
-func (am *amOperator) CreateOrUpdateClusterRoleBinding(username string, role string) error {
+func (am *amOperator) CreateOrUpdateClusterRoleBinding(username string, role string, expiration *metav1.Time) error {
 	if _, err := am.GetClusterRole(role); err != nil {
 		return err
 	}
@@ -623,7 +650,7 @@ func (am *amOperator) CreateOrUpdateClusterRoleBinding(username string, role str
 
 	for _, roleBinding := range roleBindings {
 		if role == roleBinding.RoleRef.Name {
-			return nil
+			return am.updateExpirationTime(&roleBinding, expiration)
 		}
 		if err := am.resourceManager.Delete(context.Background(), &roleBinding); err != nil {
 			if errors.IsNotFound(err) {
@@ -631,6 +658,7 @@ func (am *amOperator) CreateOrUpdateClusterRoleBinding(username string, role str
 			}
 			return err
 		}
+		expiration = inheritExpirationTime(&roleBinding, expiration)
 	}
 
 	roleBinding := iamv1beta1.ClusterRoleBinding{
@@ -658,6 +686,8 @@ func (am *amOperator) CreateOrUpdateClusterRoleBinding(username string, role str
 		},
 	}
 
+	setExpirationTime(&roleBinding, expiration)
+
 	if err := am.resourceManager.Create(context.Background(), &roleBinding); err != nil {
 		return err
 	}
@@ -665,6 +695,57 @@ func (am *amOperator) CreateOrUpdateClusterRoleBinding(username string, role str
 	return nil
 }
 
+// setExpirationTime sets the expiration time of the role binding, the current expiration time is kept if it's nil,
+// so the new bindings never expire without it, and it's removed if it's zero. It returns whether the expiration
+// time is changed.
+func setExpirationTime(roleBinding metav1.Object, expiration *metav1.Time) bool {
+	if expiration == nil {
+		return false
+	}
+	annotations := roleBinding.GetAnnotations()
+	if expiration.IsZero() {
+		if _, ok := annotations[iamv1beta1.ExpirationTimeAnnotation]; !ok {
+			return false
+		}
+		delete(annotations, iamv1beta1.ExpirationTimeAnnotation)
+		delete(annotations, iamv1beta1.ExpirationWarningAnnotation)
+		roleBinding.SetAnnotations(annotations)
+		return true
+	}
+	expected := expiration.UTC().Format(time.RFC3339)
+	if annotations[iamv1beta1.ExpirationTimeAnnotation] == expected {
+		return false
+	}
+	if annotations == nil {
+		annotations = make(map[string]string)
+	}
+	annotations[iamv1beta1.ExpirationTimeAnnotation] = expected
+	// warn again before the new expiration time
+	delete(annotations, iamv1beta1.ExpirationWarningAnnotation)
+	roleBinding.SetAnnotations(annotations)
+	return true
+}
+
+// inheritExpirationTime returns the expiration time of the role binding replaced by the binding of another role
+// if the expiration time is not given, so that changing the role doesn't extend the access.
+func inheritExpirationTime(replaced metav1.Object, expiration *metav1.Time) *metav1.Time {
+	if expiration != nil {
+		return expiration
+	}
+	value, err := time.Parse(time.RFC3339, replaced.GetAnnotations()[iamv1beta1.ExpirationTimeAnnotation])
+	if err != nil {
+		return nil
+	}
+	return &metav1.Time{Time: value}
+}
+
+func (am *amOperator) updateExpirationTime(roleBinding client.Object, expiration *metav1.Time) error {
+	if !setExpirationTime(roleBinding, expiration) {
+		return nil
+	}
+	return am.resourceManager.Update(context.Background(), roleBinding)
+}
+
 func (am *amOperator) RemoveUserFromWorkspace(username string, workspace string) error {
 	roleBindings, err := am.ListWorkspaceRoleBindings(username, "", nil, workspace)
 	if err != nil {
@@ -0,0 +1,82 @@
+/*
+ * Copyright 2024 the KubeSphere Authors.
+ * Please refer to the LICENSE file in the root directory of the project.
+ * https://github.com/kubesphere/kubesphere/blob/master/LICENSE
+ */
+
+package am
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	iamv1beta1 "kubesphere.io/api/iam/v1beta1"
+	"sigs.k8s.io/controller-runtime/pkg/cache/informertest"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	runtimefakeclient "sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	resourcev1beta1 "kubesphere.io/kubesphere/pkg/models/resources/v1beta1"
+	"kubesphere.io/kubesphere/pkg/scheme"
+)
+
+func TestSetExpirationTime(t *testing.T) {
+	roleBinding := &iamv1beta1.WorkspaceRoleBinding{}
+	assert.False(t, setExpirationTime(roleBinding, nil))
+	assert.Empty(t, roleBinding.Annotations)
+
+	expiration := &metav1.Time{Time: time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)}
+	assert.True(t, setExpirationTime(roleBinding, expiration))
+	assert.Equal(t, "2030-01-01T00:00:00Z", roleBinding.Annotations[iamv1beta1.ExpirationTimeAnnotation])
+	assert.False(t, setExpirationTime(roleBinding, expiration))
+
+	// the current expiration time is kept
+	roleBinding.Annotations[iamv1beta1.ExpirationWarningAnnotation] = "true"
+	assert.False(t, setExpirationTime(roleBinding, nil))
+	assert.Equal(t, "2030-01-01T00:00:00Z", roleBinding.Annotations[iamv1beta1.ExpirationTimeAnnotation])
+
+	// warn again before the new expiration time
+	assert.True(t, setExpirationTime(roleBinding, &metav1.Time{Time: expiration.AddDate(0, 1, 0)}))
+	assert.Equal(t, "2030-02-01T00:00:00Z", roleBinding.Annotations[iamv1beta1.ExpirationTimeAnnotation])
+	assert.NotContains(t, roleBinding.Annotations, iamv1beta1.ExpirationWarningAnnotation)
+
+	// the zero expiration time removes it
+	assert.True(t, setExpirationTime(roleBinding, &metav1.Time{}))
+	assert.NotContains(t, roleBinding.Annotations, iamv1beta1.ExpirationTimeAnnotation)
+	assert.False(t, setExpirationTime(roleBinding, &metav1.Time{}))
+}
+
+func TestChangeRoleKeepsExpirationTime(t *testing.T) {
+	expiration := "2030-01-01T00:00:00Z"
+	c := runtimefakeclient.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(
+		&iamv1beta1.GlobalRole{ObjectMeta: metav1.ObjectMeta{Name: "platform-regular"}},
+		&iamv1beta1.GlobalRole{ObjectMeta: metav1.ObjectMeta{Name: "platform-admin"}},
+		&iamv1beta1.GlobalRoleBinding{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        "alice-platform-regular",
+				Labels:      map[string]string{iamv1beta1.UserReferenceLabel: "alice", iamv1beta1.RoleReferenceLabel: "platform-regular"},
+				Annotations: map[string]string{iamv1beta1.ExpirationTimeAnnotation: expiration},
+			},
+			RoleRef: rbacv1.RoleRef{APIGroup: iamv1beta1.SchemeGroupVersion.Group, Kind: iamv1beta1.ResourceKindGlobalRole, Name: "platform-regular"},
+		},
+	).Build()
+	resourceManager, err := resourcev1beta1.New(context.Background(), c, &informertest.FakeInformers{Scheme: scheme.Scheme})
+	assert.NoError(t, err)
+	am := NewOperator(resourceManager)
+
+	// the expiration time of the replaced binding is kept
+	assert.NoError(t, am.CreateOrUpdateGlobalRoleBinding("alice", "platform-admin", nil))
+	roleBinding := &iamv1beta1.GlobalRoleBinding{}
+	assert.NoError(t, c.Get(context.Background(), client.ObjectKey{Name: "alice-platform-admin"}, roleBinding))
+	assert.Equal(t, expiration, roleBinding.Annotations[iamv1beta1.ExpirationTimeAnnotation])
+	err = c.Get(context.Background(), client.ObjectKey{Name: "alice-platform-regular"}, &iamv1beta1.GlobalRoleBinding{})
+	assert.True(t, client.IgnoreNotFound(err) == nil && err != nil)
+
+	// the zero expiration time removes it
+	assert.NoError(t, am.CreateOrUpdateGlobalRoleBinding("alice", "platform-regular", &metav1.Time{}))
+	assert.NoError(t, c.Get(context.Background(), client.ObjectKey{Name: "alice-platform-regular"}, roleBinding))
+	assert.NotContains(t, roleBinding.Annotations, iamv1beta1.ExpirationTimeAnnotation)
+}
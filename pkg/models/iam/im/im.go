@@ -8,10 +8,12 @@ package im
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"strconv"
 	"time"
 
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
@@ -24,6 +26,7 @@ import (
 	"kubesphere.io/kubesphere/pkg/apiserver/authentication"
 	"kubesphere.io/kubesphere/pkg/apiserver/query"
 	"kubesphere.io/kubesphere/pkg/models/auth"
+	"kubesphere.io/kubesphere/pkg/models/iam/passwordpolicy"
 	resourcev1beta1 "kubesphere.io/kubesphere/pkg/models/resources/v1beta1"
 )
 
@@ -68,12 +71,17 @@ func (im *imOperator) UpdateUser(new *iamv1beta1.User) (*iamv1beta1.User, error)
 		status.State = new.Status.State
 		status.LastTransitionTime = &metav1.Time{Time: time.Now()}
 	}
+	// force the user to change the password at the next login
+	if new.Status.PasswordChangeRequired {
+		status.PasswordChangeRequired = true
+	}
 	new.Status = status
 	if err := im.client.Update(context.Background(), new); err != nil {
 		return nil, err
 	}
 	new = new.DeepCopy()
 	new.Spec.EncryptedPassword = ""
+	new.Status.PasswordHistory = nil
 	return new, nil
 }
 
@@ -90,6 +98,21 @@ func (im *imOperator) ModifyPassword(username string, password string) error {
 	if err != nil {
 		return err
 	}
+	// validate the password in advance to return a readable error
+	policy, err := passwordpolicy.Load(context.Background(), im.client)
+	if errors.Is(err, passwordpolicy.ErrInvalidPolicy) {
+		klog.Warningf("fall back to the default password policy: %s", err)
+		policy, err = passwordpolicy.NewPolicy(), nil
+	}
+	if err != nil {
+		return err
+	}
+	if err = policy.Check(password); err != nil {
+		return apierrors.NewBadRequest(err.Error())
+	}
+	if err = policy.CheckReuse(password, passwordpolicy.RecentPasswords(user)); err != nil {
+		return apierrors.NewBadRequest(err.Error())
+	}
 	user.Spec.EncryptedPassword = password
 	if err := im.client.Update(context.Background(), user); err != nil {
 		return err
@@ -107,6 +130,7 @@ func (im *imOperator) ListUsers(query *query.Query) (*api.ListResult, error) {
 	for _, item := range userList.Items {
 		out := item.DeepCopy()
 		out.Spec.EncryptedPassword = ""
+		out.Status.PasswordHistory = nil
 		items = append(items, out)
 	}
 	total, err := strconv.ParseInt(userList.GetContinue(), 10, 64)
@@ -134,6 +158,7 @@ func (im *imOperator) DescribeUser(username string) (*iamv1beta1.User, error) {
 	}
 	out := user.DeepCopy()
 	out.Spec.EncryptedPassword = ""
+	out.Status.PasswordHistory = nil
 	return out, nil
 }
 
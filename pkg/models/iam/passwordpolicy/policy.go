@@ -0,0 +1,253 @@
+/*
+ * Copyright 2024 the KubeSphere Authors.
+ * Please refer to the LICENSE file in the root directory of the project.
+ * https://github.com/kubesphere/kubesphere/blob/master/LICENSE
+ */
+
+package passwordpolicy
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha1"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+	"unicode"
+
+	"golang.org/x/crypto/bcrypt"
+	"gopkg.in/yaml.v3"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	iamv1beta1 "kubesphere.io/api/iam/v1beta1"
+	runtimeclient "sigs.k8s.io/controller-runtime/pkg/client"
+
+	"kubesphere.io/kubesphere/pkg/constants"
+)
+
+const (
+	// ConfigName is the name of the platform config Secret of the password policy.
+	ConfigName = "io.kubesphere.config.platformconfig.passwordpolicy"
+
+	// maxPasswordLength is limited by the max length of the password in the User.
+	maxPasswordLength = 64
+	// breachedPrefixLength is the length of the SHA-1 prefix of the breached password list, the list is
+	// split into files by the prefix, e.g. 21BD1.txt, as the range API of Pwned Passwords.
+	breachedPrefixLength = 5
+)
+
+// ErrInvalidPolicy is returned if the platform config Secret of the password policy is malformed, the callers
+// should fall back to the default policy, so that a misconfiguration doesn't block the users.
+var ErrInvalidPolicy = errors.New("invalid password policy")
+
+// Policy is the password policy of the users, the passwords of the users authenticated by
+// the identity providers are not restricted.
+type Policy struct {
+	MinLength        int  `json:"minLength,omitempty" yaml:"minLength,omitempty"`
+	MaxLength        int  `json:"maxLength,omitempty" yaml:"maxLength,omitempty"`
+	RequireUppercase bool `json:"requireUppercase" yaml:"requireUppercase"`
+	RequireLowercase bool `json:"requireLowercase" yaml:"requireLowercase"`
+	RequireDigit     bool `json:"requireDigit" yaml:"requireDigit"`
+	RequireSymbol    bool `json:"requireSymbol" yaml:"requireSymbol"`
+	// HistoryDepth is the number of the recent passwords including the current one which can't be reused,
+	// 0 means the passwords can be reused.
+	HistoryDepth int `json:"historyDepth,omitempty" yaml:"historyDepth,omitempty"`
+	// MaxAge is the maximum age of the passwords, the users must change the expired passwords
+	// at the next login, 0 means the passwords never expire.
+	MaxAge time.Duration `json:"maxAge,omitempty" yaml:"maxAge,omitempty"`
+	// BreachedPasswords checks the passwords against the local breached password list.
+	BreachedPasswords BreachedPasswordsOptions `json:"breachedPasswords,omitempty" yaml:"breachedPasswords,omitempty"`
+}
+
+// BreachedPasswordsOptions is the breached password list in the format of the range API of Pwned Passwords,
+// the files named by the first 5 characters of the SHA-1 hashes contain the lines of the other characters
+// and the counts, e.g. 0018A45C4D1DEF81644B54AB7F969B88D65:1, so only the prefix of the hash is used to look up.
+type BreachedPasswordsOptions struct {
+	Enabled bool `json:"enabled" yaml:"enabled"`
+	// Path is the directory of the list, it must be mounted in ks-apiserver and ks-controller-manager.
+	Path string `json:"path,omitempty" yaml:"path,omitempty"`
+}
+
+// NewPolicy returns the default policy, which is the same as the former password rule of the users.
+func NewPolicy() *Policy {
+	return &Policy{
+		MinLength:        8,
+		MaxLength:        maxPasswordLength,
+		RequireUppercase: true,
+		RequireLowercase: true,
+		RequireDigit:     true,
+	}
+}
+
+func (p *Policy) Validate() error {
+	var errs []error
+	if p.MinLength < 1 {
+		errs = append(errs, fmt.Errorf("minLength must be greater than 0"))
+	}
+	if p.MaxLength < p.MinLength || p.MaxLength > maxPasswordLength {
+		errs = append(errs, fmt.Errorf("maxLength must be between minLength and %d", maxPasswordLength))
+	}
+	if p.HistoryDepth < 0 {
+		errs = append(errs, fmt.Errorf("historyDepth must not be negative"))
+	}
+	if p.MaxAge < 0 {
+		errs = append(errs, fmt.Errorf("maxAge must not be negative"))
+	}
+	if p.BreachedPasswords.Enabled && p.BreachedPasswords.Path == "" {
+		errs = append(errs, fmt.Errorf("the path of the breached password list is required"))
+	}
+	return errors.Join(errs...)
+}
+
+// Load loads the policy from the platform config Secret, the default policy is returned if it's not found.
+func Load(ctx context.Context, reader runtimeclient.Reader) (*Policy, error) {
+	secret := &corev1.Secret{}
+	if err := reader.Get(ctx, runtimeclient.ObjectKey{Namespace: constants.KubeSphereNamespace, Name: ConfigName}, secret); err != nil {
+		if apierrors.IsNotFound(err) {
+			return NewPolicy(), nil
+		}
+		return nil, err
+	}
+	return LoadFromSecret(secret)
+}
+
+func LoadFromSecret(secret *corev1.Secret) (*Policy, error) {
+	policy := NewPolicy()
+	if err := yaml.Unmarshal(secret.Data[constants.GenericPlatformConfigFileName], policy); err != nil {
+		return nil, fmt.Errorf("%w: failed to unmarshal: %s", ErrInvalidPolicy, err)
+	}
+	if err := policy.Validate(); err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidPolicy, err)
+	}
+	return policy, nil
+}
+
+// Check checks the plain text password against the length, the character classes and the breached password list.
+func (p *Policy) Check(password string) error {
+	length := len([]rune(password))
+	if length < p.MinLength || length > p.MaxLength {
+		return fmt.Errorf("the length of the password must be between %d and %d", p.MinLength, p.MaxLength)
+	}
+
+	var upper, lower, digit, symbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsDigit(r):
+			digit = true
+		case unicode.IsPunct(r) || unicode.IsSymbol(r):
+			symbol = true
+		}
+	}
+	var missing []string
+	if p.RequireUppercase && !upper {
+		missing = append(missing, "an uppercase letter")
+	}
+	if p.RequireLowercase && !lower {
+		missing = append(missing, "a lowercase letter")
+	}
+	if p.RequireDigit && !digit {
+		missing = append(missing, "a digit")
+	}
+	if p.RequireSymbol && !symbol {
+		missing = append(missing, "a symbol")
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("the password must contain at least %s", strings.Join(missing, ", "))
+	}
+
+	if p.BreachedPasswords.Enabled {
+		breached, err := isBreached(p.BreachedPasswords.Path, password)
+		if err != nil {
+			return err
+		}
+		if breached {
+			return fmt.Errorf("the password has appeared in a data breach, please choose another one")
+		}
+	}
+	return nil
+}
+
+// CheckReuse checks the plain text password against the encrypted recent passwords from the latest one.
+func (p *Policy) CheckReuse(password string, history []string) error {
+	for i, encrypted := range history {
+		if i >= p.HistoryDepth {
+			break
+		}
+		if bcrypt.CompareHashAndPassword([]byte(encrypted), []byte(password)) == nil {
+			return fmt.Errorf("the password must not be the same as the last %d passwords", p.HistoryDepth)
+		}
+	}
+	return nil
+}
+
+// RecentPasswords returns the encrypted recent passwords of the user, the current password is returned
+// for the users created before the password history is recorded.
+func RecentPasswords(user *iamv1beta1.User) []string {
+	if len(user.Status.PasswordHistory) > 0 {
+		return user.Status.PasswordHistory
+	}
+	if cost, _ := bcrypt.Cost([]byte(user.Spec.EncryptedPassword)); cost > 0 {
+		return []string{user.Spec.EncryptedPassword}
+	}
+	return nil
+}
+
+// AppendHistory returns the history with the encrypted password as the latest one, the history is trimmed to the depth.
+func (p *Policy) AppendHistory(history []string, encrypted string) []string {
+	if p.HistoryDepth == 0 {
+		return nil
+	}
+	result := append([]string{encrypted}, history...)
+	if len(result) > p.HistoryDepth {
+		result = result[:p.HistoryDepth]
+	}
+	return result
+}
+
+// ExpirationTime returns the expiration time of the password changed at the time, nil if the passwords never expire.
+func (p *Policy) ExpirationTime(changed time.Time) *time.Time {
+	if p.MaxAge == 0 {
+		return nil
+	}
+	expiration := changed.Add(p.MaxAge)
+	return &expiration
+}
+
+// isBreached looks up the SHA-1 hash of the password in the file of the hash prefix, so that only the
+// passwords with the same prefix are read.
+func isBreached(path, password string) (bool, error) {
+	if _, err := os.Stat(path); err != nil {
+		return false, fmt.Errorf("breached password list is not available: %s", err)
+	}
+	hash := strings.ToUpper(fmt.Sprintf("%x", sha1.Sum([]byte(password))))
+	prefix, suffix := hash[:breachedPrefixLength], hash[breachedPrefixLength:]
+
+	file, err := os.Open(filepath.Join(path, prefix+".txt"))
+	if err != nil {
+		// no breached password with the prefix
+		if os.IsNotExist(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if i := strings.IndexByte(line, ':'); i >= 0 {
+			line = line[:i]
+		}
+		if strings.EqualFold(line, suffix) {
+			return true, nil
+		}
+	}
+	return false, scanner.Err()
+}
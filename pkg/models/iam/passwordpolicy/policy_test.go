@@ -0,0 +1,128 @@
+/*
+ * Copyright 2024 the KubeSphere Authors.
+ * Please refer to the LICENSE file in the root directory of the project.
+ * https://github.com/kubesphere/kubesphere/blob/master/LICENSE
+ */
+
+package passwordpolicy
+
+import (
+	"context"
+	"crypto/sha1"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/bcrypt"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	iamv1beta1 "kubesphere.io/api/iam/v1beta1"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"kubesphere.io/kubesphere/pkg/constants"
+	"kubesphere.io/kubesphere/pkg/scheme"
+)
+
+func encrypt(t *testing.T, password string) string {
+	bytes, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.MinCost)
+	assert.NoError(t, err)
+	return string(bytes)
+}
+
+func TestCheck(t *testing.T) {
+	policy := NewPolicy()
+	assert.NoError(t, policy.Check("P@88w0rd"))
+	assert.NoError(t, policy.Check("Passw0rd"))
+	assert.Error(t, policy.Check("Pa0"))
+	assert.Error(t, policy.Check("password1"))
+	assert.Error(t, policy.Check("PASSWORD1"))
+	assert.Error(t, policy.Check("Password"))
+	assert.Error(t, policy.Check(strings.Repeat("Pa0", 22)))
+
+	policy.RequireSymbol = true
+	assert.NoError(t, policy.Check("P@88w0rd"))
+	assert.EqualError(t, policy.Check("Passw0rd"), "the password must contain at least a symbol")
+}
+
+func TestHistory(t *testing.T) {
+	policy := NewPolicy()
+	first, second := encrypt(t, "Passw0rd1"), encrypt(t, "Passw0rd2")
+
+	// the passwords can be reused by default
+	assert.Nil(t, policy.AppendHistory([]string{first}, second))
+	assert.NoError(t, policy.CheckReuse("Passw0rd1", []string{first}))
+
+	policy.HistoryDepth = 2
+	history := policy.AppendHistory(nil, first)
+	history = policy.AppendHistory(history, second)
+	assert.Equal(t, []string{second, first}, history)
+	assert.Error(t, policy.CheckReuse("Passw0rd1", history))
+	assert.Error(t, policy.CheckReuse("Passw0rd2", history))
+	assert.NoError(t, policy.CheckReuse("Passw0rd3", history))
+
+	third := encrypt(t, "Passw0rd3")
+	history = policy.AppendHistory(history, third)
+	assert.Equal(t, []string{third, second}, history)
+	assert.NoError(t, policy.CheckReuse("Passw0rd1", history))
+
+	user := &iamv1beta1.User{Spec: iamv1beta1.UserSpec{EncryptedPassword: first}}
+	assert.Equal(t, []string{first}, RecentPasswords(user))
+	user.Status.PasswordHistory = history
+	assert.Equal(t, history, RecentPasswords(user))
+	assert.Nil(t, RecentPasswords(&iamv1beta1.User{Spec: iamv1beta1.UserSpec{EncryptedPassword: "Passw0rd1"}}))
+}
+
+func TestBreachedPasswords(t *testing.T) {
+	path := t.TempDir()
+	hash := fmt.Sprintf("%X", sha1.Sum([]byte("P@88w0rd")))
+	assert.NoError(t, os.WriteFile(filepath.Join(path, hash[:5]+".txt"),
+		[]byte("0018A45C4D1DEF81644B54AB7F969B88D65:1\r\n"+strings.ToLower(hash[5:])+":3861\r\n"), 0644))
+
+	policy := NewPolicy()
+	policy.BreachedPasswords = BreachedPasswordsOptions{Enabled: true, Path: path}
+	assert.Error(t, policy.Check("P@88w0rd"))
+	assert.NoError(t, policy.Check("Passw0rd"))
+
+	policy.BreachedPasswords.Path = filepath.Join(path, "not-found")
+	assert.Error(t, policy.Check("Passw0rd"))
+}
+
+func TestLoad(t *testing.T) {
+	client := fake.NewClientBuilder().WithScheme(scheme.Scheme).Build()
+	policy, err := Load(context.Background(), client)
+	assert.NoError(t, err)
+	assert.Equal(t, NewPolicy(), policy)
+
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Namespace: constants.KubeSphereNamespace, Name: ConfigName},
+		Type:       constants.SecretTypeGenericPlatformConfig,
+		Data: map[string][]byte{constants.GenericPlatformConfigFileName: []byte(`minLength: 12
+requireSymbol: true
+historyDepth: 5
+maxAge: 2160h
+`)},
+	}
+	client = fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(secret).Build()
+	policy, err = Load(context.Background(), client)
+	assert.NoError(t, err)
+	assert.Equal(t, 12, policy.MinLength)
+	assert.Equal(t, 64, policy.MaxLength)
+	assert.True(t, policy.RequireUppercase)
+	assert.True(t, policy.RequireSymbol)
+	assert.Equal(t, 5, policy.HistoryDepth)
+	assert.Equal(t, 90*24*time.Hour, policy.MaxAge)
+
+	changed := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	assert.Equal(t, time.Date(2024, 3, 31, 0, 0, 0, 0, time.UTC), *policy.ExpirationTime(changed))
+
+	secret.Data[constants.GenericPlatformConfigFileName] = []byte("minLength: 80")
+	_, err = LoadFromSecret(secret)
+	assert.ErrorIs(t, err, ErrInvalidPolicy)
+	secret.Data[constants.GenericPlatformConfigFileName] = []byte("minLength: [")
+	_, err = LoadFromSecret(secret)
+	assert.ErrorIs(t, err, ErrInvalidPolicy)
+}
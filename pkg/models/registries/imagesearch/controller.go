@@ -24,6 +24,8 @@ var SharedImageSearchProviderController = NewController()
 const (
 	dockerHubRegisterProvider = "DockerHubRegistryProvider"
 	harborRegisterProvider    = "HarborRegistryProvider"
+	ociRegisterProvider       = "OCIRegistryProvider"
+	quayRegisterProvider      = "QuayRegistryProvider"
 
 	SecretTypeImageSearchProvider = "config.kubesphere.io/imagesearchprovider"
 )
@@ -82,6 +84,12 @@ func (c *Controller) initGenericProvider() {
 
 	harborProvider, _ := searchProviderFactories[harborRegisterProvider].Create(nil)
 	c.imageSearchProviders.Store(harborRegisterProvider, harborProvider)
+
+	ociProvider, _ := searchProviderFactories[ociRegisterProvider].Create(nil)
+	c.imageSearchProviders.Store(ociRegisterProvider, ociProvider)
+
+	quayProvider, _ := searchProviderFactories[quayRegisterProvider].Create(nil)
+	c.imageSearchProviders.Store(quayRegisterProvider, quayProvider)
 }
 
 func IsImageSearchProviderConfiguration(secret *v1.Secret) bool {
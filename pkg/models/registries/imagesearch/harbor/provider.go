@@ -44,7 +44,7 @@ type repository struct {
 
 func (d harborRegistrySearchProvider) Search(imageName string, config imagesearch.SearchConfig) (*imagesearch.Results, error) {
 
-	url := fmt.Sprintf("%s/%s", config.Host, fmt.Sprintf(harborSearchUrl, imageName))
+	url := fmt.Sprintf("%s/%s", imagesearch.BaseURL(config.Host), fmt.Sprintf(harborSearchUrl, imageName))
 
 	request, err := http.NewRequest(http.MethodGet, url, nil)
 	if err != nil {
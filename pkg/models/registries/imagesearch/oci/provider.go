@@ -0,0 +1,179 @@
+/*
+ * Copyright 2024 the KubeSphere Authors.
+ * Please refer to the LICENSE file in the root directory of the project.
+ * https://github.com/kubesphere/kubesphere/blob/master/LICENSE
+ */
+
+package oci
+
+import (
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"kubesphere.io/kubesphere/pkg/models/registries/imagesearch"
+	"kubesphere.io/kubesphere/pkg/simple/client/oci"
+)
+
+const (
+	OCIRegisterProvider = "OCIRegistryProvider"
+
+	defaultCacheTTL        = 5 * time.Minute
+	defaultTimeout         = 30 * time.Second
+	catalogPageSize        = 1000
+	maxCatalogRepositories = 10000
+	maxSearchEntries       = 100
+)
+
+var errCatalogFull = errors.New("catalog is full")
+
+func init() {
+	imagesearch.RegistrySearchProvider(&ociSearchProviderFactory{})
+}
+
+var _ imagesearch.SearchProvider = &ociSearchProvider{}
+
+// ociSearchProvider searches the images by the catalog API of the OCI Distribution registries, e.g. Quay, GitLab,
+// Nexus, Artifactory and distribution. The catalog is not searchable, so it's cached and filtered by prefix.
+type ociSearchProvider struct {
+	cacheTTL time.Duration
+	timeout  time.Duration
+	// listRepositories lists the repositories of the registry, it's replaced in tests.
+	listRepositories func(ctx context.Context, config imagesearch.SearchConfig) ([]string, error)
+
+	mutex   sync.Mutex
+	catalog map[string]catalogEntry
+}
+
+type catalogEntry struct {
+	repositories []string
+	expiration   time.Time
+}
+
+func (p *ociSearchProvider) Search(imageName string, config imagesearch.SearchConfig) (*imagesearch.Results, error) {
+	repositories, err := p.repositories(config)
+	if err != nil {
+		return nil, err
+	}
+	imageResult := &imagesearch.Results{
+		Entries: make([]string, 0),
+	}
+	for _, repository := range repositories {
+		if !matchPrefix(repository, imageName) {
+			continue
+		}
+		imageResult.Total++
+		if len(imageResult.Entries) < maxSearchEntries {
+			imageResult.Entries = append(imageResult.Entries, repository)
+		}
+	}
+	return imageResult, nil
+}
+
+// matchPrefix returns true if the repository or any path component of it starts with the prefix,
+// e.g. both "lib" and "ngi" match "library/nginx".
+func matchPrefix(repository, prefix string) bool {
+	if prefix == "" || strings.HasPrefix(repository, prefix) {
+		return true
+	}
+	for i := strings.IndexByte(repository, '/'); i >= 0; i = strings.IndexByte(repository, '/') {
+		repository = repository[i+1:]
+		if strings.HasPrefix(repository, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// repositories returns the cached catalog of the registry, the catalog is cached per credential,
+// since the repositories visible to the users are different.
+func (p *ociSearchProvider) repositories(config imagesearch.SearchConfig) ([]string, error) {
+	key := fmt.Sprintf("%x", sha256.Sum256([]byte(strings.Join([]string{imagesearch.RegistryHost(config.Host), config.Username, config.Password}, "\x00"))))
+
+	p.mutex.Lock()
+	now := time.Now()
+	for k, entry := range p.catalog {
+		if now.After(entry.expiration) {
+			delete(p.catalog, k)
+		}
+	}
+	entry, ok := p.catalog[key]
+	p.mutex.Unlock()
+	if ok {
+		return entry.repositories, nil
+	}
+
+	// the catalog is listed without the lock, so that a slow registry doesn't block the others
+	ctx, cancel := context.WithTimeout(context.Background(), p.timeout)
+	defer cancel()
+	repositories, err := p.listRepositories(ctx, config)
+	if err != nil {
+		return nil, err
+	}
+	p.mutex.Lock()
+	p.catalog[key] = catalogEntry{repositories: repositories, expiration: now.Add(p.cacheTTL)}
+	p.mutex.Unlock()
+	return repositories, nil
+}
+
+func listRepositories(ctx context.Context, config imagesearch.SearchConfig) ([]string, error) {
+	baseURL, err := url.Parse(imagesearch.BaseURL(config.Host))
+	if err != nil {
+		return nil, err
+	}
+	registry, err := oci.NewRegistry(baseURL.Host,
+		oci.WithBasicAuth(config.Username, config.Password),
+		oci.WithTimeout(defaultTimeout),
+		oci.WithInsecureSkipVerifyTLS(config.Insecure))
+	if err != nil {
+		return nil, err
+	}
+	registry.RepositoryListPageSize = catalogPageSize
+
+	var repositories []string
+	err = registry.Repositories(ctx, "", func(repos []string) error {
+		repositories = append(repositories, repos...)
+		if len(repositories) >= maxCatalogRepositories {
+			return errCatalogFull
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, errCatalogFull) {
+		return nil, fmt.Errorf("failed to list repositories of %s: %s", config.Host, err)
+	}
+	if len(repositories) > maxCatalogRepositories {
+		repositories = repositories[:maxCatalogRepositories]
+	}
+	return repositories, nil
+}
+
+var _ imagesearch.SearchProviderFactory = &ociSearchProviderFactory{}
+
+type ociSearchProviderFactory struct{}
+
+func (d ociSearchProviderFactory) Type() string {
+	return OCIRegisterProvider
+}
+
+// Create creates the provider, the catalog is cached for the duration of the "cacheTTL" option.
+func (d ociSearchProviderFactory) Create(options map[string]interface{}) (imagesearch.SearchProvider, error) {
+	provider := &ociSearchProvider{
+		cacheTTL:         defaultCacheTTL,
+		timeout:          defaultTimeout,
+		listRepositories: listRepositories,
+		catalog:          make(map[string]catalogEntry),
+	}
+	if value, ok := options["cacheTTL"].(string); ok && value != "" {
+		ttl, err := time.ParseDuration(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid cacheTTL %q: %s", value, err)
+		}
+		provider.cacheTTL = ttl
+	}
+	return provider, nil
+}
@@ -0,0 +1,82 @@
+/*
+ * Copyright 2024 the KubeSphere Authors.
+ * Please refer to the LICENSE file in the root directory of the project.
+ * https://github.com/kubesphere/kubesphere/blob/master/LICENSE
+ */
+
+package oci
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"kubesphere.io/kubesphere/pkg/models/registries/imagesearch"
+)
+
+func TestSearch(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if user, password, _ := r.BasicAuth(); user != "admin" || password != "secret" {
+			w.Header().Set("WWW-Authenticate", `Basic realm="registry"`)
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch {
+		case r.URL.Path == "/v2/":
+		case r.URL.Path == "/v2/_catalog" && r.URL.Query().Get("last") == "":
+			requests++
+			w.Header().Set("Link", `</v2/_catalog?last=library%2Fnginx&n=1000>; rel="next"`)
+			_, _ = fmt.Fprint(w, `{"repositories":["library/busybox","library/nginx"]}`)
+		case r.URL.Path == "/v2/_catalog":
+			_, _ = fmt.Fprint(w, `{"repositories":["team/nginx-exporter","team/redis"]}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	provider, err := ociSearchProviderFactory{}.Create(map[string]interface{}{"cacheTTL": "1m"})
+	assert.NoError(t, err)
+	config := imagesearch.SearchConfig{Host: server.URL, Username: "admin", Password: "secret"}
+
+	results, err := provider.Search("ngi", config)
+	assert.NoError(t, err)
+	assert.Equal(t, &imagesearch.Results{Total: 2, Entries: []string{"library/nginx", "team/nginx-exporter"}}, results)
+
+	results, err = provider.Search("team/", config)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), results.Total)
+	assert.Equal(t, 1, requests, "the catalog should be cached")
+
+	// the catalog is cached per credential
+	config.Password = "invalid"
+	_, err = provider.Search("ngi", config)
+	assert.Error(t, err)
+
+	_, err = ociSearchProviderFactory{}.Create(map[string]interface{}{"cacheTTL": "soon"})
+	assert.Error(t, err)
+}
+
+func TestCatalogLimit(t *testing.T) {
+	provider := &ociSearchProvider{
+		cacheTTL: defaultCacheTTL,
+		timeout:  defaultTimeout,
+		listRepositories: func(_ context.Context, _ imagesearch.SearchConfig) ([]string, error) {
+			repositories := make([]string, maxSearchEntries+1)
+			for i := range repositories {
+				repositories[i] = fmt.Sprintf("app-%d", i)
+			}
+			return repositories, nil
+		},
+		catalog: make(map[string]catalogEntry),
+	}
+	results, err := provider.Search("app", imagesearch.SearchConfig{Host: "registry.example.com"})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(maxSearchEntries+1), results.Total)
+	assert.Len(t, results.Entries, maxSearchEntries)
+}
@@ -0,0 +1,118 @@
+/*
+ * Copyright 2024 the KubeSphere Authors.
+ * Please refer to the LICENSE file in the root directory of the project.
+ * https://github.com/kubesphere/kubesphere/blob/master/LICENSE
+ */
+
+package quay
+
+import (
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+
+	"k8s.io/klog/v2"
+
+	"kubesphere.io/kubesphere/pkg/models/registries/imagesearch"
+)
+
+const (
+	QuayRegisterProvider = "QuayRegistryProvider"
+	quaySearchUrl        = "api/v1/find/repositories?query=%s"
+	// oauthTokenUsername is the username to log in Quay with an OAuth token, the token is used to call the API.
+	oauthTokenUsername = "$oauthtoken"
+)
+
+func init() {
+	imagesearch.RegistrySearchProvider(&quaySearchProviderFactory{})
+}
+
+var _ imagesearch.SearchProvider = &quaySearchProvider{}
+
+type quaySearchProvider struct {
+	HttpClient *http.Client `json:"-" yaml:"-"`
+}
+
+type searchResponse struct {
+	Results []result `json:"results"`
+}
+
+type result struct {
+	Kind      string `json:"kind"`
+	Name      string `json:"name"`
+	Namespace struct {
+		Name string `json:"name"`
+	} `json:"namespace"`
+}
+
+// Search searches the repositories by the API of Quay. The API doesn't accept the credentials of the robot
+// accounts, only the OAuth token is sent, otherwise only the public repositories are found.
+func (q quaySearchProvider) Search(imageName string, config imagesearch.SearchConfig) (*imagesearch.Results, error) {
+	host := config.Host
+	if host == "" {
+		host = imagesearch.HostQuayIo
+	}
+	searchUrl := fmt.Sprintf("%s/%s", imagesearch.BaseURL(host), fmt.Sprintf(quaySearchUrl, url.QueryEscape(imageName)))
+	request, err := http.NewRequest(http.MethodGet, searchUrl, nil)
+	if err != nil {
+		return nil, err
+	}
+	if config.Username == oauthTokenUsername && config.Password != "" {
+		request.Header.Set("Authorization", fmt.Sprintf("Bearer %s", config.Password))
+	}
+
+	resp, err := q.HttpClient.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	bytes, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		klog.Errorf("search images failed with status code: %d, %s", resp.StatusCode, string(bytes))
+		return nil, fmt.Errorf("search images failed with status code: %d", resp.StatusCode)
+	}
+
+	searchResp := &searchResponse{}
+	err = json.Unmarshal(bytes, searchResp)
+	if err != nil {
+		return nil, err
+	}
+	imageResult := &imagesearch.Results{
+		Entries: make([]string, 0),
+	}
+	for _, v := range searchResp.Results {
+		if v.Kind != "repository" {
+			continue
+		}
+		imageResult.Entries = append(imageResult.Entries, fmt.Sprintf("%s/%s", v.Namespace.Name, v.Name))
+	}
+
+	imageResult.Total = int64(len(imageResult.Entries))
+
+	return imageResult, nil
+}
+
+var _ imagesearch.SearchProviderFactory = &quaySearchProviderFactory{}
+
+type quaySearchProviderFactory struct{}
+
+func (q quaySearchProviderFactory) Type() string {
+	return QuayRegisterProvider
+}
+
+func (q quaySearchProviderFactory) Create(_ map[string]interface{}) (imagesearch.SearchProvider, error) {
+	var provider quaySearchProvider
+	provider.HttpClient = &http.Client{
+		Transport: &http.Transport{
+			TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+		},
+	}
+	return provider, nil
+}
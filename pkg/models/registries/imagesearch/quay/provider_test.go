@@ -0,0 +1,45 @@
+/*
+ * Copyright 2024 the KubeSphere Authors.
+ * Please refer to the LICENSE file in the root directory of the project.
+ * https://github.com/kubesphere/kubesphere/blob/master/LICENSE
+ */
+
+package quay
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"kubesphere.io/kubesphere/pkg/models/registries/imagesearch"
+)
+
+func TestSearch(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/find/repositories" || r.URL.Query().Get("query") != "prometheus" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if r.Header.Get("Authorization") != "Bearer token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		_, _ = fmt.Fprint(w, `{"results":[
+{"kind":"repository","name":"prometheus","namespace":{"name":"prometheus"}},
+{"kind":"application","name":"prometheus-app","namespace":{"name":"coreos"}}]}`)
+	}))
+	defer server.Close()
+
+	provider, err := quaySearchProviderFactory{}.Create(nil)
+	assert.NoError(t, err)
+	results, err := provider.Search("prometheus", imagesearch.SearchConfig{Host: server.URL, Username: oauthTokenUsername, Password: "token"})
+	assert.NoError(t, err)
+	assert.Equal(t, &imagesearch.Results{Total: 1, Entries: []string{"prometheus/prometheus"}}, results)
+
+	// the credentials of the robot accounts are not sent
+	_, err = provider.Search("prometheus", imagesearch.SearchConfig{Host: server.URL, Username: "org+robot", Password: "token"})
+	assert.ErrorContains(t, err, "401")
+}
@@ -8,15 +8,25 @@ package imagesearch
 
 import (
 	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
 
-	jsoniter "github.com/json-iterator/go"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/types"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
 const (
 	HostDockerIo = "https://docker.io"
+	HostQuayIo   = "https://quay.io"
+
+	// ForceInsecureAnnotation skips the tls verification of the registry in the secret
+	ForceInsecureAnnotation = "secret.kubesphere.io/force-insecure"
 )
 
 var (
@@ -37,6 +47,8 @@ type SearchConfig struct {
 	ProviderType string
 	Username     string
 	Password     string
+	// Insecure skips the tls verification of the registry
+	Insecure bool
 }
 
 type SearchProviderFactory interface {
@@ -49,7 +61,9 @@ func RegistrySearchProvider(factory SearchProviderFactory) {
 }
 
 type SecretGetter interface {
-	GetSecretConfig(ctx context.Context, name, namespace string) (*SearchConfig, error)
+	// GetSecretConfig returns the search config of the registry in the secret, the registry can be omitted
+	// only if the secret holds the credential of a single registry.
+	GetSecretConfig(ctx context.Context, name, namespace, registry string) (*SearchConfig, error)
 }
 
 func NewSecretGetter(reader client.Reader) SecretGetter {
@@ -60,9 +74,15 @@ type secretGetter struct {
 	client.Reader
 }
 
+type dockerConfigEntry struct {
+	Username string `json:"username,omitempty"`
+	Password string `json:"password,omitempty"`
+	Auth     string `json:"auth,omitempty"`
+}
+
 // {"auths":{"https://harbor.172.31.19.17.nip.io":{"username":"admin","password":"Harbor12345","email":"","auth":"YWRtaW46SGFyYm9yMTIzNDU="}}}
 
-func (s *secretGetter) GetSecretConfig(ctx context.Context, name, namespace string) (*SearchConfig, error) {
+func (s *secretGetter) GetSecretConfig(ctx context.Context, name, namespace, registry string) (*SearchConfig, error) {
 	secret := &corev1.Secret{}
 	err := s.Get(ctx, types.NamespacedName{Namespace: namespace, Name: name}, secret)
 	if err != nil {
@@ -70,16 +90,97 @@ func (s *secretGetter) GetSecretConfig(ctx context.Context, name, namespace stri
 	}
 	provider := secret.Annotations[SecretTypeImageSearchProvider]
 
-	data := secret.Data[".dockerconfigjson"]
-	auths := jsoniter.Get(data, "auths")
+	auths, err := dockerConfigAuths(secret)
+	if err != nil {
+		return nil, errors.NewBadRequest(fmt.Sprintf("invalid registry secret %s: %s", name, err))
+	}
+	hosts := make([]string, 0, len(auths))
+	for host := range auths {
+		hosts = append(hosts, host)
+	}
+	sort.Strings(hosts)
+
+	var host string
+	switch {
+	case registry != "":
+		for _, h := range hosts {
+			if RegistryHost(h) == RegistryHost(registry) {
+				host = h
+				break
+			}
+		}
+		if host == "" {
+			return nil, errors.NewNotFound(corev1.Resource("registries"), registry)
+		}
+	case len(hosts) == 1:
+		host = hosts[0]
+	case len(hosts) == 0:
+		return nil, errors.NewBadRequest(fmt.Sprintf("registry secret %s has no registry", name))
+	default:
+		return nil, errors.NewBadRequest(fmt.Sprintf("registry secret %s has multiple registries, one of %s must be specified",
+			name, strings.Join(hosts, ", ")))
+	}
 
-	host := auths.Keys()[0]
-	username := auths.Get(host, "username").ToString()
-	password := auths.Get(host, "password").ToString()
+	entry := auths[host]
+	if entry.Username == "" && entry.Auth != "" {
+		if decoded, err := base64.StdEncoding.DecodeString(entry.Auth); err == nil {
+			entry.Username, entry.Password, _ = strings.Cut(string(decoded), ":")
+		}
+	}
 	return &SearchConfig{
 		Host:         host,
 		ProviderType: provider,
-		Username:     username,
-		Password:     password,
+		Username:     entry.Username,
+		Password:     entry.Password,
+		Insecure:     secret.Annotations[ForceInsecureAnnotation] == "true",
 	}, nil
 }
+
+// dockerConfigAuths returns the credentials of the registries in the secret of type
+// kubernetes.io/dockerconfigjson or kubernetes.io/dockercfg.
+func dockerConfigAuths(secret *corev1.Secret) (map[string]dockerConfigEntry, error) {
+	if data, ok := secret.Data[corev1.DockerConfigJsonKey]; ok {
+		config := struct {
+			Auths map[string]dockerConfigEntry `json:"auths"`
+		}{}
+		if err := json.Unmarshal(data, &config); err != nil {
+			return nil, err
+		}
+		return config.Auths, nil
+	}
+	if data, ok := secret.Data[corev1.DockerConfigKey]; ok {
+		auths := make(map[string]dockerConfigEntry)
+		if err := json.Unmarshal(data, &auths); err != nil {
+			return nil, err
+		}
+		return auths, nil
+	}
+	return nil, fmt.Errorf("expected key %s in data, found none", corev1.DockerConfigJsonKey)
+}
+
+// RegistryHost returns the host of the registry address, e.g. harbor.example.com for https://harbor.example.com/v2/,
+// all the addresses of Docker Hub are docker.io.
+func RegistryHost(address string) string {
+	host := addressHost(address)
+	switch host {
+	case "index.docker.io", "registry-1.docker.io", "registry.hub.docker.com", "hub.docker.com":
+		return "docker.io"
+	}
+	return host
+}
+
+// BaseURL returns the base URL of the registry address, https is used if the scheme is omitted.
+func BaseURL(address string) string {
+	if u, err := url.Parse(address); err == nil && u.Host != "" {
+		return fmt.Sprintf("%s://%s", u.Scheme, u.Host)
+	}
+	return fmt.Sprintf("https://%s", addressHost(address))
+}
+
+func addressHost(address string) string {
+	if u, err := url.Parse(address); err == nil && u.Host != "" {
+		return strings.ToLower(u.Host)
+	}
+	host, _, _ := strings.Cut(address, "/")
+	return strings.ToLower(host)
+}
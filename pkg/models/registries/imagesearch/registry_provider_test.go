@@ -0,0 +1,86 @@
+/*
+ * Copyright 2024 the KubeSphere Authors.
+ * Please refer to the LICENSE file in the root directory of the project.
+ * https://github.com/kubesphere/kubesphere/blob/master/LICENSE
+ */
+
+package imagesearch
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"kubesphere.io/kubesphere/pkg/scheme"
+)
+
+func TestGetSecretConfig(t *testing.T) {
+	secret := func(name string, data map[string][]byte) *corev1.Secret {
+		return &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "demo", Name: name},
+			Data:       data,
+		}
+	}
+	c := fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(
+		secret("single", map[string][]byte{corev1.DockerConfigJsonKey: []byte(
+			`{"auths":{"https://harbor.example.com":{"username":"admin","password":"Harbor12345"}}}`)}),
+		secret("multiple", map[string][]byte{corev1.DockerConfigJsonKey: []byte(
+			`{"auths":{"quay.io":{"auth":"JG9hdXRodG9rZW46dG9rZW4="},"https://index.docker.io/v1/":{"username":"user","password":"pass"}}}`)}),
+		secret("legacy", map[string][]byte{corev1.DockerConfigKey: []byte(
+			`{"registry.example.com:5000":{"username":"user","password":"pass"}}`)}),
+		&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "demo", Name: "insecure", Annotations: map[string]string{ForceInsecureAnnotation: "true"}},
+			Data: map[string][]byte{corev1.DockerConfigJsonKey: []byte(
+				`{"auths":{"registry.example.com":{"username":"user","password":"pass"}}}`)},
+		},
+	).Build()
+	getter := NewSecretGetter(c)
+	ctx := context.Background()
+
+	config, err := getter.GetSecretConfig(ctx, "single", "demo", "")
+	assert.NoError(t, err)
+	assert.Equal(t, &SearchConfig{Host: "https://harbor.example.com", Username: "admin", Password: "Harbor12345"}, config)
+
+	config, err = getter.GetSecretConfig(ctx, "insecure", "demo", "")
+	assert.NoError(t, err)
+	assert.True(t, config.Insecure)
+
+	_, err = getter.GetSecretConfig(ctx, "multiple", "demo", "")
+	assert.True(t, errors.IsBadRequest(err))
+	assert.ErrorContains(t, err, "https://index.docker.io/v1/, quay.io")
+
+	config, err = getter.GetSecretConfig(ctx, "multiple", "demo", "https://quay.io")
+	assert.NoError(t, err)
+	assert.Equal(t, &SearchConfig{Host: "quay.io", Username: "$oauthtoken", Password: "token"}, config)
+
+	config, err = getter.GetSecretConfig(ctx, "multiple", "demo", "docker.io")
+	assert.NoError(t, err)
+	assert.Equal(t, "https://index.docker.io/v1/", config.Host)
+
+	_, err = getter.GetSecretConfig(ctx, "multiple", "demo", "ghcr.io")
+	assert.True(t, errors.IsNotFound(err))
+
+	config, err = getter.GetSecretConfig(ctx, "legacy", "demo", "")
+	assert.NoError(t, err)
+	assert.Equal(t, "registry.example.com:5000", config.Host)
+	assert.Equal(t, "https://registry.example.com:5000", BaseURL(config.Host))
+}
+
+func TestRegistryHost(t *testing.T) {
+	for address, host := range map[string]string{
+		"https://index.docker.io/v1/": "docker.io",
+		"docker.io":                   "docker.io",
+		"HTTPS://Harbor.Example.com/": "harbor.example.com",
+		"localhost:5000/v2":           "localhost:5000",
+		"http://10.0.0.1:8080":        "10.0.0.1:8080",
+	} {
+		assert.Equal(t, host, RegistryHost(address), address)
+	}
+	assert.Equal(t, "http://10.0.0.1:8080", BaseURL("http://10.0.0.1:8080/v2/"))
+	assert.Equal(t, "https://index.docker.io", BaseURL("index.docker.io"))
+}
@@ -0,0 +1,217 @@
+/*
+ * Copyright 2024 the KubeSphere Authors.
+ * Please refer to the LICENSE file in the root directory of the project.
+ * https://github.com/kubesphere/kubesphere/blob/master/LICENSE
+ */
+
+package v2
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/google/go-containerregistry/pkg/v1/remote/transport"
+	"k8s.io/klog/v2"
+)
+
+const (
+	// the annotations of the attestation manifests added to the manifest list by docker buildx
+	dockerReferenceTypeAnnotation = "vnd.docker.reference.type"
+	attestationManifestType       = "attestation-manifest"
+)
+
+// cosignTagSuffixes are the suffixes of the tags of the artifacts attached by cosign without the referrers API.
+var cosignTagSuffixes = []struct {
+	suffix string
+	kind   ReferrerKind
+}{{".sig", ReferrerSignature}, {".att", ReferrerAttestation}, {".sbom", ReferrerSBOM}}
+
+func (r *registryer) Inspect(ctx context.Context, image string) (*ImageInspection, error) {
+	ref, err := name.ParseReference(image, r.opts.name...)
+	if err != nil {
+		return nil, err
+	}
+	opts := append(r.opts.remote[:len(r.opts.remote):len(r.opts.remote)], remote.WithContext(ctx))
+	desc, err := remote.Get(ref, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	inspection := &ImageInspection{
+		Registry:   ref.Context().RegistryStr(),
+		Repository: ref.Context().RepositoryStr(),
+		Digest:     desc.Digest.String(),
+		MediaType:  string(desc.MediaType),
+		Index:      desc.MediaType.IsIndex(),
+		Manifests:  make([]ImageManifestDetails, 0),
+		Referrers:  make([]ImageReferrer, 0),
+	}
+	switch {
+	case desc.MediaType.IsIndex():
+		index, err := desc.ImageIndex()
+		if err != nil {
+			return nil, err
+		}
+		indexManifest, err := index.IndexManifest()
+		if err != nil {
+			return nil, err
+		}
+		for _, manifest := range indexManifest.Manifests {
+			if manifest.Annotations[dockerReferenceTypeAnnotation] == attestationManifestType {
+				inspection.Referrers = append(inspection.Referrers, newImageReferrer(ReferrerAttestation, manifest))
+				continue
+			}
+			if !manifest.MediaType.IsImage() {
+				continue
+			}
+			img, err := index.Image(manifest.Digest)
+			if err != nil {
+				return nil, err
+			}
+			details, err := imageManifestDetails(img)
+			if err != nil {
+				return nil, err
+			}
+			if manifest.Platform != nil {
+				details.Platform = manifest.Platform.String()
+			}
+			inspection.Manifests = append(inspection.Manifests, *details)
+		}
+	case desc.MediaType.IsImage():
+		img, err := desc.Image()
+		if err != nil {
+			return nil, err
+		}
+		details, err := imageManifestDetails(img)
+		if err != nil {
+			return nil, err
+		}
+		if config, err := img.ConfigFile(); err == nil && config.Platform() != nil {
+			details.Platform = config.Platform().String()
+		}
+		inspection.Manifests = append(inspection.Manifests, *details)
+	default:
+		return nil, fmt.Errorf("unsupported media type %s", desc.MediaType)
+	}
+
+	referrers, unknown := referrers(ref.Context().Digest(desc.Digest.String()), opts)
+	inspection.Referrers = append(inspection.Referrers, referrers...)
+	inspection.UnknownReferrers = unknown
+	return inspection, nil
+}
+
+func imageManifestDetails(img v1.Image) (*ImageManifestDetails, error) {
+	digest, err := img.Digest()
+	if err != nil {
+		return nil, err
+	}
+	mediaType, err := img.MediaType()
+	if err != nil {
+		return nil, err
+	}
+	manifest, err := img.Manifest()
+	if err != nil {
+		return nil, err
+	}
+	details := &ImageManifestDetails{
+		Digest:    digest.String(),
+		MediaType: string(mediaType),
+		Layers:    make([]ImageLayer, 0, len(manifest.Layers)),
+	}
+	for _, layer := range manifest.Layers {
+		details.Layers = append(details.Layers, ImageLayer{
+			Digest:    layer.Digest.String(),
+			MediaType: string(layer.MediaType),
+			Size:      layer.Size,
+		})
+		details.CompressedSize += layer.Size
+	}
+	return details, nil
+}
+
+// referrers returns the artifacts referring to the digest by the referrers API, which falls back to
+// the referrers tag schema, and the artifacts attached by cosign with the tag schema. The kinds of the
+// artifacts which could not be checked are returned as unknown, all kinds are unknown if the referrers
+// could not be listed.
+func referrers(digest name.Digest, opts []remote.Option) ([]ImageReferrer, []ReferrerKind) {
+	var referrers []ImageReferrer
+	var unknown []ReferrerKind
+	indexManifest, err := listReferrers(digest, opts)
+	if err != nil {
+		// the image is still inspected, e.g. the registry may deny the request of the referrers API
+		klog.V(4).Infof("failed to list referrers of %s: %s", digest, err)
+		unknown = append(unknown, ReferrerSignature, ReferrerSBOM, ReferrerAttestation, ReferrerOther)
+	} else {
+		for _, manifest := range indexManifest.Manifests {
+			referrers = append(referrers, newImageReferrer(referrerKind(manifest.ArtifactType), manifest))
+		}
+	}
+
+	prefix := strings.Replace(digest.DigestStr(), ":", "-", 1)
+	for _, cosign := range cosignTagSuffixes {
+		tag := digest.Context().Tag(prefix + cosign.suffix)
+		desc, err := remote.Head(tag, opts...)
+		var terr *transport.Error
+		if errors.As(err, &terr) && terr.StatusCode == http.StatusNotFound {
+			continue
+		}
+		if err != nil {
+			// the image is still inspected, e.g. the registry may deny the request of the tag
+			klog.V(4).Infof("failed to get %s: %s", tag, err)
+			if !slices.Contains(unknown, cosign.kind) {
+				unknown = append(unknown, cosign.kind)
+			}
+			continue
+		}
+		referrer := newImageReferrer(cosign.kind, *desc)
+		referrer.Tag = tag.TagStr()
+		referrers = append(referrers, referrer)
+	}
+	return referrers, unknown
+}
+
+func listReferrers(digest name.Digest, opts []remote.Option) (*v1.IndexManifest, error) {
+	index, err := remote.Referrers(digest, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return index.IndexManifest()
+}
+
+func newImageReferrer(kind ReferrerKind, desc v1.Descriptor) ImageReferrer {
+	return ImageReferrer{
+		Kind:         kind,
+		Digest:       desc.Digest.String(),
+		MediaType:    string(desc.MediaType),
+		ArtifactType: desc.ArtifactType,
+		Size:         desc.Size,
+		Annotations:  desc.Annotations,
+	}
+}
+
+// referrerKind returns the kind of the artifact by the artifact type, e.g. application/vnd.dev.cosign.artifact.sig.v1+json.
+func referrerKind(artifactType string) ReferrerKind {
+	artifactType = strings.ToLower(artifactType)
+	switch {
+	case strings.Contains(artifactType, "cosign.artifact.sig"),
+		strings.Contains(artifactType, "sigstore.bundle"),
+		strings.Contains(artifactType, "notary.signature"):
+		return ReferrerSignature
+	case strings.Contains(artifactType, "spdx"),
+		strings.Contains(artifactType, "cyclonedx"),
+		strings.Contains(artifactType, "syft"),
+		strings.Contains(artifactType, "sbom"):
+		return ReferrerSBOM
+	case strings.Contains(artifactType, "in-toto"),
+		strings.Contains(artifactType, "attestation"):
+		return ReferrerAttestation
+	}
+	return ReferrerOther
+}
@@ -0,0 +1,181 @@
+/*
+ * Copyright 2024 the KubeSphere Authors.
+ * Please refer to the LICENSE file in the root directory of the project.
+ * https://github.com/kubesphere/kubesphere/blob/master/LICENSE
+ */
+
+package v2
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/google/go-containerregistry/pkg/v1/types"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+)
+
+type testManifest struct {
+	mediaType types.MediaType
+	content   string
+}
+
+func (m testManifest) digest() string {
+	return fmt.Sprintf("sha256:%x", sha256.Sum256([]byte(m.content)))
+}
+
+func (m testManifest) descriptor(extra string) string {
+	return fmt.Sprintf(`{"mediaType":%q,"digest":%q,"size":%d%s}`, m.mediaType, m.digest(), len(m.content), extra)
+}
+
+func newTestRegistry(t *testing.T) (*httptest.Server, testManifest) {
+	amd64 := testManifest{types.OCIManifestSchema1, `{"schemaVersion":2,"mediaType":"application/vnd.oci.image.manifest.v1+json",
+"config":{"mediaType":"application/vnd.oci.image.config.v1+json","digest":"sha256:` + strings.Repeat("a", 64) + `","size":10},
+"layers":[{"mediaType":"application/vnd.oci.image.layer.v1.tar+gzip","digest":"sha256:` + strings.Repeat("b", 64) + `","size":100},
+{"mediaType":"application/vnd.oci.image.layer.v1.tar+gzip","digest":"sha256:` + strings.Repeat("c", 64) + `","size":200}]}`}
+	arm64 := testManifest{types.OCIManifestSchema1, `{"schemaVersion":2,"mediaType":"application/vnd.oci.image.manifest.v1+json",
+"config":{"mediaType":"application/vnd.oci.image.config.v1+json","digest":"sha256:` + strings.Repeat("d", 64) + `","size":10},
+"layers":[{"mediaType":"application/vnd.oci.image.layer.v1.tar+gzip","digest":"sha256:` + strings.Repeat("e", 64) + `","size":50}]}`}
+	attestation := testManifest{types.OCIManifestSchema1, `{"schemaVersion":2,"mediaType":"application/vnd.oci.image.manifest.v1+json",
+"config":{"mediaType":"application/vnd.oci.image.config.v1+json","digest":"sha256:` + strings.Repeat("f", 64) + `","size":10},"layers":[]}`}
+	index := testManifest{types.OCIImageIndex, `{"schemaVersion":2,"mediaType":"application/vnd.oci.image.index.v1+json","manifests":[` +
+		amd64.descriptor(`,"platform":{"os":"linux","architecture":"amd64"}`) + `,` +
+		arm64.descriptor(`,"platform":{"os":"linux","architecture":"arm64","variant":"v8"}`) + `,` +
+		attestation.descriptor(`,"platform":{"os":"unknown","architecture":"unknown"},"annotations":{"vnd.docker.reference.type":"attestation-manifest"}`) + `]}`}
+	sbom := testManifest{types.OCIManifestSchema1, `{"schemaVersion":2}`}
+	referrers := testManifest{types.OCIImageIndex, `{"schemaVersion":2,"mediaType":"application/vnd.oci.image.index.v1+json","manifests":[` +
+		sbom.descriptor(`,"artifactType":"application/spdx+json"`) + `]}`}
+	signature := testManifest{types.OCIManifestSchema1, `{"schemaVersion":2,"layers":[]}`}
+
+	manifests := map[string]testManifest{"v1": index, strings.Replace(index.digest(), ":", "-", 1) + ".sig": signature}
+	for _, m := range []testManifest{index, amd64, arm64, attestation, sbom, signature} {
+		manifests[m.digest()] = m
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if user, password, _ := r.BasicAuth(); user != "admin" || password != "secret" {
+			w.Header().Set("WWW-Authenticate", `Basic realm="registry"`)
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		var m testManifest
+		switch {
+		case r.URL.Path == "/v2/":
+			return
+		case r.URL.Path == "/v2/app/referrers/"+index.digest():
+			m = referrers
+		case strings.HasSuffix(r.URL.Path, ".att"):
+			// the registry denies the request of the attestation tag
+			w.WriteHeader(http.StatusForbidden)
+			return
+		case strings.HasPrefix(r.URL.Path, "/v2/app/manifests/"):
+			var ok bool
+			if m, ok = manifests[strings.TrimPrefix(r.URL.Path, "/v2/app/manifests/")]; !ok {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", string(m.mediaType))
+		w.Header().Set("Docker-Content-Digest", m.digest())
+		w.Header().Set("Content-Length", strconv.Itoa(len(m.content)))
+		if r.Method != http.MethodHead {
+			_, _ = w.Write([]byte(m.content))
+		}
+	}))
+	return server, index
+}
+
+func TestInspect(t *testing.T) {
+	server, index := newTestRegistry(t)
+	defer server.Close()
+	host := strings.TrimPrefix(server.URL, "http://")
+	secret := &corev1.Secret{
+		Type: corev1.SecretTypeDockerConfigJson,
+		Data: map[string][]byte{corev1.DockerConfigJsonKey: []byte(fmt.Sprintf(
+			`{"auths":{"quay.io":{"username":"robot","password":"token"},%q:{"username":"admin","password":"secret"}}}`, host))},
+	}
+
+	inspection, err := NewRegistryHelper().Inspect(context.Background(), secret, host+"/app:v1")
+	assert.NoError(t, err)
+	assert.Equal(t, host, inspection.Registry)
+	assert.Equal(t, "app", inspection.Repository)
+	assert.Equal(t, index.digest(), inspection.Digest)
+	assert.True(t, inspection.Index)
+
+	assert.Len(t, inspection.Manifests, 2)
+	assert.Equal(t, "linux/amd64", inspection.Manifests[0].Platform)
+	assert.Len(t, inspection.Manifests[0].Layers, 2)
+	assert.Equal(t, int64(300), inspection.Manifests[0].CompressedSize)
+	assert.Equal(t, "linux/arm64/v8", inspection.Manifests[1].Platform)
+	assert.Equal(t, int64(50), inspection.Manifests[1].CompressedSize)
+
+	var kinds []ReferrerKind
+	for _, referrer := range inspection.Referrers {
+		kinds = append(kinds, referrer.Kind)
+	}
+	assert.Equal(t, []ReferrerKind{ReferrerAttestation, ReferrerSBOM, ReferrerSignature}, kinds)
+	assert.Equal(t, strings.Replace(index.digest(), ":", "-", 1)+".sig", inspection.Referrers[2].Tag)
+	assert.Equal(t, []ReferrerKind{ReferrerAttestation}, inspection.UnknownReferrers)
+
+	_, err = NewRegistryHelper().Inspect(context.Background(), nil, host+"/app:v1")
+	assert.Error(t, err)
+}
+
+func TestInspectWithoutReferrers(t *testing.T) {
+	server, index := newTestRegistry(t)
+	defer server.Close()
+	handler := server.Config.Handler
+	server.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// the registry denies the request of the referrers API
+		if strings.HasPrefix(r.URL.Path, "/v2/app/referrers/") {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		handler.ServeHTTP(w, r)
+	})
+	host := strings.TrimPrefix(server.URL, "http://")
+	secret := &corev1.Secret{
+		Type: corev1.SecretTypeDockerConfigJson,
+		Data: map[string][]byte{corev1.DockerConfigJsonKey: []byte(fmt.Sprintf(`{"auths":{%q:{"username":"admin","password":"secret"}}}`, host))},
+	}
+
+	inspection, err := NewRegistryHelper().Inspect(context.Background(), secret, host+"/app:v1")
+	assert.NoError(t, err)
+	assert.Equal(t, index.digest(), inspection.Digest)
+	var kinds []ReferrerKind
+	for _, referrer := range inspection.Referrers {
+		kinds = append(kinds, referrer.Kind)
+	}
+	assert.Equal(t, []ReferrerKind{ReferrerAttestation, ReferrerSignature}, kinds)
+	assert.Equal(t, []ReferrerKind{ReferrerSignature, ReferrerSBOM, ReferrerAttestation, ReferrerOther}, inspection.UnknownReferrers)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = NewRegistryHelper().Inspect(ctx, secret, host+"/app:v1")
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func TestReferrerKind(t *testing.T) {
+	for artifactType, kind := range map[string]ReferrerKind{
+		"application/vnd.dev.cosign.artifact.sig.v1+json": ReferrerSignature,
+		"application/vnd.dev.sigstore.bundle.v0.3+json":   ReferrerSignature,
+		"application/vnd.cncf.notary.signature":           ReferrerSignature,
+		"application/vnd.cyclonedx+json":                  ReferrerSBOM,
+		"application/vnd.in-toto+json":                    ReferrerAttestation,
+		"application/vnd.oci.image.config.v1+json":        ReferrerOther,
+		"":                          ReferrerOther,
+		"application/vnd.syft+json": ReferrerSBOM,
+		"application/vnd.dev.cosign.artifact.sbom.v1+json": ReferrerSBOM,
+	} {
+		assert.Equal(t, kind, referrerKind(artifactType), artifactType)
+	}
+}
@@ -7,6 +7,7 @@
 package v2
 
 import (
+	"context"
 	"sort"
 
 	"github.com/google/go-containerregistry/pkg/name"
@@ -20,6 +21,9 @@ type Registryer interface {
 
 	// get image config
 	Config(image string) (*v1.ConfigFile, error)
+
+	// inspect the manifests, the layers and the referrers of image
+	Inspect(ctx context.Context, image string) (*ImageInspection, error)
 }
 
 type registryer struct {
@@ -7,6 +7,9 @@
 package v2
 
 import (
+	"context"
+
+	"github.com/google/go-containerregistry/pkg/name"
 	corev1 "k8s.io/api/core/v1"
 )
 
@@ -19,6 +22,9 @@ type RegistryHelper interface {
 
 	// list all tags of given repository, experimental
 	ListRepositoryTags(secret *corev1.Secret, repository string) (RepositoryTags, error)
+
+	// inspect the platforms, the layers and the attached artifacts of the image
+	Inspect(ctx context.Context, secret *corev1.Secret, image string) (*ImageInspection, error)
 }
 
 type registryHelper struct{}
@@ -56,3 +62,17 @@ func (r *registryHelper) ListRepositoryTags(secret *corev1.Secret, image string)
 	registryer := NewRegistryer(secretAuth.Options()...)
 	return registryer.ListRepositoryTags(image)
 }
+
+func (r *registryHelper) Inspect(ctx context.Context, secret *corev1.Secret, image string) (*ImageInspection, error) {
+	secretAuth, err := NewSecretAuthenticator(secret)
+	if err != nil {
+		return nil, err
+	}
+	ref, err := name.ParseReference(image)
+	if err != nil {
+		return nil, err
+	}
+
+	registryer := NewRegistryer(secretAuth.RegistryOptions(ref.Context().RegistryStr())...)
+	return registryer.Inspect(ctx, image)
+}
@@ -18,15 +18,21 @@ import (
 	"github.com/google/go-containerregistry/pkg/name"
 	"github.com/google/go-containerregistry/pkg/v1/remote/transport"
 	v1 "k8s.io/api/core/v1"
+
+	"kubesphere.io/kubesphere/pkg/models/registries/imagesearch"
 )
 
 const (
-	forceInsecure = "secret.kubesphere.io/force-insecure"
+	forceInsecure = imagesearch.ForceInsecureAnnotation
 )
 
 type SecretAuthenticator interface {
 	Options() []Option
 
+	// RegistryOptions returns the options with the credential of the registry,
+	// which is used if the secret holds the credentials of multiple registries.
+	RegistryOptions(registry string) []Option
+
 	Auth() (bool, error)
 
 	Authorization() (*authn.AuthConfig, error)
@@ -139,6 +145,21 @@ func (s *secretAuthenticator) Options() []Option {
 	return options
 }
 
+func (s *secretAuthenticator) RegistryOptions(registry string) []Option {
+	options := s.Options()
+	for address, entry := range s.auths {
+		if imagesearch.RegistryHost(address) == imagesearch.RegistryHost(registry) {
+			options = append(options, WithAuth(authn.FromConfig(authn.AuthConfig{
+				Username: entry.Username,
+				Password: entry.Password,
+				Auth:     entry.Auth,
+			})))
+			break
+		}
+	}
+	return options
+}
+
 func (s *secretAuthenticator) registryScheme() string {
 	for registry := range s.auths {
 		u, err := url.Parse(registry)
@@ -42,3 +42,61 @@ type RepositoryTags struct {
 type ImageConfig struct {
 	*v1.ConfigFile `json:",inline"`
 }
+
+// ImageInspection describes the manifest of an image, the platform manifests of a manifest list
+// and the artifacts attached to it, e.g. signatures and SBOMs.
+type ImageInspection struct {
+	Registry   string `json:"registry"`
+	Repository string `json:"repository"`
+	// Digest is the digest of the manifest or the manifest list the image refers to.
+	Digest    string `json:"digest"`
+	MediaType string `json:"mediaType"`
+	// Index is true if the image refers to a manifest list or an OCI image index.
+	Index bool `json:"index"`
+	// Manifests are the image manifests, one for each platform if the image refers to a manifest list.
+	Manifests []ImageManifestDetails `json:"manifests"`
+	// Referrers are the artifacts referring to the digest, found by the referrers API and the tags of cosign.
+	Referrers []ImageReferrer `json:"referrers"`
+	// UnknownReferrers are the kinds of the artifacts which could not be checked, e.g. the signature is unknown
+	// if the registry fails to answer the request of the signature tag, and all kinds are unknown if the
+	// registry fails to answer the request of the referrers API.
+	UnknownReferrers []ReferrerKind `json:"unknownReferrers,omitempty"`
+}
+
+// ImageManifestDetails describes the layers of an image manifest for a platform.
+type ImageManifestDetails struct {
+	Digest    string `json:"digest"`
+	MediaType string `json:"mediaType"`
+	// Platform is in the form of os/architecture[/variant], e.g. linux/arm64/v8.
+	Platform string       `json:"platform,omitempty"`
+	Layers   []ImageLayer `json:"layers"`
+	// CompressedSize is the total size of the compressed layers.
+	CompressedSize int64 `json:"compressedSize"`
+}
+
+type ImageLayer struct {
+	Digest    string `json:"digest"`
+	MediaType string `json:"mediaType"`
+	Size      int64  `json:"size"`
+}
+
+type ReferrerKind string
+
+const (
+	ReferrerSignature   ReferrerKind = "signature"
+	ReferrerSBOM        ReferrerKind = "sbom"
+	ReferrerAttestation ReferrerKind = "attestation"
+	ReferrerOther       ReferrerKind = "other"
+)
+
+// ImageReferrer is an artifact attached to an image, e.g. a cosign signature.
+type ImageReferrer struct {
+	Kind         ReferrerKind `json:"kind"`
+	Digest       string       `json:"digest"`
+	MediaType    string       `json:"mediaType,omitempty"`
+	ArtifactType string       `json:"artifactType,omitempty"`
+	// Tag is the tag of the artifact attached by the tag scheme, e.g. sha256-<digest>.sig of cosign.
+	Tag         string            `json:"tag,omitempty"`
+	Size        int64             `json:"size,omitempty"`
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
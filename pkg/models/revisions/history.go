@@ -0,0 +1,438 @@
+/*
+ * Copyright 2024 the KubeSphere Authors.
+ * Please refer to the LICENSE file in the root directory of the project.
+ * https://github.com/kubesphere/kubesphere/blob/master/LICENSE
+ */
+
+package revisions
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apiserver/pkg/authentication/user"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/util/retry"
+	runtimeclient "sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	KindDeployments  = "deployments"
+	KindStatefulSets = "statefulsets"
+	KindDaemonSets   = "daemonsets"
+
+	ChangeCauseAnnotation        = "kubernetes.io/change-cause"
+	deploymentRevisionAnnotation = "deployment.kubernetes.io/revision"
+
+	ChangeAdded    = "added"
+	ChangeRemoved  = "removed"
+	ChangeModified = "modified"
+)
+
+// Revision is a revision in the history of a workload.
+type Revision struct {
+	Revision int64 `json:"revision" description:"revision number"`
+	// Name is the name of the ReplicaSet of the Deployments, or the ControllerRevision of the others.
+	Name              string      `json:"name" description:"name of the ReplicaSet or ControllerRevision"`
+	ChangeCause       string      `json:"changeCause,omitempty" description:"the change cause of the revision"`
+	Images            []string    `json:"images,omitempty" description:"images of the containers"`
+	Current           bool        `json:"current" description:"whether it's the current revision"`
+	CreationTimestamp metav1.Time `json:"creationTimestamp" description:"creation time of the revision"`
+}
+
+// RevisionDiff is the difference of the pod templates between two revisions.
+type RevisionDiff struct {
+	From    int64    `json:"from" description:"the revision compared from"`
+	To      int64    `json:"to" description:"the revision compared to"`
+	Changes []Change `json:"changes" description:"changes of the pod template"`
+}
+
+// Change is a change of a field of the pod template, the path is like spec.containers[nginx].image,
+// the list items with names are identified by the names, the others by the indexes.
+type Change struct {
+	Path string      `json:"path" description:"path of the field"`
+	Type string      `json:"type" description:"added, removed or modified"`
+	From interface{} `json:"from,omitempty" description:"value in the revision compared from"`
+	To   interface{} `json:"to,omitempty" description:"value in the revision compared to"`
+}
+
+type Operator interface {
+	ListRevisions(ctx context.Context, kind, namespace, name string) ([]Revision, error)
+	// DiffRevisions compares the pod templates of the revisions, the current revision is compared to if to is 0.
+	DiffRevisions(ctx context.Context, kind, namespace, name string, from, to int64) (*RevisionDiff, error)
+	// Rollback sets the pod template of the workload to the template of the revision, the workload
+	// controller then creates a new revision for it, like kubectl rollout undo. The workload is updated
+	// by impersonating the user, so that the update is authorized and audited as the user.
+	Rollback(ctx context.Context, user user.Info, kind, namespace, name string, revision int64, changeCause string) (runtimeclient.Object, error)
+}
+
+type operator struct {
+	client runtimeclient.Client
+	// impersonate returns the client which impersonates the user
+	impersonate func(user user.Info) (runtimeclient.Client, error)
+}
+
+func NewOperator(client runtimeclient.Client, config *rest.Config) Operator {
+	return &operator{
+		client: client,
+		impersonate: func(user user.Info) (runtimeclient.Client, error) {
+			conf := rest.CopyConfig(config)
+			conf.Impersonate = rest.ImpersonationConfig{
+				UserName: user.GetName(),
+				UID:      user.GetUID(),
+				Groups:   user.GetGroups(),
+				Extra:    user.GetExtra(),
+			}
+			return runtimeclient.New(conf, runtimeclient.Options{Scheme: client.Scheme(), Mapper: client.RESTMapper()})
+		},
+	}
+}
+
+type workloadRevision struct {
+	Revision
+	template corev1.PodTemplateSpec
+}
+
+// IsWorkloadKind returns true if the kind supports revision history.
+func IsWorkloadKind(kind string) bool {
+	return kind == KindDeployments || kind == KindStatefulSets || kind == KindDaemonSets
+}
+
+func newWorkload(kind string) (runtimeclient.Object, error) {
+	switch kind {
+	case KindDeployments:
+		return &appsv1.Deployment{}, nil
+	case KindStatefulSets:
+		return &appsv1.StatefulSet{}, nil
+	case KindDaemonSets:
+		return &appsv1.DaemonSet{}, nil
+	}
+	return nil, apierrors.NewBadRequest(fmt.Sprintf("unsupported workload kind %s", kind))
+}
+
+func workloadSelector(workload runtimeclient.Object) *metav1.LabelSelector {
+	switch workload := workload.(type) {
+	case *appsv1.Deployment:
+		return workload.Spec.Selector
+	case *appsv1.StatefulSet:
+		return workload.Spec.Selector
+	case *appsv1.DaemonSet:
+		return workload.Spec.Selector
+	}
+	return nil
+}
+
+// history returns the revisions owned by the workload sorted from the latest one, the latest revision
+// is the current one, since the controllers renumber the revision rolled back to.
+func (o *operator) history(ctx context.Context, kind, namespace, name string) (runtimeclient.Object, []workloadRevision, error) {
+	workload, err := newWorkload(kind)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := o.client.Get(ctx, types.NamespacedName{Namespace: namespace, Name: name}, workload); err != nil {
+		return nil, nil, err
+	}
+	selector, err := metav1.LabelSelectorAsSelector(workloadSelector(workload))
+	if err != nil {
+		return nil, nil, err
+	}
+	listOptions := []runtimeclient.ListOption{runtimeclient.InNamespace(namespace), runtimeclient.MatchingLabelsSelector{Selector: selector}}
+
+	var revisions []workloadRevision
+	if kind == KindDeployments {
+		replicaSets := &appsv1.ReplicaSetList{}
+		if err := o.client.List(ctx, replicaSets, listOptions...); err != nil {
+			return nil, nil, err
+		}
+		for i := range replicaSets.Items {
+			rs := &replicaSets.Items[i]
+			revision, err := strconv.ParseInt(rs.Annotations[deploymentRevisionAnnotation], 10, 64)
+			if err != nil || !metav1.IsControlledBy(rs, workload) {
+				continue
+			}
+			template := *rs.Spec.Template.DeepCopy()
+			delete(template.Labels, appsv1.DefaultDeploymentUniqueLabelKey)
+			revisions = append(revisions, newWorkloadRevision(revision, &rs.ObjectMeta, template))
+		}
+	} else {
+		controllerRevisions := &appsv1.ControllerRevisionList{}
+		if err := o.client.List(ctx, controllerRevisions, listOptions...); err != nil {
+			return nil, nil, err
+		}
+		for i := range controllerRevisions.Items {
+			cr := &controllerRevisions.Items[i]
+			if !metav1.IsControlledBy(cr, workload) {
+				continue
+			}
+			template, err := controllerRevisionTemplate(cr)
+			if err != nil {
+				return nil, nil, err
+			}
+			revisions = append(revisions, newWorkloadRevision(cr.Revision, &cr.ObjectMeta, template))
+		}
+	}
+
+	sort.Slice(revisions, func(i, j int) bool {
+		return revisions[i].Revision.Revision > revisions[j].Revision.Revision
+	})
+	if len(revisions) > 0 {
+		revisions[0].Current = true
+	}
+	return workload, revisions, nil
+}
+
+func newWorkloadRevision(revision int64, meta *metav1.ObjectMeta, template corev1.PodTemplateSpec) workloadRevision {
+	images := make([]string, 0, len(template.Spec.Containers))
+	for _, container := range template.Spec.Containers {
+		images = append(images, container.Image)
+	}
+	return workloadRevision{
+		Revision: Revision{
+			Revision:          revision,
+			Name:              meta.Name,
+			ChangeCause:       meta.Annotations[ChangeCauseAnnotation],
+			Images:            images,
+			CreationTimestamp: meta.CreationTimestamp,
+		},
+		template: template,
+	}
+}
+
+// controllerRevisionTemplate returns the pod template in the patch saved by the StatefulSet and DaemonSet controllers,
+// e.g. {"spec":{"template":{"$patch":"replace",...}}}.
+func controllerRevisionTemplate(cr *appsv1.ControllerRevision) (corev1.PodTemplateSpec, error) {
+	var template corev1.PodTemplateSpec
+	raw := cr.Data.Raw
+	if len(raw) == 0 && cr.Data.Object != nil {
+		var err error
+		if raw, err = json.Marshal(cr.Data.Object); err != nil {
+			return template, err
+		}
+	}
+	patch := struct {
+		Spec struct {
+			Template corev1.PodTemplateSpec `json:"template"`
+		} `json:"spec"`
+	}{}
+	if err := json.Unmarshal(raw, &patch); err != nil {
+		return template, fmt.Errorf("failed to decode controller revision %s: %s", cr.Name, err)
+	}
+	return patch.Spec.Template, nil
+}
+
+func (o *operator) ListRevisions(ctx context.Context, kind, namespace, name string) ([]Revision, error) {
+	_, history, err := o.history(ctx, kind, namespace, name)
+	if err != nil {
+		return nil, err
+	}
+	revisions := make([]Revision, 0, len(history))
+	for _, revision := range history {
+		revisions = append(revisions, revision.Revision)
+	}
+	return revisions, nil
+}
+
+func findRevision(kind, name string, history []workloadRevision, revision int64) (*workloadRevision, error) {
+	for i := range history {
+		if history[i].Revision.Revision == revision {
+			return &history[i], nil
+		}
+	}
+	resource := "controllerrevisions"
+	if kind == KindDeployments {
+		resource = "replicasets"
+	}
+	return nil, apierrors.NewNotFound(schema.GroupResource{Group: appsv1.GroupName, Resource: resource}, fmt.Sprintf("%s#%d", name, revision))
+}
+
+func (o *operator) DiffRevisions(ctx context.Context, kind, namespace, name string, from, to int64) (*RevisionDiff, error) {
+	_, history, err := o.history(ctx, kind, namespace, name)
+	if err != nil {
+		return nil, err
+	}
+	fromRevision, err := findRevision(kind, name, history, from)
+	if err != nil {
+		return nil, err
+	}
+	// compare to the current revision by default
+	if to == 0 && len(history) > 0 {
+		to = history[0].Revision.Revision
+	}
+	toRevision, err := findRevision(kind, name, history, to)
+	if err != nil {
+		return nil, err
+	}
+	fromObj, err := runtime.DefaultUnstructuredConverter.ToUnstructured(&fromRevision.template)
+	if err != nil {
+		return nil, err
+	}
+	toObj, err := runtime.DefaultUnstructuredConverter.ToUnstructured(&toRevision.template)
+	if err != nil {
+		return nil, err
+	}
+	result := &RevisionDiff{From: from, To: to, Changes: make([]Change, 0)}
+	diff("", fromObj, toObj, &result.Changes)
+	return result, nil
+}
+
+// diff appends the changes from the value a to the value b at the path.
+func diff(path string, a, b interface{}, changes *[]Change) {
+	mapA, okA := a.(map[string]interface{})
+	mapB, okB := b.(map[string]interface{})
+	if okA && okB {
+		keys := make(map[string]struct{}, len(mapA)+len(mapB))
+		for key := range mapA {
+			keys[key] = struct{}{}
+		}
+		for key := range mapB {
+			keys[key] = struct{}{}
+		}
+		sortedKeys := make([]string, 0, len(keys))
+		for key := range keys {
+			sortedKeys = append(sortedKeys, key)
+		}
+		sort.Strings(sortedKeys)
+		for _, key := range sortedKeys {
+			valueA, inA := mapA[key]
+			valueB, inB := mapB[key]
+			childPath := key
+			if path != "" {
+				childPath = path + "." + key
+			}
+			switch {
+			case !inA:
+				*changes = append(*changes, Change{Path: childPath, Type: ChangeAdded, To: valueB})
+			case !inB:
+				*changes = append(*changes, Change{Path: childPath, Type: ChangeRemoved, From: valueA})
+			default:
+				diff(childPath, valueA, valueB, changes)
+			}
+		}
+		return
+	}
+
+	listA, okA := a.([]interface{})
+	listB, okB := b.([]interface{})
+	if okA && okB {
+		diffList(path, listA, listB, changes)
+		return
+	}
+
+	if !reflect.DeepEqual(a, b) {
+		*changes = append(*changes, Change{Path: path, Type: ChangeModified, From: a, To: b})
+	}
+}
+
+// diffList compares the items with the same names if all the items have names, e.g. the containers,
+// otherwise the items with the same indexes.
+func diffList(path string, a, b []interface{}, changes *[]Change) {
+	namesA, namedA := itemNames(a)
+	namesB, namedB := itemNames(b)
+	if namedA && namedB {
+		indexB := make(map[string]int, len(namesB))
+		for i, name := range namesB {
+			indexB[name] = i
+		}
+		for i, name := range namesA {
+			itemPath := fmt.Sprintf("%s[%s]", path, name)
+			if j, ok := indexB[name]; ok {
+				diff(itemPath, a[i], b[j], changes)
+				delete(indexB, name)
+				continue
+			}
+			*changes = append(*changes, Change{Path: itemPath, Type: ChangeRemoved, From: a[i]})
+		}
+		for j, name := range namesB {
+			if _, ok := indexB[name]; ok {
+				*changes = append(*changes, Change{Path: fmt.Sprintf("%s[%s]", path, name), Type: ChangeAdded, To: b[j]})
+			}
+		}
+		return
+	}
+
+	for i := 0; i < len(a) || i < len(b); i++ {
+		itemPath := fmt.Sprintf("%s[%d]", path, i)
+		switch {
+		case i >= len(a):
+			*changes = append(*changes, Change{Path: itemPath, Type: ChangeAdded, To: b[i]})
+		case i >= len(b):
+			*changes = append(*changes, Change{Path: itemPath, Type: ChangeRemoved, From: a[i]})
+		default:
+			diff(itemPath, a[i], b[i], changes)
+		}
+	}
+}
+
+func itemNames(items []interface{}) ([]string, bool) {
+	names := make([]string, 0, len(items))
+	for _, item := range items {
+		obj, ok := item.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		name, ok := obj["name"].(string)
+		if !ok || name == "" || strings.ContainsAny(name, "[]") {
+			return nil, false
+		}
+		names = append(names, name)
+	}
+	return names, true
+}
+
+func (o *operator) Rollback(ctx context.Context, user user.Info, kind, namespace, name string, revision int64, changeCause string) (runtimeclient.Object, error) {
+	workload, history, err := o.history(ctx, kind, namespace, name)
+	if err != nil {
+		return nil, err
+	}
+	if deployment, ok := workload.(*appsv1.Deployment); ok && deployment.Spec.Paused {
+		return nil, apierrors.NewBadRequest(fmt.Sprintf("cannot rollback paused deployment %s", name))
+	}
+	target, err := findRevision(kind, name, history, revision)
+	if err != nil {
+		return nil, err
+	}
+	client, err := o.impersonate(user)
+	if err != nil {
+		return nil, err
+	}
+
+	err = retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		if err := client.Get(ctx, types.NamespacedName{Namespace: namespace, Name: name}, workload); err != nil {
+			return err
+		}
+		template := target.template.DeepCopy()
+		switch workload := workload.(type) {
+		case *appsv1.Deployment:
+			workload.Spec.Template = *template
+		case *appsv1.StatefulSet:
+			workload.Spec.Template = *template
+		case *appsv1.DaemonSet:
+			workload.Spec.Template = *template
+		}
+		if changeCause != "" {
+			annotations := workload.GetAnnotations()
+			if annotations == nil {
+				annotations = make(map[string]string)
+			}
+			annotations[ChangeCauseAnnotation] = changeCause
+			workload.SetAnnotations(annotations)
+		}
+		return client.Update(ctx, workload)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return workload, nil
+}
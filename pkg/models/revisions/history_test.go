@@ -0,0 +1,178 @@
+/*
+ * Copyright 2024 the KubeSphere Authors.
+ * Please refer to the LICENSE file in the root directory of the project.
+ * https://github.com/kubesphere/kubesphere/blob/master/LICENSE
+ */
+
+package revisions
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apiserver/pkg/authentication/user"
+	"k8s.io/utils/ptr"
+	runtimeclient "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"kubesphere.io/kubesphere/pkg/scheme"
+)
+
+var selector = &metav1.LabelSelector{MatchLabels: map[string]string{"app": "nginx"}}
+
+func podTemplate(image string, env ...corev1.EnvVar) corev1.PodTemplateSpec {
+	return corev1.PodTemplateSpec{
+		ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{"app": "nginx"}},
+		Spec: corev1.PodSpec{Containers: []corev1.Container{
+			{Name: "nginx", Image: image, Env: env},
+			{Name: "sidecar", Image: "busybox"},
+		}},
+	}
+}
+
+func ownerReference(kind, name string) []metav1.OwnerReference {
+	return []metav1.OwnerReference{{APIVersion: "apps/v1", Kind: kind, Name: name, UID: types.UID(name), Controller: ptr.To(true)}}
+}
+
+func replicaSet(name, revision string, owner string, template corev1.PodTemplateSpec) *appsv1.ReplicaSet {
+	template.Labels = map[string]string{"app": "nginx", appsv1.DefaultDeploymentUniqueLabelKey: name}
+	return &appsv1.ReplicaSet{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            name,
+			Namespace:       "default",
+			Labels:          map[string]string{"app": "nginx"},
+			Annotations:     map[string]string{deploymentRevisionAnnotation: revision, ChangeCauseAnnotation: "image " + template.Spec.Containers[0].Image},
+			OwnerReferences: ownerReference("Deployment", owner),
+		},
+		Spec: appsv1.ReplicaSetSpec{Selector: selector, Template: template},
+	}
+}
+
+func TestDeploymentRevisions(t *testing.T) {
+	deployment := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "nginx", Namespace: "default", UID: "nginx"},
+		Spec:       appsv1.DeploymentSpec{Selector: selector, Template: podTemplate("nginx:1.26")},
+	}
+	client := fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(
+		deployment,
+		replicaSet("nginx-1", "1", "nginx", podTemplate("nginx:1.25", corev1.EnvVar{Name: "DEBUG", Value: "true"})),
+		replicaSet("nginx-2", "2", "nginx", podTemplate("nginx:1.26")),
+		// owned by another deployment with the same labels
+		replicaSet("other-3", "3", "other", podTemplate("nginx:1.27")),
+	).Build()
+	var impersonated user.Info
+	operator := &operator{client: client, impersonate: func(user user.Info) (runtimeclient.Client, error) {
+		impersonated = user
+		return client, nil
+	}}
+	ctx := context.Background()
+	admin := &user.DefaultInfo{Name: "admin"}
+
+	revisions, err := operator.ListRevisions(ctx, KindDeployments, "default", "nginx")
+	assert.NoError(t, err)
+	assert.Len(t, revisions, 2)
+	assert.Equal(t, int64(2), revisions[0].Revision)
+	assert.True(t, revisions[0].Current)
+	assert.False(t, revisions[1].Current)
+	assert.Equal(t, "image nginx:1.25", revisions[1].ChangeCause)
+	assert.Equal(t, []string{"nginx:1.25", "busybox"}, revisions[1].Images)
+
+	diff, err := operator.DiffRevisions(ctx, KindDeployments, "default", "nginx", 1, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), diff.To)
+	assert.Equal(t, []Change{
+		{Path: "spec.containers[nginx].env", Type: ChangeRemoved, From: []interface{}{map[string]interface{}{"name": "DEBUG", "value": "true"}}},
+		{Path: "spec.containers[nginx].image", Type: ChangeModified, From: "nginx:1.25", To: "nginx:1.26"},
+	}, diff.Changes)
+
+	_, err = operator.DiffRevisions(ctx, KindDeployments, "default", "nginx", 3, 2)
+	assert.True(t, apierrors.IsNotFound(err))
+
+	_, err = operator.Rollback(ctx, admin, KindDeployments, "default", "nginx", 1, "rollback to revision 1 by admin")
+	assert.NoError(t, err)
+	assert.Equal(t, admin, impersonated)
+	updated := &appsv1.Deployment{}
+	assert.NoError(t, client.Get(ctx, types.NamespacedName{Namespace: "default", Name: "nginx"}, updated))
+	assert.Equal(t, "nginx:1.25", updated.Spec.Template.Spec.Containers[0].Image)
+	assert.Equal(t, map[string]string{"app": "nginx"}, updated.Spec.Template.Labels)
+	assert.Equal(t, "rollback to revision 1 by admin", updated.Annotations[ChangeCauseAnnotation])
+
+	updated.Spec.Paused = true
+	assert.NoError(t, client.Update(ctx, updated))
+	_, err = operator.Rollback(ctx, admin, KindDeployments, "default", "nginx", 2, "")
+	assert.True(t, apierrors.IsBadRequest(err))
+}
+
+func TestStatefulSetRevisions(t *testing.T) {
+	statefulSet := &appsv1.StatefulSet{
+		ObjectMeta: metav1.ObjectMeta{Name: "nginx", Namespace: "default", UID: "nginx"},
+		Spec:       appsv1.StatefulSetSpec{Selector: selector, Template: podTemplate("nginx:1.26")},
+	}
+	controllerRevision := func(name string, revision int64, template corev1.PodTemplateSpec) *appsv1.ControllerRevision {
+		patch, err := json.Marshal(map[string]interface{}{"spec": map[string]interface{}{"template": template}})
+		assert.NoError(t, err)
+		return &appsv1.ControllerRevision{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:            name,
+				Namespace:       "default",
+				Labels:          map[string]string{"app": "nginx"},
+				OwnerReferences: ownerReference("StatefulSet", "nginx"),
+			},
+			Data:     runtime.RawExtension{Raw: patch},
+			Revision: revision,
+		}
+	}
+	client := fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(
+		statefulSet,
+		controllerRevision("nginx-a", 1, podTemplate("nginx:1.25")),
+		controllerRevision("nginx-b", 2, podTemplate("nginx:1.26")),
+	).Build()
+	operator := &operator{client: client, impersonate: func(user.Info) (runtimeclient.Client, error) {
+		return client, nil
+	}}
+	ctx := context.Background()
+
+	revisions, err := operator.ListRevisions(ctx, KindStatefulSets, "default", "nginx")
+	assert.NoError(t, err)
+	assert.Len(t, revisions, 2)
+	assert.Equal(t, "nginx-b", revisions[0].Name)
+
+	diff, err := operator.DiffRevisions(ctx, KindStatefulSets, "default", "nginx", 2, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, []Change{{Path: "spec.containers[nginx].image", Type: ChangeModified, From: "nginx:1.26", To: "nginx:1.25"}}, diff.Changes)
+
+	_, err = operator.Rollback(ctx, &user.DefaultInfo{Name: "admin"}, KindStatefulSets, "default", "nginx", 1, "")
+	assert.NoError(t, err)
+	updated := &appsv1.StatefulSet{}
+	assert.NoError(t, client.Get(ctx, types.NamespacedName{Namespace: "default", Name: "nginx"}, updated))
+	assert.Equal(t, "nginx:1.25", updated.Spec.Template.Spec.Containers[0].Image)
+
+	_, err = operator.ListRevisions(ctx, "cronjobs", "default", "nginx")
+	assert.True(t, apierrors.IsBadRequest(err))
+}
+
+func TestDiffList(t *testing.T) {
+	var changes []Change
+	diff("args", []interface{}{"a", "b"}, []interface{}{"a", "c", "d"}, &changes)
+	assert.Equal(t, []Change{
+		{Path: "args[1]", Type: ChangeModified, From: "b", To: "c"},
+		{Path: "args[2]", Type: ChangeAdded, To: "d"},
+	}, changes)
+
+	changes = nil
+	diff("volumes",
+		[]interface{}{map[string]interface{}{"name": "data"}, map[string]interface{}{"name": "logs"}},
+		[]interface{}{map[string]interface{}{"name": "config"}, map[string]interface{}{"name": "data"}}, &changes)
+	assert.Equal(t, []Change{
+		{Path: "volumes[logs]", Type: ChangeRemoved, From: map[string]interface{}{"name": "logs"}},
+		{Path: "volumes[config]", Type: ChangeAdded, To: map[string]interface{}{"name": "config"}},
+	}, changes)
+}
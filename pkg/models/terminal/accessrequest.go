@@ -0,0 +1,165 @@
+/*
+ * Copyright 2024 the KubeSphere Authors.
+ * Please refer to the LICENSE file in the root directory of the project.
+ * https://github.com/kubesphere/kubesphere/blob/master/LICENSE
+ */
+
+package terminal
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apiserver/pkg/authentication/user"
+	iamv1beta1 "kubesphere.io/api/iam/v1beta1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"kubesphere.io/kubesphere/pkg/apiserver/authorization/authorizer"
+	"kubesphere.io/kubesphere/pkg/apiserver/request"
+)
+
+// AccessRequestUserIndex is the field index of the access requests by the requester.
+const AccessRequestUserIndex = "spec.user"
+
+var (
+	// ErrSelfApproval is returned if the user approves or denies their own access request.
+	ErrSelfApproval = errors.New("the access request can not be approved or denied by the requester")
+	// ErrAccessRequestDecided is returned if the access request has been approved or denied.
+	ErrAccessRequestDecided = errors.New("the access request has been approved or denied")
+	// ErrApproverNotAuthorized is returned if the approver is not allowed to access the target of the access request.
+	ErrApproverNotAuthorized = errors.New("the approver is not allowed to access the target of the access request")
+)
+
+// AccessRequestIndexByUser indexes the access requests by the requester, see AccessRequestUserIndex.
+func AccessRequestIndexByUser(obj client.Object) []string {
+	return []string{obj.(*iamv1beta1.AccessRequest).Spec.User}
+}
+
+// AccessRequestDecision is the decision of the approver.
+type AccessRequestDecision struct {
+	Comment string `json:"comment,omitempty"`
+}
+
+// ValidateAccessRequest checks the target and the duration of the access request.
+func ValidateAccessRequest(spec *iamv1beta1.AccessRequestSpec, maxDuration time.Duration) error {
+	switch spec.Type {
+	case iamv1beta1.AccessRequestTypePodExec:
+		if spec.Namespace == "" || spec.Pod == "" {
+			return fmt.Errorf("namespace and pod are required by %s", spec.Type)
+		}
+	case iamv1beta1.AccessRequestTypeNodeShell:
+		if spec.Node == "" {
+			return fmt.Errorf("node is required by %s", spec.Type)
+		}
+	default:
+		return fmt.Errorf("unsupported access request type %q", spec.Type)
+	}
+	if spec.Reason == "" {
+		return fmt.Errorf("reason is required")
+	}
+	if spec.Duration.Duration <= 0 {
+		return fmt.Errorf("duration must be positive")
+	}
+	if maxDuration > 0 && spec.Duration.Duration > maxDuration {
+		return fmt.Errorf("duration %s exceeds the maximum %s", spec.Duration.Duration, maxDuration)
+	}
+	return nil
+}
+
+// ActiveAccessRequest returns the approved and unexpired access request of the user to the target,
+// the one expiring last is returned if there are more than one. It returns nil if not found.
+// The access requests are listed by the AccessRequestUserIndex of the cache.
+func ActiveAccessRequest(ctx context.Context, c client.Reader, username string, target iamv1beta1.AccessRequestSpec) (*iamv1beta1.AccessRequest, error) {
+	accessRequests := &iamv1beta1.AccessRequestList{}
+	if err := c.List(ctx, accessRequests, client.MatchingFields{AccessRequestUserIndex: username}); err != nil {
+		return nil, fmt.Errorf("failed to list access requests: %s", err)
+	}
+	now := time.Now()
+	var active *iamv1beta1.AccessRequest
+	for i := range accessRequests.Items {
+		accessRequest := &accessRequests.Items[i]
+		spec := accessRequest.Spec
+		if spec.User != username || spec.Type != target.Type ||
+			spec.Namespace != target.Namespace || spec.Pod != target.Pod || spec.Node != target.Node {
+			continue
+		}
+		if accessRequest.Status.Phase != iamv1beta1.AccessRequestApproved ||
+			accessRequest.Status.ExpirationTime == nil || !now.Before(accessRequest.Status.ExpirationTime.Time) {
+			continue
+		}
+		if active == nil || active.Status.ExpirationTime.Before(accessRequest.Status.ExpirationTime) {
+			active = accessRequest
+		}
+	}
+	return active, nil
+}
+
+// DecideAccessRequest approves or denies the pending access request, the granted access expires after the
+// requested duration since the approval. The approver must be allowed to access the target of the access request.
+func DecideAccessRequest(ctx context.Context, c client.Client, authz authorizer.Authorizer, name string, approver user.Info, approved bool, decision AccessRequestDecision) (*iamv1beta1.AccessRequest, error) {
+	accessRequest := &iamv1beta1.AccessRequest{}
+	if err := c.Get(ctx, types.NamespacedName{Name: name}, accessRequest); err != nil {
+		return nil, err
+	}
+	if accessRequest.Spec.User == approver.GetName() {
+		return nil, ErrSelfApproval
+	}
+	if phase := accessRequest.Status.Phase; phase != "" && phase != iamv1beta1.AccessRequestPending {
+		return nil, ErrAccessRequestDecided
+	}
+	if approved {
+		allowed, err := authorizeTarget(authz, approver, &accessRequest.Spec)
+		if err != nil {
+			return nil, err
+		}
+		if !allowed {
+			return nil, ErrApproverNotAuthorized
+		}
+	}
+	now := metav1.Now()
+	accessRequest.Status.Approver = approver.GetName()
+	accessRequest.Status.Comment = decision.Comment
+	accessRequest.Status.DecisionTime = &now
+	if approved {
+		accessRequest.Status.Phase = iamv1beta1.AccessRequestApproved
+		accessRequest.Status.ExpirationTime = &metav1.Time{Time: now.Add(accessRequest.Spec.Duration.Duration)}
+	} else {
+		accessRequest.Status.Phase = iamv1beta1.AccessRequestDenied
+	}
+	if err := c.Status().Update(ctx, accessRequest); err != nil {
+		return nil, err
+	}
+	return accessRequest, nil
+}
+
+// authorizeTarget checks whether the user is allowed to open the terminal session requested by the access request.
+func authorizeTarget(authz authorizer.Authorizer, user user.Info, spec *iamv1beta1.AccessRequestSpec) (bool, error) {
+	attributes := authorizer.AttributesRecord{
+		User:            user,
+		Verb:            "create",
+		Subresource:     "exec",
+		ResourceRequest: true,
+	}
+	switch spec.Type {
+	case iamv1beta1.AccessRequestTypePodExec:
+		attributes.Resource = "pods"
+		attributes.Name = spec.Pod
+		attributes.Namespace = spec.Namespace
+		attributes.ResourceScope = request.NamespaceScope
+	case iamv1beta1.AccessRequestTypeNodeShell:
+		attributes.Resource = "nodes"
+		attributes.Name = spec.Node
+		attributes.ResourceScope = request.ClusterScope
+	default:
+		return false, fmt.Errorf("unsupported access request type %q", spec.Type)
+	}
+	decision, _, err := authz.Authorize(attributes)
+	if err != nil {
+		return false, err
+	}
+	return decision == authorizer.DecisionAllow, nil
+}
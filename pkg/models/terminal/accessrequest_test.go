@@ -0,0 +1,114 @@
+/*
+ * Copyright 2024 the KubeSphere Authors.
+ * Please refer to the LICENSE file in the root directory of the project.
+ * https://github.com/kubesphere/kubesphere/blob/master/LICENSE
+ */
+
+package terminal
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apiserver/pkg/authentication/user"
+	iamv1beta1 "kubesphere.io/api/iam/v1beta1"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"kubesphere.io/kubesphere/pkg/apiserver/authorization/authorizer"
+	"kubesphere.io/kubesphere/pkg/scheme"
+)
+
+func TestValidateAccessRequest(t *testing.T) {
+	tests := []struct {
+		name    string
+		spec    iamv1beta1.AccessRequestSpec
+		wantErr bool
+	}{
+		{
+			name: "pod exec",
+			spec: iamv1beta1.AccessRequestSpec{Type: iamv1beta1.AccessRequestTypePodExec, Namespace: "default", Pod: "nginx", Reason: "debug", Duration: metav1.Duration{Duration: time.Hour}},
+		},
+		{
+			name:    "missing pod",
+			spec:    iamv1beta1.AccessRequestSpec{Type: iamv1beta1.AccessRequestTypePodExec, Namespace: "default", Reason: "debug", Duration: metav1.Duration{Duration: time.Hour}},
+			wantErr: true,
+		},
+		{
+			name:    "missing reason",
+			spec:    iamv1beta1.AccessRequestSpec{Type: iamv1beta1.AccessRequestTypeNodeShell, Node: "node1", Duration: metav1.Duration{Duration: time.Hour}},
+			wantErr: true,
+		},
+		{
+			name:    "exceeds the maximum duration",
+			spec:    iamv1beta1.AccessRequestSpec{Type: iamv1beta1.AccessRequestTypeNodeShell, Node: "node1", Reason: "debug", Duration: metav1.Duration{Duration: 9 * time.Hour}},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateAccessRequest(&tt.spec, 8*time.Hour)
+			assert.Equal(t, tt.wantErr, err != nil, err)
+		})
+	}
+}
+
+func TestDecideAccessRequest(t *testing.T) {
+	accessRequest := &iamv1beta1.AccessRequest{
+		ObjectMeta: metav1.ObjectMeta{Name: "alice-abcde"},
+		Spec: iamv1beta1.AccessRequestSpec{
+			User:      "alice",
+			Type:      iamv1beta1.AccessRequestTypePodExec,
+			Namespace: "default",
+			Pod:       "nginx",
+			Reason:    "debug",
+			Duration:  metav1.Duration{Duration: time.Hour},
+		},
+		Status: iamv1beta1.AccessRequestStatus{Phase: iamv1beta1.AccessRequestPending},
+	}
+	c := fake.NewClientBuilder().WithScheme(scheme.Scheme).
+		WithIndex(&iamv1beta1.AccessRequest{}, AccessRequestUserIndex, AccessRequestIndexByUser).
+		WithStatusSubresource(&iamv1beta1.AccessRequest{}).WithObjects(accessRequest).Build()
+	ctx := context.Background()
+	target := iamv1beta1.AccessRequestSpec{Type: iamv1beta1.AccessRequestTypePodExec, Namespace: "default", Pod: "nginx"}
+	// only the admin is allowed to exec into the pod
+	authz := authorizer.AuthorizerFunc(func(a authorizer.Attributes) (authorizer.Decision, string, error) {
+		if a.GetUser().GetName() == "admin" && a.GetResource() == "pods" && a.GetSubresource() == "exec" &&
+			a.GetNamespace() == "default" && a.GetName() == "nginx" {
+			return authorizer.DecisionAllow, "", nil
+		}
+		return authorizer.DecisionNoOpinion, "", nil
+	})
+	admin := &user.DefaultInfo{Name: "admin"}
+
+	_, err := DecideAccessRequest(ctx, c, authz, accessRequest.Name, &user.DefaultInfo{Name: "alice"}, true, AccessRequestDecision{})
+	assert.ErrorIs(t, err, ErrSelfApproval)
+
+	// the approver must be allowed to access the target
+	_, err = DecideAccessRequest(ctx, c, authz, accessRequest.Name, &user.DefaultInfo{Name: "bob"}, true, AccessRequestDecision{})
+	assert.ErrorIs(t, err, ErrApproverNotAuthorized)
+
+	active, err := ActiveAccessRequest(ctx, c, "alice", target)
+	assert.NoError(t, err)
+	assert.Nil(t, active)
+
+	decided, err := DecideAccessRequest(ctx, c, authz, accessRequest.Name, admin, true, AccessRequestDecision{Comment: "ok"})
+	assert.NoError(t, err)
+	assert.Equal(t, iamv1beta1.AccessRequestApproved, decided.Status.Phase)
+	assert.Equal(t, "admin", decided.Status.Approver)
+	assert.NotNil(t, decided.Status.ExpirationTime)
+
+	active, err = ActiveAccessRequest(ctx, c, "alice", target)
+	assert.NoError(t, err)
+	assert.NotNil(t, active)
+	assert.Equal(t, accessRequest.Name, active.Name)
+
+	active, err = ActiveAccessRequest(ctx, c, "alice", iamv1beta1.AccessRequestSpec{Type: iamv1beta1.AccessRequestTypePodExec, Namespace: "default", Pod: "other"})
+	assert.NoError(t, err)
+	assert.Nil(t, active)
+
+	_, err = DecideAccessRequest(ctx, c, authz, accessRequest.Name, admin, false, AccessRequestDecision{})
+	assert.ErrorIs(t, err, ErrAccessRequestDecided)
+}
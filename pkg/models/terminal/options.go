@@ -6,12 +6,18 @@
 
 package terminal
 
-import "github.com/spf13/pflag"
+import (
+	"time"
+
+	"github.com/spf13/pflag"
+)
 
 type Options struct {
-	KubectlOptions   KubectlOptions   `json:"kubectl" yaml:"kubectl" mapstructure:"kubectl"`
-	NodeShellOptions NodeShellOptions `json:"node" yaml:"node" mapstructure:"node"`
-	UploadFileLimit  string           `json:"uploadFileLimit" yaml:"uploadFileLimit"`
+	KubectlOptions   KubectlOptions       `json:"kubectl" yaml:"kubectl" mapstructure:"kubectl"`
+	NodeShellOptions NodeShellOptions     `json:"node" yaml:"node" mapstructure:"node"`
+	UploadFileLimit  string               `json:"uploadFileLimit" yaml:"uploadFileLimit"`
+	Recording        RecordingOptions     `json:"recording" yaml:"recording" mapstructure:"recording"`
+	AccessRequest    AccessRequestOptions `json:"accessRequest" yaml:"accessRequest" mapstructure:"accessRequest"`
 }
 
 type KubectlOptions struct {
@@ -25,6 +31,23 @@ type NodeShellOptions struct {
 	Timeout int    `json:"timeout,omitempty" yaml:"timeout,omitempty"`
 }
 
+type RecordingOptions struct {
+	// Enabled records the terminal sessions in asciicast v2 format to the object storage.
+	Enabled bool `json:"enabled" yaml:"enabled"`
+	// MaxSize limits the size of a single recording, the events beyond the limit are dropped.
+	// The limit defaults to 64Mi if it is not set or invalid.
+	MaxSize string `json:"maxSize,omitempty" yaml:"maxSize,omitempty"`
+}
+
+type AccessRequestOptions struct {
+	// PodExecRequired requires an approved access request to exec into a pod.
+	PodExecRequired bool `json:"podExecRequired" yaml:"podExecRequired"`
+	// NodeShellRequired requires an approved access request to open a node shell.
+	NodeShellRequired bool `json:"nodeShellRequired" yaml:"nodeShellRequired"`
+	// MaxDuration limits the duration of the requested access.
+	MaxDuration time.Duration `json:"maxDuration,omitempty" yaml:"maxDuration,omitempty"`
+}
+
 func NewOptions() *Options {
 	return &Options{
 		KubectlOptions: KubectlOptions{
@@ -35,6 +58,12 @@ func NewOptions() *Options {
 			Timeout: 600,
 		},
 		UploadFileLimit: "100Mi",
+		Recording: RecordingOptions{
+			MaxSize: "64Mi",
+		},
+		AccessRequest: AccessRequestOptions{
+			MaxDuration: 8 * time.Hour,
+		},
 	}
 }
 
@@ -0,0 +1,472 @@
+/*
+ * Copyright 2024 the KubeSphere Authors.
+ * Please refer to the LICENSE file in the root directory of the project.
+ * https://github.com/kubesphere/kubesphere/blob/master/LICENSE
+ */
+
+package terminal
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	s3lib "github.com/aws/aws-sdk-go/service/s3"
+	"github.com/google/uuid"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog/v2"
+	"kubesphere.io/utils/s3"
+
+	"kubesphere.io/kubesphere/pkg/apiserver/request"
+)
+
+// SessionType is the type of the recorded terminal session.
+type SessionType string
+
+const (
+	SessionTypePod     SessionType = "pod"
+	SessionTypeNode    SessionType = "node"
+	SessionTypeKubectl SessionType = "kubectl"
+)
+
+const (
+	// RecordingKeyPrefix is the key prefix of the recordings in the object storage,
+	// the recordings are stored as {prefix}/{workspace}/{id}.cast with the metadata in {id}.json.
+	// The metadata is also added to the index {prefix}/{workspace}/index when the recording is saved, so that
+	// the recordings are listed and read without scanning the objects, and the workspaces with recordings are
+	// added to {prefix}/workspaces. The metadata is scanned only to repair the index when the recordings are pruned.
+	RecordingKeyPrefix = "terminal-recordings"
+
+	castExtension     = ".cast"
+	metadataExtension = ".json"
+	indexName         = "index"
+	workspacesName    = "workspaces"
+
+	// defaultRecordingMaxSize limits the size of a recording if the size limit is not configured or invalid.
+	defaultRecordingMaxSize = 64 << 20
+	// recordingQueueSize is the number of the events waiting to be uploaded, the events are dropped if the
+	// object storage can't keep up with the session.
+	recordingQueueSize = 4096
+
+	defaultTerminalWidth  = 80
+	defaultTerminalHeight = 24
+)
+
+var ErrRecordingNotFound = errors.New("terminal recording not found")
+
+// Recording is the metadata of a terminal session recording.
+type Recording struct {
+	ID   string      `json:"id"`
+	Type SessionType `json:"type"`
+	// User is the user who started the session.
+	User string `json:"user"`
+	// Workspace is the workspace of the namespace, sessions to nodes and kubectl belong to the system workspace.
+	Workspace string `json:"workspace"`
+	Namespace string `json:"namespace,omitempty"`
+	Pod       string `json:"pod,omitempty"`
+	Container string `json:"container,omitempty"`
+	Node      string `json:"node,omitempty"`
+	// AuditID is the ID of the audit event of the request that started the session.
+	AuditID   string      `json:"auditID,omitempty"`
+	StartTime metav1.Time `json:"startTime"`
+	EndTime   metav1.Time `json:"endTime"`
+	// Size is the size of the asciicast file in bytes.
+	Size int `json:"size"`
+	// Truncated is true if the events beyond the size limit were dropped.
+	Truncated bool `json:"truncated,omitempty"`
+}
+
+// RecordingList is the paginated list of the recordings.
+type RecordingList struct {
+	Items      []Recording `json:"items"`
+	TotalItems int         `json:"totalItems"`
+}
+
+// castHeader is the header line of an asciicast v2 file, see https://docs.asciinema.org/manual/asciicast/v2/
+// The duration is optional, it's unknown when the header is uploaded.
+type castHeader struct {
+	Version   int               `json:"version"`
+	Width     uint16            `json:"width"`
+	Height    uint16            `json:"height"`
+	Timestamp int64             `json:"timestamp"`
+	Duration  float64           `json:"duration,omitempty"`
+	Title     string            `json:"title,omitempty"`
+	Env       map[string]string `json:"env,omitempty"`
+}
+
+// objectLister is implemented by the object storage which lists the objects by the key prefix,
+// it's required to repair the indexes of the recordings.
+type objectLister interface {
+	List(prefix string) ([]string, error)
+}
+
+// recorder streams the events of a terminal session to the object storage in parts, the upload is started
+// by the first event and completed when the session is closed.
+type recorder struct {
+	sync.Mutex
+	store     s3.Interface
+	recording *Recording
+	start     time.Time
+	width     uint16
+	height    uint16
+	maxSize   int
+	// events are the encoded events waiting to be uploaded, it's closed when the session is closed
+	events   chan []byte
+	closed   bool
+	uploaded chan error
+}
+
+func newRecorder(ctx context.Context, store s3.Interface, recording *Recording, maxSize int) *recorder {
+	recording.ID = uuid.New().String()
+	if user, ok := request.UserFrom(ctx); ok {
+		recording.User = user.GetName()
+	}
+	if auditID, ok := request.AuditIDFrom(ctx); ok {
+		recording.AuditID = string(auditID)
+	}
+	now := time.Now()
+	recording.StartTime = metav1.NewTime(now)
+	if maxSize <= 0 {
+		maxSize = defaultRecordingMaxSize
+	}
+	return &recorder{store: store, recording: recording, start: now, maxSize: maxSize}
+}
+
+// record appends an event of the code "i" (input), "o" (output) or "r" (resize).
+func (r *recorder) record(code, data string) {
+	r.Lock()
+	defer r.Unlock()
+	if r.recording.Truncated || r.closed {
+		return
+	}
+	event, err := json.Marshal([]interface{}{time.Since(r.start).Seconds(), code, data})
+	if err != nil {
+		klog.Warningf("failed to encode terminal event: %s", err)
+		return
+	}
+	event = append(event, '\n')
+	r.startUpload()
+	if r.recording.Size+len(event) > r.maxSize {
+		klog.Warningf("terminal recording %s exceeds the size limit, the remaining events are dropped", r.recording.ID)
+		r.recording.Truncated = true
+		return
+	}
+	select {
+	case r.events <- event:
+		r.recording.Size += len(event)
+	default:
+		klog.Warningf("terminal recording %s can't keep up with the session, the remaining events are dropped", r.recording.ID)
+		r.recording.Truncated = true
+	}
+}
+
+func (r *recorder) input(data string) {
+	r.record("i", data)
+}
+
+func (r *recorder) output(data string) {
+	r.record("o", data)
+}
+
+func (r *recorder) resize(width, height uint16) {
+	r.Lock()
+	// the initial size of the terminal is recorded in the header
+	if r.width == 0 && r.height == 0 {
+		r.width, r.height = width, height
+	}
+	r.Unlock()
+	r.record("r", fmt.Sprintf("%dx%d", width, height))
+}
+
+// startUpload uploads the header and the queued events of the asciicast file in parts, it must be called with the lock held.
+func (r *recorder) startUpload() {
+	if r.events != nil {
+		return
+	}
+	header, err := r.header()
+	if err != nil {
+		// the header is always encoded
+		klog.Warningf("failed to encode terminal recording header: %s", err)
+	}
+	r.recording.Size += len(header)
+	r.events = make(chan []byte, recordingQueueSize)
+	r.uploaded = make(chan error, 1)
+
+	reader, writer := io.Pipe()
+	key := recordingKey(r.recording.Workspace, r.recording.ID, castExtension)
+	go func() {
+		err := r.store.Upload(key, path.Base(key), reader, 0)
+		// the remaining events are discarded if the upload failed
+		reader.CloseWithError(err)
+		r.uploaded <- err
+	}()
+	go func(events <-chan []byte) {
+		_, err := writer.Write(header)
+		for event := range events {
+			if err == nil {
+				_, err = writer.Write(event)
+			}
+		}
+		writer.CloseWithError(err)
+	}(r.events)
+}
+
+func (r *recorder) header() ([]byte, error) {
+	header := castHeader{
+		Version:   2,
+		Width:     r.width,
+		Height:    r.height,
+		Timestamp: r.start.Unix(),
+		Title:     r.title(),
+		Env:       map[string]string{"TERM": "xterm"},
+	}
+	if header.Width == 0 || header.Height == 0 {
+		header.Width, header.Height = defaultTerminalWidth, defaultTerminalHeight
+	}
+	data, err := json.Marshal(header)
+	if err != nil {
+		return nil, err
+	}
+	return append(data, '\n'), nil
+}
+
+func (r *recorder) title() string {
+	switch r.recording.Type {
+	case SessionTypeNode:
+		return fmt.Sprintf("%s@node/%s", r.recording.User, r.recording.Node)
+	case SessionTypeKubectl:
+		return fmt.Sprintf("%s@kubectl", r.recording.User)
+	default:
+		return fmt.Sprintf("%s@%s/%s/%s", r.recording.User, r.recording.Namespace, r.recording.Pod, r.recording.Container)
+	}
+}
+
+// save completes the upload of the asciicast file, uploads the metadata of the recording and adds it to the index.
+func (r *recorder) save() error {
+	r.Lock()
+	// the header is uploaded even if no event is recorded
+	r.startUpload()
+	r.closed = true
+	close(r.events)
+	r.recording.EndTime = metav1.Now()
+	r.Unlock()
+
+	key := recordingKey(r.recording.Workspace, r.recording.ID, castExtension)
+	if err := <-r.uploaded; err != nil {
+		return fmt.Errorf("failed to upload terminal recording %s: %s", key, err)
+	}
+	metadata, err := json.Marshal(r.recording)
+	if err != nil {
+		return fmt.Errorf("failed to encode terminal recording metadata: %s", err)
+	}
+	key = recordingKey(r.recording.Workspace, r.recording.ID, metadataExtension)
+	if err = r.store.Upload(key, path.Base(key), bytes.NewReader(metadata), len(metadata)); err != nil {
+		return fmt.Errorf("failed to upload terminal recording metadata %s: %s", key, err)
+	}
+	return addToIndex(r.store, *r.recording)
+}
+
+func recordingKey(workspace, id, extension string) string {
+	return path.Join(RecordingKeyPrefix, workspace, id+extension)
+}
+
+func indexKey(workspace string) string {
+	return path.Join(RecordingKeyPrefix, workspace, indexName)
+}
+
+func workspacesKey() string {
+	return path.Join(RecordingKeyPrefix, workspacesName)
+}
+
+// indexLock serializes the updates of the indexes in this process. The updates of the concurrent replicas may be
+// lost, and the index is repaired from the metadata when the recordings of the workspace are pruned.
+var indexLock sync.Mutex
+
+func isNotFound(err error) bool {
+	var aerr awserr.Error
+	return errors.As(err, &aerr) && aerr.Code() == s3lib.ErrCodeNoSuchKey
+}
+
+// readObject decodes the JSON object, it returns false if the object does not exist.
+func readObject(store s3.Interface, key string, v interface{}) (bool, error) {
+	data, err := store.Read(key)
+	if err != nil {
+		if isNotFound(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	if err = json.Unmarshal(data, v); err != nil {
+		return false, fmt.Errorf("invalid %s: %s", key, err)
+	}
+	return true, nil
+}
+
+func writeObject(store s3.Interface, key string, v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return store.Upload(key, path.Base(key), bytes.NewReader(data), len(data))
+}
+
+func readIndex(store s3.Interface, workspace string) ([]Recording, error) {
+	index := make([]Recording, 0)
+	if _, err := readObject(store, indexKey(workspace), &index); err != nil {
+		return nil, fmt.Errorf("failed to read terminal recording index: %s", err)
+	}
+	return index, nil
+}
+
+// writeIndex writes the index of the workspace, and adds the workspace to the workspaces with recordings.
+func writeIndex(store s3.Interface, workspace string, index []Recording) error {
+	if err := writeObject(store, indexKey(workspace), index); err != nil {
+		return fmt.Errorf("failed to upload terminal recording index: %s", err)
+	}
+	workspaces := make([]string, 0)
+	if _, err := readObject(store, workspacesKey(), &workspaces); err != nil {
+		return fmt.Errorf("failed to read the workspaces of terminal recordings: %s", err)
+	}
+	if slices.Contains(workspaces, workspace) {
+		return nil
+	}
+	if err := writeObject(store, workspacesKey(), append(workspaces, workspace)); err != nil {
+		return fmt.Errorf("failed to upload the workspaces of terminal recordings: %s", err)
+	}
+	return nil
+}
+
+func addToIndex(store s3.Interface, recording Recording) error {
+	indexLock.Lock()
+	defer indexLock.Unlock()
+	index, err := readIndex(store, recording.Workspace)
+	if err != nil {
+		return err
+	}
+	return writeIndex(store, recording.Workspace, append(index, recording))
+}
+
+// ListRecordings returns the recordings of the workspace, or of all workspaces if the workspace is empty,
+// the latest recordings come first.
+func ListRecordings(store s3.Interface, workspace string) ([]Recording, error) {
+	workspaces := []string{workspace}
+	if workspace == "" {
+		workspaces = make([]string, 0)
+		if _, err := readObject(store, workspacesKey(), &workspaces); err != nil {
+			return nil, fmt.Errorf("failed to read the workspaces of terminal recordings: %s", err)
+		}
+	}
+	recordings := make([]Recording, 0)
+	for _, workspace := range workspaces {
+		index, err := readIndex(store, workspace)
+		if err != nil {
+			return nil, err
+		}
+		recordings = append(recordings, index...)
+	}
+	sort.Slice(recordings, func(i, j int) bool {
+		return recordings[j].StartTime.Before(&recordings[i].StartTime)
+	})
+	return recordings, nil
+}
+
+// ReadRecording returns the asciicast file of the recording in the workspace, or in any workspace if the
+// workspace is empty.
+func ReadRecording(store s3.Interface, workspace, id string) ([]byte, error) {
+	recordings, err := ListRecordings(store, workspace)
+	if err != nil {
+		return nil, err
+	}
+	for _, recording := range recordings {
+		if recording.ID == id {
+			data, err := store.Read(recordingKey(recording.Workspace, id, castExtension))
+			if isNotFound(err) {
+				return nil, ErrRecordingNotFound
+			}
+			return data, err
+		}
+	}
+	return nil, ErrRecordingNotFound
+}
+
+// PruneRecordings deletes the recordings of the workspace which ended before the deadline, and repairs the index
+// of the workspace from the metadata of the recordings if the object storage can list the objects. Nothing is
+// deleted if the deadline is zero. It returns the number of the deleted recordings.
+func PruneRecordings(store s3.Interface, workspace string, deadline time.Time) (int, error) {
+	indexLock.Lock()
+	defer indexLock.Unlock()
+	index, err := readIndex(store, workspace)
+	if err != nil {
+		return 0, err
+	}
+	repaired, err := repairIndex(store, workspace, index)
+	if err != nil {
+		return 0, err
+	}
+	kept := make([]Recording, 0, len(repaired))
+	var expiredKeys []string
+	for _, recording := range repaired {
+		if recording.EndTime.Time.Before(deadline) {
+			expiredKeys = append(expiredKeys, recordingKey(workspace, recording.ID, castExtension),
+				recordingKey(workspace, recording.ID, metadataExtension))
+		} else {
+			kept = append(kept, recording)
+		}
+	}
+	if len(expiredKeys) == 0 && len(repaired) == len(index) {
+		return 0, nil
+	}
+	// the recordings are deleted after the index is saved, they're never listed after deleted
+	if err = writeIndex(store, workspace, kept); err != nil {
+		return 0, err
+	}
+	if len(expiredKeys) > 0 {
+		if err = store.Delete(expiredKeys); err != nil {
+			return 0, fmt.Errorf("failed to delete terminal recordings: %s", err)
+		}
+	}
+	return len(expiredKeys) / 2, nil
+}
+
+// repairIndex adds the recordings missing from the index, only the metadata of the missing recordings is read.
+func repairIndex(store s3.Interface, workspace string, index []Recording) ([]Recording, error) {
+	lister, ok := store.(objectLister)
+	if !ok {
+		return index, nil
+	}
+	keys, err := lister.List(path.Join(RecordingKeyPrefix, workspace) + "/")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list terminal recordings: %s", err)
+	}
+	indexed := make(map[string]bool, len(index))
+	for _, recording := range index {
+		indexed[recording.ID] = true
+	}
+	for _, key := range keys {
+		id, ok := strings.CutSuffix(path.Base(key), metadataExtension)
+		if !ok || indexed[id] {
+			continue
+		}
+		recording := Recording{}
+		found, err := readObject(store, key, &recording)
+		if err != nil {
+			klog.Warningf("failed to read terminal recording metadata %s: %s", key, err)
+			continue
+		}
+		if found {
+			indexed[id] = true
+			index = append(index, recording)
+		}
+	}
+	return index, nil
+}
@@ -0,0 +1,180 @@
+/*
+ * Copyright 2024 the KubeSphere Authors.
+ * Please refer to the LICENSE file in the root directory of the project.
+ * https://github.com/kubesphere/kubesphere/blob/master/LICENSE
+ */
+
+package terminal
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	s3lib "github.com/aws/aws-sdk-go/service/s3"
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apiserver/pkg/authentication/user"
+
+	"kubesphere.io/kubesphere/pkg/apiserver/request"
+)
+
+type fakeS3 struct {
+	objects map[string][]byte
+}
+
+func (f *fakeS3) Read(key string) ([]byte, error) {
+	data, ok := f.objects[key]
+	if !ok {
+		return nil, awserr.New(s3lib.ErrCodeNoSuchKey, key, nil)
+	}
+	return data, nil
+}
+
+func (f *fakeS3) Upload(key, _ string, body io.Reader, _ int) error {
+	data, err := io.ReadAll(body)
+	if err != nil {
+		return err
+	}
+	f.objects[key] = data
+	return nil
+}
+
+func (f *fakeS3) Delete(keys []string) error {
+	for _, key := range keys {
+		delete(f.objects, key)
+	}
+	return nil
+}
+
+func (f *fakeS3) List(prefix string) ([]string, error) {
+	var keys []string
+	for key := range f.objects {
+		if strings.HasPrefix(key, prefix) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
+
+func TestRecorder(t *testing.T) {
+	store := &fakeS3{objects: map[string][]byte{}}
+	ctx := request.WithUser(context.Background(), &user.DefaultInfo{Name: "admin"})
+	ctx = request.WithAuditID(ctx, types.UID("audit-id"))
+	r := newRecorder(ctx, store, &Recording{Type: SessionTypeNode, Workspace: "system-workspace", Node: "node1"}, 0)
+
+	r.resize(120, 40)
+	r.input("ls\r")
+	r.output("file\r\n")
+	assert.NoError(t, r.save())
+
+	data, err := ReadRecording(store, "system-workspace", r.recording.ID)
+	assert.NoError(t, err)
+	lines := bytes.Split(bytes.TrimSpace(data), []byte("\n"))
+	assert.Len(t, lines, 4)
+	header := castHeader{}
+	assert.NoError(t, json.Unmarshal(lines[0], &header))
+	assert.Equal(t, 2, header.Version)
+	assert.Equal(t, uint16(120), header.Width)
+	assert.Equal(t, uint16(40), header.Height)
+	assert.Equal(t, "admin@node/node1", header.Title)
+	var event []interface{}
+	assert.NoError(t, json.Unmarshal(lines[1], &event))
+	assert.Equal(t, []interface{}{"r", "120x40"}, event[1:])
+	assert.NoError(t, json.Unmarshal(lines[2], &event))
+	assert.Equal(t, []interface{}{"i", "ls\r"}, event[1:])
+	assert.NoError(t, json.Unmarshal(lines[3], &event))
+	assert.Equal(t, []interface{}{"o", "file\r\n"}, event[1:])
+
+	recordings, err := ListRecordings(store, "")
+	assert.NoError(t, err)
+	assert.Len(t, recordings, 1)
+	assert.Equal(t, "admin", recordings[0].User)
+	assert.Equal(t, "audit-id", recordings[0].AuditID)
+	assert.Equal(t, len(data), recordings[0].Size)
+
+	_, err = ReadRecording(store, "other", r.recording.ID)
+	assert.ErrorIs(t, err, ErrRecordingNotFound)
+}
+
+func TestRecorderMaxSize(t *testing.T) {
+	store := &fakeS3{objects: map[string][]byte{}}
+	r := newRecorder(context.Background(), store, &Recording{Type: SessionTypePod}, 256)
+	r.output(strings.Repeat("x", 64))
+	r.output(strings.Repeat("x", 64))
+	r.output("x")
+	assert.True(t, r.recording.Truncated)
+	assert.NoError(t, r.save())
+	data := store.objects[recordingKey("", r.recording.ID, castExtension)]
+	assert.LessOrEqual(t, len(data), 256)
+	assert.Equal(t, len(data), r.recording.Size)
+	// the header and the first event
+	assert.Equal(t, 2, bytes.Count(data, []byte("\n")))
+
+	// the recording is always limited
+	r = newRecorder(context.Background(), store, &Recording{Type: SessionTypePod}, 0)
+	assert.Equal(t, defaultRecordingMaxSize, r.maxSize)
+}
+
+func TestPruneRecordings(t *testing.T) {
+	store := &fakeS3{objects: map[string][]byte{}}
+	save := func(workspace string, age time.Duration) string {
+		r := newRecorder(context.Background(), store, &Recording{Type: SessionTypePod, Workspace: workspace}, 0)
+		assert.NoError(t, r.save())
+		// the recording ended before
+		index, err := readIndex(store, workspace)
+		assert.NoError(t, err)
+		for i := range index {
+			if index[i].ID == r.recording.ID {
+				index[i].EndTime.Time = time.Now().Add(-age)
+			}
+		}
+		assert.NoError(t, writeObject(store, indexKey(workspace), index))
+		return r.recording.ID
+	}
+	expired := save("ws1", 48*time.Hour)
+	kept := save("ws1", time.Hour)
+	other := save("ws2", 48*time.Hour)
+
+	// the recording is missing from the index if the update of the index is lost
+	lost := newRecorder(context.Background(), store, &Recording{Type: SessionTypePod, Workspace: "ws1"}, 0)
+	assert.NoError(t, lost.save())
+	index, err := readIndex(store, "ws1")
+	assert.NoError(t, err)
+	assert.NoError(t, writeObject(store, indexKey("ws1"), index[:len(index)-1]))
+	recordings, err := ListRecordings(store, "ws1")
+	assert.NoError(t, err)
+	assert.Len(t, recordings, 2)
+
+	deleted, err := PruneRecordings(store, "ws1", time.Now().Add(-24*time.Hour))
+	assert.NoError(t, err)
+	assert.Equal(t, 1, deleted)
+
+	recordings, err = ListRecordings(store, "")
+	assert.NoError(t, err)
+	var ids []string
+	for _, recording := range recordings {
+		ids = append(ids, recording.ID)
+	}
+	assert.ElementsMatch(t, []string{kept, lost.recording.ID, other}, ids)
+	assert.NotContains(t, store.objects, recordingKey("ws1", expired, castExtension))
+	assert.NotContains(t, store.objects, recordingKey("ws1", expired, metadataExtension))
+	_, err = ReadRecording(store, "ws1", expired)
+	assert.ErrorIs(t, err, ErrRecordingNotFound)
+
+	deleted, err = PruneRecordings(store, "ws1", time.Now().Add(-24*time.Hour))
+	assert.NoError(t, err)
+	assert.Equal(t, 0, deleted)
+
+	// nothing is deleted without the deadline
+	deleted, err = PruneRecordings(store, "ws2", time.Time{})
+	assert.NoError(t, err)
+	assert.Equal(t, 0, deleted)
+}
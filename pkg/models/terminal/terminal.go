@@ -25,6 +25,7 @@ import (
 	corev1 "k8s.io/api/core/v1"
 	v1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/client-go/kubernetes"
@@ -32,6 +33,7 @@ import (
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/remotecommand"
 	"k8s.io/klog/v2"
+	"kubesphere.io/utils/s3"
 
 	"kubesphere.io/kubesphere/pkg/constants"
 	"kubesphere.io/kubesphere/pkg/controller/kubectl/lease"
@@ -59,6 +61,8 @@ type PtyHandler interface {
 type Session struct {
 	conn     *websocket.Conn
 	sizeChan chan remotecommand.TerminalSize
+	// recorder records the session if the recording is enabled
+	recorder *recorder
 }
 
 var (
@@ -98,8 +102,14 @@ func (t Session) Read(p []byte) (int, error) {
 
 	switch msg.Op {
 	case "stdin":
+		if t.recorder != nil {
+			t.recorder.input(msg.Data)
+		}
 		return copy(p, msg.Data), nil
 	case "resize":
+		if t.recorder != nil {
+			t.recorder.resize(msg.Cols, msg.Rows)
+		}
 		t.sizeChan <- remotecommand.TerminalSize{Width: msg.Cols, Height: msg.Rows}
 		return 0, nil
 	default:
@@ -110,6 +120,9 @@ func (t Session) Read(p []byte) (int, error) {
 // Write handles process->pty stdout
 // Called from remote command whenever there is any output
 func (t Session) Write(p []byte) (int, error) {
+	if t.recorder != nil {
+		t.recorder.output(string(p))
+	}
 	msg, err := json.Marshal(Message{
 		Op:   "stdout",
 		Data: string(p),
@@ -154,6 +167,11 @@ func (t Session) Close(status uint32, reason string) {
 	if err := t.conn.Close(); err != nil {
 		klog.Warning("failed to close websocket connection: ", err)
 	}
+	if t.recorder != nil {
+		if err := t.recorder.save(); err != nil {
+			klog.Errorf("failed to save terminal recording: %s", err)
+		}
+	}
 }
 
 type Interface interface {
@@ -167,6 +185,9 @@ type terminaler struct {
 	config        *rest.Config
 	options       *Options
 	leaseOperator *lease.Operator
+	// store saves the session recordings, the sessions are not recorded if it is nil
+	store            s3.Interface
+	recordingMaxSize int
 }
 
 type NodeTerminaler struct {
@@ -180,8 +201,24 @@ type NodeTerminaler struct {
 	client        kubernetes.Interface
 }
 
-func NewTerminaler(client kubernetes.Interface, config *rest.Config, options *Options) Interface {
-	return &terminaler{client: client, config: config, options: options, leaseOperator: lease.NewOperator(client)}
+func NewTerminaler(client kubernetes.Interface, config *rest.Config, options *Options, store s3.Interface) Interface {
+	t := &terminaler{client: client, config: config, options: options, leaseOperator: lease.NewOperator(client)}
+	if options.Recording.Enabled {
+		if store == nil {
+			klog.Warning("terminal recording is enabled but the s3 storage is not configured, sessions will not be recorded")
+			return t
+		}
+		t.store = store
+		if options.Recording.MaxSize != "" {
+			q, err := resource.ParseQuantity(options.Recording.MaxSize)
+			if err != nil {
+				klog.Warningf("parse recording MaxSize failed: %s, the default size limit is used", err)
+			} else {
+				t.recordingMaxSize = int(q.Value())
+			}
+		}
+	}
+	return t
 }
 
 func NewNodeTerminaler(ctx context.Context, nodename string, options *Options, client kubernetes.Interface) (*NodeTerminaler, error) {
@@ -398,10 +435,41 @@ func (t *terminaler) createKubectlPod(ctx context.Context, podName, username str
 	return t.client.CoreV1().Pods(constants.KubeSphereNamespace).Create(ctx, pod, metav1.CreateOptions{})
 }
 
+// newSession creates the session of the connection, the session is recorded if the recording is enabled.
+func (t *terminaler) newSession(ctx context.Context, conn *websocket.Conn, recording *Recording) *Session {
+	session := &Session{conn: conn, sizeChan: make(chan remotecommand.TerminalSize)}
+	if t.store != nil {
+		session.recorder = newRecorder(ctx, t.store, recording, t.recordingMaxSize)
+	}
+	return session
+}
+
+// workspaceOf returns the workspace of the namespace, the namespaces which do not belong to any workspace
+// belong to the system workspace, so that their recordings are pruned by the retention of the system workspace.
+func (t *terminaler) workspaceOf(ctx context.Context, namespace string) string {
+	ns, err := t.client.CoreV1().Namespaces().Get(ctx, namespace, metav1.GetOptions{})
+	if err != nil {
+		klog.Warningf("failed to get namespace %s: %s", namespace, err)
+		return constants.SystemWorkspace
+	}
+	if workspace := ns.Labels[constants.WorkspaceLabelKey]; workspace != "" {
+		return workspace
+	}
+	return constants.SystemWorkspace
+}
+
 func (t *terminaler) HandleSession(ctx context.Context, shell, namespace, podName, containerName string, conn *websocket.Conn) {
+	recording := &Recording{Type: SessionTypePod, Namespace: namespace, Pod: podName, Container: containerName}
+	if t.store != nil {
+		recording.Workspace = t.workspaceOf(ctx, namespace)
+	}
+	t.handleSession(ctx, shell, namespace, podName, containerName, conn, recording)
+}
+
+func (t *terminaler) handleSession(ctx context.Context, shell, namespace, podName, containerName string, conn *websocket.Conn, recording *Recording) {
 	var err error
 	validShells := []string{"bash", "sh"}
-	session := &Session{conn: conn, sizeChan: make(chan remotecommand.TerminalSize)}
+	session := t.newSession(ctx, conn, recording)
 
 	if isValidShell(validShells, shell) {
 		cmd := []string{shell}
@@ -466,7 +534,8 @@ func (t *terminaler) HandleUserKubectlSession(ctx context.Context, username stri
 		return nil
 	})
 
-	t.HandleSession(ctx, "bash", pod.Namespace, pod.Name, "kubectl", conn)
+	recording := &Recording{Type: SessionTypeKubectl, Workspace: constants.SystemWorkspace, Namespace: pod.Namespace, Pod: pod.Name, Container: "kubectl"}
+	t.handleSession(ctx, "bash", pod.Namespace, pod.Name, "kubectl", conn, recording)
 }
 
 func (t *terminaler) HandleShellAccessToNode(ctx context.Context, nodename string, conn *websocket.Conn) {
@@ -520,7 +589,8 @@ func (t *terminaler) HandleShellAccessToNode(ctx context.Context, nodename strin
 		return nil
 	})
 
-	t.HandleSession(ctx, nodeTerminaler.Shell, nodeTerminaler.Namespace, nodeTerminaler.PodName, nodeTerminaler.ContainerName, conn)
+	recording := &Recording{Type: SessionTypeNode, Workspace: constants.SystemWorkspace, Node: nodename}
+	t.handleSession(ctx, nodeTerminaler.Shell, nodeTerminaler.Namespace, nodeTerminaler.PodName, nodeTerminaler.ContainerName, conn, recording)
 }
 
 func (n *NodeTerminaler) WatchPodStatusBeRunning(ctx context.Context, pod *v1.Pod) error {
@@ -52,6 +52,44 @@ type Options struct {
 	// By default, no setting is required.
 	// If you need to customize it, you can mount the chart file to the ks-controller-manager Pod and change this value.
 	ChartPath string `json:"chartPath,omitempty" yaml:"chartPath,omitempty"`
+
+	// HealthProbe configures the health probes of the clusters.
+	HealthProbe *HealthProbeOptions `json:"healthProbe,omitempty" yaml:"healthProbe,omitempty"`
+}
+
+type HealthProbeOptions struct {
+	// Period is the interval of the health probes.
+	Period time.Duration `json:"period,omitempty" yaml:"period,omitempty"`
+
+	// RecoveryInterval is the interval of the health probes while any probe fails, so that the recovery of
+	// the cluster is detected and the cluster becomes ready again without waiting for the next period.
+	RecoveryInterval time.Duration `json:"recoveryInterval,omitempty" yaml:"recoveryInterval,omitempty"`
+
+	// KubeAPIServerLatencyThreshold is the maximum latency of the kube-apiserver readiness check
+	// before the kube-apiserver is considered unhealthy.
+	KubeAPIServerLatencyThreshold time.Duration `json:"kubeAPIServerLatencyThreshold,omitempty" yaml:"kubeAPIServerLatencyThreshold,omitempty"`
+
+	// NodeReadyRatioThreshold is the minimum ratio of the ready nodes, between 0 and 1.
+	NodeReadyRatioThreshold float64 `json:"nodeReadyRatioThreshold,omitempty" yaml:"nodeReadyRatioThreshold,omitempty"`
+
+	// CertExpirationThreshold is the minimum remaining validity of the kubeconfig credentials,
+	// the credentials of the member clusters are rotated once the threshold is reached.
+	CertExpirationThreshold time.Duration `json:"certExpirationThreshold,omitempty" yaml:"certExpirationThreshold,omitempty"`
+
+	// HistoryLimit is the number of the previous conditions kept for each condition type.
+	HistoryLimit int `json:"historyLimit,omitempty" yaml:"historyLimit,omitempty"`
+}
+
+// NewHealthProbeOptions returns the default health probe options
+func NewHealthProbeOptions() *HealthProbeOptions {
+	return &HealthProbeOptions{
+		Period:                        5 * time.Minute,
+		RecoveryInterval:              30 * time.Second,
+		KubeAPIServerLatencyThreshold: 3 * time.Second,
+		NodeReadyRatioThreshold:       0.8,
+		CertExpirationThreshold:       7 * 24 * time.Hour,
+		HistoryLimit:                  10,
+	}
 }
 
 // NewOptions returns a default nil options
@@ -62,12 +100,17 @@ func NewOptions() *Options {
 		AgentImage:                    "kubesphere/tower:v1.0",
 		ClusterControllerResyncPeriod: DefaultResyncPeriod,
 		HostClusterName:               DefaultHostClusterName,
+		HealthProbe:                   NewHealthProbeOptions(),
 	}
 }
 
 func (o *Options) Validate() []error {
 	var err []error
 
+	if o.HealthProbe != nil && (o.HealthProbe.NodeReadyRatioThreshold < 0 || o.HealthProbe.NodeReadyRatioThreshold > 1) {
+		err = append(err, errors.New("the node ready ratio threshold of the health probe must be between 0 and 1"))
+	}
+
 	res := validation.IsQualifiedName(o.HostClusterName)
 	if len(res) == 0 {
 		return err
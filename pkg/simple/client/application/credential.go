@@ -0,0 +1,376 @@
+/*
+ * Copyright 2024 the KubeSphere Authors.
+ * Please refer to the LICENSE file in the root directory of the project.
+ * https://github.com/kubesphere/kubesphere/blob/master/LICENSE
+ */
+
+package application
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+
+	corev1 "k8s.io/api/core/v1"
+	apiequality "k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/klog/v2"
+	appv2 "kubesphere.io/api/application/v2"
+	runtimeclient "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	"kubesphere.io/kubesphere/pkg/constants"
+	"kubesphere.io/kubesphere/pkg/utils/hashutil"
+)
+
+// RepoCredential is the credential of a repo resolved at use time, it's never stored in the Repo.
+type RepoCredential struct {
+	Username string
+	Password string
+	// CertData and KeyData are the PEM encoded client certificate and key.
+	CertData []byte
+	KeyData  []byte
+	// CAData is the PEM encoded CA bundle to verify the repo.
+	CAData                []byte
+	InsecureSkipTLSVerify bool
+}
+
+// IsEmpty returns true if the credential carries no secret.
+func (c RepoCredential) IsEmpty() bool {
+	return c.Username == "" && c.Password == "" && len(c.CertData) == 0 && len(c.KeyData) == 0 && len(c.CAData) == 0
+}
+
+func (c RepoCredential) tlsConfig() (*tls.Config, error) {
+	config := &tls.Config{InsecureSkipVerify: c.InsecureSkipTLSVerify}
+	if len(c.CertData) > 0 || len(c.KeyData) > 0 {
+		cert, err := tls.X509KeyPair(c.CertData, c.KeyData)
+		if err != nil {
+			return nil, fmt.Errorf("invalid client certificate: %s", err)
+		}
+		config.Certificates = []tls.Certificate{cert}
+	}
+	if len(c.CAData) > 0 {
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(c.CAData) {
+			return nil, errors.New("invalid CA bundle")
+		}
+		config.RootCAs = pool
+	}
+	return config, nil
+}
+
+// RepoCredentialSecretName returns the name of the Secret holding the credential of the repo.
+func RepoCredentialSecretName(repoName string) string {
+	return fmt.Sprintf("repo-credential-%s", repoName)
+}
+
+// WorkspaceSystemNamespace returns the namespace holding the system resources of the workspace, such as the
+// credentials of the repos. The resources not belonging to any workspace are kept in the kubesphere-system namespace.
+func WorkspaceSystemNamespace(workspace string) string {
+	if workspace == "" || workspace == constants.SystemWorkspace {
+		return constants.KubeSphereNamespace
+	}
+	namespace := fmt.Sprintf("%s-system", workspace)
+	if len(namespace) <= validation.DNS1123LabelMaxLength {
+		return namespace
+	}
+	return fmt.Sprintf("system-%s", hashutil.FNVString([]byte(workspace)))
+}
+
+// ensureWorkspaceSystemNamespace creates the system namespace of the workspace if it does not exist,
+// a namespace of another workspace with the same name is never used.
+func ensureWorkspaceSystemNamespace(ctx context.Context, c runtimeclient.Client, workspace string) (string, error) {
+	name := WorkspaceSystemNamespace(workspace)
+	if name == constants.KubeSphereNamespace {
+		return name, nil
+	}
+	namespace := &corev1.Namespace{}
+	err := c.Get(ctx, runtimeclient.ObjectKey{Name: name}, namespace)
+	if err == nil {
+		if namespace.Labels[constants.WorkspaceLabelKey] != workspace {
+			return "", fmt.Errorf("namespace %s does not belong to workspace %s", name, workspace)
+		}
+		return name, nil
+	}
+	if !apierrors.IsNotFound(err) {
+		return "", err
+	}
+	namespace = &corev1.Namespace{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: name,
+			Labels: map[string]string{
+				constants.WorkspaceLabelKey:      workspace,
+				constants.KubeSphereManagedLabel: "true",
+			},
+		},
+	}
+	if err = c.Create(ctx, namespace); err != nil && !apierrors.IsAlreadyExists(err) {
+		return "", err
+	}
+	return name, nil
+}
+
+// insecureSkipTLSVerify returns whether the tls certificate checks are skipped, which is true by default.
+func insecureSkipTLSVerify(repo *appv2.Repo) bool {
+	return repo.Spec.Credential.InsecureSkipTLSVerify == nil || *repo.Spec.Credential.InsecureSkipTLSVerify
+}
+
+// ResolveRepoCredential reads the credential of the repo from the Secret referred by the repo,
+// the inline credential of the repos not migrated yet is used as a fallback.
+func ResolveRepoCredential(ctx context.Context, c runtimeclient.Reader, repo *appv2.Repo) (RepoCredential, error) {
+	cred := RepoCredential{InsecureSkipTLSVerify: insecureSkipTLSVerify(repo)}
+	if repo.Spec.SecretRef != nil && repo.Spec.SecretRef.Name != "" {
+		secret, err := getRepoCredentialSecret(ctx, c, repo)
+		if err != nil {
+			return cred, err
+		}
+		secretRepoCredential(secret, &cred)
+		// the tls files not migrated yet are still read from the inline paths
+		for _, file := range inlineRepoFiles(repo, &cred) {
+			if file.empty() || file.loaded() {
+				continue
+			}
+			if err := file.read(); err != nil {
+				return cred, err
+			}
+		}
+		return cred, nil
+	}
+	return inlineRepoCredential(repo)
+}
+
+// getRepoCredentialSecret returns the Secret referred by the repo in the system namespace of the workspace.
+func getRepoCredentialSecret(ctx context.Context, c runtimeclient.Reader, repo *appv2.Repo) (*corev1.Secret, error) {
+	secret := &corev1.Secret{}
+	key := runtimeclient.ObjectKey{Namespace: WorkspaceSystemNamespace(repo.GetWorkspace()), Name: repo.Spec.SecretRef.Name}
+	if err := c.Get(ctx, key, secret); err != nil {
+		return nil, fmt.Errorf("failed to get the credential of repo %s: %w", repo.Name, err)
+	}
+	// only the Secrets created for the repo can be referred, so that the other Secrets are never sent to the repo
+	if secret.Labels[appv2.RepoIDLabelKey] != repo.Name {
+		return nil, fmt.Errorf("secret %s is not the credential of repo %s", secret.Name, repo.Name)
+	}
+	return secret, nil
+}
+
+func secretRepoCredential(secret *corev1.Secret, cred *RepoCredential) {
+	cred.Username = string(secret.Data[corev1.BasicAuthUsernameKey])
+	cred.Password = string(secret.Data[corev1.BasicAuthPasswordKey])
+	cred.CertData = secret.Data[corev1.TLSCertKey]
+	cred.KeyData = secret.Data[corev1.TLSPrivateKeyKey]
+	cred.CAData = secret.Data[corev1.ServiceAccountRootCAKey]
+}
+
+// inlineRepoFile is a deprecated inline tls file of the repo, the certificate and the key are read together.
+type inlineRepoFile struct {
+	paths []*string
+	data  []*[]byte
+}
+
+func inlineRepoFiles(repo *appv2.Repo, cred *RepoCredential) []inlineRepoFile {
+	inline := &repo.Spec.Credential
+	return []inlineRepoFile{
+		{paths: []*string{&inline.CertFile, &inline.KeyFile}, data: []*[]byte{&cred.CertData, &cred.KeyData}},
+		{paths: []*string{&inline.CAFile}, data: []*[]byte{&cred.CAData}},
+	}
+}
+
+func (f inlineRepoFile) empty() bool {
+	for _, path := range f.paths {
+		if *path != "" {
+			return false
+		}
+	}
+	return true
+}
+
+func (f inlineRepoFile) loaded() bool {
+	for _, data := range f.data {
+		if len(*data) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+func (f inlineRepoFile) read() error {
+	data := make([][]byte, len(f.paths))
+	for i, path := range f.paths {
+		if *path == "" {
+			continue
+		}
+		var err error
+		if data[i], err = os.ReadFile(*path); err != nil {
+			return fmt.Errorf("failed to read %s: %s", *path, err)
+		}
+	}
+	for i := range f.data {
+		*f.data[i] = data[i]
+	}
+	return nil
+}
+
+func (f inlineRepoFile) clear() {
+	for _, path := range f.paths {
+		*path = ""
+	}
+}
+
+// inlineRepoCredential reads the deprecated inline credential of the repo.
+func inlineRepoCredential(repo *appv2.Repo) (RepoCredential, error) {
+	inline := repo.Spec.Credential
+	cred := RepoCredential{
+		Username:              inline.Username,
+		Password:              inline.Password,
+		InsecureSkipTLSVerify: insecureSkipTLSVerify(repo),
+	}
+	if parsed, err := url.Parse(repo.Spec.Url); err == nil && parsed.User != nil && cred.Username == "" {
+		cred.Username = parsed.User.Username()
+		cred.Password, _ = parsed.User.Password()
+	}
+	for _, file := range inlineRepoFiles(repo, &cred) {
+		if err := file.read(); err != nil {
+			return cred, err
+		}
+	}
+	return cred, nil
+}
+
+func hasInlineRepoCredential(repo *appv2.Repo) bool {
+	inline := repo.Spec.Credential
+	return inline.Username != "" || inline.Password != "" || inline.CertFile != "" || inline.KeyFile != "" || inline.CAFile != "" ||
+		StripURLUserInfo(repo.Spec.Url) != repo.Spec.Url
+}
+
+// SaveRepoCredential stores the credential in the Secret owned by the repo and refers the repo to it,
+// the Secret is deleted if the credential is empty. The repo is updated by the caller.
+func SaveRepoCredential(ctx context.Context, c runtimeclient.Client, repo *appv2.Repo, cred RepoCredential) error {
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Namespace: WorkspaceSystemNamespace(repo.GetWorkspace()), Name: RepoCredentialSecretName(repo.Name)},
+	}
+	if cred.IsEmpty() {
+		if err := c.Delete(ctx, secret); runtimeclient.IgnoreNotFound(err) != nil {
+			return fmt.Errorf("failed to delete the credential of repo %s: %s", repo.Name, err)
+		}
+		repo.Spec.SecretRef = nil
+		return nil
+	}
+	if _, err := ensureWorkspaceSystemNamespace(ctx, c, repo.GetWorkspace()); err != nil {
+		return fmt.Errorf("failed to save the credential of repo %s: %s", repo.Name, err)
+	}
+	if _, err := controllerutil.CreateOrUpdate(ctx, c, secret, func() error {
+		if secret.Labels == nil {
+			secret.Labels = make(map[string]string)
+		}
+		secret.Labels[appv2.RepoIDLabelKey] = repo.Name
+		if workspace := repo.GetWorkspace(); workspace != "" {
+			secret.Labels[constants.WorkspaceLabelKey] = workspace
+		}
+		secret.Type = corev1.SecretTypeOpaque
+		secret.Data = map[string][]byte{}
+		for key, value := range map[string][]byte{
+			corev1.BasicAuthUsernameKey:    []byte(cred.Username),
+			corev1.BasicAuthPasswordKey:    []byte(cred.Password),
+			corev1.TLSCertKey:              cred.CertData,
+			corev1.TLSPrivateKeyKey:        cred.KeyData,
+			corev1.ServiceAccountRootCAKey: cred.CAData,
+		} {
+			if len(value) > 0 {
+				secret.Data[key] = value
+			}
+		}
+		if repo.UID == "" {
+			return nil
+		}
+		return controllerutil.SetControllerReference(repo, secret, c.Scheme())
+	}); err != nil {
+		return fmt.Errorf("failed to save the credential of repo %s: %s", repo.Name, err)
+	}
+	repo.Spec.SecretRef = &corev1.LocalObjectReference{Name: secret.Name}
+	return nil
+}
+
+// MigrateRepoCredential moves the inline credential of the repo to the Secret, only the insecureSkipTLSVerify
+// is kept in the repo. The tls files not readable by the controller are kept in the repo until they are migrated.
+// It returns true if the repo is updated.
+func MigrateRepoCredential(ctx context.Context, c runtimeclient.Client, repo *appv2.Repo) (bool, error) {
+	if !hasInlineRepoCredential(repo) {
+		return false, nil
+	}
+	spec := repo.Spec.DeepCopy()
+	cred := RepoCredential{}
+	// the Secret referred by the repo takes precedence over the inline credential
+	if repo.Spec.SecretRef != nil && repo.Spec.SecretRef.Name != "" {
+		secret, err := getRepoCredentialSecret(ctx, c, repo)
+		if err != nil && !apierrors.IsNotFound(err) {
+			return false, err
+		}
+		if secret != nil {
+			secretRepoCredential(secret, &cred)
+		}
+	}
+	if cred.Username == "" && cred.Password == "" {
+		cred.Username, cred.Password = repo.Spec.Credential.Username, repo.Spec.Credential.Password
+		if parsed, err := url.Parse(repo.Spec.Url); err == nil && parsed.User != nil && cred.Username == "" {
+			cred.Username = parsed.User.Username()
+			cred.Password, _ = parsed.User.Password()
+		}
+	}
+	files := inlineRepoFiles(repo, &cred)
+	migrated := make([]bool, len(files))
+	for i, file := range files {
+		if file.empty() || file.loaded() {
+			migrated[i] = true
+			continue
+		}
+		if err := file.read(); err != nil {
+			// the files are not readable by the controller, they are kept in the repo until they are migrated
+			klog.Warningf("failed to migrate the tls credential of repo %s: %s", repo.Name, err)
+			continue
+		}
+		migrated[i] = true
+	}
+	if err := SaveRepoCredential(ctx, c, repo, cred); err != nil {
+		return false, err
+	}
+	for i, file := range files {
+		if migrated[i] {
+			file.clear()
+		}
+	}
+	repo.Spec.Credential.Username, repo.Spec.Credential.Password = "", ""
+	repo.Spec.Url = StripURLUserInfo(repo.Spec.Url)
+	if apiequality.Semantic.DeepEqual(spec, &repo.Spec) {
+		return false, nil
+	}
+	if err := c.Update(ctx, repo); err != nil {
+		return false, fmt.Errorf("failed to migrate the credential of repo %s: %s", repo.Name, err)
+	}
+	return true, nil
+}
+
+// RedactRepo removes the deprecated inline credential and the user info of the URL from the repo,
+// it's used before the repo is returned by the API.
+func RedactRepo(repo *appv2.Repo) {
+	repo.Spec.Credential = appv2.RepoCredential{InsecureSkipTLSVerify: repo.Spec.Credential.InsecureSkipTLSVerify}
+	repo.Spec.Url = StripURLUserInfo(repo.Spec.Url)
+	if repo.Annotations != nil {
+		delete(repo.Annotations, corev1.LastAppliedConfigAnnotation)
+	}
+}
+
+// StripURLUserInfo removes the user info from the URL, the URL is returned as is if it can't be parsed.
+func StripURLUserInfo(u string) string {
+	parsed, err := url.Parse(u)
+	if err != nil || parsed.User == nil {
+		return u
+	}
+	parsed.User = nil
+	return parsed.String()
+}
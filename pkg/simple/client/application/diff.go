@@ -0,0 +1,217 @@
+/*
+ * Copyright 2024 the KubeSphere Authors.
+ * Please refer to the LICENSE file in the root directory of the project.
+ * https://github.com/kubesphere/kubesphere/blob/master/LICENSE
+ */
+
+package application
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/pmezard/go-difflib/difflib"
+	"helm.sh/helm/v3/pkg/releaseutil"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/yaml"
+)
+
+type ResourceChange string
+
+const (
+	ResourceAdded    ResourceChange = "Added"
+	ResourceRemoved  ResourceChange = "Removed"
+	ResourceModified ResourceChange = "Modified"
+)
+
+// ResourceDiff is the change of a live resource if the release is deployed with the proposed manifest.
+type ResourceDiff struct {
+	APIVersion string         `json:"apiVersion"`
+	Kind       string         `json:"kind"`
+	Namespace  string         `json:"namespace,omitempty"`
+	Name       string         `json:"name"`
+	Change     ResourceChange `json:"change"`
+	// Diff is the unified diff between the live resource and the resource after applied.
+	Diff string `json:"diff,omitempty"`
+	// Error is the reason why the resource can't be compared, e.g. an immutable field is changed.
+	Error string `json:"error,omitempty"`
+}
+
+// ReleaseDiff is the result of rendering the release with the proposed version and values.
+type ReleaseDiff struct {
+	// Installed is false if the release has not been installed, all resources are added.
+	Installed    bool           `json:"installed"`
+	AppVersionID string         `json:"appVersionID"`
+	Manifest     string         `json:"manifest"`
+	Diffs        []ResourceDiff `json:"diffs"`
+}
+
+type manifestObject struct {
+	gvr schema.GroupVersionResource
+	obj *unstructured.Unstructured
+}
+
+func (o manifestObject) key() string {
+	gvk := o.obj.GroupVersionKind()
+	return fmt.Sprintf("%s/%s/%s/%s", gvk.Group, gvk.Kind, o.obj.GetNamespace(), o.obj.GetName())
+}
+
+// parseManifestObjects parses the resources of the manifest, the namespace of the namespaced resources
+// defaults to the namespace of the release.
+func parseManifestObjects(manifest string, mapper meta.RESTMapper, namespace string) ([]manifestObject, error) {
+	var objects []manifestObject
+	for _, content := range releaseutil.SplitManifests(manifest) {
+		obj := &unstructured.Unstructured{}
+		if err := yaml.Unmarshal([]byte(content), &obj.Object); err != nil {
+			return nil, err
+		}
+		if obj.Object == nil || obj.GetKind() == "" {
+			continue
+		}
+		gvk := obj.GroupVersionKind()
+		mapping, err := mapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+		if err != nil {
+			return nil, err
+		}
+		if mapping.Scope.Name() == meta.RESTScopeNameNamespace && obj.GetNamespace() == "" {
+			obj.SetNamespace(namespace)
+		}
+		objects = append(objects, manifestObject{gvr: mapping.Resource, obj: obj})
+	}
+	return objects, nil
+}
+
+// DiffLiveResources compares the live resources with the resources applied with the proposed manifest in
+// server-side dry-run mode, so that the defaulting and the admission of the cluster are taken into account.
+// The resources in the current manifest but not in the proposed manifest are removed, the unchanged resources are omitted.
+func DiffLiveResources(ctx context.Context, dynamicClient dynamic.Interface, mapper meta.RESTMapper,
+	namespace, fieldManager, current, proposed string) ([]ResourceDiff, error) {
+	proposedObjects, err := parseManifestObjects(proposed, mapper, namespace)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse proposed manifest: %s", err)
+	}
+	currentObjects, err := parseManifestObjects(current, mapper, namespace)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse current manifest: %s", err)
+	}
+
+	diffs := make([]ResourceDiff, 0)
+	proposing := make(map[string]struct{}, len(proposedObjects))
+	for _, object := range proposedObjects {
+		proposing[object.key()] = struct{}{}
+		diff, err := diffProposedObject(ctx, dynamicClient, fieldManager, object)
+		if err != nil {
+			return nil, err
+		}
+		if diff != nil {
+			diffs = append(diffs, *diff)
+		}
+	}
+	for _, object := range currentObjects {
+		if _, ok := proposing[object.key()]; ok {
+			continue
+		}
+		diff := newResourceDiff(object.obj, ResourceRemoved)
+		live, err := dynamicClient.Resource(object.gvr).Namespace(object.obj.GetNamespace()).Get(ctx, object.obj.GetName(), metav1.GetOptions{})
+		if apierrors.IsNotFound(err) {
+			continue
+		}
+		if err != nil {
+			diff.Error = err.Error()
+		} else if diff.Diff, err = unifiedDiff(live, nil); err != nil {
+			return nil, err
+		}
+		diffs = append(diffs, diff)
+	}
+	return diffs, nil
+}
+
+func diffProposedObject(ctx context.Context, dynamicClient dynamic.Interface, fieldManager string, object manifestObject) (*ResourceDiff, error) {
+	resource := dynamicClient.Resource(object.gvr).Namespace(object.obj.GetNamespace())
+	live, err := resource.Get(ctx, object.obj.GetName(), metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		diff := newResourceDiff(object.obj, ResourceAdded)
+		if diff.Diff, err = unifiedDiff(nil, object.obj); err != nil {
+			return nil, err
+		}
+		return &diff, nil
+	}
+	diff := newResourceDiff(object.obj, ResourceModified)
+	if err != nil {
+		diff.Error = err.Error()
+		return &diff, nil
+	}
+
+	data, err := object.obj.MarshalJSON()
+	if err != nil {
+		return nil, err
+	}
+	applied, err := resource.Patch(ctx, object.obj.GetName(), types.ApplyPatchType, data, metav1.PatchOptions{
+		DryRun:       []string{metav1.DryRunAll},
+		FieldManager: fieldManager,
+		Force:        ptr.To(true),
+	})
+	if err != nil {
+		diff.Error = err.Error()
+		return &diff, nil
+	}
+	if diff.Diff, err = unifiedDiff(live, applied); err != nil {
+		return nil, err
+	}
+	if diff.Diff == "" {
+		return nil, nil
+	}
+	return &diff, nil
+}
+
+func newResourceDiff(obj *unstructured.Unstructured, change ResourceChange) ResourceDiff {
+	return ResourceDiff{
+		APIVersion: obj.GetAPIVersion(),
+		Kind:       obj.GetKind(),
+		Namespace:  obj.GetNamespace(),
+		Name:       obj.GetName(),
+		Change:     change,
+	}
+}
+
+// unifiedDiff returns the unified diff of the objects, the fields maintained by the server are ignored.
+func unifiedDiff(from, to *unstructured.Unstructured) (string, error) {
+	fromContent, err := comparableYAML(from)
+	if err != nil {
+		return "", err
+	}
+	toContent, err := comparableYAML(to)
+	if err != nil {
+		return "", err
+	}
+	if fromContent == toContent {
+		return "", nil
+	}
+	return difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        difflib.SplitLines(fromContent),
+		B:        difflib.SplitLines(toContent),
+		FromFile: "live",
+		ToFile:   "proposed",
+		Context:  3,
+	})
+}
+
+func comparableYAML(obj *unstructured.Unstructured) (string, error) {
+	if obj == nil {
+		return "", nil
+	}
+	obj = obj.DeepCopy()
+	unstructured.RemoveNestedField(obj.Object, "status")
+	for _, field := range []string{"managedFields", "resourceVersion", "generation", "uid", "creationTimestamp"} {
+		unstructured.RemoveNestedField(obj.Object, "metadata", field)
+	}
+	data, err := yaml.Marshal(obj.Object)
+	return string(data), err
+}
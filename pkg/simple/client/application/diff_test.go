@@ -0,0 +1,132 @@
+/*
+ * Copyright 2024 the KubeSphere Authors.
+ * Please refer to the LICENSE file in the root directory of the project.
+ * https://github.com/kubesphere/kubesphere/blob/master/LICENSE
+ */
+
+package application
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	clienttesting "k8s.io/client-go/testing"
+	"sigs.k8s.io/yaml"
+
+	"kubesphere.io/kubesphere/pkg/scheme"
+)
+
+func TestDiffLiveResources(t *testing.T) {
+	configMap := func(name string, data map[string]string) *corev1.ConfigMap {
+		return &corev1.ConfigMap{
+			TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "ConfigMap"},
+			ObjectMeta: metav1.ObjectMeta{Namespace: "demo", Name: name, ResourceVersion: "1"},
+			Data:       data,
+		}
+	}
+	dynamicClient := dynamicfake.NewSimpleDynamicClient(scheme.Scheme,
+		configMap("config", map[string]string{"replicas": "1"}),
+		configMap("unchanged", map[string]string{"key": "value"}),
+		configMap("legacy", map[string]string{"key": "value"}),
+	)
+	// the fake client doesn't support server-side dry-run, the applied object is returned as is
+	dynamicClient.PrependReactor("patch", "configmaps", func(action clienttesting.Action) (bool, runtime.Object, error) {
+		patch := action.(clienttesting.PatchAction)
+		assert.Equal(t, types.ApplyPatchType, patch.GetPatchType())
+		applied := &unstructured.Unstructured{}
+		if err := applied.UnmarshalJSON(patch.GetPatch()); err != nil {
+			return true, nil, err
+		}
+		applied.SetResourceVersion("2")
+		return true, applied, nil
+	})
+	mapper := meta.NewDefaultRESTMapper(nil)
+	mapper.Add(corev1.SchemeGroupVersion.WithKind("ConfigMap"), meta.RESTScopeNamespace)
+
+	current := `---
+# Source: demo/templates/config.yaml
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: config
+data:
+  replicas: "1"
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: unchanged
+data:
+  key: value
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: legacy
+data:
+  key: value
+`
+	proposed := `---
+# Source: demo/templates/config.yaml
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: config
+data:
+  replicas: "2"
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: unchanged
+data:
+  key: value
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: added
+  namespace: demo
+data:
+  key: value
+`
+	diffs, err := DiffLiveResources(context.Background(), dynamicClient, mapper, "demo", YamlFieldManager, current, proposed)
+	assert.NoError(t, err)
+	assert.Len(t, diffs, 3)
+
+	changes := make(map[string]ResourceDiff)
+	for _, diff := range diffs {
+		assert.Equal(t, "demo", diff.Namespace)
+		assert.Empty(t, diff.Error)
+		changes[diff.Name] = diff
+	}
+	assert.Equal(t, ResourceModified, changes["config"].Change)
+	assert.Contains(t, changes["config"].Diff, `-  replicas: "1"`)
+	assert.Contains(t, changes["config"].Diff, `+  replicas: "2"`)
+	assert.NotContains(t, changes["config"].Diff, "resourceVersion")
+	assert.Equal(t, ResourceAdded, changes["added"].Change)
+	assert.Equal(t, ResourceRemoved, changes["legacy"].Change)
+}
+
+func TestParseManifestObjects(t *testing.T) {
+	mapper := meta.NewDefaultRESTMapper(nil)
+	mapper.Add(corev1.SchemeGroupVersion.WithKind("Namespace"), meta.RESTScopeRoot)
+
+	namespace, _ := yaml.Marshal(&corev1.Namespace{TypeMeta: metav1.TypeMeta{APIVersion: "v1", Kind: "Namespace"}, ObjectMeta: metav1.ObjectMeta{Name: "demo"}})
+	objects, err := parseManifestObjects("---\n# empty\n---\n"+string(namespace), mapper, "demo")
+	assert.NoError(t, err)
+	assert.Len(t, objects, 1)
+	assert.Empty(t, objects[0].obj.GetNamespace())
+	assert.Equal(t, "namespaces", objects[0].gvr.Resource)
+
+	_, err = parseManifestObjects("apiVersion: v1\nkind: Unknown\nmetadata:\n  name: demo\n", mapper, "demo")
+	assert.Error(t, err)
+}
@@ -12,7 +12,6 @@ import (
 	"context"
 	"crypto/md5"
 	"crypto/sha1"
-	"crypto/tls"
 	"encoding/hex"
 	"encoding/json"
 	"errors"
@@ -92,7 +91,6 @@ type AppRequest struct {
 	AppType      string                       `json:"appType,omitempty"`
 	Package      []byte                       `json:"package,omitempty"`
 	PullUrl      string                       `json:"pullUrl,omitempty"`
-	Credential   appv2.RepoCredential         `json:"credential,omitempty"`
 	Maintainers  []appv2.Maintainer           `json:"maintainers,omitempty"`
 	Abstraction  string                       `json:"abstraction,omitempty"`
 	Attachments  []string                     `json:"attachments,omitempty"`
@@ -324,16 +322,14 @@ func UpdateLatestAppVersion(ctx context.Context, client runtimeclient.Client, ap
 	return err
 }
 
-func HelmPull(u string, cred appv2.RepoCredential) (*bytes.Buffer, error) {
+func HelmPull(u string, cred RepoCredential) (*bytes.Buffer, error) {
 	parsedURL, err := url.Parse(u)
 	if err != nil {
 		return nil, err
 	}
-	var resp *bytes.Buffer
-
-	skipTLS := true
-	if cred.InsecureSkipTLSVerify != nil && !*cred.InsecureSkipTLSVerify {
-		skipTLS = false
+	tlsConfig, err := cred.tlsConfig()
+	if err != nil {
+		return nil, err
 	}
 
 	indexURL := parsedURL.String()
@@ -341,21 +337,14 @@ func HelmPull(u string, cred appv2.RepoCredential) (*bytes.Buffer, error) {
 	options := []getter.Option{
 		getter.WithTimeout(5 * time.Minute),
 		getter.WithURL(u),
-		getter.WithInsecureSkipVerifyTLS(skipTLS),
-		getter.WithTLSClientConfig(cred.CertFile, cred.KeyFile, cred.CAFile),
-		getter.WithBasicAuth(cred.Username, cred.Password)}
-
-	if skipTLS {
-		options = append(options, getter.WithTransport(
-			&http.Transport{TLSClientConfig: &tls.Config{InsecureSkipVerify: true}},
-		))
+		getter.WithBasicAuth(cred.Username, cred.Password),
+		getter.WithTransport(&http.Transport{TLSClientConfig: tlsConfig, Proxy: http.ProxyFromEnvironment}),
 	}
 
-	resp, err = g.Get(indexURL, options...)
-	return resp, err
+	return g.Get(indexURL, options...)
 }
 
-func LoadRepoIndex(u string, cred appv2.RepoCredential) (idx helmrepo.IndexFile, err error) {
+func LoadRepoIndex(u string, cred RepoCredential) (idx helmrepo.IndexFile, err error) {
 	if registry.IsOCI(u) {
 		return LoadRepoIndexFromOci(u, cred)
 	}
@@ -8,7 +8,6 @@ package application
 
 import (
 	"context"
-	"crypto/tls"
 	"errors"
 	"fmt"
 	"net/http"
@@ -19,12 +18,11 @@ import (
 	"helm.sh/helm/v3/pkg/registry"
 	helmrepo "helm.sh/helm/v3/pkg/repo"
 	"k8s.io/klog/v2"
-	appv2 "kubesphere.io/api/application/v2"
 
 	"kubesphere.io/kubesphere/pkg/simple/client/oci"
 )
 
-func HelmPullFromOci(u string, cred appv2.RepoCredential) ([]byte, error) {
+func HelmPullFromOci(u string, cred RepoCredential) ([]byte, error) {
 	if !registry.IsOCI(u) {
 		return nil, fmt.Errorf("invalid oci URL format: %s", u)
 	}
@@ -49,7 +47,7 @@ func HelmPullFromOci(u string, cred appv2.RepoCredential) ([]byte, error) {
 	return pullResult.Chart.Data, nil
 }
 
-func LoadRepoIndexFromOci(u string, cred appv2.RepoCredential) (idx helmrepo.IndexFile, err error) {
+func LoadRepoIndexFromOci(u string, cred RepoCredential) (idx helmrepo.IndexFile, err error) {
 	if !registry.IsOCI(u) {
 		return idx, fmt.Errorf("invalid oci URL format: %s", u)
 	}
@@ -107,20 +105,21 @@ func LoadRepoIndexFromOci(u string, cred appv2.RepoCredential) (idx helmrepo.Ind
 	return *index, nil
 }
 
-func GetRepoChartsFromOci(parsedURL *url.URL, cred appv2.RepoCredential) ([]string, error) {
+func GetRepoChartsFromOci(parsedURL *url.URL, cred RepoCredential) ([]string, error) {
 	if parsedURL == nil {
 		return nil, errors.New("missing parsedURL")
 	}
 
-	skipTLS := true
-	if cred.InsecureSkipTLSVerify != nil && !*cred.InsecureSkipTLSVerify {
-		skipTLS = false
+	tlsConfig, err := cred.tlsConfig()
+	if err != nil {
+		return nil, err
 	}
 
 	reg, err := oci.NewRegistry(parsedURL.Host,
 		oci.WithTimeout(5*time.Second),
 		oci.WithBasicAuth(cred.Username, cred.Password),
-		oci.WithInsecureSkipVerifyTLS(skipTLS))
+		oci.WithInsecureSkipVerifyTLS(cred.InsecureSkipTLSVerify),
+		oci.WithTLSClientConfig(tlsConfig))
 	if err != nil {
 		return nil, err
 	}
@@ -152,28 +151,29 @@ func GetRepoChartsFromOci(parsedURL *url.URL, cred appv2.RepoCredential) ([]stri
 	return repoCharts, nil
 }
 
-func newOCIRegistryClient(u string, cred appv2.RepoCredential) (*registry.Client, error) {
+func newOCIRegistryClient(u string, cred RepoCredential) (*registry.Client, error) {
 	parsedURL, err := url.Parse(u)
 	if err != nil {
 		klog.Errorf("invalid oci repo URL format: %s, err:%v", u, err)
 		return nil, err
 	}
 
-	skipTLS := true
-	if cred.InsecureSkipTLSVerify != nil && !*cred.InsecureSkipTLSVerify {
-		skipTLS = false
+	tlsConfig, err := cred.tlsConfig()
+	if err != nil {
+		return nil, err
 	}
 
 	reg, err := oci.NewRegistry(parsedURL.Host,
 		oci.WithTimeout(5*time.Second),
 		oci.WithBasicAuth(cred.Username, cred.Password),
-		oci.WithInsecureSkipVerifyTLS(skipTLS))
+		oci.WithInsecureSkipVerifyTLS(cred.InsecureSkipTLSVerify),
+		oci.WithTLSClientConfig(tlsConfig))
 	if err != nil {
 		return nil, err
 	}
 
 	transport := &http.Transport{
-		TLSClientConfig: &tls.Config{InsecureSkipVerify: skipTLS},
+		TLSClientConfig: tlsConfig,
 		Proxy:           http.ProxyFromEnvironment,
 	}
 
@@ -13,8 +13,6 @@ import (
 	"net/http/httptest"
 	"net/url"
 	"testing"
-
-	appv2 "kubesphere.io/api/application/v2"
 )
 
 func TestLoadRepoIndexFromOci(t *testing.T) {
@@ -59,9 +57,10 @@ func TestLoadRepoIndexFromOci(t *testing.T) {
 	}
 
 	url := fmt.Sprintf("oci://%s/helmcharts", uri.Host)
-	cred := appv2.RepoCredential{
-		Username: "",
-		Password: "",
+	cred := RepoCredential{
+		Username:              "",
+		Password:              "",
+		InsecureSkipTLSVerify: true,
 	}
 	index, err := LoadRepoIndexFromOci(url, cred)
 	if err != nil {
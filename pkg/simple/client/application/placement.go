@@ -0,0 +1,61 @@
+/*
+ * Copyright 2024 the KubeSphere Authors.
+ * Please refer to the LICENSE file in the root directory of the project.
+ * https://github.com/kubesphere/kubesphere/blob/master/LICENSE
+ */
+
+package application
+
+import (
+	"context"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apiserver/pkg/authentication/user"
+	appv2 "kubesphere.io/api/application/v2"
+	runtimeclient "sigs.k8s.io/controller-runtime/pkg/client"
+
+	"kubesphere.io/kubesphere/pkg/apiserver/authorization/authorizer"
+	"kubesphere.io/kubesphere/pkg/apiserver/request"
+	"kubesphere.io/kubesphere/pkg/constants"
+)
+
+// AuthorizeClusterRelease checks that the user is allowed to create the release in the namespace of the placed cluster,
+// and that the namespace belongs to the workspace of the release if it exists. It's checked when the release is
+// created or updated, and by the controller for the clusters newly selected by the placement.
+func AuthorizeClusterRelease(ctx context.Context, a authorizer.Authorizer, user user.Info, clusterClient runtimeclient.Reader, rls *appv2.ApplicationRelease, cluster string) error {
+	workspace := rls.GetLabels()[constants.WorkspaceLabelKey]
+	namespace := rls.GetRlsNamespace()
+	createRelease := authorizer.AttributesRecord{
+		User:            user,
+		Verb:            "create",
+		Cluster:         cluster,
+		Workspace:       workspace,
+		Namespace:       namespace,
+		APIGroup:        appv2.SchemeGroupVersion.Group,
+		APIVersion:      appv2.SchemeGroupVersion.Version,
+		Resource:        "applications",
+		ResourceRequest: true,
+		ResourceScope:   request.NamespaceScope,
+	}
+	decision, reason, err := a.Authorize(createRelease)
+	if err != nil {
+		return err
+	}
+	if decision != authorizer.DecisionAllow {
+		return apierrors.NewForbidden(appv2.Resource("applications"), rls.Name,
+			fmt.Errorf("user %s is not allowed to deploy to namespace %s of cluster %s: %s", user.GetName(), namespace, cluster, reason))
+	}
+
+	ns := &corev1.Namespace{}
+	if err = clusterClient.Get(ctx, types.NamespacedName{Name: namespace}, ns); err != nil {
+		return runtimeclient.IgnoreNotFound(err)
+	}
+	if owner := ns.Labels[constants.WorkspaceLabelKey]; owner != workspace {
+		return apierrors.NewForbidden(appv2.Resource("applications"), rls.Name,
+			fmt.Errorf("namespace %s of cluster %s does not belong to workspace %s", namespace, cluster, workspace))
+	}
+	return nil
+}
@@ -0,0 +1,60 @@
+/*
+ * Copyright 2024 the KubeSphere Authors.
+ * Please refer to the LICENSE file in the root directory of the project.
+ * https://github.com/kubesphere/kubesphere/blob/master/LICENSE
+ */
+
+package application
+
+import (
+	"bytes"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	"helm.sh/helm/v3/pkg/chart/loader"
+	"helm.sh/helm/v3/pkg/chartutil"
+	"helm.sh/helm/v3/pkg/engine"
+	appv2 "kubesphere.io/api/application/v2"
+)
+
+// RenderManifest renders the package of an app version with the default values without a cluster,
+// the package of the yaml apps is the manifest itself.
+func RenderManifest(data []byte, appType string) (string, error) {
+	if appType != appv2.AppTypeHelm {
+		return string(data), nil
+	}
+	chart, err := loader.LoadArchive(bytes.NewReader(data))
+	if err != nil {
+		return "", fmt.Errorf("failed to load chart: %s", err)
+	}
+	values, err := chartutil.ToRenderValues(chart, chart.Values, chartutil.ReleaseOptions{
+		Name:      chart.Name(),
+		Namespace: "default",
+		IsInstall: true,
+	}, chartutil.DefaultCapabilities)
+	if err != nil {
+		return "", err
+	}
+	files, err := engine.Render(chart, values)
+	if err != nil {
+		return "", fmt.Errorf("failed to render chart: %s", err)
+	}
+
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var manifest strings.Builder
+	for _, name := range names {
+		content := strings.TrimSpace(files[name])
+		// the partials and the notes are not manifests
+		if content == "" || strings.HasPrefix(path.Base(name), "_") || strings.HasSuffix(name, "NOTES.txt") {
+			continue
+		}
+		fmt.Fprintf(&manifest, "---\n# Source: %s\n%s\n", name, content)
+	}
+	return manifest.String(), nil
+}
@@ -0,0 +1,42 @@
+/*
+ * Copyright 2024 the KubeSphere Authors.
+ * Please refer to the LICENSE file in the root directory of the project.
+ * https://github.com/kubesphere/kubesphere/blob/master/LICENSE
+ */
+
+package application
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/chartutil"
+	appv2 "kubesphere.io/api/application/v2"
+)
+
+func TestRenderManifest(t *testing.T) {
+	manifest, err := RenderManifest([]byte("kind: Pod\n"), appv2.AppTypeYaml)
+	assert.NoError(t, err)
+	assert.Equal(t, "kind: Pod\n", manifest)
+
+	c := &chart.Chart{
+		Metadata: &chart.Metadata{APIVersion: chart.APIVersionV2, Name: "nginx", Version: "1.0.0"},
+		Values:   map[string]interface{}{"image": "nginx:1.25"},
+		Raw:      []*chart.File{{Name: chartutil.ValuesfileName, Data: []byte("image: nginx:1.25\n")}},
+		Templates: []*chart.File{
+			{Name: "templates/_helpers.tpl", Data: []byte(`{{- define "nginx.name" -}}{{ .Release.Name }}{{- end -}}`)},
+			{Name: "templates/NOTES.txt", Data: []byte("installed")},
+			{Name: "templates/pod.yaml", Data: []byte("kind: Pod\nmetadata:\n  name: {{ include \"nginx.name\" . }}\nimage: {{ .Values.image }}\n")},
+		},
+	}
+	path, err := chartutil.Save(c, t.TempDir())
+	assert.NoError(t, err)
+	data, err := os.ReadFile(path)
+	assert.NoError(t, err)
+
+	manifest, err = RenderManifest(data, appv2.AppTypeHelm)
+	assert.NoError(t, err)
+	assert.Equal(t, "---\n# Source: nginx/templates/pod.yaml\nkind: Pod\nmetadata:\n  name: nginx\nimage: nginx:1.25\n", manifest)
+}
@@ -0,0 +1,73 @@
+/*
+ * Copyright 2024 the KubeSphere Authors.
+ * Please refer to the LICENSE file in the root directory of the project.
+ * https://github.com/kubesphere/kubesphere/blob/master/LICENSE
+ */
+
+package application
+
+import (
+	"context"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	appv2 "kubesphere.io/api/application/v2"
+	runtimeclient "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+)
+
+const revisionValuesKey = "values"
+
+// RevisionValuesSecretName returns the name of the Secret holding the values of the revision.
+func RevisionValuesSecretName(apprls string, revision int) string {
+	return fmt.Sprintf("%s-revision-%d", apprls, revision)
+}
+
+// SaveRevisionValues stores the values of the revision in a Secret owned by the application release,
+// it returns the name of the Secret.
+func SaveRevisionValues(ctx context.Context, c runtimeclient.Client, apprls *appv2.ApplicationRelease, revision int, values []byte) (string, error) {
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: appv2.ApplicationNamespace,
+			Name:      RevisionValuesSecretName(apprls.Name, revision),
+		},
+	}
+	if _, err := controllerutil.CreateOrUpdate(ctx, c, secret, func() error {
+		if secret.Labels == nil {
+			secret.Labels = make(map[string]string)
+		}
+		secret.Labels[appv2.AppReleaseReferenceLabelKey] = apprls.Name
+		secret.Type = corev1.SecretTypeOpaque
+		secret.Data = map[string][]byte{revisionValuesKey: values}
+		return controllerutil.SetControllerReference(apprls, secret, c.Scheme())
+	}); err != nil {
+		return "", fmt.Errorf("failed to save the values of revision %d: %s", revision, err)
+	}
+	return secret.Name, nil
+}
+
+// GetRevisionValues reads the values of the revision from the Secret referred by the revision.
+func GetRevisionValues(ctx context.Context, c runtimeclient.Reader, apprls *appv2.ApplicationRelease, revision *appv2.ApplicationReleaseRevision) ([]byte, error) {
+	if revision.ValuesRef == "" {
+		return nil, nil
+	}
+	secret := &corev1.Secret{}
+	if err := c.Get(ctx, runtimeclient.ObjectKey{Namespace: appv2.ApplicationNamespace, Name: revision.ValuesRef}, secret); err != nil {
+		return nil, fmt.Errorf("failed to get the values of revision %d: %w", revision.Revision, err)
+	}
+	// only the Secrets created for the release can be referred
+	if secret.Labels[appv2.AppReleaseReferenceLabelKey] != apprls.Name {
+		return nil, fmt.Errorf("secret %s is not the values of application release %s", secret.Name, apprls.Name)
+	}
+	return secret.Data[revisionValuesKey], nil
+}
+
+// DeleteRevisionValues deletes the Secret holding the values of the revision.
+func DeleteRevisionValues(ctx context.Context, c runtimeclient.Client, revision *appv2.ApplicationReleaseRevision) error {
+	if revision.ValuesRef == "" {
+		return nil
+	}
+	secret := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Namespace: appv2.ApplicationNamespace, Name: revision.ValuesRef}}
+	return runtimeclient.IgnoreNotFound(c.Delete(ctx, secret))
+}
@@ -152,10 +152,14 @@ func DownLoadChart(cli runtimeclient.Client, pullUrl, repoName string) (data []b
 		klog.Errorf("failed to get app repo, err: %v", err)
 		return data, err
 	}
+	cred, err := ResolveRepoCredential(context.TODO(), cli, &repo)
+	if err != nil {
+		return data, err
+	}
 	if registry.IsOCI(pullUrl) {
-		return HelmPullFromOci(pullUrl, repo.Spec.Credential)
+		return HelmPullFromOci(pullUrl, cred)
 	}
-	buf, err := HelmPull(pullUrl, repo.Spec.Credential)
+	buf, err := HelmPull(pullUrl, cred)
 	if err != nil {
 		klog.Errorf("load chart failed, error: %s", err)
 		return data, err
@@ -24,6 +24,9 @@ import (
 
 var _ helm.Executor = &YamlInstaller{}
 
+// YamlFieldManager is the field manager of the resources applied by the YamlInstaller.
+const YamlFieldManager = "v1.FieldManager"
+
 type YamlInstaller struct {
 	Mapper      meta.RESTMapper
 	DynamicCli  *dynamic.DynamicClient
@@ -77,6 +80,14 @@ func (t YamlInstaller) Get(ctx context.Context, releaseName string, options ...h
 	return rv, nil
 }
 
+// DryRun returns the manifests as is, since the values of the yaml application are the manifests to be applied.
+func (t YamlInstaller) DryRun(ctx context.Context, release string, values []byte, options ...helm.HelmOption) (*helmrelease.Release, error) {
+	if _, err := ReadYaml(values); err != nil {
+		return nil, err
+	}
+	return &helmrelease.Release{Name: release, Namespace: t.Namespace, Manifest: string(values)}, nil
+}
+
 func (t YamlInstaller) WaitingForResourcesReady(ctx context.Context, release string, timeout time.Duration, options ...helm.HelmOption) (bool, error) {
 	return true, nil
 }
@@ -89,7 +100,7 @@ func (t YamlInstaller) ForApply(tasks []json.RawMessage) (err error) {
 		if err != nil {
 			return err
 		}
-		opt := metav1.PatchOptions{FieldManager: "v1.FieldManager"}
+		opt := metav1.PatchOptions{FieldManager: YamlFieldManager}
 		_, err = t.DynamicCli.Resource(gvr).
 			Namespace(utd.GetNamespace()).
 			Patch(context.TODO(), utd.GetName(), types.ApplyPatchType, js, opt)
@@ -36,6 +36,7 @@ type Registry struct {
 	password              string
 	timeout               time.Duration
 	insecureSkipVerifyTLS bool
+	tlsConfig             *tls.Config
 }
 
 func NewRegistry(name string, options ...RegistryOption) (*Registry, error) {
@@ -53,12 +54,18 @@ func NewRegistry(name string, options ...RegistryOption) (*Registry, error) {
 		option(reg)
 	}
 
+	tlsConfig := &tls.Config{}
+	if reg.tlsConfig != nil {
+		tlsConfig = reg.tlsConfig.Clone()
+	}
+	tlsConfig.InsecureSkipVerify = reg.insecureSkipVerifyTLS
+
 	headers := http.Header{}
 	headers.Set("User-Agent", "kubesphere.io")
 	reg.Client = &auth.Client{
 		Client: &http.Client{
 			Timeout:   reg.timeout,
-			Transport: &http.Transport{TLSClientConfig: &tls.Config{InsecureSkipVerify: reg.insecureSkipVerifyTLS}},
+			Transport: &http.Transport{TLSClientConfig: tlsConfig},
 		},
 		Header: headers,
 		Credential: func(_ context.Context, _ string) (auth.Credential, error) {
@@ -100,6 +107,13 @@ func WithInsecureSkipVerifyTLS(insecureSkipVerifyTLS bool) RegistryOption {
 	}
 }
 
+// WithTLSClientConfig sets the client certificates and the CA bundle of the registry.
+func WithTLSClientConfig(tlsConfig *tls.Config) RegistryOption {
+	return func(reg *Registry) {
+		reg.tlsConfig = tlsConfig
+	}
+}
+
 func (r *Registry) client() remote.Client {
 	if r.Client == nil {
 		return auth.DefaultClient
@@ -0,0 +1,64 @@
+/*
+ * Copyright 2024 the KubeSphere Authors.
+ * Please refer to the LICENSE file in the root directory of the project.
+ * https://github.com/kubesphere/kubesphere/blob/master/LICENSE
+ */
+
+package scanner
+
+import (
+	"fmt"
+	"net/url"
+	"time"
+
+	appv2 "kubesphere.io/api/application/v2"
+)
+
+type Options struct {
+	// Enabled scans the application versions when submitted, the versions can't be published unless the scan is passed.
+	Enabled bool `json:"enabled" yaml:"enabled" mapstructure:"enabled"`
+	// TrivyServer is the address of the Trivy server, the images are not scanned if empty.
+	TrivyServer string `json:"trivyServer,omitempty" yaml:"trivyServer,omitempty" mapstructure:"trivyServer,omitempty"`
+	// Token is sent to the Trivy server in the Trivy-Token header.
+	Token   string        `json:"token,omitempty" yaml:"token,omitempty" mapstructure:"token,omitempty"`
+	Timeout time.Duration `json:"timeout,omitempty" yaml:"timeout,omitempty" mapstructure:"timeout,omitempty"`
+	// SeverityThreshold is the severity at and above which the findings block the publishing.
+	SeverityThreshold string        `json:"severityThreshold,omitempty" yaml:"severityThreshold,omitempty" mapstructure:"severityThreshold,omitempty"`
+	Policies          PolicyOptions `json:"policies" yaml:"policies" mapstructure:"policies"`
+}
+
+// PolicyOptions are the policies which the manifests are evaluated against.
+type PolicyOptions struct {
+	// DisallowPrivileged reports the privileged containers.
+	DisallowPrivileged bool `json:"disallowPrivileged" yaml:"disallowPrivileged" mapstructure:"disallowPrivileged"`
+	// RequireResourceLimits reports the containers without cpu or memory limits.
+	RequireResourceLimits bool `json:"requireResourceLimits" yaml:"requireResourceLimits" mapstructure:"requireResourceLimits"`
+	// DisallowHostPath reports the pods mounting hostPath volumes.
+	DisallowHostPath bool `json:"disallowHostPath" yaml:"disallowHostPath" mapstructure:"disallowHostPath"`
+}
+
+func NewOptions() *Options {
+	return &Options{
+		Enabled:           false,
+		Timeout:           5 * time.Minute,
+		SeverityThreshold: appv2.SeverityHigh,
+		Policies: PolicyOptions{
+			DisallowPrivileged:    true,
+			RequireResourceLimits: true,
+			DisallowHostPath:      true,
+		},
+	}
+}
+
+func (o *Options) Validate() []error {
+	var errs []error
+	if severityLevel(o.SeverityThreshold) < 0 {
+		errs = append(errs, fmt.Errorf("invalid severity threshold %q", o.SeverityThreshold))
+	}
+	if o.TrivyServer != "" {
+		if u, err := url.Parse(o.TrivyServer); err != nil || u.Host == "" {
+			errs = append(errs, fmt.Errorf("invalid trivy server %q", o.TrivyServer))
+		}
+	}
+	return errs
+}
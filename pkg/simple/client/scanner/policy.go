@@ -0,0 +1,153 @@
+/*
+ * Copyright 2024 the KubeSphere Authors.
+ * Please refer to the LICENSE file in the root directory of the project.
+ * https://github.com/kubesphere/kubesphere/blob/master/LICENSE
+ */
+
+package scanner
+
+import (
+	"fmt"
+	"sort"
+
+	"helm.sh/helm/v3/pkg/releaseutil"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	appv2 "kubesphere.io/api/application/v2"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	PolicyNoPrivileged          = "no-privileged"
+	PolicyRequireResourceLimits = "require-resource-limits"
+	PolicyNoHostPath            = "no-host-path"
+)
+
+// podSpecPaths are the paths of the pod spec in the workloads.
+var podSpecPaths = map[string][]string{
+	"Pod":                   {"spec"},
+	"Deployment":            {"spec", "template", "spec"},
+	"StatefulSet":           {"spec", "template", "spec"},
+	"DaemonSet":             {"spec", "template", "spec"},
+	"ReplicaSet":            {"spec", "template", "spec"},
+	"ReplicationController": {"spec", "template", "spec"},
+	"Job":                   {"spec", "template", "spec"},
+	"CronJob":               {"spec", "jobTemplate", "spec", "template", "spec"},
+}
+
+type workload struct {
+	resource string
+	podSpec  *corev1.PodSpec
+}
+
+// parseManifest parses the resources of the manifest, the items of the lists are expanded.
+func parseManifest(manifest string) ([]*unstructured.Unstructured, error) {
+	var objects []*unstructured.Unstructured
+	for _, content := range releaseutil.SplitManifests(manifest) {
+		obj := &unstructured.Unstructured{}
+		if err := yaml.Unmarshal([]byte(content), &obj.Object); err != nil {
+			return nil, err
+		}
+		if obj.Object == nil || obj.GetKind() == "" {
+			continue
+		}
+		if !obj.IsList() {
+			objects = append(objects, obj)
+			continue
+		}
+		if err := obj.EachListItem(func(item runtime.Object) error {
+			objects = append(objects, item.(*unstructured.Unstructured))
+			return nil
+		}); err != nil {
+			return nil, err
+		}
+	}
+	return objects, nil
+}
+
+func workloadsOf(objects []*unstructured.Unstructured) []workload {
+	var workloads []workload
+	for _, obj := range objects {
+		path, ok := podSpecPaths[obj.GetKind()]
+		if !ok {
+			continue
+		}
+		spec, found, err := unstructured.NestedMap(obj.Object, path...)
+		if err != nil || !found {
+			continue
+		}
+		podSpec := &corev1.PodSpec{}
+		if err = runtime.DefaultUnstructuredConverter.FromUnstructured(spec, podSpec); err != nil {
+			continue
+		}
+		workloads = append(workloads, workload{resource: fmt.Sprintf("%s/%s", obj.GetKind(), obj.GetName()), podSpec: podSpec})
+	}
+	return workloads
+}
+
+func containersOf(podSpec *corev1.PodSpec) []corev1.Container {
+	containers := append([]corev1.Container{}, podSpec.InitContainers...)
+	return append(containers, podSpec.Containers...)
+}
+
+// imagesOf returns the sorted images of the workloads in the objects.
+func imagesOf(objects []*unstructured.Unstructured) []string {
+	set := make(map[string]struct{})
+	for _, w := range workloadsOf(objects) {
+		for _, container := range containersOf(w.podSpec) {
+			if container.Image != "" {
+				set[container.Image] = struct{}{}
+			}
+		}
+	}
+	images := make([]string, 0, len(set))
+	for image := range set {
+		images = append(images, image)
+	}
+	sort.Strings(images)
+	return images
+}
+
+// EvaluatePolicies evaluates the workloads in the objects against the enabled policies.
+func EvaluatePolicies(objects []*unstructured.Unstructured, policies PolicyOptions) []appv2.ScanFinding {
+	var findings []appv2.ScanFinding
+	violate := func(policy, severity, resource, message string) {
+		findings = append(findings, appv2.ScanFinding{
+			Type:     appv2.FindingTypePolicy,
+			Severity: severity,
+			ID:       policy,
+			Resource: resource,
+			Message:  message,
+		})
+	}
+	for _, w := range workloadsOf(objects) {
+		for _, container := range containersOf(w.podSpec) {
+			if policies.DisallowPrivileged && container.SecurityContext != nil &&
+				container.SecurityContext.Privileged != nil && *container.SecurityContext.Privileged {
+				violate(PolicyNoPrivileged, appv2.SeverityHigh, w.resource,
+					fmt.Sprintf("container %s is privileged", container.Name))
+			}
+			if policies.RequireResourceLimits {
+				limits := container.Resources.Limits
+				if _, ok := limits[corev1.ResourceCPU]; !ok {
+					violate(PolicyRequireResourceLimits, appv2.SeverityMedium, w.resource,
+						fmt.Sprintf("container %s has no cpu limit", container.Name))
+				}
+				if _, ok := limits[corev1.ResourceMemory]; !ok {
+					violate(PolicyRequireResourceLimits, appv2.SeverityMedium, w.resource,
+						fmt.Sprintf("container %s has no memory limit", container.Name))
+				}
+			}
+		}
+		if policies.DisallowHostPath {
+			for _, volume := range w.podSpec.Volumes {
+				if volume.HostPath != nil {
+					violate(PolicyNoHostPath, appv2.SeverityHigh, w.resource,
+						fmt.Sprintf("volume %s mounts host path %s", volume.Name, volume.HostPath.Path))
+				}
+			}
+		}
+	}
+	return findings
+}
@@ -0,0 +1,108 @@
+/*
+ * Copyright 2024 the KubeSphere Authors.
+ * Please refer to the LICENSE file in the root directory of the project.
+ * https://github.com/kubesphere/kubesphere/blob/master/LICENSE
+ */
+
+package scanner
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	appv2 "kubesphere.io/api/application/v2"
+)
+
+// Interface scans the images for vulnerabilities.
+type Interface interface {
+	Scan(ctx context.Context, image string) ([]appv2.ScanFinding, error)
+}
+
+// NewScanner returns the scanner configured by the options, nil is returned if no scanner is configured.
+func NewScanner(options *Options) Interface {
+	if options == nil || options.TrivyServer == "" {
+		return nil
+	}
+	return NewTrivyScanner(options.TrivyServer, options.Token, options.Timeout)
+}
+
+var severities = []string{
+	appv2.SeverityUnknown,
+	appv2.SeverityLow,
+	appv2.SeverityMedium,
+	appv2.SeverityHigh,
+	appv2.SeverityCritical,
+}
+
+// severityLevel returns the level of the severity, -1 is returned if the severity is invalid.
+func severityLevel(severity string) int {
+	for i, s := range severities {
+		if strings.EqualFold(s, severity) {
+			return i
+		}
+	}
+	return -1
+}
+
+// normalizeSeverity returns the severity in upper case, the unrecognized severity is unknown.
+func normalizeSeverity(severity string) string {
+	if level := severityLevel(severity); level >= 0 {
+		return severities[level]
+	}
+	return appv2.SeverityUnknown
+}
+
+// ScanManifest scans the images in the manifest and evaluates the manifest against the policies,
+// the images are not scanned if the scanner is nil.
+func ScanManifest(ctx context.Context, scanner Interface, policies PolicyOptions, manifest string) ([]appv2.ScanFinding, error) {
+	objects, err := parseManifest(manifest)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse manifest: %s", err)
+	}
+	findings := EvaluatePolicies(objects, policies)
+	if scanner == nil {
+		return findings, nil
+	}
+	for _, image := range imagesOf(objects) {
+		vulnerabilities, err := scanner.Scan(ctx, image)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan image %s: %s", image, err)
+		}
+		findings = append(findings, vulnerabilities...)
+	}
+	return findings, nil
+}
+
+// Evaluate returns the scan result of the findings, the publishing is blocked if any finding is at or above the threshold.
+func Evaluate(findings []appv2.ScanFinding, threshold string) *appv2.ApplicationVersionScan {
+	threshold = normalizeSeverity(threshold)
+	findings = append([]appv2.ScanFinding{}, findings...)
+	sort.SliceStable(findings, func(i, j int) bool {
+		return severityLevel(findings[i].Severity) > severityLevel(findings[j].Severity)
+	})
+	result := &appv2.ApplicationVersionScan{
+		State:     appv2.ScanStatePassed,
+		Threshold: threshold,
+		Summary:   make(map[string]int),
+		ScannedAt: &metav1.Time{Time: metav1.Now().Time},
+	}
+	var blocking int
+	for _, finding := range findings {
+		result.Summary[finding.Severity]++
+		if severityLevel(finding.Severity) >= severityLevel(threshold) {
+			blocking++
+		}
+	}
+	if blocking > 0 {
+		result.State = appv2.ScanStateBlocked
+		result.Message = fmt.Sprintf("%d findings at or above severity %s", blocking, threshold)
+	}
+	if len(findings) > appv2.MaxNumOfScanFindings {
+		findings = findings[:appv2.MaxNumOfScanFindings]
+	}
+	result.Findings = findings
+	return result
+}
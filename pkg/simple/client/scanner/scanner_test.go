@@ -0,0 +1,177 @@
+/*
+ * Copyright 2024 the KubeSphere Authors.
+ * Please refer to the LICENSE file in the root directory of the project.
+ * https://github.com/kubesphere/kubesphere/blob/master/LICENSE
+ */
+
+package scanner
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	appv2 "kubesphere.io/api/application/v2"
+)
+
+const testManifest = `
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+spec:
+  template:
+    spec:
+      initContainers:
+      - name: init
+        image: busybox:1.36
+        resources:
+          limits:
+            cpu: 100m
+            memory: 64Mi
+      containers:
+      - name: nginx
+        image: nginx:1.25
+        securityContext:
+          privileged: true
+        resources:
+          limits:
+            cpu: 500m
+      volumes:
+      - name: logs
+        hostPath:
+          path: /var/log
+---
+apiVersion: v1
+kind: List
+items:
+- apiVersion: batch/v1
+  kind: CronJob
+  metadata:
+    name: backup
+  spec:
+    jobTemplate:
+      spec:
+        template:
+          spec:
+            containers:
+            - name: backup
+              image: nginx:1.25
+              resources:
+                limits:
+                  cpu: 100m
+                  memory: 64Mi
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: config
+`
+
+type fakeScanner map[string][]appv2.ScanFinding
+
+func (f fakeScanner) Scan(_ context.Context, image string) ([]appv2.ScanFinding, error) {
+	return f[image], nil
+}
+
+func TestScanManifest(t *testing.T) {
+	objects, err := parseManifest(testManifest)
+	assert.NoError(t, err)
+	assert.Len(t, objects, 3)
+	assert.Equal(t, []string{"busybox:1.36", "nginx:1.25"}, imagesOf(objects))
+
+	findings := EvaluatePolicies(objects, NewOptions().Policies)
+	var policies []string
+	for _, finding := range findings {
+		assert.Equal(t, appv2.FindingTypePolicy, finding.Type)
+		assert.Equal(t, "Deployment/web", finding.Resource)
+		policies = append(policies, finding.ID)
+	}
+	assert.Equal(t, []string{PolicyNoPrivileged, PolicyRequireResourceLimits, PolicyNoHostPath}, policies)
+	assert.Empty(t, EvaluatePolicies(objects, PolicyOptions{}))
+
+	scanner := fakeScanner{"nginx:1.25": {{Type: appv2.FindingTypeVulnerability, Severity: appv2.SeverityCritical, ID: "CVE-2024-0001"}}}
+	findings, err = ScanManifest(context.Background(), scanner, PolicyOptions{RequireResourceLimits: true}, testManifest)
+	assert.NoError(t, err)
+	assert.Len(t, findings, 2)
+
+	findings, err = ScanManifest(context.Background(), nil, PolicyOptions{RequireResourceLimits: true}, testManifest)
+	assert.NoError(t, err)
+	assert.Len(t, findings, 1)
+}
+
+func TestEvaluate(t *testing.T) {
+	findings := []appv2.ScanFinding{
+		{Severity: appv2.SeverityMedium, ID: "require-resource-limits"},
+		{Severity: appv2.SeverityCritical, ID: "CVE-2024-0001"},
+		{Severity: appv2.SeverityLow, ID: "CVE-2024-0002"},
+	}
+	result := Evaluate(findings, appv2.SeverityHigh)
+	assert.Equal(t, appv2.ScanStateBlocked, result.State)
+	assert.Equal(t, "CVE-2024-0001", result.Findings[0].ID)
+	assert.Equal(t, map[string]int{appv2.SeverityMedium: 1, appv2.SeverityCritical: 1, appv2.SeverityLow: 1}, result.Summary)
+
+	result = Evaluate(findings[:1], "high")
+	assert.Equal(t, appv2.ScanStatePassed, result.State)
+	assert.Equal(t, appv2.SeverityHigh, result.Threshold)
+
+	many := make([]appv2.ScanFinding, appv2.MaxNumOfScanFindings+1)
+	result = Evaluate(many, appv2.SeverityCritical)
+	assert.Len(t, result.Findings, appv2.MaxNumOfScanFindings)
+	assert.Equal(t, appv2.MaxNumOfScanFindings+1, result.Summary[""])
+}
+
+func TestTrivyScanner(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/scan" || r.Header.Get(trivyTokenHeader) != "token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		body := map[string]string{}
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		if body["image"] != "nginx:1.25" {
+			http.Error(w, "image not found", http.StatusNotFound)
+			return
+		}
+		_, _ = w.Write([]byte(`{"Results":[
+{"Target":"nginx:1.25 (debian 12.4)","Vulnerabilities":[
+  {"VulnerabilityID":"CVE-2024-0001","PkgName":"openssl","InstalledVersion":"3.0.11","FixedVersion":"3.0.13","Severity":"CRITICAL","Title":"openssl: overflow"},
+  {"VulnerabilityID":"CVE-2024-0002","PkgName":"zlib","InstalledVersion":"1.2.13","Severity":"negligible"}]},
+{"Target":"usr/lib","Vulnerabilities":[
+  {"VulnerabilityID":"CVE-2024-0001","PkgName":"openssl","InstalledVersion":"3.0.11","Severity":"CRITICAL"}]}]}`))
+	}))
+	defer server.Close()
+
+	scanner := NewScanner(&Options{TrivyServer: server.URL + "/", Token: "token", Timeout: time.Second})
+	findings, err := scanner.Scan(context.Background(), "nginx:1.25")
+	assert.NoError(t, err)
+	assert.Equal(t, []appv2.ScanFinding{
+		{
+			Type:             appv2.FindingTypeVulnerability,
+			Severity:         appv2.SeverityCritical,
+			ID:               "CVE-2024-0001",
+			Image:            "nginx:1.25",
+			Package:          "openssl",
+			InstalledVersion: "3.0.11",
+			FixedVersion:     "3.0.13",
+			Message:          "openssl: overflow",
+		},
+		{
+			Type:             appv2.FindingTypeVulnerability,
+			Severity:         appv2.SeverityUnknown,
+			ID:               "CVE-2024-0002",
+			Image:            "nginx:1.25",
+			Package:          "zlib",
+			InstalledVersion: "1.2.13",
+		},
+	}, findings)
+
+	_, err = scanner.Scan(context.Background(), "redis:7")
+	assert.ErrorContains(t, err, "image not found")
+
+	assert.Nil(t, NewScanner(&Options{}))
+}
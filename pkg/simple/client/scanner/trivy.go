@@ -0,0 +1,105 @@
+/*
+ * Copyright 2024 the KubeSphere Authors.
+ * Please refer to the LICENSE file in the root directory of the project.
+ * https://github.com/kubesphere/kubesphere/blob/master/LICENSE
+ */
+
+package scanner
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	appv2 "kubesphere.io/api/application/v2"
+)
+
+const trivyTokenHeader = "Trivy-Token"
+
+// trivyReport is the report of `trivy image --format json`, only the fields used are defined.
+type trivyReport struct {
+	Results []struct {
+		Target          string `json:"Target"`
+		Vulnerabilities []struct {
+			VulnerabilityID  string `json:"VulnerabilityID"`
+			PkgName          string `json:"PkgName"`
+			InstalledVersion string `json:"InstalledVersion"`
+			FixedVersion     string `json:"FixedVersion"`
+			Severity         string `json:"Severity"`
+			Title            string `json:"Title"`
+		} `json:"Vulnerabilities"`
+	} `json:"Results"`
+}
+
+// TrivyScanner scans the images by a Trivy server, which accepts `POST /scan` with the image
+// and responds the report in the JSON format of Trivy.
+type TrivyScanner struct {
+	server string
+	token  string
+	client *http.Client
+}
+
+func NewTrivyScanner(server, token string, timeout time.Duration) *TrivyScanner {
+	return &TrivyScanner{
+		server: strings.TrimSuffix(server, "/"),
+		token:  token,
+		client: &http.Client{Timeout: timeout},
+	}
+}
+
+func (t *TrivyScanner) Scan(ctx context.Context, image string) ([]appv2.ScanFinding, error) {
+	body, err := json.Marshal(map[string]string{"image": image})
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, t.server+"/scan", bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if t.token != "" {
+		req.Header.Set(trivyTokenHeader, t.token)
+	}
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		data, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return nil, fmt.Errorf("unexpected status %d: %s", resp.StatusCode, strings.TrimSpace(string(data)))
+	}
+
+	report := &trivyReport{}
+	if err = json.NewDecoder(resp.Body).Decode(report); err != nil {
+		return nil, fmt.Errorf("failed to decode report: %s", err)
+	}
+	var findings []appv2.ScanFinding
+	// the same vulnerability of a package may be reported by multiple targets
+	seen := make(map[string]struct{})
+	for _, result := range report.Results {
+		for _, v := range result.Vulnerabilities {
+			key := v.VulnerabilityID + "/" + v.PkgName + "/" + v.InstalledVersion
+			if _, ok := seen[key]; ok {
+				continue
+			}
+			seen[key] = struct{}{}
+			findings = append(findings, appv2.ScanFinding{
+				Type:             appv2.FindingTypeVulnerability,
+				Severity:         normalizeSeverity(v.Severity),
+				ID:               v.VulnerabilityID,
+				Image:            image,
+				Package:          v.PkgName,
+				InstalledVersion: v.InstalledVersion,
+				FixedVersion:     v.FixedVersion,
+				Message:          v.Title,
+			})
+		}
+	}
+	return findings, nil
+}
@@ -9,6 +9,7 @@ package serviceaccount
 import (
 	"strings"
 
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apiserver/pkg/authentication/user"
 	corev1alpha1 "kubesphere.io/api/core/v1alpha1"
 )
@@ -34,6 +35,26 @@ func GetSecretName(info user.Info) (name, namespace string) {
 	return
 }
 
+// IsRevoked returns whether the token with the given ID is revoked by the secret. Once the ID of the current
+// token is recorded, only the current token and the previous one rotated before expiring are valid, the others
+// including the tokens without the ID are revoked.
+func IsRevoked(secret *corev1.Secret, tokenID string) bool {
+	if current := secret.Annotations[corev1alpha1.ServiceAccountTokenID]; current != "" {
+		if tokenID == "" || (tokenID != current && tokenID != secret.Annotations[corev1alpha1.ServiceAccountTokenPreviousID]) {
+			return true
+		}
+	}
+	if tokenID == "" {
+		return false
+	}
+	for _, revoked := range strings.Split(secret.Annotations[corev1alpha1.ServiceAccountTokenRevoked], ",") {
+		if strings.TrimSpace(revoked) == tokenID {
+			return true
+		}
+	}
+	return false
+}
+
 func SplitUsername(username string) (name, namespace string) {
 	if !strings.HasPrefix(username, corev1alpha1.ServiceAccountTokenPrefix) {
 		return "", ""
@@ -20,6 +20,7 @@ const (
 	LatestAppVersionKey         = "application.kubesphere.io/latest-app-version"
 	AppMaintainersKey           = "application.kubesphere.io/app-maintainers"
 	AppReleaseReferenceLabelKey = "application.kubesphere.io/app-release-name"
+	ParentAppReleaseLabelKey    = "application.kubesphere.io/parent-release"
 	UncategorizedCategoryID     = "kubesphere-app-uncategorized"
 	StatusActive                = "active"
 	StatusSuccessful            = "successful"
@@ -35,12 +36,15 @@ const (
 	StatusCreated               = "created"
 	StatusUpgraded              = "upgraded"
 	StatusNosync                = "nosync"
+	StatusPending               = "pending"
 	AppTypeHelm                 = "helm"
 	AppTypeYaml                 = "yaml"
 	AppTypeEdge                 = "edge"
 	BinaryKey                   = "BinaryKey"
 	UploadRepoKey               = "upload"
 	MaxNumOfVersions            = 10
+	MaxNumOfRevisions           = 10
+	MaxNumOfScanFindings        = 100
 	MaxImageWidth               = 128
 	ApplicationNamespace        = "extension-openpitrix"
 	StoreCleanFinalizer         = "storeCleanFinalizer.application.kubesphere.io"
@@ -53,4 +57,18 @@ const (
 	ReviewStatusRejected  = "rejected"
 	ReviewStatusSuspended = "suspended"
 	ReviewStatusActive    = "active"
+	// App version scan state: scanning, passed, blocked, failed
+	ScanStateScanning = "scanning"
+	ScanStatePassed   = "passed"
+	ScanStateBlocked  = "blocked"
+	ScanStateFailed   = "failed"
+	// Scan finding type: vulnerability, policy
+	FindingTypeVulnerability = "vulnerability"
+	FindingTypePolicy        = "policy"
+	// Scan finding severity in ascending order, the same as the severity of Trivy
+	SeverityUnknown  = "UNKNOWN"
+	SeverityLow      = "LOW"
+	SeverityMedium   = "MEDIUM"
+	SeverityHigh     = "HIGH"
+	SeverityCritical = "CRITICAL"
 )
@@ -11,6 +11,7 @@ import (
 	"encoding/json"
 	"fmt"
 
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
 	"kubesphere.io/api/constants"
@@ -87,6 +88,23 @@ type ApplicationReleaseSpec struct {
 	Values       []byte `json:"values,omitempty"`
 	AppType      string `json:"appType,omitempty"`
 	Icon         string `json:"icon,omitempty"`
+	// Placement deploys the release to multiple clusters instead of the cluster in the label kubesphere.io/cluster,
+	// an ApplicationRelease is created for each placed cluster and managed by this release.
+	// +optional
+	Placement *ReleasePlacement `json:"placement,omitempty"`
+}
+
+// ReleasePlacement describes the clusters which the release is deployed to.
+type ReleasePlacement struct {
+	// Clusters takes precedence over ClusterSelector.
+	// +listType=set
+	// +optional
+	Clusters        []string              `json:"clusters,omitempty"`
+	ClusterSelector *metav1.LabelSelector `json:"clusterSelector,omitempty"`
+	// Overrides are the values of each cluster, which are merged into the values of helm applications,
+	// or replace the manifests of yaml applications.
+	// +optional
+	Overrides map[string]string `json:"overrides,omitempty"`
 }
 
 // ApplicationReleaseStatus defines the observed state of ApplicationRelease
@@ -98,6 +116,32 @@ type ApplicationReleaseStatus struct {
 	UninstallJobName  string            `json:"uninstallJobName,omitempty"`
 	LastUpdate        metav1.Time       `json:"lastUpdate,omitempty"`
 	RealTimeResources []json.RawMessage `json:"realTimeResources,omitempty"`
+	// Revisions is the history of the deployed specs, the latest one is the last,
+	// at most MaxNumOfRevisions revisions are kept.
+	Revisions []ApplicationReleaseRevision `json:"revisions,omitempty"`
+	// ClusterStatuses describes the release of each placed cluster, only for the release with placement.
+	ClusterStatuses map[string]ClusterReleaseStatus `json:"clusterStatuses,omitempty"`
+}
+
+// ClusterReleaseStatus is the status of the release deployed to a placed cluster.
+type ClusterReleaseStatus struct {
+	// ReleaseName is the name of the ApplicationRelease created for the cluster.
+	ReleaseName string      `json:"releaseName"`
+	State       string      `json:"state,omitempty"`
+	Message     string      `json:"message,omitempty"`
+	LastUpdate  metav1.Time `json:"lastUpdate,omitempty"`
+}
+
+// ApplicationReleaseRevision is a spec of the ApplicationRelease which has been deployed successfully.
+type ApplicationReleaseRevision struct {
+	// Revision increases each time a different spec is deployed, including rolling back.
+	Revision     int    `json:"revision"`
+	AppVersionID string `json:"appVersionID"`
+	// ValuesRef is the name of the Secret holding the values of the revision in the ApplicationNamespace,
+	// the values are not kept in the status to stay within the size limit of the object.
+	ValuesRef  string      `json:"valuesRef,omitempty"`
+	SpecHash   string      `json:"specHash,omitempty"`
+	DeployedAt metav1.Time `json:"deployedAt,omitempty"`
 }
 
 // +kubebuilder:object:root=true
@@ -151,6 +195,24 @@ func (in *ApplicationRelease) GetRlsNamespace() string {
 	return ns
 }
 
+// LatestRevision returns the latest deployed revision, or nil if no spec has been deployed.
+func (in *ApplicationRelease) LatestRevision() *ApplicationReleaseRevision {
+	if len(in.Status.Revisions) == 0 {
+		return nil
+	}
+	return &in.Status.Revisions[len(in.Status.Revisions)-1]
+}
+
+// GetRevision returns the deployed revision, or nil if it's not found in the history.
+func (in *ApplicationRelease) GetRevision(revision int) *ApplicationReleaseRevision {
+	for i := range in.Status.Revisions {
+		if in.Status.Revisions[i].Revision == revision {
+			return &in.Status.Revisions[i]
+		}
+	}
+	return nil
+}
+
 func (in *ApplicationRelease) HashSpec() string {
 	specJSON, _ := json.Marshal(in.Spec)
 	return fmt.Sprintf("%x", md5.Sum(specJSON))
@@ -174,6 +236,39 @@ type ApplicationVersionStatus struct {
 	Message  string       `json:"message,omitempty"`
 	UserName string       `json:"userName,omitempty"`
 	Updated  *metav1.Time `json:"updated,omitempty"`
+	// Scan is the result of the automated review when the version is submitted,
+	// the version can't be passed or activated unless the scan is passed.
+	Scan *ApplicationVersionScan `json:"scan,omitempty"`
+}
+
+// ApplicationVersionScan is the result of scanning the images and the manifests of an ApplicationVersion.
+type ApplicationVersionScan struct {
+	// State is one of scanning, passed, blocked and failed.
+	State   string `json:"state"`
+	Message string `json:"message,omitempty"`
+	// Threshold is the severity at and above which the findings block the publishing.
+	Threshold string `json:"threshold,omitempty"`
+	// Summary is the number of findings of each severity.
+	Summary map[string]int `json:"summary,omitempty"`
+	// Findings are sorted by severity in descending order, at most MaxNumOfScanFindings findings are kept.
+	Findings  []ScanFinding `json:"findings,omitempty"`
+	ScannedAt *metav1.Time  `json:"scannedAt,omitempty"`
+}
+
+// ScanFinding is a vulnerability of an image or a violation of a policy.
+type ScanFinding struct {
+	// Type is either vulnerability or policy.
+	Type     string `json:"type"`
+	Severity string `json:"severity"`
+	// ID is the ID of the vulnerability, e.g. CVE-2024-3094, or the name of the violated policy.
+	ID               string `json:"id"`
+	Image            string `json:"image,omitempty"`
+	Package          string `json:"package,omitempty"`
+	InstalledVersion string `json:"installedVersion,omitempty"`
+	FixedVersion     string `json:"fixedVersion,omitempty"`
+	// Resource is the resource violating the policy, in the form of kind/name.
+	Resource string `json:"resource,omitempty"`
+	Message  string `json:"message,omitempty"`
 }
 
 // +kubebuilder:object:root=true
@@ -266,14 +361,19 @@ type CategoryList struct {
 
 type RepoCredential struct {
 	// chart repository username
+	// Deprecated: the username is kept in the Secret referred by SecretRef.
 	Username string `json:"username,omitempty"`
 	// chart repository password
+	// Deprecated: the password is kept in the Secret referred by SecretRef.
 	Password string `json:"password,omitempty"`
 	// identify HTTPS client using this SSL certificate file
+	// Deprecated: the certificate is kept in the Secret referred by SecretRef.
 	CertFile string `json:"certFile,omitempty"`
 	// identify HTTPS client using this SSL key file
+	// Deprecated: the key is kept in the Secret referred by SecretRef.
 	KeyFile string `json:"keyFile,omitempty"`
 	// verify certificates of HTTPS-enabled servers using this CA bundle
+	// Deprecated: the CA bundle is kept in the Secret referred by SecretRef.
 	CAFile string `json:"caFile,omitempty"`
 	// skip tls certificate checks for the repository, default is ture
 	InsecureSkipTLSVerify *bool `json:"insecureSkipTLSVerify,omitempty"`
@@ -281,10 +381,14 @@ type RepoCredential struct {
 
 // RepoSpec defines the desired state of Repo
 type RepoSpec struct {
-	Url         string         `json:"url"`
-	Credential  RepoCredential `json:"credential,omitempty"`
-	Description string         `json:"description,omitempty"`
-	SyncPeriod  *int           `json:"syncPeriod"`
+	Url        string         `json:"url"`
+	Credential RepoCredential `json:"credential,omitempty"`
+	// SecretRef refers to the Secret holding the credential in the system namespace of the workspace,
+	// with the keys username, password, tls.crt, tls.key and ca.crt.
+	// +optional
+	SecretRef   *corev1.LocalObjectReference `json:"secretRef,omitempty"`
+	Description string                       `json:"description,omitempty"`
+	SyncPeriod  *int                         `json:"syncPeriod"`
 }
 
 // RepoStatus defines the observed state of Repo
@@ -12,6 +12,8 @@ package v2
 
 import (
 	"encoding/json"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 )
 
@@ -133,6 +135,22 @@ func (in *ApplicationReleaseList) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ApplicationReleaseRevision) DeepCopyInto(out *ApplicationReleaseRevision) {
+	*out = *in
+	in.DeployedAt.DeepCopyInto(&out.DeployedAt)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ApplicationReleaseRevision.
+func (in *ApplicationReleaseRevision) DeepCopy() *ApplicationReleaseRevision {
+	if in == nil {
+		return nil
+	}
+	out := new(ApplicationReleaseRevision)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ApplicationReleaseSpec) DeepCopyInto(out *ApplicationReleaseSpec) {
 	*out = *in
@@ -141,6 +159,11 @@ func (in *ApplicationReleaseSpec) DeepCopyInto(out *ApplicationReleaseSpec) {
 		*out = make([]byte, len(*in))
 		copy(*out, *in)
 	}
+	if in.Placement != nil {
+		in, out := &in.Placement, &out.Placement
+		*out = new(ReleasePlacement)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ApplicationReleaseSpec.
@@ -168,6 +191,20 @@ func (in *ApplicationReleaseStatus) DeepCopyInto(out *ApplicationReleaseStatus)
 			}
 		}
 	}
+	if in.Revisions != nil {
+		in, out := &in.Revisions, &out.Revisions
+		*out = make([]ApplicationReleaseRevision, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.ClusterStatuses != nil {
+		in, out := &in.ClusterStatuses, &out.ClusterStatuses
+		*out = make(map[string]ClusterReleaseStatus, len(*in))
+		for key, val := range *in {
+			(*out)[key] = *val.DeepCopy()
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ApplicationReleaseStatus.
@@ -283,6 +320,37 @@ func (in *ApplicationVersionList) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ApplicationVersionScan) DeepCopyInto(out *ApplicationVersionScan) {
+	*out = *in
+	if in.Summary != nil {
+		in, out := &in.Summary, &out.Summary
+		*out = make(map[string]int, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.Findings != nil {
+		in, out := &in.Findings, &out.Findings
+		*out = make([]ScanFinding, len(*in))
+		copy(*out, *in)
+	}
+	if in.ScannedAt != nil {
+		in, out := &in.ScannedAt, &out.ScannedAt
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ApplicationVersionScan.
+func (in *ApplicationVersionScan) DeepCopy() *ApplicationVersionScan {
+	if in == nil {
+		return nil
+	}
+	out := new(ApplicationVersionScan)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ApplicationVersionSpec) DeepCopyInto(out *ApplicationVersionSpec) {
 	*out = *in
@@ -314,6 +382,11 @@ func (in *ApplicationVersionStatus) DeepCopyInto(out *ApplicationVersionStatus)
 		in, out := &in.Updated, &out.Updated
 		*out = (*in).DeepCopy()
 	}
+	if in.Scan != nil {
+		in, out := &in.Scan, &out.Scan
+		*out = new(ApplicationVersionScan)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ApplicationVersionStatus.
@@ -415,6 +488,22 @@ func (in *CategoryStatus) DeepCopy() *CategoryStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ClusterReleaseStatus) DeepCopyInto(out *ClusterReleaseStatus) {
+	*out = *in
+	in.LastUpdate.DeepCopyInto(&out.LastUpdate)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ClusterReleaseStatus.
+func (in *ClusterReleaseStatus) DeepCopy() *ClusterReleaseStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ClusterReleaseStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *GroupVersionResource) DeepCopyInto(out *GroupVersionResource) {
 	*out = *in
@@ -465,6 +554,38 @@ func (in *Metadata) DeepCopy() *Metadata {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ReleasePlacement) DeepCopyInto(out *ReleasePlacement) {
+	*out = *in
+	if in.Clusters != nil {
+		in, out := &in.Clusters, &out.Clusters
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.ClusterSelector != nil {
+		in, out := &in.ClusterSelector, &out.ClusterSelector
+		*out = new(v1.LabelSelector)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Overrides != nil {
+		in, out := &in.Overrides, &out.Overrides
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ReleasePlacement.
+func (in *ReleasePlacement) DeepCopy() *ReleasePlacement {
+	if in == nil {
+		return nil
+	}
+	out := new(ReleasePlacement)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Repo) DeepCopyInto(out *Repo) {
 	*out = *in
@@ -548,6 +669,11 @@ func (in *RepoList) DeepCopyObject() runtime.Object {
 func (in *RepoSpec) DeepCopyInto(out *RepoSpec) {
 	*out = *in
 	in.Credential.DeepCopyInto(&out.Credential)
+	if in.SecretRef != nil {
+		in, out := &in.SecretRef, &out.SecretRef
+		*out = new(corev1.LocalObjectReference)
+		**out = **in
+	}
 	if in.SyncPeriod != nil {
 		in, out := &in.SyncPeriod, &out.SyncPeriod
 		*out = new(int)
@@ -580,3 +706,18 @@ func (in *RepoStatus) DeepCopy() *RepoStatus {
 	in.DeepCopyInto(out)
 	return out
 }
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ScanFinding) DeepCopyInto(out *ScanFinding) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ScanFinding.
+func (in *ScanFinding) DeepCopy() *ScanFinding {
+	if in == nil {
+		return nil
+	}
+	out := new(ScanFinding)
+	in.DeepCopyInto(out)
+	return out
+}
@@ -299,6 +299,7 @@ func GetOpenAPIDefinitions(ref common.ReferenceCallback) map[string]common.OpenA
 		"kubesphere.io/api/cluster/v1alpha1.ClusterSpec":                 schema_kubesphereio_api_cluster_v1alpha1_ClusterSpec(ref),
 		"kubesphere.io/api/cluster/v1alpha1.ClusterStatus":               schema_kubesphereio_api_cluster_v1alpha1_ClusterStatus(ref),
 		"kubesphere.io/api/cluster/v1alpha1.Connection":                  schema_kubesphereio_api_cluster_v1alpha1_Connection(ref),
+		"kubesphere.io/api/cluster/v1alpha1.CredentialRotationStatus":    schema_kubesphereio_api_cluster_v1alpha1_CredentialRotationStatus(ref),
 		"kubesphere.io/api/cluster/v1alpha1.Label":                       schema_kubesphereio_api_cluster_v1alpha1_Label(ref),
 		"kubesphere.io/api/cluster/v1alpha1.LabelList":                   schema_kubesphereio_api_cluster_v1alpha1_LabelList(ref),
 		"kubesphere.io/api/cluster/v1alpha1.LabelSpec":                   schema_kubesphereio_api_cluster_v1alpha1_LabelSpec(ref),
@@ -15409,11 +15410,31 @@ func schema_kubesphereio_api_cluster_v1alpha1_ClusterStatus(ref common.Reference
 							Format:      "",
 						},
 					},
+					"credentialRotation": {
+						SchemaProps: spec.SchemaProps{
+							Description: "CredentialRotation is the status of the rotation of the credentials in the kubeconfig.",
+							Ref:         ref("kubesphere.io/api/cluster/v1alpha1.CredentialRotationStatus"),
+						},
+					},
+					"conditionHistory": {
+						SchemaProps: spec.SchemaProps{
+							Description: "ConditionHistory holds the previous health conditions of the cluster in the order of the transitions, a limited number of conditions are kept for each condition type.",
+							Type:        []string{"array"},
+							Items: &spec.SchemaOrArray{
+								Schema: &spec.Schema{
+									SchemaProps: spec.SchemaProps{
+										Default: map[string]interface{}{},
+										Ref:     ref("kubesphere.io/api/cluster/v1alpha1.ClusterCondition"),
+									},
+								},
+							},
+						},
+					},
 				},
 			},
 		},
 		Dependencies: []string{
-			"kubesphere.io/api/cluster/v1alpha1.ClusterCondition"},
+			"kubesphere.io/api/cluster/v1alpha1.ClusterCondition", "kubesphere.io/api/cluster/v1alpha1.CredentialRotationStatus"},
 	}
 }
 
@@ -15485,6 +15506,59 @@ func schema_kubesphereio_api_cluster_v1alpha1_Connection(ref common.ReferenceCal
 	}
 }
 
+func schema_kubesphereio_api_cluster_v1alpha1_CredentialRotationStatus(ref common.ReferenceCallback) common.OpenAPIDefinition {
+	return common.OpenAPIDefinition{
+		Schema: spec.Schema{
+			SchemaProps: spec.SchemaProps{
+				Type: []string{"object"},
+				Properties: map[string]spec.Schema{
+					"type": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Type of the credential in the kubeconfig",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+					"expirationTime": {
+						SchemaProps: spec.SchemaProps{
+							Description: "ExpirationTime is the time when the credential in the kubeconfig expires.",
+							Ref:         ref("k8s.io/apimachinery/pkg/apis/meta/v1.Time"),
+						},
+					},
+					"phase": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Phase of the last rotation",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+					"lastAttemptTime": {
+						SchemaProps: spec.SchemaProps{
+							Description: "LastAttemptTime is the time of the last rotation attempt.",
+							Ref:         ref("k8s.io/apimachinery/pkg/apis/meta/v1.Time"),
+						},
+					},
+					"lastRotationTime": {
+						SchemaProps: spec.SchemaProps{
+							Description: "LastRotationTime is the time when the credential was rotated successfully.",
+							Ref:         ref("k8s.io/apimachinery/pkg/apis/meta/v1.Time"),
+						},
+					},
+					"message": {
+						SchemaProps: spec.SchemaProps{
+							Description: "A human-readable message indicating details about the last rotation.",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+				},
+			},
+		},
+		Dependencies: []string{
+			"k8s.io/apimachinery/pkg/apis/meta/v1.Time"},
+	}
+}
+
 func schema_kubesphereio_api_cluster_v1alpha1_Label(ref common.ReferenceCallback) common.OpenAPIDefinition {
 	return common.OpenAPIDefinition{
 		Schema: spec.Schema{
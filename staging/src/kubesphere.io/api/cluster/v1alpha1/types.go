@@ -137,6 +137,19 @@ const (
 	ClusterKubeConfigCertExpiresInSevenDays ClusterConditionType = "KubeConfigCertExpiresInSevenDays"
 
 	ClusterKSCoreReady = "KSCoreReady"
+
+	// ClusterKubeAPIServerHealthy indicates whether the kube-apiserver is reachable and responds within the latency threshold.
+	ClusterKubeAPIServerHealthy ClusterConditionType = "KubeAPIServerHealthy"
+
+	// ClusterKubeSphereAPIServerHealthy indicates whether the ks-apiserver of the cluster is reachable.
+	ClusterKubeSphereAPIServerHealthy ClusterConditionType = "KubeSphereAPIServerHealthy"
+
+	// ClusterNodesReady indicates whether the ratio of the ready nodes reaches the threshold.
+	ClusterNodesReady ClusterConditionType = "NodesReady"
+
+	// ClusterKubeConfigCertValid indicates whether the remaining validity of the kubeconfig client certificate
+	// exceeds the expiration threshold.
+	ClusterKubeConfigCertValid ClusterConditionType = "KubeConfigCertValid"
 )
 
 type ClusterCondition struct {
@@ -184,6 +197,50 @@ type ClusterStatus struct {
 
 	// UID is the kube-system namespace UID of the cluster, which represents the unique ID of the cluster.
 	UID types.UID `json:"uid,omitempty"`
+
+	// CredentialRotation is the status of the rotation of the credentials in the kubeconfig.
+	// +optional
+	CredentialRotation *CredentialRotationStatus `json:"credentialRotation,omitempty"`
+
+	// ConditionHistory holds the previous health conditions of the cluster in the order of the transitions,
+	// a limited number of conditions are kept for each condition type.
+	// +optional
+	ConditionHistory []ClusterCondition `json:"conditionHistory,omitempty"`
+}
+
+type CredentialType string
+
+const (
+	CredentialTypeClientCertificate   CredentialType = "ClientCertificate"
+	CredentialTypeServiceAccountToken CredentialType = "ServiceAccountToken"
+)
+
+type CredentialRotationPhase string
+
+const (
+	CredentialRotationSucceeded CredentialRotationPhase = "Succeeded"
+	CredentialRotationFailed    CredentialRotationPhase = "Failed"
+)
+
+type CredentialRotationStatus struct {
+	// Type of the credential in the kubeconfig
+	// +optional
+	Type CredentialType `json:"type,omitempty"`
+	// ExpirationTime is the time when the credential in the kubeconfig expires.
+	// +optional
+	ExpirationTime *metav1.Time `json:"expirationTime,omitempty"`
+	// Phase of the last rotation
+	// +optional
+	Phase CredentialRotationPhase `json:"phase,omitempty"`
+	// LastAttemptTime is the time of the last rotation attempt.
+	// +optional
+	LastAttemptTime *metav1.Time `json:"lastAttemptTime,omitempty"`
+	// LastRotationTime is the time when the credential was rotated successfully.
+	// +optional
+	LastRotationTime *metav1.Time `json:"lastRotationTime,omitempty"`
+	// A human-readable message indicating details about the last rotation.
+	// +optional
+	Message string `json:"message,omitempty"`
 }
 
 // +kubebuilder:object:root=true
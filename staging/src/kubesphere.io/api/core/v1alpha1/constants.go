@@ -40,7 +40,9 @@ const (
 	RepositoryReferenceLabel = "kubesphere.io/repository-ref"
 	CategoryLabel            = "kubesphere.io/category"
 
-	ForceDeleteAnnotation                = "kubesphere.io/force-delete"
+	ForceDeleteAnnotation = "kubesphere.io/force-delete"
+	// BreakGlassAnnotation is the reason to override the ProtectionPolicy by the exempted users and groups.
+	BreakGlassAnnotation                 = "kubesphere.io/break-glass-reason"
 	ExecutorHookImageAnnotation          = "executor-hook-image.kubesphere.io"
 	ExecutorInstallHookImageAnnotation   = "executor-hook-image.kubesphere.io/install"
 	ExecutorUpgradeHookImageAnnotation   = "executor-hook-image.kubesphere.io/upgrade"
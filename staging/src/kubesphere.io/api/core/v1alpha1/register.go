@@ -40,6 +40,8 @@ func addKnownTypes(scheme *runtime.Scheme) error {
 		&CategoryList{},
 		&ServiceAccount{},
 		&ServiceAccountList{},
+		&ProtectionPolicy{},
+		&ProtectionPolicyList{},
 	)
 	// Add the watch version that applies
 	metav1.AddToGroupVersion(scheme, SchemeGroupVersion)
@@ -31,6 +31,27 @@ const (
 	ServiceAccountTokenSubFormat            = ServiceAccountTokenPrefix + "%s:%s"
 	ServiceAccountTokenExtraSecretNamespace = "secret-namespace"
 	ServiceAccountTokenExtraSecretName      = "secret-name"
+	ServiceAccountTokenExtraScopes          = "scopes"
+
+	// ServiceAccountTokenTTL is the lifetime of the tokens issued to the secret, e.g. 720h,
+	// the tokens never expire if it's not set.
+	ServiceAccountTokenTTL = "kubesphere.io/service-account-token.ttl"
+	// ServiceAccountTokenRotationOverlap is the duration before the token expires that a new token is issued,
+	// both tokens are valid during the overlap, it's 1/5 of the TTL by default.
+	ServiceAccountTokenRotationOverlap = "kubesphere.io/service-account-token.rotation-overlap"
+	// ServiceAccountTokenScopes restricts the tokens to the space separated scopes in the format of
+	// <verbs>:<resources>, e.g. "get,list,watch:pods,deployments.apps create:pods/exec",
+	// the non-resource URLs start with /, e.g. "get:/kapis/version".
+	ServiceAccountTokenScopes = "kubesphere.io/service-account-token.scopes"
+	// ServiceAccountTokenRevoked is the comma separated IDs of the revoked tokens.
+	ServiceAccountTokenRevoked = "kubesphere.io/service-account-token.revoked"
+	// ServiceAccountTokenID is the ID of the current token, the tokens with other IDs are rejected once it's recorded.
+	ServiceAccountTokenID = "kubesphere.io/service-account-token.id"
+	// ServiceAccountTokenPreviousID is the ID of the token replaced by the current one when it's rotated before
+	// expiring, it's valid until it expires.
+	ServiceAccountTokenPreviousID = "kubesphere.io/service-account-token.previous-id"
+	// ServiceAccountTokenExpiration is the expiration time of the current token.
+	ServiceAccountTokenExpiration = "kubesphere.io/service-account-token.expiration"
 )
 
 // Provider describes an extension provider.
@@ -202,6 +223,30 @@ type Placement struct {
 type ClusterScheduling struct {
 	Placement *Placement        `json:"placement,omitempty"`
 	Overrides map[string]string `json:"overrides,omitempty"`
+	// RolloutStrategy describes how the agent chart is rolled out to the placed clusters.
+	// If not specified, the agent chart will be installed or upgraded on all placed clusters at once.
+	// +optional
+	RolloutStrategy *RolloutStrategy `json:"rolloutStrategy,omitempty"`
+}
+
+// RolloutStrategy rolls out the agent chart to the placed clusters in batches,
+// the next batch starts only after all clusters of the current batch are healthy.
+type RolloutStrategy struct {
+	// BatchSize is the number of clusters in each batch. A value of 0 indicates that all clusters are in one batch.
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	BatchSize int `json:"batchSize,omitempty"`
+	// MaxUnavailable is the maximum number of clusters allowed to be unavailable (failed or not schedulable) during the rollout.
+	// The rollout will be halted automatically if the number is exceeded. The default is 0.
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	MaxUnavailable int `json:"maxUnavailable,omitempty"`
+	// PauseBetweenBatches is the duration to wait after a batch succeeded before starting the next batch.
+	// +optional
+	PauseBetweenBatches metav1.Duration `json:"pauseBetweenBatches,omitempty"`
+	// Paused suspends the rollout, clusters in batches that have not started will not be installed or upgraded.
+	// +optional
+	Paused bool `json:"paused,omitempty"`
 }
 
 type InstallPlanState struct {
@@ -236,11 +281,58 @@ type InstallPlanSpec struct {
 	ClusterScheduling *ClusterScheduling `json:"clusterScheduling,omitempty"`
 }
 
+type RolloutPhase string
+
+const (
+	RolloutPhaseProgressing RolloutPhase = "Progressing"
+	RolloutPhasePaused      RolloutPhase = "Paused"
+	RolloutPhaseHalted      RolloutPhase = "Halted"
+	RolloutPhaseCompleted   RolloutPhase = "Completed"
+)
+
+type RolloutBatchPhase string
+
+const (
+	RolloutBatchPhasePending     RolloutBatchPhase = "Pending"
+	RolloutBatchPhaseProgressing RolloutBatchPhase = "Progressing"
+	RolloutBatchPhaseSucceeded   RolloutBatchPhase = "Succeeded"
+	RolloutBatchPhaseFailed      RolloutBatchPhase = "Failed"
+)
+
+type RolloutBatchStatus struct {
+	Clusters []string          `json:"clusters"`
+	Phase    RolloutBatchPhase `json:"phase"`
+	// UnavailableClusters are the clusters in the batch that failed or are not schedulable.
+	// +optional
+	UnavailableClusters []string `json:"unavailableClusters,omitempty"`
+	// +optional
+	StartTime *metav1.Time `json:"startTime,omitempty"`
+	// +optional
+	CompletionTime *metav1.Time `json:"completionTime,omitempty"`
+}
+
+// RolloutStatus records the progress of the rollout, so that it can be resumed after the controller restarts.
+type RolloutStatus struct {
+	// Revision identifies the target version and configuration of the rollout, a new rollout starts when it changes.
+	Revision string       `json:"revision"`
+	Phase    RolloutPhase `json:"phase"`
+	// CurrentBatch is the index of the batch in progress.
+	CurrentBatch int                  `json:"currentBatch"`
+	Batches      []RolloutBatchStatus `json:"batches,omitempty"`
+	// +optional
+	Message string `json:"message,omitempty"`
+	// +optional
+	LastTransitionTime metav1.Time `json:"lastTransitionTime,omitempty"`
+}
+
 type InstallPlanStatus struct {
 	InstallationStatus `json:",inline"`
 	Enabled            bool `json:"enabled,omitempty"`
 	// ClusterSchedulingStatuses describes the subchart installation status of the extension
 	ClusterSchedulingStatuses map[string]InstallationStatus `json:"clusterSchedulingStatuses,omitempty"`
+	// Rollout describes the progress of the rollout if the rollout strategy is specified.
+	// +optional
+	Rollout *RolloutStatus `json:"rollout,omitempty"`
 }
 
 // +kubebuilder:object:root=true
@@ -290,11 +382,53 @@ type RepositorySpec struct {
 	// The maximum number of synchronized versions for each extension. A value of 0 indicates that all versions will be synchronized. The default is 3.
 	// +optional
 	Depth *int `json:"depth,omitempty"`
+	// Bundle declares an offline bundle as the source of the repository, it is used in air-gapped environments.
+	// The URL will be ignored if the bundle is specified.
+	// +optional
+	Bundle *RepositoryBundle `json:"bundle,omitempty"`
+}
+
+type BundleFormat string
+
+const (
+	BundleFormatTarball   BundleFormat = "Tarball"
+	BundleFormatOCILayout BundleFormat = "OCILayout"
+)
+
+// RepositoryBundle describes an offline bundle which contains the repository index, charts and optional image lists.
+// The bundle can be loaded from a path mounted into ks-controller-manager (e.g. a PersistentVolumeClaim),
+// or uploaded through ks-apiserver.
+type RepositoryBundle struct {
+	// Path is the location of the bundle, it can be a tarball or an OCI image layout directory.
+	// If empty, the bundle should be uploaded through ks-apiserver.
+	// +optional
+	Path string `json:"path,omitempty"`
+	// Format of the bundle, it will be detected automatically if empty.
+	// +kubebuilder:validation:Enum=Tarball;OCILayout
+	// +optional
+	Format BundleFormat `json:"format,omitempty"`
+	// PublicKey is a PEM encoded public key used to verify the signature of the bundle index.
+	// Unsigned bundles are rejected if the public key is specified.
+	// +optional
+	PublicKey string `json:"publicKey,omitempty"`
+}
+
+type RepositoryBundleStatus struct {
+	// Digest of the last imported bundle.
+	// +optional
+	Digest string `json:"digest,omitempty"`
+	// +optional
+	ImportTime *metav1.Time `json:"importTime,omitempty"`
+	// Verified indicates whether the signature of the last imported bundle has been verified.
+	// +optional
+	Verified bool `json:"verified,omitempty"`
 }
 
 type RepositoryStatus struct {
 	// +optional
 	LastSyncTime *metav1.Time `json:"lastSyncTime,omitempty'"`
+	// +optional
+	Bundle *RepositoryBundleStatus `json:"bundle,omitempty"`
 }
 
 // +kubebuilder:object:root=true
@@ -335,3 +469,76 @@ type ServiceAccountList struct {
 	metav1.ListMeta `json:"metadata,omitempty"`
 	Items           []ServiceAccount `json:"items"`
 }
+
+// ProtectionOperation is an operation denied by the ProtectionPolicy.
+// +kubebuilder:validation:Enum=Delete;Update;ScaleToZero;Finalize
+type ProtectionOperation string
+
+const (
+	// ProtectionOperationDelete denies the deletion.
+	ProtectionOperationDelete ProtectionOperation = "Delete"
+	// ProtectionOperationUpdate denies the updates of the fields, or any update if no field is specified.
+	ProtectionOperationUpdate ProtectionOperation = "Update"
+	// ProtectionOperationScaleToZero denies scaling the workloads to zero replicas.
+	ProtectionOperationScaleToZero ProtectionOperation = "ScaleToZero"
+	// ProtectionOperationFinalize denies the namespace finalization and the removal of the finalizers.
+	ProtectionOperationFinalize ProtectionOperation = "Finalize"
+)
+
+// ProtectedResource selects the resources by the API group and the kind, "*" matches any kind.
+type ProtectedResource struct {
+	APIGroup string `json:"apiGroup"`
+	Kind     string `json:"kind"`
+}
+
+// ProtectionExemption is the users and the groups allowed to override the protection with the break-glass annotation.
+type ProtectionExemption struct {
+	// +optional
+	Users []string `json:"users,omitempty"`
+	// +optional
+	Groups []string `json:"groups,omitempty"`
+}
+
+type ProtectionPolicySpec struct {
+	// Resources is the protected resources.
+	Resources []ProtectedResource `json:"resources"`
+	// NamespaceSelector selects the namespaces of the protected resources, a namespace is selected by its own labels.
+	// +optional
+	NamespaceSelector *metav1.LabelSelector `json:"namespaceSelector,omitempty"`
+	// WorkspaceSelector selects the workspaces of the namespaces of the protected resources.
+	// +optional
+	WorkspaceSelector *metav1.LabelSelector `json:"workspaceSelector,omitempty"`
+	// ObjectSelector selects the protected resources by labels.
+	// +optional
+	ObjectSelector *metav1.LabelSelector `json:"objectSelector,omitempty"`
+	// Operations is the denied operations.
+	// +kubebuilder:validation:MinItems=1
+	Operations []ProtectionOperation `json:"operations"`
+	// Fields is the protected field paths for the Update operation, e.g. spec.replicas.
+	// +optional
+	Fields []string `json:"fields,omitempty"`
+	// Exemption allows the users and the groups to override the protection,
+	// the break-glass annotation with the reason is required on the object.
+	// +optional
+	Exemption ProtectionExemption `json:"exemption,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:resource:scope="Cluster"
+
+// ProtectionPolicy protects the selected resources from the operations, the denials and the overrides are recorded
+// as events of the policy.
+type ProtectionPolicy struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec ProtectionPolicySpec `json:"spec"`
+}
+
+// +kubebuilder:object:root=true
+
+type ProtectionPolicyList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []ProtectionPolicy `json:"items"`
+}
@@ -41,6 +41,9 @@ const (
 	ResourceKindRole                      = "Role"
 	ResourcesSingularRole                 = "role"
 	ResourcesPluralRole                   = "roles"
+	ResourceKindAccessRequest             = "AccessRequest"
+	ResourcesSingularAccessRequest        = "accessrequest"
+	ResourcesPluralAccessRequest          = "accessrequests"
 	ResourcesKindRoleTemplate             = "RoleTemplate"
 	ResourcesSingularRoleTemplate         = "roletemplate"
 	ResourcesPluralRoleTemplate           = "roletemplates"
@@ -55,12 +58,16 @@ const (
 	GrantedClustersAnnotation             = "iam.kubesphere.io/granted-clusters"
 	UninitializedAnnotation               = "iam.kubesphere.io/uninitialized"
 	LastPasswordChangeTimeAnnotation      = "iam.kubesphere.io/last-password-change-time"
+	ExpirationTimeAnnotation              = "iam.kubesphere.io/expiration-time"
+	ExpirationWarningAnnotation           = "iam.kubesphere.io/expiration-warning"
+	GlobalRoleExpirationTimeAnnotation    = "iam.kubesphere.io/globalrole-expiration-time"
 	RoleAnnotation                        = "iam.kubesphere.io/role"
 	RoleTemplateLabel                     = "iam.kubesphere.io/role-template"
 	ScopeLabel                            = "iam.kubesphere.io/scope"
 	UserReferenceLabel                    = "iam.kubesphere.io/user-ref"
 	RoleReferenceLabel                    = "iam.kubesphere.io/role-ref"
 	IdentityProviderAnnotation            = "iam.kubesphere.io/identity-provider"
+	AccessRequestReferenceLabel           = "iam.kubesphere.io/accessrequest-ref"
 	ServiceAccountReferenceLabel          = "iam.kubesphere.io/serviceaccount-ref"
 	FieldEmail                            = "email"
 	ExtraEmail                            = FieldEmail
@@ -69,6 +76,7 @@ const (
 	ExtraUsername                         = "username"
 	ExtraDisplayName                      = "displayName"
 	ExtraUninitialized                    = "uninitialized"
+	ExtraPasswordChangeRequired           = "passwordChangeRequired"
 	InGroup                               = "ingroup"
 	NotInGroup                            = "notingroup"
 	AggregateTo                           = "aggregateTo"
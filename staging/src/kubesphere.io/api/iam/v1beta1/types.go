@@ -71,24 +71,9 @@ type UserSpec struct {
 	// +optional
 	Groups []string `json:"groups,omitempty"`
 
-	// password will be encrypted by mutating admission webhook
-	// +kubebuilder:validation:MinLength=8
+	// password will be encrypted by the user controller, the plain text password is validated
+	// against the password policy by the validating admission webhook.
 	// +kubebuilder:validation:MaxLength=64
-	// +kubebuilder:validation:Pattern=`^(.*[a-z].*[A-Z].*[0-9].*)$|^(.*[a-z].*[0-9].*[A-Z].*)$|^(.*[A-Z].*[a-z].*[0-9].*)$|^(.*[A-Z].*[0-9].*[a-z].*)$|^(.*[0-9].*[a-z].*[A-Z].*)$|^(.*[0-9].*[A-Z].*[a-z].*)$|^(\$2[ayb]\$.{56})$`
-	// Password pattern is tricky here.
-	// The rule is simple: length between [6,64], at least one uppercase letter, one lowercase letter, one digit.
-	// The regexp in console(javascript) is quite straightforward: ^(?=.*[a-z])(?=.*[A-Z])(?=.*\d)[^]{6,64}$
-	// But in Go, we don't have ?= (back tracking) capability in regexp (also in CRD validation pattern)
-	// So we adopted an alternative scheme to achieve.
-	// Use 6 different regexp to combine to achieve the same effect.
-	// These six schemes enumerate the arrangement of numbers, uppercase letters, and lowercase letters that appear for the first time.
-	// - ^(.*[a-z].*[A-Z].*[0-9].*)$ stands for lowercase letter comes first, then followed by an uppercase letter, then a digit.
-	// - ^(.*[a-z].*[0-9].*[A-Z].*)$ stands for lowercase letter comes first, then followed by a digit, then an uppercase leeter.
-	// - ^(.*[A-Z].*[a-z].*[0-9].*)$ ...
-	// - ^(.*[A-Z].*[0-9].*[a-z].*)$ ...
-	// - ^(.*[0-9].*[a-z].*[A-Z].*)$ ...
-	// - ^(.*[0-9].*[A-Z].*[a-z].*)$ ...
-	// Last but not least, the bcrypt string is also included to match the encrypted password. ^(\$2[ayb]\$.{56})$
 	EncryptedPassword string `json:"password,omitempty"`
 }
 
@@ -118,6 +103,19 @@ type UserStatus struct {
 	// Last login attempt timestamp
 	// +optional
 	LastLoginTime *metav1.Time `json:"lastLoginTime,omitempty"`
+	// The time when the password was changed last time.
+	// +optional
+	LastPasswordChangeTime *metav1.Time `json:"lastPasswordChangeTime,omitempty"`
+	// The time when the password expires according to the maximum age of the password policy.
+	// +optional
+	PasswordExpirationTime *metav1.Time `json:"passwordExpirationTime,omitempty"`
+	// The password must be changed at the next login, e.g. it has expired.
+	// +optional
+	PasswordChangeRequired bool `json:"passwordChangeRequired,omitempty"`
+	// The encrypted recent passwords from the latest one, they can't be reused according to the
+	// history depth of the password policy.
+	// +optional
+	PasswordHistory []string `json:"passwordHistory,omitempty"`
 }
 
 // +kubebuilder:object:root=true
@@ -672,3 +670,91 @@ type RoleTemplateList struct {
 	metav1.ListMeta `json:"metadata,omitempty"`
 	Items           []RoleTemplate `json:"items"`
 }
+
+// AccessRequestType is the type of the privileged access.
+type AccessRequestType string
+
+const (
+	// AccessRequestTypePodExec grants the access to exec into a pod.
+	AccessRequestTypePodExec AccessRequestType = "PodExec"
+	// AccessRequestTypeNodeShell grants the access to open a shell on a node.
+	AccessRequestTypeNodeShell AccessRequestType = "NodeShell"
+)
+
+type AccessRequestPhase string
+
+const (
+	AccessRequestPending  AccessRequestPhase = "Pending"
+	AccessRequestApproved AccessRequestPhase = "Approved"
+	AccessRequestDenied   AccessRequestPhase = "Denied"
+	AccessRequestExpired  AccessRequestPhase = "Expired"
+)
+
+// AccessRequestSpec defines the privileged access requested by a user.
+type AccessRequestSpec struct {
+	// User is the name of the user who requested the access.
+	User string `json:"user"`
+	// +kubebuilder:validation:Enum=PodExec;NodeShell
+	Type AccessRequestType `json:"type"`
+	// Namespace of the pod, required by PodExec.
+	// +optional
+	Namespace string `json:"namespace,omitempty"`
+	// Pod is the name of the pod, required by PodExec.
+	// +optional
+	Pod string `json:"pod,omitempty"`
+	// Node is the name of the node, required by NodeShell.
+	// +optional
+	Node string `json:"node,omitempty"`
+	// Reason explains why the access is required.
+	Reason string `json:"reason"`
+	// Duration is how long the access is granted after the approval.
+	Duration metav1.Duration `json:"duration"`
+}
+
+// AccessRequestStatus defines the approval of the access request.
+type AccessRequestStatus struct {
+	// +optional
+	Phase AccessRequestPhase `json:"phase,omitempty"`
+	// Approver is the name of the user who approved or denied the request.
+	// +optional
+	Approver string `json:"approver,omitempty"`
+	// Comment of the approver.
+	// +optional
+	Comment string `json:"comment,omitempty"`
+	// DecisionTime is the time when the request was approved or denied.
+	// +optional
+	DecisionTime *metav1.Time `json:"decisionTime,omitempty"`
+	// ExpirationTime is the time when the granted access expires.
+	// +optional
+	ExpirationTime *metav1.Time `json:"expirationTime,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="User",type="string",JSONPath=".spec.user"
+// +kubebuilder:printcolumn:name="Type",type="string",JSONPath=".spec.type"
+// +kubebuilder:printcolumn:name="Phase",type="string",JSONPath=".status.phase"
+// +kubebuilder:printcolumn:name="Expiration",type="date",JSONPath=".status.expirationTime"
+// +kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
+// +kubebuilder:resource:categories=iam,scope=Cluster
+// +kubebuilder:storageversion
+
+// AccessRequest is a just-in-time request for the privileged access to a pod or a node, a temporary
+// role binding is created once it is approved and deleted when it expires.
+type AccessRequest struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   AccessRequestSpec   `json:"spec"`
+	Status AccessRequestStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:resource:categories=iam,scope=Cluster
+
+// AccessRequestList contains a list of AccessRequest
+type AccessRequestList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []AccessRequest `json:"items"`
+}
@@ -79,3 +79,118 @@ type ResourceQuotaList struct {
 	// More info: https://kubernetes.io/docs/concepts/policy/resource-quotas/
 	Items []ResourceQuota `json:"items" protobuf:"bytes,2,rep,name=items"`
 }
+
+const (
+	ResourceKindWorkspaceQuota      = "WorkspaceQuota"
+	ResourcesSingularWorkspaceQuota = "workspacequota"
+	ResourcesPluralWorkspaceQuota   = "workspacequotas"
+
+	// WorkspaceQuotaLabel is the label of the resource quotas allotted by the workspace quota in the member clusters.
+	WorkspaceQuotaLabel = "quota.kubesphere.io/workspace-quota"
+	// WorkspaceQuotaFinalizer makes sure the allotted resource quotas are removed from the member clusters.
+	WorkspaceQuotaFinalizer = "finalizers.quota.kubesphere.io/workspace-quota"
+
+	WorkspaceQuotaConditionSynced   = "Synced"
+	WorkspaceQuotaConditionExceeded = "Exceeded"
+)
+
+// AllocationPolicy defines how the capacity of the workspace quota is allotted to the member clusters.
+// +kubebuilder:validation:Enum=Divide;Borrow
+type AllocationPolicy string
+
+const (
+	// AllocationPolicyDivide divides the capacity among the clusters by weight, the allotments are static.
+	AllocationPolicyDivide AllocationPolicy = "Divide"
+	// AllocationPolicyBorrow allots each cluster its current usage plus a weighted share of the unused capacity,
+	// so a cluster can borrow the capacity which is not used by the other clusters.
+	AllocationPolicyBorrow AllocationPolicy = "Borrow"
+)
+
+// +kubebuilder:object:root=true
+// +kubebuilder:resource:categories="quota",scope="Cluster",path=workspacequotas
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="Workspace",type="string",JSONPath=".spec.workspace"
+// +kubebuilder:printcolumn:name="Policy",type="string",JSONPath=".spec.policy"
+// +kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
+
+// WorkspaceQuota sets the aggregate quota of a workspace across all the clusters where the workspace is placed.
+// It only takes effect in the host cluster, the capacity is allotted to the member clusters as resource quotas.
+type WorkspaceQuota struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec WorkspaceQuotaSpec `json:"spec"`
+	// +optional
+	Status WorkspaceQuotaStatus `json:"status,omitempty"`
+}
+
+// WorkspaceQuotaSpec defines the capacity purchased by the workspace
+type WorkspaceQuotaSpec struct {
+	// Workspace is the name of the workspace
+	Workspace string `json:"workspace"`
+
+	// Hard is the total capacity of the workspace across all clusters
+	Hard corev1.ResourceList `json:"hard"`
+
+	// Policy defines how the capacity is allotted to the clusters, defaults to Divide.
+	// +optional
+	Policy AllocationPolicy `json:"policy,omitempty"`
+
+	// Clusters overrides the weight of the clusters, the weight of the clusters not listed is 1.
+	// +optional
+	// +listType=map
+	// +listMapKey=name
+	Clusters []ClusterAllotment `json:"clusters,omitempty"`
+}
+
+// ClusterAllotment defines the share of a cluster
+type ClusterAllotment struct {
+	// Name of the cluster
+	Name string `json:"name"`
+
+	// Weight of the cluster, a cluster with weight 0 gets no capacity.
+	// +kubebuilder:validation:Minimum=0
+	Weight int32 `json:"weight"`
+}
+
+// WorkspaceQuotaStatus defines the allotments and the aggregated usage
+type WorkspaceQuotaStatus struct {
+	// Used is the usage aggregated from all clusters
+	// +optional
+	Used corev1.ResourceList `json:"used,omitempty"`
+
+	// Clusters is the allotment and the usage of each cluster
+	// +optional
+	// +listType=map
+	// +listMapKey=name
+	Clusters []ClusterQuotaStatus `json:"clusters,omitempty"`
+
+	// +optional
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+}
+
+// ClusterQuotaStatus is the allotment and the usage of a cluster
+type ClusterQuotaStatus struct {
+	// Name of the cluster
+	Name string `json:"name"`
+
+	// Ready is false if the usage can not be collected from the cluster, the last observed values are kept.
+	Ready bool `json:"ready"`
+
+	// Hard is the capacity allotted to the cluster
+	// +optional
+	Hard corev1.ResourceList `json:"hard,omitempty"`
+
+	// Used is the usage in the cluster
+	// +optional
+	Used corev1.ResourceList `json:"used,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// WorkspaceQuotaList is a list of WorkspaceQuota items.
+type WorkspaceQuotaList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []WorkspaceQuota `json:"items"`
+}
@@ -69,15 +69,27 @@ func GetOpenAPIDefinitions(ref common.ReferenceCallback) map[string]common.OpenA
 		"k8s.io/apimachinery/pkg/apis/meta/v1.TypeMeta":                  schema_pkg_apis_meta_v1_TypeMeta(ref),
 		"k8s.io/apimachinery/pkg/apis/meta/v1.UpdateOptions":             schema_pkg_apis_meta_v1_UpdateOptions(ref),
 		"k8s.io/apimachinery/pkg/apis/meta/v1.WatchEvent":                schema_pkg_apis_meta_v1_WatchEvent(ref),
+		"kubesphere.io/api/tenant/v1beta1.BlueprintNamespace":            schema_kubesphereio_api_tenant_v1beta1_BlueprintNamespace(ref),
+		"kubesphere.io/api/tenant/v1beta1.BlueprintNetworkPolicy":        schema_kubesphereio_api_tenant_v1beta1_BlueprintNetworkPolicy(ref),
+		"kubesphere.io/api/tenant/v1beta1.BlueprintRole":                 schema_kubesphereio_api_tenant_v1beta1_BlueprintRole(ref),
 		"kubesphere.io/api/tenant/v1beta1.GenericPlacement":              schema_kubesphereio_api_tenant_v1beta1_GenericPlacement(ref),
 		"kubesphere.io/api/tenant/v1beta1.Template":                      schema_kubesphereio_api_tenant_v1beta1_Template(ref),
+		"kubesphere.io/api/tenant/v1beta1.TerminalRecordingPolicy":       schema_kubesphereio_api_tenant_v1beta1_TerminalRecordingPolicy(ref),
 		"kubesphere.io/api/tenant/v1beta1.Workspace":                     schema_kubesphereio_api_tenant_v1beta1_Workspace(ref),
+		"kubesphere.io/api/tenant/v1beta1.WorkspaceArchive":              schema_kubesphereio_api_tenant_v1beta1_WorkspaceArchive(ref),
+		"kubesphere.io/api/tenant/v1beta1.WorkspaceBlueprint":            schema_kubesphereio_api_tenant_v1beta1_WorkspaceBlueprint(ref),
+		"kubesphere.io/api/tenant/v1beta1.WorkspaceBlueprintList":        schema_kubesphereio_api_tenant_v1beta1_WorkspaceBlueprintList(ref),
+		"kubesphere.io/api/tenant/v1beta1.WorkspaceBlueprintReference":   schema_kubesphereio_api_tenant_v1beta1_WorkspaceBlueprintReference(ref),
+		"kubesphere.io/api/tenant/v1beta1.WorkspaceBlueprintSpec":        schema_kubesphereio_api_tenant_v1beta1_WorkspaceBlueprintSpec(ref),
+		"kubesphere.io/api/tenant/v1beta1.WorkspaceClusterStatus":        schema_kubesphereio_api_tenant_v1beta1_WorkspaceClusterStatus(ref),
+		"kubesphere.io/api/tenant/v1beta1.WorkspaceLifecycle":            schema_kubesphereio_api_tenant_v1beta1_WorkspaceLifecycle(ref),
 		"kubesphere.io/api/tenant/v1beta1.WorkspaceList":                 schema_kubesphereio_api_tenant_v1beta1_WorkspaceList(ref),
 		"kubesphere.io/api/tenant/v1beta1.WorkspaceSpec":                 schema_kubesphereio_api_tenant_v1beta1_WorkspaceSpec(ref),
 		"kubesphere.io/api/tenant/v1beta1.WorkspaceStatus":               schema_kubesphereio_api_tenant_v1beta1_WorkspaceStatus(ref),
 		"kubesphere.io/api/tenant/v1beta1.WorkspaceTemplate":             schema_kubesphereio_api_tenant_v1beta1_WorkspaceTemplate(ref),
 		"kubesphere.io/api/tenant/v1beta1.WorkspaceTemplateList":         schema_kubesphereio_api_tenant_v1beta1_WorkspaceTemplateList(ref),
 		"kubesphere.io/api/tenant/v1beta1.WorkspaceTemplateSpec":         schema_kubesphereio_api_tenant_v1beta1_WorkspaceTemplateSpec(ref),
+		"kubesphere.io/api/tenant/v1beta1.WorkspaceTemplateStatus":       schema_kubesphereio_api_tenant_v1beta1_WorkspaceTemplateStatus(ref),
 	}
 }
 
@@ -2345,13 +2357,424 @@ func schema_pkg_apis_meta_v1_WatchEvent(ref common.ReferenceCallback) common.Ope
 	}
 }
 
+func schema_kubesphereio_api_tenant_v1beta1_BlueprintNamespace(ref common.ReferenceCallback) common.OpenAPIDefinition {
+	return common.OpenAPIDefinition{
+		Schema: spec.Schema{
+			SchemaProps: spec.SchemaProps{
+				Type: []string{"object"},
+				Properties: map[string]spec.Schema{
+					"name": {
+						SchemaProps: spec.SchemaProps{
+							Default: "",
+							Type:    []string{"string"},
+							Format:  "",
+						},
+					},
+					"labels": {
+						SchemaProps: spec.SchemaProps{
+							Type: []string{"object"},
+							AdditionalProperties: &spec.SchemaOrBool{
+								Allows: true,
+								Schema: &spec.Schema{
+									SchemaProps: spec.SchemaProps{
+										Default: "",
+										Type:    []string{"string"},
+										Format:  "",
+									},
+								},
+							},
+						},
+					},
+					"annotations": {
+						SchemaProps: spec.SchemaProps{
+							Type: []string{"object"},
+							AdditionalProperties: &spec.SchemaOrBool{
+								Allows: true,
+								Schema: &spec.Schema{
+									SchemaProps: spec.SchemaProps{
+										Default: "",
+										Type:    []string{"string"},
+										Format:  "",
+									},
+								},
+							},
+						},
+					},
+				},
+				Required: []string{"name"},
+			},
+		},
+	}
+}
+
+func schema_kubesphereio_api_tenant_v1beta1_BlueprintNetworkPolicy(ref common.ReferenceCallback) common.OpenAPIDefinition {
+	return common.OpenAPIDefinition{
+		Schema: spec.Schema{
+			SchemaProps: spec.SchemaProps{
+				Type: []string{"object"},
+				Properties: map[string]spec.Schema{
+					"name": {
+						SchemaProps: spec.SchemaProps{
+							Default: "",
+							Type:    []string{"string"},
+							Format:  "",
+						},
+					},
+					"spec": {
+						SchemaProps: spec.SchemaProps{
+							Default: map[string]interface{}{},
+							Ref:     ref("k8s.io/api/networking/v1.NetworkPolicySpec"),
+						},
+					},
+				},
+				Required: []string{"name", "spec"},
+			},
+		},
+		Dependencies: []string{
+			"k8s.io/api/networking/v1.NetworkPolicySpec"},
+	}
+}
+
+func schema_kubesphereio_api_tenant_v1beta1_BlueprintRole(ref common.ReferenceCallback) common.OpenAPIDefinition {
+	return common.OpenAPIDefinition{
+		Schema: spec.Schema{
+			SchemaProps: spec.SchemaProps{
+				Type: []string{"object"},
+				Properties: map[string]spec.Schema{
+					"name": {
+						SchemaProps: spec.SchemaProps{
+							Default: "",
+							Type:    []string{"string"},
+							Format:  "",
+						},
+					},
+					"templateNames": {
+						SchemaProps: spec.SchemaProps{
+							Description: "TemplateNames are the role templates aggregated into the role.",
+							Type:        []string{"array"},
+							Items: &spec.SchemaOrArray{
+								Schema: &spec.Schema{
+									SchemaProps: spec.SchemaProps{
+										Default: "",
+										Type:    []string{"string"},
+										Format:  "",
+									},
+								},
+							},
+						},
+					},
+					"rules": {
+						SchemaProps: spec.SchemaProps{
+							Type: []string{"array"},
+							Items: &spec.SchemaOrArray{
+								Schema: &spec.Schema{
+									SchemaProps: spec.SchemaProps{
+										Default: map[string]interface{}{},
+										Ref:     ref("k8s.io/api/rbac/v1.PolicyRule"),
+									},
+								},
+							},
+						},
+					},
+				},
+				Required: []string{"name"},
+			},
+		},
+		Dependencies: []string{
+			"k8s.io/api/rbac/v1.PolicyRule"},
+	}
+}
+
 func schema_kubesphereio_api_tenant_v1beta1_GenericPlacement(ref common.ReferenceCallback) common.OpenAPIDefinition {
 	return common.OpenAPIDefinition{
 		Schema: spec.Schema{
 			SchemaProps: spec.SchemaProps{
 				Type: []string{"object"},
 				Properties: map[string]spec.Schema{
-					"clusters": {
+					"clusters": {
+						VendorExtensible: spec.VendorExtensible{
+							Extensions: spec.Extensions{
+								"x-kubernetes-list-map-keys": []interface{}{
+									"name",
+								},
+								"x-kubernetes-list-type": "map",
+							},
+						},
+						SchemaProps: spec.SchemaProps{
+							Type: []string{"array"},
+							Items: &spec.SchemaOrArray{
+								Schema: &spec.Schema{
+									SchemaProps: spec.SchemaProps{
+										Default: map[string]interface{}{},
+										Ref:     ref("kubesphere.io/api/tenant/v1beta1.GenericClusterReference"),
+									},
+								},
+							},
+						},
+					},
+					"clusterSelector": {
+						SchemaProps: spec.SchemaProps{
+							Ref: ref("k8s.io/apimachinery/pkg/apis/meta/v1.LabelSelector"),
+						},
+					},
+				},
+			},
+		},
+		Dependencies: []string{
+			"k8s.io/apimachinery/pkg/apis/meta/v1.LabelSelector", "kubesphere.io/api/tenant/v1beta1.GenericClusterReference"},
+	}
+}
+
+func schema_kubesphereio_api_tenant_v1beta1_Template(ref common.ReferenceCallback) common.OpenAPIDefinition {
+	return common.OpenAPIDefinition{
+		Schema: spec.Schema{
+			SchemaProps: spec.SchemaProps{
+				Type: []string{"object"},
+				Properties: map[string]spec.Schema{
+					"metadata": {
+						SchemaProps: spec.SchemaProps{
+							Default: map[string]interface{}{},
+							Ref:     ref("kubesphere.io/api/tenant/v1beta1.ObjectMeta"),
+						},
+					},
+					"spec": {
+						SchemaProps: spec.SchemaProps{
+							Default: map[string]interface{}{},
+							Ref:     ref("kubesphere.io/api/tenant/v1beta1.WorkspaceSpec"),
+						},
+					},
+				},
+			},
+		},
+		Dependencies: []string{
+			"kubesphere.io/api/tenant/v1beta1.ObjectMeta", "kubesphere.io/api/tenant/v1beta1.WorkspaceSpec"},
+	}
+}
+
+func schema_kubesphereio_api_tenant_v1beta1_TerminalRecordingPolicy(ref common.ReferenceCallback) common.OpenAPIDefinition {
+	return common.OpenAPIDefinition{
+		Schema: spec.Schema{
+			SchemaProps: spec.SchemaProps{
+				Description: "TerminalRecordingPolicy defines the retention policy of the terminal session recordings.",
+				Type:        []string{"object"},
+				Properties: map[string]spec.Schema{
+					"retentionPeriod": {
+						SchemaProps: spec.SchemaProps{
+							Description: "RetentionPeriod is how long the recordings are kept after the sessions ended, the recordings are kept forever if it is not set.",
+							Ref:         ref("k8s.io/apimachinery/pkg/apis/meta/v1.Duration"),
+						},
+					},
+				},
+			},
+		},
+		Dependencies: []string{
+			"k8s.io/apimachinery/pkg/apis/meta/v1.Duration"},
+	}
+}
+
+func schema_kubesphereio_api_tenant_v1beta1_Workspace(ref common.ReferenceCallback) common.OpenAPIDefinition {
+	return common.OpenAPIDefinition{
+		Schema: spec.Schema{
+			SchemaProps: spec.SchemaProps{
+				Description: "Workspace is the Schema for the workspaces API",
+				Type:        []string{"object"},
+				Properties: map[string]spec.Schema{
+					"kind": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Kind is a string value representing the REST resource this object represents. Servers may infer this from the endpoint the client submits requests to. Cannot be updated. In CamelCase. More info: https://git.k8s.io/community/contributors/devel/sig-architecture/api-conventions.md#types-kinds",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+					"apiVersion": {
+						SchemaProps: spec.SchemaProps{
+							Description: "APIVersion defines the versioned schema of this representation of an object. Servers should convert recognized schemas to the latest internal value, and may reject unrecognized values. More info: https://git.k8s.io/community/contributors/devel/sig-architecture/api-conventions.md#resources",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+					"metadata": {
+						SchemaProps: spec.SchemaProps{
+							Default: map[string]interface{}{},
+							Ref:     ref("k8s.io/apimachinery/pkg/apis/meta/v1.ObjectMeta"),
+						},
+					},
+					"spec": {
+						SchemaProps: spec.SchemaProps{
+							Default: map[string]interface{}{},
+							Ref:     ref("kubesphere.io/api/tenant/v1beta1.WorkspaceSpec"),
+						},
+					},
+					"status": {
+						SchemaProps: spec.SchemaProps{
+							Default: map[string]interface{}{},
+							Ref:     ref("kubesphere.io/api/tenant/v1beta1.WorkspaceStatus"),
+						},
+					},
+				},
+			},
+		},
+		Dependencies: []string{
+			"k8s.io/apimachinery/pkg/apis/meta/v1.ObjectMeta", "kubesphere.io/api/tenant/v1beta1.WorkspaceSpec", "kubesphere.io/api/tenant/v1beta1.WorkspaceStatus"},
+	}
+}
+
+func schema_kubesphereio_api_tenant_v1beta1_WorkspaceArchive(ref common.ReferenceCallback) common.OpenAPIDefinition {
+	return common.OpenAPIDefinition{
+		Schema: spec.Schema{
+			SchemaProps: spec.SchemaProps{
+				Type: []string{"object"},
+				Properties: map[string]spec.Schema{
+					"location": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Location is the key of the exported manifests in the object storage.",
+							Default:     "",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+					"archiveTime": {
+						SchemaProps: spec.SchemaProps{
+							Description: "ArchiveTime is the time when the manifests were exported.",
+							Ref:         ref("k8s.io/apimachinery/pkg/apis/meta/v1.Time"),
+						},
+					},
+				},
+				Required: []string{"location", "archiveTime"},
+			},
+		},
+		Dependencies: []string{
+			"k8s.io/apimachinery/pkg/apis/meta/v1.Time"},
+	}
+}
+
+func schema_kubesphereio_api_tenant_v1beta1_WorkspaceBlueprint(ref common.ReferenceCallback) common.OpenAPIDefinition {
+	return common.OpenAPIDefinition{
+		Schema: spec.Schema{
+			SchemaProps: spec.SchemaProps{
+				Description: "WorkspaceBlueprint is a reusable set of namespaces, quota, network policies and roles applied to workspaces",
+				Type:        []string{"object"},
+				Properties: map[string]spec.Schema{
+					"kind": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Kind is a string value representing the REST resource this object represents. Servers may infer this from the endpoint the client submits requests to. Cannot be updated. In CamelCase. More info: https://git.k8s.io/community/contributors/devel/sig-architecture/api-conventions.md#types-kinds",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+					"apiVersion": {
+						SchemaProps: spec.SchemaProps{
+							Description: "APIVersion defines the versioned schema of this representation of an object. Servers should convert recognized schemas to the latest internal value, and may reject unrecognized values. More info: https://git.k8s.io/community/contributors/devel/sig-architecture/api-conventions.md#resources",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+					"metadata": {
+						SchemaProps: spec.SchemaProps{
+							Default: map[string]interface{}{},
+							Ref:     ref("k8s.io/apimachinery/pkg/apis/meta/v1.ObjectMeta"),
+						},
+					},
+					"spec": {
+						SchemaProps: spec.SchemaProps{
+							Default: map[string]interface{}{},
+							Ref:     ref("kubesphere.io/api/tenant/v1beta1.WorkspaceBlueprintSpec"),
+						},
+					},
+				},
+			},
+		},
+		Dependencies: []string{
+			"k8s.io/apimachinery/pkg/apis/meta/v1.ObjectMeta", "kubesphere.io/api/tenant/v1beta1.WorkspaceBlueprintSpec"},
+	}
+}
+
+func schema_kubesphereio_api_tenant_v1beta1_WorkspaceBlueprintList(ref common.ReferenceCallback) common.OpenAPIDefinition {
+	return common.OpenAPIDefinition{
+		Schema: spec.Schema{
+			SchemaProps: spec.SchemaProps{
+				Description: "WorkspaceBlueprintList contains a list of WorkspaceBlueprint",
+				Type:        []string{"object"},
+				Properties: map[string]spec.Schema{
+					"kind": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Kind is a string value representing the REST resource this object represents. Servers may infer this from the endpoint the client submits requests to. Cannot be updated. In CamelCase. More info: https://git.k8s.io/community/contributors/devel/sig-architecture/api-conventions.md#types-kinds",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+					"apiVersion": {
+						SchemaProps: spec.SchemaProps{
+							Description: "APIVersion defines the versioned schema of this representation of an object. Servers should convert recognized schemas to the latest internal value, and may reject unrecognized values. More info: https://git.k8s.io/community/contributors/devel/sig-architecture/api-conventions.md#resources",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+					"metadata": {
+						SchemaProps: spec.SchemaProps{
+							Default: map[string]interface{}{},
+							Ref:     ref("k8s.io/apimachinery/pkg/apis/meta/v1.ListMeta"),
+						},
+					},
+					"items": {
+						SchemaProps: spec.SchemaProps{
+							Type: []string{"array"},
+							Items: &spec.SchemaOrArray{
+								Schema: &spec.Schema{
+									SchemaProps: spec.SchemaProps{
+										Default: map[string]interface{}{},
+										Ref:     ref("kubesphere.io/api/tenant/v1beta1.WorkspaceBlueprint"),
+									},
+								},
+							},
+						},
+					},
+				},
+				Required: []string{"items"},
+			},
+		},
+		Dependencies: []string{
+			"k8s.io/apimachinery/pkg/apis/meta/v1.ListMeta", "kubesphere.io/api/tenant/v1beta1.WorkspaceBlueprint"},
+	}
+}
+
+func schema_kubesphereio_api_tenant_v1beta1_WorkspaceBlueprintReference(ref common.ReferenceCallback) common.OpenAPIDefinition {
+	return common.OpenAPIDefinition{
+		Schema: spec.Schema{
+			SchemaProps: spec.SchemaProps{
+				Type: []string{"object"},
+				Properties: map[string]spec.Schema{
+					"name": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Name of the workspace blueprint",
+							Default:     "",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+					"overrides": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Overrides customizes the blueprint for the workspace. The namespaces, network policies and roles are merged by name, the other fields replace the values of the blueprint if they are set.",
+							Ref:         ref("kubesphere.io/api/tenant/v1beta1.WorkspaceBlueprintSpec"),
+						},
+					},
+				},
+				Required: []string{"name"},
+			},
+		},
+		Dependencies: []string{
+			"kubesphere.io/api/tenant/v1beta1.WorkspaceBlueprintSpec"},
+	}
+}
+
+func schema_kubesphereio_api_tenant_v1beta1_WorkspaceBlueprintSpec(ref common.ReferenceCallback) common.OpenAPIDefinition {
+	return common.OpenAPIDefinition{
+		Schema: spec.Schema{
+			SchemaProps: spec.SchemaProps{
+				Description: "WorkspaceBlueprintSpec defines the resources created for the workspaces.",
+				Type:        []string{"object"},
+				Properties: map[string]spec.Schema{
+					"namespaces": {
 						VendorExtensible: spec.VendorExtensible{
 							Extensions: spec.Extensions{
 								"x-kubernetes-list-map-keys": []interface{}{
@@ -2361,100 +2784,169 @@ func schema_kubesphereio_api_tenant_v1beta1_GenericPlacement(ref common.Referenc
 							},
 						},
 						SchemaProps: spec.SchemaProps{
-							Type: []string{"array"},
+							Description: "Namespaces are created in every placement cluster, the name of the namespace is prefixed with the workspace name.",
+							Type:        []string{"array"},
 							Items: &spec.SchemaOrArray{
 								Schema: &spec.Schema{
 									SchemaProps: spec.SchemaProps{
 										Default: map[string]interface{}{},
-										Ref:     ref("kubesphere.io/api/tenant/v1beta1.GenericClusterReference"),
+										Ref:     ref("kubesphere.io/api/tenant/v1beta1.BlueprintNamespace"),
 									},
 								},
 							},
 						},
 					},
-					"clusterSelector": {
+					"quota": {
 						SchemaProps: spec.SchemaProps{
-							Ref: ref("k8s.io/apimachinery/pkg/apis/meta/v1.LabelSelector"),
+							Description: "Quota is the resource quota of the workspace in every placement cluster.",
+							Ref:         ref("k8s.io/api/core/v1.ResourceQuotaSpec"),
+						},
+					},
+					"limitRange": {
+						SchemaProps: spec.SchemaProps{
+							Description: "LimitRange is applied to every namespace of the workspace.",
+							Ref:         ref("k8s.io/api/core/v1.LimitRangeSpec"),
+						},
+					},
+					"networkIsolation": {
+						SchemaProps: spec.SchemaProps{
+							Description: "NetworkIsolation only allows the ingress traffic from the namespaces of the same workspace.",
+							Type:        []string{"boolean"},
+							Format:      "",
+						},
+					},
+					"networkPolicies": {
+						VendorExtensible: spec.VendorExtensible{
+							Extensions: spec.Extensions{
+								"x-kubernetes-list-map-keys": []interface{}{
+									"name",
+								},
+								"x-kubernetes-list-type": "map",
+							},
+						},
+						SchemaProps: spec.SchemaProps{
+							Description: "NetworkPolicies are applied to every namespace of the workspace.",
+							Type:        []string{"array"},
+							Items: &spec.SchemaOrArray{
+								Schema: &spec.Schema{
+									SchemaProps: spec.SchemaProps{
+										Default: map[string]interface{}{},
+										Ref:     ref("kubesphere.io/api/tenant/v1beta1.BlueprintNetworkPolicy"),
+									},
+								},
+							},
+						},
+					},
+					"roles": {
+						VendorExtensible: spec.VendorExtensible{
+							Extensions: spec.Extensions{
+								"x-kubernetes-list-map-keys": []interface{}{
+									"name",
+								},
+								"x-kubernetes-list-type": "map",
+							},
+						},
+						SchemaProps: spec.SchemaProps{
+							Description: "Roles are the custom workspace roles.",
+							Type:        []string{"array"},
+							Items: &spec.SchemaOrArray{
+								Schema: &spec.Schema{
+									SchemaProps: spec.SchemaProps{
+										Default: map[string]interface{}{},
+										Ref:     ref("kubesphere.io/api/tenant/v1beta1.BlueprintRole"),
+									},
+								},
+							},
 						},
 					},
 				},
 			},
 		},
 		Dependencies: []string{
-			"k8s.io/apimachinery/pkg/apis/meta/v1.LabelSelector", "kubesphere.io/api/tenant/v1beta1.GenericClusterReference"},
+			"k8s.io/api/core/v1.LimitRangeSpec", "k8s.io/api/core/v1.ResourceQuotaSpec", "kubesphere.io/api/tenant/v1beta1.BlueprintNamespace", "kubesphere.io/api/tenant/v1beta1.BlueprintNetworkPolicy", "kubesphere.io/api/tenant/v1beta1.BlueprintRole"},
 	}
 }
 
-func schema_kubesphereio_api_tenant_v1beta1_Template(ref common.ReferenceCallback) common.OpenAPIDefinition {
+func schema_kubesphereio_api_tenant_v1beta1_WorkspaceClusterStatus(ref common.ReferenceCallback) common.OpenAPIDefinition {
 	return common.OpenAPIDefinition{
 		Schema: spec.Schema{
 			SchemaProps: spec.SchemaProps{
 				Type: []string{"object"},
 				Properties: map[string]spec.Schema{
-					"metadata": {
+					"name": {
 						SchemaProps: spec.SchemaProps{
-							Default: map[string]interface{}{},
-							Ref:     ref("kubesphere.io/api/tenant/v1beta1.ObjectMeta"),
+							Default: "",
+							Type:    []string{"string"},
+							Format:  "",
 						},
 					},
-					"spec": {
+					"ready": {
 						SchemaProps: spec.SchemaProps{
-							Default: map[string]interface{}{},
-							Ref:     ref("kubesphere.io/api/tenant/v1beta1.WorkspaceSpec"),
+							Description: "Ready is false if the cluster is not ready, the namespace count is the last observed value.",
+							Default:     false,
+							Type:        []string{"boolean"},
+							Format:      "",
+						},
+					},
+					"namespaces": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Namespaces is the number of namespaces in the workspace.",
+							Default:     0,
+							Type:        []string{"integer"},
+							Format:      "int32",
 						},
 					},
 				},
+				Required: []string{"name", "ready", "namespaces"},
 			},
 		},
-		Dependencies: []string{
-			"kubesphere.io/api/tenant/v1beta1.ObjectMeta", "kubesphere.io/api/tenant/v1beta1.WorkspaceSpec"},
 	}
 }
 
-func schema_kubesphereio_api_tenant_v1beta1_Workspace(ref common.ReferenceCallback) common.OpenAPIDefinition {
+func schema_kubesphereio_api_tenant_v1beta1_WorkspaceLifecycle(ref common.ReferenceCallback) common.OpenAPIDefinition {
 	return common.OpenAPIDefinition{
 		Schema: spec.Schema{
 			SchemaProps: spec.SchemaProps{
-				Description: "Workspace is the Schema for the workspaces API",
+				Description: "WorkspaceLifecycle defines the lifecycle policy of the workspace.",
 				Type:        []string{"object"},
 				Properties: map[string]spec.Schema{
-					"kind": {
+					"state": {
 						SchemaProps: spec.SchemaProps{
-							Description: "Kind is a string value representing the REST resource this object represents. Servers may infer this from the endpoint the client submits requests to. Cannot be updated. In CamelCase. More info: https://git.k8s.io/community/contributors/devel/sig-architecture/api-conventions.md#types-kinds",
+							Description: "State is the desired state of the workspace, defaults to Active.",
 							Type:        []string{"string"},
 							Format:      "",
 						},
 					},
-					"apiVersion": {
+					"expirationTime": {
 						SchemaProps: spec.SchemaProps{
-							Description: "APIVersion defines the versioned schema of this representation of an object. Servers should convert recognized schemas to the latest internal value, and may reject unrecognized values. More info: https://git.k8s.io/community/contributors/devel/sig-architecture/api-conventions.md#resources",
-							Type:        []string{"string"},
-							Format:      "",
+							Description: "ExpirationTime is the time when the workspace expires, it takes precedence over TTL.",
+							Ref:         ref("k8s.io/apimachinery/pkg/apis/meta/v1.Time"),
 						},
 					},
-					"metadata": {
+					"ttl": {
 						SchemaProps: spec.SchemaProps{
-							Default: map[string]interface{}{},
-							Ref:     ref("k8s.io/apimachinery/pkg/apis/meta/v1.ObjectMeta"),
+							Description: "TTL is the time to live of the workspace since it was created.",
+							Ref:         ref("k8s.io/apimachinery/pkg/apis/meta/v1.Duration"),
 						},
 					},
-					"spec": {
+					"expirationAction": {
 						SchemaProps: spec.SchemaProps{
-							Default: map[string]interface{}{},
-							Ref:     ref("kubesphere.io/api/tenant/v1beta1.WorkspaceSpec"),
+							Description: "ExpirationAction is the state of the workspace after it expires, defaults to Suspended.",
+							Type:        []string{"string"},
+							Format:      "",
 						},
 					},
-					"status": {
+					"notifyBefore": {
 						SchemaProps: spec.SchemaProps{
-							Default: map[string]interface{}{},
-							Ref:     ref("kubesphere.io/api/tenant/v1beta1.WorkspaceStatus"),
+							Description: "NotifyBefore is the period before the expiration to notify the manager of the workspace, defaults to 72h.",
+							Ref:         ref("k8s.io/apimachinery/pkg/apis/meta/v1.Duration"),
 						},
 					},
 				},
 			},
 		},
 		Dependencies: []string{
-			"k8s.io/apimachinery/pkg/apis/meta/v1.ObjectMeta", "kubesphere.io/api/tenant/v1beta1.WorkspaceSpec", "kubesphere.io/api/tenant/v1beta1.WorkspaceStatus"},
+			"k8s.io/apimachinery/pkg/apis/meta/v1.Duration", "k8s.io/apimachinery/pkg/apis/meta/v1.Time"},
 	}
 }
 
@@ -2532,8 +3024,90 @@ func schema_kubesphereio_api_tenant_v1beta1_WorkspaceStatus(ref common.Reference
 			SchemaProps: spec.SchemaProps{
 				Description: "WorkspaceStatus defines the observed state of Workspace",
 				Type:        []string{"object"},
+				Properties: map[string]spec.Schema{
+					"clusters": {
+						VendorExtensible: spec.VendorExtensible{
+							Extensions: spec.Extensions{
+								"x-kubernetes-list-map-keys": []interface{}{
+									"name",
+								},
+								"x-kubernetes-list-type": "map",
+							},
+						},
+						SchemaProps: spec.SchemaProps{
+							Description: "Clusters is the status of the workspace in the clusters where it is placed.",
+							Type:        []string{"array"},
+							Items: &spec.SchemaOrArray{
+								Schema: &spec.Schema{
+									SchemaProps: spec.SchemaProps{
+										Default: map[string]interface{}{},
+										Ref:     ref("kubesphere.io/api/tenant/v1beta1.WorkspaceClusterStatus"),
+									},
+								},
+							},
+						},
+					},
+					"members": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Members is the number of members broken out by workspace role.",
+							Type:        []string{"object"},
+							AdditionalProperties: &spec.SchemaOrBool{
+								Allows: true,
+								Schema: &spec.Schema{
+									SchemaProps: spec.SchemaProps{
+										Default: 0,
+										Type:    []string{"integer"},
+										Format:  "int32",
+									},
+								},
+							},
+						},
+					},
+					"quota": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Quota is the aggregated hard limits and usage of the workspace resource quotas across clusters.",
+							Ref:         ref("k8s.io/api/core/v1.ResourceQuotaStatus"),
+						},
+					},
+					"conditions": {
+						VendorExtensible: spec.VendorExtensible{
+							Extensions: spec.Extensions{
+								"x-kubernetes-list-map-keys": []interface{}{
+									"type",
+								},
+								"x-kubernetes-list-type": "map",
+							},
+						},
+						SchemaProps: spec.SchemaProps{
+							Type: []string{"array"},
+							Items: &spec.SchemaOrArray{
+								Schema: &spec.Schema{
+									SchemaProps: spec.SchemaProps{
+										Default: map[string]interface{}{},
+										Ref:     ref("k8s.io/apimachinery/pkg/apis/meta/v1.Condition"),
+									},
+								},
+							},
+						},
+					},
+					"state": {
+						SchemaProps: spec.SchemaProps{
+							Description: "State is the lifecycle state of the workspace enforced in the current cluster.",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+					"lastUpdateTime": {
+						SchemaProps: spec.SchemaProps{
+							Description: "LastUpdateTime is the last time the aggregated status changed.",
+							Ref:         ref("k8s.io/apimachinery/pkg/apis/meta/v1.Time"),
+						},
+					},
+				},
 			},
 		},
+		Dependencies: []string{
+			"k8s.io/api/core/v1.ResourceQuotaStatus", "k8s.io/apimachinery/pkg/apis/meta/v1.Condition", "k8s.io/apimachinery/pkg/apis/meta/v1.Time", "kubesphere.io/api/tenant/v1beta1.WorkspaceClusterStatus"},
 	}
 }
 
@@ -2570,11 +3144,17 @@ func schema_kubesphereio_api_tenant_v1beta1_WorkspaceTemplate(ref common.Referen
 							Ref:     ref("kubesphere.io/api/tenant/v1beta1.WorkspaceTemplateSpec"),
 						},
 					},
+					"status": {
+						SchemaProps: spec.SchemaProps{
+							Default: map[string]interface{}{},
+							Ref:     ref("kubesphere.io/api/tenant/v1beta1.WorkspaceTemplateStatus"),
+						},
+					},
 				},
 			},
 		},
 		Dependencies: []string{
-			"k8s.io/apimachinery/pkg/apis/meta/v1.ObjectMeta", "kubesphere.io/api/tenant/v1beta1.WorkspaceTemplateSpec"},
+			"k8s.io/apimachinery/pkg/apis/meta/v1.ObjectMeta", "kubesphere.io/api/tenant/v1beta1.WorkspaceTemplateSpec", "kubesphere.io/api/tenant/v1beta1.WorkspaceTemplateStatus"},
 	}
 }
 
@@ -2645,11 +3225,83 @@ func schema_kubesphereio_api_tenant_v1beta1_WorkspaceTemplateSpec(ref common.Ref
 							Ref:     ref("kubesphere.io/api/tenant/v1beta1.GenericPlacement"),
 						},
 					},
+					"lifecycle": {
+						SchemaProps: spec.SchemaProps{
+							Ref: ref("kubesphere.io/api/tenant/v1beta1.WorkspaceLifecycle"),
+						},
+					},
+					"blueprint": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Blueprint is applied to the workspace in every placement cluster, the applied resources are kept after the reference is removed.",
+							Ref:         ref("kubesphere.io/api/tenant/v1beta1.WorkspaceBlueprintReference"),
+						},
+					},
+					"terminalRecording": {
+						SchemaProps: spec.SchemaProps{
+							Description: "TerminalRecording is the retention policy of the terminal session recordings of the workspace.",
+							Ref:         ref("kubesphere.io/api/tenant/v1beta1.TerminalRecordingPolicy"),
+						},
+					},
 				},
 				Required: []string{"template", "placement"},
 			},
 		},
 		Dependencies: []string{
-			"kubesphere.io/api/tenant/v1beta1.GenericPlacement", "kubesphere.io/api/tenant/v1beta1.Template"},
+			"kubesphere.io/api/tenant/v1beta1.GenericPlacement", "kubesphere.io/api/tenant/v1beta1.Template", "kubesphere.io/api/tenant/v1beta1.TerminalRecordingPolicy", "kubesphere.io/api/tenant/v1beta1.WorkspaceBlueprintReference", "kubesphere.io/api/tenant/v1beta1.WorkspaceLifecycle"},
+	}
+}
+
+func schema_kubesphereio_api_tenant_v1beta1_WorkspaceTemplateStatus(ref common.ReferenceCallback) common.OpenAPIDefinition {
+	return common.OpenAPIDefinition{
+		Schema: spec.Schema{
+			SchemaProps: spec.SchemaProps{
+				Description: "WorkspaceTemplateStatus defines the observed lifecycle state of the workspace.",
+				Type:        []string{"object"},
+				Properties: map[string]spec.Schema{
+					"state": {
+						SchemaProps: spec.SchemaProps{
+							Description: "State is the lifecycle state applied to the placement clusters.",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+					"expirationTime": {
+						SchemaProps: spec.SchemaProps{
+							Description: "ExpirationTime is the effective expiration time of the workspace.",
+							Ref:         ref("k8s.io/apimachinery/pkg/apis/meta/v1.Time"),
+						},
+					},
+					"archive": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Archive is the location of the exported manifests.",
+							Ref:         ref("kubesphere.io/api/tenant/v1beta1.WorkspaceArchive"),
+						},
+					},
+					"conditions": {
+						VendorExtensible: spec.VendorExtensible{
+							Extensions: spec.Extensions{
+								"x-kubernetes-list-map-keys": []interface{}{
+									"type",
+								},
+								"x-kubernetes-list-type": "map",
+							},
+						},
+						SchemaProps: spec.SchemaProps{
+							Type: []string{"array"},
+							Items: &spec.SchemaOrArray{
+								Schema: &spec.Schema{
+									SchemaProps: spec.SchemaProps{
+										Default: map[string]interface{}{},
+										Ref:     ref("k8s.io/apimachinery/pkg/apis/meta/v1.Condition"),
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+		Dependencies: []string{
+			"k8s.io/apimachinery/pkg/apis/meta/v1.Condition", "k8s.io/apimachinery/pkg/apis/meta/v1.Time", "kubesphere.io/api/tenant/v1beta1.WorkspaceArchive"},
 	}
 }
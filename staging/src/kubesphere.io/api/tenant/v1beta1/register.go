@@ -31,6 +31,8 @@ func addKnownTypes(scheme *runtime.Scheme) error {
 		&WorkspaceList{},
 		&WorkspaceTemplate{},
 		&WorkspaceTemplateList{},
+		&WorkspaceBlueprint{},
+		&WorkspaceBlueprintList{},
 	)
 	metav1.AddToGroupVersion(scheme, SchemeGroupVersion)
 	return nil
@@ -7,6 +7,9 @@
 package v1beta1
 
 import (
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
@@ -19,6 +22,45 @@ const (
 	ResourceKindWorkspaceTemplate     = "WorkspaceTemplate"
 	ResourceSingularWorkspaceTemplate = "workspacetemplate"
 	ResourcePluralWorkspaceTemplate   = "workspacetemplates"
+
+	ResourceKindWorkspaceBlueprint     = "WorkspaceBlueprint"
+	ResourceSingularWorkspaceBlueprint = "workspaceblueprint"
+	ResourcePluralWorkspaceBlueprint   = "workspaceblueprints"
+	// WorkspaceBlueprintLabel is set on the resources applied from the blueprint, the value is the name of the blueprint.
+	WorkspaceBlueprintLabel = "tenant.kubesphere.io/blueprint"
+
+	// WorkspaceConditionClustersReady indicates whether all the clusters where the workspace is placed are ready.
+	WorkspaceConditionClustersReady = "ClustersReady"
+	// WorkspaceConditionQuotaExceeded indicates whether the usage of any workspace resource quota has reached the limit.
+	WorkspaceConditionQuotaExceeded = "QuotaExceeded"
+
+	// WorkspaceStateLabel propagates the lifecycle state of the workspace template to the workspaces in the member clusters.
+	WorkspaceStateLabel = "tenant.kubesphere.io/state"
+	// WorkspaceSuspendedLabel is set on the namespaces of the suspended workspaces, new workloads are rejected in these namespaces.
+	WorkspaceSuspendedLabel = "tenant.kubesphere.io/suspended"
+	// SuspendedReplicasAnnotation records the replicas of a workload before it was scaled to zero by the suspension.
+	SuspendedReplicasAnnotation = "tenant.kubesphere.io/suspended-replicas"
+	// SuspendedCronJobAnnotation marks the cron jobs suspended by the suspension of the workspace.
+	SuspendedCronJobAnnotation = "tenant.kubesphere.io/suspended-cronjob"
+
+	// WorkspaceTemplateConditionExpiring indicates whether the workspace is going to expire within the notification period.
+	WorkspaceTemplateConditionExpiring = "Expiring"
+	// WorkspaceTemplateConditionArchived indicates whether the manifests of the workspace have been exported.
+	WorkspaceTemplateConditionArchived = "Archived"
+	// WorkspaceTemplateConditionBlueprintApplied indicates whether the namespaces of the blueprint have been applied,
+	// the existing namespaces which belong to other workspaces or were not created from the blueprint are not adopted.
+	WorkspaceTemplateConditionBlueprintApplied = "BlueprintApplied"
+)
+
+type WorkspaceState string
+
+const (
+	WorkspaceStateActive WorkspaceState = "Active"
+	// WorkspaceStateSuspended scales the workloads of the workspace to zero and rejects new workloads, the data is kept.
+	WorkspaceStateSuspended WorkspaceState = "Suspended"
+	// WorkspaceStateArchived exports the manifests of the workspace to the object storage, and then deletes
+	// the workspace and its namespaces from the member clusters.
+	WorkspaceStateArchived WorkspaceState = "Archived"
 )
 
 // WorkspaceSpec defines the desired state of Workspace
@@ -28,13 +70,41 @@ type WorkspaceSpec struct {
 
 // WorkspaceStatus defines the observed state of Workspace
 type WorkspaceStatus struct {
-	// INSERT ADDITIONAL STATUS FIELD - define observed state of cluster
-	// Important: Run "make" to regenerate code after modifying this file
+	// Clusters is the status of the workspace in the clusters where it is placed.
+	// +optional
+	// +listType=map
+	// +listMapKey=name
+	Clusters []WorkspaceClusterStatus `json:"clusters,omitempty"`
+	// Members is the number of members broken out by workspace role.
+	// +optional
+	Members map[string]int32 `json:"members,omitempty"`
+	// Quota is the aggregated hard limits and usage of the workspace resource quotas across clusters.
+	// +optional
+	Quota *corev1.ResourceQuotaStatus `json:"quota,omitempty"`
+	// +optional
+	// +listType=map
+	// +listMapKey=type
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+	// State is the lifecycle state of the workspace enforced in the current cluster.
+	// +optional
+	State WorkspaceState `json:"state,omitempty"`
+	// LastUpdateTime is the last time the aggregated status changed.
+	// +optional
+	LastUpdateTime *metav1.Time `json:"lastUpdateTime,omitempty"`
+}
+
+type WorkspaceClusterStatus struct {
+	Name string `json:"name"`
+	// Ready is false if the cluster is not ready, the namespace count is the last observed value.
+	Ready bool `json:"ready"`
+	// Namespaces is the number of namespaces in the workspace.
+	Namespaces int32 `json:"namespaces"`
 }
 
 // +kubebuilder:object:root=true
 // +kubebuilder:storageversion
 // +kubebuilder:resource:categories="tenant",scope="Cluster"
+// +kubebuilder:subresource:status
 
 // Workspace is the Schema for the workspaces API
 type Workspace struct {
@@ -56,6 +126,77 @@ type WorkspaceList struct {
 type WorkspaceTemplateSpec struct {
 	Template  Template         `json:"template"`
 	Placement GenericPlacement `json:"placement"`
+	// +optional
+	Lifecycle *WorkspaceLifecycle `json:"lifecycle,omitempty"`
+	// Blueprint is applied to the workspace in every placement cluster, the applied resources are kept
+	// after the reference is removed.
+	// +optional
+	Blueprint *WorkspaceBlueprintReference `json:"blueprint,omitempty"`
+	// TerminalRecording is the retention policy of the terminal session recordings of the workspace.
+	// +optional
+	TerminalRecording *TerminalRecordingPolicy `json:"terminalRecording,omitempty"`
+}
+
+// TerminalRecordingPolicy defines the retention policy of the terminal session recordings.
+type TerminalRecordingPolicy struct {
+	// RetentionPeriod is how long the recordings are kept after the sessions ended,
+	// the recordings are kept forever if it is not set.
+	// +optional
+	RetentionPeriod *metav1.Duration `json:"retentionPeriod,omitempty"`
+}
+
+type WorkspaceBlueprintReference struct {
+	// Name of the workspace blueprint
+	Name string `json:"name"`
+	// Overrides customizes the blueprint for the workspace. The namespaces, network policies and roles are merged
+	// by name, the other fields replace the values of the blueprint if they are set.
+	// +optional
+	Overrides *WorkspaceBlueprintSpec `json:"overrides,omitempty"`
+}
+
+// WorkspaceLifecycle defines the lifecycle policy of the workspace.
+type WorkspaceLifecycle struct {
+	// State is the desired state of the workspace, defaults to Active.
+	// +kubebuilder:validation:Enum=Active;Suspended;Archived
+	// +optional
+	State WorkspaceState `json:"state,omitempty"`
+	// ExpirationTime is the time when the workspace expires, it takes precedence over TTL.
+	// +optional
+	ExpirationTime *metav1.Time `json:"expirationTime,omitempty"`
+	// TTL is the time to live of the workspace since it was created.
+	// +optional
+	TTL *metav1.Duration `json:"ttl,omitempty"`
+	// ExpirationAction is the state of the workspace after it expires, defaults to Suspended.
+	// +kubebuilder:validation:Enum=Suspended;Archived
+	// +optional
+	ExpirationAction WorkspaceState `json:"expirationAction,omitempty"`
+	// NotifyBefore is the period before the expiration to notify the manager of the workspace, defaults to 72h.
+	// +optional
+	NotifyBefore *metav1.Duration `json:"notifyBefore,omitempty"`
+}
+
+// WorkspaceTemplateStatus defines the observed lifecycle state of the workspace.
+type WorkspaceTemplateStatus struct {
+	// State is the lifecycle state applied to the placement clusters.
+	// +optional
+	State WorkspaceState `json:"state,omitempty"`
+	// ExpirationTime is the effective expiration time of the workspace.
+	// +optional
+	ExpirationTime *metav1.Time `json:"expirationTime,omitempty"`
+	// Archive is the location of the exported manifests.
+	// +optional
+	Archive *WorkspaceArchive `json:"archive,omitempty"`
+	// +optional
+	// +listType=map
+	// +listMapKey=type
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+}
+
+type WorkspaceArchive struct {
+	// Location is the key of the exported manifests in the object storage.
+	Location string `json:"location"`
+	// ArchiveTime is the time when the manifests were exported.
+	ArchiveTime metav1.Time `json:"archiveTime"`
 }
 
 type ObjectMeta struct {
@@ -84,12 +225,14 @@ type GenericPlacement struct {
 // +kubebuilder:object:root=true
 // +kubebuilder:storageversion
 // +kubebuilder:resource:categories="tenant",scope="Cluster"
+// +kubebuilder:subresource:status
 
 // WorkspaceTemplate is the Schema for the workspacetemplates API
 type WorkspaceTemplate struct {
 	metav1.TypeMeta   `json:",inline"`
 	metav1.ObjectMeta `json:"metadata,omitempty"`
-	Spec              WorkspaceTemplateSpec `json:"spec,omitempty"`
+	Spec              WorkspaceTemplateSpec   `json:"spec,omitempty"`
+	Status            WorkspaceTemplateStatus `json:"status,omitempty"`
 }
 
 // +kubebuilder:object:root=true
@@ -100,3 +243,73 @@ type WorkspaceTemplateList struct {
 	metav1.ListMeta `json:"metadata,omitempty"`
 	Items           []WorkspaceTemplate `json:"items"`
 }
+
+// WorkspaceBlueprintSpec defines the resources created for the workspaces.
+type WorkspaceBlueprintSpec struct {
+	// Namespaces are created in every placement cluster, the name of the namespace is prefixed with the workspace name.
+	// +optional
+	// +listType=map
+	// +listMapKey=name
+	Namespaces []BlueprintNamespace `json:"namespaces,omitempty"`
+	// Quota is the resource quota of the workspace in every placement cluster.
+	// +optional
+	Quota *corev1.ResourceQuotaSpec `json:"quota,omitempty"`
+	// LimitRange is applied to every namespace of the workspace.
+	// +optional
+	LimitRange *corev1.LimitRangeSpec `json:"limitRange,omitempty"`
+	// NetworkIsolation only allows the ingress traffic from the namespaces of the same workspace.
+	// +optional
+	NetworkIsolation *bool `json:"networkIsolation,omitempty"`
+	// NetworkPolicies are applied to every namespace of the workspace.
+	// +optional
+	// +listType=map
+	// +listMapKey=name
+	NetworkPolicies []BlueprintNetworkPolicy `json:"networkPolicies,omitempty"`
+	// Roles are the custom workspace roles.
+	// +optional
+	// +listType=map
+	// +listMapKey=name
+	Roles []BlueprintRole `json:"roles,omitempty"`
+}
+
+type BlueprintNamespace struct {
+	Name string `json:"name"`
+	// +optional
+	Labels map[string]string `json:"labels,omitempty"`
+	// +optional
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
+type BlueprintNetworkPolicy struct {
+	Name string                         `json:"name"`
+	Spec networkingv1.NetworkPolicySpec `json:"spec"`
+}
+
+type BlueprintRole struct {
+	Name string `json:"name"`
+	// TemplateNames are the role templates aggregated into the role.
+	// +optional
+	TemplateNames []string `json:"templateNames,omitempty"`
+	// +optional
+	Rules []rbacv1.PolicyRule `json:"rules,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:storageversion
+// +kubebuilder:resource:categories="tenant",scope="Cluster"
+
+// WorkspaceBlueprint is a reusable set of namespaces, quota, network policies and roles applied to workspaces
+type WorkspaceBlueprint struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+	Spec              WorkspaceBlueprintSpec `json:"spec,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// WorkspaceBlueprintList contains a list of WorkspaceBlueprint
+type WorkspaceBlueprintList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []WorkspaceBlueprint `json:"items"`
+}
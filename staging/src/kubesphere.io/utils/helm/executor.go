@@ -19,8 +19,11 @@ import (
 	"time"
 
 	"helm.sh/helm/v3/pkg/action"
+	"helm.sh/helm/v3/pkg/chart/loader"
+	"helm.sh/helm/v3/pkg/chartutil"
 	helmrelease "helm.sh/helm/v3/pkg/release"
 	"helm.sh/helm/v3/pkg/storage/driver"
+	"helm.sh/helm/v3/pkg/strvals"
 	batchv1 "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/resource"
@@ -56,6 +59,11 @@ type Executor interface {
 
 	// helm get all RELEASE_NAME [flags]
 	Get(ctx context.Context, releaseName string, options ...HelmOption) (*helmrelease.Release, error)
+
+	// DryRun renders the chart set by SetChartData with the values in dry-run mode and returns the release that would be deployed,
+	// the release is upgraded if it exists, otherwise it is installed.
+	// helm upgrade --install --dry-run RELEASE_NAME CHART [flags]
+	DryRun(ctx context.Context, release string, values []byte, options ...HelmOption) (*helmrelease.Release, error)
 }
 
 const (
@@ -963,6 +971,49 @@ func (e *executor) Get(ctx context.Context, release string, options ...HelmOptio
 	return result, nil
 }
 
+// DryRun runs the install or upgrade action in process instead of a Job, so that the rendered manifest can be returned.
+func (e *executor) DryRun(ctx context.Context, release string, values []byte, options ...HelmOption) (*helmrelease.Release, error) {
+	helmOptions := e.newHelmOption(append(options, SetDryRun(true)))
+	if len(helmOptions.chartData) == 0 {
+		return nil, errors.New("chart data is required for dry run")
+	}
+	chrt, err := loader.LoadArchive(bytes.NewReader(helmOptions.chartData))
+	if err != nil {
+		return nil, fmt.Errorf("failed to load chart data: %v", err)
+	}
+	vals, err := chartutil.ReadValues(values)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse values: %v", err)
+	}
+	for _, override := range helmOptions.overrides {
+		// the same syntax as "helm --set"
+		if err = strvals.ParseInto(override, vals); err != nil {
+			return nil, fmt.Errorf("failed to parse override %q: %v", override, err)
+		}
+	}
+
+	helmConf, err := InitHelmConf(helmOptions.kubeConfig, helmOptions.namespace)
+	if err != nil {
+		return nil, err
+	}
+	if _, err = e.status(helmConf, release); err != nil {
+		if !errors.Is(err, driver.ErrReleaseNotFound) {
+			return nil, err
+		}
+		install := action.NewInstall(helmConf)
+		install.DryRun = helmOptions.dryRun
+		install.ReleaseName = release
+		install.Namespace = helmOptions.namespace
+		install.Labels = helmOptions.labels
+		return install.RunWithContext(ctx, chrt, vals)
+	}
+	upgrade := action.NewUpgrade(helmConf)
+	upgrade.DryRun = helmOptions.dryRun
+	upgrade.Namespace = helmOptions.namespace
+	upgrade.Labels = helmOptions.labels
+	return upgrade.RunWithContext(ctx, release, chrt, vals)
+}
+
 func (e *executor) WaitingForResourcesReady(ctx context.Context, release string, timeout time.Duration, options ...HelmOption) (bool, error) {
 	helmOptions := e.newHelmOption(options)
 	helmConf, err := InitHelmConf(helmOptions.kubeConfig, helmOptions.namespace)
@@ -130,6 +130,24 @@ func (s *Client) Delete(ids []string) error {
 	return nil
 }
 
+// List returns the keys of the objects with the prefix.
+func (s *Client) List(prefix string) ([]string, error) {
+	var keys []string
+	err := s.s3Client.ListObjectsV2Pages(&s3.ListObjectsV2Input{
+		Bucket: aws.String(s.bucket),
+		Prefix: aws.String(prefix),
+	}, func(page *s3.ListObjectsV2Output, _ bool) bool {
+		for _, object := range page.Contents {
+			keys = append(keys, aws.StringValue(object.Key))
+		}
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
 func NewS3Client(options *Options) (Interface, error) {
 	cred := credentials.NewStaticCredentials(options.AccessKeyID, options.SecretAccessKey, options.SessionToken)
 